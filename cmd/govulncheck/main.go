@@ -25,10 +25,13 @@ func main() {
 		err = cmd.Wait()
 	}
 	if err != nil {
-		var e interface{ ExitCode() int }
+		var e interface {
+			error
+			ExitCode() int
+		}
 		if errors.As(err, &e) {
 			printErrorToStderr := true
-			if _, ok := err.(interface{ ExitCode() int }); ok {
+			if err.Error() == e.Error() {
 				// Avoid printing the error to stderr if the exit code error wasn't
 				// wrapped with another error providing context.
 				printErrorToStderr = false
@@ -157,11 +157,14 @@ func runTestSuite(t *testing.T, testfilesDir string, vulndbDir string, cfg *conf
 		}
 		err := cmd.Wait()
 		if err != nil {
-			var e interface{ ExitCode() int }
+			var e interface {
+				error
+				ExitCode() int
+			}
 			if errors.As(err, &e) {
 				code := e.ExitCode()
 				printErrorToStderr := true
-				if _, ok := err.(interface{ ExitCode() int }); ok {
+				if err.Error() == e.Error() {
 					// Avoid printing the error to stderr if the exit code error wasn't
 					// wrapped with another error providing context.
 					printErrorToStderr = false
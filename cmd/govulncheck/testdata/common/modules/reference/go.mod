@@ -0,0 +1,7 @@
+module golang.org/reference
+
+go 1.18
+
+// This version has a vulnerability whose vulnerable
+// function is referenced but not called.
+require golang.org/x/text v0.3.0
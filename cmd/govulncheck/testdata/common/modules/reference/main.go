@@ -0,0 +1,14 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/text/language"
+)
+
+// parsers are never called.
+var parsers = []func(string) (language.Tag, error){language.Parse}
+
+func main() {
+	fmt.Println(len(parsers))
+}
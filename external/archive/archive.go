@@ -0,0 +1,285 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package archive finds Go executables inside of zip, tar, deb,
+// and rpm archives without unpacking them to disk.
+//
+// Archives nested in archives, such as the layers of an AWS Lambda
+// deployment bundle, are searched as well.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external/buildinfo"
+)
+
+// maxNesting is the maximum depth of archives nested
+// in other archives that are searched.
+const maxNesting = 4
+
+// maxMemberSize is the maximum size, once decompressed, of the
+// archive members that are read into memory. Larger members, such as
+// those of decompression bombs, are skipped. It is a variable for tests.
+var maxMemberSize int64 = 512 << 20
+
+// File is a Go executable read from an archive.
+type File struct {
+	// Name is the path of the executable within the archive. The
+	// path of an executable in a nested archive is made of the path
+	// of the nested archive, "!/", and the path within it.
+	Name string
+	// Data is the content of the executable.
+	Data []byte
+}
+
+// IsArchive reports whether the file name has the extension of
+// an archive format supported by GoExecutables.
+func IsArchive(name string) bool {
+	return format(name) != ""
+}
+
+func format(name string) string {
+	name = strings.ToLower(name)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz", ".tar.bz2", ".deb", ".rpm"} {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// IsArchiveFile reports whether the file at name is an archive supported
+// by GoExecutables, judging by its extension or else by its content.
+// Deployment bundles, such as Java or Python packages, or artifacts
+// stored under content hashes often lack the extension of their format.
+func IsArchiveFile(name string) bool {
+	if IsArchive(name) {
+		return true
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return sniff(f) != ""
+}
+
+// IsArchiveData is like IsArchiveFile for a file
+// named name with content data.
+func IsArchiveData(name string, data []byte) bool {
+	return IsArchiveReader(name, bytes.NewReader(data))
+}
+
+// IsArchiveReader is like IsArchiveFile for a file
+// named name with content read from r.
+func IsArchiveReader(name string, r io.ReaderAt) bool {
+	return IsArchive(name) || sniff(r) != ""
+}
+
+// sniff returns the archive format of the content of r
+// as the extension of the format, or "" if it is not an
+// archive supported by GoExecutables.
+func sniff(r io.ReaderAt) string {
+	// The size of r is not known, but reads past its end just fail.
+	sr := io.NewSectionReader(r, 0, 1<<62)
+	b := readHead(sr)
+	switch {
+	case bytes.HasPrefix(b, []byte("PK\x03\x04")), bytes.HasPrefix(b, []byte("PK\x05\x06")):
+		return ".zip"
+	case bytes.HasPrefix(b, []byte("!<arch>\ndebian-binary")):
+		return ".deb"
+	case bytes.HasPrefix(b, []byte("\xED\xAB\xEE\xDB")):
+		return ".rpm"
+	case isTar(b):
+		return ".tar"
+	case bytes.HasPrefix(b, []byte("\x1F\x8B")):
+		if zr, err := gzip.NewReader(io.NewSectionReader(r, 0, 1<<62)); err == nil && isTar(readHead(zr)) {
+			return ".tar.gz"
+		}
+	case bytes.HasPrefix(b, []byte("BZh")):
+		if isTar(readHead(bzip2.NewReader(io.NewSectionReader(r, 0, 1<<62)))) {
+			return ".tar.bz2"
+		}
+	}
+	return ""
+}
+
+// readHead returns what can be read of the first 512 bytes of r.
+func readHead(r io.Reader) []byte {
+	var head [512]byte
+	n, _ := io.ReadFull(r, head[:])
+	return head[:n]
+}
+
+// isTar reports whether head, the start of a file,
+// is the header of a POSIX or GNU tar archive.
+func isTar(head []byte) bool {
+	return len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar"))
+}
+
+// GoExecutables returns the Go executables contained in the archive
+// at file. Other files in the archive are ignored.
+func GoExecutables(file string) ([]File, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ReadGoExecutables(file, f, fi.Size())
+}
+
+// ReadGoExecutables is like GoExecutables for an archive of the given
+// size read from r. The archive format is determined from name, or
+// from the content of the archive if name has no known extension.
+func ReadGoExecutables(name string, r io.ReaderAt, size int64) ([]File, error) {
+	return readGoExecutables(name, r, size, 0)
+}
+
+func readGoExecutables(name string, r io.ReaderAt, size int64, depth int) ([]File, error) {
+	var files []File
+	add := func(member string, r io.Reader) error {
+		member = strings.TrimPrefix(path.Clean("/"+member), "/")
+		// Only executables and archives are read in full.
+		br := bufio.NewReader(r)
+		head, _ := br.Peek(512)
+		if !isExecutableHead(head) && (depth >= maxNesting || !mayBeArchive(member, head)) {
+			return nil
+		}
+		data, err := io.ReadAll(io.LimitReader(br, maxMemberSize+1))
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", member, err)
+		}
+		if int64(len(data)) > maxMemberSize {
+			return nil
+		}
+		switch {
+		case isGoExecutable(data):
+			files = append(files, File{Name: member, Data: data})
+		case depth < maxNesting && IsArchiveData(member, data):
+			nested, err := readGoExecutables(member, bytes.NewReader(data), int64(len(data)), depth+1)
+			if err != nil {
+				return fmt.Errorf("%s: %w", member, err)
+			}
+			for _, f := range nested {
+				files = append(files, File{Name: member + "!/" + f.Name, Data: f.Data})
+			}
+		}
+		return nil
+	}
+
+	sr := io.NewSectionReader(r, 0, size)
+	f := format(name)
+	if f == "" {
+		f = sniff(r)
+	}
+	var err error
+	switch f {
+	case ".zip":
+		err = readZip(r, size, add)
+	case ".tar":
+		err = readTar(sr, add)
+	case ".tar.gz", ".tgz":
+		var zr *gzip.Reader
+		if zr, err = gzip.NewReader(sr); err == nil {
+			err = readTar(zr, add)
+		}
+	case ".tar.bz2":
+		err = readTar(bzip2.NewReader(sr), add)
+	case ".deb":
+		err = readDeb(bufio.NewReader(sr), add)
+	case ".rpm":
+		err = readRPM(bufio.NewReader(sr), add)
+	default:
+		err = fmt.Errorf("%s: unsupported archive format", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// isGoExecutable reports whether data is an executable, shared or
+// static library, or WebAssembly module containing Go build information.
+func isGoExecutable(data []byte) bool {
+	if !isExecutableHead(data) {
+		return false
+	}
+	_, err := buildinfo.ReadBuildInfo(bytes.NewReader(data))
+	return err == nil
+}
+
+// isExecutableHead reports whether head, the start of a file, is that
+// of an executable, shared or static library, or WebAssembly module.
+func isExecutableHead(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("\x7FELF")) ||
+		bytes.HasPrefix(head, []byte("MZ")) ||
+		bytes.HasPrefix(head, []byte("\xFE\xED\xFA")) ||
+		(len(head) > 1 && bytes.HasPrefix(head[1:], []byte("\xFA\xED\xFE"))) ||
+		bytes.HasPrefix(head, []byte("\x00asm")) ||
+		bytes.HasPrefix(head, []byte("!<arch>\n"))
+}
+
+// mayBeArchive reports whether the file named name, starting with head,
+// may be an archive supported by GoExecutables. Compressed files are
+// only known to hold tar archives once decompressed, so all of them may.
+func mayBeArchive(name string, head []byte) bool {
+	return IsArchive(name) || sniff(bytes.NewReader(head)) != "" ||
+		bytes.HasPrefix(head, []byte("\x1F\x8B")) || bytes.HasPrefix(head, []byte("BZh"))
+}
+
+func readZip(r io.ReaderAt, size int64, add func(string, io.Reader) error) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		if !zf.Mode().IsRegular() {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = add(zf.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTar(r io.Reader, add func(string, io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := add(hdr.Name, tr); err != nil {
+			return err
+		}
+	}
+}
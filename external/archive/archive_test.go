@@ -0,0 +1,297 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// member is a file stored in a test archive.
+type member struct {
+	name string
+	data []byte
+}
+
+func TestGoExecutables(t *testing.T) {
+	// The test binary is a Go executable.
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	exeData, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := []member{
+		{"usr/share/doc/README", []byte("not an executable")},
+		{"usr/bin/app", exeData},
+	}
+
+	for _, tt := range []struct {
+		name  string
+		build func([]member) []byte
+	}{
+		{"app.zip", buildZip},
+		{"app.tar", func(ms []member) []byte { return buildTar(ms) }},
+		{"app.tar.gz", func(ms []member) []byte { return gz(buildTar(ms)) }},
+		{"app.deb", buildDeb},
+		{"app.rpm", buildRPM},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(path, tt.build(members), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if !IsArchive(path) {
+				t.Fatalf("IsArchive(%q) = false", path)
+			}
+			files, err := GoExecutables(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != 1 {
+				t.Fatalf("got %d executables, want 1", len(files))
+			}
+			if files[0].Name != "usr/bin/app" || !bytes.Equal(files[0].Data, exeData) {
+				t.Errorf("got executable %q of %d bytes, want usr/bin/app of %d bytes", files[0].Name, len(files[0].Data), len(exeData))
+			}
+		})
+	}
+}
+
+func TestNestedAndUnnamedArchives(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	exeData, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An AWS Lambda style bundle: the handler at the top level,
+	// and a layer archive holding an extension.
+	layer := buildZip([]member{{"extensions/agent", exeData}})
+	bundle := buildZip([]member{
+		{"bootstrap", exeData},
+		{"layers/agent.zip", layer},
+		{"assets/site.tar.gz", gz(buildTar([]member{{"bin/server", exeData}}))},
+		{"assets/data.gz", gz([]byte("not an archive"))},
+	})
+
+	// Artifacts stored by content hash have no extension.
+	path := filepath.Join(t.TempDir(), "3f7a9c")
+	if err := os.WriteFile(path, bundle, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if IsArchive(path) || !IsArchiveFile(path) {
+		t.Fatalf("IsArchive(%q) = %t, IsArchiveFile = %t; want false, true", path, IsArchive(path), IsArchiveFile(path))
+	}
+	files, err := GoExecutables(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Name)
+	}
+	want := []string{"bootstrap", "layers/agent.zip!/extensions/agent", "assets/site.tar.gz!/bin/server"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got executables %q, want %q", got, want)
+	}
+
+	if IsArchiveData("app", exeData) {
+		t.Error("IsArchiveData reported a Go executable as an archive")
+	}
+}
+
+func TestMaxMemberSize(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	exeData, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := buildZip([]member{
+		{"bin/app", exeData},
+		{"data/zeros", make([]byte, 1<<20)},
+	})
+
+	defer func(size int64) { maxMemberSize = size }(maxMemberSize)
+	maxMemberSize = int64(len(exeData))
+	files, err := ReadGoExecutables("app.zip", bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "bin/app" {
+		t.Fatalf("got %d executables, want bin/app", len(files))
+	}
+
+	maxMemberSize = int64(len(exeData)) - 1
+	files, err = ReadGoExecutables("app.zip", bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Errorf("got %d executables larger than maxMemberSize, want 0", len(files))
+	}
+}
+
+func TestMalformedCPIO(t *testing.T) {
+	// header returns a cpio header of a regular file
+	// with the given size and name size.
+	header := func(size, nameSize uint64) []byte {
+		fields := make([]uint64, 13)
+		fields[1], fields[6], fields[11] = 0o100644, size, nameSize
+		hdr := cpioNewcMagic
+		for _, f := range fields {
+			hdr += fmt.Sprintf("%08x", f)
+		}
+		return []byte(hdr)
+	}
+	add := func(string, io.Reader) error { return nil }
+	for _, tt := range []struct {
+		name string
+		hdr  []byte
+	}{
+		{"huge name", header(0, 0xffffffff)},
+		{"empty name", header(0, 0)},
+		{"huge member", header(0xffffffff, 8)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := readCPIO(bytes.NewReader(tt.hdr), add); err == nil || !strings.Contains(err.Error(), "invalid cpio member") {
+				t.Errorf("got error %v; want invalid cpio member", err)
+			}
+		})
+	}
+}
+
+func TestMalformedDeb(t *testing.T) {
+	add := func(string, io.Reader) error { return nil }
+	for _, size := range []string{"-5", "9999999999"} {
+		var buf bytes.Buffer
+		buf.WriteString(arMagic)
+		fmt.Fprintf(&buf, "%-16s%-12d%-6d%-6d%-8s%-10s`\n", "debian-binary/", 0, 0, 0, "100644", size)
+		buf.WriteString("2.0\n")
+		if err := readDeb(&buf, add); err == nil || !strings.Contains(err.Error(), "invalid ar member size") {
+			t.Errorf("size %s: got error %v; want invalid ar member size", size, err)
+		}
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	for name, want := range map[string]bool{
+		"app":           false,
+		"app.exe":       false,
+		"app.blob":      false,
+		"app.ZIP":       true,
+		"app.tgz":       true,
+		"app.tar.bz2":   true,
+		"app_amd64.deb": true,
+	} {
+		if got := IsArchive(name); got != want {
+			t.Errorf("IsArchive(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func buildZip(ms []member) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range ms {
+		w, err := zw.Create(m.name)
+		if err != nil {
+			panic(err)
+		}
+		w.Write(m.data)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func buildTar(ms []member) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "./usr/", Typeflag: tar.TypeDir, Mode: 0o755})
+	for _, m := range ms {
+		tw.WriteHeader(&tar.Header{Name: "./" + m.name, Typeflag: tar.TypeReg, Mode: 0o755, Size: int64(len(m.data))})
+		tw.Write(m.data)
+	}
+	tw.Close()
+	return buf.Bytes()
+}
+
+func gz(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func buildDeb(ms []member) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(arMagic)
+	for _, m := range []member{
+		{"debian-binary", []byte("2.0\n")},
+		{"control.tar.gz", gz(buildTar(nil))},
+		{"data.tar.gz", gz(buildTar(ms))},
+	} {
+		fmt.Fprintf(&buf, "%-16s%-12d%-6d%-6d%-8s%-10d`\n", m.name+"/", 0, 0, 0, "100644", len(m.data))
+		buf.Write(m.data)
+		if len(m.data)%2 == 1 {
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+func buildRPM(ms []member) []byte {
+	var buf bytes.Buffer
+	lead := make([]byte, rpmLeadSize)
+	copy(lead, rpmLeadMagic)
+	buf.Write(lead)
+
+	header := func(tag uint32, value string) {
+		store := append([]byte(value), 0)
+		buf.WriteString(rpmHeaderMagic)
+		binary.Write(&buf, binary.BigEndian, []uint32{0, 1, uint32(len(store))})
+		binary.Write(&buf, binary.BigEndian, []uint32{tag, 6, 0, 1})
+		buf.Write(store)
+	}
+	// Signature header, padded to 8 bytes.
+	header(1000, "sig")
+	buf.Write(make([]byte, (8-buf.Len()%8)%8))
+	header(rpmTagPayloadCompressor, "gzip")
+
+	var cpio bytes.Buffer
+	entry := func(name string, mode int64, data []byte) {
+		fmt.Fprintf(&cpio, "%s%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x",
+			cpioNewcMagic, 0, mode, 0, 0, 1, 0, len(data), 0, 0, 0, 0, len(name)+1, 0)
+		cpio.WriteString(name)
+		cpio.WriteByte(0)
+		cpio.Write(make([]byte, pad4(int64(cpio.Len()))-int64(cpio.Len())))
+		cpio.Write(data)
+		cpio.Write(make([]byte, pad4(int64(cpio.Len()))-int64(cpio.Len())))
+	}
+	entry("./usr", 0o040755, nil)
+	for _, m := range ms {
+		entry("./"+m.name, 0o100755, m.data)
+	}
+	entry(cpioTrailer, 0, nil)
+	buf.Write(gz(cpio.Bytes()))
+	return buf.Bytes()
+}
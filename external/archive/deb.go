@@ -0,0 +1,67 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const arMagic = "!<arch>\n"
+
+// readDeb reads the files of the data archive of a Debian package,
+// which is an ar archive holding a (possibly compressed) tar archive
+// named data.tar.
+func readDeb(r io.Reader, add func(string, io.Reader) error) error {
+	magic := make([]byte, len(arMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != arMagic {
+		return errors.New("not a Debian package: missing ar header")
+	}
+	for {
+		// An ar member header is 60 bytes: name (16), mtime (12),
+		// uid (6), gid (6), mode (8), size (10), and magic (2).
+		hdr := make([]byte, 60)
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			if err == io.EOF {
+				return errors.New("not a Debian package: missing data.tar member")
+			}
+			return err
+		}
+		name := strings.TrimSuffix(strings.TrimSpace(string(hdr[:16])), "/")
+		size, err := strconv.ParseInt(strings.TrimSpace(string(hdr[48:58])), 10, 64)
+		if err != nil || size < 0 || size > maxMemberSize {
+			return fmt.Errorf("invalid ar member size for %q", name)
+		}
+		member := io.LimitReader(r, size)
+		if strings.HasPrefix(name, "data.tar") {
+			return readDebData(name, member, add)
+		}
+		// Members are aligned to even offsets.
+		if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+			return err
+		}
+	}
+}
+
+func readDebData(name string, r io.Reader, add func(string, io.Reader) error) error {
+	switch name {
+	case "data.tar":
+		return readTar(r, add)
+	case "data.tar.gz":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		return readTar(zr, add)
+	case "data.tar.bz2":
+		return readTar(bzip2.NewReader(r), add)
+	}
+	return fmt.Errorf("unsupported Debian package compression: %s", name)
+}
@@ -0,0 +1,193 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package archive
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	rpmLeadSize    = 96
+	rpmLeadMagic   = "\xed\xab\xee\xdb"
+	rpmHeaderMagic = "\x8e\xad\xe8\x01"
+
+	// rpmTagPayloadCompressor is the header tag holding the name
+	// of the compression applied to the cpio payload.
+	rpmTagPayloadCompressor = 1125
+)
+
+// readRPM reads the files of the payload of an rpm package.
+//
+// An rpm package consists of a fixed size lead, a signature header,
+// the main header, and the payload, a compressed cpio archive.
+func readRPM(r io.Reader, add func(string, io.Reader) error) error {
+	lead := make([]byte, rpmLeadSize)
+	if _, err := io.ReadFull(r, lead); err != nil || !bytes.HasPrefix(lead, []byte(rpmLeadMagic)) {
+		return errors.New("not an rpm package: missing lead")
+	}
+	// The signature header is padded to a multiple of 8 bytes.
+	if _, _, err := readRPMHeader(r, true); err != nil {
+		return fmt.Errorf("reading rpm signature: %w", err)
+	}
+	index, store, err := readRPMHeader(r, false)
+	if err != nil {
+		return fmt.Errorf("reading rpm header: %w", err)
+	}
+
+	compressor := "gzip" // the historical default
+	if s, ok := rpmString(index, store, rpmTagPayloadCompressor); ok {
+		compressor = s
+	}
+	var payload io.Reader
+	switch compressor {
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		payload = zr
+	case "bzip2":
+		payload = bzip2.NewReader(r)
+	case "", "identity":
+		payload = r
+	default:
+		return fmt.Errorf("unsupported rpm payload compression: %s", compressor)
+	}
+	return readCPIO(payload, add)
+}
+
+// readRPMHeader reads an rpm header structure and returns
+// its index entries and data store.
+func readRPMHeader(r io.Reader, padded bool) (index []byte, store []byte, err error) {
+	// The intro is the magic, 4 reserved bytes, the number
+	// of index entries, and the size of the data store.
+	intro := make([]byte, 16)
+	if _, err := io.ReadFull(r, intro); err != nil {
+		return nil, nil, err
+	}
+	if !bytes.HasPrefix(intro, []byte(rpmHeaderMagic)) {
+		return nil, nil, errors.New("bad header magic")
+	}
+	n := int64(binary.BigEndian.Uint32(intro[8:12]))
+	size := int64(binary.BigEndian.Uint32(intro[12:16]))
+	if n > 1<<16 || size > 1<<28 {
+		return nil, nil, errors.New("header too large")
+	}
+	index = make([]byte, 16*n)
+	if _, err := io.ReadFull(r, index); err != nil {
+		return nil, nil, err
+	}
+	store = make([]byte, size)
+	if _, err := io.ReadFull(r, store); err != nil {
+		return nil, nil, err
+	}
+	if padded {
+		if _, err := io.CopyN(io.Discard, r, (8-size%8)%8); err != nil {
+			return nil, nil, err
+		}
+	}
+	return index, store, nil
+}
+
+// rpmString returns the value of the string tag in an rpm header.
+func rpmString(index, store []byte, tag uint32) (string, bool) {
+	const stringType = 6
+	for i := 0; i+16 <= len(index); i += 16 {
+		e := index[i : i+16]
+		if binary.BigEndian.Uint32(e[0:4]) != tag || binary.BigEndian.Uint32(e[4:8]) != stringType {
+			continue
+		}
+		off := int(binary.BigEndian.Uint32(e[8:12]))
+		if off >= len(store) {
+			return "", false
+		}
+		s, _, _ := bytes.Cut(store[off:], []byte{0})
+		return string(s), true
+	}
+	return "", false
+}
+
+// cpioNewcMagic and cpioCRCMagic identify the cpio formats
+// used for rpm payloads.
+const (
+	cpioNewcMagic = "070701"
+	cpioCRCMagic  = "070702"
+	cpioTrailer   = "TRAILER!!!"
+)
+
+// maxCPIONameLen bounds the length of the names
+// of the members of cpio archives.
+const maxCPIONameLen = 4096
+
+// readCPIO reads the regular files of a cpio archive
+// in the "new" portable format.
+func readCPIO(r io.Reader, add func(string, io.Reader) error) error {
+	// The header is a 6 byte magic number followed by
+	// 13 fields of 8 hexadecimal digits.
+	hdr := make([]byte, 110)
+	field := func(i int) (int64, error) {
+		return strconv.ParseInt(string(hdr[6+8*i:6+8*(i+1)]), 16, 64)
+	}
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			return err
+		}
+		if magic := string(hdr[:6]); magic != cpioNewcMagic && magic != cpioCRCMagic {
+			return fmt.Errorf("unsupported cpio format %q", magic)
+		}
+		mode, err := field(1)
+		if err != nil {
+			return err
+		}
+		size, err := field(6)
+		if err != nil {
+			return err
+		}
+		if size < 0 || size > maxMemberSize {
+			return fmt.Errorf("invalid cpio member size %d", size)
+		}
+		nameSize, err := field(11)
+		if err != nil {
+			return err
+		}
+		if nameSize <= 0 || nameSize > maxCPIONameLen {
+			return fmt.Errorf("invalid cpio member name size %d", nameSize)
+		}
+		// The name is NUL terminated and padded so that
+		// header and name are a multiple of 4 bytes long.
+		nameBuf := make([]byte, pad4(110+nameSize)-110)
+		if _, err := io.ReadFull(r, nameBuf); err != nil {
+			return err
+		}
+		name := string(bytes.TrimRight(nameBuf[:nameSize], "\x00"))
+		if name == cpioTrailer {
+			return nil
+		}
+		data := io.LimitReader(r, size)
+		if mode&0o170000 == 0o100000 {
+			if err := add(name, data); err != nil {
+				return err
+			}
+		}
+		// Skip what add did not consume, including padding.
+		if _, err := io.Copy(io.Discard, data); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(io.Discard, r, pad4(size)-size); err != nil {
+			return err
+		}
+	}
+}
+
+func pad4(n int64) int64 {
+	return (n + 3) &^ 3
+}
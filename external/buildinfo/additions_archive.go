@@ -0,0 +1,162 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package buildinfo
+
+// Addition: this file adds support for the static libraries built with
+// -buildmode=c-archive. They are ar archives of C object files and of
+// a single object file, go.o, holding the Go code. Unlike executables
+// and shared libraries, object files are not laid out in memory, so the
+// build information is read directly from its section.
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+const arMagic = "!<arch>\n"
+
+// maxArNameLen bounds the length of the long names of the
+// members of BSD archives, which are read into memory.
+const maxArNameLen = 4096
+
+func isArArchive(r io.ReaderAt) bool {
+	data := make([]byte, len(arMagic))
+	_, err := r.ReadAt(data, 0)
+	return err == nil && string(data) == arMagic
+}
+
+// goObject returns the go.o member of the ar archive in r.
+// The members must fit in r, if its size is known.
+func goObject(r io.ReaderAt) (*io.SectionReader, error) {
+	end, sized := readerSize(r)
+	off := int64(len(arMagic))
+	var hdr [60]byte
+	for {
+		if _, err := r.ReadAt(hdr[:], off); err != nil {
+			if err == io.EOF {
+				return nil, errors.New("no Go object file in archive")
+			}
+			return nil, err
+		}
+		off += int64(len(hdr))
+		size, err := strconv.ParseInt(strings.TrimSpace(string(hdr[48:58])), 10, 64)
+		if err != nil || size < 0 || size > math.MaxInt64-off-1 || sized && size > end-off {
+			return nil, fmt.Errorf("malformed archive header at offset %d", off-int64(len(hdr)))
+		}
+		name := strings.TrimSpace(string(hdr[0:16]))
+		data := io.NewSectionReader(r, off, size)
+		if n, ok := strings.CutPrefix(name, "#1/"); ok {
+			// BSD archives store long names before the data.
+			nlen, err := strconv.ParseInt(n, 10, 64)
+			if err != nil || nlen < 0 || nlen > size || nlen > maxArNameLen {
+				return nil, fmt.Errorf("malformed archive member name %q", name)
+			}
+			b := make([]byte, nlen)
+			if _, err := r.ReadAt(b, off); err != nil {
+				return nil, err
+			}
+			name = string(bytes.TrimRight(b, "\x00"))
+			data = io.NewSectionReader(r, off+nlen, size-nlen)
+		}
+		if strings.TrimSuffix(name, "/") == "go.o" {
+			return data, nil
+		}
+		off += size + size%2
+	}
+}
+
+// readerSize returns the size of r, and whether it is known.
+func readerSize(r io.ReaderAt) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Size() int64 }:
+		return r.Size(), true
+	case interface{ Stat() (os.FileInfo, error) }:
+		if fi, err := r.Stat(); err == nil && fi.Mode().IsRegular() {
+			return fi.Size(), true
+		}
+	}
+	return 0, false
+}
+
+// readObjectBuildInfo returns the build information of the
+// ELF or Mach-O object file in r.
+func readObjectBuildInfo(r io.ReaderAt) (*debug.BuildInfo, error) {
+	var data []byte
+	var err error
+	if ef, eerr := elf.NewFile(r); eerr == nil {
+		s := ef.Section(".go.buildinfo")
+		if s == nil {
+			return nil, errors.New("not a Go object file")
+		}
+		data, err = s.Data()
+	} else if mf, merr := macho.NewFile(r); merr == nil {
+		s := mf.Section("__go_buildinfo")
+		if s == nil {
+			return nil, errors.New("not a Go object file")
+		}
+		data, err = s.Data()
+	} else {
+		return nil, errors.New("unrecognized object file format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseBuildInfoSection(data)
+}
+
+// parseBuildInfoSection parses the content of the build information
+// section written by Go 1.18 and later, which holds the Go version
+// and module information inline.
+func parseBuildInfoSection(data []byte) (*debug.BuildInfo, error) {
+	const (
+		buildInfoMagic      = "\xff Go buildinf:"
+		buildInfoHeaderSize = 32
+		flagsVersionInl     = 0x2
+	)
+	if len(data) < buildInfoHeaderSize || !bytes.HasPrefix(data, []byte(buildInfoMagic)) {
+		return nil, errors.New("not a Go object file")
+	}
+	if data[len(buildInfoMagic)+1]&flagsVersionInl == 0 {
+		return nil, errors.New("not built with Go 1.18 or later")
+	}
+	data = data[buildInfoHeaderSize:]
+	str := func() string {
+		n, w := binary.Uvarint(data)
+		if w <= 0 || n > uint64(len(data)-w) {
+			data = nil
+			return ""
+		}
+		s := string(data[w : w+int(n)])
+		data = data[w+int(n):]
+		return s
+	}
+	vers := str()
+	mod := str()
+	if vers == "" {
+		return nil, errors.New("not a Go object file")
+	}
+	// The module information is framed by 16 byte sentinels.
+	if len(mod) >= 33 && mod[len(mod)-17] == '\n' {
+		mod = mod[16 : len(mod)-16]
+	} else {
+		mod = ""
+	}
+	bi, err := debug.ParseBuildInfo(mod)
+	if err != nil {
+		return nil, err
+	}
+	bi.GoVersion = vers
+	return bi, nil
+}
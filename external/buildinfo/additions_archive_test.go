@@ -0,0 +1,114 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package buildinfo
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/testenv"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestBuildModes checks that the plugins, shared libraries, and
+// static libraries built from Go code can be scanned like executables.
+func TestBuildModes(t *testing.T) {
+	testenv.NeedsGoBuild(t)
+	if runtime.GOOS != "linux" {
+		t.Skip("plugins and c-archive scanning are tested on linux only")
+	}
+	goCmd := filepath.Join(runtime.GOROOT(), "bin", "go")
+	if out, err := exec.Command(goCmd, "env", "CGO_ENABLED").Output(); err != nil || strings.TrimSpace(string(out)) != "1" {
+		t.Skip("cgo is not available")
+	}
+
+	src := t.TempDir()
+	main, err := os.ReadFile(filepath.Join("testdata", "src", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "main.go"), main, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "go.mod"), []byte("module example.com/src\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		mode, out string
+		pkg       string // package of the symbols of package main
+	}{
+		// The main package of a plugin keeps its import path.
+		{"plugin", "src.so", "example.com/src"},
+		{"c-shared", "src.so", "main"},
+		{"c-archive", "src.a", "main"},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), tt.out)
+			cmd := exec.Command(goCmd, "build", "-buildmode="+tt.mode, "-o", out, ".")
+			cmd.Dir = src
+			cmd.Env = append(os.Environ(), "GOFLAGS=")
+			if b, err := cmd.CombinedOutput(); err != nil {
+				t.Skipf("building with -buildmode=%s: %v\n%s", tt.mode, err, b)
+			}
+
+			_, syms, bi, err := ExtractPackagesAndSymbols(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := sortedSymbols(tt.pkg, syms)
+			want := []Symbol{
+				{tt.pkg, "f"},
+				{tt.pkg, "g"},
+				{tt.pkg, "main"},
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("(-want,+got):%s", diff)
+			}
+			if got := setting(bi, SymbolPrecisionSetting); got != SymbolsComplete {
+				t.Errorf("got symbol precision %q; want %q", got, SymbolsComplete)
+			}
+			if got := setting(bi, "GOOS"); got != "linux" {
+				t.Errorf("got GOOS %q; want linux", got)
+			}
+		})
+	}
+}
+
+func TestParseBuildInfoSection(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"not build information at all, but long enough",
+		"\xff Go buildinf:\x08\x00" + strings.Repeat("\x00", 16) + "\x06go1.22",
+	} {
+		if _, err := parseBuildInfoSection([]byte(data)); err == nil {
+			t.Errorf("parseBuildInfoSection(%q) succeeded; want error", data)
+		}
+	}
+}
+
+func TestGoObjectMalformed(t *testing.T) {
+	header := func(name string, size string) string {
+		return fmt.Sprintf("%-16s%-12s%-6s%-6s%-8s%-10s`\n", name, "0", "0", "0", "644", size)
+	}
+	for _, tc := range []struct {
+		name, archive string
+	}{
+		{"negative name length", arMagic + header("#1/-5", "10") + "go.o\x00\x00\x00\x00\x00\x00"},
+		{"name past member", arMagic + header("#1/20", "10") + "go.o\x00\x00\x00\x00\x00\x00"},
+		{"member past archive", arMagic + header("go.o", "999999999")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ReadBuildInfo(strings.NewReader(tc.archive)); err == nil || !strings.Contains(err.Error(), "malformed archive") {
+				t.Errorf("got error %v; want a malformed archive", err)
+			}
+		})
+	}
+}
@@ -31,6 +31,14 @@ func (x *elfExe) SymbolInfo(name string) (uint64, uint64, io.ReaderAt, error) {
 		}
 		return 0, 0, nil, fmt.Errorf("no symbol %q", name)
 	}
+	if x.f.Type == elf.ET_REL {
+		// Object files are not laid out in memory, and
+		// symbol values are offsets in their sections.
+		if int(sym.Section) >= len(x.f.Sections) {
+			return 0, 0, nil, fmt.Errorf("no section containing %q", name)
+		}
+		return sym.Value, 0, x.f.Sections[sym.Section], nil
+	}
 	prog := x.progContaining(sym.Value)
 	if prog == nil {
 		return 0, 0, nil, fmt.Errorf("no Prog containing value %d for %q", sym.Value, name)
@@ -38,23 +46,59 @@ func (x *elfExe) SymbolInfo(name string) (uint64, uint64, io.ReaderAt, error) {
 	return sym.Value, prog.Vaddr, prog.ReaderAt, nil
 }
 
+// lookupSymbol returns the symbol of x named name, or nil if there is
+// none. Rather than decoding all the symbols, as elf.File.Symbols does,
+// which allocates the names of the millions of symbols of large
+// binaries, it only compares their names to name in the string table.
 func (x *elfExe) lookupSymbol(name string) (*elf.Symbol, error) {
-	x.symbolsOnce.Do(func() {
-		syms, err := x.f.Symbols()
-		if err != nil {
-			x.symbolsErr = err
-			return
+	var symtab *elf.Section
+	for _, s := range x.f.Sections {
+		if s.Type == elf.SHT_SYMTAB {
+			symtab = s
+			break
 		}
-		x.symbols = make(map[string]*elf.Symbol, len(syms))
-		for _, s := range syms {
-			s := s // make a copy to prevent aliasing
-			x.symbols[s.Name] = &s
+	}
+	if symtab == nil {
+		return nil, elf.ErrNoSymbols
+	}
+	if int(symtab.Link) >= len(x.f.Sections) {
+		return nil, fmt.Errorf("invalid string table index %d", symtab.Link)
+	}
+	data, err := symtab.Data()
+	if err != nil {
+		return nil, err
+	}
+	strtab, err := x.f.Sections[symtab.Link].Data()
+	if err != nil {
+		return nil, err
+	}
+	bo := x.f.ByteOrder
+	size := elf.Sym32Size
+	if x.f.Class == elf.ELFCLASS64 {
+		size = elf.Sym64Size
+	}
+	if len(data) < size {
+		return nil, nil
+	}
+	// The first symbol is the null symbol.
+	for b := data[size:]; len(b) >= size; b = b[size:] {
+		off := int(bo.Uint32(b))
+		if off+len(name) >= len(strtab) || strtab[off+len(name)] != 0 || string(strtab[off:off+len(name)]) != name {
+			continue
 		}
-	})
-	if x.symbolsErr != nil {
-		return nil, x.symbolsErr
+		sym := &elf.Symbol{Name: name}
+		if x.f.Class == elf.ELFCLASS64 {
+			sym.Info, sym.Other = b[4], b[5]
+			sym.Section = elf.SectionIndex(bo.Uint16(b[6:]))
+			sym.Value, sym.Size = bo.Uint64(b[8:]), bo.Uint64(b[16:])
+		} else {
+			sym.Value, sym.Size = uint64(bo.Uint32(b[4:])), uint64(bo.Uint32(b[8:]))
+			sym.Info, sym.Other = b[12], b[13]
+			sym.Section = elf.SectionIndex(bo.Uint16(b[14:]))
+		}
+		return sym, nil
 	}
-	return x.symbols[name], nil
+	return nil, nil
 }
 
 func (x *elfExe) progContaining(addr uint64) *elf.Prog {
@@ -68,6 +112,30 @@ func (x *elfExe) progContaining(addr uint64) *elf.Prog {
 
 const go12magic = 0xfffffffb
 const go116magic = 0xfffffffa
+const go118magic = 0xfffffff0
+const go120magic = 0xfffffff1
+
+// searchPCLNTab returns the suffix of b starting with the header of
+// a PCLN table, or nil if there is none. The header is recognized by
+// its magic number followed by two zero bytes, the instruction size
+// quantum, and the pointer size.
+func searchPCLNTab(b []byte) []byte {
+	for i := 0; i+8 <= len(b); i += 4 {
+		if b[i+4] != 0 || b[i+5] != 0 ||
+			(b[i+6] != 1 && b[i+6] != 2 && b[i+6] != 4) ||
+			(b[i+7] != 4 && b[i+7] != 8) {
+			continue
+		}
+		le := binary.LittleEndian.Uint32(b[i:])
+		be := binary.BigEndian.Uint32(b[i:])
+		for _, magic := range []uint32{go12magic, go116magic, go118magic, go120magic} {
+			if le == magic || be == magic {
+				return b[i:]
+			}
+		}
+	}
+	return nil
+}
 
 // PCLNTab is derived from cmd/external/objfile/elf.go:pcln.
 func (x *elfExe) PCLNTab() ([]byte, uint64) {
@@ -156,8 +224,6 @@ func (x *peExe) lookupSymbol(name string) (*pe.Symbol, error) {
 }
 
 // PCLNTab is derived from cmd/external/objfile/pe.go:pcln.
-// Assumes that the underlying symbol table exists, otherwise
-// it might panic.
 func (x *peExe) PCLNTab() ([]byte, uint64) {
 	var textOffset uint64
 	for _, section := range x.f.Sections {
@@ -177,6 +243,15 @@ func (x *peExe) PCLNTab() ([]byte, uint64) {
 		end = int64(s.Value)
 	}
 	if start == 0 || end == 0 {
+		// Addition: stripped binaries have no symbols delimiting
+		// the PCLN table, so look for its header in read-only data.
+		if rdata := x.f.Section(".rdata"); rdata != nil {
+			if b, err := rdata.Data(); err == nil {
+				if pclntab := searchPCLNTab(b); pclntab != nil {
+					return pclntab, textOffset
+				}
+			}
+		}
 		return nil, 0
 	}
 	offset := int64(x.f.Sections[section].Offset) + start
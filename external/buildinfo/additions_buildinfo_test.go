@@ -0,0 +1,91 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+
+package buildinfo
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLookupSymbolELF(t *testing.T) {
+	testAll(t, []string{"linux"}, []string{"amd64", "386", "arm64"}, func(t *testing.T, goos, goarch string) {
+		binary, done := test.GoBuild(t, "testdata/src", "", false, "GOOS", goos, "GOARCH", goarch)
+		defer done()
+
+		f, err := os.Open(binary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		x, err := openExe(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ex := x.(*elfExe)
+		syms, err := ex.f.Symbols()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make(map[string]*elf.Symbol)
+		for i := range syms {
+			if _, ok := want[syms[i].Name]; !ok {
+				want[syms[i].Name] = &syms[i]
+			}
+		}
+		for _, name := range []string{"go:func.*", "main.main", "runtime.text", "no.such.symbol"} {
+			got, err := ex.lookupSymbol(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want[name], got); diff != "" {
+				t.Errorf("lookupSymbol(%q) mismatch (-want, +got):\n%s", name, diff)
+			}
+		}
+	})
+}
+
+func TestReadBuildInfoMalformedWasm(t *testing.T) {
+	uvarint := func(v uint64) string { return string(binary.AppendUvarint(nil, v)) }
+	for _, tc := range []struct {
+		name, module, want string
+	}{
+		{
+			name:   "truncated section",
+			module: wasmMagic + "\x00" + uvarint(1<<62) + "\x09producers",
+			want:   "malformed wasm section 0: unexpected EOF",
+		},
+		{
+			name:   "truncated skipped section",
+			module: wasmMagic + "\x01" + uvarint(1<<40) + "\x00",
+			want:   "malformed wasm section 1: unexpected EOF",
+		},
+		{
+			name:   "section too large",
+			module: wasmMagic + "\x0b" + uvarint(1<<63),
+			want:   "too large",
+		},
+		{
+			name: "segments far apart",
+			// Two active segments, at 0 and at 0xffffff00.
+			module: wasmMagic + "\x0b\x0e\x02" + "\x00\x41\x00\x0b\x01a" + "\x00\x41\x80\x7e\x0b\x01b",
+			want:   "wasm data segments span 4294967041 bytes of memory, for 2 bytes of data",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadBuildInfo(strings.NewReader(tc.module))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got error %v; want %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+
+package buildinfo
+
+// This file adds to buildinfo the functionality for listing the
+// shared libraries a binary is dynamically linked against, which
+// for Go binaries are typically the C libraries linked through cgo.
+
+// ImportedLibraries returns the shared libraries the binary
+// is dynamically linked against.
+func (x *elfExe) ImportedLibraries() []string {
+	libs, _ := x.f.ImportedLibraries()
+	return libs
+}
+
+// ImportedLibraries returns the DLLs the binary imports.
+func (x *peExe) ImportedLibraries() []string {
+	libs, _ := x.f.ImportedLibraries()
+	return libs
+}
+
+// ImportedLibraries returns the dynamic libraries the binary loads.
+func (x *machoExe) ImportedLibraries() []string {
+	libs, _ := x.f.ImportedLibraries()
+	return libs
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+
+package buildinfo
+
+// This file adds to buildinfo the functionality for inferring the
+// target platform of a binary from its executable headers.
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"os"
+	"runtime/debug"
+)
+
+// ExtractPlatform returns the GOOS and GOARCH the binary file was built
+// for. The build settings recorded by the linker are preferred. When they
+// are not available, as is the case for binaries built without module
+// support, the platform is inferred from the executable headers. Either
+// of the returned values is empty if it cannot be determined.
+func ExtractPlatform(file string) (goos, goarch string, err error) {
+	bin, err := os.Open(file)
+	if err != nil {
+		return "", "", err
+	}
+	defer bin.Close()
+
+	x, err := openExe(bin)
+	if err != nil {
+		return "", "", err
+	}
+	bi, err := ReadBuildInfo(bin)
+	if err != nil {
+		// Ancient binaries have no build info, so rely on the headers.
+		bi = &debug.BuildInfo{}
+	}
+	addPlatformSettings(bi, x)
+	return setting(bi, "GOOS"), setting(bi, "GOARCH"), nil
+}
+
+// addPlatformSettings adds GOOS and GOARCH settings to bi when they are
+// missing, using the platform inferred from the headers of x. This makes
+// the target platform available independently of the host platform that
+// is doing the scanning.
+func addPlatformSettings(bi *debug.BuildInfo, x exe) {
+	goos, goarch := x.Platform()
+	if setting(bi, "GOOS") == "" && goos != "" {
+		bi.Settings = append(bi.Settings, debug.BuildSetting{Key: "GOOS", Value: goos})
+	}
+	if setting(bi, "GOARCH") == "" && goarch != "" {
+		bi.Settings = append(bi.Settings, debug.BuildSetting{Key: "GOARCH", Value: goarch})
+	}
+}
+
+func setting(bi *debug.BuildInfo, key string) string {
+	for _, s := range bi.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// Platform infers the platform from the ELF header. ELF is used by
+// many operating systems, most of which Go marks with ELFOSABI_NONE,
+// so we assume linux unless the header says otherwise.
+func (x *elfExe) Platform() (goos, goarch string) {
+	switch x.f.OSABI {
+	case elf.ELFOSABI_NONE, elf.ELFOSABI_LINUX:
+		goos = "linux"
+	case elf.ELFOSABI_FREEBSD:
+		goos = "freebsd"
+	case elf.ELFOSABI_NETBSD:
+		goos = "netbsd"
+	case elf.ELFOSABI_OPENBSD:
+		goos = "openbsd"
+	case elf.ELFOSABI_SOLARIS:
+		goos = "solaris"
+	}
+
+	le := x.f.Data == elf.ELFDATA2LSB
+	is64 := x.f.Class == elf.ELFCLASS64
+	switch x.f.Machine {
+	case elf.EM_X86_64:
+		goarch = "amd64"
+	case elf.EM_386:
+		goarch = "386"
+	case elf.EM_ARM:
+		goarch = "arm"
+	case elf.EM_AARCH64:
+		goarch = "arm64"
+	case elf.EM_PPC64:
+		goarch = choose(le, "ppc64le", "ppc64")
+	case elf.EM_S390:
+		goarch = "s390x"
+	case elf.EM_RISCV:
+		goarch = "riscv64"
+	case elf.EM_LOONGARCH:
+		goarch = "loong64"
+	case elf.EM_MIPS:
+		if is64 {
+			goarch = choose(le, "mips64le", "mips64")
+		} else {
+			goarch = choose(le, "mipsle", "mips")
+		}
+	}
+	return goos, goarch
+}
+
+// Platform infers the platform from the PE header.
+func (x *peExe) Platform() (goos, goarch string) {
+	switch x.f.Machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		goarch = "amd64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		goarch = "386"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		goarch = "arm64"
+	case pe.IMAGE_FILE_MACHINE_ARMNT:
+		goarch = "arm"
+	}
+	return "windows", goarch
+}
+
+// Platform infers the platform from the Mach-O header. Mach-O
+// binaries for ios cannot be distinguished from darwin ones by
+// the header alone, so we report darwin.
+func (x *machoExe) Platform() (goos, goarch string) {
+	switch x.f.Cpu {
+	case macho.CpuAmd64:
+		goarch = "amd64"
+	case macho.Cpu386:
+		goarch = "386"
+	case macho.CpuArm64:
+		goarch = "arm64"
+	case macho.CpuArm:
+		goarch = "arm"
+	}
+	return "darwin", goarch
+}
+
+func choose(b bool, yes, no string) string {
+	if b {
+		return yes
+	}
+	return no
+}
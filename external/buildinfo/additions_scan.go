@@ -10,16 +10,20 @@ package buildinfo
 // and cmd/go/external/version/exe.go.
 
 import (
-	"debug/buildinfo"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net/url"
 	"os"
+	"runtime"
 	"runtime/debug"
 	"strings"
+	"unicode"
 
 	"github.com/StevenACoffman/invuln/external/gosym"
 	"github.com/StevenACoffman/invuln/external/goversion"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -45,11 +49,42 @@ type Symbol struct {
 	Name string `json:"name,omitempty"`
 }
 
+// SymbolPrecisionSetting is the key of the setting added to the build
+// info returned by ExtractPackagesAndSymbols to report how completely
+// the symbols of the binary were recovered. Its value is one of
+// SymbolsComplete, SymbolsNoInlined, and SymbolsNone.
+const SymbolPrecisionSetting = "govulncheck.symbols"
+
+const (
+	// SymbolsComplete means that all symbols, including those
+	// inlined into other functions, were recovered.
+	SymbolsComplete = "complete"
+
+	// SymbolsNoInlined means that the binary has no symbol table,
+	// such as when built with -ldflags="-s -w", and its symbols were
+	// recovered from the function table of the PCLN table alone.
+	// Functions inlined into other functions cannot be recovered.
+	SymbolsNoInlined = "no-inlined"
+
+	// SymbolsNone means that no symbols could be recovered.
+	SymbolsNone = "none"
+)
+
+// ImportedLibrariesSetting is the key of the setting added to the build
+// info returned by ExtractPackagesAndSymbols that lists, separated by
+// commas, the shared libraries the binary is dynamically linked against.
+// It is absent for statically linked binaries.
+const ImportedLibrariesSetting = "govulncheck.libraries"
+
 // ExtractPackagesAndSymbols extracts symbols, packages, modules from
 // Go binary file as well as bin's metadata.
 //
 // If the symbol table is not available, such as in the case of stripped
-// binaries, returns module and binary info but without the symbol info.
+// binaries, symbols are recovered from the PCLN table, but without
+// the symbols that were inlined. If that is not possible either, or
+// reading the symbols fails, returns module and binary info but without
+// the symbol info. The setting SymbolPrecisionSetting of the returned
+// build info tells these cases apart.
 func ExtractPackagesAndSymbols(file string) ([]*packages.Module, []Symbol, *debug.BuildInfo, error) {
 	bin, err := os.Open(file)
 	if err != nil {
@@ -57,7 +92,7 @@ func ExtractPackagesAndSymbols(file string) ([]*packages.Module, []Symbol, *debu
 	}
 	defer bin.Close()
 
-	bi, err := buildinfo.Read(bin)
+	bi, err := ReadBuildInfo(bin)
 	if err != nil {
 		// It could be that bin is an ancient Go binary.
 		v, err := goversion.ReadExe(file)
@@ -68,84 +103,170 @@ func ExtractPackagesAndSymbols(file string) ([]*packages.Module, []Symbol, *debu
 			GoVersion: v.Release,
 			Main:      debug.Module{Path: v.ModuleInfo},
 		}
+		if x, err := openExe(bin); err == nil {
+			addPlatformSettings(bi, x)
+		}
 		// We cannot analyze symbol tables of ancient binaries.
+		setSymbolPrecision(bi, SymbolsNone)
 		return nil, nil, bi, nil
 	}
+	return extractPackagesAndSymbols(bin, bi)
+}
 
-	funcSymName := gosym.FuncSymName(bi.GoVersion)
-	if funcSymName == "" {
-		return nil, nil, nil, fmt.Errorf("binary built using unsupported Go version: %q", bi.GoVersion)
+// ExtractPackagesAndSymbolsFromReader is like ExtractPackagesAndSymbols
+// but reads the binary from r, for instance when it is held in memory.
+// Unlike ExtractPackagesAndSymbols, it does not support binaries built
+// with Go versions that predate debug.BuildInfo (< go1.18).
+func ExtractPackagesAndSymbolsFromReader(r io.ReaderAt) ([]*packages.Module, []Symbol, *debug.BuildInfo, error) {
+	bi, err := ReadBuildInfo(r)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	return extractPackagesAndSymbols(r, bi)
+}
 
+func extractPackagesAndSymbols(bin io.ReaderAt, bi *debug.BuildInfo) ([]*packages.Module, []Symbol, *debug.BuildInfo, error) {
+	var syms []Symbol
+	precision := SymbolsNone
 	x, err := openExe(bin)
+	if err == nil {
+		addPlatformSettings(bi, x)
+		if libs := x.ImportedLibraries(); len(libs) > 0 {
+			bi.Settings = append(bi.Settings, debug.BuildSetting{Key: ImportedLibrariesSetting, Value: strings.Join(libs, ",")})
+		}
+		syms, precision, err = extractSymbols(x, bi.GoVersion)
+	}
 	if err != nil {
-		return nil, nil, nil, err
+		// The build information alone still supports module
+		// level findings, so do not give up on the binary.
+		syms, precision = nil, SymbolsNone
 	}
+	setSymbolPrecision(bi, precision)
+	return debugModulesToPackagesModules(bi.Deps), syms, bi, nil
+}
 
-	value, base, r, err := x.SymbolInfo(funcSymName)
-	if err != nil {
-		if errors.Is(err, ErrNoSymbols) {
-			// bin is stripped, so return just module info and metadata.
-			return debugModulesToPackagesModules(bi.Deps), nil, bi, nil
-		}
-		return nil, nil, nil, fmt.Errorf("reading %v: %v", funcSymName, err)
+// extractSymbols returns the symbols of x, built with goVersion,
+// along with how precisely they were recovered.
+//
+// The symbol table is searched while the PCLN table is read, and the
+// inline trees of the functions are then read in parallel, which
+// dominates the extraction of large binaries.
+func extractSymbols(x exe, goVersion string) ([]Symbol, string, error) {
+	funcSymName := gosym.FuncSymName(goVersion)
+	if funcSymName == "" {
+		return nil, "", fmt.Errorf("binary built using unsupported Go version: %q", goVersion)
 	}
 
+	var (
+		value, base uint64
+		r           io.ReaderAt
+		symErr      error
+		done        = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		value, base, r, symErr = x.SymbolInfo(funcSymName)
+	}()
 	pclntab, textOffset := x.PCLNTab()
+	<-done
+
+	precision := SymbolsComplete
+	if symErr != nil {
+		if !errors.Is(symErr, ErrNoSymbols) {
+			return nil, "", fmt.Errorf("reading %v: %v", funcSymName, symErr)
+		}
+		// bin is stripped. The PCLN table is still needed at run time,
+		// but inline trees cannot be located without the symbol table.
+		precision = SymbolsNoInlined
+	}
+
 	if pclntab == nil {
 		// If we have build information, but not PCLN table, fall
 		// back to much higher granularity vulnerability checking.
-		return debugModulesToPackagesModules(bi.Deps), nil, bi, nil
+		return nil, SymbolsNone, nil
 	}
 	lineTab := gosym.NewLineTable(pclntab, textOffset)
 	if lineTab == nil {
-		return nil, nil, nil, errors.New("invalid line table")
+		return nil, "", errors.New("invalid line table")
 	}
 	tab, err := gosym.NewTable(nil, lineTab)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, "", err
 	}
 
-	pkgSyms := make(map[Symbol]bool)
-	for _, f := range tab.Funcs {
-		if f.Func == nil {
-			continue
-		}
-		pkgName, symName, err := parseName(f.Func.Sym)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		pkgSyms[Symbol{pkgName, symName}] = true
+	// Each worker collects the symbols of a share of the functions.
+	workers := len(tab.Funcs)/minFuncsPerWorker + 1
+	if n := runtime.GOMAXPROCS(0); workers > n {
+		workers = n
+	}
+	found := make([]map[Symbol]bool, workers)
+	var g errgroup.Group
+	for w := 0; w < workers; w++ {
+		pkgSyms := make(map[Symbol]bool)
+		found[w] = pkgSyms
+		funcs := tab.Funcs[w*len(tab.Funcs)/workers : (w+1)*len(tab.Funcs)/workers]
+		g.Go(func() error {
+			for i := range funcs {
+				f := &funcs[i]
+				if f.Func == nil {
+					continue
+				}
+				pkgName, symName, err := parseName(f.Func.Sym)
+				if err != nil {
+					return err
+				}
+				pkgSyms[Symbol{pkgName, symName}] = true
 
-		// Collect symbols that were inlined in f.
-		it, err := lineTab.InlineTree(&f, value, base, r)
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("InlineTree: %v", err)
-		}
-		for _, ic := range it {
-			pkgName, symName, err := parseName(&gosym.Sym{Name: ic.Name})
-			if err != nil {
-				return nil, nil, nil, err
+				if precision != SymbolsComplete {
+					continue
+				}
+				// Collect symbols that were inlined in f.
+				it, err := lineTab.InlineTree(f, value, base, r)
+				if err != nil {
+					return fmt.Errorf("InlineTree: %v", err)
+				}
+				for _, ic := range it {
+					pkgName, symName, err := parseName(&gosym.Sym{Name: ic.Name})
+					if err != nil {
+						return err
+					}
+					pkgSyms[Symbol{pkgName, symName}] = true
+				}
 			}
-			pkgSyms[Symbol{pkgName, symName}] = true
-		}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, "", err
 	}
 
+	pkgSyms := found[0]
+	for _, m := range found[1:] {
+		maps.Copy(pkgSyms, m)
+	}
 	var syms []Symbol
 	for ps := range pkgSyms {
 		syms = append(syms, ps)
 	}
+	return syms, precision, nil
+}
 
-	return debugModulesToPackagesModules(bi.Deps), syms, bi, nil
+// minFuncsPerWorker is the minimum number of functions whose symbols
+// are extracted by each worker, below which workers cost more than
+// they save.
+const minFuncsPerWorker = 1024
+
+func setSymbolPrecision(bi *debug.BuildInfo, precision string) {
+	bi.Settings = append(bi.Settings, debug.BuildSetting{Key: SymbolPrecisionSetting, Value: precision})
 }
 
 func parseName(s *gosym.Sym) (pkg, sym string, err error) {
-	symName := s.BaseName()
+	symName := stripTypeArgs(s.BaseName())
 	if r := s.ReceiverName(); r != "" {
 		if strings.HasPrefix(r, "(*") {
 			r = strings.Trim(r, "(*)")
 		}
-		symName = fmt.Sprintf("%s.%s", r, symName)
+		symName = fmt.Sprintf("%s.%s", stripTypeArgs(r), symName)
 	}
 
 	pkgName := s.PackageName()
@@ -157,3 +278,30 @@ func parseName(s *gosym.Sym) (pkg, sym string, err error) {
 	}
 	return pkgName, symName, nil
 }
+
+// stripTypeArgs removes the type arguments of generic functions
+// and types from name, such as "[go.shape.int]" or "[...]" in
+// Map[go.shape.int,go.shape.string] or List[...].Push. Array
+// types, as in the eq.[2]string equality functions, are kept.
+func stripTypeArgs(name string) string {
+	if !strings.Contains(name, "[") {
+		return name
+	}
+	var b strings.Builder
+	depth := 0
+	prev := '.'
+	for _, r := range name {
+		switch {
+		case r == '[' && (depth > 0 || prev == '_' || unicode.IsLetter(prev) || unicode.IsDigit(prev)):
+			depth++
+		case r == ']' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+		if depth == 0 {
+			prev = r
+		}
+	}
+	return b.String()
+}
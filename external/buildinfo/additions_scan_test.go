@@ -8,6 +8,7 @@ package buildinfo
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"sort"
@@ -52,7 +53,7 @@ func TestExtractPackagesAndSymbols(t *testing.T) {
 			binary, done := test.GoBuild(t, "testdata/src", "", false, "GOOS", goos, "GOARCH", goarch)
 			defer done()
 
-			_, syms, _, err := ExtractPackagesAndSymbols(binary)
+			_, syms, bi, err := ExtractPackagesAndSymbols(binary)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -67,7 +68,99 @@ func TestExtractPackagesAndSymbols(t *testing.T) {
 			if diff := cmp.Diff(want, got); diff != "" {
 				t.Errorf("(-want,+got):%s", diff)
 			}
+			if got := setting(bi, SymbolPrecisionSetting); got != SymbolsComplete {
+				t.Errorf("got symbol precision %q; want %q", got, SymbolsComplete)
+			}
+
+			gotOS, gotArch, err := ExtractPlatform(binary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotOS != goos || gotArch != goarch {
+				t.Errorf("ExtractPlatform() = %s/%s; want %s/%s", gotOS, gotArch, goos, goarch)
+			}
+
+			// The platform should also be derivable from the
+			// executable headers alone.
+			f, err := os.Open(binary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			x, err := openExe(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hOS, hArch := x.Platform(); hOS != goos || hArch != goarch {
+				t.Errorf("Platform() = %s/%s; want %s/%s", hOS, hArch, goos, goarch)
+			}
+		})
+}
+
+func TestExtractPackagesAndSymbolsWasm(t *testing.T) {
+	for _, goos := range []string{"js", "wasip1"} {
+		t.Run(goos, func(t *testing.T) {
+			binary, done := test.GoBuild(t, "testdata/src", "", false, "GOOS", goos, "GOARCH", "wasm")
+			defer done()
+
+			_, syms, bi, err := ExtractPackagesAndSymbols(binary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bi.GoVersion == "" || bi.Path == "" {
+				t.Errorf("got Go version %q and path %q; want both", bi.GoVersion, bi.Path)
+			}
+			// WebAssembly modules have no symbol table,
+			// so inlined functions are not recovered.
+			if !containsSymbol(syms, Symbol{"main", "main"}) {
+				t.Errorf("main.main not found in %v", sortedSymbols("main", syms))
+			}
+			if got := setting(bi, SymbolPrecisionSetting); got != SymbolsNoInlined {
+				t.Errorf("got symbol precision %q; want %q", got, SymbolsNoInlined)
+			}
+
+			f, err := os.Open(binary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			x, err := openExe(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hOS, hArch := x.Platform(); hOS != goos || hArch != "wasm" {
+				t.Errorf("Platform() = %s/%s; want %s/wasm", hOS, hArch, goos)
+			}
 		})
+	}
+}
+
+func TestExtractPackagesAndSymbolsFailure(t *testing.T) {
+	binary, done := test.GoBuild(t, "testdata/src", "", false)
+	defer done()
+
+	f, err := os.Open(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	bi, err := ReadBuildInfo(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Symbols cannot be read from binaries claiming
+	// to be built with an unsupported Go version.
+	bi.GoVersion = "go1.16"
+	_, syms, bi, err := extractPackagesAndSymbols(f, bi)
+	if err != nil {
+		t.Fatalf("want fallback to build info; got %v", err)
+	}
+	if len(syms) != 0 {
+		t.Errorf("got %d symbols; want none", len(syms))
+	}
+	if got := setting(bi, SymbolPrecisionSetting); got != SymbolsNone {
+		t.Errorf("got symbol precision %q; want %q", got, SymbolsNone)
+	}
 }
 
 func TestAncientGoBinaries(t *testing.T) {
@@ -78,6 +171,15 @@ func TestAncientGoBinaries(t *testing.T) {
 	if bi.GoVersion != "go1.6.4" {
 		t.Errorf("want go1.6.4 Go binary version; got %s", bi.GoVersion)
 	}
+	// Ancient binaries have no build settings, so the
+	// platform is inferred from the executable headers.
+	goos, goarch, err := ExtractPlatform("testdata/bin/hello-world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if goos == "" || goarch == "" {
+		t.Errorf("want inferred platform; got %q/%q", goos, goarch)
+	}
 }
 
 // sortedSymbols gets symbols for pkg and
@@ -93,6 +195,25 @@ func sortedSymbols(pkg string, syms []Symbol) []Symbol {
 	return s
 }
 
+func TestStripTypeArgs(t *testing.T) {
+	for _, tc := range []struct {
+		name, want string
+	}{
+		{"Vuln", "Vuln"},
+		{"Map[go.shape.string,go.shape.int]", "Map"},
+		{"List[go.shape.int]", "List"},
+		{"Apply[go.shape.[]int,go.shape.map[string]int]", "Apply"},
+		{"Map[...].func1", "Map.func1"},
+		{"eq.[2]string", "eq.[2]string"},
+		{"eq.[2][3]string", "eq.[2][3]string"},
+		{"eq.[2]List[go.shape.int]", "eq.[2]List"},
+	} {
+		if got := stripTypeArgs(tc.name); got != tc.want {
+			t.Errorf("stripTypeArgs(%q) = %q; want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
 // Test58509 is supposed to test issue #58509 where a whole
 // vulnerable function is deleted from the binary so we
 // cannot detect its presence.
@@ -12,20 +12,32 @@ import (
 	"github.com/StevenACoffman/invuln/external/test"
 )
 
-// TestStrippedBinary checks that there is no symbol table for
-// stripped binaries.
+// TestStrippedBinary checks that symbols of stripped binaries
+// are recovered from the PCLN table, without inlined symbols.
 func TestStrippedBinary(t *testing.T) {
 	testAll(t, []string{"linux", "windows", "freebsd", "darwin"}, []string{"amd64", "386", "arm", "arm64"},
 		func(t *testing.T, goos, goarch string) {
 			binary, done := test.GoBuild(t, "testdata/src", "", true, "GOOS", goos, "GOARCH", goarch)
 			defer done()
 
-			_, syms, _, err := ExtractPackagesAndSymbols(binary)
+			_, syms, bi, err := ExtractPackagesAndSymbols(binary)
 			if err != nil {
 				t.Fatal(err)
 			}
-			if len(syms) != 0 {
-				t.Errorf("want empty symbol table; got %v symbols", len(syms))
+			if !containsSymbol(syms, Symbol{"main", "main"}) {
+				t.Errorf("want main.main among %v recovered symbols", len(syms))
+			}
+			if got := setting(bi, SymbolPrecisionSetting); got != SymbolsNoInlined {
+				t.Errorf("got symbol precision %q; want %q", got, SymbolsNoInlined)
 			}
 		})
 }
+
+func containsSymbol(syms []Symbol, want Symbol) bool {
+	for _, s := range syms {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
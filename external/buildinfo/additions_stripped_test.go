@@ -13,9 +13,9 @@ import (
 	"github.com/google/go-cmp/cmp"
 )
 
-// TestStrippedBinary checks that there is no symbol table for
-// stripped binaries. This does not include darwin binaries.
-// For more info, see #61051.
+// TestStrippedBinary checks that symbols of stripped binaries are
+// recovered from the PCLN table, without inlined symbols. This does
+// not include darwin binaries. For more info, see #61051.
 func TestStrippedBinary(t *testing.T) {
 	// We exclude darwin as its stripped binaries seem to
 	// preserve the symbol table. See TestStrippedDarwin.
@@ -24,16 +24,28 @@ func TestStrippedBinary(t *testing.T) {
 			binary, done := test.GoBuild(t, "testdata/src", "", true, "GOOS", goos, "GOARCH", goarch)
 			defer done()
 
-			_, syms, _, err := ExtractPackagesAndSymbols(binary)
+			_, syms, bi, err := ExtractPackagesAndSymbols(binary)
 			if err != nil {
 				t.Fatal(err)
 			}
-			if syms != nil {
-				t.Errorf("want empty symbol table; got %v symbols", len(syms))
+			if !containsSymbol(syms, Symbol{"main", "main"}) {
+				t.Errorf("want main.main among %v recovered symbols", len(syms))
+			}
+			if got := setting(bi, SymbolPrecisionSetting); got != SymbolsNoInlined {
+				t.Errorf("got symbol precision %q; want %q", got, SymbolsNoInlined)
 			}
 		})
 }
 
+func containsSymbol(syms []Symbol, want Symbol) bool {
+	for _, s := range syms {
+		if s == want {
+			return true
+		}
+	}
+	return false
+}
+
 // TestStrippedDarwin checks that the symbol table exists and
 // is complete on darwin even in the presence of stripping.
 // For more info, see #61051.
@@ -0,0 +1,398 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+
+package buildinfo
+
+// This file adds to buildinfo support for WebAssembly modules built
+// with GOOS=js or GOOS=wasip1. The Go linker does not write a build
+// info header to them, so the build information is recovered from
+// the data segments and the custom sections of the module instead.
+
+import (
+	"bufio"
+	"bytes"
+	"debug/buildinfo"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime/debug"
+)
+
+const wasmMagic = "\x00asm\x01\x00\x00\x00"
+
+// maxWasmZeros bounds the zeros between the data segments of a
+// WebAssembly module, which the linker leaves out, so that crafted
+// segment addresses cannot make the module take more memory than that,
+// along with the size of its segments.
+const maxWasmZeros = 64 << 20
+
+// Sentinels that cmd/go writes around the module information
+// stored in runtime.modinfo.
+var (
+	modinfoStart = []byte("\x30\x77\xaf\x0c\x92\x74\x08\x02\x41\xe1\xc1\x07\xe6\xd6\x18\xe6")
+	modinfoEnd   = []byte("\xf9\x32\x43\x31\x86\x18\x20\x72\x00\x82\x42\x10\x41\x16\xd8\xf2")
+)
+
+// wasmExe is the WebAssembly implementation of the exe interface.
+type wasmExe struct {
+	mem       []byte // initial contents of linear memory from base on
+	base      uint64
+	imports   map[string]bool // modules of imported functions
+	goVersion string          // from the producers section
+}
+
+func isWasm(r io.ReaderAt) bool {
+	data := make([]byte, len(wasmMagic))
+	_, err := r.ReadAt(data, 0)
+	return err == nil && string(data) == wasmMagic
+}
+
+// ReadBuildInfo is like debug/buildinfo.Read, but also supports
+// WebAssembly modules, the static libraries built with
+// -buildmode=c-archive, and their Go object files.
+func ReadBuildInfo(r io.ReaderAt) (*debug.BuildInfo, error) {
+	bi, err := buildinfo.Read(r)
+	if err == nil {
+		return bi, nil
+	}
+	switch {
+	case isWasm(r):
+		x, err := newWasmExe(r)
+		if err != nil {
+			return nil, err
+		}
+		return x.buildInfo()
+	case isArArchive(r):
+		obj, err := goObject(r)
+		if err != nil {
+			return nil, err
+		}
+		return readObjectBuildInfo(obj)
+	}
+	if bi, oerr := readObjectBuildInfo(r); oerr == nil {
+		return bi, nil
+	}
+	return nil, err
+}
+
+// newWasmExe parses the sections of the WebAssembly module in r
+// that are needed for extracting build and symbol information.
+func newWasmExe(r io.ReaderAt) (*wasmExe, error) {
+	br := bufio.NewReader(io.NewSectionReader(r, 0, math.MaxInt64))
+	if _, err := br.Discard(len(wasmMagic)); err != nil {
+		return nil, err
+	}
+	x := &wasmExe{imports: make(map[string]bool)}
+	type segment struct {
+		addr uint64
+		data []byte
+	}
+	var segs []segment
+	for {
+		id, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		if size > math.MaxInt64 {
+			return nil, fmt.Errorf("malformed wasm section %d: size %d too large", id, size)
+		}
+		switch id {
+		case 0, 2, 11: // custom, import, and data sections
+			// The section is read as it arrives, rather than
+			// allocated at the size it claims, which the file
+			// may not have.
+			var sec bytes.Buffer
+			if _, err := io.CopyN(&sec, br, int64(size)); err != nil {
+				return nil, fmt.Errorf("malformed wasm section %d: %v", id, noEOF(err))
+			}
+			s := &wasmReader{b: sec.Bytes()}
+			switch id {
+			case 0:
+				x.readCustom(s)
+			case 2:
+				x.readImports(s)
+			case 11:
+				for n := s.uvarint(); n > 0 && s.err == nil; n-- {
+					addr, data, ok := s.segment()
+					if ok {
+						segs = append(segs, segment{addr, data})
+					}
+				}
+			}
+			if s.err != nil {
+				return nil, fmt.Errorf("malformed wasm section %d: %v", id, s.err)
+			}
+		default:
+			if _, err := io.CopyN(io.Discard, br, int64(size)); err != nil {
+				return nil, fmt.Errorf("malformed wasm section %d: %v", id, noEOF(err))
+			}
+		}
+	}
+
+	// The linker splits the data into many segments to skip over
+	// runs of zeros, so put them back together.
+	if len(segs) > 0 {
+		x.base = math.MaxUint64
+		var end, size uint64
+		for _, s := range segs {
+			if s.addr+uint64(len(s.data)) < s.addr {
+				return nil, fmt.Errorf("malformed wasm data segment at 0x%x", s.addr)
+			}
+			x.base = min(x.base, s.addr)
+			end = max(end, s.addr+uint64(len(s.data)))
+			size += uint64(len(s.data))
+		}
+		if end-x.base > size+maxWasmZeros {
+			return nil, fmt.Errorf("wasm data segments span %d bytes of memory, for %d bytes of data", end-x.base, size)
+		}
+		x.mem = make([]byte, end-x.base)
+		for _, s := range segs {
+			copy(x.mem[s.addr-x.base:], s.data)
+		}
+	}
+	return x, nil
+}
+
+// noEOF returns err, or io.ErrUnexpectedEOF if err is io.EOF,
+// for a file ending in the middle of a section.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// readCustom records the Go version from the producers section.
+func (x *wasmExe) readCustom(s *wasmReader) {
+	if s.name() != "producers" {
+		return
+	}
+	for n := s.uvarint(); n > 0 && s.err == nil; n-- {
+		field := s.name()
+		for m := s.uvarint(); m > 0 && s.err == nil; m-- {
+			name, version := s.name(), s.name()
+			if field == "language" && name == "Go" {
+				x.goVersion = version
+			}
+		}
+	}
+}
+
+// readImports records the modules of imported functions,
+// which identify the host environment the module targets.
+func (x *wasmExe) readImports(s *wasmReader) {
+	for n := s.uvarint(); n > 0 && s.err == nil; n-- {
+		module := s.name()
+		s.name()
+		kind := s.byte()
+		switch kind {
+		case 0: // func: type index
+			s.uvarint()
+		case 1: // table: element type and limits
+			s.byte()
+			s.limits()
+		case 2: // memory: limits
+			s.limits()
+		case 3: // global: value type and mutability
+			s.byte()
+			s.byte()
+		default:
+			s.err = fmt.Errorf("unknown import kind %d", kind)
+		}
+		if kind == 0 {
+			x.imports[module] = true
+		}
+	}
+}
+
+// buildInfo recovers the build information stored in runtime.modinfo.
+func (x *wasmExe) buildInfo() (*debug.BuildInfo, error) {
+	i := bytes.Index(x.mem, modinfoStart)
+	if i < 0 {
+		return nil, errors.New("not a Go executable")
+	}
+	info := x.mem[i+len(modinfoStart):]
+	j := bytes.Index(info, modinfoEnd)
+	if j < 0 {
+		return nil, errors.New("not a Go executable")
+	}
+	if x.goVersion == "" {
+		return nil, errors.New("wasm module does not record the Go version used to build it")
+	}
+	bi, err := debug.ParseBuildInfo(string(info[:j]))
+	if err != nil {
+		return nil, err
+	}
+	bi.GoVersion = x.goVersion
+	return bi, nil
+}
+
+func (x *wasmExe) ReadData(addr, size uint64) ([]byte, error) {
+	if addr < x.base || addr-x.base >= uint64(len(x.mem)) {
+		return nil, fmt.Errorf("address not mapped")
+	}
+	data := x.mem[addr-x.base:]
+	if uint64(len(data)) > size {
+		data = data[:size]
+	}
+	return data, nil
+}
+
+func (x *wasmExe) DataStart() uint64 {
+	return x.base
+}
+
+// PCLNTab looks for the PCLN table in the initial contents of linear
+// memory. Program counters of WebAssembly functions do not correspond
+// to file offsets, so there is no text offset.
+func (x *wasmExe) PCLNTab() ([]byte, uint64) {
+	return searchPCLNTab(x.mem), 0
+}
+
+// SymbolInfo always fails. The name section of a WebAssembly module
+// does not describe data, so inline trees cannot be located.
+func (x *wasmExe) SymbolInfo(name string) (uint64, uint64, io.ReaderAt, error) {
+	return 0, 0, nil, ErrNoSymbols
+}
+
+// Platform infers the host environment from the imported modules:
+// wasip1 programs import WASI, while js programs import the functions
+// provided by wasm_exec.js.
+func (x *wasmExe) Platform() (goos, goarch string) {
+	switch {
+	case x.imports["wasi_snapshot_preview1"]:
+		goos = "wasip1"
+	case x.imports["gojs"], x.imports["go"]:
+		goos = "js"
+	}
+	return goos, "wasm"
+}
+
+// ImportedLibraries returns nil as WebAssembly modules
+// built by Go cannot link C libraries.
+func (x *wasmExe) ImportedLibraries() []string {
+	return nil
+}
+
+// wasmReader decodes values from the contents of a section.
+// After the first error, all reads return zero values.
+type wasmReader struct {
+	b   []byte
+	err error
+}
+
+func (s *wasmReader) byte() byte {
+	if s.err != nil {
+		return 0
+	}
+	if len(s.b) == 0 {
+		s.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	c := s.b[0]
+	s.b = s.b[1:]
+	return c
+}
+
+func (s *wasmReader) uvarint() uint64 {
+	if s.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(s.b)
+	if n <= 0 {
+		s.err = errors.New("invalid LEB128 value")
+		return 0
+	}
+	s.b = s.b[n:]
+	return v
+}
+
+// svarint decodes a signed LEB128 value.
+func (s *wasmReader) svarint() int64 {
+	var v int64
+	var shift uint
+	for {
+		c := s.byte()
+		if s.err != nil {
+			return 0
+		}
+		v |= int64(c&0x7f) << shift
+		shift += 7
+		if c&0x80 == 0 {
+			if shift < 64 && c&0x40 != 0 {
+				v |= -1 << shift
+			}
+			return v
+		}
+		if shift >= 64 {
+			s.err = errors.New("invalid LEB128 value")
+			return 0
+		}
+	}
+}
+
+func (s *wasmReader) bytes(n uint64) []byte {
+	if s.err != nil {
+		return nil
+	}
+	if uint64(len(s.b)) < n {
+		s.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := s.b[:n]
+	s.b = s.b[n:]
+	return b
+}
+
+func (s *wasmReader) name() string {
+	return string(s.bytes(s.uvarint()))
+}
+
+func (s *wasmReader) limits() {
+	if s.byte()&1 != 0 {
+		s.uvarint()
+	}
+	s.uvarint()
+}
+
+// segment decodes a data segment. It reports false for
+// passive segments, which have no address.
+func (s *wasmReader) segment() (addr uint64, data []byte, ok bool) {
+	flags := s.uvarint()
+	switch flags {
+	case 0:
+	case 1:
+		s.bytes(s.uvarint())
+		return 0, nil, false
+	case 2:
+		s.uvarint() // memory index
+	default:
+		s.err = fmt.Errorf("unknown data segment kind %d", flags)
+		return 0, nil, false
+	}
+	switch op := s.byte(); op {
+	case 0x41: // i32.const
+		addr = uint64(uint32(s.svarint()))
+	case 0x42: // i64.const
+		addr = uint64(s.svarint())
+	default:
+		s.err = fmt.Errorf("unsupported data segment offset instruction 0x%x", op)
+	}
+	if s.byte() != 0x0b && s.err == nil {
+		s.err = errors.New("unsupported data segment offset expression")
+	}
+	data = s.bytes(s.uvarint())
+	return addr, data, s.err == nil
+}
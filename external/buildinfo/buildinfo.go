@@ -48,6 +48,18 @@ func openExe(r io.ReaderAt) (exe, error) {
 		}
 		return &machoExe{f: e}, nil
 	}
+	// Addition: support for c-archive static libraries.
+	if bytes.HasPrefix(data, []byte(arMagic)) {
+		obj, err := goObject(r)
+		if err != nil {
+			return nil, err
+		}
+		return openExe(obj)
+	}
+	// Addition: support for WebAssembly modules.
+	if bytes.HasPrefix(data, []byte(wasmMagic)) {
+		return newWasmExe(r)
+	}
 	return nil, fmt.Errorf("unrecognized executable format")
 }
 
@@ -63,15 +75,15 @@ type exe interface {
 	PCLNTab() ([]byte, uint64) // Addition: for constructing symbol table
 
 	SymbolInfo(name string) (uint64, uint64, io.ReaderAt, error) // Addition: for inlining purposes
+
+	Platform() (goos, goarch string) // Addition: for cross-platform scanning
+
+	ImportedLibraries() []string // Addition: for reporting linked C libraries
 }
 
 // elfExe is the ELF implementation of the exe interface.
 type elfExe struct {
 	f *elf.File
-
-	symbols     map[string]*elf.Symbol // Addition: symbols in the binary
-	symbolsOnce sync.Once              // Addition: for computing symbols
-	symbolsErr  error                  // Addition: error for computing symbols
 }
 
 func (x *elfExe) ReadData(addr, size uint64) ([]byte, error) {
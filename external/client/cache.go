@@ -0,0 +1,112 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// diskCacheSource caches the responses of another source in files
+// under dir, which are reused for ttl after they are written.
+// Once they expire, sources supporting it are only asked for
+// the endpoints that changed since. Failed requests are not cached.
+type diskCacheSource struct {
+	src source
+	dir string
+	ttl time.Duration
+}
+
+// validators identify the version of a response of an http(s)
+// source, which later requests can be conditioned on.
+type validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// errNotModified is returned for conditional requests
+// of endpoints that have not changed.
+var errNotModified = errors.New("not modified")
+
+// A conditionalSource is a source that can read
+// endpoints only if they changed since a response.
+type conditionalSource interface {
+	getIfModified(ctx context.Context, endpoint string, v validators) ([]byte, validators, error)
+}
+
+func newDiskCacheSource(src source, dir string, ttl time.Duration) *diskCacheSource {
+	return &diskCacheSource{src: src, dir: dir, ttl: ttl}
+}
+
+func (ds *diskCacheSource) get(ctx context.Context, endpoint string) ([]byte, error) {
+	return ds.cached(filepath.FromSlash(endpoint)+".json", func(v validators) ([]byte, validators, error) {
+		if cs, ok := ds.src.(conditionalSource); ok {
+			return cs.getIfModified(ctx, endpoint, v)
+		}
+		b, err := ds.src.get(ctx, endpoint)
+		return b, validators{}, err
+	})
+}
+
+func (ds *diskCacheSource) signature(ctx context.Context, endpoint string) ([]byte, error) {
+	src, ok := ds.src.(signedSource)
+	if !ok {
+		return nil, fmt.Errorf("signatures of %s cannot be read", endpoint)
+	}
+	return ds.cached(filepath.FromSlash(endpoint)+".json.minisig", func(validators) ([]byte, validators, error) {
+		b, err := src.signature(ctx, endpoint)
+		return b, validators{}, err
+	})
+}
+
+// cached returns the contents of the file name of the cache, fetching
+// them if they are missing or expired. Expired contents are fetched
+// given the validators of the response they were cached from, if any,
+// and reused for ttl again if fetch reports they have not changed.
+func (ds *diskCacheSource) cached(name string, fetch func(validators) ([]byte, validators, error)) ([]byte, error) {
+	file := filepath.Join(ds.dir, name)
+	meta := file + ".validators"
+	var v validators
+	if fi, err := os.Stat(file); err == nil {
+		if time.Since(fi.ModTime()) < ds.ttl {
+			if b, err := os.ReadFile(file); err == nil {
+				return b, nil
+			}
+		} else if b, err := os.ReadFile(meta); err == nil {
+			json.Unmarshal(b, &v)
+		}
+	}
+	b, nv, err := fetch(v)
+	if errors.Is(err, errNotModified) {
+		if b, err := os.ReadFile(file); err == nil {
+			now := time.Now()
+			os.Chtimes(file, now, now)
+			return b, nil
+		}
+		b, nv, err = fetch(validators{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The cache only saves requests, so failing to write it is not
+	// an error. It is only readable by the user, as the responses
+	// of private databases may be confidential.
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err == nil {
+		writeFileAtomic(file, b, 0o600)
+		if nv != (validators{}) {
+			if b, err := json.Marshal(nv); err == nil {
+				writeFileAtomic(meta, b, 0o600)
+			}
+		} else {
+			os.Remove(meta)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiskCacheSource(t *testing.T) {
+	ctx := context.Background()
+	cs := &countingSource{source: newLocalSource(testVulndb)}
+	dir := t.TempDir()
+	ds := newDiskCacheSource(cs, dir, time.Hour)
+
+	get := func(endpoint string, wantCalls int) {
+		t.Helper()
+		if _, err := ds.get(ctx, endpoint); err != nil {
+			t.Fatal(err)
+		}
+		if cs.calls != wantCalls {
+			t.Errorf("got %d calls to the underlying source, want %d", cs.calls, wantCalls)
+		}
+	}
+	get("index/db", 1)
+	get("index/db", 1)
+	get("ID/GO-2021-0068", 2)
+	// Failures are not cached.
+	for i := 0; i < 2; i++ {
+		if _, err := ds.get(ctx, "index/missing"); err == nil {
+			t.Fatal("want error for missing endpoint")
+		}
+	}
+	if cs.calls != 4 {
+		t.Errorf("got %d calls to the underlying source, want 4", cs.calls)
+	}
+	// Stale responses are requested again.
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "index", "db.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	get("index/db", 5)
+	get("index/db", 5)
+
+	// The responses are only readable by the user.
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(filepath.Join(dir, "index", "db.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0o600 {
+			t.Errorf("cached response has permissions %v; want -rw-------", perm)
+		}
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(testVulndb)
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+	c, err := NewClient(srv.URL, &Options{HTTPClient: srv.Client(), CacheDir: dir, CacheTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ByModules(ctx, []*ModuleRequest{{Path: "github.com/beego/beego"}}); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, url.PathEscape(srv.URL), "index", "modules.json")
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("modules index not cached: %v", err)
+	}
+}
+
+func TestConditionalCache(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu       sync.Mutex
+		etag     = `"v1"`
+		statuses []int
+	)
+	files := http.FileServer(http.Dir(testVulndb))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		rec := httptest.NewRecorder()
+		rec.Header().Set("ETag", etag)
+		files.ServeHTTP(rec, r)
+		statuses = append(statuses, rec.Code)
+		for k, vs := range rec.Header() {
+			w.Header()[k] = vs
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	t.Cleanup(srv.Close)
+	hs, err := newHTTPSource(srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	ds := newDiskCacheSource(hs, dir, time.Hour)
+	expire := func() {
+		t.Helper()
+		old := time.Now().Add(-2 * time.Hour)
+		if err := os.Chtimes(filepath.Join(dir, "index", "db.json"), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(want ...int) {
+		t.Helper()
+		mu.Lock()
+		statuses = nil
+		mu.Unlock()
+		if _, err := ds.get(ctx, "index/db"); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !slices.Equal(statuses, want) {
+			t.Errorf("got responses %v, want %v", statuses, want)
+		}
+	}
+	get(http.StatusOK)
+	get() // cached
+	expire()
+	get(http.StatusNotModified)
+	get() // cached again
+	expire()
+	mu.Lock()
+	etag = `"v2"`
+	mu.Unlock()
+	get(http.StatusOK)
+}
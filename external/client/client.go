@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/StevenACoffman/invuln/external/derrors"
+	"github.com/StevenACoffman/invuln/external/minisign"
 	"github.com/StevenACoffman/invuln/external/osv"
 	isem "github.com/StevenACoffman/invuln/external/semver"
 	"github.com/StevenACoffman/invuln/external/web"
@@ -32,57 +33,192 @@ import (
 // A Client for reading vulnerability databases.
 type Client struct {
 	source
+
+	// name is the URL of the database read by the client.
+	name string
+
+	// merged, if set, are the clients of the databases read by a
+	// client of NewMergedClient instead of source, by precedence.
+	merged []*Client
+
+	// osvAPI, if set, is the OSV API queried instead of source.
+	osvAPI *httpSource
+
+	// enriched, if set, is the client of Enrich whose responses
+	// are read instead of source, and added to by enrichers.
+	enriched  *Client
+	enrichers []Enricher
+
+	// concurrency, if set, is the number of entries read at once.
+	concurrency int
 }
 
+// Options configure the clients of NewClient.
+//
+// The headers, credentials, and client certificate of Options are not
+// used for the Go vulnerability database at https://vuln.go.dev, which
+// requires none, so as not to disclose them when reading it along with
+// private databases.
 type Options struct {
+	// HTTPClient, if set, makes the requests to http(s) databases.
 	HTTPClient *http.Client
+
+	// Transport, if set and HTTPClient is not, makes the requests to
+	// http(s) databases, for instance to route them through a proxy
+	// requiring authentication, to trace them, or to trust custom
+	// TLS roots.
+	Transport http.RoundTripper
+
+	// Header is added to the requests made to http(s) databases,
+	// for instance for proxies expecting particular headers.
+	Header http.Header
+
+	// BearerToken, if set, authenticates the requests made to
+	// http(s) databases with an "Authorization: Bearer" header.
+	BearerToken string
+
+	// Username and Password, if Username is set, authenticate the
+	// requests made to http(s) databases with basic authentication.
+	Username string
+	Password string
+
+	// CertFile and KeyFile, if set, are the PEM encoded certificate
+	// and key presented to http(s) databases requiring client
+	// certificates, as with mutual TLS.
+	CertFile string
+	KeyFile  string
+
+	// CacheDir, if set, is the directory under which the responses
+	// of http(s) databases are stored, in a directory for each
+	// database, and reused for CacheTTL after they are received.
+	// Expired responses are requested again conditionally, with the
+	// ETag and Last-Modified headers they had, so that they are only
+	// downloaded again if they changed.
+	CacheDir string
+	CacheTTL time.Duration
+
+	// Retries is the number of times a request to an http(s) database
+	// failing with an error that might be transient, such as a network
+	// error, a timeout, or a server error, is retried. Backoff is the
+	// delay before the first retry, doubled before each next one, and
+	// one second if not set. Timeout, if set, limits each attempt.
+	// Requests that still fail return an *UnreachableError.
+	Retries int
+	Backoff time.Duration
+	Timeout time.Duration
+
+	// PublicKeys, if set, are minisign public keys, one of which must
+	// have signed each endpoint read from the database, as verified
+	// before its contents are trusted. The detached signature of the
+	// uncompressed JSON of an endpoint, such as index/modules.json, is
+	// read next to it, from index/modules.json.minisig. Endpoints
+	// without a valid signature fail with a *VerificationError. It
+	// is not supported for OSV APIs.
+	PublicKeys []*minisign.PublicKey
+
+	// Concurrency is the number of requests made at once to http(s)
+	// databases and OSV APIs, and of entries read at once from other
+	// databases, 10 if not set. RateLimit, if set, is the most
+	// requests made per second to http(s) databases and OSV APIs.
+	Concurrency int
+	RateLimit   float64
+}
+
+// httpClient returns the client making the requests of opts.
+func (opts *Options) httpClient() *http.Client {
+	switch {
+	case opts == nil:
+		return http.DefaultClient
+	case opts.HTTPClient != nil:
+		return opts.HTTPClient
+	case opts.Transport != nil:
+		return &http.Client{Transport: opts.Transport}
+	}
+	return http.DefaultClient
 }
 
 // NewClient returns a client that reads the vulnerability database
-// in source (an "http" or "file" prefixed URL).
+// in source (an "http" or "file" prefixed URL). A "file" URL is to a
+// directory, or to a zip archive of the database as written by WriteZip.
+// An "osv+http" or "osv+https" URL, such as osv+https://api.osv.dev, is
+// to an OSV API, which is queried for the entries affecting the module
+// versions of requests instead.
 //
 // It supports databases following the API described
 // in https://go.dev/security/vuln/database#api.
-func NewClient(source string, opts *Options) (_ *Client, err error) {
+func NewClient(source string, opts *Options) (*Client, error) {
+	return NewClientContext(context.Background(), source, opts)
+}
+
+// NewClientContext is like NewClient, but the requests made to
+// find the API of the database, if any, are made with ctx.
+func NewClientContext(ctx context.Context, source string, opts *Options) (_ *Client, err error) {
 	source = strings.TrimRight(source, "/")
 	uri, err := url.Parse(source)
 	if err != nil {
 		return nil, err
 	}
+	var c *Client
 	switch uri.Scheme {
 	case "http", "https":
-		return newHTTPClient(uri, opts)
+		c, err = newHTTPClient(ctx, uri, opts)
 	case "file":
-		return newLocalClient(uri)
+		c, err = newLocalClient(uri)
+	case "osv+http", "osv+https":
+		c, err = newOSVClient(uri, opts)
 	default:
 		return nil, fmt.Errorf("source %q has unsupported scheme", uri)
 	}
+	if err != nil {
+		return nil, err
+	}
+	c.name = source
+	if opts == nil {
+		return c, nil
+	}
+	if len(opts.PublicKeys) > 0 {
+		if c, err = verify(c, opts.PublicKeys); err != nil {
+			return nil, err
+		}
+	}
+	c.concurrency = opts.Concurrency
+	return c, nil
 }
 
+// publicDB is the URL of the Go vulnerability database.
+const publicDB = "https://vuln.go.dev"
+
 var errUnknownSchema = errors.New("unrecognized vulndb format; see https://go.dev/security/vuln/database#api for accepted schema")
 
-func newHTTPClient(uri *url.URL, opts *Options) (*Client, error) {
+func newHTTPClient(ctx context.Context, uri *url.URL, opts *Options) (*Client, error) {
 	source := uri.String()
-
-	// v1 returns true if the source likely follows the V1 schema.
-	v1 := func() bool {
-		return source == "https://vuln.go.dev" ||
-			endpointExistsHTTP(source, "index/modules.json.gz")
+	hs, err := newHTTPSource(source, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	if v1() {
-		return &Client{source: newHTTPSource(uri.String(), opts)}, nil
+	// v1 is true if the source likely follows the V1 schema.
+	v1 := source == publicDB
+	if !v1 {
+		if v1, err = hs.exists(ctx, "index/modules.json.gz"); err != nil {
+			return nil, err
+		}
+	}
+	if v1 {
+		if opts != nil && opts.CacheDir != "" && opts.CacheTTL > 0 {
+			dir := filepath.Join(opts.CacheDir, url.PathEscape(source))
+			return &Client{source: newDiskCacheSource(hs, dir, opts.CacheTTL)}, nil
+		}
+		return &Client{source: hs}, nil
 	}
 
 	return nil, errUnknownSchema
 }
 
-func endpointExistsHTTP(source, endpoint string) bool {
-	r, err := http.Head(source + "/" + endpoint)
-	return err == nil && r.StatusCode == http.StatusOK
-}
-
 func newLocalClient(uri *url.URL) (*Client, error) {
+	if file, err := web.URLToFilePath(uri); err == nil && filepath.Ext(file) == ".zip" {
+		return newZipClient(file)
+	}
 	dir, err := toDir(uri)
 	if err != nil {
 		return nil, err
@@ -133,9 +269,38 @@ func NewInMemoryClient(entries []*osv.Entry) (*Client, error) {
 	return &Client{source: s}, nil
 }
 
+// Memoize returns a client that reads the database through c and
+// keeps every response in memory. It is intended for long running
+// processes, such as watch mode, that query the same data repeatedly
+// and do not need to observe database updates.
+func Memoize(c *Client) *Client {
+	if c.enriched != nil {
+		return Enrich(Memoize(c.enriched), c.enrichers...)
+	}
+	if len(c.merged) > 0 {
+		return memoizeMerged(c)
+	}
+	if c.osvAPI != nil {
+		// The responses of the OSV API depend on the
+		// versions of the requests, so they are not kept.
+		return c
+	}
+	return &Client{source: newMemoSource(c.source), name: c.name, concurrency: c.concurrency}
+}
+
 func (c *Client) LastModifiedTime(ctx context.Context) (_ time.Time, err error) {
 	derrors.Wrap(&err, "LastModifiedTime()")
 
+	if c.enriched != nil {
+		return c.enriched.LastModifiedTime(ctx)
+	}
+	if len(c.merged) > 0 {
+		return c.mergedLastModifiedTime(ctx)
+	}
+	if c.osvAPI != nil {
+		return time.Time{}, errOSVLastModified
+	}
+
 	b, err := c.source.get(ctx, dbEndpoint)
 	if err != nil {
 		return time.Time{}, err
@@ -173,6 +338,16 @@ type ModuleResponse struct {
 func (c *Client) ByModules(ctx context.Context, reqs []*ModuleRequest) (_ []*ModuleResponse, err error) {
 	derrors.Wrap(&err, "ByModules(%v)", reqs)
 
+	if c.enriched != nil {
+		return c.enrichedByModules(ctx, reqs)
+	}
+	if len(c.merged) > 0 {
+		return c.mergedByModules(ctx, reqs)
+	}
+	if c.osvAPI != nil {
+		return c.osvByModules(ctx, reqs)
+	}
+
 	metas, err := c.moduleMetas(ctx, reqs)
 	if err != nil {
 		return nil, err
@@ -180,7 +355,7 @@ func (c *Client) ByModules(ctx context.Context, reqs []*ModuleRequest) (_ []*Mod
 
 	resps := make([]*ModuleResponse, len(reqs))
 	g, gctx := errgroup.WithContext(ctx)
-	g.SetLimit(10)
+	g.SetLimit(c.limit())
 	for i, req := range reqs {
 		i, req := i, req
 		g.Go(func() error {
@@ -295,7 +470,7 @@ func (c *Client) byModule(ctx context.Context, req *ModuleRequest, m *moduleMeta
 func (c *Client) byIDs(ctx context.Context, ids []string) (_ []*osv.Entry, err error) {
 	entries := make([]*osv.Entry, len(ids))
 	g, gctx := errgroup.WithContext(ctx)
-	g.SetLimit(10)
+	g.SetLimit(c.limit())
 	for i, id := range ids {
 		i, id := i, id
 		g.Go(func() error {
@@ -319,17 +494,16 @@ func (c *Client) byIDs(ctx context.Context, ids []string) (_ []*osv.Entry, err e
 func (c *Client) byID(ctx context.Context, id string) (_ *osv.Entry, err error) {
 	derrors.Wrap(&err, "byID(%s)", id)
 
+	// The entries of local databases are not read outside of them.
+	if !filepath.IsLocal(id) || filepath.Base(id) != id {
+		return nil, fmt.Errorf("invalid entry ID %q", id)
+	}
 	b, err := c.source.get(ctx, entryEndpoint(id))
 	if err != nil {
 		return nil, err
 	}
 
-	var entry osv.Entry
-	if err := json.Unmarshal(b, &entry); err != nil {
-		return nil, err
-	}
-
-	return &entry, nil
+	return osv.DecodeEntry(b)
 }
 
 // newStreamDecoder returns a decoder that can be used
@@ -6,9 +6,19 @@ package client
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -115,6 +125,18 @@ func TestNewClient(t *testing.T) {
 		}
 	})
 
+	t.Run("http/canceled", func(t *testing.T) {
+		srv := newTestServer(testVulndb)
+		t.Cleanup(srv.Close)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := NewClientContext(ctx, srv.URL, &Options{HTTPClient: srv.Client(), Retries: 3})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("NewClientContext() = %v, want error %v", err, context.Canceled)
+		}
+	})
+
 	t.Run("local/v1", func(t *testing.T) {
 		src := testVulndbFileURL
 		c, err := NewClient(src, nil)
@@ -339,3 +361,130 @@ func testAllClientTypes(t *testing.T, test func(t *testing.T, c *Client)) {
 		test(t, mc)
 	})
 }
+
+func TestAuthenticatedClient(t *testing.T) {
+	// newAuthServer returns a server of the test database
+	// only serving the requests with the header key set to value.
+	newAuthServer := func(key, value string) *httptest.Server {
+		files := http.FileServer(http.Dir(testVulndb))
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(key) != value {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			files.ServeHTTP(w, r)
+		}))
+	}
+	for _, test := range []struct {
+		name       string
+		key, value string
+		opts       Options
+	}{
+		{"bearer", "Authorization", "Bearer secret", Options{BearerToken: "secret"}},
+		{"basic", "Authorization", "Basic dXNlcjpwYXNz", Options{Username: "user", Password: "pass"}},
+		{"header", "X-Proxy-Token", "secret", Options{Header: http.Header{"X-Proxy-Token": {"secret"}}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			srv := newAuthServer(test.key, test.value)
+			t.Cleanup(srv.Close)
+
+			if _, err := NewClient(srv.URL, nil); !errors.Is(err, errUnknownSchema) {
+				t.Fatalf("NewClient without credentials = %v; want error %s", err, errUnknownSchema)
+			}
+			c, err := NewClient(srv.URL, &test.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.LastModifiedTime(context.Background()); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	t.Run("mtls", func(t *testing.T) {
+		srv := httptest.NewUnstartedServer(http.FileServer(http.Dir(testVulndb)))
+		srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+		srv.Config.ErrorLog = log.New(io.Discard, "", 0) // for the handshakes without certificate
+		srv.StartTLS()
+		t.Cleanup(srv.Close)
+
+		if _, err := NewClient(srv.URL, &Options{HTTPClient: srv.Client()}); !errors.Is(err, errUnknownSchema) {
+			t.Fatalf("NewClient without certificate = %v; want error %s", err, errUnknownSchema)
+		}
+		certFile, keyFile := writeTestCert(t)
+		c, err := NewClient(srv.URL, &Options{HTTPClient: srv.Client(), CertFile: certFile, KeyFile: keyFile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.LastModifiedTime(context.Background()); err != nil {
+			t.Error(err)
+		}
+
+		if _, err := NewClient(srv.URL, &Options{CertFile: certFile, KeyFile: certFile}); err == nil {
+			t.Error("NewClient with an invalid key: want error")
+		}
+	})
+}
+
+// writeTestCert writes a self-signed certificate and
+// its key to PEM files, and returns their names.
+func writeTestCert(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "govulncheck test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// recordingTransport records the URLs of the requests it makes.
+type recordingTransport struct {
+	http.RoundTripper
+	urls []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.urls = append(rt.urls, req.URL.Path)
+	return rt.RoundTripper.RoundTrip(req)
+}
+
+func TestTransport(t *testing.T) {
+	srv := newTestServer(testVulndb)
+	t.Cleanup(srv.Close)
+
+	rt := &recordingTransport{RoundTripper: srv.Client().Transport}
+	c, err := NewClient(srv.URL, &Options{Transport: rt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.LastModifiedTime(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/index/modules.json.gz", "/index/db.json.gz"}
+	if diff := cmp.Diff(want, rt.urls); diff != "" {
+		t.Errorf("requests mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/derrors"
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// ModifiedSince returns the entries of the database of c modified
+// after t, ordered by modification time and then ID. If modules is
+// not nil, only the entries affecting one of the modules, given by
+// path, are returned. Only the entries returned are downloaded, as
+// the modules index of the database lists the modification times of
+// the entries.
+//
+// It is not supported for clients reading several databases.
+func (c *Client) ModifiedSince(ctx context.Context, t time.Time, modules []string) (_ []*osv.Entry, err error) {
+	derrors.Wrap(&err, "ModifiedSince(%s)", t.Format(time.RFC3339))
+
+	if len(c.merged) > 0 {
+		return nil, errors.New("cannot diff several databases")
+	}
+	if c.osvAPI != nil {
+		return nil, errors.New("cannot diff the OSV API")
+	}
+	if c.enriched != nil {
+		return c.enriched.ModifiedSince(ctx, t, modules)
+	}
+	b, err := c.source.get(ctx, modulesEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	var metas []*moduleMeta
+	if err := json.Unmarshal(b, &metas); err != nil {
+		return nil, err
+	}
+	scope := make(map[string]bool)
+	for _, m := range modules {
+		scope[m] = true
+	}
+	seen := make(map[string]bool)
+	var ids []string
+	for _, m := range metas {
+		if modules != nil && !scope[m.Path] {
+			continue
+		}
+		for _, v := range m.Vulns {
+			if v.Modified.After(t) && !seen[v.ID] {
+				seen[v.ID] = true
+				ids = append(ids, v.ID)
+			}
+		}
+	}
+	entries, err := c.byIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Modified.Equal(entries[j].Modified) {
+			return entries[i].Modified.Before(entries[j].Modified)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestModifiedSince(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 5, d, 0, 0, 0, 0, time.UTC) }
+	entry := func(id, module string, modified time.Time) *osv.Entry {
+		return &osv.Entry{ID: id, Modified: modified, Affected: []osv.Affected{{Module: osv.Module{Path: module}}}}
+	}
+	c, err := NewInMemoryClient([]*osv.Entry{
+		entry("GO-2024-0003", "example.com/a", day(3)),
+		entry("GO-2024-0001", "example.com/a", day(1)),
+		entry("GO-2024-0002", "example.com/b", day(2)),
+		entry("GO-2024-0004", "example.com/b", day(2)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := func(modules []string) []string {
+		entries, err := c.ModifiedSince(context.Background(), day(1), modules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+	for _, tc := range []struct {
+		modules []string
+		want    []string
+	}{
+		{nil, []string{"GO-2024-0002", "GO-2024-0004", "GO-2024-0003"}},
+		{[]string{"example.com/a"}, []string{"GO-2024-0003"}},
+		{[]string{}, nil},
+	} {
+		if diff := cmp.Diff(tc.want, ids(tc.modules)); diff != "" {
+			t.Errorf("ModifiedSince(%q) mismatch (-want, +got):\n%s", tc.modules, diff)
+		}
+	}
+}
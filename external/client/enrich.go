@@ -0,0 +1,63 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// An Enricher adds information about vulnerabilities from
+// sources other than vulnerability databases to OSV entries.
+type Enricher interface {
+	// Enrich adds information to entries in place, recording its
+	// sources in their DatabaseSpecific.Enrichment fields.
+	// Entries it has no information about are left as is.
+	Enrich(ctx context.Context, entries []*osv.Entry) error
+}
+
+// Enrich returns a client that reads the database of c and adds
+// information to the entries of its responses with enrichers,
+// in order.
+func Enrich(c *Client, enrichers ...Enricher) *Client {
+	if len(enrichers) == 0 {
+		return c
+	}
+	return &Client{enriched: c, enrichers: enrichers}
+}
+
+// enrichedByModules is ByModules for a client of Enrich.
+func (c *Client) enrichedByModules(ctx context.Context, reqs []*ModuleRequest) ([]*ModuleResponse, error) {
+	resps, err := c.enriched.ByModules(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*osv.Entry
+	seen := make(map[*osv.Entry]bool)
+	for _, r := range resps {
+		for _, e := range r.Entries {
+			if !seen[e] {
+				seen[e] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	for _, en := range c.enrichers {
+		if err := en.Enrich(ctx, entries); err != nil {
+			return nil, err
+		}
+	}
+	return resps, nil
+}
+
+// enrichment returns the database specific information of e,
+// which is added if missing, for recording an enrichment.
+func enrichment(e *osv.Entry) *osv.DatabaseSpecific {
+	if e.DatabaseSpecific == nil {
+		e.DatabaseSpecific = &osv.DatabaseSpecific{}
+	}
+	return e.DatabaseSpecific
+}
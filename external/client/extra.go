@@ -0,0 +1,115 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	isem "github.com/StevenACoffman/invuln/external/semver"
+)
+
+// ReadEntries reads the OSV entries in files, each holding a single
+// entry in JSON. Each entry records the "file" URL of its file in its
+// DatabaseSpecific.Source field. Entries that could not be matched
+// against modules, such as those without affected versions, and
+// entries with the same ID are reported as errors.
+func ReadEntries(files []string) ([]*osv.Entry, error) {
+	var entries []*osv.Entry
+	seen := make(map[string]string) // file by entry ID
+	for _, file := range files {
+		e, err := readEntry(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if other, ok := seen[e.ID]; ok {
+			return nil, fmt.Errorf("%s: entry %s is also in %s", file, e.ID, other)
+		}
+		seen[e.ID] = file
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func readEntry(file string) (*osv.Entry, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var e osv.Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	if err := checkEntry(&e); err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	return withSource(&e, (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()), nil
+}
+
+// checkEntry checks that e has the fields needed to match it
+// against modules, and that its versions are valid.
+func checkEntry(e *osv.Entry) error {
+	if e.ID == "" {
+		return errors.New("missing id")
+	}
+	if e.Modified.IsZero() {
+		return fmt.Errorf("%s: missing modified time", e.ID)
+	}
+	if len(e.Affected) == 0 {
+		return fmt.Errorf("%s: no affected modules", e.ID)
+	}
+	for _, a := range e.Affected {
+		if a.Module.Path == "" {
+			return fmt.Errorf("%s: affected module without path", e.ID)
+		}
+		for _, r := range a.Ranges {
+			if r.Type != osv.RangeTypeSemver {
+				return fmt.Errorf("%s: unsupported range type %q of %s", e.ID, r.Type, a.Module.Path)
+			}
+			for _, ev := range r.Events {
+				if (ev.Introduced == "") == (ev.Fixed == "") {
+					return fmt.Errorf("%s: range event of %s must have exactly one of introduced and fixed", e.ID, a.Module.Path)
+				}
+				if v := ev.Introduced; v != "" && v != "0" && !isem.Valid(v) {
+					return fmt.Errorf("%s: invalid introduced version %q of %s", e.ID, v, a.Module.Path)
+				}
+				if v := ev.Fixed; v != "" && !isem.Valid(v) {
+					return fmt.Errorf("%s: invalid fixed version %q of %s", e.ID, v, a.Module.Path)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// WithEntries returns a client reading entries along with the database
+// of c, which are merged as by NewMergedClient, entries taking
+// precedence. The entries keep the source they record, if any.
+func WithEntries(c *Client, entries []*osv.Entry) (*Client, error) {
+	extra, err := NewInMemoryClient(entries)
+	if err != nil {
+		return nil, err
+	}
+	if c.enriched != nil {
+		merged, err := WithEntries(c.enriched, entries)
+		if err != nil {
+			return nil, err
+		}
+		return Enrich(merged, c.enrichers...), nil
+	}
+	if len(c.merged) > 0 {
+		return &Client{merged: append([]*Client{extra}, c.merged...)}, nil
+	}
+	return &Client{merged: []*Client{extra, c}}, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithEntries(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	fork := write("fork.json", `{"id":"INTERNAL-1","modified":"2024-01-01T00:00:00Z",
+		"affected":[{"package":{"name":"example.com/fork","ecosystem":"Go"},
+		"ranges":[{"type":"SEMVER","events":[{"introduced":"0"},{"fixed":"1.2.0"}]}]}]}`)
+	// An unpublished advisory of the CVE of GO-2021-0068,
+	// which takes precedence over it.
+	toolchain := write("toolchain.json", `{"id":"INTERNAL-2","modified":"2024-01-01T00:00:00Z","aliases":["CVE-2021-3115"],
+		"affected":[{"package":{"name":"toolchain","ecosystem":"Go"},
+		"ranges":[{"type":"SEMVER","events":[{"introduced":"0"}]}]}]}`)
+	entries, err := ReadEntries([]string{fork, toolchain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := NewClient(localURL(testVulndb), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := WithEntries(db, entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resps, err := c.ByModules(context.Background(), []*ModuleRequest{{Path: "example.com/fork"}, {Path: "toolchain"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string) // source by ID
+	for _, r := range resps {
+		for _, e := range r.Entries {
+			got[e.ID] = e.DatabaseSpecific.Source
+		}
+	}
+	if _, ok := got["GO-2021-0068"]; ok {
+		t.Error("GO-2021-0068 reported along with INTERNAL-2, which has the same alias")
+	}
+	for id, file := range map[string]string{"INTERNAL-1": fork, "INTERNAL-2": toolchain} {
+		if src := got[id]; !strings.HasPrefix(src, "file://") || !strings.HasSuffix(src, filepath.Base(file)) {
+			t.Errorf("%s: source %q, want the URL of %s", id, src, file)
+		}
+	}
+	if len(got) < 3 {
+		t.Errorf("got entries %v, want those of the database too", got)
+	}
+
+	for _, tc := range []struct {
+		content, want string
+	}{
+		{`{"modified":"2024-01-01T00:00:00Z"}`, "missing id"},
+		{`{"id":"X","modified":"2024-01-01T00:00:00Z"}`, "X: no affected modules"},
+		{`{"id":"X","modified":"2024-01-01T00:00:00Z","affected":[{"package":{"name":"m"},"ranges":[{"type":"SEMVER","events":[{"fixed":"one"}]}]}]}`, `invalid fixed version "one" of m`},
+		{`{"id":"X","modified":"2024-01-01T00:00:00Z","affected":[{"package":{"name":"m"},"ranges":[{"type":"GIT","events":[{"introduced":"0"}]}]}]}`, `unsupported range type "GIT"`},
+		{`{"id":"INTERNAL-1","modified":"2024-01-01T00:00:00Z","affected":[{"package":{"name":"m"}}]}`, "entry INTERNAL-1 is also in"},
+	} {
+		_, err := ReadEntries([]string{fork, write("bad.json", tc.content)})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("ReadEntries(%s) = %v, want error containing %q", tc.content, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// githubGraphQL is the URL of the GraphQL API of GitHub.
+const githubGraphQL = "https://api.github.com/graphql"
+
+// ghsaBatchSize is the largest number of advisories
+// requested at once from the GraphQL API.
+const ghsaBatchSize = 50
+
+// ghsaAdvisory is the information of a GitHub advisory
+// that is added to the Go advisories it aliases.
+type ghsaAdvisory struct {
+	ID         string
+	Severity   string // LOW, MODERATE, HIGH, or CRITICAL
+	CVSS       []osv.Severity
+	References []string
+	Fixed      map[string]string // first patched version by module path
+}
+
+// GHSAEnricher adds the severity, references, and fixed versions of
+// the GitHub advisories that OSV entries alias to those entries.
+//
+// The severity and fixed versions are only added to entries that have
+// none, and the references to those that do not already have them.
+type GHSAEnricher struct {
+	// dir, if set, is a clone of github/advisory-database
+	// read instead of the GraphQL API.
+	dir string
+	// token authenticates the requests to the GraphQL API at url.
+	token string
+	url   string
+	c     *http.Client
+
+	mu    sync.Mutex
+	cache map[string]*ghsaAdvisory // nil for advisories not found
+	files map[string]string        // paths of the advisories of dir by ID
+}
+
+// NewGHSAEnricher returns an enricher reading GitHub advisories from the
+// GraphQL API of GitHub, authenticated with token, if source is "api",
+// or else from the clone of github/advisory-database in the directory
+// source. A nil client means http.DefaultClient.
+func NewGHSAEnricher(source, token string, c *http.Client) (*GHSAEnricher, error) {
+	if c == nil {
+		c = http.DefaultClient
+	}
+	g := &GHSAEnricher{url: githubGraphQL, token: token, c: c, cache: make(map[string]*ghsaAdvisory)}
+	if source == "api" {
+		if token == "" {
+			return nil, fmt.Errorf("reading GitHub advisories from the GraphQL API requires a token")
+		}
+		return g, nil
+	}
+	fi, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", source)
+	}
+	g.dir = source
+	return g, nil
+}
+
+// Enrich implements Enricher.
+func (g *GHSAEnricher) Enrich(ctx context.Context, entries []*osv.Entry) error {
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, ghsaAliases(e)...)
+	}
+	advs, err := g.advisories(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		for _, id := range ghsaAliases(e) {
+			adv := advs[id]
+			if adv == nil {
+				continue
+			}
+			// The references of adv are added to those of e.
+			if err := e.Load(); err != nil {
+				return err
+			}
+			if addGHSA(e, adv) {
+				ds := enrichment(e)
+				ds.Enrichment = append(ds.Enrichment, "github:"+id)
+			}
+		}
+	}
+	return nil
+}
+
+func ghsaAliases(e *osv.Entry) []string {
+	var ids []string
+	for _, a := range e.Aliases {
+		if strings.HasPrefix(a, "GHSA-") {
+			ids = append(ids, a)
+		}
+	}
+	return ids
+}
+
+// addGHSA adds the information of adv missing from e,
+// and reports whether there was any.
+func addGHSA(e *osv.Entry, adv *ghsaAdvisory) bool {
+	added := false
+	if len(e.Severity) == 0 && len(adv.CVSS) > 0 {
+		e.Severity = adv.CVSS
+		added = true
+	}
+	if adv.Severity != "" && (e.DatabaseSpecific == nil || e.DatabaseSpecific.Severity == "") {
+		enrichment(e).Severity = adv.Severity
+		added = true
+	}
+	for _, u := range adv.References {
+		if !hasReference(e, u) {
+			e.References = append(e.References, osv.Reference{Type: osv.ReferenceTypeWeb, URL: u})
+			added = true
+		}
+	}
+	for i := range e.Affected {
+		a := &e.Affected[i]
+		fixed, ok := adv.Fixed[a.Module.Path]
+		// Only a single range without a fix
+		// is known to be the one fixed.
+		if !ok || len(a.Ranges) != 1 || hasFix(a.Ranges[0]) {
+			continue
+		}
+		a.Ranges[0].Events = append(a.Ranges[0].Events, osv.RangeEvent{Fixed: strings.TrimPrefix(fixed, "v")})
+		added = true
+	}
+	return added
+}
+
+func hasReference(e *osv.Entry, url string) bool {
+	for _, r := range e.References {
+		if r.URL == url {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFix(r osv.Range) bool {
+	for _, ev := range r.Events {
+		if ev.Fixed != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// advisories returns the advisories with the IDs in ids by ID,
+// reading those not read before.
+func (g *GHSAEnricher) advisories(ctx context.Context, ids []string) (map[string]*ghsaAdvisory, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var missing []string
+	for _, id := range ids {
+		if _, ok := g.cache[id]; !ok {
+			g.cache[id] = nil
+			missing = append(missing, id)
+		}
+	}
+	for len(missing) > 0 {
+		batch := missing[:min(len(missing), ghsaBatchSize)]
+		missing = missing[len(batch):]
+		var advs []*ghsaAdvisory
+		var err error
+		if g.dir != "" {
+			advs, err = g.readDir(batch)
+		} else {
+			advs, err = g.query(ctx, batch)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, adv := range advs {
+			g.cache[adv.ID] = adv
+		}
+	}
+	advs := make(map[string]*ghsaAdvisory)
+	for _, id := range ids {
+		advs[id] = g.cache[id]
+	}
+	return advs, nil
+}
+
+// readDir reads the advisories with the IDs in ids from
+// the OSV files of the advisory database clone of g.
+func (g *GHSAEnricher) readDir(ids []string) ([]*ghsaAdvisory, error) {
+	if g.files == nil {
+		g.files = make(map[string]string)
+		err := filepath.WalkDir(g.dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if id, ok := strings.CutSuffix(d.Name(), ".json"); ok && strings.HasPrefix(id, "GHSA-") {
+				g.files[id] = path
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var advs []*ghsaAdvisory
+	for _, id := range ids {
+		file, ok := g.files[id]
+		if !ok {
+			continue
+		}
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var e struct {
+			ID               string          `json:"id"`
+			Severity         []osv.Severity  `json:"severity"`
+			Affected         []osv.Affected  `json:"affected"`
+			References       []osv.Reference `json:"references"`
+			DatabaseSpecific struct {
+				Severity string `json:"severity"`
+			} `json:"database_specific"`
+		}
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		adv := &ghsaAdvisory{ID: e.ID, Severity: e.DatabaseSpecific.Severity, CVSS: e.Severity, Fixed: make(map[string]string)}
+		for _, r := range e.References {
+			adv.References = append(adv.References, r.URL)
+		}
+		for _, a := range e.Affected {
+			if a.Module.Ecosystem != osv.GoEcosystem {
+				continue
+			}
+			for _, r := range a.Ranges {
+				for _, ev := range r.Events {
+					if ev.Fixed != "" {
+						adv.Fixed[a.Module.Path] = ev.Fixed
+					}
+				}
+			}
+		}
+		advs = append(advs, adv)
+	}
+	return advs, nil
+}
+
+// ghsaQuery is the GraphQL query of an advisory, aliased by a
+// field name and given the variable of its ID.
+const ghsaQuery = `%s: securityAdvisory(ghsaId: $%[1]s) {
+	ghsaId
+	severity
+	cvssSeverities { cvssV3 { vectorString } cvssV4 { vectorString } }
+	references { url }
+	vulnerabilities(first: 100, ecosystem: GO) {
+		nodes { package { name } firstPatchedVersion { identifier } }
+	}
+}
+`
+
+type ghsaNode struct {
+	GHSAID         string `json:"ghsaId"`
+	Severity       string `json:"severity"`
+	CVSSSeverities struct {
+		CVSSV3 struct {
+			VectorString string `json:"vectorString"`
+		} `json:"cvssV3"`
+		CVSSV4 struct {
+			VectorString string `json:"vectorString"`
+		} `json:"cvssV4"`
+	} `json:"cvssSeverities"`
+	References []struct {
+		URL string `json:"url"`
+	} `json:"references"`
+	Vulnerabilities struct {
+		Nodes []struct {
+			Package struct {
+				Name string `json:"name"`
+			} `json:"package"`
+			FirstPatchedVersion *struct {
+				Identifier string `json:"identifier"`
+			} `json:"firstPatchedVersion"`
+		} `json:"nodes"`
+	} `json:"vulnerabilities"`
+}
+
+// query reads the advisories with the IDs in ids from the GraphQL API.
+func (g *GHSAEnricher) query(ctx context.Context, ids []string) ([]*ghsaAdvisory, error) {
+	var params, fields []string
+	vars := make(map[string]string)
+	for i, id := range ids {
+		name := fmt.Sprintf("a%d", i)
+		params = append(params, "$"+name+": String!")
+		fields = append(fields, fmt.Sprintf(ghsaQuery, name))
+		vars[name] = id
+	}
+	body, err := json.Marshal(map[string]any{
+		"query":     fmt.Sprintf("query(%s) {\n%s}", strings.Join(params, ", "), strings.Join(fields, "")),
+		"variables": vars,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "bearer "+g.token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := g.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP POST %s returned unexpected status: %s", g.url, resp.Status)
+	}
+	var res struct {
+		Data   map[string]*ghsaNode `json:"data"`
+		Errors []struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+	for _, e := range res.Errors {
+		// Advisories that do not exist are left out.
+		if e.Type != "NOT_FOUND" {
+			return nil, fmt.Errorf("GitHub GraphQL API: %s", e.Message)
+		}
+	}
+	var advs []*ghsaAdvisory
+	for _, n := range res.Data {
+		if n == nil {
+			continue
+		}
+		adv := &ghsaAdvisory{ID: n.GHSAID, Severity: n.Severity, Fixed: make(map[string]string)}
+		if v := n.CVSSSeverities.CVSSV3.VectorString; v != "" {
+			adv.CVSS = append(adv.CVSS, osv.Severity{Type: osv.SeverityTypeCVSSV3, Score: v})
+		}
+		if v := n.CVSSSeverities.CVSSV4.VectorString; v != "" {
+			adv.CVSS = append(adv.CVSS, osv.Severity{Type: osv.SeverityTypeCVSSV4, Score: v})
+		}
+		for _, r := range n.References {
+			adv.References = append(adv.References, r.URL)
+		}
+		for _, v := range n.Vulnerabilities.Nodes {
+			if v.FirstPatchedVersion != nil {
+				adv.Fixed[v.Package.Name] = v.FirstPatchedVersion.Identifier
+			}
+		}
+		advs = append(advs, adv)
+	}
+	return advs, nil
+}
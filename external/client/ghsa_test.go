@@ -0,0 +1,160 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/google/go-cmp/cmp"
+)
+
+const testCVSS = "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"
+
+func TestGHSAEnricher(t *testing.T) {
+	ctx := context.Background()
+
+	// A clone of github/advisory-database with the
+	// GitHub advisory aliased by GO-2022-0463.
+	dir := t.TempDir()
+	adv := filepath.Join(dir, "advisories", "github-reviewed", "2022", "07", "GHSA-qx32-f6g6-fcfr")
+	if err := os.MkdirAll(adv, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(map[string]any{
+		"id":       "GHSA-qx32-f6g6-fcfr",
+		"severity": []osv.Severity{{Type: osv.SeverityTypeCVSSV3, Score: testCVSS}},
+		"affected": []osv.Affected{{
+			Module: osv.Module{Path: "github.com/astaxie/beego", Ecosystem: osv.GoEcosystem},
+			Ranges: []osv.Range{{Type: "ECOSYSTEM", Events: []osv.RangeEvent{{Introduced: "0"}, {Fixed: "1.12.9"}}}},
+		}},
+		"references": []osv.Reference{
+			{Type: osv.ReferenceTypeWeb, URL: "https://github.com/beego/beego/pull/4958"},
+			{Type: osv.ReferenceTypeAdvisory, URL: "https://github.com/advisories/GHSA-qx32-f6g6-fcfr"},
+		},
+		"database_specific": map[string]string{"severity": "CRITICAL"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(adv, "GHSA-qx32-f6g6-fcfr.json"), b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	local, err := NewGHSAEnricher(dir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The GraphQL API, with the same advisory.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data := make(map[string]any)
+		var errs []map[string]string
+		for name, id := range req.Variables {
+			if !strings.Contains(req.Query, name+": securityAdvisory(ghsaId: $"+name+")") {
+				http.Error(w, "bad query", http.StatusBadRequest)
+				return
+			}
+			if id != "GHSA-qx32-f6g6-fcfr" {
+				data[name] = nil
+				errs = append(errs, map[string]string{"type": "NOT_FOUND", "message": "not found"})
+				continue
+			}
+			data[name] = map[string]any{
+				"ghsaId":         id,
+				"severity":       "CRITICAL",
+				"cvssSeverities": map[string]any{"cvssV3": map[string]string{"vectorString": testCVSS}},
+				"references": []map[string]string{
+					{"url": "https://github.com/beego/beego/pull/4958"},
+					{"url": "https://github.com/advisories/GHSA-qx32-f6g6-fcfr"},
+				},
+				"vulnerabilities": map[string]any{"nodes": []map[string]any{{
+					"package":             map[string]string{"name": "github.com/astaxie/beego"},
+					"firstPatchedVersion": map[string]string{"identifier": "1.12.9"},
+				}}},
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data, "errors": errs})
+	}))
+	t.Cleanup(srv.Close)
+	api, err := NewGHSAEnricher("api", "secret", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	api.url = srv.URL
+
+	if _, err := NewGHSAEnricher("api", "", nil); err == nil {
+		t.Error("NewGHSAEnricher of the API without a token succeeded; want error")
+	}
+
+	for _, test := range []struct {
+		name string
+		en   Enricher
+	}{
+		{"local", local},
+		{"api", api},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			lc, err := NewClient(testVulndbFileURL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := Enrich(lc, test.en)
+			resps, err := c.ByModules(ctx, []*ModuleRequest{{Path: "github.com/beego/beego"}, {Path: "github.com/astaxie/beego"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var e *osv.Entry
+			for _, r := range resps[0].Entries {
+				if r.ID == "GO-2022-0463" {
+					e = r
+				} else if r.DatabaseSpecific != nil && len(r.DatabaseSpecific.Enrichment) > 0 {
+					t.Errorf("%s: got enrichment %v; want none", r.ID, r.DatabaseSpecific.Enrichment)
+				}
+			}
+			if e == nil {
+				t.Fatal("GO-2022-0463 not found")
+			}
+			if diff := cmp.Diff([]string{"github:GHSA-qx32-f6g6-fcfr"}, e.DatabaseSpecific.Enrichment); diff != "" {
+				t.Errorf("enrichment mismatch (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]osv.Severity{{Type: osv.SeverityTypeCVSSV3, Score: testCVSS}}, e.Severity); diff != "" {
+				t.Errorf("severity mismatch (-want, +got):\n%s", diff)
+			}
+			if e.DatabaseSpecific.Severity != "CRITICAL" {
+				t.Errorf("got severity %q; want CRITICAL", e.DatabaseSpecific.Severity)
+			}
+			if !hasReference(e, "https://github.com/advisories/GHSA-qx32-f6g6-fcfr") {
+				t.Error("missing the reference to the GitHub advisory")
+			}
+			if n := len(e.References); n != 6 {
+				t.Errorf("got %d references; want 6", n)
+			}
+			// The unfixed range of github.com/astaxie/beego is fixed.
+			want := []osv.RangeEvent{{Introduced: "0"}, {Fixed: "1.12.9"}}
+			if diff := cmp.Diff(want, e.Affected[0].Ranges[0].Events); diff != "" {
+				t.Errorf("events mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/derrors"
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// vulnMeta is an entry of the vulns index.
+type vulnMeta struct {
+	ID       string    `json:"id"`
+	Modified time.Time `json:"modified"`
+	Aliases  []string  `json:"aliases,omitempty"`
+}
+
+// legacyAffected is the part of an affected module of a legacy entry
+// which osv.Affected leaves out: legacy entries record their URL for
+// each module rather than for the entry.
+type legacyAffected struct {
+	DatabaseSpecific struct {
+		URL string `json:"url"`
+	} `json:"database_specific"`
+}
+
+// ConvertLegacy writes the database in the legacy layout in the
+// directory src, which predates the v1 layout, to the directory dest
+// in the v1 layout, as Mirror does, and returns the number of entries
+// written.
+//
+// The legacy layout has an index.json file mapping module paths to
+// modification times, a file for each module listing its entries, named
+// after the escaped module path, and an ID directory with a file for
+// each entry. The entries of both are converted, and entries listed
+// more than once are written in their most recently modified version.
+// The URLs recorded in the modules affected by the entries are moved
+// to the entries.
+func ConvertLegacy(src, dest string) (_ int, err error) {
+	derrors.Wrap(&err, "ConvertLegacy(%s, %s)", src, dest)
+
+	if _, err := os.Stat(filepath.Join(src, "index.json")); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, fmt.Errorf("%s is not a legacy database: it has no index.json file", src)
+		}
+		return 0, err
+	}
+	entries := make(map[string]*osv.Entry)
+	fsys := os.DirFS(src)
+	if err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case d.IsDir(), path.Ext(name) != ".json":
+			return nil
+		case name == "index.json", name == "aliases.json", name == path.Join(idDir, "index.json"):
+			return nil
+		}
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		var es []*osv.Entry
+		if strings.HasPrefix(name, idDir+"/") {
+			e, err := convertLegacyEntry(b)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			es = append(es, e)
+		} else {
+			var raws []json.RawMessage
+			if err := json.Unmarshal(b, &raws); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			for _, raw := range raws {
+				e, err := convertLegacyEntry(raw)
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				es = append(es, e)
+			}
+		}
+		for _, e := range es {
+			if err := checkID(e.ID); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if old, ok := entries[e.ID]; !ok || e.Modified.After(old.Modified) {
+				entries[e.ID] = e
+			}
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	idx := newIndex()
+	vulns := make([]vulnMeta, 0, len(ids))
+	for _, id := range ids {
+		e := entries[id]
+		b, err := json.Marshal(e)
+		if err != nil {
+			return 0, err
+		}
+		if err := writeEndpoint(dest, entryEndpoint(id), b, nil); err != nil {
+			return 0, err
+		}
+		idx.add(e)
+		vulns = append(vulns, vulnMeta{ID: id, Modified: e.Modified, Aliases: e.Aliases})
+	}
+
+	// The indexes are written last, so that
+	// they only list entries that are in dest.
+	b, err := json.Marshal(vulns)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeEndpoint(dest, vulnsEndpoint, b, nil); err != nil {
+		return 0, err
+	}
+	raw, err := idx.raw()
+	if err != nil {
+		return 0, err
+	}
+	if err := writeEndpoint(dest, modulesEndpoint, raw[modulesEndpoint], nil); err != nil {
+		return 0, err
+	}
+	if err := writeEndpoint(dest, dbEndpoint, raw[dbEndpoint], nil); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// convertLegacyEntry returns the entry of the raw legacy entry b,
+// with the URL of its first affected module, if any, as its own.
+func convertLegacyEntry(b []byte) (*osv.Entry, error) {
+	var e osv.Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+	if e.ID == "" {
+		return nil, errors.New("entry has no ID")
+	}
+	var legacy struct {
+		Affected []legacyAffected `json:"affected"`
+	}
+	if err := json.Unmarshal(b, &legacy); err != nil {
+		return nil, err
+	}
+	for _, a := range legacy.Affected {
+		if a.DatabaseSpecific.URL == "" {
+			continue
+		}
+		if e.DatabaseSpecific == nil {
+			e.DatabaseSpecific = &osv.DatabaseSpecific{}
+		}
+		if e.DatabaseSpecific.URL == "" {
+			e.DatabaseSpecific.URL = a.DatabaseSpecific.URL
+		}
+		break
+	}
+	return &e, nil
+}
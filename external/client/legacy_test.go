@@ -0,0 +1,52 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestConvertLegacy(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "v1")
+	n, err := ConvertLegacy(testLegacyVulndb, dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 9; n != want {
+		t.Errorf("converted %d entries; want %d", n, want)
+	}
+
+	ctx := context.Background()
+	c, err := NewClient(localURL(dest), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resps, err := c.ByModules(ctx, []*ModuleRequest{{Path: "github.com/tidwall/gjson"}, {Path: "stdlib"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range resps {
+		for _, e := range r.Entries {
+			got = append(got, e.ID)
+		}
+	}
+	want := []string{"GO-2021-0054", "GO-2021-0059", "GO-2021-0265", "GO-2022-0592", "GO-2021-0157", "GO-2021-0159"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got entries %q; want %q", got, want)
+	}
+	e := resps[1].Entries[1]
+	if e.DatabaseSpecific == nil || e.DatabaseSpecific.URL != "https://pkg.go.dev/vuln/GO-2021-0159" {
+		t.Errorf("got database specific %+v; want the URL of the affected module", e.DatabaseSpecific)
+	}
+
+	// A converted database is not a legacy database.
+	if _, err := ConvertLegacy(dest, t.TempDir()); err == nil {
+		t.Error("converting a v1 database succeeded; want an error")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultConcurrency is the number of requests made at
+// once to a database when Options.Concurrency is not set.
+const defaultConcurrency = 10
+
+// limit returns the number of requests c makes at once.
+func (c *Client) limit() int {
+	if c.concurrency > 0 {
+		return c.concurrency
+	}
+	return defaultConcurrency
+}
+
+// A limiter bounds the number of requests in flight,
+// and spaces them out to a rate, if set.
+type limiter struct {
+	sem      chan struct{}
+	interval time.Duration // between two requests, if positive
+
+	mu   sync.Mutex
+	next time.Time // when the next request may start
+}
+
+// newLimiter returns a limiter of concurrency requests at once,
+// or defaultConcurrency if not positive, and at most rate requests
+// per second, if positive.
+func newLimiter(concurrency int, rate float64) *limiter {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	l := &limiter{sem: make(chan struct{}, concurrency)}
+	if rate > 0 {
+		l.interval = time.Duration(float64(time.Second) / rate)
+	}
+	return l
+}
+
+// acquire waits until a request may start, and returns the function
+// to call once it is done, unless ctx is done first.
+func (l *limiter) acquire(ctx context.Context) (release func(), err error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release = func() { <-l.sem }
+	if l.interval <= 0 {
+		return release, nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	if d := start.Sub(now); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	var (
+		mu                  sync.Mutex
+		inFlight, most, all int
+		first, last         time.Time
+	)
+	files := http.FileServer(http.Dir(testVulndb))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		all++
+		most = max(most, inFlight)
+		if first.IsZero() {
+			first = time.Now()
+		}
+		last = time.Now()
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		files.ServeHTTP(w, r)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+
+	var reqs []*ModuleRequest
+	for _, m := range []string{"github.com/beego/beego", "github.com/beego/beego/v2", "golang.org/x/crypto", "stdlib", "toolchain"} {
+		reqs = append(reqs, &ModuleRequest{Path: m})
+	}
+	for _, tc := range []struct {
+		concurrency int
+		rate        float64
+	}{
+		{concurrency: 2},
+		{concurrency: 10, rate: 100},
+	} {
+		most, all, first = 0, 0, time.Time{}
+		c, err := NewClient(srv.URL, &Options{HTTPClient: srv.Client(), Concurrency: tc.concurrency, RateLimit: tc.rate})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.ByModules(context.Background(), reqs); err != nil {
+			t.Fatal(err)
+		}
+		if most > tc.concurrency {
+			t.Errorf("concurrency %d: got %d requests at once", tc.concurrency, most)
+		}
+		if tc.rate > 0 {
+			// The requests are at least 1/rate apart.
+			want := time.Duration(float64(all-1) / tc.rate * float64(time.Second))
+			if got := last.Sub(first); got < want {
+				t.Errorf("rate %g: %d requests in %s, want at least %s", tc.rate, all, got, want)
+			}
+		}
+	}
+}
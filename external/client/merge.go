@@ -0,0 +1,146 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	"golang.org/x/sync/errgroup"
+)
+
+// NewMergedClient returns a client that reads the union of the
+// vulnerability databases in sources, each as read by NewClient.
+//
+// The databases take precedence in the order of sources: an entry is
+// left out if a database before its own has an entry with the same ID
+// or aliases in common, so that advisories are not reported twice.
+// Each entry records the URL of the database it was read from in its
+// DatabaseSpecific.Source field.
+//
+// A single source is read as by NewClient, without provenance.
+func NewMergedClient(sources []string, opts *Options) (*Client, error) {
+	return NewMergedClientContext(context.Background(), sources, opts)
+}
+
+// NewMergedClientContext is like NewMergedClient, with
+// the databases read as by NewClientContext with ctx.
+func NewMergedClientContext(ctx context.Context, sources []string, opts *Options) (*Client, error) {
+	if len(sources) == 1 {
+		return NewClientContext(ctx, sources[0], opts)
+	}
+	var clients []*Client
+	for _, s := range sources {
+		c, err := NewClientContext(ctx, s, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s, err)
+		}
+		clients = append(clients, c)
+	}
+	return &Client{merged: clients}, nil
+}
+
+// mergedLastModifiedTime returns the latest
+// last modified time of the databases of c.
+func (c *Client) mergedLastModifiedTime(ctx context.Context) (time.Time, error) {
+	var latest time.Time
+	for _, m := range c.merged {
+		if m.name == "" {
+			// The entries of WithEntries are not a database.
+			continue
+		}
+		t, err := m.LastModifiedTime(ctx)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest, nil
+}
+
+// mergedByModules is ByModules for a client reading several databases.
+func (c *Client) mergedByModules(ctx context.Context, reqs []*ModuleRequest) ([]*ModuleResponse, error) {
+	all := make([][]*ModuleResponse, len(c.merged))
+	g, gctx := errgroup.WithContext(ctx)
+	for i, m := range c.merged {
+		i, m := i, m
+		g.Go(func() error {
+			resps, err := m.ByModules(gctx, reqs)
+			if err != nil {
+				return fmt.Errorf("%s: %w", m.name, err)
+			}
+			all[i] = resps
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	resps := make([]*ModuleResponse, len(reqs))
+	for i, req := range reqs {
+		resp := &ModuleResponse{Path: req.Path, Version: req.Version}
+		seen := make(map[string]bool) // IDs and aliases of the previous databases
+		for j, m := range c.merged {
+			var ids []string
+			for _, e := range all[j][i].Entries {
+				if seen[e.ID] || anySeen(seen, e.Aliases) {
+					continue
+				}
+				ids = append(append(ids, e.ID), e.Aliases...)
+				if m.name != "" {
+					// Entries not read from a database, as by
+					// WithEntries, keep their own source.
+					e = withSource(e, m.name)
+				}
+				resp.Entries = append(resp.Entries, e)
+			}
+			for _, id := range ids {
+				seen[id] = true
+			}
+		}
+		sort.SliceStable(resp.Entries, func(i, j int) bool {
+			return resp.Entries[i].ID < resp.Entries[j].ID
+		})
+		resps[i] = resp
+	}
+	return resps, nil
+}
+
+func anySeen(seen map[string]bool, ids []string) bool {
+	for _, id := range ids {
+		if seen[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// withSource returns a copy of e recording that
+// it was read from the database named source.
+func withSource(e *osv.Entry, source string) *osv.Entry {
+	c := *e
+	ds := osv.DatabaseSpecific{}
+	if e.DatabaseSpecific != nil {
+		ds = *e.DatabaseSpecific
+	}
+	ds.Source = source
+	c.DatabaseSpecific = &ds
+	return &c
+}
+
+// memoizeMerged is Memoize for a client reading several databases.
+func memoizeMerged(c *Client) *Client {
+	var merged []*Client
+	for _, m := range c.merged {
+		merged = append(merged, Memoize(m))
+	}
+	return &Client{merged: merged}
+}
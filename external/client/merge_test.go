@@ -0,0 +1,105 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergedClient(t *testing.T) {
+	// An internal database with an advisory of its own, another
+	// sharing an alias with GO-2022-0463, and a copy of GO-2022-0569.
+	dir := t.TempDir()
+	affected := []osv.Affected{{
+		Module: osv.Module{Path: "github.com/beego/beego", Ecosystem: osv.GoEcosystem},
+		Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{{Introduced: "0"}}}},
+	}}
+	modified := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, e := range []*osv.Entry{
+		{ID: "CORP-0001", Modified: modified, Affected: affected},
+		{ID: "CORP-0002", Modified: modified, Aliases: []string{"CVE-2022-31259"}, Affected: affected},
+		{ID: "GO-2022-0569", Modified: modified, Affected: affected},
+	} {
+		b, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.ID+".json"), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	internal := localURL(dir)
+
+	c, err := NewMergedClient([]string{testVulndbFileURL, internal}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	resps, err := c.ByModules(ctx, []*ModuleRequest{
+		{Path: "github.com/beego/beego"},
+		{Path: "stdlib", Version: "go1.17"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := func(r *ModuleResponse) map[string]string {
+		m := make(map[string]string)
+		for _, e := range r.Entries {
+			m[e.ID] = e.DatabaseSpecific.Source
+		}
+		return m
+	}
+	want := map[string]string{
+		"CORP-0001":    internal,
+		"GO-2022-0463": testVulndbFileURL,
+		"GO-2022-0569": testVulndbFileURL,
+		"GO-2022-0572": testVulndbFileURL,
+	}
+	if diff := cmp.Diff(want, sources(resps[0])); diff != "" {
+		t.Errorf("beego entries mismatch (-want, +got):\n%s", diff)
+	}
+	want = map[string]string{
+		"GO-2021-0264": testVulndbFileURL,
+		"GO-2022-0273": testVulndbFileURL,
+	}
+	if diff := cmp.Diff(want, sources(resps[1])); diff != "" {
+		t.Errorf("stdlib entries mismatch (-want, +got):\n%s", diff)
+	}
+	// The URL of the Go advisories is kept.
+	if got := resps[0].Entries[1].DatabaseSpecific.URL; got == "" {
+		t.Errorf("%s: lost database specific URL", resps[0].Entries[1].ID)
+	}
+
+	got, err := c.LastModifiedTime(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(modified) {
+		t.Errorf("LastModifiedTime = %s; want %s", got, modified)
+	}
+
+	// A single database is read without provenance.
+	c, err = NewMergedClient([]string{testVulndbFileURL}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resps, err = c.ByModules(ctx, []*ModuleRequest{{Path: "github.com/beego/beego"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range resps[0].Entries {
+		if e.DatabaseSpecific != nil && e.DatabaseSpecific.Source != "" {
+			t.Errorf("%s: got source %s; want none", e.ID, e.DatabaseSpecific.Source)
+		}
+	}
+}
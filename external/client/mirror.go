@@ -0,0 +1,209 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/derrors"
+	"golang.org/x/sync/errgroup"
+)
+
+// Mirror updates the directory dir to a copy of the database of c, in
+// the v1 layout. Each endpoint is written both as JSON, for NewClient
+// to read from a "file" URL, and as gzipped JSON, so that any static
+// file server can serve dir as a database.
+//
+// Only the entries that were modified since the last mirror to dir
+// are downloaded, and those no longer in the database are removed.
+// The indexes are written last, so that they only list entries that
+// are in dir. The signatures of the endpoints are copied if c verifies
+// them. Mirror returns the number of entries downloaded.
+//
+// It is not supported for clients reading several databases.
+func (c *Client) Mirror(ctx context.Context, dir string) (_ int, err error) {
+	derrors.Wrap(&err, "Mirror(%s)", dir)
+
+	if len(c.merged) > 0 {
+		return 0, errors.New("cannot mirror several databases")
+	}
+	if c.osvAPI != nil {
+		return 0, errors.New("cannot mirror the OSV API")
+	}
+	if c.enriched != nil {
+		// The database is copied as is, without enrichment.
+		return c.enriched.Mirror(ctx, dir)
+	}
+	modules, ids, err := c.modulesIndex(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var old map[string]time.Time
+	if b, err := os.ReadFile(filepath.Join(dir, modulesEndpoint+".json")); err == nil {
+		if _, old, err = entriesOf(b); err != nil {
+			return 0, err
+		}
+	}
+
+	var stale []string
+	for id, modified := range ids {
+		_, err := os.Stat(filepath.Join(dir, entryEndpoint(id)+".json"))
+		if t, ok := old[id]; !ok || !t.Equal(modified) || err != nil {
+			stale = append(stale, entryEndpoint(id))
+		}
+	}
+	sort.Strings(stale)
+	data, err := c.getAll(ctx, stale)
+	if err != nil {
+		return 0, err
+	}
+	for i, e := range stale {
+		if err := writeEndpoint(dir, e, data[i], c.signatureOf(e)); err != nil {
+			return 0, err
+		}
+	}
+
+	db, err := c.source.get(ctx, dbEndpoint)
+	if err != nil {
+		return 0, err
+	}
+	// The vulns index is not needed to read the mirror,
+	// so databases without one are still mirrored.
+	if b, err := c.source.get(ctx, vulnsEndpoint); err == nil {
+		if err := writeEndpoint(dir, vulnsEndpoint, b, c.signatureOf(vulnsEndpoint)); err != nil {
+			return 0, err
+		}
+	}
+	if err := writeEndpoint(dir, modulesEndpoint, modules, c.signatureOf(modulesEndpoint)); err != nil {
+		return 0, err
+	}
+	if err := writeEndpoint(dir, dbEndpoint, db, c.signatureOf(dbEndpoint)); err != nil {
+		return 0, err
+	}
+
+	files, err := os.ReadDir(filepath.Join(dir, idDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+	for _, f := range files {
+		id, _, _ := strings.Cut(f.Name(), ".json")
+		if _, ok := ids[id]; !ok {
+			if err := os.Remove(filepath.Join(dir, idDir, f.Name())); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(stale), nil
+}
+
+// modulesIndex returns the raw modules index of c, and
+// the modified times of the entries it lists by ID.
+func (c *Client) modulesIndex(ctx context.Context) ([]byte, map[string]time.Time, error) {
+	b, err := c.source.get(ctx, modulesEndpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entriesOf(b)
+}
+
+// entriesOf returns the modified times of the
+// entries listed in the raw modules index b by ID.
+func entriesOf(b []byte) ([]byte, map[string]time.Time, error) {
+	var modules []*moduleMeta
+	if err := json.Unmarshal(b, &modules); err != nil {
+		return nil, nil, err
+	}
+	ids := make(map[string]time.Time)
+	for _, m := range modules {
+		for _, v := range m.Vulns {
+			if err := checkID(v.ID); err != nil {
+				return nil, nil, err
+			}
+			ids[v.ID] = v.Modified
+		}
+	}
+	return b, ids, nil
+}
+
+// getAll returns the raw data at the endpoints of c.
+func (c *Client) getAll(ctx context.Context, endpoints []string) ([][]byte, error) {
+	data := make([][]byte, len(endpoints))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(c.limit())
+	for i, e := range endpoints {
+		i, e := i, e
+		g.Go(func() error {
+			b, err := c.source.get(gctx, e)
+			data[i] = b
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeEndpoint writes the data of endpoint in dir as a JSON
+// file and a gzipped JSON file, along with its signature sig, if any.
+func writeEndpoint(dir, endpoint string, data, sig []byte) error {
+	file := filepath.Join(dir, filepath.FromSlash(endpoint)) + ".json"
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if sig != nil {
+		if err := writeFileAtomic(file+".minisig", sig, 0o644); err != nil {
+			return err
+		}
+	}
+	if err := writeFileAtomic(file, data, 0o644); err != nil {
+		return err
+	}
+	return writeFileAtomic(file+".gz", buf.Bytes(), 0o644)
+}
+
+// writeFileAtomic writes data to file with the permissions perm, which
+// is replaced only once data is written, so that readers see either
+// version in full.
+func writeFileAtomic(file string, data []byte, perm os.FileMode) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
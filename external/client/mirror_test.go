@@ -0,0 +1,133 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMirror(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(testVulndb)
+	t.Cleanup(srv.Close)
+	hc, err := NewClient(srv.URL, &Options{HTTPClient: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	n, err := hc.Mirror(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(testIDs); n != want {
+		t.Errorf("first mirror downloaded %d entries; want %d", n, want)
+	}
+
+	// The mirror is read both from files and over http.
+	mirror := newTestServer(dir)
+	t.Cleanup(mirror.Close)
+	mc, err := NewClient(mirror.URL, &Options{HTTPClient: mirror.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc, err := NewClient(localURL(dir), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs := []*ModuleRequest{{Path: "github.com/beego/beego"}, {Path: "stdlib", Version: "go1.17"}}
+	want, err := hc.ByModules(ctx, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []*Client{mc, lc} {
+		got, err := c.ByModules(ctx, reqs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%s: ByModules mismatch (-want, +got):\n%s", c.name, diff)
+		}
+	}
+
+	// Only missing entries are downloaded again,
+	// and those not in the database are removed.
+	if err := os.Remove(filepath.Join(dir, "ID", "GO-2021-0068.json")); err != nil {
+		t.Fatal(err)
+	}
+	extra := filepath.Join(dir, "ID", "GO-1999-0001.json")
+	if err := os.WriteFile(extra, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	n, err = hc.Mirror(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("second mirror downloaded %d entries; want 1", n)
+	}
+	if _, err := os.Stat(extra); err == nil {
+		t.Errorf("%s was not removed", extra)
+	}
+}
+
+func TestMirrorInvalidID(t *testing.T) {
+	// The entry of the ID "../../escaped" would be written
+	// two directories above the ID directory of the mirror.
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	files := map[string]string{
+		"index/db.json":      `{"modified":"2024-01-01T00:00:00Z"}`,
+		"index/modules.json": `[{"path":"example.com/m","vulns":[{"id":"../../escaped","modified":"2024-01-01T00:00:00Z"}]}]`,
+		"escaped.json":       `{"id":"../../escaped","modified":"2024-01-01T00:00:00Z"}`,
+	}
+	for name, data := range files {
+		file := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := NewClient(localURL(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(root, "mirror", "dest")
+	if _, err := c.Mirror(context.Background(), dest); err == nil || !strings.Contains(err.Error(), `invalid entry ID "../../escaped"`) {
+		t.Errorf("got error %v; want an invalid entry ID", err)
+	}
+	if err := c.WriteZip(context.Background(), io.Discard); err == nil || !strings.Contains(err.Error(), "invalid entry ID") {
+		t.Errorf("WriteZip: got error %v; want an invalid entry ID", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "mirror", "escaped.json")); err == nil {
+		t.Error("entry written outside of the mirror")
+	}
+}
+
+func TestCheckID(t *testing.T) {
+	for id, valid := range map[string]bool{
+		"GO-2023-0001":            true,
+		"GHSA-xxxx-xxxx-xxxx":     true,
+		"openSUSE-SU-2024:0001-1": runtime.GOOS != "windows",
+		"../../escaped":           false,
+		"GO-2023-0001/../x":       false,
+		"..":                      false,
+		"":                        false,
+		"GO-2023 0001":            false,
+	} {
+		if err := checkID(id); (err == nil) != valid {
+			t.Errorf("checkID(%q) = %v; want valid %t", id, err, valid)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/StevenACoffman/invuln/external/derrors"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"golang.org/x/sync/errgroup"
+)
+
+// The rates of requests per second that NVD allows without and with an
+// API key: 5 and 50 in a rolling window of 30 seconds.
+const (
+	nvdRate    = 5.0 / 30
+	nvdKeyRate = 50.0 / 30
+)
+
+// NVDEnricher adds the CVSS vectors of the CVEs that OSV entries alias,
+// as published by the National Vulnerability Database, to the entries
+// that have no severity.
+//
+// The enrichment is best effort: CVEs that cannot be read, for instance
+// because NVD is down or the rate limit was exceeded anyway, are logged
+// and skipped, leaving the entries aliasing them as they are.
+type NVDEnricher struct {
+	src source // responses of the CVE API by CVE ID
+
+	mu   sync.Mutex
+	cves map[string]*nvdCVE // CVEs read, by ID
+}
+
+// nvdCVE is the information of a CVE of NVD
+// that is added to the entries aliasing it.
+type nvdCVE struct {
+	sevs     []osv.Severity
+	severity string
+}
+
+// NewNVDEnricher returns an enricher reading CVEs from the CVE API
+// of NVD at url, such as https://services.nvd.nist.gov, or a mirror.
+//
+// Of opts, only HTTPClient, Transport, Header, which can hold the apiKey
+// header of NVD, CacheDir, CacheTTL, Concurrency, and RateLimit are used.
+// The CVEs are cached in CacheDir itself. The requests are spaced out to
+// the rate limit of NVD, which depends on whether an API key is given,
+// unless RateLimit is set.
+func NewNVDEnricher(url string, opts *Options) *NVDEnricher {
+	ns := &nvdSource{url: strings.TrimRight(url, "/"), c: opts.httpClient()}
+	var src source = ns
+	rate, concurrency := nvdRate, 0
+	if opts != nil {
+		ns.header = opts.Header
+		if opts.Header.Get("apiKey") != "" {
+			rate = nvdKeyRate
+		}
+		if opts.RateLimit > 0 {
+			rate = opts.RateLimit
+		}
+		concurrency = opts.Concurrency
+		if opts.CacheDir != "" && opts.CacheTTL > 0 {
+			src = newDiskCacheSource(ns, opts.CacheDir, opts.CacheTTL)
+		}
+	}
+	ns.limiter = newLimiter(concurrency, rate)
+	return &NVDEnricher{src: src, cves: make(map[string]*nvdCVE)}
+}
+
+// Enrich implements Enricher.
+func (n *NVDEnricher) Enrich(ctx context.Context, entries []*osv.Entry) error {
+	var ids []string
+	for _, e := range entries {
+		if len(e.Severity) == 0 {
+			ids = append(ids, cveAliases(e)...)
+		}
+	}
+	cves, err := n.read(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if len(e.Severity) > 0 {
+			continue
+		}
+		for _, id := range cveAliases(e) {
+			cve := cves[id]
+			if cve == nil || len(cve.sevs) == 0 {
+				continue
+			}
+			e.Severity = cve.sevs
+			ds := enrichment(e)
+			if ds.Severity == "" {
+				ds.Severity = cve.severity
+			}
+			ds.Enrichment = append(ds.Enrichment, "nvd:"+id)
+			break
+		}
+	}
+	return nil
+}
+
+func cveAliases(e *osv.Entry) []string {
+	var ids []string
+	for _, a := range e.Aliases {
+		if strings.HasPrefix(a, "CVE-") {
+			ids = append(ids, a)
+		}
+	}
+	return ids
+}
+
+// read returns the CVEs with the IDs in ids by ID, requesting those not
+// read before all at once, as fast as the limiter of the source allows.
+// Only CVEs read successfully, or known not to be in NVD, are kept, so
+// that those failing are requested again by later calls. It only fails
+// if ctx is done.
+func (n *NVDEnricher) read(ctx context.Context, ids []string) (map[string]*nvdCVE, error) {
+	cves := make(map[string]*nvdCVE)
+	var missing []string
+	n.mu.Lock()
+	for _, id := range ids {
+		if cve, ok := n.cves[id]; ok {
+			cves[id] = cve
+		} else if _, ok := cves[id]; !ok {
+			cves[id] = nil
+			missing = append(missing, id)
+		}
+	}
+	n.mu.Unlock()
+
+	var mu sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	for _, id := range missing {
+		g.Go(func() error {
+			b, err := n.src.get(gctx, id)
+			var cve nvdCVE
+			if err == nil {
+				cve.sevs, cve.severity, err = nvdSeverities(b)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				govulncheck.Logger(ctx).Warn("skipping the CVSS vectors of a CVE of NVD", "id", id, "error", err)
+				return nil
+			}
+			mu.Lock()
+			cves[id] = &cve
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for id, cve := range cves {
+		if cve != nil {
+			n.cves[id] = cve
+		}
+	}
+	return cves, nil
+}
+
+type nvdMetric struct {
+	Type     string `json:"type"` // Primary or Secondary
+	CVSSData struct {
+		VectorString string `json:"vectorString"`
+		BaseSeverity string `json:"baseSeverity"`
+	} `json:"cvssData"`
+}
+
+// nvdSeverities returns the CVSS v3 and v4 vectors and the
+// qualitative severity of the CVE in the response b of the CVE API,
+// preferring the scores of NVD, which are primary, to the others.
+func nvdSeverities(b []byte) ([]osv.Severity, string, error) {
+	var resp struct {
+		Vulnerabilities []struct {
+			CVE struct {
+				Metrics struct {
+					V40 []nvdMetric `json:"cvssMetricV40"`
+					V31 []nvdMetric `json:"cvssMetricV31"`
+					V30 []nvdMetric `json:"cvssMetricV30"`
+				} `json:"metrics"`
+			} `json:"cve"`
+		} `json:"vulnerabilities"`
+	}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, "", err
+	}
+	if len(resp.Vulnerabilities) == 0 {
+		return nil, "", nil
+	}
+	m := resp.Vulnerabilities[0].CVE.Metrics
+	var sevs []osv.Severity
+	var severity string
+	if v3 := primary(append(m.V31, m.V30...)); v3 != nil {
+		sevs = append(sevs, osv.Severity{Type: osv.SeverityTypeCVSSV3, Score: v3.CVSSData.VectorString})
+		severity = v3.CVSSData.BaseSeverity
+	}
+	if v4 := primary(m.V40); v4 != nil {
+		sevs = append(sevs, osv.Severity{Type: osv.SeverityTypeCVSSV4, Score: v4.CVSSData.VectorString})
+		if severity == "" {
+			severity = v4.CVSSData.BaseSeverity
+		}
+	}
+	return sevs, severity, nil
+}
+
+// primary returns the first primary metric of ms,
+// or else the first one, if any.
+func primary(ms []nvdMetric) *nvdMetric {
+	for i := range ms {
+		if ms[i].Type == "Primary" {
+			return &ms[i]
+		}
+	}
+	if len(ms) > 0 {
+		return &ms[0]
+	}
+	return nil
+}
+
+// nvdSource reads the responses of the CVE API of NVD, whose
+// endpoints are CVE IDs. CVEs unknown to NVD have no vulnerabilities.
+type nvdSource struct {
+	url     string
+	c       *http.Client
+	header  http.Header
+	limiter *limiter
+}
+
+func (ns *nvdSource) get(ctx context.Context, id string) (_ []byte, err error) {
+	derrors.Wrap(&err, "get(%s)", id)
+
+	reqURL := ns.url + "/rest/json/cves/2.0?cveId=" + url.QueryEscape(id)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range ns.header {
+		req.Header[k] = vs
+	}
+	release, err := ns.limiter.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	resp, err := ns.c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return []byte(`{"vulnerabilities":[]}`), nil
+	default:
+		return nil, fmt.Errorf("HTTP GET %s returned unexpected status: %s", reqURL, resp.Status)
+	}
+}
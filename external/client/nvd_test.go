@@ -0,0 +1,167 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNVDEnricher(t *testing.T) {
+	ctx := context.Background()
+	const v4 = "CVSS:4.0/AV:N/AC:L/AT:N/PR:N/UI:N/VC:H/VI:H/VA:H/SC:N/SI:N/SA:N"
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/rest/json/cves/2.0" || r.Header.Get("apiKey") != "key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("cveId") != "CVE-2022-31259" {
+			fmt.Fprint(w, `{"vulnerabilities":[]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"vulnerabilities":[{"cve":{"id":"CVE-2022-31259","metrics":{
+			"cvssMetricV40":[{"type":"Secondary","cvssData":{"vectorString":%q,"baseSeverity":"CRITICAL"}}],
+			"cvssMetricV31":[
+				{"type":"Secondary","cvssData":{"vectorString":"CVSS:3.1/AV:L","baseSeverity":"LOW"}},
+				{"type":"Primary","cvssData":{"vectorString":%q,"baseSeverity":"HIGH"}}
+			]}}}]}`, v4, testCVSS)
+	}))
+	t.Cleanup(srv.Close)
+
+	opts := &Options{
+		HTTPClient: srv.Client(),
+		Header:     http.Header{"Apikey": {"key"}},
+		CacheDir:   t.TempDir(),
+		CacheTTL:   time.Hour,
+		RateLimit:  1000,
+	}
+	enrich := func() (*osv.Entry, *osv.Entry) {
+		lc, err := NewClient(testVulndbFileURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := Enrich(lc, NewNVDEnricher(srv.URL, opts))
+		resps, err := c.ByModules(ctx, []*ModuleRequest{{Path: "github.com/beego/beego"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got, other *osv.Entry
+		for _, e := range resps[0].Entries {
+			switch e.ID {
+			case "GO-2022-0463":
+				got = e
+			case "GO-2022-0569":
+				other = e
+			}
+		}
+		return got, other
+	}
+	e, other := enrich()
+	want := []osv.Severity{
+		{Type: osv.SeverityTypeCVSSV3, Score: testCVSS},
+		{Type: osv.SeverityTypeCVSSV4, Score: v4},
+	}
+	if diff := cmp.Diff(want, e.Severity); diff != "" {
+		t.Errorf("severity mismatch (-want, +got):\n%s", diff)
+	}
+	if got := e.DatabaseSpecific.Severity; got != "HIGH" {
+		t.Errorf("got severity %q; want HIGH", got)
+	}
+	if diff := cmp.Diff([]string{"nvd:CVE-2022-31259"}, e.DatabaseSpecific.Enrichment); diff != "" {
+		t.Errorf("enrichment mismatch (-want, +got):\n%s", diff)
+	}
+	if len(other.Severity) > 0 || len(other.DatabaseSpecific.Enrichment) > 0 {
+		t.Errorf("%s: got severity %v from %v; want none", other.ID, other.Severity, other.DatabaseSpecific.Enrichment)
+	}
+
+	// The CVEs are read from the cache on later runs.
+	n := requests.Load()
+	enrich()
+	if got := requests.Load(); got != n {
+		t.Errorf("got %d more requests with the cache; want none", got-n)
+	}
+}
+
+func TestNVDEnricherFailures(t *testing.T) {
+	ctx := context.Background()
+	var requests atomic.Int32
+	var down atomic.Bool
+	down.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"vulnerabilities":[{"cve":{"metrics":{
+			"cvssMetricV31":[{"type":"Primary","cvssData":{"vectorString":%q,"baseSeverity":"HIGH"}}]}}}]}`, testCVSS)
+	}))
+	t.Cleanup(srv.Close)
+
+	n := NewNVDEnricher(srv.URL, &Options{HTTPClient: srv.Client(), RateLimit: 1000})
+	e := &osv.Entry{ID: "GO-0000-0001", Aliases: []string{"CVE-0000-0001"}}
+	// Failures leave the entries as they are.
+	if err := n.Enrich(ctx, []*osv.Entry{e}); err != nil {
+		t.Fatalf("got error %v from NVD being down; want none", err)
+	}
+	if len(e.Severity) > 0 {
+		t.Errorf("got severity %v from NVD being down; want none", e.Severity)
+	}
+	// Nor are they kept.
+	down.Store(false)
+	if err := n.Enrich(ctx, []*osv.Entry{e}); err != nil {
+		t.Fatal(err)
+	}
+	want := []osv.Severity{{Type: osv.SeverityTypeCVSSV3, Score: testCVSS}}
+	if diff := cmp.Diff(want, e.Severity); diff != "" {
+		t.Errorf("severity mismatch once NVD is back (-want, +got):\n%s", diff)
+	}
+	// Unlike the CVEs read.
+	r := requests.Load()
+	e2 := &osv.Entry{ID: "GO-0000-0002", Aliases: []string{"CVE-0000-0001"}}
+	if err := n.Enrich(ctx, []*osv.Entry{e2}); err != nil {
+		t.Fatal(err)
+	}
+	if got := requests.Load(); got != r {
+		t.Errorf("got %d more requests for a CVE read; want none", got-r)
+	}
+
+	// Cancellation is not skipped.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	e3 := &osv.Entry{ID: "GO-0000-0003", Aliases: []string{"CVE-0000-0003"}}
+	if err := n.Enrich(cctx, []*osv.Entry{e3}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v once canceled; want %v", err, context.Canceled)
+	}
+}
+
+func TestNVDRate(t *testing.T) {
+	for _, test := range []struct {
+		opts *Options
+		want float64
+	}{
+		{nil, nvdRate},
+		{&Options{}, nvdRate},
+		{&Options{Header: http.Header{"Apikey": {"key"}}}, nvdKeyRate},
+		{&Options{Header: http.Header{"Apikey": {"key"}}, RateLimit: 1}, 1},
+	} {
+		n := NewNVDEnricher("https://services.nvd.nist.gov", test.opts)
+		want := time.Duration(float64(time.Second) / test.want)
+		if got := n.src.(*nvdSource).limiter.interval; got != want {
+			t.Errorf("%+v: got an interval of %v between requests; want %v", test.opts, got, want)
+		}
+	}
+}
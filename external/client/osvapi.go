@@ -0,0 +1,199 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external/derrors"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"golang.org/x/sync/errgroup"
+)
+
+// osvBatchSize is the largest number of queries
+// of a request to the querybatch endpoint.
+const osvBatchSize = 1000
+
+var errOSVLastModified = errors.New("the OSV API does not report when it was last modified")
+
+// newOSVClient returns a client of the OSV API, such as the one at
+// https://api.osv.dev, given by an "osv+http" or "osv+https" URL.
+// Instead of downloading the entries of the modules in the requests,
+// it queries the API for those affecting their versions.
+func newOSVClient(uri *url.URL, opts *Options) (*Client, error) {
+	hs, err := newHTTPSource(strings.TrimPrefix(uri.String(), "osv+"), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{osvAPI: hs}, nil
+}
+
+type osvQuery struct {
+	Package   osvPackage `json:"package"`
+	Version   string     `json:"version,omitempty"`
+	PageToken string     `json:"page_token,omitempty"`
+}
+
+type osvPackage struct {
+	Name      string `json:"name"`
+	Ecosystem string `json:"ecosystem"`
+}
+
+type osvBatchResponse struct {
+	Results []struct {
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+		NextPageToken string `json:"next_page_token"`
+	} `json:"results"`
+}
+
+// osvByModules is ByModules for a client of the OSV API.
+func (c *Client) osvByModules(ctx context.Context, reqs []*ModuleRequest) ([]*ModuleResponse, error) {
+	queries := make([]osvQuery, len(reqs))
+	pending := make([]int, len(reqs)) // indexes of the queries with more results
+	for i, req := range reqs {
+		if req.Path == "" {
+			return nil, fmt.Errorf("module path must be set")
+		}
+		queries[i] = osvQuery{
+			Package: osvPackage{Name: req.Path, Ecosystem: string(osv.GoEcosystem)},
+			// The versions of the Go ecosystem have no "v" prefix.
+			Version: strings.TrimPrefix(req.Version, "v"),
+		}
+		pending[i] = i
+	}
+
+	ids := make([][]string, len(reqs))
+	for len(pending) > 0 {
+		var next []int
+		for len(pending) > 0 {
+			batch := pending[:min(len(pending), osvBatchSize)]
+			pending = pending[len(batch):]
+			var body struct {
+				Queries []osvQuery `json:"queries"`
+			}
+			for _, i := range batch {
+				body.Queries = append(body.Queries, queries[i])
+			}
+			var resp osvBatchResponse
+			if err := c.osvPost(ctx, "v1/querybatch", body, &resp); err != nil {
+				return nil, err
+			}
+			if len(resp.Results) != len(batch) {
+				return nil, fmt.Errorf("querybatch returned %d results for %d queries", len(resp.Results), len(batch))
+			}
+			for j, r := range resp.Results {
+				i := batch[j]
+				for _, v := range r.Vulns {
+					ids[i] = append(ids[i], v.ID)
+				}
+				if r.NextPageToken != "" {
+					queries[i].PageToken = r.NextPageToken
+					next = append(next, i)
+				}
+			}
+		}
+		pending = next
+	}
+
+	entries, err := c.osvEntries(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	resps := make([]*ModuleResponse, len(reqs))
+	for i, req := range reqs {
+		resp := &ModuleResponse{Path: req.Path, Version: req.Version}
+		for _, id := range ids[i] {
+			resp.Entries = append(resp.Entries, entries[id])
+		}
+		resp.Entries = withoutGHSADuplicates(resp.Entries)
+		resps[i] = resp
+	}
+	return resps, nil
+}
+
+// osvEntries returns the entries with the IDs in ids by ID.
+func (c *Client) osvEntries(ctx context.Context, ids [][]string) (map[string]*osv.Entry, error) {
+	entries := make(map[string]*osv.Entry)
+	for _, l := range ids {
+		for _, id := range l {
+			entries[id] = nil
+		}
+	}
+	all := make([]string, 0, len(entries))
+	for id := range entries {
+		all = append(all, id)
+	}
+	fetched := make([]*osv.Entry, len(all))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(c.limit())
+	for i, id := range all {
+		i, id := i, id
+		g.Go(func() (err error) {
+			derrors.Wrap(&err, "byID(%s)", id)
+			fetched[i] = new(osv.Entry)
+			return c.osvGet(gctx, "v1/vulns/"+url.PathEscape(id), fetched[i])
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	for i, id := range all {
+		entries[id] = fetched[i]
+	}
+	return entries, nil
+}
+
+// withoutGHSADuplicates returns entries without those that are
+// aliases of Go advisories, such as the GitHub advisories the OSV
+// API also reports for Go modules, sorted by ID.
+func withoutGHSADuplicates(entries []*osv.Entry) []*osv.Entry {
+	aliased := make(map[string]bool)
+	for _, e := range entries {
+		if strings.HasPrefix(e.ID, "GO-") {
+			for _, a := range e.Aliases {
+				aliased[a] = true
+			}
+		}
+	}
+	var kept []*osv.Entry
+	for _, e := range entries {
+		if !aliased[e.ID] {
+			kept = append(kept, e)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].ID < kept[j].ID
+	})
+	return kept
+}
+
+func (c *Client) osvPost(ctx context.Context, endpoint string, body, v any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return c.osvDo(ctx, http.MethodPost, endpoint, b, v)
+}
+
+func (c *Client) osvGet(ctx context.Context, endpoint string, v any) error {
+	return c.osvDo(ctx, http.MethodGet, endpoint, nil, v)
+}
+
+// osvDo makes a request to the endpoint of the OSV API
+// with the JSON body b, if any, and decodes the response in v.
+func (c *Client) osvDo(ctx context.Context, method, endpoint string, b []byte, v any) error {
+	return c.osvAPI.fetch(ctx, method, c.osvAPI.url+"/"+endpoint, nil, b, func(resp *http.Response) error {
+		return json.NewDecoder(resp.Body).Decode(v)
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	isem "github.com/StevenACoffman/invuln/external/semver"
+	"github.com/google/go-cmp/cmp"
+)
+
+// newOSVTestServer returns a server of the OSV API for entries,
+// which returns one vulnerability per page of query results.
+func newOSVTestServer(t *testing.T, entries []*osv.Entry) *httptest.Server {
+	byID := make(map[string]*osv.Entry)
+	for _, e := range entries {
+		byID[e.ID] = e
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/querybatch", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Queries []osvQuery `json:"queries"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var resp osvBatchResponse
+		resp.Results = make([]struct {
+			Vulns []struct {
+				ID string `json:"id"`
+			} `json:"vulns"`
+			NextPageToken string `json:"next_page_token"`
+		}, len(body.Queries))
+		for i, q := range body.Queries {
+			if q.Package.Ecosystem != "Go" || strings.HasPrefix(q.Version, "v") {
+				http.Error(w, "bad query", http.StatusBadRequest)
+				return
+			}
+			var ids []string
+			for _, e := range entries {
+				for _, a := range e.Affected {
+					if a.Module.Path == q.Package.Name && (q.Version == "" || isem.Affects(a.Ranges, q.Version)) {
+						ids = append(ids, e.ID)
+						break
+					}
+				}
+			}
+			page, _ := strconv.Atoi(q.PageToken)
+			if page < len(ids) {
+				resp.Results[i].Vulns = append(resp.Results[i].Vulns, struct {
+					ID string `json:"id"`
+				}{ids[page]})
+			}
+			if page+1 < len(ids) {
+				resp.Results[i].NextPageToken = strconv.Itoa(page + 1)
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("GET /v1/vulns/{id}", func(w http.ResponseWriter, r *http.Request) {
+		e, ok := byID[r.PathValue("id")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(e)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOSVClient(t *testing.T) {
+	ctx := context.Background()
+	testEntries, err := entries(testIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A GitHub advisory duplicating GO-2022-0463.
+	ghsa := &osv.Entry{
+		ID:       "GHSA-qx32-f6g6-fcfr",
+		Aliases:  []string{"CVE-2022-31259"},
+		Affected: testEntries[2].Affected,
+	}
+	srv := newOSVTestServer(t, append(testEntries, ghsa))
+	oc, err := NewClient("osv+"+srv.URL, &Options{HTTPClient: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc, err := NewClient(testVulndbFileURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := []*ModuleRequest{
+		{Path: "github.com/beego/beego", Version: "v1.12.0"},
+		{Path: "github.com/beego/beego"},
+		{Path: "stdlib", Version: "v1.17.0"},
+		{Path: "example.com/none", Version: "v1.0.0"},
+	}
+	got, err := oc.ByModules(ctx, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := func(resps []*ModuleResponse) [][]string {
+		var all [][]string
+		for _, r := range resps {
+			var l []string
+			for _, e := range r.Entries {
+				l = append(l, e.ID)
+			}
+			all = append(all, l)
+		}
+		return all
+	}
+	want, err := lc.ByModules(ctx, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The database reports the entries of the module for any
+	// version affected by some, which the OSV API filters.
+	wantIDs := ids(want)
+	wantIDs[0] = []string{"GO-2022-0463", "GO-2022-0569", "GO-2022-0572"}
+	if diff := cmp.Diff(wantIDs, ids(got)); diff != "" {
+		t.Errorf("ByModules mismatch (-want, +got):\n%s", diff)
+	}
+
+	if _, err := oc.LastModifiedTime(ctx); err == nil {
+		t.Error("LastModifiedTime succeeded; want error")
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+)
+
+const (
+	// defaultBackoff is the delay before the first retry
+	// of a request when Options.Backoff is not set.
+	defaultBackoff = time.Second
+	// maxBackoff is the longest delay between two attempts.
+	maxBackoff = 30 * time.Second
+)
+
+// An UnreachableError is returned when a request to an http(s)
+// database fails with an error that might be transient, such as
+// a network error, a timeout, or a server error, on each attempt.
+type UnreachableError struct {
+	URL      string // of the request
+	Attempts int
+	Err      error // of the last attempt
+}
+
+func (e *UnreachableError) Error() string {
+	attempts := "1 attempt"
+	if e.Attempts > 1 {
+		attempts = fmt.Sprintf("%d attempts", e.Attempts)
+	}
+	return fmt.Sprintf("vulnerability database unreachable after %s: %v", attempts, e.Err)
+}
+
+func (e *UnreachableError) Unwrap() error {
+	return e.Err
+}
+
+// statusError is the error of a response with a status other than OK.
+type statusError struct {
+	method, url string
+	status      string
+	code        int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("HTTP %s %s returned unexpected status: %s", e.method, e.url, e.status)
+}
+
+// fetch makes a request to url with the header and body b, if any, and
+// reads its response with read if its status is OK. Requests failing
+// with transient errors are retried, with exponential backoff, up to
+// the number of retries of hs, each attempt being given the timeout
+// of hs, if any.
+func (hs *httpSource) fetch(ctx context.Context, method, url string, header http.Header, b []byte, read func(*http.Response) error) error {
+	delay := hs.backoff
+	if delay <= 0 {
+		delay = defaultBackoff
+	}
+	for attempt := 1; ; attempt++ {
+		err := hs.try(ctx, method, url, header, b, read)
+		if err == nil || !transient(ctx, err) {
+			return err
+		}
+		if attempt > hs.retries {
+			return &UnreachableError{URL: url, Attempts: attempt, Err: err}
+		}
+		govulncheck.MetricsOf(ctx).Add(govulncheck.MetricDBRetries, 1)
+		govulncheck.Logger(ctx).Info("retrying request", "method", method, "url", url, "attempt", attempt, "delay", delay, "error", err)
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		delay = min(2*delay, maxBackoff)
+	}
+}
+
+// try makes a single attempt of a request of fetch.
+func (hs *httpSource) try(ctx context.Context, method, url string, header http.Header, b []byte, read func(*http.Response) error) (err error) {
+	release, err := hs.limiter.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if hs.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hs.timeout)
+		defer cancel()
+	}
+	var body io.Reader
+	if b != nil {
+		body = bytes.NewReader(b)
+	}
+	metrics := govulncheck.MetricsOf(ctx)
+	metrics.Add(govulncheck.MetricDBRequests, 1)
+	start := time.Now()
+	defer func() {
+		metrics.Observe(govulncheck.MetricDBRequestDuration, time.Since(start))
+		if err != nil {
+			metrics.Add(govulncheck.MetricDBErrors, 1)
+		}
+	}()
+	resp, err := hs.do(ctx, method, url, header, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	govulncheck.Logger(ctx).Debug("request", "method", method, "url", url, "status", resp.StatusCode, "duration", time.Since(start))
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{method: method, url: url, status: resp.Status, code: resp.StatusCode}
+	}
+	return read(resp)
+}
+
+// transient reports whether the error err of a request made
+// with ctx might not happen again if the request is retried.
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		// The caller gave up, as opposed to an attempt timing out.
+		return false
+	}
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code == http.StatusTooManyRequests || se.code >= 500
+	}
+	// Errors such as those of TLS handshakes are not retried.
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	var de *net.DNSError
+	return errors.As(err, &de) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,149 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+)
+
+// newFlakyServer returns a server of the database in dir that fails
+// the first n requests of each method and endpoint with the handler fail.
+func newFlakyServer(dir string, n int, fail http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	var (
+		failed atomic.Int32
+		mu     sync.Mutex
+		seen   = make(map[string]int) // requests by method and path
+	)
+	files := http.FileServer(http.Dir(dir))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Method+" "+r.URL.Path]++
+		k := seen[r.Method+" "+r.URL.Path]
+		mu.Unlock()
+		if k <= n {
+			failed.Add(1)
+			fail(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	return srv, &failed
+}
+
+// countingMetrics records the counters of Metrics.
+type countingMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (m *countingMetrics) Add(name string, delta int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[name] += delta
+}
+
+func (m *countingMetrics) Observe(string, time.Duration) {}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	unavailable := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	t.Run("retried", func(t *testing.T) {
+		srv, failed := newFlakyServer(testVulndb, 2, unavailable)
+		t.Cleanup(srv.Close)
+		c, err := NewClient(srv.URL, &Options{Retries: 2, Backoff: time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.LastModifiedTime(ctx); err != nil {
+			t.Fatal(err)
+		}
+		// The HEAD request of NewClient and the
+		// request of LastModifiedTime each failed twice.
+		if got, want := failed.Load(), int32(4); got != want {
+			t.Errorf("failed requests = %d, want %d", got, want)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		srv, _ := newFlakyServer(testVulndb, 100, unavailable)
+		t.Cleanup(srv.Close)
+		hs, err := newHTTPSource(srv.URL, &Options{Retries: 2, Backoff: time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &countingMetrics{counts: make(map[string]int64)}
+		_, err = hs.get(govulncheck.WithMetrics(ctx, m), "index/db")
+		var ue *UnreachableError
+		if !errors.As(err, &ue) {
+			t.Fatalf("get() error = %v, want an *UnreachableError", err)
+		}
+		if ue.Attempts != 3 {
+			t.Errorf("attempts = %d, want 3", ue.Attempts)
+		}
+		for name, want := range map[string]int64{
+			govulncheck.MetricDBRequests: 3,
+			govulncheck.MetricDBRetries:  2,
+			govulncheck.MetricDBErrors:   3,
+		} {
+			if got := m.counts[name]; got != want {
+				t.Errorf("%s = %d, want %d", name, got, want)
+			}
+		}
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+		_, err := NewClient(srv.URL, &Options{Retries: 1, Backoff: time.Millisecond})
+		var ue *UnreachableError
+		if !errors.As(err, &ue) {
+			t.Fatalf("NewClient() error = %v, want an *UnreachableError", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		srv, _ := newFlakyServer(testVulndb, 0, nil)
+		t.Cleanup(srv.Close)
+		hs, err := newHTTPSource(srv.URL, &Options{Retries: 2, Backoff: time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = hs.get(ctx, "ID/GO-0000-0000")
+		var ue *UnreachableError
+		if err == nil || errors.As(err, &ue) {
+			t.Errorf("get() error = %v, want a status error", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		slow := func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * time.Second):
+			}
+		}
+		srv, _ := newFlakyServer(testVulndb, 1, slow)
+		t.Cleanup(srv.Close)
+		hs, err := newHTTPSource(srv.URL, &Options{Retries: 1, Backoff: time.Millisecond, Timeout: 100 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := hs.get(ctx, "index/db"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
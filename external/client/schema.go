@@ -6,7 +6,10 @@ package client
 
 import (
 	"encoding/json"
+	"fmt"
 	"path"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"time"
 )
@@ -25,6 +28,20 @@ func entryEndpoint(id string) string {
 	return path.Join(idDir, id)
 }
 
+// idPattern matches the IDs of OSV entries, a prefix naming the
+// database followed by an identifier, as in GO-2023-0001.
+var idPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*-[A-Za-z0-9._:-]+$`)
+
+// checkID returns an error unless id is the ID of an OSV entry that
+// names a single file of the ID directory, so that the entries of a
+// database cannot be read or written outside of it.
+func checkID(id string) error {
+	if !idPattern.MatchString(id) || !filepath.IsLocal(id) || filepath.Base(id) != id {
+		return fmt.Errorf("invalid entry ID %q", id)
+	}
+	return nil
+}
+
 // dbMeta contains metadata about the database itself.
 type dbMeta struct {
 	// Modified is the time the database was last modified, calculated
@@ -0,0 +1,91 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// NewHandler returns a handler serving the database in the directory
+// dir, in the v1 layout, as an http(s) database. The directory can be
+// a mirror written by Mirror, or a directory of the cache of a client,
+// whose endpoints are only stored as JSON: their gzipped JSON is then
+// compressed when requested.
+//
+// Only the endpoints, and their signatures, are served.
+func NewHandler(dir string) (http.Handler, error) {
+	if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(dbEndpoint)+".json")); err != nil {
+		return nil, fmt.Errorf("%s is not a database in the v1 layout: %w", dir, err)
+	}
+	return &handler{fsys: os.DirFS(dir)}, nil
+}
+
+type handler struct {
+	fsys fs.FS
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	var contentType string
+	switch {
+	case strings.HasSuffix(name, ".json.gz"):
+		contentType = "application/gzip"
+	case strings.HasSuffix(name, ".json"):
+		contentType = "application/json"
+	case strings.HasSuffix(name, ".json.minisig"):
+		contentType = "text/plain; charset=utf-8"
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	b, modTime, err := h.read(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, name, modTime, bytes.NewReader(b))
+}
+
+// read returns the contents of the file name and its modification time.
+// Missing gzipped JSON files are compressed from their JSON file.
+func (h *handler) read(name string) ([]byte, time.Time, error) {
+	fi, err := fs.Stat(h.fsys, name)
+	if err == nil && fi.Mode().IsRegular() {
+		b, err := fs.ReadFile(h.fsys, name)
+		return b, fi.ModTime(), err
+	}
+	plain, ok := strings.CutSuffix(name, ".gz")
+	if !ok {
+		return nil, time.Time{}, fs.ErrNotExist
+	}
+	b, modTime, err := h.read(plain)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, time.Time{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, time.Time{}, err
+	}
+	return buf.Bytes(), modTime, nil
+}
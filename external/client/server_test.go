@@ -0,0 +1,99 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	lc, err := NewClient(localURL(testVulndb), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs := []*ModuleRequest{{Path: "github.com/beego/beego"}, {Path: "stdlib", Version: "go1.17"}}
+	want, err := lc.ByModules(ctx, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Serve both a mirror and a cache, which has no gzipped JSON.
+	mirror := t.TempDir()
+	if _, err := lc.Mirror(ctx, mirror); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := t.TempDir()
+	srv := newTestServer(testVulndb)
+	t.Cleanup(srv.Close)
+	cc, err := NewClient(srv.URL, &Options{HTTPClient: srv.Client(), CacheDir: cacheDir, CacheTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cc.ByModules(ctx, reqs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cc.LastModifiedTime(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cache := filepath.Join(cacheDir, url.PathEscape(srv.URL))
+	for _, dir := range []string{mirror, cache} {
+		h, err := NewHandler(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hs := httptest.NewServer(h)
+		t.Cleanup(hs.Close)
+		c, err := NewClient(hs.URL, &Options{HTTPClient: hs.Client()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.ByModules(ctx, reqs)
+		if err != nil {
+			t.Fatalf("%s: %v", dir, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%s: ByModules mismatch (-want, +got):\n%s", dir, diff)
+		}
+	}
+
+	// Other files and methods are rejected.
+	h, err := NewHandler(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/index/db.json.validators", http.StatusNotFound},
+		{http.MethodGet, "/", http.StatusNotFound},
+		{http.MethodGet, "/../client.go", http.StatusNotFound},
+		{http.MethodPost, "/index/db.json", http.StatusMethodNotAllowed},
+		{http.MethodHead, "/index/db.json.gz", http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		if w.Code != tc.want {
+			t.Errorf("%s %s: got status %d, want %d", tc.method, tc.path, w.Code, tc.want)
+		}
+	}
+
+	if _, err := NewHandler(t.TempDir()); err == nil {
+		t.Error("NewHandler of an empty directory succeeded, want error")
+	}
+	if _, err := os.Stat(filepath.Join(cache, "index", "db.json.gz")); err == nil {
+		t.Error("the cache unexpectedly has gzipped JSON")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// A Rating is the severity and priority an organization
+// gives to a vulnerability, either of which may be empty.
+type Rating struct {
+	Severity string `json:"severity,omitempty"`
+	Priority string `json:"priority,omitempty"`
+}
+
+// UnmarshalJSON accepts either a Rating object
+// or a string, which is the severity alone.
+func (r *Rating) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte(`"`)) {
+		*r = Rating{}
+		return json.Unmarshal(b, &r.Severity)
+	}
+	type rating Rating // without this method
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*rating)(r))
+}
+
+// ParseRatings parses the JSON object b, which maps the IDs or aliases
+// of vulnerabilities, such as GO-2023-0001 or CVE-2023-1234, to their
+// ratings, given either as objects with "severity" and "priority"
+// fields or as strings, which are severities.
+func ParseRatings(b []byte) (map[string]Rating, error) {
+	var ratings map[string]Rating
+	if err := json.Unmarshal(b, &ratings); err != nil {
+		return nil, err
+	}
+	if ratings == nil {
+		return nil, errors.New("want a JSON object")
+	}
+	for id, r := range ratings {
+		if r == (Rating{}) {
+			return nil, fmt.Errorf("%s: empty rating", id)
+		}
+	}
+	return ratings, nil
+}
+
+// A RatingEnricher is an Enricher setting the severity and priority
+// of entries to those rated by an organization, overriding those of
+// the databases and of other enrichers, such as ratings derived from
+// CVSS scores. It is meant to run after them.
+type RatingEnricher struct {
+	ratings map[string]Rating
+	name    string
+}
+
+// NewRatingEnricher returns an Enricher setting the ratings of entries
+// to ratings by ID or alias, recording name as the source in their
+// DatabaseSpecific.Enrichment fields. Ratings by ID take precedence
+// over ratings by alias.
+func NewRatingEnricher(ratings map[string]Rating, name string) *RatingEnricher {
+	return &RatingEnricher{ratings: ratings, name: name}
+}
+
+// Enrich implements Enricher.
+func (r *RatingEnricher) Enrich(ctx context.Context, entries []*osv.Entry) error {
+	for _, e := range entries {
+		rating, ok := r.ratings[e.ID]
+		for _, a := range e.Aliases {
+			if ok {
+				break
+			}
+			rating, ok = r.ratings[a]
+		}
+		if !ok {
+			continue
+		}
+		ds := enrichment(e)
+		if rating.Severity != "" {
+			ds.Severity = rating.Severity
+		}
+		if rating.Priority != "" {
+			ds.Priority = rating.Priority
+		}
+		ds.Enrichment = append(ds.Enrichment, r.name)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRatingEnricher(t *testing.T) {
+	ratings, err := ParseRatings([]byte(`{
+		"GO-2024-0001": {"severity": "LOW", "priority": "P3"},
+		"CVE-2024-0002": "CRITICAL",
+		"GO-2024-0002": {"priority": "P1"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []*osv.Entry{
+		{ID: "GO-2024-0001", DatabaseSpecific: &osv.DatabaseSpecific{Severity: "HIGH", Enrichment: []string{"nvd:CVE-2024-0001"}}},
+		{ID: "GO-2024-0002", Aliases: []string{"CVE-2024-0002"}},
+		{ID: "GO-2024-0003", Aliases: []string{"CVE-2024-0002"}},
+		{ID: "GO-2024-0004"},
+	}
+	if err := NewRatingEnricher(ratings, "ratings:test").Enrich(context.Background(), entries); err != nil {
+		t.Fatal(err)
+	}
+	want := []*osv.DatabaseSpecific{
+		{Severity: "LOW", Priority: "P3", Enrichment: []string{"nvd:CVE-2024-0001", "ratings:test"}},
+		// The rating by ID takes precedence over that by alias.
+		{Priority: "P1", Enrichment: []string{"ratings:test"}},
+		{Severity: "CRITICAL", Enrichment: []string{"ratings:test"}},
+		nil,
+	}
+	for i, e := range entries {
+		if diff := cmp.Diff(want[i], e.DatabaseSpecific); diff != "" {
+			t.Errorf("%s: mismatch (-want, +got):\n%s", e.ID, diff)
+		}
+	}
+
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{`[]`, "cannot unmarshal array"},
+		{`null`, "want a JSON object"},
+		{`{"GO-2024-0001": {}}`, "GO-2024-0001: empty rating"},
+		{`{"GO-2024-0001": {"severty": "LOW"}}`, `unknown field "severty"`},
+	} {
+		if _, err := ParseRatings([]byte(tc.in)); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("ParseRatings(%s) = %v, want error containing %q", tc.in, err, tc.want)
+		}
+	}
+}
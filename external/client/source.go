@@ -7,13 +7,18 @@ package client
 import (
 	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/StevenACoffman/invuln/external/derrors"
 	"github.com/StevenACoffman/invuln/external/osv"
@@ -28,46 +33,167 @@ type source interface {
 	get(ctx context.Context, endpoint string) ([]byte, error)
 }
 
-func newHTTPSource(url string, opts *Options) *httpSource {
-	c := http.DefaultClient
-	if opts != nil && opts.HTTPClient != nil {
-		c = opts.HTTPClient
+func newHTTPSource(url string, opts *Options) (*httpSource, error) {
+	hs := &httpSource{url: url, c: opts.httpClient(), header: make(http.Header)}
+	if opts == nil {
+		hs.limiter = newLimiter(0, 0)
+		return hs, nil
 	}
-	return &httpSource{url: url, c: c}
+	hs.retries, hs.backoff, hs.timeout = opts.Retries, opts.Backoff, opts.Timeout
+	hs.limiter = newLimiter(opts.Concurrency, opts.RateLimit)
+	if url == publicDB {
+		// The public database requires no credentials, which are
+		// not disclosed to it when it is read with private ones.
+		return hs, nil
+	}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		c, err := withClientCert(hs.c, opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		hs.c = c
+	}
+	for k, vs := range opts.Header {
+		for _, v := range vs {
+			hs.header.Add(k, v)
+		}
+	}
+	switch {
+	case opts.BearerToken != "":
+		hs.header.Set("Authorization", "Bearer "+opts.BearerToken)
+	case opts.Username != "":
+		auth := opts.Username + ":" + opts.Password
+		hs.header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+	return hs, nil
+}
+
+// withClientCert returns a copy of c presenting the
+// certificate in certFile with the key in keyFile.
+func withClientCert(c *http.Client, certFile, keyFile string) (*http.Client, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading client certificate: %w", err)
+	}
+	rt := c.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("client certificates require an *http.Transport, got %T", rt)
+	}
+	t = t.Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.Certificates = append(t.TLSClientConfig.Certificates, cert)
+	cc := *c
+	cc.Transport = t
+	return &cc, nil
 }
 
 // httpSource reads a vulnerability database from an http(s) source.
 type httpSource struct {
-	url string
-	c   *http.Client
+	url    string
+	c      *http.Client
+	header http.Header // added to each request, including authentication
+
+	// retries, backoff, and timeout configure
+	// the attempts of each request; see fetch.
+	retries int
+	backoff time.Duration
+	timeout time.Duration
+
+	// limiter bounds the requests in flight and their rate.
+	limiter *limiter
 }
 
-func (hs *httpSource) get(ctx context.Context, endpoint string) (_ []byte, err error) {
+func (hs *httpSource) get(ctx context.Context, endpoint string) ([]byte, error) {
+	b, _, err := hs.getIfModified(ctx, endpoint, validators{})
+	return b, err
+}
+
+// getIfModified is get, unless the endpoint has not changed since the
+// response with the validators v, in which case it returns an error
+// matching errNotModified. It also returns the validators of the response.
+func (hs *httpSource) getIfModified(ctx context.Context, endpoint string, v validators) (_ []byte, _ validators, err error) {
 	derrors.Wrap(&err, "get(%s)", endpoint)
 
-	method := http.MethodGet
 	reqURL := fmt.Sprintf("%s/%s", hs.url, endpoint+".json.gz")
-	req, err := http.NewRequestWithContext(ctx, method, reqURL, nil)
-	if err != nil {
-		return nil, err
+	header := make(http.Header)
+	if v.ETag != "" {
+		header.Set("If-None-Match", v.ETag)
 	}
-	resp, err := hs.c.Do(req)
-	if err != nil {
-		return nil, err
+	if v.LastModified != "" {
+		header.Set("If-Modified-Since", v.LastModified)
+	}
+	var (
+		b  []byte
+		nv validators
+	)
+	err = hs.fetch(ctx, http.MethodGet, reqURL, header, nil, func(resp *http.Response) error {
+		nv = validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+		// Uncompress the result.
+		r, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		b, err = io.ReadAll(r)
+		return err
+	})
+	var se *statusError
+	if errors.As(err, &se) && se.code == http.StatusNotModified {
+		return nil, v, errNotModified
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %s %s returned unexpected status: %s", method, reqURL, resp.Status)
+	return b, nv, err
+}
+
+func (hs *httpSource) signature(ctx context.Context, endpoint string) (_ []byte, err error) {
+	derrors.Wrap(&err, "signature(%s)", endpoint)
+
+	var b []byte
+	err = hs.fetch(ctx, http.MethodGet, hs.url+"/"+endpoint+".json.minisig", nil, nil, func(resp *http.Response) error {
+		b, err = io.ReadAll(resp.Body)
+		return err
+	})
+	return b, err
+}
+
+// exists reports whether the file at endpoint, with its extension,
+// can be read. It only errors if the source is unreachable, or ctx
+// is done.
+func (hs *httpSource) exists(ctx context.Context, endpoint string) (bool, error) {
+	err := hs.fetch(ctx, http.MethodHead, hs.url+"/"+endpoint, nil, nil, func(*http.Response) error {
+		return nil
+	})
+	var ue *UnreachableError
+	if errors.As(err, &ue) {
+		return false, err
+	}
+	if err := ctx.Err(); err != nil {
+		return false, err
 	}
+	return err == nil, nil
+}
 
-	// Uncompress the result.
-	r, err := gzip.NewReader(resp.Body)
+func (hs *httpSource) do(ctx context.Context, method, url string, header http.Header, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
-	defer r.Close()
-
-	return io.ReadAll(r)
+	if body != nil {
+		// Only the OSV API is sent requests with a body.
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, vs := range hs.header {
+		req.Header[k] = vs
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	return hs.c.Do(req)
 }
 
 func newLocalSource(dir string) *localSource {
@@ -85,6 +211,12 @@ func (ls *localSource) get(ctx context.Context, endpoint string) (_ []byte, err
 	return fs.ReadFile(ls.fs, endpoint+".json")
 }
 
+func (ls *localSource) signature(ctx context.Context, endpoint string) (_ []byte, err error) {
+	derrors.Wrap(&err, "signature(%s)", endpoint)
+
+	return fs.ReadFile(ls.fs, endpoint+".json.minisig")
+}
+
 func newHybridSource(dir string) (*hybridSource, error) {
 	index, err := indexFromDir(dir)
 	if err != nil {
@@ -148,3 +280,33 @@ func (db *inMemorySource) get(ctx context.Context, endpoint string) ([]byte, err
 	}
 	return b, nil
 }
+
+// memoSource caches the responses of another source in memory.
+// Failed requests are not cached.
+type memoSource struct {
+	src source
+
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func newMemoSource(src source) *memoSource {
+	return &memoSource{src: src, data: make(map[string][]byte)}
+}
+
+func (ms *memoSource) get(ctx context.Context, endpoint string) ([]byte, error) {
+	ms.mu.Lock()
+	b, ok := ms.data[endpoint]
+	ms.mu.Unlock()
+	if ok {
+		return b, nil
+	}
+	b, err := ms.src.get(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	ms.mu.Lock()
+	ms.data[endpoint] = b
+	ms.mu.Unlock()
+	return b, nil
+}
@@ -46,7 +46,10 @@ func TestGet(t *testing.T) {
 func testAllSourceTypes(t *testing.T, test func(t *testing.T, s source)) {
 	t.Run("http", func(t *testing.T) {
 		srv := newTestServer(testVulndb)
-		hs := newHTTPSource(srv.URL, &Options{HTTPClient: srv.Client()})
+		hs, err := newHTTPSource(srv.URL, &Options{HTTPClient: srv.Client()})
+		if err != nil {
+			t.Fatal(err)
+		}
 		test(t, hs)
 	})
 
@@ -68,6 +71,10 @@ func testAllSourceTypes(t *testing.T, test func(t *testing.T, s source)) {
 		test(t, ms)
 	})
 
+	t.Run("memo", func(t *testing.T) {
+		test(t, newMemoSource(newLocalSource(testVulndb)))
+	})
+
 	t.Run("hybrid", func(t *testing.T) {
 		hs, err := newHybridSource(testFlatVulndb)
 		if err != nil {
@@ -77,3 +84,32 @@ func testAllSourceTypes(t *testing.T, test func(t *testing.T, s source)) {
 		test(t, hs)
 	})
 }
+
+type countingSource struct {
+	source
+	calls int
+}
+
+func (cs *countingSource) get(ctx context.Context, endpoint string) ([]byte, error) {
+	cs.calls++
+	return cs.source.get(ctx, endpoint)
+}
+
+func TestMemoSource(t *testing.T) {
+	cs := &countingSource{source: newLocalSource(testVulndb)}
+	ms := newMemoSource(cs)
+	for i := 0; i < 3; i++ {
+		if _, err := ms.get(context.Background(), "index/db"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Failures are not cached.
+	for i := 0; i < 2; i++ {
+		if _, err := ms.get(context.Background(), "index/missing"); err == nil {
+			t.Fatal("want error for missing endpoint")
+		}
+	}
+	if cs.calls != 3 {
+		t.Errorf("got %d calls to the underlying source, want 3", cs.calls)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/StevenACoffman/invuln/external/minisign"
+)
+
+// A VerificationError is returned when an endpoint of a database
+// read with Options.PublicKeys has no valid signature by those keys.
+type VerificationError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("verifying the signature of %s: %v", e.Endpoint, e.Err)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// A signedSource is a source that can also read the detached
+// minisign signatures of the uncompressed JSON of its endpoints.
+type signedSource interface {
+	source
+	// signature returns the contents of the .minisig file of endpoint.
+	signature(ctx context.Context, endpoint string) ([]byte, error)
+}
+
+// verifyingSource reads the endpoints of another source, only
+// returning those signed by one of its keys.
+type verifyingSource struct {
+	src  signedSource
+	keys []*minisign.PublicKey
+
+	mu   sync.Mutex
+	sigs map[string][]byte // verified signatures by endpoint
+}
+
+// verify returns a client reading the database of c, whose endpoints
+// must be signed by one of keys.
+func verify(c *Client, keys []*minisign.PublicKey) (*Client, error) {
+	src, ok := c.source.(signedSource)
+	if !ok {
+		return nil, fmt.Errorf("signatures can only be verified for databases following the v1 schema")
+	}
+	return &Client{name: c.name, source: &verifyingSource{src: src, keys: keys, sigs: make(map[string][]byte)}}, nil
+}
+
+func (vs *verifyingSource) get(ctx context.Context, endpoint string) ([]byte, error) {
+	b, err := vs.src.get(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := vs.src.signature(ctx, endpoint)
+	if err != nil {
+		return nil, &VerificationError{Endpoint: endpoint, Err: err}
+	}
+	if _, err := minisign.Verify(vs.keys, b, sig); err != nil {
+		return nil, &VerificationError{Endpoint: endpoint, Err: err}
+	}
+	vs.mu.Lock()
+	vs.sigs[endpoint] = sig
+	vs.mu.Unlock()
+	return b, nil
+}
+
+// signatureOf returns the verified signature of endpoint, if the
+// client verifies signatures and has read endpoint.
+func (c *Client) signatureOf(endpoint string) []byte {
+	vs, ok := c.source.(*verifyingSource)
+	if !ok {
+		return nil
+	}
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	return vs.sigs[endpoint]
+}
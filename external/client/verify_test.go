@@ -0,0 +1,120 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/minisign"
+	"github.com/google/go-cmp/cmp"
+)
+
+// signedDB returns a copy of the test database in which
+// each endpoint is signed with a new key, and that key.
+func signedDB(t *testing.T) (string, *minisign.PublicKey) {
+	t.Helper()
+	c, err := NewClient(localURL(testVulndb), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if _, err := c.Mirror(context.Background(), dir); err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path+".minisig", minisign.Sign(priv, id, b, "test"), 0o644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, minisign.NewPublicKey(id, pub)
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	dir, key := signedDB(t)
+	reqs := []*ModuleRequest{{Path: "github.com/beego/beego"}, {Path: "stdlib", Version: "go1.17"}}
+	uc, err := NewClient(localURL(testVulndb), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := uc.ByModules(ctx, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := newTestServer(dir)
+	t.Cleanup(srv.Close)
+	opts := &Options{
+		HTTPClient: srv.Client(),
+		PublicKeys: []*minisign.PublicKey{key},
+		CacheDir:   t.TempDir(),
+		CacheTTL:   time.Hour,
+	}
+	hc, err := NewClient(srv.URL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A mirror made by a verifying client is signed too.
+	mirror := t.TempDir()
+	if _, err := hc.Mirror(ctx, mirror); err != nil {
+		t.Fatal(err)
+	}
+	for _, source := range []string{localURL(dir), srv.URL, localURL(mirror)} {
+		c, err := NewClient(source, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := c.ByModules(ctx, reqs)
+		if err != nil {
+			t.Fatalf("%s: %v", source, err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%s: ByModules mismatch (-want, +got):\n%s", source, diff)
+		}
+	}
+
+	// Tampered and unsigned endpoints are rejected.
+	entry := filepath.Join(dir, idDir, "GO-2021-0068.json")
+	if err := os.WriteFile(entry, []byte(`{"id":"GO-2021-0068"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, dbEndpoint+".json.minisig")); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewClient(localURL(dir), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []func() error{
+		func() error { _, err := c.ByModules(ctx, []*ModuleRequest{{Path: "toolchain"}}); return err },
+		func() error { _, err := c.LastModifiedTime(ctx); return err },
+	} {
+		var ve *VerificationError
+		if err := f(); !errors.As(err, &ve) {
+			t.Errorf("got error %v, want a *VerificationError", err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+
+	"github.com/StevenACoffman/invuln/external/derrors"
+)
+
+// vulnsEndpoint is the index of all the entries of a database. It is
+// not read by clients, but is part of the database layout.
+var vulnsEndpoint = path.Join(indexDir, "vulns")
+
+// newZipClient returns a client reading the database in the zip
+// archive at file, which holds the endpoints of the v1 layout as
+// uncompressed JSON files at its root, as written by WriteZip.
+func newZipClient(file string) (*Client, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	// The archive is read for as long as the client
+	// is used, so it is left open.
+	if _, err := fs.Stat(r, modulesEndpoint+".json"); err != nil {
+		r.Close()
+		return nil, errUnknownSchema
+	}
+	return &Client{source: &localSource{fs: r}}, nil
+}
+
+// WriteZip writes a snapshot of the database of c to w as a zip archive,
+// which NewClient reads from a "file" URL to the archive. The snapshot
+// holds the endpoints of the database as uncompressed JSON files, and
+// their signatures if c verifies them.
+//
+// It is not supported for clients reading several databases.
+func (c *Client) WriteZip(ctx context.Context, w io.Writer) (err error) {
+	derrors.Wrap(&err, "WriteZip")
+
+	if len(c.merged) > 0 {
+		return errors.New("cannot snapshot several databases")
+	}
+	if c.osvAPI != nil {
+		return errors.New("cannot snapshot the OSV API")
+	}
+	if c.enriched != nil {
+		// The database is copied as is, without enrichment.
+		return c.enriched.WriteZip(ctx, w)
+	}
+	_, ids, err := c.modulesIndex(ctx)
+	if err != nil {
+		return err
+	}
+	endpoints := []string{dbEndpoint, modulesEndpoint}
+	for id := range ids {
+		endpoints = append(endpoints, entryEndpoint(id))
+	}
+	sort.Strings(endpoints[2:])
+	data, err := c.getAll(ctx, endpoints)
+	if err != nil {
+		return err
+	}
+	// The vulns index is not needed to read the snapshot,
+	// so databases without one are still written.
+	if b, err := c.source.get(ctx, vulnsEndpoint); err == nil {
+		endpoints = append(endpoints, vulnsEndpoint)
+		data = append(data, b)
+	}
+
+	zw := zip.NewWriter(w)
+	for i, e := range endpoints {
+		f, err := zw.Create(e + ".json")
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data[i]); err != nil {
+			return err
+		}
+		if sig := c.signatureOf(e); sig != nil {
+			f, err := zw.Create(e + ".json.minisig")
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(sig); err != nil {
+				return err
+			}
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteZip(t *testing.T) {
+	ctx := context.Background()
+	srv := newTestServer(testVulndb)
+	t.Cleanup(srv.Close)
+	hc, err := NewClient(srv.URL, &Options{HTTPClient: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := hc.WriteZip(ctx, &buf); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "vulndb.zip")
+	if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	zc, err := NewClient(localURL(file), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc, err := NewClient(testVulndbFileURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, endpoint := range []string{dbEndpoint, modulesEndpoint, vulnsEndpoint, entryEndpoint("GO-2021-0068")} {
+		got, err := zc.source.get(ctx, endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := lc.source.get(ctx, endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: got %s; want %s", endpoint, got, want)
+		}
+	}
+	reqs := []*ModuleRequest{{Path: "github.com/beego/beego"}, {Path: "stdlib", Version: "go1.17"}}
+	got, err := zc.ByModules(ctx, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := lc.ByModules(ctx, reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ByModules mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Archives without a v1 database are rejected.
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClient(localURL(file), nil); err == nil {
+		t.Error("NewClient of an invalid archive succeeded; want error")
+	}
+}
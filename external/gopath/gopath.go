@@ -0,0 +1,172 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gopath derives module-like information for code built in
+// GOPATH mode, which has no go.mod files recording the versions of
+// dependencies. Versions are taken from the manifests of the vendoring
+// tools that predate modules and from version control tags.
+package gopath
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// A Project is a repository providing packages,
+// the GOPATH mode equivalent of a module.
+type Project struct {
+	Path string
+	// Version is the semantic version of the project,
+	// or "" if it is not known.
+	Version string
+}
+
+// A Resolver maps packages to the projects providing them.
+type Resolver struct {
+	gopath    []string
+	manifests map[string][]Project // by vendoring directory
+	vcs       map[string]Project   // by repository root directory
+}
+
+// NewResolver returns a Resolver for packages
+// in the given GOPATH directories.
+func NewResolver(gopath []string) *Resolver {
+	return &Resolver{
+		gopath:    gopath,
+		manifests: make(map[string][]Project),
+		vcs:       make(map[string]Project),
+	}
+}
+
+// Resolve returns the project providing the package with import path
+// pkgPath in directory dir.
+//
+// A vendored package, with a path of the form p/vendor/q, belongs to
+// the project listed for q in the vendoring manifest of p, if any.
+// Other packages belong to the version control repository containing
+// them, which is versioned by a tag on its checked out revision.
+func (r *Resolver) Resolve(pkgPath, dir string) Project {
+	if i := strings.LastIndex(pkgPath, "/vendor/"); i >= 0 {
+		vendored := pkgPath[i+len("/vendor/"):]
+		// The vendoring project is the directory containing
+		// the vendor directory the package is in.
+		vdir := dir
+		for range strings.Split(vendored, "/") {
+			vdir = filepath.Dir(vdir)
+		}
+		vdir = filepath.Dir(vdir)
+		return r.vendored(vendored, vdir)
+	}
+	for _, gp := range r.gopath {
+		src := filepath.Join(gp, "src")
+		if rel, err := filepath.Rel(src, dir); err == nil && !strings.HasPrefix(rel, "..") {
+			if root := vcsRoot(dir, src); root != "" {
+				return r.repository(root, src)
+			}
+		}
+	}
+	return Project{Path: repoPath(pkgPath)}
+}
+
+// vendored returns the project providing the package with import
+// path pkgPath vendored in the vendor directory of dir.
+func (r *Resolver) vendored(pkgPath, dir string) Project {
+	projects, ok := r.manifests[dir]
+	if !ok {
+		projects = readManifests(dir)
+		r.manifests[dir] = projects
+	}
+	best := Project{Path: repoPath(pkgPath)}
+	found := false
+	for _, p := range projects {
+		if (pkgPath == p.Path || strings.HasPrefix(pkgPath, p.Path+"/")) && (!found || len(p.Path) > len(best.Path)) {
+			best, found = p, true
+		}
+	}
+	return best
+}
+
+// repository returns the project of the repository at root
+// in the GOPATH source directory src.
+func (r *Resolver) repository(root, src string) Project {
+	if p, ok := r.vcs[root]; ok {
+		return p
+	}
+	rel, _ := filepath.Rel(src, root)
+	p := Project{Path: filepath.ToSlash(rel), Version: gitVersion(root)}
+	r.vcs[root] = p
+	return p
+}
+
+// vcsRoot returns the innermost directory containing dir, but not
+// above src, that is the root of a version control repository.
+func vcsRoot(dir, src string) string {
+	for d := dir; d != src && strings.HasPrefix(d, src); d = filepath.Dir(d) {
+		for _, vcs := range []string{".git", ".hg", ".svn", ".bzr"} {
+			if _, err := os.Stat(filepath.Join(d, vcs)); err == nil {
+				return d
+			}
+		}
+	}
+	return ""
+}
+
+// gitVersion returns the semantic version of the tag of the
+// revision checked out in the git repository at root, if any.
+func gitVersion(root string) string {
+	if _, err := os.Stat(filepath.Join(root, ".git")); err != nil {
+		return ""
+	}
+	cmd := exec.Command("git", "tag", "--points-at", "HEAD")
+	cmd.Dir = root
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	var best string
+	for _, tag := range strings.Fields(string(out)) {
+		if v := CanonicalVersion(tag); v != "" && (best == "" || semver.Compare(v, best) > 0) {
+			best = v
+		}
+	}
+	return best
+}
+
+// CanonicalVersion returns v as a canonical semantic version,
+// adding the "v" prefix commonly omitted by vendoring tools,
+// or "" if v is not a semantic version.
+func CanonicalVersion(v string) string {
+	if !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	}
+	if !semver.IsValid(v) {
+		return ""
+	}
+	return semver.Canonical(v)
+}
+
+// repoPath guesses the path of the repository providing
+// the package with import path pkgPath, for hosts whose
+// repositories are named by three path elements.
+func repoPath(pkgPath string) string {
+	elems := strings.Split(pkgPath, "/")
+	switch elems[0] {
+	case "github.com", "gitlab.com", "bitbucket.org", "golang.org", "go.googlesource.com":
+		if len(elems) >= 3 {
+			return strings.Join(elems[:3], "/")
+		}
+	case "gopkg.in":
+		// gopkg.in/pkg.v1 and gopkg.in/user/pkg.v1
+		for i, e := range elems {
+			if i > 0 && strings.Contains(e, ".v") {
+				return strings.Join(elems[:i+1], "/")
+			}
+		}
+	}
+	return pkgPath
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopath
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestManifests(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		parse func([]byte) []Project
+		data  string
+		want  []Project
+	}{
+		{"Gopkg.lock", parseGopkgLock, `
+[[projects]]
+  digest = "1:abc"
+  name = "github.com/pkg/errors"
+  packages = ["."]
+  revision = "ba968bfe8b2f7e042a574c888954fccecfa385b4"
+  version = "v0.8.1"
+
+[[projects]]
+  branch = "master"
+  name = "golang.org/x/net"
+  packages = ["http2"]
+  revision = "a1b2c3"
+
+[solve-meta]
+  inputs-digest = "xyz"
+`, []Project{{"github.com/pkg/errors", "v0.8.1"}, {"golang.org/x/net", ""}}},
+		{"glide.lock", parseGlideLock, `
+hash: abc
+imports:
+- name: github.com/pkg/errors
+  version: 0.8.1
+- name: golang.org/x/net
+  version: a1b2c3d4e5f6
+  subpackages:
+  - http2
+`, []Project{{"github.com/pkg/errors", "v0.8.1"}, {"golang.org/x/net", ""}}},
+		{"Godeps.json", parseGodeps, `{
+	"ImportPath": "example.com/app",
+	"Deps": [
+		{"ImportPath": "github.com/pkg/errors", "Comment": "v0.8.1", "Rev": "ba968bf"},
+		{"ImportPath": "github.com/gorilla/mux", "Comment": "v1.6.2-3-g1a2b3c4", "Rev": "1a2b3c4"}
+	]
+}`, []Project{{"github.com/pkg/errors", "v0.8.1"}, {"github.com/gorilla/mux", ""}}},
+		{"vendor.json", parseVendorJSON, `{
+	"package": [
+		{"path": "github.com/pkg/errors", "revision": "ba968bf", "version": "v0.8", "versionExact": "v0.8.1"},
+		{"path": "golang.org/x/net/http2", "revision": "a1b2c3"}
+	]
+}`, []Project{{"github.com/pkg/errors", "v0.8.1"}, {"golang.org/x/net/http2", ""}}},
+		{"modules.txt", parseModulesTxt, `
+# github.com/pkg/errors v0.8.1
+## explicit
+github.com/pkg/errors
+# golang.org/x/net v0.0.0-20190620200207-3b0461eec859
+golang.org/x/net/http2
+`, []Project{{"github.com/pkg/errors", "v0.8.1"}, {"golang.org/x/net", "v0.0.0-20190620200207-3b0461eec859"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, tt.parse([]byte(tt.data))); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolveVendored(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	app := filepath.Join(src, "example.com", "app")
+	if err := os.MkdirAll(app, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	lock := `
+[[projects]]
+  name = "github.com/pkg/errors"
+  version = "v0.8.1"
+`
+	if err := os.WriteFile(filepath.Join(app, "Gopkg.lock"), []byte(lock), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewResolver([]string{filepath.Dir(src)})
+	for _, tt := range []struct {
+		pkgPath string
+		want    Project
+	}{
+		{"example.com/app/vendor/github.com/pkg/errors", Project{"github.com/pkg/errors", "v0.8.1"}},
+		{"example.com/app/vendor/github.com/gorilla/mux/internal", Project{"github.com/gorilla/mux", ""}},
+		{"example.com/app/vendor/gopkg.in/yaml.v2", Project{"gopkg.in/yaml.v2", ""}},
+	} {
+		dir := filepath.Join(src, filepath.FromSlash(tt.pkgPath))
+		if got := r.Resolve(tt.pkgPath, dir); got != tt.want {
+			t.Errorf("Resolve(%q) = %v; want %v", tt.pkgPath, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalVersion(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"v1.2.3", "v1.2.3"},
+		{"1.2.3", "v1.2.3"},
+		{"v1.2", "v1.2.0"},
+		{"master", ""},
+		{"a1b2c3d4", ""},
+	} {
+		if got := CanonicalVersion(tt.in); got != tt.want {
+			t.Errorf("CanonicalVersion(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gopath
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// readManifests returns the projects listed in the manifests of the
+// vendoring tools found in dir. The supported manifests are those of
+// dep, glide, godep, govendor, and the vendor/modules.txt file of the
+// go command.
+func readManifests(dir string) []Project {
+	var projects []Project
+	for _, m := range []struct {
+		name  string
+		parse func([]byte) []Project
+	}{
+		{"Gopkg.lock", parseGopkgLock},
+		{"glide.lock", parseGlideLock},
+		{filepath.Join("Godeps", "Godeps.json"), parseGodeps},
+		{filepath.Join("vendor", "vendor.json"), parseVendorJSON},
+		{filepath.Join("vendor", "modules.txt"), parseModulesTxt},
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, m.name))
+		if err != nil {
+			continue
+		}
+		projects = append(projects, m.parse(data)...)
+	}
+	return projects
+}
+
+// parseGopkgLock parses the [[projects]] tables of a dep Gopkg.lock
+// file, which is TOML.
+func parseGopkgLock(data []byte) []Project {
+	var projects []Project
+	var cur *Project
+	flush := func() {
+		if cur != nil && cur.Path != "" {
+			projects = append(projects, *cur)
+		}
+		cur = nil
+	}
+	for _, line := range lines(data) {
+		if strings.HasPrefix(line, "[") {
+			flush()
+			if line == "[[projects]]" {
+				cur = &Project{}
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		v, err := strconv.Unquote(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "name":
+			cur.Path = v
+		case "version":
+			cur.Version = CanonicalVersion(v)
+		}
+	}
+	flush()
+	return projects
+}
+
+// parseGlideLock parses the imports of a glide.lock file, which
+// is YAML. Glide mostly records revisions rather than versions.
+func parseGlideLock(data []byte) []Project {
+	var projects []Project
+	for _, line := range lines(data) {
+		if name, ok := strings.CutPrefix(line, "- name:"); ok {
+			projects = append(projects, Project{Path: strings.TrimSpace(name)})
+		} else if v, ok := strings.CutPrefix(line, "version:"); ok && len(projects) > 0 {
+			projects[len(projects)-1].Version = CanonicalVersion(strings.Trim(strings.TrimSpace(v), `"'`))
+		}
+	}
+	return projects
+}
+
+// describeSuffix matches the suffix git describe adds
+// to a tag for revisions after the tagged one.
+var describeSuffix = regexp.MustCompile(`-\d+-g[0-9a-f]+$`)
+
+// parseGodeps parses a godep Godeps.json file. The comment of a
+// dependency is the output of git describe for its revision, which
+// is a version only for tagged revisions.
+func parseGodeps(data []byte) []Project {
+	var godeps struct {
+		Deps []struct {
+			ImportPath string
+			Comment    string
+		}
+	}
+	if err := json.Unmarshal(data, &godeps); err != nil {
+		return nil
+	}
+	var projects []Project
+	for _, d := range godeps.Deps {
+		p := Project{Path: d.ImportPath}
+		if !describeSuffix.MatchString(d.Comment) {
+			p.Version = CanonicalVersion(d.Comment)
+		}
+		projects = append(projects, p)
+	}
+	return projects
+}
+
+// parseVendorJSON parses a govendor vendor.json file. Only the exact
+// version of a dependency is used, as its version can be a range.
+func parseVendorJSON(data []byte) []Project {
+	var vendor struct {
+		Package []struct {
+			Path         string `json:"path"`
+			VersionExact string `json:"versionExact"`
+		} `json:"package"`
+	}
+	if err := json.Unmarshal(data, &vendor); err != nil {
+		return nil
+	}
+	var projects []Project
+	for _, p := range vendor.Package {
+		projects = append(projects, Project{Path: p.Path, Version: CanonicalVersion(p.VersionExact)})
+	}
+	return projects
+}
+
+// parseModulesTxt parses the module lines of a vendor/modules.txt file.
+func parseModulesTxt(data []byte) []Project {
+	var projects []Project
+	for _, line := range lines(data) {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "#" {
+			projects = append(projects, Project{Path: fields[1], Version: CanonicalVersion(fields[2])})
+		}
+	}
+	return projects
+}
+
+// lines returns the non-empty lines of data without surrounding spaces.
+func lines(data []byte) []string {
+	var ls []string
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		if l := strings.TrimSpace(s.Text()); l != "" {
+			ls = append(ls, l)
+		}
+	}
+	return ls
+}
@@ -5,6 +5,7 @@
 package gosym
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io"
 	"strings"
@@ -45,6 +46,10 @@ const (
 // goFuncValue is the value of the gosym.FuncSymName symbol.
 // baseAddr is the address of the memory region (ELF Prog) containing goFuncValue.
 // progReader is a ReaderAt positioned at the start of that region.
+//
+// The tree is read from progReader at once. Unlike other methods of
+// LineTable, InlineTree is safe for concurrent use, so that the trees of
+// the functions of large binaries can be read in parallel.
 func (t *LineTable) InlineTree(f *Func, goFuncValue, baseAddr uint64, progReader io.ReaderAt) ([]InlinedCall, error) {
 	if f.inlineTreeCount == 0 {
 		return nil, nil
@@ -59,34 +64,51 @@ func (t *LineTable) InlineTree(f *Func, goFuncValue, baseAddr uint64, progReader
 		offset = int64(uint64(f.inlineTreeOffset) - baseAddr)
 	}
 
-	r := io.NewSectionReader(progReader, offset, 1<<32) // pick a size larger than we need
+	size := binary.Size(rawInlinedCall112{})
+	if t.version >= ver120 {
+		size = binary.Size(rawInlinedCall120{})
+	}
+	data := make([]byte, size*f.inlineTreeCount)
+	if _, err := progReader.ReadAt(data, offset); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
 	ics := make([]InlinedCall, 0, f.inlineTreeCount)
-	for i := 0; i < f.inlineTreeCount; i++ {
+	for b := data; len(b) > 0; b = b[size:] {
 		if t.version >= ver120 {
-			var ric rawInlinedCall120
-			if err := binary.Read(r, t.binary, &ric); err != nil {
-				return nil, err
-			}
+			// The fields of rawInlinedCall120.
 			ics = append(ics, InlinedCall{
-				FuncID:   ric.FuncID,
-				Name:     t.funcName(uint32(ric.NameOff)),
-				ParentPC: ric.ParentPC,
+				FuncID:   b[0],
+				Name:     t.inlinedFuncName(t.binary.Uint32(b[4:])),
+				ParentPC: int32(t.binary.Uint32(b[8:])),
 			})
 		} else {
-			var ric rawInlinedCall112
-			if err := binary.Read(r, t.binary, &ric); err != nil {
-				return nil, err
-			}
+			// The fields of rawInlinedCall112.
 			ics = append(ics, InlinedCall{
-				FuncID:   ric.FuncID,
-				Name:     t.funcName(uint32(ric.Func_)),
-				ParentPC: ric.ParentPC,
+				FuncID:   b[2],
+				Name:     t.inlinedFuncName(t.binary.Uint32(b[12:])),
+				ParentPC: int32(t.binary.Uint32(b[16:])),
 			})
 		}
 	}
 	return ics, nil
 }
 
+// inlinedFuncName is like funcName, but it does not cache the
+// name, so that it is safe for concurrent use.
+func (t *LineTable) inlinedFuncName(off uint32) string {
+	if off >= uint32(len(t.funcnametab)) {
+		return ""
+	}
+	b := t.funcnametab[off:]
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
 // InlinedCall describes a call to an inlined function.
 type InlinedCall struct {
 	FuncID   uint8  // type of the called function
@@ -0,0 +1,186 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package govulncheck
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// Flush flushes h if it has a Flush method, as the handlers
+// writing output or buffering messages do, and otherwise does
+// nothing. Handlers wrapping others flush them when flushed.
+func Flush(h Handler) error {
+	if f, ok := h.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// FilterHandler returns a handler passing on to next the findings
+// for which keep returns true, and all other messages.
+func FilterHandler(keep func(*Finding) bool, next Handler) Handler {
+	return &filterHandler{Handler: next, keep: keep}
+}
+
+type filterHandler struct {
+	Handler
+	keep func(*Finding) bool
+}
+
+func (h *filterHandler) Finding(finding *Finding) error {
+	if !h.keep(finding) {
+		return nil
+	}
+	return h.Handler.Finding(finding)
+}
+
+func (h *filterHandler) Flush() error {
+	return Flush(h.Handler)
+}
+
+// TransformHandler returns a handler passing on to next the findings
+// returned by transform for the findings passed to it, and all other
+// messages. The findings for which transform returns nil are dropped.
+// Since the findings passed to transform may be shared with other
+// handlers, it should modify copies of them.
+func TransformHandler(transform func(*Finding) *Finding, next Handler) Handler {
+	return &transformHandler{Handler: next, transform: transform}
+}
+
+type transformHandler struct {
+	Handler
+	transform func(*Finding) *Finding
+}
+
+func (h *transformHandler) Finding(finding *Finding) error {
+	if f := h.transform(finding); f != nil {
+		return h.Handler.Finding(f)
+	}
+	return nil
+}
+
+func (h *transformHandler) Flush() error {
+	return Flush(h.Handler)
+}
+
+// TeeHandler returns a handler passing on all messages to each of
+// handlers in turn, stopping at the first error, such as to write
+// several output formats in one scan.
+func TeeHandler(handlers ...Handler) Handler {
+	return teeHandler(slices.Clone(handlers))
+}
+
+type teeHandler []Handler
+
+func (t teeHandler) each(f func(Handler) error) error {
+	for _, h := range t {
+		if err := f(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t teeHandler) Config(config *Config) error {
+	return t.each(func(h Handler) error { return h.Config(config) })
+}
+
+func (t teeHandler) SBOM(sbom *SBOM) error {
+	return t.each(func(h Handler) error { return h.SBOM(sbom) })
+}
+
+func (t teeHandler) Progress(progress *Progress) error {
+	return t.each(func(h Handler) error { return h.Progress(progress) })
+}
+
+func (t teeHandler) OSV(entry *osv.Entry) error {
+	return t.each(func(h Handler) error { return h.OSV(entry) })
+}
+
+func (t teeHandler) Finding(finding *Finding) error {
+	return t.each(func(h Handler) error { return h.Finding(finding) })
+}
+
+func (t teeHandler) Flush() error {
+	return t.each(Flush)
+}
+
+// SortingHandler returns a handler passing on all messages to next,
+// except that the findings are held until it is flushed, and then
+// passed on in the order of CompareFindings, followed by a flush of
+// next. Scans compute findings in stages, so the findings passed to
+// handlers are only ordered within each stage.
+func SortingHandler(next Handler) Handler {
+	return &sortingHandler{Handler: next}
+}
+
+type sortingHandler struct {
+	Handler
+	findings []*Finding
+}
+
+func (h *sortingHandler) Finding(finding *Finding) error {
+	h.findings = append(h.findings, finding)
+	return nil
+}
+
+func (h *sortingHandler) Flush() error {
+	slices.SortStableFunc(h.findings, CompareFindings)
+	for _, f := range h.findings {
+		if err := h.Handler.Finding(f); err != nil {
+			return err
+		}
+	}
+	h.findings = nil
+	return Flush(h.Handler)
+}
+
+// CompareFindings orders findings by vulnerability,
+// and then by their traces, starting from the vulnerable symbol.
+func CompareFindings(f1, f2 *Finding) int {
+	if c := strings.Compare(f1.OSV, f2.OSV); c != 0 {
+		return c
+	}
+	return CompareTraces(f1.Trace, f2.Trace)
+}
+
+// CompareTraces orders traces frame by frame, comparing the
+// module, version, package, symbol, and position of frames.
+func CompareTraces(t1, t2 []*Frame) int {
+	return slices.CompareFunc(t1, t2, func(fr1, fr2 *Frame) int {
+		return cmp.Or(
+			strings.Compare(fr1.Module, fr2.Module),
+			strings.Compare(fr1.Version, fr2.Version),
+			strings.Compare(fr1.Package, fr2.Package),
+			strings.Compare(fr1.Receiver, fr2.Receiver),
+			strings.Compare(fr1.Function, fr2.Function),
+			strings.Compare(fr1.TypeArgs, fr2.TypeArgs),
+			comparePositions(fr1.Position, fr2.Position),
+		)
+	})
+}
+
+// comparePositions orders positions by file and offset,
+// with missing positions first.
+func comparePositions(p1, p2 *Position) int {
+	switch {
+	case p1 == nil && p2 == nil:
+		return 0
+	case p1 == nil:
+		return -1
+	case p2 == nil:
+		return 1
+	}
+	return cmp.Or(
+		strings.Compare(p1.Filename, p2.Filename),
+		cmp.Compare(p1.Offset, p2.Offset),
+		cmp.Compare(p1.Line, p2.Line),
+		cmp.Compare(p1.Column, p2.Column),
+	)
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package govulncheck_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+)
+
+// flushCounter is a MockHandler counting its flushes.
+type flushCounter struct {
+	*test.MockHandler
+	flushes int
+}
+
+func (h *flushCounter) Flush() error {
+	h.flushes++
+	return nil
+}
+
+func newFlushCounter() *flushCounter {
+	return &flushCounter{MockHandler: test.NewMockHandler()}
+}
+
+// osvs returns the vulnerabilities of the findings of h.
+func osvs(h *flushCounter) []string {
+	var ids []string
+	for _, f := range h.FindingMessages {
+		ids = append(ids, f.OSV)
+	}
+	return ids
+}
+
+func TestChain(t *testing.T) {
+	frame := &govulncheck.Frame{Module: "m"}
+	findings := []*govulncheck.Finding{
+		{OSV: "GO-3", Trace: []*govulncheck.Frame{frame}},
+		{OSV: "GO-1", Trace: []*govulncheck.Frame{frame}},
+		{OSV: "GO-2", Trace: []*govulncheck.Frame{frame}},
+	}
+	all, some := newFlushCounter(), newFlushCounter()
+	h := govulncheck.TeeHandler(
+		govulncheck.SortingHandler(all),
+		govulncheck.FilterHandler(func(f *govulncheck.Finding) bool { return f.OSV != "GO-1" },
+			govulncheck.TransformHandler(func(f *govulncheck.Finding) *govulncheck.Finding {
+				if f.OSV == "GO-3" {
+					return nil
+				}
+				g := *f
+				g.FixedVersion = "v1.0.0"
+				return &g
+			}, some)),
+	)
+	if err := h.Config(&govulncheck.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range findings {
+		if err := h.Finding(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(all.FindingMessages) != 0 {
+		t.Errorf("got %d findings before flushing the sorting handler; want none", len(all.FindingMessages))
+	}
+	if err := govulncheck.Flush(h); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := osvs(all), []string{"GO-1", "GO-2", "GO-3"}; !slices.Equal(got, want) {
+		t.Errorf("got sorted findings %q; want %q", got, want)
+	}
+	if got, want := osvs(some), []string{"GO-2"}; !slices.Equal(got, want) {
+		t.Errorf("got filtered findings %q; want %q", got, want)
+	}
+	if some.FindingMessages[0].FixedVersion != "v1.0.0" || findings[2].FixedVersion != "" {
+		t.Errorf("got fixed versions %q and %q; want the transformed finding only to be fixed", some.FindingMessages[0].FixedVersion, findings[2].FixedVersion)
+	}
+	for _, h := range []*flushCounter{all, some} {
+		if len(h.ConfigMessages) != 1 || h.flushes != 1 {
+			t.Errorf("got %d config messages and %d flushes; want 1 each", len(h.ConfigMessages), h.flushes)
+		}
+	}
+}
+
+// failingHandler fails on every finding.
+type failingHandler struct{ *test.MockHandler }
+
+func (failingHandler) Finding(*govulncheck.Finding) error { return errors.New("failed") }
+
+func TestTeeHandlerError(t *testing.T) {
+	after := test.NewMockHandler()
+	h := govulncheck.TeeHandler(failingHandler{test.NewMockHandler()}, after)
+	if err := h.Finding(&govulncheck.Finding{OSV: "GO-1"}); err == nil {
+		t.Fatal("got no error; want the error of the first handler")
+	}
+	if len(after.FindingMessages) != 0 {
+		t.Errorf("got %d findings passed on after an error; want none", len(after.FindingMessages))
+	}
+}
+
+func TestCompareFindings(t *testing.T) {
+	frame := func(pkg, fn string, line int) *govulncheck.Frame {
+		fr := &govulncheck.Frame{Module: "m", Package: pkg, Function: fn}
+		if line > 0 {
+			fr.Position = &govulncheck.Position{Filename: "f.go", Line: line}
+		}
+		return fr
+	}
+	ordered := []*govulncheck.Finding{
+		{OSV: "GO-1", Trace: []*govulncheck.Frame{frame("p", "", 0)}},
+		{OSV: "GO-1", Trace: []*govulncheck.Frame{frame("p", "F", 0)}},
+		{OSV: "GO-1", Trace: []*govulncheck.Frame{frame("p", "F", 1)}},
+		{OSV: "GO-1", Trace: []*govulncheck.Frame{frame("p", "F", 1), frame("q", "G", 2)}},
+		{OSV: "GO-1", Trace: []*govulncheck.Frame{frame("p", "F", 1), frame("q", "G", 3)}},
+		{OSV: "GO-1", Trace: []*govulncheck.Frame{frame("q", "F", 1)}},
+		{OSV: "GO-2", Trace: []*govulncheck.Frame{frame("p", "", 0)}},
+	}
+	for i := range ordered {
+		for j := range ordered {
+			got := govulncheck.CompareFindings(ordered[i], ordered[j])
+			var want int
+			switch {
+			case i < j:
+				want = -1
+			case i > j:
+				want = 1
+			}
+			if got != want {
+				t.Errorf("CompareFindings(%d, %d) = %d; want %d", i, j, got, want)
+			}
+		}
+	}
+}
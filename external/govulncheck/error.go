@@ -0,0 +1,86 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package govulncheck
+
+// An ErrorCode classifies the failure of a scan, so that programs
+// running govulncheck can tell failures apart without matching
+// their messages, which may change.
+type ErrorCode string
+
+const (
+	// ErrorUsage is a failure due to invalid flags or arguments.
+	ErrorUsage ErrorCode = "usage"
+
+	// ErrorNoGoMod is the failure of a source scan of a directory
+	// not in a module.
+	ErrorNoGoMod ErrorCode = "no_go_mod"
+
+	// ErrorNoPackages is the failure of a source scan whose
+	// patterns matched no packages.
+	ErrorNoPackages ErrorCode = "no_packages"
+
+	// ErrorBuildFailed is a failure to load the packages
+	// of a source scan, such as one due to a compile error.
+	ErrorBuildFailed ErrorCode = "build_failed"
+
+	// ErrorGoVersionMismatch is a failure to load packages caused by
+	// govulncheck being built with a different version of Go than
+	// the one on PATH.
+	ErrorGoVersionMismatch ErrorCode = "go_version_mismatch"
+
+	// ErrorUnsupportedBinary is a failure to read a binary, which
+	// is neither a Go executable nor a blob of extract mode, or is
+	// an archive with no Go executables.
+	ErrorUnsupportedBinary ErrorCode = "unsupported_binary"
+
+	// ErrorDBUnreachable is a failure to read the vulnerabilities
+	// of the vulnerability database.
+	ErrorDBUnreachable ErrorCode = "db_unreachable"
+
+	// ErrorMemoryLimit is the failure of a source scan whose call
+	// graph exceeded the limit of -max-memory. The vulnerable packages
+	// imported are then reported, as at the package level, but whether
+	// their vulnerable symbols are called is unknown.
+	ErrorMemoryLimit ErrorCode = "memory_limit"
+
+	// ErrorCanceled is a scan canceled, or which timed out.
+	ErrorCanceled ErrorCode = "canceled"
+
+	// ErrorUnknown is any other failure.
+	ErrorUnknown ErrorCode = "unknown"
+)
+
+// Error is the failure of a scan. It is the error returned by failed
+// scans, as found by errors.As, and is written as the last message of
+// the JSON output of scans failing once the output has started.
+type Error struct {
+	// Code classifies the failure.
+	Code ErrorCode `json:"code"`
+
+	// Message is the message of the error, as printed by govulncheck.
+	Message string `json:"message"`
+
+	// Err is the underlying error, if known.
+	Err error `json:"-"`
+}
+
+// NewError returns an Error with code wrapping err.
+func NewError(code ErrorCode, err error) *Error {
+	return &Error{Code: code, Message: err.Error(), Err: err}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// ReportError passes e to h if h reports the failures of scans,
+// as the handler of NewJSONHandler does by writing it as an
+// error message.
+func ReportError(h Handler, e *Error) error {
+	if r, ok := h.(interface{ ReportError(*Error) error }); ok {
+		return r.ReportError(e)
+	}
+	return nil
+}
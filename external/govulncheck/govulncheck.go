@@ -47,6 +47,8 @@ type Message struct {
 	// and the desired scan level.
 	OSV     *osv.Entry `json:"osv,omitempty"`
 	Finding *Finding   `json:"finding,omitempty"`
+	// Error is the last message of the stream of a failed scan.
+	Error *Error `json:"error,omitempty"`
 }
 
 // Config must occur as the first message of a stream and informs the client
@@ -66,7 +68,8 @@ type Config struct {
 	ScannerVersion string `json:"scanner_version,omitempty"`
 
 	// DB is the database used by the tool, for example,
-	// vuln.go.dev.
+	// vuln.go.dev. Several databases read at once are
+	// separated by ", ".
 	DB string `json:"db,omitempty"`
 
 	// LastModified is the last modified time of the data source.
@@ -77,13 +80,138 @@ type Config struct {
 	GoVersion string `json:"go_version,omitempty"`
 
 	// ScanLevel instructs govulncheck to analyze at a specific level of detail.
-	// Valid values include module, package and symbol.
+	// Valid values include stdlib, module, package and symbol.
 	ScanLevel ScanLevel `json:"scan_level,omitempty"`
 
+	// CallGraph is the algorithm used to construct the call graph for
+	// symbol level source scans. Valid values are vta, the default,
+	// rta, and cha, in order of decreasing precision and cost.
+	CallGraph CallGraphAlgorithm `json:"call_graph,omitempty"`
+
+	// Reflection indicates whether calls made through reflection, such
+	// as with reflect.Value.Call or reflect.Value.MethodByName and by
+	// text/template and html/template, are conservatively assumed to
+	// possibly call any vulnerable function, or exported method as
+	// applicable. Otherwise, such calls are not visible to the analysis.
+	Reflection bool `json:"reflection,omitempty"`
+
+	// Taint indicates whether the sources of untrusted data, from the
+	// network, files, or the environment, that can flow into the
+	// arguments of vulnerable calls are tracked, for the vulnerabilities
+	// triggered by crafted inputs. See Finding.Taint.
+	Taint bool `json:"taint,omitempty"`
+
+	// Library indicates whether the top-level packages are scanned as a
+	// library, with their exported functions and methods as the entry
+	// points of symbol level source scans. See Finding.ExposedBy.
+	Library bool `json:"library,omitempty"`
+
+	// MaxMemory, if positive, is the size in bytes of the heap that the
+	// scan tries to stay under. It is the soft memory limit of the
+	// process while the scan runs, as set by debug.SetMemoryLimit, so
+	// that the garbage collector works harder to stay under it, and the
+	// live heap beyond which the construction of the call graph of
+	// symbol level source scans is abandoned. Only package level
+	// findings are then reported, and the scan fails, instead of running
+	// out of memory. As the soft limit is that of the whole process,
+	// scans running at once should share one limit.
+	MaxMemory int64 `json:"max_memory,omitempty"`
+
+	// Workers is the maximum number of goroutines running the symbol
+	// level analysis of source scans at once. Zero means the number of
+	// CPUs usable by the process. It does not affect findings.
+	Workers int `json:"workers,omitempty"`
+
+	// LoadWorkers, if positive, is the maximum number of files parsed,
+	// and of packages built by the go command and converted to SSA, at
+	// once when loading the packages of source scans. It does not
+	// affect findings.
+	LoadWorkers int `json:"load_workers,omitempty"`
+
+	// ExportData indicates whether symbol level source scans type check
+	// from source only the packages on the import paths from the
+	// top-level packages to vulnerable ones, loading the others from the
+	// export data of the go command. Calls to vulnerable symbols made by
+	// the other packages through interfaces or function values, which
+	// are then not seen, are not reported.
+	ExportData bool `json:"export_data,omitempty"`
+
+	// WitnessWorkers, if positive, is the maximum number of goroutines
+	// searching for the call stacks of symbol level source findings at
+	// once, which is Workers otherwise. It does not affect findings.
+	WitnessWorkers int `json:"witness_workers,omitempty"`
+
+	// WitnessRanking is the preference used to choose the call stack
+	// reported for symbol level source findings. Valid values are
+	// shortest, the default, fewest-third-party, and main-module.
+	WitnessRanking WitnessRanking `json:"witness_ranking,omitempty"`
+
+	// WitnessTimeout and WitnessDepth, if positive, limit the search
+	// for the call stack of each symbol level source finding, by time
+	// and by number of calls from the vulnerable symbol. A search
+	// reaching a limit before finding an entry point reports a partial
+	// call stack instead. See Finding.Partial.
+	WitnessTimeout time.Duration `json:"witness_timeout,omitempty"`
+	WitnessDepth   int           `json:"witness_depth,omitempty"`
+
+	// WitnessThroughVulns indicates whether the call stacks of symbol
+	// level source findings may go through the other vulnerable symbols
+	// of the same vulnerability, which they otherwise avoid so that each
+	// symbol is shown with a call stack of its own. The call stacks are
+	// then the shortest ones, even when they chain through such symbols.
+	WitnessThroughVulns bool `json:"witness_through_vulns,omitempty"`
+
+	// StrictPlatform indicates whether symbol level source scans leave
+	// out the calls made in files whose build constraints GOOS and
+	// GOARCH cannot satisfy, which are loaded when build tags name
+	// other platforms. See Frame.BuildConstraint.
+	StrictPlatform bool `json:"strict_platform,omitempty"`
+
+	// Slice indicates whether symbol level source findings include the
+	// source ranges along their traces, for editors to highlight the
+	// whole path to the vulnerable symbol. See Finding.Slice.
+	Slice bool `json:"slice,omitempty"`
+
+	// EntryPoints, if set, are the patterns of the functions used as the
+	// entry points of symbol level source scans, instead of the main and
+	// exported functions and package initialization of the top-level
+	// packages. Patterns have the form package.symbol, where the symbol
+	// is in the database format, as in Server.ServeHTTP, and both parts
+	// may use the wildcards of path.Match.
+	EntryPoints []string `json:"entry_points,omitempty"`
+
+	// SkipInit indicates whether package initialization, that is init
+	// functions and the initializers of package-level variables, is left
+	// out of the entry points of symbol level source scans. Vulnerable
+	// symbols only reached during initialization are then not reported.
+	SkipInit bool `json:"skip_init,omitempty"`
+
+	// MinConfidence, if set, is the minimum confidence of the symbol
+	// level findings that are reported. See Finding.Confidence.
+	MinConfidence Confidence `json:"min_confidence,omitempty"`
+
+	// MaxDepth, when positive, restricts module and package level
+	// findings to dependencies reached through at most MaxDepth module
+	// boundaries from the main module in source mode. Symbol level
+	// findings are not restricted.
+	MaxDepth int `json:"max_depth,omitempty"`
+
+	// MaxFindings, when positive, is the number of findings after
+	// which the others are not reported, only counted by module in
+	// a progress message.
+	MaxFindings int `json:"max_findings,omitempty"`
+
 	// ScanMode instructs govulncheck how to interpret the input and
-	// what to do with it. Valid values are source, binary, query,
+	// what to do with it. Valid values are source, binary, sbom, query,
 	// and extract.
 	ScanMode ScanMode `json:"scan_mode,omitempty"`
+
+	// GOOS and GOARCH describe the platform the scanned artifact targets,
+	// which can differ from the platform govulncheck is running on.
+	// They are populated in binary mode, and in source mode when a
+	// platform is selected.
+	GOOS   string `json:"goos,omitempty"`
+	GOARCH string `json:"goarch,omitempty"`
 }
 
 // SBOM contains minimal information about the artifacts govulncheck is scanning.
@@ -99,6 +227,23 @@ type SBOM struct {
 	// For binaries, this will be the main package.
 	// For source code, this will be the packages matching the provided package patterns.
 	Roots []string `json:"roots,omitempty"`
+
+	// GOOS and GOARCH describe the platform the scanned artifact targets.
+	// They are currently only populated for binaries.
+	GOOS   string `json:"goos,omitempty"`
+	GOARCH string `json:"goarch,omitempty"`
+
+	// Artifact is the path of the scanned artifact the SBOM
+	// describes. It is only set when several binaries are
+	// scanned in a single run.
+	Artifact string `json:"artifact,omitempty"`
+
+	// CLibraries are the C libraries that the scanned code links
+	// using cgo, as far as they can be determined. For source code,
+	// these are named by #cgo directives and for binaries, they are
+	// the dynamically linked libraries. Govulncheck does not check
+	// them for vulnerabilities.
+	CLibraries []string `json:"c_libraries,omitempty"`
 }
 
 type Module struct {
@@ -163,6 +308,64 @@ type Finding struct {
 	// findings, the trace will contain a single-frame with no symbol or position
 	// information.
 	Trace []*Frame `json:"trace,omitempty"`
+
+	// Artifact is the path of the scanned artifact the finding
+	// originates from. It is only set when several binaries are
+	// scanned in a single run.
+	Artifact string `json:"artifact,omitempty"`
+
+	// Platforms are the goos/goarch platforms the finding was
+	// found for. It is only set when the code is analyzed for
+	// several platforms in a single run.
+	Platforms []string `json:"platforms,omitempty"`
+
+	// Reflection is set if the trace contains a call made through
+	// reflection, such as with reflect.Value.Call or by a template,
+	// which was conservatively assumed to reach the next frame. Such
+	// findings may not be actual. It is only set when reflection is
+	// modeled, see Config.Reflection.
+	Reflection bool `json:"reflection,omitempty"`
+
+	// Confidence is how confident the analysis is that the vulnerable
+	// symbol of a symbol level finding is actually reached, depending
+	// on how the trace was found. It is not set for module and package
+	// level findings.
+	Confidence Confidence `json:"confidence,omitempty"`
+
+	// Taint are the sources of untrusted data that can flow into the
+	// arguments of the calls of the vulnerable symbol, anywhere in the
+	// code. It is only set when taint tracking is enabled, see
+	// Config.Taint, for symbol level findings of vulnerabilities
+	// triggered by crafted inputs, such as in parsers.
+	Taint []TaintSource `json:"taint,omitempty"`
+
+	// ExposedBy are the exported functions and methods, in the form
+	// package.symbol, from which the vulnerable symbol can be reached.
+	// It is only set for symbol level findings of library scans, see
+	// Config.Library, so that library authors can tell their users
+	// which of their APIs are affected.
+	ExposedBy []string `json:"exposed_by,omitempty"`
+
+	// Linkage is set if the vulnerable symbol is not called by Go code
+	// but reached through a //go:linkname directive or from assembly,
+	// which the call graph does not account for. The confidence of such
+	// findings is at most likely, or possible for assembly, which is
+	// only inspected textually.
+	Linkage Linkage `json:"linkage,omitempty"`
+
+	// Partial is set if the search for the trace reached a limit of
+	// Config.WitnessTimeout or Config.WitnessDepth before finding an
+	// entry point. The trace then starts with the function farthest
+	// from the vulnerable symbol that the search reached, rather than
+	// with an entry point.
+	Partial bool `json:"partial,omitempty"`
+
+	// Slice are the source ranges along the trace of a symbol level
+	// source finding, by file: for each function, the lines from its
+	// declaration to the call it makes in the trace, and all the lines
+	// of the vulnerable symbol, with overlapping ranges merged. It is
+	// only set when requested, see Config.Slice.
+	Slice []*SourceRange `json:"slice,omitempty"`
 }
 
 // Frame represents an entry in a finding trace.
@@ -187,6 +390,18 @@ type Frame struct {
 	// prepending Receiver to FuncName.
 	Receiver string `json:"receiver,omitempty"`
 
+	// TypeArgs lists the type arguments of the instance of a generic
+	// function or method, or of the receiver type of a method, if any,
+	// as in "[int, string]". Function and Receiver name the generic
+	// function or type.
+	TypeArgs string `json:"type_args,omitempty"`
+
+	// Kind is set when the symbol is a type, variable, constant, or
+	// struct field used by the code, or a function referenced but not
+	// called by it. Function is then the name of the symbol and, for
+	// struct fields, Receiver is the name of the struct type.
+	Kind SymbolKind `json:"kind,omitempty"`
+
 	// Position describes an arbitrary source position
 	// including the file, line, and column location.
 	// A Position is valid if the line number is > 0.
@@ -195,6 +410,12 @@ type Frame struct {
 	// the enclosing module and always use "/" for
 	// portability.
 	Position *Position `json:"position,omitempty"`
+
+	// BuildConstraint is the build constraint of the file of Position,
+	// if any, combining its //go:build line with the constraint implied
+	// by its name, as in linux && amd64 for x_linux_amd64.go. It tells
+	// for which platforms and build tags the frame is part of the trace.
+	BuildConstraint string `json:"build_constraint,omitempty"`
 }
 
 // Position represents arbitrary source position.
@@ -205,6 +426,22 @@ type Position struct {
 	Column   int    `json:"column"`             // column number, starting at 1 (byte count)
 }
 
+// A SourceRange is a span of lines of a source file.
+type SourceRange struct {
+	// Module and Version are those of the module of the file.
+	Module  string `json:"module,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// Filename is relative to the directory of the module
+	// and always uses "/" for portability, as for Position.
+	Filename string `json:"filename"`
+
+	// StartLine and EndLine are the first and the last
+	// lines of the range, starting at 1.
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+}
+
 // ScanLevel represents the detail level at which a scan occurred.
 // This can be necessary to correctly interpret the findings, for instance if
 // a scan is at symbol level and a finding does not have a symbol it means the
@@ -216,6 +453,10 @@ const (
 	ScanLevelModule  = "module"
 	ScanLevelPackage = "package"
 	ScanLevelSymbol  = "symbol"
+
+	// ScanLevelStdlib is like ScanLevelModule, but only the
+	// standard library is checked for vulnerabilities.
+	ScanLevelStdlib = "stdlib"
 )
 
 // WantSymbols can be used to check whether the scan level is one that is able
@@ -226,6 +467,147 @@ func (l ScanLevel) WantSymbols() bool { return l == ScanLevelSymbol }
 // to generate package-level findings.
 func (l ScanLevel) WantPackages() bool { return l == ScanLevelPackage || l == ScanLevelSymbol }
 
+// CallGraphAlgorithm is an algorithm for constructing call graphs,
+// which trades off precision for speed.
+type CallGraphAlgorithm string
+
+const (
+	// CallGraphVTA uses variable type analysis, which resolves
+	// dynamic calls to the types of the values that can flow to
+	// them. It is the most precise and the slowest algorithm.
+	CallGraphVTA = "vta"
+
+	// CallGraphRTA uses rapid type analysis, which resolves dynamic
+	// calls to the types instantiated in the reachable code. It is
+	// best suited to programs, whose entry points are main and init
+	// functions.
+	CallGraphRTA = "rta"
+
+	// CallGraphCHA uses class hierarchy analysis, which resolves
+	// dynamic calls to all types implementing the called interface.
+	// It is the least precise and the fastest algorithm.
+	CallGraphCHA = "cha"
+)
+
+// SymbolKind is the kind of a vulnerable symbol
+// that is used by the code rather than called.
+type SymbolKind string
+
+const (
+	SymbolKindType  = "type"
+	SymbolKindVar   = "var"
+	SymbolKindConst = "const"
+	SymbolKindField = "field"
+
+	// SymbolKindFunc is the kind of functions and methods that are
+	// referenced as values, for instance passed as callbacks, but
+	// that the analysis could not show to be called. This is a level
+	// of reachability between imported and called.
+	SymbolKindFunc = "func"
+
+	// SymbolKindLinked is the kind of functions and methods found in
+	// the symbol table of a binary, so that the linker did not remove
+	// them as dead code, but that binaries cannot show to be called.
+	// This is a level of reachability between imported and called, and
+	// the highest one of binary scans.
+	SymbolKindLinked = "linked"
+)
+
+// WitnessRanking is a preference among the call stacks
+// from the entry points to a vulnerable symbol.
+type WitnessRanking string
+
+const (
+	// WitnessShortest prefers the shortest call stacks, and then
+	// those with the fewest dynamic calls.
+	WitnessShortest = "shortest"
+
+	// WitnessFewestThirdParty prefers the call stacks with the fewest
+	// frames in modules other than the main module and the standard
+	// library.
+	WitnessFewestThirdParty = "fewest-third-party"
+
+	// WitnessMainModule prefers the call stacks leaving the main
+	// module the closest to the vulnerable symbol, so that they are
+	// anchored in user code.
+	WitnessMainModule = "main-module"
+)
+
+// Confidence is the confidence of a symbol level finding.
+type Confidence string
+
+const (
+	// ConfidenceCertain is the confidence of findings whose trace
+	// only consists of static calls, or of direct uses of symbols.
+	ConfidenceCertain = "certain"
+
+	// ConfidenceLikely is the confidence of findings whose trace goes
+	// through dynamic calls, such as interface method calls, which
+	// were resolved by the call graph analysis, and of symbols found
+	// in binaries.
+	ConfidenceLikely = "likely"
+
+	// ConfidencePossible is the confidence of findings relying on
+	// conservative approximations, such as calls through reflection,
+	// or of functions that are referenced but not shown to be called.
+	ConfidencePossible = "possible"
+)
+
+// AtLeast reports whether c is at least as high as min. Findings
+// without a confidence, such as module and package level findings,
+// are at least as high as any confidence.
+func (c Confidence) AtLeast(min Confidence) bool {
+	return confidenceRank(c) >= confidenceRank(min)
+}
+
+// Less reports whether c is lower than d, for sorting
+// findings by decreasing confidence.
+func (c Confidence) Less(d Confidence) bool {
+	return confidenceRank(c) < confidenceRank(d)
+}
+
+func confidenceRank(c Confidence) int {
+	switch c {
+	case ConfidencePossible:
+		return 1
+	case ConfidenceLikely:
+		return 2
+	case ConfidenceCertain:
+		return 3
+	}
+	return 4
+}
+
+// Linkage is how a vulnerable symbol is reached other than by a call.
+type Linkage string
+
+const (
+	// LinkageLinkname is the linkage of symbols pulled into another
+	// package by a //go:linkname directive, and called there.
+	LinkageLinkname = "linkname"
+
+	// LinkageAssembly is the linkage of symbols referenced by
+	// assembly functions, such as with CALL instructions.
+	LinkageAssembly = "assembly"
+)
+
+// TaintSource is a source of untrusted data.
+type TaintSource string
+
+const (
+	// TaintSourceNetwork is data received from the network,
+	// such as HTTP requests and responses.
+	TaintSourceNetwork = "network"
+
+	// TaintSourceFile is data read from files, including
+	// standard input.
+	TaintSourceFile = "file"
+
+	// TaintSourceEnv is data from the environment of the
+	// process, that is environment variables and arguments.
+	TaintSourceEnv = "env"
+)
+
 // ScanMode represents the mode in which a scan occurred. This can
 // be necessary to correctly to interpret findings. For instance,
 // a binary can be checked for vulnerabilities or the user just wants
@@ -238,4 +620,5 @@ const (
 	ScanModeConvert = "convert"
 	ScanModeQuery   = "query"
 	ScanModeExtract = "extract" // currently, only binary extraction is supported
+	ScanModeSBOM    = "sbom"
 )
@@ -7,6 +7,7 @@ package govulncheck_test
 import (
 	"testing"
 
+	"github.com/StevenACoffman/invuln/external/govulncheck"
 	"github.com/StevenACoffman/invuln/external/test"
 )
 
@@ -15,3 +16,21 @@ func TestImports(t *testing.T) {
 		"github.com/StevenACoffman/invuln/external/osv", // allowed to pull in the osv json entries
 	)
 }
+
+func TestConfidenceAtLeast(t *testing.T) {
+	for _, tc := range []struct {
+		c, min govulncheck.Confidence
+		want   bool
+	}{
+		{govulncheck.ConfidenceCertain, govulncheck.ConfidenceLikely, true},
+		{govulncheck.ConfidenceLikely, govulncheck.ConfidenceLikely, true},
+		{govulncheck.ConfidencePossible, govulncheck.ConfidenceLikely, false},
+		{govulncheck.ConfidencePossible, govulncheck.ConfidencePossible, true},
+		{govulncheck.ConfidenceLikely, govulncheck.ConfidenceCertain, false},
+		{"", govulncheck.ConfidenceCertain, true},
+	} {
+		if got := tc.c.AtLeast(tc.min); got != tc.want {
+			t.Errorf("%q.AtLeast(%q) = %v; want %v", tc.c, tc.min, got, tc.want)
+		}
+	}
+}
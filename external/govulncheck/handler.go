@@ -7,6 +7,7 @@ package govulncheck
 import (
 	"encoding/json"
 	"io"
+	"iter"
 
 	"github.com/StevenACoffman/invuln/external/osv"
 )
@@ -31,17 +32,14 @@ type Handler interface {
 }
 
 // HandleJSON reads the json from the supplied stream and hands the decoded
-// output to the handler.
+// output to the handler. The error message of the stream of a failed scan
+// is returned as its *Error.
 func HandleJSON(from io.Reader, to Handler) error {
-	dec := json.NewDecoder(from)
-	for dec.More() {
-		msg := Message{}
-		// decode the next message in the stream
-		if err := dec.Decode(&msg); err != nil {
+	for msg, err := range NewReader(from).All() {
+		if err != nil {
 			return err
 		}
 		// dispatch the message
-		var err error
 		if msg.Config != nil {
 			err = to.Config(msg.Config)
 		}
@@ -57,9 +55,54 @@ func HandleJSON(from io.Reader, to Handler) error {
 		if msg.Finding != nil {
 			err = to.Finding(msg.Finding)
 		}
+		if msg.Error != nil {
+			err = msg.Error
+		}
 		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// A Reader reads the messages of a json stream one at a time, for
+// consumers iterating over them, and stopping when they like, rather
+// than having them handed to a Handler.
+type Reader struct {
+	dec *json.Decoder
+}
+
+// NewReader returns a Reader reading the messages of r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: json.NewDecoder(r)}
+}
+
+// Next returns the next message of the stream, exactly one of
+// whose fields is set for messages written by govulncheck.
+// At the end of the stream, it returns io.EOF.
+func (r *Reader) Next() (Message, error) {
+	if !r.dec.More() {
+		return Message{}, io.EOF
+	}
+	var msg Message
+	if err := r.dec.Decode(&msg); err != nil {
+		return Message{}, err
+	}
+	return msg, nil
+}
+
+// All returns an iterator over the remaining messages of the stream,
+// which stops after the first error, yielded with a zero message.
+func (r *Reader) All() iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		for {
+			msg, err := r.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(msg, err) || err != nil {
+				return
+			}
+		}
+	}
+}
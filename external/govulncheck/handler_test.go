@@ -0,0 +1,76 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package govulncheck_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+)
+
+const stream = `{"config": {"protocol_version": "v1.0.0"}}
+{"progress": {"message": "Scanning..."}}
+{"osv": {"id": "GO-0000-0001"}}
+{"finding": {"osv": "GO-0000-0001"}}
+`
+
+func TestReader(t *testing.T) {
+	r := govulncheck.NewReader(strings.NewReader(stream))
+	msg, err := r.Next()
+	if err != nil || msg.Config == nil || msg.Config.ProtocolVersion != "v1.0.0" {
+		t.Fatalf("got %+v, %v; want the config", msg, err)
+	}
+	var got []string
+	for msg, err := range r.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case msg.Progress != nil:
+			got = append(got, "progress")
+		case msg.OSV != nil:
+			got = append(got, "osv "+msg.OSV.ID)
+		case msg.Finding != nil:
+			got = append(got, "finding "+msg.Finding.OSV)
+		}
+	}
+	if want := "progress,osv GO-0000-0001,finding GO-0000-0001"; strings.Join(got, ",") != want {
+		t.Errorf("got messages %q; want %q", got, want)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("got %v at the end of the stream; want io.EOF", err)
+	}
+
+	// Iteration may stop early.
+	n := 0
+	for range govulncheck.NewReader(strings.NewReader(stream)).All() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("iterated over %d messages after break; want 1", n)
+	}
+
+	r = govulncheck.NewReader(strings.NewReader(`{"config": {}} {"finding": `))
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); err == nil || err == io.EOF {
+		t.Errorf("got %v for a truncated message; want an error", err)
+	}
+}
+
+func TestHandleJSONError(t *testing.T) {
+	failed := `{"config": {"protocol_version": "v1.0.0"}}
+{"error": {"code": "db_unreachable", "message": "fetching vulnerabilities: timeout"}}`
+	err := govulncheck.HandleJSON(strings.NewReader(failed), govulncheck.NewJSONHandler(io.Discard))
+	var e *govulncheck.Error
+	if !errors.As(err, &e) || e.Code != govulncheck.ErrorDBUnreachable || err.Error() != "fetching vulnerabilities: timeout" {
+		t.Errorf("got error %v; want the error of the stream", err)
+	}
+}
@@ -47,3 +47,8 @@ func (h *jsonHandler) OSV(entry *osv.Entry) error {
 func (h *jsonHandler) Finding(finding *Finding) error {
 	return h.enc.Encode(Message{Finding: finding})
 }
+
+// ReportError writes the failure of the scan in JSON to the underlying writer.
+func (h *jsonHandler) ReportError(e *Error) error {
+	return h.enc.Encode(Message{Error: e})
+}
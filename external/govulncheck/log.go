@@ -0,0 +1,34 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package govulncheck
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+// discard is the logger of contexts with none.
+var discard = slog.New(slog.DiscardHandler)
+
+// WithLogger returns a context whose scans log their internals to l,
+// such as the packages loaded, the requests made to the databases, and
+// the time taken by the analysis. Records are logged at the debug
+// level, except for unexpected events, such as requests retried, which
+// are logged at the info level or above, so that the level of the
+// handler of l selects the records wanted.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// Logger returns the logger of ctx, which discards
+// the records logged to it if ctx has none.
+func Logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok && l != nil {
+		return l
+	}
+	return discard
+}
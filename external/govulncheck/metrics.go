@@ -0,0 +1,105 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package govulncheck
+
+import (
+	"context"
+	"time"
+)
+
+// Metrics records measurements of scans, such as the time taken by
+// each phase and the requests made to the databases, for programs
+// embedding govulncheck to export them, as to Prometheus or
+// OpenTelemetry. Its methods may be called concurrently.
+type Metrics interface {
+	// Add adds delta to the counter name.
+	Add(name string, delta int64)
+
+	// Observe records the duration d measured by the timer name.
+	Observe(name string, d time.Duration)
+}
+
+// The names of the counters and timers of Metrics.
+const (
+	// MetricDBRequests counts the requests made to http(s) databases
+	// and OSV APIs, including the retried ones, MetricDBRetries the
+	// retries, and MetricDBErrors the requests failing.
+	MetricDBRequests = "db_requests"
+	MetricDBRetries  = "db_retries"
+	MetricDBErrors   = "db_errors"
+
+	// MetricDBRequestDuration times the requests made
+	// to http(s) databases and OSV APIs.
+	MetricDBRequestDuration = "db_request_duration"
+
+	// MetricModuleFindings, MetricPackageFindings, and
+	// MetricSymbolFindings count the findings of each level.
+	MetricModuleFindings  = "module_findings"
+	MetricPackageFindings = "package_findings"
+	MetricSymbolFindings  = "symbol_findings"
+)
+
+// PhaseMetric returns the name of the timer of the phases phase
+// completed, as in phase_load_duration for PhaseLoad.
+func PhaseMetric(phase Phase) string {
+	return "phase_" + string(phase) + "_duration"
+}
+
+type metricsKey struct{}
+
+// WithMetrics returns a context whose scans record their measurements to m.
+func WithMetrics(ctx context.Context, m Metrics) context.Context {
+	return context.WithValue(ctx, metricsKey{}, m)
+}
+
+// MetricsOf returns the metrics of ctx, which discard
+// the measurements recorded if ctx has none.
+func MetricsOf(ctx context.Context) Metrics {
+	if m, ok := ctx.Value(metricsKey{}).(Metrics); ok && m != nil {
+		return m
+	}
+	return noMetrics{}
+}
+
+// RecordsMetrics reports whether the scans of ctx record their measurements.
+func RecordsMetrics(ctx context.Context) bool {
+	m, ok := ctx.Value(metricsKey{}).(Metrics)
+	return ok && m != nil
+}
+
+// noMetrics discards the measurements recorded.
+type noMetrics struct{}
+
+func (noMetrics) Add(string, int64)             {}
+func (noMetrics) Observe(string, time.Duration) {}
+
+// CountFindings returns a handler passing on all messages to next,
+// counting the findings it is passed in m.
+func CountFindings(m Metrics, next Handler) Handler {
+	return &findingCounter{Handler: next, m: m}
+}
+
+type findingCounter struct {
+	Handler
+	m Metrics
+}
+
+func (h *findingCounter) Finding(f *Finding) error {
+	name := MetricModuleFindings
+	if len(f.Trace) > 0 {
+		switch {
+		case f.Trace[0].Function != "":
+			name = MetricSymbolFindings
+		case f.Trace[0].Package != "":
+			name = MetricPackageFindings
+		}
+	}
+	h.m.Add(name, 1)
+	return h.Handler.Finding(f)
+}
+
+func (h *findingCounter) Flush() error {
+	return Flush(h.Handler)
+}
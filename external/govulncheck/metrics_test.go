@@ -0,0 +1,72 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package govulncheck_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+)
+
+// testMetrics records the counters and the
+// number of observations of each timer.
+type testMetrics struct {
+	mu     sync.Mutex
+	counts map[string]int64
+	timers map[string]int
+}
+
+func (m *testMetrics) Add(name string, delta int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[name] += delta
+}
+
+func (m *testMetrics) Observe(name string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.timers[name]++
+}
+
+func TestMetrics(t *testing.T) {
+	// Without Metrics, nothing is recorded.
+	if govulncheck.RecordsMetrics(context.Background()) {
+		t.Error("RecordsMetrics = true for a context with no metrics")
+	}
+	govulncheck.StartPhase(context.Background(), govulncheck.PhaseLoad, 0).Complete()
+
+	m := &testMetrics{counts: make(map[string]int64), timers: make(map[string]int)}
+	ctx := govulncheck.WithMetrics(context.Background(), m)
+	if !govulncheck.RecordsMetrics(ctx) {
+		t.Fatal("RecordsMetrics = false; want true")
+	}
+	govulncheck.StartPhase(ctx, govulncheck.PhaseFetch, 2).Complete()
+	govulncheck.StartPhase(ctx, govulncheck.PhaseAnalyze, 1) // not completed
+	if got := m.timers; len(got) != 1 || got["phase_fetch_duration"] != 1 {
+		t.Errorf("timers = %v; want phase_fetch_duration observed once", got)
+	}
+
+	h := govulncheck.CountFindings(m, test.NewMockHandler())
+	for _, f := range []*govulncheck.Finding{
+		{Trace: []*govulncheck.Frame{{Module: "m"}}},
+		{Trace: []*govulncheck.Frame{{Module: "m", Package: "m/p"}}},
+		{Trace: []*govulncheck.Frame{{Module: "m", Package: "m/p", Function: "F"}}},
+		{Trace: []*govulncheck.Frame{{Module: "m", Package: "m/p", Function: "G"}}},
+	} {
+		if err := h.Finding(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]int64{govulncheck.MetricModuleFindings: 1, govulncheck.MetricPackageFindings: 1, govulncheck.MetricSymbolFindings: 2}
+	for name, n := range want {
+		if m.counts[name] != n {
+			t.Errorf("%s = %d; want %d", name, m.counts[name], n)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package govulncheck
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A Phase is a phase of a scan whose progress is reported
+// to the ProgressFunc of the context of the scan.
+type Phase string
+
+const (
+	// PhaseLoad is the loading of packages. Its units are the
+	// files parsed, whose total is only known once it completes.
+	PhaseLoad Phase = "load"
+
+	// PhaseFetch is the fetching of the vulnerabilities
+	// of modules. Its units are modules.
+	PhaseFetch Phase = "fetch"
+
+	// PhaseCallGraph is the building of the call graph,
+	// which has no units.
+	PhaseCallGraph Phase = "call_graph"
+
+	// PhaseAnalyze is the search for the call stacks of the
+	// vulnerable symbols called. Its units are vulnerabilities.
+	PhaseAnalyze Phase = "analyze"
+)
+
+// A ProgressKind is the kind of a ProgressEvent.
+type ProgressKind int
+
+const (
+	// ProgressStarted reports that a phase started.
+	ProgressStarted ProgressKind = iota
+
+	// ProgressAdvanced reports that a unit of a phase is done.
+	ProgressAdvanced
+
+	// ProgressCompleted reports that a phase completed. Phases
+	// ended by an error, or by the context being done, do not
+	// complete.
+	ProgressCompleted
+)
+
+// A ProgressEvent reports the progress of a phase of a scan.
+type ProgressEvent struct {
+	Phase Phase
+	Kind  ProgressKind
+
+	// Done is the number of units of the phase done,
+	// and Total their total number, or 0 if unknown.
+	Done  int
+	Total int
+}
+
+// A ProgressFunc is passed the progress of the phases of a scan,
+// beyond its Progress messages, such as to show progress bars.
+// Calls are serialized, and so should return promptly.
+type ProgressFunc func(*ProgressEvent)
+
+type progressKey struct{}
+
+// reporter serializes the calls of a ProgressFunc.
+type reporter struct {
+	mu sync.Mutex
+	f  ProgressFunc
+}
+
+// WithProgress returns a context whose scans pass the
+// progress of their phases to f.
+func WithProgress(ctx context.Context, f ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, &reporter{f: f})
+}
+
+// ReportsProgress reports whether the scans of ctx report their progress.
+func ReportsProgress(ctx context.Context) bool {
+	_, ok := ctx.Value(progressKey{}).(*reporter)
+	return ok
+}
+
+// ReportProgress passes ev to the ProgressFunc of ctx, if any.
+func ReportProgress(ctx context.Context, ev *ProgressEvent) {
+	r, ok := ctx.Value(progressKey{}).(*reporter)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.f(ev)
+}
+
+// A PhaseProgress reports the progress of one phase of a scan,
+// counting the units done, which may be done concurrently.
+type PhaseProgress struct {
+	ctx   context.Context
+	phase Phase
+	total int
+	start time.Time
+
+	mu   sync.Mutex
+	done int
+}
+
+// StartPhase reports that phase started in ctx,
+// with total units if known, and returns its progress.
+func StartPhase(ctx context.Context, phase Phase, total int) *PhaseProgress {
+	ReportProgress(ctx, &ProgressEvent{Phase: phase, Kind: ProgressStarted, Total: total})
+	return &PhaseProgress{ctx: ctx, phase: phase, total: total, start: time.Now()}
+}
+
+// Advance reports that a unit of the phase is done.
+func (p *PhaseProgress) Advance() {
+	// Report while holding mu, so that the units done
+	// are reported in increasing order.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	ReportProgress(p.ctx, &ProgressEvent{Phase: p.phase, Kind: ProgressAdvanced, Done: p.done, Total: p.total})
+}
+
+// Complete reports that the phase completed, and records its
+// duration to the metrics of its context under PhaseMetric. If
+// the total of the phase was unknown, it is the number of units done.
+func (p *PhaseProgress) Complete() {
+	MetricsOf(p.ctx).Observe(PhaseMetric(p.phase), time.Since(p.start))
+	p.mu.Lock()
+	done, total := p.done, p.total
+	p.mu.Unlock()
+	if total == 0 {
+		total = done
+	} else {
+		done = total
+	}
+	ReportProgress(p.ctx, &ProgressEvent{Phase: p.phase, Kind: ProgressCompleted, Done: done, Total: total})
+}
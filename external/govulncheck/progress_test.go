@@ -0,0 +1,60 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package govulncheck
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestPhaseProgress(t *testing.T) {
+	// Without a ProgressFunc, nothing is reported.
+	StartPhase(context.Background(), PhaseAnalyze, 3).Advance()
+
+	var events []ProgressEvent
+	ctx := WithProgress(context.Background(), func(ev *ProgressEvent) {
+		events = append(events, *ev)
+	})
+	if !ReportsProgress(ctx) {
+		t.Fatal("ReportsProgress = false; want true")
+	}
+	p := StartPhase(ctx, PhaseAnalyze, 10)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Advance()
+		}()
+	}
+	wg.Wait()
+	p.Complete()
+
+	if len(events) != 12 {
+		t.Fatalf("got %d events; want 12", len(events))
+	}
+	if ev := events[0]; ev.Kind != ProgressStarted || ev.Total != 10 {
+		t.Errorf("got first event %+v; want the start of 10 units", ev)
+	}
+	for i, ev := range events[1:11] {
+		if ev.Kind != ProgressAdvanced || ev.Done != i+1 {
+			t.Errorf("got event %+v; want %d units done", ev, i+1)
+		}
+	}
+	if ev := events[11]; ev.Kind != ProgressCompleted || ev.Done != 10 {
+		t.Errorf("got last event %+v; want the completion of 10 units", ev)
+	}
+
+	// The total of a phase of unknown total is the units done.
+	events = nil
+	p = StartPhase(ctx, PhaseLoad, 0)
+	p.Advance()
+	p.Advance()
+	p.Complete()
+	if ev := events[len(events)-1]; ev.Done != 2 || ev.Total != 2 {
+		t.Errorf("got completion %+v; want 2 of 2 units", ev)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package minisign verifies detached signatures made with minisign
+// (https://jedisct1.github.io/minisign/), whose Ed25519 public keys
+// are short enough to be passed on command lines.
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	algEd25519   = "Ed" // legacy signatures of messages
+	algPrehashed = "ED" // signatures of BLAKE2b-512 digests of messages
+
+	untrustedPrefix = "untrusted comment: "
+	trustedPrefix   = "trusted comment: "
+)
+
+// A PublicKey is a minisign public key.
+type PublicKey struct {
+	id  [8]byte
+	key ed25519.PublicKey
+}
+
+// ID returns the ID of k, in the hexadecimal
+// form minisign shows it in.
+func (k *PublicKey) ID() string {
+	// minisign shows the ID, which is little-endian, as a number.
+	var id [8]byte
+	for i, b := range k.id {
+		id[len(id)-1-i] = b
+	}
+	return strings.ToUpper(hex.EncodeToString(id[:]))
+}
+
+// NewPublicKey returns the minisign public key with the ID id of key.
+func NewPublicKey(id [8]byte, key ed25519.PublicKey) *PublicKey {
+	return &PublicKey{id: id, key: key}
+}
+
+// ParsePublicKey parses a public key given either as its base64
+// encoding, as printed by "minisign -G", or as the contents of a
+// minisign.pub file, which adds an untrusted comment line.
+func ParsePublicKey(s string) (*PublicKey, error) {
+	lines := nonEmptyLines(s)
+	if len(lines) == 2 && strings.HasPrefix(lines[0], untrustedPrefix) {
+		lines = lines[1:]
+	}
+	if len(lines) != 1 {
+		return nil, errors.New("invalid minisign public key: want a single base64 line")
+	}
+	b, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid minisign public key: %w", err)
+	}
+	if len(b) != 2+8+ed25519.PublicKeySize || string(b[:2]) != algEd25519 {
+		return nil, errors.New("invalid minisign public key: not an Ed25519 key")
+	}
+	k := &PublicKey{key: ed25519.PublicKey(b[10:])}
+	copy(k.id[:], b[2:10])
+	return k, nil
+}
+
+// Verify checks that sig, the contents of a .minisig file, is a
+// signature of message by one of keys, along with its trusted comment,
+// and returns that comment.
+func Verify(keys []*PublicKey, message, sig []byte) (trustedComment string, err error) {
+	lines := nonEmptyLines(string(sig))
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], untrustedPrefix) || !strings.HasPrefix(lines[2], trustedPrefix) {
+		return "", errors.New("invalid minisign signature: want 4 lines, with untrusted and trusted comments")
+	}
+	s, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid minisign signature: %w", err)
+	}
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil {
+		return "", fmt.Errorf("invalid minisign signature: %w", err)
+	}
+	if len(s) != 2+8+ed25519.SignatureSize || len(global) != ed25519.SignatureSize {
+		return "", errors.New("invalid minisign signature: wrong length")
+	}
+	alg, id, signature := string(s[:2]), s[2:10], s[10:]
+
+	var key *PublicKey
+	for _, k := range keys {
+		if bytes.Equal(k.id[:], id) {
+			key = k
+			break
+		}
+	}
+	if key == nil {
+		return "", fmt.Errorf("signed with unknown key %s", (&PublicKey{id: [8]byte(id)}).ID())
+	}
+	switch alg {
+	case algEd25519:
+	case algPrehashed:
+		sum := blake2b512(message)
+		message = sum[:]
+	default:
+		return "", fmt.Errorf("unsupported minisign signature algorithm %q", alg)
+	}
+	if !ed25519.Verify(key.key, message, signature) {
+		return "", fmt.Errorf("signature verification failed with key %s", key.ID())
+	}
+	comment := strings.TrimPrefix(lines[2], trustedPrefix)
+	if !ed25519.Verify(key.key, append(bytes.Clone(signature), comment...), global) {
+		return "", fmt.Errorf("trusted comment verification failed with key %s", key.ID())
+	}
+	return comment, nil
+}
+
+// Sign returns the contents of a .minisig file of message signed with
+// priv, whose public key has the ID id, in the prehashed format minisign
+// signs with by default, along with trustedComment.
+func Sign(priv ed25519.PrivateKey, id [8]byte, message []byte, trustedComment string) []byte {
+	sum := blake2b512(message)
+	return sign(priv, id, algPrehashed, sum[:], trustedComment)
+}
+
+// sign returns the contents of a .minisig file of the signature
+// of msg, which is either a message or its digest as given by alg.
+func sign(priv ed25519.PrivateKey, id [8]byte, alg string, msg []byte, trustedComment string) []byte {
+	sig := ed25519.Sign(priv, msg)
+	global := ed25519.Sign(priv, append(bytes.Clone(sig), trustedComment...))
+	b := append(append([]byte(alg), id[:]...), sig...)
+	return []byte(untrustedPrefix + "signature from minisign secret key\n" +
+		base64.StdEncoding.EncodeToString(b) + "\n" +
+		trustedPrefix + trustedComment + "\n" +
+		base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+// nonEmptyLines returns the lines of s that are not blank,
+// without their line endings.
+func nonEmptyLines(s string) []string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimRight(l, "\r"); strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package minisign
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestBLAKE2b512(t *testing.T) {
+	var long []byte // longer than several blocks, not a multiple of one
+	for i := 0; i < 512; i++ {
+		long = append(long, byte(i))
+	}
+	long = append(long, 'x')
+	for _, tc := range []struct {
+		in   []byte
+		want string
+	}{
+		{nil, "786a02f742015903c6c6fd852552d272912f4740e15847618a86e217f71f5419d25e1031afee585313896444934eb04b903a685b1448b755d56f701afe9be2ce"},
+		{[]byte("abc"), "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{long, "32b55964b89b7747c70c57ee2833adbdaedca36e81634ed984f9ce26cca85f3656d4027aa3548e545041e42e505460f62a74a7ec1515a9c60dfceb0f0c9ee528"},
+	} {
+		got := blake2b512(tc.in)
+		if hex.EncodeToString(got[:]) != tc.want {
+			t.Errorf("blake2b512(%d bytes) = %x, want %s", len(tc.in), got, tc.want)
+		}
+	}
+}
+
+// testKey is a minisign key pair for tests.
+type testKey struct {
+	id   [8]byte
+	priv ed25519.PrivateKey
+}
+
+func newTestKey(t *testing.T, id byte) *testKey {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKey{id: [8]byte{id, 1, 2, 3, 4, 5, 6, 7}, priv: priv}
+}
+
+// public returns the contents of the minisign.pub file of k.
+func (k *testKey) public() string {
+	b := append([]byte(algEd25519), k.id[:]...)
+	b = append(b, k.priv.Public().(ed25519.PublicKey)...)
+	return untrustedPrefix + "minisign public key\n" + base64.StdEncoding.EncodeToString(b) + "\n"
+}
+
+// sign returns the contents of the .minisig file of message,
+// signed with k using the algorithm alg.
+func (k *testKey) sign(alg string, message []byte, comment string) []byte {
+	if alg == algPrehashed {
+		return Sign(k.priv, k.id, message, comment)
+	}
+	return sign(k.priv, k.id, alg, message, comment)
+}
+
+func TestVerify(t *testing.T) {
+	k1, k2 := newTestKey(t, 1), newTestKey(t, 2)
+	pub1, err := ParsePublicKey(k1.public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Bare base64 keys, as printed by minisign -G, are accepted too.
+	pub2, err := ParsePublicKey(strings.Split(k2.public(), "\n")[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []*PublicKey{pub1, pub2}
+	msg := []byte(`{"modified":"2024-01-01T00:00:00Z"}`)
+
+	for _, alg := range []string{algEd25519, algPrehashed} {
+		comment, err := Verify(keys, msg, k2.sign(alg, msg, "timestamp:1"))
+		if err != nil {
+			t.Errorf("Verify(%s) = %v", alg, err)
+		} else if comment != "timestamp:1" {
+			t.Errorf("Verify(%s) trusted comment = %q, want %q", alg, comment, "timestamp:1")
+		}
+	}
+
+	tampered := k1.sign(algPrehashed, msg, "ok")
+	tampered = bytes.Replace(tampered, []byte(trustedPrefix+"ok"), []byte(trustedPrefix+"ko"), 1)
+	for _, tc := range []struct {
+		name    string
+		keys    []*PublicKey
+		message []byte
+		sig     []byte
+		want    string
+	}{
+		{"tampered message", keys, []byte(`{}`), k1.sign(algPrehashed, msg, ""), "signature verification failed with key 0706050403020101"},
+		{"tampered comment", keys, msg, tampered, "trusted comment verification failed"},
+		{"unknown key", keys[:1], msg, k2.sign(algEd25519, msg, ""), "signed with unknown key 0706050403020102"},
+		{"not a signature", keys, msg, []byte("{}"), "invalid minisign signature"},
+	} {
+		if _, err := Verify(tc.keys, tc.message, tc.sig); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: Verify() = %v, want error containing %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestParsePublicKey(t *testing.T) {
+	// The public key of minisign itself.
+	k, err := ParsePublicKey("RWQf6LRCGA9i53mlYecO4IzT51TGPpvWucNSCh1CBM0QTaLn73Y7GFO3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := k.ID(), "E7620F1842B4E81F"; got != want {
+		t.Errorf("ID() = %s, want %s", got, want)
+	}
+	for _, s := range []string{"", "not base64", "RWQf6LRC", "untrusted comment: a\nb\nc"} {
+		if _, err := ParsePublicKey(s); err == nil {
+			t.Errorf("ParsePublicKey(%q) succeeded, want error", s)
+		}
+	}
+}
@@ -22,6 +22,7 @@ const (
 	invalid findingLevel = iota
 	required
 	imported
+	referenced
 	called
 )
 
@@ -69,46 +70,40 @@ func (h *handler) OSV(e *osv.Entry) error {
 // scanned product.
 func foundAtLevel(f *govulncheck.Finding) findingLevel {
 	frame := f.Trace[0]
-	if frame.Function != "" {
+	switch {
+	case frame.Function != "" && frame.Kind == govulncheck.SymbolKindFunc:
+		// The function is referenced, say as a value,
+		// but it was not found to be called.
+		return referenced
+	case frame.Function != "":
 		return called
-	}
-	if frame.Package != "" {
+	case frame.Package != "":
 		return imported
 	}
 	return required
 }
 
-// moreSpecific favors a call finding over a non-call
-// finding and a package finding over a module finding.
+// moreSpecific favors a call finding over a reference
+// finding, a symbol finding over a package finding, and a
+// package finding over a module finding.
 func moreSpecific(f1, f2 *govulncheck.Finding) int {
-	if len(f1.Trace) > 1 && len(f2.Trace) > 1 {
-		// Both are call stack findings.
-		return 0
-	}
-	if len(f1.Trace) > 1 {
-		return -1
-	}
-	if len(f2.Trace) > 1 {
-		return 1
-	}
-
-	fr1, fr2 := f1.Trace[0], f2.Trace[0]
-	if fr1.Function != "" && fr2.Function == "" {
+	l1, l2 := foundAtLevel(f1), foundAtLevel(f2)
+	switch {
+	case l1 > l2:
 		return -1
-	}
-	if fr1.Function == "" && fr2.Function != "" {
+	case l1 < l2:
 		return 1
 	}
-	if fr1.Package != "" && fr2.Package == "" {
-		return -1
-	}
-	if fr1.Package == "" && fr2.Package != "" {
-		return -1
-	}
-	return 0 // findings always have module info
+	return 0
 }
 
 func (h *handler) Finding(f *govulncheck.Finding) error {
+	// The entries of the vulnerabilities found are reported in full.
+	if e := h.osvs[f.OSV]; e != nil {
+		if err := e.Load(); err != nil {
+			return err
+		}
+	}
 	fs := h.findings[f.OSV]
 	if len(fs) == 0 {
 		fs = []*govulncheck.Finding{f}
@@ -140,13 +135,18 @@ func (h *handler) Flush() error {
 }
 
 func toVex(h *handler) Document {
+	return NewDocument(statements(h))
+}
+
+// NewDocument returns the VEX document of govulncheck holding statements.
+func NewDocument(statements []Statement) Document {
 	doc := Document{
 		Context:    ContextURI,
 		Author:     DefaultAuthor,
 		Timestamp:  time.Now().UTC(),
 		Version:    1,
 		Tooling:    Tooling,
-		Statements: statements(h),
+		Statements: statements,
 	}
 
 	id := hashVex(doc)
@@ -154,6 +154,30 @@ func toVex(h *handler) Document {
 	return doc
 }
 
+// NewStatement returns the statement with status of the vulnerability
+// of entry for the vulnerable dependencies of findings.
+func NewStatement(entry *osv.Entry, findings []*govulncheck.Finding, status string) Statement {
+	description := entry.Summary
+	if description == "" {
+		description = entry.Details
+	}
+	return Statement{
+		Vulnerability: Vulnerability{
+			ID:          fmt.Sprintf("https://pkg.go.dev/vuln/%s", entry.ID),
+			Name:        entry.ID,
+			Description: description,
+			Aliases:     entry.Aliases,
+		},
+		Products: []Product{
+			{
+				Component:     Component{ID: DefaultPID},
+				Subcomponents: subcomponentSet(findings),
+			},
+		},
+		Status: status,
+	}
+}
+
 // Given a slice of findings, returns those findings as a set of subcomponents
 // that are unique per the vulnerable artifact's PURL.
 func subcomponentSet(findings []*govulncheck.Finding) []Component {
@@ -192,31 +216,18 @@ func statements(h *handler) []Statement {
 		if len(h.findings[id]) == 0 {
 			continue
 		}
-		description := osv.Summary
-		if description == "" {
-			description = osv.Details
-		}
-
-		s := Statement{
-			Vulnerability: Vulnerability{
-				ID:          fmt.Sprintf("https://pkg.go.dev/vuln/%s", id),
-				Name:        id,
-				Description: description,
-				Aliases:     osv.Aliases,
-			},
-			Products: []Product{
-				{
-					Component:     Component{ID: DefaultPID},
-					Subcomponents: subcomponentSet(h.findings[id]),
-				},
-			},
-		}
+		s := NewStatement(osv, h.findings[id], "")
 
 		// Findings are guaranteed to be at the same level, so we can just check the first element
 		fLevel := foundAtLevel(h.findings[id][0])
-		if fLevel >= scanLevel {
+		switch {
+		case fLevel >= scanLevel:
 			s.Status = StatusAffected
-		} else {
+		case fLevel == referenced:
+			// Referenced functions may be called through
+			// values, which the analysis cannot rule out.
+			s.Status = StatusUnderInvestigation
+		default:
 			s.Status = StatusNotAffected
 			s.ImpactStatement = Impact
 			s.Justification = JustificationNotPresent
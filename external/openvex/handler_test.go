@@ -127,6 +127,11 @@ func TestMoreSpecific(t *testing.T) {
 			Function: f,
 		}
 	}
+	ref := func(m, p, f string) *govulncheck.Frame {
+		fr := frame(m, p, f)
+		fr.Kind = govulncheck.SymbolKindFunc
+		return fr
+	}
 
 	for _, tc := range []struct {
 		name   string
@@ -164,6 +169,18 @@ func TestMoreSpecific(t *testing.T) {
 			[]*govulncheck.Frame{
 				frame("m1", "p1", "v2"), frame("m1", "p1", "f1")},
 		},
+		{"sym-vs-ref", -1,
+			[]*govulncheck.Frame{
+				frame("m1", "p1", "v1"), frame("m1", "p1", "f1")},
+			[]*govulncheck.Frame{
+				ref("m1", "p1", "v2"), frame("m1", "p1", "f2")},
+		},
+		{"ref-vs-pkg", -1,
+			[]*govulncheck.Frame{
+				ref("m1", "p1", "v1"), frame("m1", "p1", "f1")},
+			[]*govulncheck.Frame{
+				frame("m1", "p1", "")},
+		},
 		{"mod-vs-mod", 0,
 			[]*govulncheck.Frame{
 				frame("m1", "", "")},
@@ -23,12 +23,15 @@ const (
 	DefaultPID    = "Unknown Product"
 
 	// The following are defined by the VEX standard.
-	StatusAffected    = "affected"
-	StatusNotAffected = "not_affected"
+	StatusAffected           = "affected"
+	StatusNotAffected        = "not_affected"
+	StatusUnderInvestigation = "under_investigation"
 
 	// The following are defined by the VEX standard.
-	JustificationNotExecuted = "vulnerable_code_not_in_execute_path"
-	JustificationNotPresent  = "vulnerable_code_not_present"
+	JustificationNotExecuted     = "vulnerable_code_not_in_execute_path"
+	JustificationNotPresent      = "vulnerable_code_not_present"
+	JustificationNotControllable = "vulnerable_code_cannot_be_controlled_by_adversary"
+	JustificationMitigated       = "inline_mitigations_already_exist"
 )
 
 // Document is the top-level struct for a VEX document.
@@ -79,6 +82,10 @@ type Statement struct {
 	// If the status is not_affected, this must be filled. For govulncheck, this will always be:
 	// "Govulncheck determined that the vulnerable code isn't called"
 	ImpactStatement string `json:"impact_statement,omitempty"`
+
+	// If the status is affected, this describes the action remediating
+	// the vulnerability, such as the upgrade of the vulnerable module.
+	ActionStatement string `json:"action_statement,omitempty"`
 }
 
 // Vulnerability captures a vulnerability and its identifiers/aliases.
@@ -0,0 +1,87 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package osv
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// deferSize is the size of the encoding of an entry above which
+// DecodeEntry defers the decoding of its details, references, and
+// credits.
+const deferSize = 16 << 10
+
+// Deferred holds the encoding of an entry whose
+// fields DecodeEntry did not all decode.
+type Deferred struct {
+	data []byte
+}
+
+// entryFields has the fields of Entry, but not its methods.
+type entryFields Entry
+
+// skipped is the type of the fields DecodeEntry skips. The decoder
+// only checks their syntax, without allocating their values.
+type skipped struct{}
+
+func (*skipped) UnmarshalJSON([]byte) error { return nil }
+
+// DecodeEntry decodes the entry encoded in data. The details,
+// references, and credits of some entries take hundreds of KB but are
+// only needed to report the vulnerabilities found, not to match them
+// against the code, so the decoding of those of large entries is
+// deferred: they are skipped, and decoded by Load from data, which the
+// caller must not modify afterwards.
+func DecodeEntry(data []byte) (*Entry, error) {
+	var e Entry
+	if len(data) <= deferSize {
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, err
+		}
+		return &e, nil
+	}
+	// The fields of v dominate the fields of Entry with the same names.
+	v := struct {
+		*entryFields
+		Details    skipped `json:"details"`
+		References skipped `json:"references"`
+		Credits    skipped `json:"credits"`
+	}{entryFields: (*entryFields)(&e)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	e.Deferred = &Deferred{data: data}
+	return &e, nil
+}
+
+// Load decodes the fields of e whose decoding DecodeEntry deferred, if
+// any. It must be called before these fields are read or modified, and
+// not concurrently with other uses of e.
+func (e *Entry) Load() error {
+	if e.Deferred == nil {
+		return nil
+	}
+	var v struct {
+		Details    string      `json:"details"`
+		References []Reference `json:"references"`
+		Credits    []Credit    `json:"credits"`
+	}
+	if err := json.Unmarshal(e.Deferred.data, &v); err != nil {
+		return fmt.Errorf("decoding %s: %w", e.ID, err)
+	}
+	e.Details, e.References, e.Credits = v.Details, v.References, v.Credits
+	e.Deferred = nil
+	return nil
+}
+
+// MarshalJSON encodes e, whose fields not decoded
+// by DecodeEntry, if any, are decoded first.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	if err := e.Load(); err != nil {
+		return nil, err
+	}
+	return json.Marshal((*entryFields)(&e))
+}
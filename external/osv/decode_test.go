@@ -0,0 +1,73 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package osv
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestDecodeEntry(t *testing.T) {
+	entry := func(details string) *Entry {
+		return &Entry{
+			ID:         "GO-0000-0001",
+			Summary:    "A vulnerability",
+			Details:    details,
+			Affected:   []Affected{{Module: Module{Path: "example.com/m"}, Ranges: []Range{{Type: RangeTypeSemver, Events: []RangeEvent{{Introduced: "0"}}}}}},
+			References: []Reference{{Type: ReferenceTypeWeb, URL: "https://example.com/advisory"}},
+			Credits:    []Credit{{Name: "someone"}},
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		details  string
+		deferred bool
+	}{
+		{"small", "Some details.", false},
+		{"large", strings.Repeat("Some details. ", deferSize/10), true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want := entry(tc.details)
+			data, err := json.Marshal(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := DecodeEntry(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got.Deferred != nil) != tc.deferred {
+				t.Fatalf("got deferred %t; want %t", got.Deferred != nil, tc.deferred)
+			}
+			if tc.deferred {
+				// The entry can be matched before it is loaded.
+				if got.Details != "" || got.References != nil || got.Credits != nil {
+					t.Errorf("deferred fields decoded: %+v", got)
+				}
+				if diff := cmp.Diff(want.Affected, got.Affected); diff != "" {
+					t.Errorf("affected mismatch (-want, +got):\n%s", diff)
+				}
+				// Encoding it encodes all of its fields.
+				enc, err := json.Marshal(got)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(enc) != string(data) {
+					t.Errorf("got encoding\n%s\nwant\n%s", enc, data)
+				}
+			}
+			if err := got.Load(); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("loaded entry mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
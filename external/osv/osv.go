@@ -130,6 +130,28 @@ type Reference struct {
 	URL string `json:"url"`
 }
 
+// SeverityType is the type of a severity score.
+type SeverityType string
+
+const (
+	// SeverityTypeCVSSV3 is a CVSS v3 vector string.
+	SeverityTypeCVSSV3 = SeverityType("CVSS_V3")
+	// SeverityTypeCVSSV4 is a CVSS v4 vector string.
+	SeverityTypeCVSSV4 = SeverityType("CVSS_V4")
+)
+
+// Severity is a quantitative severity score of the vulnerability.
+// It is not published by the Go vulnerability database, but can be
+// added from other sources by govulncheck.
+//
+// See https://ossf.github.io/osv-schema/#severity-field.
+type Severity struct {
+	// The type of the score. Required.
+	Type SeverityType `json:"type"`
+	// The score, such as a CVSS vector string. Required.
+	Score string `json:"score"`
+}
+
 // Affected gives details about a module affected by the vulnerability.
 //
 // See https://ossf.github.io/osv-schema/#affected-fields.
@@ -212,9 +234,15 @@ type Entry struct {
 	// Credits contains credits to entities that helped find or fix the
 	// vulnerability.
 	Credits []Credit `json:"credits,omitempty"`
+	// Severity contains severity scores of the vulnerability.
+	Severity []Severity `json:"severity,omitempty"`
 	// DatabaseSpecific contains additional information about the
 	// vulnerability, specific to the Go vulnerability database.
 	DatabaseSpecific *DatabaseSpecific `json:"database_specific,omitempty"`
+	// Deferred, if set, holds the encoding of the Details, References,
+	// and Credits of the entry, whose decoding DecodeEntry deferred
+	// until Load is called.
+	Deferred *Deferred `json:"-"`
 }
 
 // Credit represents a credit for the discovery, confirmation, patch, or
@@ -237,4 +265,17 @@ type DatabaseSpecific struct {
 	URL string `json:"url,omitempty"`
 	// The review status of this report (UNREVIEWED or REVIEWED).
 	ReviewStatus ReviewStatus `json:"review_status,omitempty"`
+	// The URL of the database the entry was read from, when several
+	// databases are read at once. It is set by the client reading
+	// them rather than by the databases.
+	Source string `json:"source,omitempty"`
+	// The qualitative severity of the vulnerability, such as HIGH,
+	// when it is added from another source by govulncheck.
+	Severity string `json:"severity,omitempty"`
+	// The priority of the vulnerability, such as P1, when it is
+	// added from the ratings of an organization by govulncheck.
+	Priority string `json:"priority,omitempty"`
+	// The sources of the information added to the entry by govulncheck,
+	// such as "github:GHSA-xxxx-xxxx-xxxx" for a GitHub advisory.
+	Enrichment []string `json:"enrichment,omitempty"`
 }
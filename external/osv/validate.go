@@ -0,0 +1,356 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package osv
+
+import (
+	"fmt"
+	"go/token"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// A Problem is a way in which an entry does not conform
+// to the Go OSV format.
+type Problem struct {
+	// Field is the path of the JSON field of the problem,
+	// such as "affected[0].ranges[0].events[1]", or "" if
+	// the problem is with the entry as a whole.
+	Field string
+
+	// Message describes the problem.
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Field == "" {
+		return p.Message
+	}
+	return p.Field + ": " + p.Message
+}
+
+// Validate returns the problems of e, in the order of its fields, or
+// nil if it conforms to the Go OSV format. It checks that the required
+// fields are present, that the events of each range are valid versions
+// alternately introducing and fixing the vulnerability in increasing
+// order, and the constraints of the Go ecosystem, such as that the
+// affected packages are in their module.
+//
+// Entries with problems may still be read, but govulncheck may match
+// them against the wrong versions or code.
+func Validate(e *Entry) []Problem {
+	v := &validator{}
+	v.entry(e)
+	return v.problems
+}
+
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) report(field, format string, args ...any) {
+	v.problems = append(v.problems, Problem{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) entry(e *Entry) {
+	if e.ID == "" {
+		v.report("id", "missing")
+	}
+	if e.Modified.IsZero() {
+		v.report("modified", "missing")
+	} else if e.Published.After(e.Modified) {
+		v.report("published", "after the modified time %s", e.Modified.Format(time.RFC3339))
+	}
+	for i, alias := range e.Aliases {
+		if alias == "" {
+			v.report(fmt.Sprintf("aliases[%d]", i), "empty")
+		}
+	}
+	if len(e.Affected) == 0 {
+		v.report("affected", "missing")
+	}
+	for i, a := range e.Affected {
+		v.affected(fmt.Sprintf("affected[%d]", i), &a)
+	}
+	for i, r := range e.References {
+		field := fmt.Sprintf("references[%d]", i)
+		switch r.Type {
+		case ReferenceTypeAdvisory, ReferenceTypeArticle, ReferenceTypeReport,
+			ReferenceTypeFix, ReferenceTypePackage, ReferenceTypeEvidence, ReferenceTypeWeb:
+		case "":
+			v.report(field+".type", "missing")
+		default:
+			v.report(field+".type", "unknown reference type %q", r.Type)
+		}
+		if r.URL == "" {
+			v.report(field+".url", "missing")
+		} else if u, err := url.Parse(r.URL); err != nil || !u.IsAbs() {
+			v.report(field+".url", "%q is not an absolute URL", r.URL)
+		}
+	}
+	for i, c := range e.Credits {
+		if c.Name == "" {
+			v.report(fmt.Sprintf("credits[%d].name", i), "missing")
+		}
+	}
+	for i, s := range e.Severity {
+		field := fmt.Sprintf("severity[%d]", i)
+		switch s.Type {
+		case SeverityTypeCVSSV3, SeverityTypeCVSSV4:
+		case "":
+			v.report(field+".type", "missing")
+		default:
+			v.report(field+".type", "unknown severity type %q", s.Type)
+		}
+		if s.Score == "" {
+			v.report(field+".score", "missing")
+		}
+	}
+}
+
+func (v *validator) affected(field string, a *Affected) {
+	path := a.Module.Path
+	switch {
+	case path == "":
+		v.report(field+".package.name", "missing")
+	case !validModulePath(path):
+		v.report(field+".package.name", "invalid module path %q", path)
+	}
+	if a.Module.Ecosystem != GoEcosystem {
+		v.report(field+".package.ecosystem", "%q is not %q", a.Module.Ecosystem, GoEcosystem)
+	}
+	for i, r := range a.Ranges {
+		v.versionRange(fmt.Sprintf("%s.ranges[%d]", field, i), &r)
+	}
+	for i, p := range a.EcosystemSpecific.Packages {
+		v.pkg(fmt.Sprintf("%s.ecosystem_specific.imports[%d]", field, i), path, &p)
+	}
+}
+
+// versionRange checks that the events of r are valid versions
+// alternately introducing and fixing the vulnerability, starting
+// with an introduced version, in increasing order.
+func (v *validator) versionRange(field string, r *Range) {
+	if r.Type != RangeTypeSemver {
+		v.report(field+".type", "%q is not %q", r.Type, RangeTypeSemver)
+		return
+	}
+	if len(r.Events) == 0 {
+		v.report(field+".events", "missing")
+		return
+	}
+	var prev *semver // the version of the previous valid event
+	introduced := false
+	for i, ev := range r.Events {
+		efield := fmt.Sprintf("%s.events[%d]", field, i)
+		if (ev.Introduced == "") == (ev.Fixed == "") {
+			v.report(efield, "must have exactly one of introduced and fixed")
+			continue
+		}
+		kind, version := "introduced", ev.Introduced
+		if ev.Fixed != "" {
+			kind, version = "fixed", ev.Fixed
+		}
+		efield += "." + kind
+		var sv *semver
+		if kind == "introduced" && version == "0" {
+			sv = &semver{} // before any version
+		} else if sv = parseSemver(version); sv == nil {
+			if strings.HasPrefix(version, "v") {
+				v.report(efield, "version %q has a v prefix", version)
+			} else {
+				v.report(efield, "invalid version %q", version)
+			}
+			continue
+		}
+		switch {
+		case kind == "introduced" && introduced:
+			v.report(efield, "follows another introduced version with no fixed version between them")
+		case kind == "fixed" && !introduced:
+			if i == 0 {
+				v.report(efield, "is not preceded by an introduced version")
+			} else {
+				v.report(efield, "follows another fixed version with no introduced version between them")
+			}
+		}
+		introduced = kind == "introduced"
+		if prev != nil && compareSemver(sv, prev) <= 0 {
+			v.report(efield, "version %q is not greater than the version of the previous event", version)
+		}
+		prev = sv
+	}
+}
+
+func (v *validator) pkg(field, modulePath string, p *Package) {
+	switch {
+	case p.Path == "":
+		v.report(field+".path", "missing")
+	case modulePath == GoStdModulePath:
+		if first, _, _ := strings.Cut(p.Path, "/"); strings.Contains(first, ".") || first == "cmd" {
+			v.report(field+".path", "%q is not a standard library package", p.Path)
+		}
+	case modulePath == GoCmdModulePath:
+		if p.Path != "cmd" && !strings.HasPrefix(p.Path, "cmd/") {
+			v.report(field+".path", "%q is not a toolchain package", p.Path)
+		}
+	case modulePath != "":
+		if p.Path != modulePath && !strings.HasPrefix(p.Path, modulePath+"/") {
+			v.report(field+".path", "%q is not in module %s", p.Path, modulePath)
+		}
+	}
+	for i, goos := range p.GOOS {
+		if goos == "" {
+			v.report(fmt.Sprintf("%s.goos[%d]", field, i), "empty")
+		}
+	}
+	for i, goarch := range p.GOARCH {
+		if goarch == "" {
+			v.report(fmt.Sprintf("%s.goarch[%d]", field, i), "empty")
+		}
+	}
+	seen := make(map[string]bool)
+	for i, sym := range p.Symbols {
+		sfield := fmt.Sprintf("%s.symbols[%d]", field, i)
+		if !validSymbol(sym) {
+			v.report(sfield, "%q is not a function or a method of the form <recv>.<method>", sym)
+		} else if seen[sym] {
+			v.report(sfield, "%q is listed again", sym)
+		}
+		seen[sym] = true
+	}
+}
+
+// validSymbol reports whether sym is the name of a function,
+// or of a method in the form <recv>.<method>.
+func validSymbol(sym string) bool {
+	recv, name, ok := strings.Cut(sym, ".")
+	if !ok {
+		return token.IsIdentifier(sym)
+	}
+	return token.IsIdentifier(recv) && token.IsIdentifier(name)
+}
+
+// validModulePath reports whether path is a plausible module path,
+// checking less than the go command, which this package cannot import.
+func validModulePath(path string) bool {
+	if path == GoStdModulePath || path == GoCmdModulePath {
+		return true
+	}
+	if strings.HasPrefix(path, "/") || strings.HasSuffix(path, "/") || strings.Contains(path, "//") {
+		return false
+	}
+	for _, elem := range strings.Split(path, "/") {
+		if elem == "." || elem == ".." {
+			return false
+		}
+	}
+	return !strings.ContainsAny(path, " \t\n\\\"'`:;<>|*?")
+}
+
+// semver is a parsed semantic version, as in
+// https://semver.org/spec/v2.0.0.html, whose build
+// metadata is ignored.
+type semver struct {
+	major, minor, patch string
+	prerelease          []string
+}
+
+// parseSemver parses v, which has no v prefix,
+// reporting nil if it is not a semantic version.
+func parseSemver(v string) *semver {
+	v, _, _ = strings.Cut(v, "+")
+	v, pre, hasPre := strings.Cut(v, "-")
+	nums := strings.Split(v, ".")
+	if len(nums) != 3 {
+		return nil
+	}
+	for _, n := range nums {
+		if !isNum(n) {
+			return nil
+		}
+	}
+	sv := &semver{major: nums[0], minor: nums[1], patch: nums[2]}
+	if hasPre {
+		sv.prerelease = strings.Split(pre, ".")
+		for _, id := range sv.prerelease {
+			if id == "" || strings.Trim(id, "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ-") != "" {
+				return nil
+			}
+			if isDigits(id) && !isNum(id) {
+				return nil // numeric identifiers have no leading zeros
+			}
+		}
+	}
+	return sv
+}
+
+// isNum reports whether s is a number with no leading zeros.
+func isNum(s string) bool {
+	return isDigits(s) && (s == "0" || s[0] != '0')
+}
+
+func isDigits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+// compareSemver returns -1, 0, or 1 as v1 is less than,
+// equal to, or greater than v2.
+func compareSemver(v1, v2 *semver) int {
+	if c := compareNum(v1.major, v2.major); c != 0 {
+		return c
+	}
+	if c := compareNum(v1.minor, v2.minor); c != 0 {
+		return c
+	}
+	if c := compareNum(v1.patch, v2.patch); c != 0 {
+		return c
+	}
+	// A version with a prerelease is less than the one without.
+	switch {
+	case len(v1.prerelease) == 0 && len(v2.prerelease) == 0:
+		return 0
+	case len(v1.prerelease) == 0:
+		return 1
+	case len(v2.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v1.prerelease) && i < len(v2.prerelease); i++ {
+		id1, id2 := v1.prerelease[i], v2.prerelease[i]
+		num1, num2 := isDigits(id1), isDigits(id2)
+		var c int
+		switch {
+		case num1 && num2:
+			c = compareNum(id1, id2)
+		case num1:
+			c = -1 // numeric identifiers are less than others
+		case num2:
+			c = 1
+		default:
+			c = strings.Compare(id1, id2)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(v1.prerelease) < len(v2.prerelease):
+		return -1
+	case len(v1.prerelease) > len(v2.prerelease):
+		return 1
+	}
+	return 0
+}
+
+// compareNum compares the numbers n1 and n2,
+// which have no leading zeros.
+func compareNum(n1, n2 string) int {
+	if len(n1) != len(n2) {
+		if len(n1) < len(n2) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(n1, n2)
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package osv_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+func validEntry() *osv.Entry {
+	return &osv.Entry{
+		ID:        "GO-2024-0001",
+		Modified:  time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		Published: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Details:   "Parse panics on malformed input.",
+		Affected: []osv.Affected{{
+			Module: osv.Module{Path: "golang.org/x/text", Ecosystem: osv.GoEcosystem},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{
+				{Introduced: "0"}, {Fixed: "0.3.7"},
+				{Introduced: "0.4.0-pre"}, {Fixed: "0.4.0"},
+			}}},
+			EcosystemSpecific: osv.EcosystemSpecific{Packages: []osv.Package{{
+				Path:    "golang.org/x/text/language",
+				Symbols: []string{"Parse", "Tag.String"},
+			}}},
+		}, {
+			Module: osv.Module{Path: osv.GoStdModulePath, Ecosystem: osv.GoEcosystem},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{{Introduced: "1.21.0-0"}, {Fixed: "1.21.5"}}}},
+			EcosystemSpecific: osv.EcosystemSpecific{Packages: []osv.Package{{
+				Path: "net/http",
+			}}},
+		}},
+		References: []osv.Reference{{Type: osv.ReferenceTypeFix, URL: "https://go.dev/cl/340830"}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		modify func(*osv.Entry)
+		want   []string
+	}{
+		{
+			name:   "valid",
+			modify: func(*osv.Entry) {},
+		},
+		{
+			name: "required",
+			modify: func(e *osv.Entry) {
+				e.ID = ""
+				e.Modified = time.Time{}
+				e.Affected = nil
+				e.References[0].URL = ""
+			},
+			want: []string{
+				"id: missing",
+				"modified: missing",
+				"affected: missing",
+				"references[0].url: missing",
+			},
+		},
+		{
+			name: "published",
+			modify: func(e *osv.Entry) {
+				e.Published = e.Modified.Add(time.Hour)
+			},
+			want: []string{"published: after the modified time 2024-02-01T00:00:00Z"},
+		},
+		{
+			name: "events",
+			modify: func(e *osv.Entry) {
+				e.Affected[0].Ranges[0].Events = []osv.RangeEvent{
+					{Fixed: "0.1.0"},
+					{Introduced: "0.3.0"},
+					{Introduced: "0.4.0"},
+					{Fixed: "0.2.0"},
+					{Introduced: "v0.5.0"},
+					{Introduced: "0.5.0", Fixed: "0.6.0"},
+					{Fixed: "0.06.0"},
+				}
+			},
+			want: []string{
+				"affected[0].ranges[0].events[0].fixed: is not preceded by an introduced version",
+				"affected[0].ranges[0].events[2].introduced: follows another introduced version with no fixed version between them",
+				`affected[0].ranges[0].events[3].fixed: version "0.2.0" is not greater than the version of the previous event`,
+				`affected[0].ranges[0].events[4].introduced: version "v0.5.0" has a v prefix`,
+				"affected[0].ranges[0].events[5]: must have exactly one of introduced and fixed",
+				`affected[0].ranges[0].events[6].fixed: invalid version "0.06.0"`,
+			},
+		},
+		{
+			name: "prereleases",
+			modify: func(e *osv.Entry) {
+				e.Affected[0].Ranges[0].Events = []osv.RangeEvent{
+					{Introduced: "1.0.0-rc.10"}, {Fixed: "1.0.0-rc.9"},
+				}
+			},
+			want: []string{
+				`affected[0].ranges[0].events[1].fixed: version "1.0.0-rc.9" is not greater than the version of the previous event`,
+			},
+		},
+		{
+			name: "ecosystem",
+			modify: func(e *osv.Entry) {
+				e.Affected[0].Module.Ecosystem = "npm"
+				e.Affected[0].Ranges[0].Type = "ECOSYSTEM"
+				e.Affected[0].EcosystemSpecific.Packages[0].Path = "golang.org/x/net/html"
+				e.Affected[0].EcosystemSpecific.Packages[0].Symbols = []string{"Parse", "Parse", "(*Tag).String"}
+				e.Affected[1].EcosystemSpecific.Packages[0].Path = "cmd/go"
+			},
+			want: []string{
+				`affected[0].package.ecosystem: "npm" is not "Go"`,
+				`affected[0].ranges[0].type: "ECOSYSTEM" is not "SEMVER"`,
+				`affected[0].ecosystem_specific.imports[0].path: "golang.org/x/net/html" is not in module golang.org/x/text`,
+				`affected[0].ecosystem_specific.imports[0].symbols[1]: "Parse" is listed again`,
+				`affected[0].ecosystem_specific.imports[0].symbols[2]: "(*Tag).String" is not a function or a method of the form <recv>.<method>`,
+				`affected[1].ecosystem_specific.imports[0].path: "cmd/go" is not a standard library package`,
+			},
+		},
+		{
+			name: "references",
+			modify: func(e *osv.Entry) {
+				e.References = append(e.References, osv.Reference{Type: "LINK", URL: "go.dev/cl/1"})
+				e.Severity = []osv.Severity{{Type: osv.SeverityTypeCVSSV3}}
+			},
+			want: []string{
+				`references[1].type: unknown reference type "LINK"`,
+				`references[1].url: "go.dev/cl/1" is not an absolute URL`,
+				"severity[0].score: missing",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			e := validEntry()
+			test.modify(e)
+			var got []string
+			for _, p := range osv.Validate(e) {
+				got = append(got, p.String())
+			}
+			if len(got) != len(test.want) {
+				t.Fatalf("Validate =\n%q\nwant\n%q", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("problem %d = %q; want %q", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
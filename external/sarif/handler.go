@@ -56,37 +56,47 @@ func (h *handler) OSV(e *osv.Entry) error {
 	return nil
 }
 
-// moreSpecific favors a call finding over a non-call
-// finding and a package finding over a module finding.
+// moreSpecific favors a call finding over a reference
+// finding, a symbol finding over a package finding, and a
+// package finding over a module finding.
 func moreSpecific(f1, f2 *govulncheck.Finding) int {
-	if len(f1.Trace) > 1 && len(f2.Trace) > 1 {
-		// Both are call stack findings.
-		return 0
-	}
-	if len(f1.Trace) > 1 {
+	p1, p2 := precision(f1), precision(f2)
+	switch {
+	case p1 > p2:
 		return -1
-	}
-	if len(f2.Trace) > 1 {
+	case p1 < p2:
 		return 1
 	}
+	return 0
+}
 
-	fr1, fr2 := f1.Trace[0], f2.Trace[0]
-	if fr1.Function != "" && fr2.Function == "" {
-		return -1
-	}
-	if fr1.Function == "" && fr2.Function != "" {
+// precision ranks the level of finding, from 0 for
+// required modules to 3 for called symbols.
+func precision(finding *govulncheck.Finding) int {
+	switch fr := finding.Trace[0]; {
+	case isReferenced(fr):
+		return 2
+	case fr.Function != "":
+		return 3
+	case fr.Package != "":
 		return 1
 	}
-	if fr1.Package != "" && fr2.Package == "" {
-		return -1
-	}
-	if fr1.Package == "" && fr2.Package != "" {
-		return -1
-	}
-	return 0 // findings always have module info
+	return 0
+}
+
+// isReferenced reports whether frame is that of a function
+// referenced, say as a value, but not found to be called.
+func isReferenced(frame *govulncheck.Frame) bool {
+	return frame.Function != "" && frame.Kind == govulncheck.SymbolKindFunc
 }
 
 func (h *handler) Finding(f *govulncheck.Finding) error {
+	// The entries of the vulnerabilities found are reported in full.
+	if e := h.osvs[f.OSV]; e != nil {
+		if err := e.Load(); err != nil {
+			return err
+		}
+	}
 	fs := h.findings[f.OSV]
 	if len(fs) == 0 {
 		fs = []*govulncheck.Finding{f}
@@ -177,9 +187,9 @@ func results(h *handler) []Result {
 	results := make([]Result, 0, len(h.findings)) // must not be nil
 	for osv, fs := range h.findings {
 		var locs []Location
-		if h.cfg.ScanMode != govulncheck.ScanModeBinary {
+		if h.cfg.ScanMode != govulncheck.ScanModeBinary && h.cfg.ScanMode != govulncheck.ScanModeSBOM {
 			// Attach result to the go.mod file for source analysis.
-			// But there is no such place for binaries.
+			// But there is no such place for binaries and SBOMs.
 			locs = []Location{{PhysicalLocation: PhysicalLocation{
 				ArtifactLocation: ArtifactLocation{
 					URI:       "go.mod",
@@ -230,6 +240,9 @@ func resultMessage(findings []*govulncheck.Finding, cfg *govulncheck.Config) str
 	main, addition := "", ""
 	const runCallAnalysis = "Run the call-level analysis to understand whether your code actually calls the vulnerabilities."
 	switch {
+	case isReferenced(frame):
+		main = fmt.Sprintf("references vulnerable functions in %d package%s (%s)", l, choose("", "s", l == 1), elemList)
+		addition = ", but doesn't appear to call them."
 	case frame.Function != "":
 		main = fmt.Sprintf("calls vulnerable functions in %d package%s (%s).", l, choose("", "s", l == 1), elemList)
 	case frame.Package != "":
@@ -254,7 +267,7 @@ func level(f *govulncheck.Finding, cfg *govulncheck.Config) string {
 	fr := f.Trace[0]
 	switch {
 	case cfg.ScanLevel.WantSymbols():
-		if fr.Function != "" {
+		if fr.Function != "" && !isReferenced(fr) {
 			return errorLevel
 		}
 		if fr.Package != "" {
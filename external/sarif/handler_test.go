@@ -255,6 +255,11 @@ func TestMoreSpecific(t *testing.T) {
 			Function: f,
 		}
 	}
+	ref := func(m, p, f string) *govulncheck.Frame {
+		fr := frame(m, p, f)
+		fr.Kind = govulncheck.SymbolKindFunc
+		return fr
+	}
 
 	for _, tc := range []struct {
 		name   string
@@ -292,6 +297,18 @@ func TestMoreSpecific(t *testing.T) {
 			[]*govulncheck.Frame{
 				frame("m1", "p1", "v2"), frame("m1", "p1", "f1")},
 		},
+		{"sym-vs-ref", -1,
+			[]*govulncheck.Frame{
+				frame("m1", "p1", "v1"), frame("m1", "p1", "f1")},
+			[]*govulncheck.Frame{
+				ref("m1", "p1", "v2"), frame("m1", "p1", "f2")},
+		},
+		{"ref-vs-pkg", -1,
+			[]*govulncheck.Frame{
+				ref("m1", "p1", "v1"), frame("m1", "p1", "f1")},
+			[]*govulncheck.Frame{
+				frame("m1", "p1", "")},
+		},
 		{"mod-vs-mod", 0,
 			[]*govulncheck.Frame{
 				frame("m1", "", "")},
@@ -323,6 +340,12 @@ func TestResultMessage(t *testing.T) {
 		}
 	}
 
+	referenced := func(m, p, f string) *govulncheck.Finding {
+		fi := finding(m, p, f)
+		fi.Trace[0].Kind = govulncheck.SymbolKindFunc
+		return fi
+	}
+
 	for _, tc := range []struct {
 		findings []*govulncheck.Finding
 		level    govulncheck.ScanLevel
@@ -330,6 +353,8 @@ func TestResultMessage(t *testing.T) {
 	}{
 		{[]*govulncheck.Finding{finding("m", "p", "f1"), finding("m", "p", "f2")}, govulncheck.ScanLevelSymbol,
 			"Your code calls vulnerable functions in 1 package (p)."},
+		{[]*govulncheck.Finding{referenced("m", "p", "f1")}, govulncheck.ScanLevelSymbol,
+			"Your code references vulnerable functions in 1 package (p), but doesn't appear to call them."},
 		{[]*govulncheck.Finding{finding("m", "p", "")}, govulncheck.ScanLevelPackage,
 			"Your code imports 1 vulnerable package (p). Run the call-level analysis to understand whether your code actually calls the vulnerabilities."},
 		{[]*govulncheck.Finding{finding("m", "p1", ""), finding("m", "p2", ""), finding("m", "p3", "")}, govulncheck.ScanLevelSymbol,
@@ -347,3 +372,21 @@ func TestResultMessage(t *testing.T) {
 		}
 	}
 }
+
+func TestLevel(t *testing.T) {
+	cfg := &govulncheck.Config{ScanLevel: govulncheck.ScanLevelSymbol}
+	for _, tc := range []struct {
+		frame *govulncheck.Frame
+		want  string
+	}{
+		{&govulncheck.Frame{Module: "m", Package: "p", Function: "f"}, errorLevel},
+		{&govulncheck.Frame{Module: "m", Package: "p", Function: "f", Kind: govulncheck.SymbolKindFunc}, warningLevel},
+		{&govulncheck.Frame{Module: "m", Package: "p"}, warningLevel},
+		{&govulncheck.Frame{Module: "m"}, informationalLevel},
+	} {
+		f := &govulncheck.Finding{Trace: []*govulncheck.Frame{tc.frame}}
+		if got := level(f, cfg); got != tc.want {
+			t.Errorf("level(%+v) = %s; want %s", tc.frame, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sbom extracts Go components from software bills of materials
+// in the CycloneDX and SPDX JSON formats.
+package sbom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Component is a Go module, or a package of a Go module, listed in an SBOM.
+type Component struct {
+	// Module is the module path.
+	Module string
+	// Version is the module version, if known.
+	Version string
+	// Package is the import path, if the component describes
+	// a package rather than a whole module.
+	Package string
+}
+
+// document contains the fields of CycloneDX and SPDX JSON
+// documents needed to find Go components.
+type document struct {
+	// CycloneDX
+	BOMFormat  string         `json:"bomFormat"`
+	Components []cdxComponent `json:"components"`
+
+	// SPDX
+	SPDXVersion string        `json:"spdxVersion"`
+	Packages    []spdxPackage `json:"packages"`
+}
+
+type cdxComponent struct {
+	PURL       string         `json:"purl"`
+	Components []cdxComponent `json:"components"`
+}
+
+type spdxPackage struct {
+	ExternalRefs []struct {
+		ReferenceType    string `json:"referenceType"`
+		ReferenceLocator string `json:"referenceLocator"`
+	} `json:"externalRefs"`
+}
+
+// ErrUnknownFormat is returned by Parse for documents that are
+// neither CycloneDX nor SPDX JSON.
+var ErrUnknownFormat = errors.New("unrecognized SBOM format: only CycloneDX and SPDX JSON are supported")
+
+// Parse reads a CycloneDX or SPDX JSON document from r and returns
+// the Go components it lists. Components that are not identified
+// by a golang package URL are ignored.
+func Parse(r io.Reader) ([]Component, error) {
+	var doc document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding SBOM: %w", err)
+	}
+
+	var purls []string
+	switch {
+	case doc.BOMFormat == "CycloneDX":
+		var visit func([]cdxComponent)
+		visit = func(cs []cdxComponent) {
+			for _, c := range cs {
+				purls = append(purls, c.PURL)
+				visit(c.Components)
+			}
+		}
+		visit(doc.Components)
+	case doc.SPDXVersion != "":
+		for _, p := range doc.Packages {
+			for _, ref := range p.ExternalRefs {
+				if ref.ReferenceType == "purl" {
+					purls = append(purls, ref.ReferenceLocator)
+				}
+			}
+		}
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	var comps []Component
+	for _, p := range purls {
+		c, ok := parsePURL(p)
+		if !ok {
+			continue
+		}
+		comps = append(comps, c)
+	}
+	return comps, nil
+}
+
+const golangPrefix = "pkg:golang/"
+
+// parsePURL parses a golang package URL of the form
+//
+//	pkg:golang/MODULE_PATH@VERSION?QUALIFIERS#SUBPATH
+//
+// where the module path might be escaped and the subpath, if
+// present, names a package of the module. It reports false if
+// p is not a golang package URL.
+func parsePURL(p string) (Component, bool) {
+	rest, ok := strings.CutPrefix(p, golangPrefix)
+	if !ok {
+		return Component{}, false
+	}
+	rest, subpath, _ := strings.Cut(rest, "#")
+	rest, _, _ = strings.Cut(rest, "?")
+	name, version := rest, ""
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		name, version = rest[:i], rest[i+1:]
+	}
+	name, err := url.PathUnescape(name)
+	if err != nil || name == "" {
+		return Component{}, false
+	}
+	if version, err = url.PathUnescape(version); err != nil {
+		return Component{}, false
+	}
+
+	c := Component{Module: name, Version: version}
+	if subpath = strings.Trim(subpath, "/"); subpath != "" {
+		c.Package = path.Join(name, subpath)
+	}
+	return c, true
+}
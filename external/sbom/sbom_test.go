@@ -0,0 +1,77 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sbom
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		doc  string
+		want []Component
+	}{
+		{
+			name: "cyclonedx",
+			doc: `{
+				"bomFormat": "CycloneDX",
+				"components": [
+					{"purl": "pkg:golang/github.com%2Fuser%2Fmodule@v0.5.7"},
+					{"purl": "pkg:npm/left-pad@1.3.0"},
+					{"purl": "pkg:golang/golang.org/x/text@v0.3.0?type=module", "components": [
+						{"purl": "pkg:golang/golang.org/x/text@v0.3.0#language"}
+					]},
+					{"name": "no purl"}
+				]
+			}`,
+			want: []Component{
+				{Module: "github.com/user/module", Version: "v0.5.7"},
+				{Module: "golang.org/x/text", Version: "v0.3.0"},
+				{Module: "golang.org/x/text", Version: "v0.3.0", Package: "golang.org/x/text/language"},
+			},
+		},
+		{
+			name: "spdx",
+			doc: `{
+				"spdxVersion": "SPDX-2.3",
+				"packages": [
+					{"externalRefs": [
+						{"referenceType": "cpe23Type", "referenceLocator": "cpe:2.3:a:golang:go:1.21.0"},
+						{"referenceType": "purl", "referenceLocator": "pkg:golang/stdlib@1.21.0"}
+					]},
+					{"externalRefs": [
+						{"referenceType": "purl", "referenceLocator": "pkg:golang/github.com/user/module@v0.5.7#sub/pkg/"}
+					]}
+				]
+			}`,
+			want: []Component{
+				{Module: "stdlib", Version: "1.21.0"},
+				{Module: "github.com/user/module", Version: "v0.5.7", Package: "github.com/user/module/sub/pkg"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	_, err := Parse(strings.NewReader(`{"packages": []}`))
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got %v; want %v", err, ErrUnknownFormat)
+	}
+}
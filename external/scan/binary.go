@@ -5,36 +5,213 @@
 package scan
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"runtime/debug"
+	"strings"
 
+	"github.com/StevenACoffman/invuln/external/archive"
 	"github.com/StevenACoffman/invuln/external/buildinfo"
 	"github.com/StevenACoffman/invuln/external/client"
 	"github.com/StevenACoffman/invuln/external/derrors"
 	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
 	"github.com/StevenACoffman/invuln/external/vulncheck"
 	"golang.org/x/tools/go/packages"
 )
 
 // runBinary detects presence of vulnerable symbols in an executable or its minimal blob representation.
+//
+// When several binaries are provided, they are scanned one after another
+// and their findings are merged into a single output stream, with each
+// finding carrying the path of the binary it originates from. Archives
+// are expanded to the Go executables they contain, which are scanned
+// from memory, as are binaries named by a URL with a registered Fetcher.
 func runBinary(ctx context.Context, handler govulncheck.Handler, cfg *config, client *client.Client) (err error) {
 	defer derrors.Wrap(&err, "govulncheck")
 
-	bin, err := createBin(cfg.patterns[0])
+	targets, err := binaryTargets(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 1 && targets[0].name == cfg.patterns[0] {
+		return scanBinary(ctx, handler, cfg, client, targets[0], false)
+	}
+	seen := make(map[string]bool) // OSV entries emitted so far
+	for _, t := range targets {
+		h := &artifactHandler{Handler: handler, artifact: t.name, seen: seen}
+		if err := scanBinary(ctx, h, cfg, client, t, true); err != nil {
+			return fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+	return nil
+}
+
+// binaryTarget is a binary to scan.
+type binaryTarget struct {
+	name string
+	// r reads the size bytes of a binary read from an archive,
+	// fetched from a URL, or given as a Binary. It is nil for
+	// files scanned from disk.
+	r    io.ReaderAt
+	size int64
+}
+
+// binaryTargets returns the binaries to scan for the patterns, or the
+// binaries, of cfg, fetching URLs and replacing archives with the Go
+// executables they contain.
+func binaryTargets(ctx context.Context, cfg *config) ([]binaryTarget, error) {
+	if cfg.binaries != nil {
+		var targets []binaryTarget
+		for _, b := range cfg.binaries {
+			ts, err := readerTargets(b.Name, b.Name, b.R, b.Size)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, ts...)
+		}
+		return targets, nil
+	}
+	var targets []binaryTarget
+	for _, p := range cfg.patterns {
+		switch {
+		case isURL(p):
+			data, err := fetchBinary(ctx, p)
+			if err != nil {
+				return nil, fmt.Errorf("fetching %s: %w", p, err)
+			}
+			u, _ := url.Parse(p)
+			ts, err := readerTargets(p, u.Path, bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, ts...)
+		case archive.IsArchiveFile(p):
+			files, err := archive.GoExecutables(p)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p, err)
+			}
+			ts, err := archiveTargets(p, files)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, ts...)
+		default:
+			targets = append(targets, binaryTarget{name: p})
+		}
+	}
+	return targets, nil
+}
+
+// readerTargets returns the binary named name of the given size read
+// from r, or the Go executables it contains if it is an archive, whose
+// format is determined from file, or else from its content.
+func readerTargets(name, file string, r io.ReaderAt, size int64) ([]binaryTarget, error) {
+	if !archive.IsArchiveReader(file, r) {
+		return []binaryTarget{{name: name, r: r, size: size}}, nil
+	}
+	files, err := archive.ReadGoExecutables(file, r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return archiveTargets(name, files)
+}
+
+// archiveTargets returns the Go executables files of the archive name.
+func archiveTargets(name string, files []archive.File) ([]binaryTarget, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: %w", name, errNoGoExecutables)
+	}
+	var targets []binaryTarget
+	for _, f := range files {
+		targets = append(targets, binaryTarget{name: name + "!/" + f.Name, r: bytes.NewReader(f.Data), size: int64(len(f.Data))})
+	}
+	return targets, nil
+}
+
+func scanBinary(ctx context.Context, handler govulncheck.Handler, cfg *config, client *client.Client, t binaryTarget, multi bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var bin *vulncheck.Bin
+	var err error
+	if t.r != nil {
+		bin, err = createBinFromReader(t.r, t.size)
+	} else {
+		bin, err = createBin(t.name)
+	}
 	if err != nil {
 		return err
 	}
 
 	p := &govulncheck.Progress{Message: binaryProgressMessage}
+	if multi {
+		p.Message = fmt.Sprintf("Scanning %s for known vulnerabilities...", t.name)
+	}
 	if err := handler.Progress(p); err != nil {
 		return err
 	}
+	if cfg.ScanLevel == govulncheck.ScanLevelStdlib {
+		return vulncheck.Stdlib(ctx, handler, bin.GoVersion, bin.GOOS, bin.GOARCH, client)
+	}
 	return vulncheck.Binary(ctx, handler, bin, &cfg.Config, client)
 }
 
+// artifactHandler annotates SBOMs and findings with the artifact they
+// originate from and makes sure each OSV entry is passed on only once
+// across the scans sharing seen.
+type artifactHandler struct {
+	govulncheck.Handler
+	artifact string
+	seen     map[string]bool
+}
+
+func (h *artifactHandler) SBOM(sbom *govulncheck.SBOM) error {
+	sbom.Artifact = h.artifact
+	return h.Handler.SBOM(sbom)
+}
+
+func (h *artifactHandler) OSV(entry *osv.Entry) error {
+	if h.seen[entry.ID] {
+		return nil
+	}
+	h.seen[entry.ID] = true
+	return h.Handler.OSV(entry)
+}
+
+func (h *artifactHandler) Finding(finding *govulncheck.Finding) error {
+	finding.Artifact = h.artifact
+	return h.Handler.Finding(finding)
+}
+
+// ReadBin returns the modules, symbols, and platform of the Go binary,
+// or blob of extract mode, at path, as read for binary scans. Failures
+// are returned as a *govulncheck.Error.
+func ReadBin(path string) (*vulncheck.Bin, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, scanError(err)
+	}
+	bin, err := createBin(path)
+	if err != nil {
+		return nil, scanError(fmt.Errorf("%s: %w", path, err))
+	}
+	return bin, nil
+}
+
+// ReadBinFromReader is ReadBin for a binary of the given size read from r.
+func ReadBinFromReader(r io.ReaderAt, size int64) (*vulncheck.Bin, error) {
+	bin, err := createBinFromReader(r, size)
+	if err != nil {
+		return nil, scanError(err)
+	}
+	return bin, nil
+}
+
 func createBin(path string) (*vulncheck.Bin, error) {
 	// First check if the path points to a Go binary. Otherwise, blob
 	// parsing might json decode a Go binary which takes time.
@@ -42,60 +219,126 @@ func createBin(path string) (*vulncheck.Bin, error) {
 	// TODO(#64716): use fingerprinting to make this precise, clean, and fast.
 	mods, packageSymbols, bi, err := buildinfo.ExtractPackagesAndSymbols(path)
 	if err == nil {
-		var main *packages.Module
-		if bi.Main.Path != "" {
-			main = &packages.Module{
-				Path:    bi.Main.Path,
-				Version: bi.Main.Version,
-			}
-		}
-
-		return &vulncheck.Bin{
-			Path:       bi.Path,
-			Main:       main,
-			Modules:    mods,
-			PkgSymbols: packageSymbols,
-			GoVersion:  bi.GoVersion,
-			GOOS:       findSetting("GOOS", bi),
-			GOARCH:     findSetting("GOARCH", bi),
-		}, nil
+		return newBin(mods, packageSymbols, bi), nil
 	}
 
 	// Otherwise, see if the path points to a valid blob.
-	bin := parseBlob(path)
+	bin, err := parseBlob(path)
+	if err != nil {
+		return nil, err
+	}
 	if bin != nil {
 		return bin, nil
 	}
 
-	return nil, errors.New("unrecognized binary format")
+	return nil, errUnrecognizedBinary
+}
+
+// createBinFromReader is like createBin for a Go executable
+// or blob of the given size read from r.
+func createBinFromReader(r io.ReaderAt, size int64) (*vulncheck.Bin, error) {
+	mods, packageSymbols, bi, err := buildinfo.ExtractPackagesAndSymbolsFromReader(r)
+	if err == nil {
+		return newBin(mods, packageSymbols, bi), nil
+	}
+	bin, err := decodeBlob(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	if bin != nil {
+		return bin, nil
+	}
+	return nil, errUnrecognizedBinary
+}
+
+func newBin(mods []*packages.Module, packageSymbols []buildinfo.Symbol, bi *debug.BuildInfo) *vulncheck.Bin {
+	var main *packages.Module
+	if bi.Main.Path != "" {
+		main = &packages.Module{
+			Path:    bi.Main.Path,
+			Version: bi.Main.Version,
+		}
+	}
+
+	precision := findSetting(buildinfo.SymbolPrecisionSetting, bi)
+	if precision == buildinfo.SymbolsComplete {
+		precision = ""
+	}
+
+	bin := &vulncheck.Bin{
+		Path:            bi.Path,
+		Main:            main,
+		Modules:         mods,
+		PkgSymbols:      packageSymbols,
+		GoVersion:       bi.GoVersion,
+		GOOS:            findSetting("GOOS", bi),
+		GOARCH:          findSetting("GOARCH", bi),
+		SymbolPrecision: precision,
+	}
+	if libs := findSetting(buildinfo.ImportedLibrariesSetting, bi); libs != "" {
+		bin.ImportedLibraries = strings.Split(libs, ",")
+	}
+	return bin
+}
+
+// binaryPlatform returns the GOOS and GOARCH of the binary or blob
+// at path. Empty values are returned if they cannot be determined.
+func binaryPlatform(path string) (goos, goarch string) {
+	if goos, goarch, err := buildinfo.ExtractPlatform(path); err == nil {
+		return goos, goarch
+	}
+	if bin, _ := parseBlob(path); bin != nil {
+		return bin.GOOS, bin.GOARCH
+	}
+	return "", ""
 }
 
 // parseBlob extracts vulncheck.Bin from a valid blob at path.
-// If it cannot recognize a valid blob, returns nil.
-func parseBlob(path string) *vulncheck.Bin {
+// If it cannot recognize a valid blob, returns nil. Blobs of
+// unsupported versions of extract mode are reported as errors.
+func parseBlob(path string) (*vulncheck.Bin, error) {
 	from, err := os.Open(path)
 	if err != nil {
-		return nil
+		return nil, nil
 	}
 	defer from.Close()
+	return decodeBlob(from)
+}
 
-	dec := json.NewDecoder(from)
+// decodeBlob extracts vulncheck.Bin from a valid blob read from r.
+// If it cannot recognize a valid blob, returns nil. Blobs of
+// unsupported versions of extract mode are reported as errors.
+func decodeBlob(r io.Reader) (*vulncheck.Bin, error) {
+	dec := json.NewDecoder(r)
 
 	var h header
 	if err := dec.Decode(&h); err != nil {
-		return nil // no header
-	} else if h.Name != extractModeID || h.Version != extractModeVersion {
-		return nil // invalid header
+		return nil, nil // no header
+	} else if h.Name != extractModeID {
+		return nil, nil // invalid header
+	} else if !supportedBlobVersion(h.Version) {
+		return nil, fmt.Errorf("unsupported extract version %q: want %s or %s", h.Version, extractModeUncompressedVersion, extractModeVersion)
+	}
+	dec, err := blobBody(h, dec, r)
+	if err != nil {
+		return nil, err
 	}
 
 	var b vulncheck.Bin
 	if err := dec.Decode(&b); err != nil {
-		return nil // no body
+		return nil, nil // no body
 	}
 	if dec.More() {
-		return nil // we want just header and body, nothing else
+		return nil, nil // we want just header and body, nothing else
 	}
-	return &b
+	return &b, nil
+}
+
+// supportedBlobVersion reports whether blobs of the given version
+// can be read, that is, whether it is one of the versions written:
+// extractModeUncompressedVersion, or extractModeVersion.
+func supportedBlobVersion(version string) bool {
+	return version == extractModeUncompressedVersion || version == extractModeVersion
 }
 
 // findSetting returns value of setting from bi if present.
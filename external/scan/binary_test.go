@@ -0,0 +1,196 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/test"
+)
+
+func TestArtifactHandler(t *testing.T) {
+	mock := test.NewMockHandler()
+	seen := make(map[string]bool)
+	for _, artifact := range []string{"bin/a", "bin/b"} {
+		h := &artifactHandler{Handler: mock, artifact: artifact, seen: seen}
+		if err := h.SBOM(&govulncheck.SBOM{GOOS: "linux", GOARCH: "amd64"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.OSV(&osv.Entry{ID: "GO-0000-0001"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Finding(&govulncheck.Finding{OSV: "GO-0000-0001"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := len(mock.OSVMessages); got != 1 {
+		t.Errorf("got %d OSV messages; want 1", got)
+	}
+	if got := len(mock.FindingMessages); got != 2 {
+		t.Fatalf("got %d findings; want 2", got)
+	}
+	if got := len(mock.SBOMMessages); got != 2 {
+		t.Fatalf("got %d SBOM messages; want 2", got)
+	}
+	for i, want := range []string{"bin/a", "bin/b"} {
+		if got := mock.FindingMessages[i].Artifact; got != want {
+			t.Errorf("finding %d: got artifact %q; want %q", i, got, want)
+		}
+		if got := mock.SBOMMessages[i].Artifact; got != want {
+			t.Errorf("SBOM %d: got artifact %q; want %q", i, got, want)
+		}
+	}
+}
+
+func TestBinaryTargets(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeZip := func(name string, files map[string][]byte) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		zw := zip.NewWriter(f)
+		for n, d := range files {
+			w, err := zw.Create(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(d)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	app := writeZip("app.zip", map[string][]byte{"bin/app": data, "README": []byte("readme")})
+	empty := writeZip("empty.zip", map[string][]byte{"README": []byte("readme")})
+
+	targets, err := binaryTargets(context.Background(), &config{patterns: []string{"bin/plain", app}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("got %d targets; want 2", len(targets))
+	}
+	if targets[0].name != "bin/plain" || targets[0].r != nil {
+		t.Errorf("got target %q; want bin/plain read from disk", targets[0].name)
+	}
+	if want := app + "!/bin/app"; targets[1].name != want || targets[1].r == nil {
+		t.Errorf("got target %q; want %q read from memory", targets[1].name, want)
+	}
+	if _, err := createBinFromReader(targets[1].r, targets[1].size); err != nil {
+		t.Errorf("createBinFromReader: %v", err)
+	}
+
+	if _, err := binaryTargets(context.Background(), &config{patterns: []string{empty}}); err == nil {
+		t.Errorf("want error for archive without Go executables")
+	}
+
+	// Binaries read from readers, with an archive named
+	// without extension, as under a content hash.
+	zipData, err := os.ReadFile(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bins := []Binary{
+		{Name: "objects/plain", R: bytes.NewReader(data), Size: int64(len(data))},
+		{Name: "objects/3f2a", R: bytes.NewReader(zipData), Size: int64(len(zipData))},
+	}
+	targets, err = binaryTargets(context.Background(), &config{binaries: bins})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0].name != "objects/plain" || targets[1].name != "objects/3f2a!/bin/app" {
+		t.Fatalf("got targets %v; want objects/plain and the executable of objects/3f2a", targets)
+	}
+	for _, target := range targets {
+		if _, err := createBinFromReader(target.r, target.size); err != nil {
+			t.Errorf("createBinFromReader(%s): %v", target.name, err)
+		}
+	}
+}
+
+func TestBinaryTargetsURL(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	data, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tool_linux_amd64" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	var fetched []string
+	RegisterFetcher("govulncheck-test", FetcherFunc(func(ctx context.Context, u *url.URL) (io.ReadCloser, error) {
+		fetched = append(fetched, u.Host+u.Path)
+		return io.NopCloser(strings.NewReader(string(data))), nil
+	}))
+
+	patterns := []string{srv.URL + "/tool_linux_amd64", "govulncheck-test://bucket/tool"}
+	targets, err := binaryTargets(context.Background(), &config{patterns: patterns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("got %d targets; want 2", len(targets))
+	}
+	for i, target := range targets {
+		if target.name != patterns[i] || target.size != int64(len(data)) {
+			t.Errorf("got target %q with %d bytes; want %q with %d bytes", target.name, target.size, patterns[i], len(data))
+		}
+	}
+	if len(fetched) != 1 || fetched[0] != "bucket/tool" {
+		t.Errorf("registered fetcher fetched %v; want [bucket/tool]", fetched)
+	}
+
+	if _, err := binaryTargets(context.Background(), &config{patterns: []string{srv.URL + "/missing"}}); err == nil {
+		t.Error("want error for missing binary")
+	}
+	if isURL("unregistered://host/tool") {
+		t.Error("isURL reports true for a scheme without fetcher")
+	}
+
+	// Binaries larger than maxBinarySize are rejected, whether their
+	// size is announced by the server or only found while reading.
+	defer func(size int64) { maxBinarySize = size }(maxBinarySize)
+	maxBinarySize = int64(len(data)) - 1
+	for _, p := range patterns {
+		if _, err := binaryTargets(context.Background(), &config{patterns: []string{p}}); err == nil || !strings.Contains(err.Error(), "larger than") {
+			t.Errorf("binaryTargets(%q) = %v; want error for binary larger than maxBinarySize", p, err)
+		}
+	}
+}
@@ -0,0 +1,524 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"golang.org/x/tools/go/packages"
+)
+
+// resultCache stores the OSV entries and findings of previously
+// analyzed packages in files named by their cache key, or, if mem
+// is set, in memory.
+type resultCache struct {
+	dir string
+	mem map[string][]*govulncheck.Message
+}
+
+// cacheDirs are the directories of the caches of govulncheck under its
+// cache root, which, as named by GOVULNDB_CACHE, may hold other data.
+var cacheDirs = []string{"db", "nvd", "results", "scans", "callgraphs"}
+
+// cacheRoot returns the directory of the caches of govulncheck: the
+// GOVULNDB_CACHE variable of env, or a directory under the user's
+// cache directory.
+func cacheRoot(env []string) (string, error) {
+	if dir := lookupEnv(env, "GOVULNDB_CACHE"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "govulncheck"), nil
+}
+
+// openResultCache returns the result cache in the directory named
+// name under the cache root of env, creating it if needed.
+func openResultCache(env []string, name string) (*resultCache, error) {
+	dir, err := cacheRoot(env)
+	if err != nil {
+		return nil, err
+	}
+	dir = filepath.Join(dir, name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &resultCache{dir: dir}, nil
+}
+
+// newMemoryResultCache returns a result cache
+// that only lasts as long as the process.
+func newMemoryResultCache() *resultCache {
+	return &resultCache{mem: make(map[string][]*govulncheck.Message)}
+}
+
+func (c *resultCache) get(key string) ([]*govulncheck.Message, bool) {
+	if c.mem != nil {
+		msgs, ok := c.mem[key]
+		return msgs, ok
+	}
+	data, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var msgs []*govulncheck.Message
+	if err := json.Unmarshal(data, &msgs); err != nil {
+		return nil, false
+	}
+	return msgs, true
+}
+
+func (c *resultCache) put(key string, msgs []*govulncheck.Message) error {
+	if c.mem != nil {
+		c.mem[key] = msgs
+		return nil
+	}
+	data, err := json.Marshal(msgs)
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so that concurrent
+	// runs never observe a partially written entry.
+	f, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(c.dir, key+".json"))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// cacheUnit is a top-level package, along with its test variants,
+// whose results are cached as a whole.
+type cacheUnit struct {
+	path string          // package path, used as the pattern for analysis
+	key  string          // cache key
+	pkgs map[string]bool // paths of the packages in the import closure
+	mods map[string]bool // paths of the modules in the import closure
+}
+
+// runSourceCached is like runSource, but it reuses the results of
+// previous runs for the top-level packages whose import closure, Go
+// version, build configuration, and vulnerability database did not
+// change, and only analyzes the remaining ones.
+//
+// Package loading without syntax or types is cheap compared to the
+// rest of the analysis, so it is always done to compute cache keys.
+//
+// The results are those of cfg.results if set, as for -watch, and
+// otherwise those of the result cache of -cache, which also caches
+// call graphs.
+func runSourceCached(ctx context.Context, handler govulncheck.Handler, cfg *config, client *client.Client, dir string) error {
+	ucfg := *cfg
+	ucfg.cache = false
+	ucfg.results = nil
+	ucfg.callGraphCache = cfg.callGraphCache || cfg.cache
+	if !cfg.ScanLevel.WantPackages() || cfg.DBLastModified == nil || cfg.gopath || cfg.importcfg != "" ||
+		len(cfg.patterns) == 0 || !gomodExists(dir) {
+		return runSource(ctx, handler, &ucfg, client, dir)
+	}
+	cache := cfg.results
+	if cache == nil {
+		var err error
+		if cache, err = openResultCache(cfg.env, "results"); err != nil {
+			return runSource(ctx, handler, &ucfg, client, dir)
+		}
+	}
+	graph := vulncheck.NewPackageGraph(cfg.GoVersion)
+	pkgConfig := &packages.Config{
+		Context: ctx,
+		Mode:    packages.NeedFiles,
+		Dir:     dir,
+		Tests:   cfg.test,
+		Env:     cfg.env,
+	}
+	if err := graph.LoadPackagesAndMods(pkgConfig, cfg.tags, cfg.patterns, false); err != nil || len(graph.TopPkgs()) == 0 {
+		// Let the regular scan report the problem.
+		return runSource(ctx, handler, &ucfg, client, dir)
+	}
+
+	units := cacheUnits(cfg, graph)
+	var hits [][]*govulncheck.Message
+	var misses []*cacheUnit
+	for _, u := range units {
+		if msgs, ok := cache.get(u.key); ok {
+			hits = append(hits, msgs)
+		} else {
+			misses = append(misses, u)
+		}
+	}
+
+	if err := handler.SBOM(graph.SBOM()); err != nil {
+		return err
+	}
+	if len(hits) > 0 {
+		msg := fmt.Sprintf("Reusing cached results for %d of %d packages.", len(hits), len(units))
+		if err := handler.Progress(&govulncheck.Progress{Message: msg}); err != nil {
+			return err
+		}
+	}
+
+	dh := &dedupHandler{Handler: handler, osvs: make(map[string]bool), findings: make(map[string]bool)}
+	var memErr error
+	if len(misses) > 0 {
+		rec := &recordingHandler{Handler: dh}
+		ucfg.patterns = nil
+		for _, u := range misses {
+			ucfg.patterns = append(ucfg.patterns, u.path)
+		}
+		if err := runSource(ctx, rec, &ucfg, client, dir); err != nil {
+			if !isMemoryLimit(err) {
+				return err
+			}
+			// The results of the package level are
+			// reported, but not cached.
+			memErr = err
+		}
+		if memErr == nil {
+			for _, u := range misses {
+				// Failing to populate the cache only
+				// makes the next run slower.
+				_ = cache.put(u.key, u.messages(rec.osvs, rec.findings))
+			}
+		}
+	}
+	for _, msgs := range hits {
+		if err := replay(dh, msgs); err != nil {
+			return err
+		}
+	}
+	return memErr
+}
+
+// cacheUnits groups the top-level packages of graph into cache units
+// and computes their cache keys.
+func cacheUnits(cfg *config, graph *vulncheck.PackageGraph) []*cacheUnit {
+	cfgKey := configKey(cfg)
+	pkgKeys := make(map[*packages.Package]string)
+	byPath := make(map[string]*cacheUnit)
+	tops := make(map[string][]*packages.Package)
+	for _, p := range graph.TopPkgs() {
+		path := unitPath(p.PkgPath)
+		u := byPath[path]
+		if u == nil {
+			u = &cacheUnit{path: path, pkgs: make(map[string]bool), mods: make(map[string]bool)}
+			byPath[path] = u
+		}
+		tops[path] = append(tops[path], p)
+	}
+
+	var units []*cacheUnit
+	for path, u := range byPath {
+		var keys []string
+		seen := make(map[*packages.Package]bool)
+		var visit func(*packages.Package)
+		visit = func(p *packages.Package) {
+			if seen[p] {
+				return
+			}
+			seen[p] = true
+			u.pkgs[p.PkgPath] = true
+			if m := p.Module; m != nil {
+				u.mods[m.Path] = true
+				if m.Replace != nil {
+					u.mods[m.Replace.Path] = true
+				}
+			}
+			k, ok := pkgKeys[p]
+			if !ok {
+				k = packageKey(p)
+				pkgKeys[p] = k
+			}
+			keys = append(keys, k)
+			for _, imp := range p.Imports {
+				visit(imp)
+			}
+		}
+		for _, p := range tops[path] {
+			visit(p)
+		}
+		sort.Strings(keys)
+		h := sha256.New()
+		fmt.Fprintf(h, "%s\n%s\n", cfgKey, path)
+		for _, k := range keys {
+			fmt.Fprintln(h, k)
+		}
+		u.key = hex.EncodeToString(h.Sum(nil))
+		units = append(units, u)
+	}
+	sort.Slice(units, func(i, j int) bool { return units[i].path < units[j].path })
+	return units
+}
+
+// unitPath returns the path of the package whose cache unit contains
+// the package at path, folding test variants into the package under test.
+func unitPath(path string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(path, ".test"), "_test")
+}
+
+// configKey describes the parts of cfg that affect analysis results.
+func configKey(cfg *config) string {
+	return fmt.Sprintf("%s %s %s %q %v %v %v %q %v %d %v %v %v %q %v %v %d %d %s %q %q %s %s %v %q %s %s %q",
+		cfg.ScannerVersion, cfg.GoVersion, cfg.ScanLevel, cfg.CallGraph, cfg.Reflection, cfg.Taint, cfg.SkipInit, cfg.WitnessRanking, cfg.WitnessTimeout, cfg.WitnessDepth, cfg.WitnessThroughVulns, cfg.StrictPlatform, cfg.Slice, cfg.EntryPoints, cfg.Library, cfg.ExportData, cfg.MaxMemory, cfg.MaxDepth, cfg.DB, cfg.ghsa, cfg.nvd,
+		cfg.DBLastModified.UTC().Format("2006-01-02T15:04:05Z"),
+		strings.Join(cfg.tags, ","), cfg.test, buildEnv(cfg), filesKey(cfg.osvExtra...), filesKey(cfg.severityMap), unitVersion)
+}
+
+// filesKey identifies the contents of the files matching patterns,
+// such as the entries of -osv-extra or the ratings of -severity-map.
+func filesKey(patterns ...string) string {
+	if len(patterns) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, p := range patterns {
+		matches, _ := filepath.Glob(p)
+		for _, m := range matches {
+			fmt.Fprintln(h, m)
+			if b, err := os.ReadFile(m); err == nil {
+				h.Write(b)
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// buildEnv returns the sorted variables of the environment
+// of cfg that affect builds.
+func buildEnv(cfg *config) []string {
+	var env []string
+	for _, e := range cfg.env {
+		if strings.HasPrefix(e, "GO") || strings.HasPrefix(e, "CGO_") {
+			env = append(env, e)
+		}
+	}
+	sort.Strings(env)
+	return env
+}
+
+// unitVersion is changed whenever the format of cache entries changes.
+const unitVersion = "v1"
+
+// packageKey identifies the content of package p. Packages of
+// module versions are immutable, so they are identified by the module
+// version alone. The content of other packages, such as those of the
+// main module, is hashed.
+func packageKey(p *packages.Package) string {
+	if m := p.Module; m != nil && m.Version != "" && (m.Replace == nil || m.Replace.Version != "") {
+		if m.Replace != nil {
+			m = m.Replace
+		}
+		return fmt.Sprintf("%s %s@%s %q", p.PkgPath, m.Path, m.Version, p.GoFiles)
+	}
+	h := sha256.New()
+	fmt.Fprintln(h, p.PkgPath)
+	for _, f := range p.GoFiles {
+		fmt.Fprintln(h, filepath.Base(f))
+		if r, err := os.Open(f); err == nil {
+			io.Copy(h, r)
+			r.Close()
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// callGraphCacheDir returns the directory of the
+// call graph cache under the cache root of env.
+func callGraphCacheDir(env []string) (string, error) {
+	dir, err := cacheRoot(env)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "callgraphs"), nil
+}
+
+// callGraphKey identifies the build inputs of the call graphs of the
+// packages of graph: the content of all packages and the build
+// configuration of cfg.
+func callGraphKey(cfg *config, graph *vulncheck.PackageGraph) string {
+	var keys []string
+	seen := make(map[*packages.Package]bool)
+	var visit func(*packages.Package)
+	visit = func(p *packages.Package) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		keys = append(keys, packageKey(p))
+		for _, imp := range p.Imports {
+			visit(imp)
+		}
+	}
+	for _, p := range graph.TopPkgs() {
+		visit(p)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s %s %v %q\n", cfg.ScannerVersion, cfg.GoVersion, strings.Join(cfg.tags, ","), cfg.test, buildEnv(cfg))
+	for _, k := range keys {
+		fmt.Fprintln(h, k)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// messages returns the OSV entries and findings that
+// belong to u out of those of a scan including u.
+func (u *cacheUnit) messages(osvs []*osv.Entry, findings []*govulncheck.Finding) []*govulncheck.Message {
+	var fmsgs []*govulncheck.Message
+	referenced := make(map[string]bool)
+	for _, f := range findings {
+		if len(f.Trace) == 0 {
+			continue
+		}
+		frame := f.Trace[0]
+		var ok bool
+		switch {
+		case frame.Function != "" && frame.Kind == "":
+			// Call stacks end in the top-level package.
+			ok = unitPath(f.Trace[len(f.Trace)-1].Package) == u.path
+		case frame.Kind != "":
+			// Uses are in the package of the last frame.
+			ok = u.pkgs[f.Trace[len(f.Trace)-1].Package]
+		case frame.Package != "":
+			ok = u.pkgs[frame.Package]
+		default:
+			ok = u.mods[frame.Module]
+		}
+		if ok {
+			referenced[f.OSV] = true
+			fmsgs = append(fmsgs, &govulncheck.Message{Finding: f})
+		}
+	}
+	var msgs []*govulncheck.Message
+	for _, e := range osvs {
+		ok := referenced[e.ID]
+		for _, a := range e.Affected {
+			ok = ok || u.mods[a.Module.Path]
+		}
+		if ok {
+			msgs = append(msgs, &govulncheck.Message{OSV: e})
+		}
+	}
+	return append(msgs, fmsgs...)
+}
+
+// replay passes the SBOMs, progress messages, OSV
+// entries, and findings in msgs to h.
+func replay(h govulncheck.Handler, msgs []*govulncheck.Message) error {
+	for _, m := range msgs {
+		var err error
+		switch {
+		case m.SBOM != nil:
+			err = h.SBOM(m.SBOM)
+		case m.Progress != nil:
+			err = h.Progress(m.Progress)
+		case m.OSV != nil:
+			err = h.OSV(m.OSV)
+		case m.Finding != nil:
+			err = h.Finding(m.Finding)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordingHandler records the OSV entries and findings passed to it.
+// It drops the SBOM, which is reported for all packages instead.
+type recordingHandler struct {
+	govulncheck.Handler
+	osvs     []*osv.Entry
+	findings []*govulncheck.Finding
+}
+
+func (h *recordingHandler) SBOM(*govulncheck.SBOM) error { return nil }
+
+func (h *recordingHandler) OSV(e *osv.Entry) error {
+	h.osvs = append(h.osvs, e)
+	return h.Handler.OSV(e)
+}
+
+func (h *recordingHandler) Finding(f *govulncheck.Finding) error {
+	h.findings = append(h.findings, f)
+	return h.Handler.Finding(f)
+}
+
+// dedupHandler drops the OSV entries and findings already passed to it,
+// which are shared by the cached results of packages with common
+// dependencies.
+type dedupHandler struct {
+	govulncheck.Handler
+	osvs     map[string]bool
+	findings map[string]bool
+}
+
+func (h *dedupHandler) OSV(e *osv.Entry) error {
+	if h.osvs[e.ID] {
+		return nil
+	}
+	h.osvs[e.ID] = true
+	return h.Handler.OSV(e)
+}
+
+func (h *dedupHandler) Finding(f *govulncheck.Finding) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if h.findings[string(b)] {
+		return nil
+	}
+	h.findings[string(b)] = true
+	return h.Handler.Finding(f)
+}
+
+// runCache runs the cache command. Its only subcommand, clean, removes
+// the caches of govulncheck, leaving the other files of the cache root,
+// which is only removed if they leave it empty.
+func runCache(env []string, stdout, stderr io.Writer, args []string) error {
+	if len(args) != 1 || args[0] != "clean" {
+		fmt.Fprint(stderr, `Usage:
+
+	govulncheck cache clean
+`)
+		return errUsage
+	}
+	dir, err := cacheRoot(env)
+	if err != nil {
+		return err
+	}
+	for _, name := range cacheDirs {
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	os.Remove(dir) // if empty
+	fmt.Fprintf(stdout, "Removed the caches in %s\n", dir)
+	return nil
+}
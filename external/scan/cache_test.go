@@ -0,0 +1,174 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestResultCache(t *testing.T) {
+	c := &resultCache{dir: t.TempDir()}
+	if _, ok := c.get("k"); ok {
+		t.Fatal("get on empty cache succeeded")
+	}
+	want := []*govulncheck.Message{
+		{OSV: &osv.Entry{ID: "GO-0000-0001"}},
+		{Finding: &govulncheck.Finding{OSV: "GO-0000-0001", Trace: []*govulncheck.Frame{{Module: "m"}}}},
+	}
+	if err := c.put("k", want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.get("k")
+	if !ok {
+		t.Fatal("get after put failed")
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCacheUnitMessages(t *testing.T) {
+	u := &cacheUnit{
+		path: "example.com/m/a",
+		pkgs: map[string]bool{"example.com/m/a": true, "example.com/v/p": true},
+		mods: map[string]bool{"example.com/m": true, "example.com/v": true},
+	}
+	osvs := []*osv.Entry{
+		{ID: "GO-0000-0001", Affected: []osv.Affected{{Module: osv.Module{Path: "example.com/v"}}}},
+		{ID: "GO-0000-0002", Affected: []osv.Affected{{Module: osv.Module{Path: "example.com/w"}}}},
+	}
+	modFinding := &govulncheck.Finding{OSV: "GO-0000-0001", Trace: []*govulncheck.Frame{{Module: "example.com/v"}}}
+	pkgFinding := &govulncheck.Finding{OSV: "GO-0000-0001", Trace: []*govulncheck.Frame{{Module: "example.com/v", Package: "example.com/v/p"}}}
+	callFinding := &govulncheck.Finding{OSV: "GO-0000-0001", Trace: []*govulncheck.Frame{
+		{Module: "example.com/v", Package: "example.com/v/p", Function: "F"},
+		{Module: "example.com/m", Package: "example.com/m/a_test", Function: "TestA"},
+	}}
+	otherFindings := []*govulncheck.Finding{
+		{OSV: "GO-0000-0002", Trace: []*govulncheck.Frame{{Module: "example.com/w"}}},
+		{OSV: "GO-0000-0002", Trace: []*govulncheck.Frame{{Module: "example.com/w", Package: "example.com/w/q"}}},
+		{OSV: "GO-0000-0001", Trace: []*govulncheck.Frame{
+			{Module: "example.com/v", Package: "example.com/v/p", Function: "F"},
+			{Module: "example.com/m", Package: "example.com/m/b", Function: "B"},
+		}},
+	}
+
+	got := u.messages(osvs, append([]*govulncheck.Finding{modFinding, pkgFinding, callFinding}, otherFindings...))
+	want := []*govulncheck.Message{
+		{OSV: osvs[0]},
+		{Finding: modFinding},
+		{Finding: pkgFinding},
+		{Finding: callFinding},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestConfigKey(t *testing.T) {
+	dir := t.TempDir()
+	extra := filepath.Join(dir, "extra.json")
+	if err := os.WriteFile(extra, []byte(`{"id":"GO-0000-0001"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ratings := filepath.Join(dir, "ratings.json")
+	if err := os.WriteFile(ratings, []byte(`{"GO-0000-0001":"HIGH"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newConfig := func() *config {
+		cfg := &config{}
+		cfg.DBLastModified = &modified
+		return cfg
+	}
+	base := configKey(newConfig())
+	for _, tc := range []struct {
+		name   string
+		change func(*config)
+	}{
+		{"osv-extra", func(cfg *config) {
+			cfg.osvExtra = []string{extra}
+		}},
+		{"severity-map", func(cfg *config) {
+			cfg.severityMap = ratings
+		}},
+		{"export-data", func(cfg *config) {
+			cfg.ExportData = true
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newConfig()
+			tc.change(cfg)
+			key := configKey(cfg)
+			if key == base {
+				t.Fatalf("configKey does not change with %s", tc.name)
+			}
+			if got := configKey(cfg); got != key {
+				t.Errorf("configKey is not stable: %q != %q", got, key)
+			}
+		})
+	}
+
+	// The key changes with the contents of the files of -osv-extra.
+	cfg := newConfig()
+	cfg.osvExtra = []string{extra}
+	before := configKey(cfg)
+	if err := os.WriteFile(extra, []byte(`{"id":"GO-0000-0002"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if configKey(cfg) == before {
+		t.Error("configKey does not change with the contents of -osv-extra files")
+	}
+
+	// The key changes with the contents of the file of -severity-map.
+	cfg = newConfig()
+	cfg.severityMap = ratings
+	before = configKey(cfg)
+	if err := os.WriteFile(ratings, []byte(`{"GO-0000-0001":"LOW"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if configKey(cfg) == before {
+		t.Error("configKey does not change with the contents of the -severity-map file")
+	}
+}
+
+func TestUnitPath(t *testing.T) {
+	for path, want := range map[string]string{
+		"example.com/m/a":      "example.com/m/a",
+		"example.com/m/a_test": "example.com/m/a",
+		"example.com/m/a.test": "example.com/m/a",
+	} {
+		if got := unitPath(path); got != want {
+			t.Errorf("unitPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDedupHandler(t *testing.T) {
+	rec := &recordingHandler{}
+	h := &dedupHandler{Handler: rec, osvs: make(map[string]bool), findings: make(map[string]bool)}
+	rec.Handler = govulncheck.NewJSONHandler(io.Discard)
+	f := func() *govulncheck.Finding {
+		return &govulncheck.Finding{OSV: "GO-0000-0001", Trace: []*govulncheck.Frame{{Module: "m"}}}
+	}
+	for i := 0; i < 2; i++ {
+		if err := h.OSV(&osv.Entry{ID: "GO-0000-0001"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Finding(f()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(rec.osvs) != 1 || len(rec.findings) != 1 {
+		t.Errorf("got %d OSVs and %d findings, want 1 of each", len(rec.osvs), len(rec.findings))
+	}
+}
@@ -0,0 +1,27 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+)
+
+// confidenceFilter passes on to Handler the findings with at
+// least confidence min, and all other messages.
+type confidenceFilter struct {
+	govulncheck.Handler
+	min govulncheck.Confidence
+}
+
+func (h *confidenceFilter) Finding(finding *govulncheck.Finding) error {
+	if !finding.Confidence.AtLeast(h.min) {
+		return nil
+	}
+	return h.Handler.Finding(finding)
+}
+
+func (h *confidenceFilter) Flush() error {
+	return Flush(h.Handler)
+}
@@ -0,0 +1,31 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+)
+
+func TestConfidenceFilter(t *testing.T) {
+	mock := test.NewMockHandler()
+	h := &confidenceFilter{Handler: mock, min: govulncheck.ConfidenceLikely}
+	for _, c := range []govulncheck.Confidence{"", govulncheck.ConfidenceCertain, govulncheck.ConfidenceLikely, govulncheck.ConfidencePossible} {
+		if err := h.Finding(&govulncheck.Finding{OSV: string(c), Confidence: c}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []govulncheck.Confidence
+	for _, f := range mock.FindingMessages {
+		got = append(got, f.Confidence)
+	}
+	want := []govulncheck.Confidence{"", govulncheck.ConfidenceCertain, govulncheck.ConfidenceLikely}
+	if !slices.Equal(got, want) {
+		t.Errorf("got findings with confidence %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/client"
+)
+
+// runDB runs the db command, which manages vulnerability databases.
+// Its subcommands are download, which writes a snapshot of a database
+// to a zip archive, for scanning with -db file://<archive> without
+// network access, and mirror, which updates a copy of a database in a
+// directory that static file servers can serve, serve, which serves
+// such a directory, or a database cached by scans, over http, diff,
+// which lists the entries added or modified since a time, and convert,
+// which converts a database in the legacy layout to the v1 layout.
+func runDB(ctx context.Context, env []string, stdout, stderr io.Writer, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "serve":
+			return serveDB(ctx, stdout, stderr, args[1:])
+		case "convert":
+			return convertDB(stdout, stderr, args[1:])
+		}
+	}
+	cfg := &config{env: env}
+	var target string // the -o archive, -dest directory, or -from time
+	var dir string    // the -C directory of diff
+	var jsonOut bool  // the -json flag of diff
+	flags := flag.NewFlagSet("db", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Func("db", "vulnerability database `url` (default 'https://vuln.go.dev')", func(s string) error {
+		cfg.db = append(cfg.db, s)
+		return nil
+	})
+	flags.Func("db-header", "add the `header`, of the form 'Name: value', to the requests made to http(s) databases; may be repeated", func(s string) error {
+		cfg.dbHeader = append(cfg.dbHeader, s)
+		return nil
+	})
+	flags.StringVar(&cfg.dbCert, "db-cert", "", "present the PEM encoded client certificate in `file` to http(s) databases requiring one, with the key of -db-key")
+	flags.StringVar(&cfg.dbKey, "db-key", "", "read the key of the client certificate of -db-cert from the PEM encoded `file`")
+	flags.Func("db-pubkey", "only trust the databases whose endpoints are signed by the minisign public `key`, given in base64 or as a minisign.pub file; may be repeated", func(s string) error {
+		cfg.dbPubKey = append(cfg.dbPubKey, s)
+		return nil
+	})
+	flags.IntVar(&cfg.dbRetries, "db-retries", 3, "retry the requests to http(s) databases failing with network or server errors up to `N` times, with exponential backoff")
+	flags.DurationVar(&cfg.dbTimeout, "db-timeout", 0, "give up on each attempt of a request to an http(s) database after `duration` (default none)")
+	flags.IntVar(&cfg.dbConcurrency, "db-concurrency", 10, "make up to `N` requests at once to the databases")
+	flags.Float64Var(&cfg.dbRateLimit, "db-rate-limit", 0, "make at most `rate` requests per second to http(s) databases (default unlimited)")
+	flags.Usage = func() {}
+	usage := func() {
+		fmt.Fprint(flags.Output(), `Usage:
+
+	govulncheck db download [flags] -o file
+	govulncheck db mirror [flags] -dest dir
+	govulncheck db serve [-addr address] -dir dir
+	govulncheck db diff [flags] -from time [patterns]
+	govulncheck db convert -src dir -dest dir
+
+`)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		usage()
+		return errUsage
+	}
+	cmd := args[0]
+	switch cmd {
+	case "download":
+		flags.StringVar(&target, "o", "", "write the database to the zip archive `file`")
+	case "mirror":
+		flags.StringVar(&target, "dest", "", "update the copy of the database in `dir`")
+	case "diff":
+		flags.StringVar(&target, "from", "", "list the entries added or modified since `time`, given in RFC 3339 format or as a date such as 2024-05-01")
+		flags.StringVar(&dir, "C", "", "change to `dir` before loading the packages given as patterns, whose modules the entries must affect")
+		flags.BoolVar(&jsonOut, "json", false, "output the entries as a stream of JSON objects")
+	default:
+		usage()
+		return errUsage
+	}
+	if err := flags.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			usage()
+			return errHelp
+		}
+		return errUsage
+	}
+	var from time.Time
+	if cmd == "diff" && target != "" {
+		var err error
+		if from, err = parseTime(target); err != nil {
+			fmt.Fprintf(flags.Output(), "invalid -from %q: %v\n", target, err)
+			return errUsage
+		}
+	}
+	patterns := flags.Args()
+	if cmd == "diff" {
+		patterns = nil
+	}
+	if err := validateDBCommand(cfg, cmd, target, patterns); err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		return errUsage
+	}
+
+	c, err := client.NewClientContext(ctx, cfg.db[0], clientOptions(cfg))
+	if err != nil {
+		return fmt.Errorf("creating client: %w", err)
+	}
+	if cmd == "diff" {
+		return diffDB(ctx, c, stdout, cfg.env, dir, from, flags.Args(), jsonOut)
+	}
+	if cmd == "mirror" {
+		n, err := c.Mirror(ctx, target)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Mirrored %s to %s, downloading %d entries\n", cfg.db[0], target, n)
+		return nil
+	}
+	if err := downloadDB(ctx, c, target); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Wrote %s to %s\n", cfg.db[0], target)
+	return nil
+}
+
+// validateDBCommand validates the flags of the db subcommand cmd,
+// which writes the database to target.
+func validateDBCommand(cfg *config, cmd, target string, args []string) error {
+	if len(cfg.db) == 0 {
+		cfg.db = []string{"https://vuln.go.dev"}
+	}
+	if len(cfg.db) > 1 {
+		return fmt.Errorf("the -db flag may only be given once for db %s", cmd)
+	}
+	if target == "" {
+		switch cmd {
+		case "mirror":
+			return errors.New("db mirror requires the -dest flag")
+		case "diff":
+			return errors.New("db diff requires the -from flag")
+		}
+		return errors.New("db download requires the -o flag")
+	}
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments %q", args)
+	}
+	return validateDBConfig(cfg)
+}
+
+// downloadDB writes the database of c to the zip archive file.
+// The archive is replaced only once it is complete, so that
+// concurrent scans read either the previous or the new snapshot.
+func downloadDB(ctx context.Context, c *client.Client, file string) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err := c.WriteZip(ctx, f); err != nil {
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
+
+// serveDB runs the db serve command, which serves the database
+// in a directory over http until ctx is done.
+func serveDB(ctx context.Context, stdout, stderr io.Writer, args []string) error {
+	flags := flag.NewFlagSet("db serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	addr := flags.String("addr", "localhost:8080", "listen on the TCP network `address`, such as ':8080' for all interfaces")
+	dir := flags.String("dir", "", "serve the database in `dir`, a mirror or a directory of the cache")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), `Usage:
+
+	govulncheck db serve [-addr address] -dir dir
+
+`)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return errHelp
+		}
+		return errUsage
+	}
+	if *dir == "" || flags.NArg() > 0 {
+		if *dir == "" {
+			fmt.Fprintln(flags.Output(), "db serve requires the -dir flag")
+		} else {
+			fmt.Fprintf(flags.Output(), "unexpected arguments %q\n", flags.Args())
+		}
+		return errUsage
+	}
+	h, err := client.NewHandler(*dir)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	stop := context.AfterFunc(ctx, func() { srv.Close() })
+	defer stop()
+	fmt.Fprintf(stdout, "Serving %s on http://%s\n", *dir, l.Addr())
+	if err := srv.Serve(l); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// convertDB runs the db convert command, which writes the database
+// in the legacy layout in a directory to another in the v1 layout.
+func convertDB(stdout, stderr io.Writer, args []string) error {
+	flags := flag.NewFlagSet("db convert", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	src := flags.String("src", "", "convert the database in the legacy layout in `dir`")
+	dest := flags.String("dest", "", "write the database in the v1 layout to `dir`, as db mirror does")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), `Usage:
+
+	govulncheck db convert -src dir -dest dir
+
+`)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return errHelp
+		}
+		return errUsage
+	}
+	switch {
+	case *src == "":
+		fmt.Fprintln(flags.Output(), "db convert requires the -src flag")
+		return errUsage
+	case *dest == "":
+		fmt.Fprintln(flags.Output(), "db convert requires the -dest flag")
+		return errUsage
+	case flags.NArg() > 0:
+		fmt.Fprintf(flags.Output(), "unexpected arguments %q\n", flags.Args())
+		return errUsage
+	}
+	n, err := client.ConvertLegacy(*src, *dest)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Converted %d entries from %s to %s\n", n, *src, *dest)
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// diffEntry is the JSON output of db diff for an entry.
+type diffEntry struct {
+	// Change is "added" for the entries published
+	// since the time, and "modified" for the others.
+	Change string     `json:"change"`
+	OSV    *osv.Entry `json:"osv"`
+}
+
+// diffDB writes the entries of the database of c added or modified
+// since from to w, only those affecting the modules of the packages
+// matching patterns in dir if any are given.
+func diffDB(ctx context.Context, c *client.Client, w io.Writer, env []string, dir string, from time.Time, patterns []string, jsonOut bool) error {
+	var modules []string
+	if len(patterns) > 0 {
+		var err error
+		if modules, err = buildModules(ctx, env, dir, patterns); err != nil {
+			return err
+		}
+	}
+	entries, err := c.ModifiedSince(ctx, from, modules)
+	if err != nil {
+		return err
+	}
+	if jsonOut {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		for _, e := range entries {
+			if err := enc.Encode(&diffEntry{Change: change(e, from), OSV: e}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Fprintf(w, "No advisories added or modified since %s.\n", from.Format(time.RFC3339))
+		return nil
+	}
+	fmt.Fprintf(w, "Advisories added or modified since %s:\n", from.Format(time.RFC3339))
+	for _, e := range entries {
+		var paths []string
+		for _, a := range e.Affected {
+			paths = append(paths, a.Module.Path)
+		}
+		fmt.Fprintf(w, "\n%-8s  %s  %s  %s\n", change(e, from), e.ID, e.Modified.UTC().Format(time.DateOnly), strings.Join(paths, ", "))
+		summary := e.Summary
+		if summary == "" {
+			if err := e.Load(); err != nil {
+				return err
+			}
+			summary = e.Details
+		}
+		if summary != "" {
+			fmt.Fprintf(w, "    %s\n", firstLine(summary))
+		}
+	}
+	return nil
+}
+
+// change returns whether e was added or modified since from.
+func change(e *osv.Entry, from time.Time) string {
+	if e.Published.After(from) {
+		return "added"
+	}
+	return "modified"
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// parseTime parses s, either a time in RFC 3339 format
+// or a date, which stands for its midnight in UTC.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("must be a time in RFC 3339 format or a date such as 2024-05-01")
+}
+
+// buildModules returns the paths of the modules, as named in
+// vulnerability databases, providing the packages matching patterns
+// in dir and their dependencies, as listed by the go command run with
+// env.
+func buildModules(ctx context.Context, env []string, dir string, patterns []string) ([]string, error) {
+	const format = `{{if .Standard}}` + external.GoStdModulePath +
+		`{{else if .Module}}{{with .Module.Replace}}{{.Path}}{{else}}{{.Module.Path}}{{end}}{{end}}`
+	cmd := exec.CommandContext(ctx, "go", append([]string{"list", "-deps", "-f", format}, patterns...)...)
+	cmd.Dir = dir
+	cmd.Env = env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing the modules of %s: %v\n%s", strings.Join(patterns, " "), err, stderr.Bytes())
+	}
+	seen := make(map[string]bool)
+	modules := []string{} // not nil, to scope the diff to no modules
+	for _, m := range strings.Fields(string(out)) {
+		if !seen[m] {
+			seen[m] = true
+			modules = append(modules, m)
+		}
+	}
+	return modules, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// osvWriter passes on all messages to Handler, and writes the OSV
+// entries referenced by the findings to dir when it is flushed, each
+// to a file named after its ID, so that results can be interpreted
+// with the entries they were computed with.
+type osvWriter struct {
+	govulncheck.Handler
+	dir string
+
+	entries map[string]*osv.Entry
+	found   map[string]bool // IDs of the entries of findings
+}
+
+func newOSVWriter(h govulncheck.Handler, dir string) *osvWriter {
+	return &osvWriter{Handler: h, dir: dir, entries: make(map[string]*osv.Entry), found: make(map[string]bool)}
+}
+
+func (h *osvWriter) OSV(entry *osv.Entry) error {
+	h.entries[entry.ID] = entry
+	return h.Handler.OSV(entry)
+}
+
+func (h *osvWriter) Finding(finding *govulncheck.Finding) error {
+	h.found[finding.OSV] = true
+	return h.Handler.Finding(finding)
+}
+
+func (h *osvWriter) Flush() error {
+	if err := h.write(); err != nil {
+		return fmt.Errorf("writing OSV entries to %s: %w", h.dir, err)
+	}
+	return Flush(h.Handler)
+}
+
+func (h *osvWriter) write() error {
+	if err := os.MkdirAll(h.dir, 0o777); err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(h.found))
+	for id := range h.found {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		e, ok := h.entries[id]
+		if !ok {
+			continue
+		}
+		b, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(h.dir, filepath.Base(id)+".json"), append(b, '\n'), 0o666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
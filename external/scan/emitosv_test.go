@@ -0,0 +1,60 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/test"
+)
+
+func TestOSVWriter(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "osv")
+	mock := test.NewMockHandler()
+	h := newOSVWriter(mock, dir)
+	for _, id := range []string{"GO-0000-0001", "GO-0000-0002"} {
+		if err := h.OSV(&osv.Entry{ID: id, Summary: "summary of " + id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Finding(&govulncheck.Finding{OSV: "GO-0000-0002"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.OSVMessages) != 2 || len(mock.FindingMessages) != 1 {
+		t.Errorf("got %d OSV and %d finding messages passed on; want 2 and 1", len(mock.OSVMessages), len(mock.FindingMessages))
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if want := []string{"GO-0000-0002.json"}; !slices.Equal(names, want) {
+		t.Fatalf("got files %q; want %q", names, want)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, names[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e osv.Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Summary != "summary of GO-0000-0002" {
+		t.Errorf("got entry %+v; want GO-0000-0002", e)
+	}
+}
@@ -5,8 +5,12 @@
 package scan
 
 import (
+	"context"
 	"errors"
 	"strings"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
 )
 
 //lint:file-ignore ST1005 Ignore staticcheck message about error formatting
@@ -58,6 +62,14 @@ See https://go.dev/doc/modules/managing-dependencies for more information.`)
 Did you mean to run govulncheck with -mode=binary?
 
 For details, run govulncheck -h.`)
+
+	// errUnrecognizedBinary indicates that a binary is neither
+	// a Go executable nor a blob of extract mode.
+	errUnrecognizedBinary = errors.New("unrecognized binary format")
+
+	// errNoGoExecutables indicates that an archive
+	// has no Go executables to scan.
+	errNoGoExecutables = errors.New("no Go executables found in archive")
 )
 
 type exitCodeError struct {
@@ -77,3 +89,47 @@ func isGoVersionMismatchError(err error) bool {
 	return strings.Contains(msg, "This application uses version go") &&
 		strings.Contains(msg, "It may fail to process source files")
 }
+
+// scanError returns the failure err as a *govulncheck.Error whose code
+// classifies it, or err itself if it is nil or not a failure.
+func scanError(err error) error {
+	if err == nil || err == errVulnerabilitiesFound || err == errHelp {
+		return err
+	}
+	if _, ok := err.(*govulncheck.Error); ok {
+		return err
+	}
+	return govulncheck.NewError(errorCode(err), err)
+}
+
+// errorCode returns the code classifying the failure err.
+func errorCode(err error) govulncheck.ErrorCode {
+	var e *govulncheck.Error
+	switch {
+	case errors.As(err, &e):
+		return e.Code
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return govulncheck.ErrorCanceled
+	case errors.Is(err, errUsage), errors.Is(err, errNoPatterns), errors.Is(err, errNoBinaryFlag):
+		return govulncheck.ErrorUsage
+	case errors.Is(err, errNoGoMod):
+		return govulncheck.ErrorNoGoMod
+	case errors.Is(err, errNoPackagesMatched):
+		return govulncheck.ErrorNoPackages
+	case errors.Is(err, errGoVersionMismatch):
+		return govulncheck.ErrorGoVersionMismatch
+	case errors.Is(err, errUnrecognizedBinary), errors.Is(err, errNoGoExecutables):
+		return govulncheck.ErrorUnsupportedBinary
+	case isMemoryLimit(err):
+		return govulncheck.ErrorMemoryLimit
+	}
+	return govulncheck.ErrorUnknown
+}
+
+// isMemoryLimit reports whether err is the failure of a source scan
+// whose call graph exceeded -max-memory, after reporting the results
+// of the package level.
+func isMemoryLimit(err error) bool {
+	var e *vulncheck.MemoryLimitError
+	return errors.As(err, &e)
+}
@@ -5,6 +5,8 @@
 package scan
 
 import (
+	"bufio"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,23 +14,45 @@ import (
 
 	"github.com/StevenACoffman/invuln/external/derrors"
 	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"github.com/klauspost/compress/zstd"
 )
 
 const (
 	// extractModeID is the unique name of the extract mode protocol
 	extractModeID      = "govulncheck-extract"
-	extractModeVersion = "0.1.0"
+	extractModeVersion = "0.2.0"
+
+	// extractModeUncompressedVersion is the version written for blobs
+	// without compression. Their format has not changed since 0.1.0,
+	// so older versions of govulncheck can still read them.
+	extractModeUncompressedVersion = "0.1.0"
+
+	// blobCompressionZstd is the compression of the blob bodies
+	// written with -compress.
+	blobCompressionZstd = "zstd"
+
+	// blobCompressionGzip is the compression of a blob body
+	// compressed with gzip, as written by earlier versions of
+	// govulncheck, which are still read.
+	blobCompressionGzip = "gzip"
 )
 
 // header information for the blob output.
+//
+// A blob can be read if the version of its header is one of those
+// written: extractModeUncompressedVersion or extractModeVersion. Blobs
+// of other versions are rejected, rather than decoded by guesswork.
 type header struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
+	// Compression is the compression of the blob body following the
+	// header, if any: zstd, or gzip. Added in version 0.2.0.
+	Compression string `json:"compression,omitempty"`
 }
 
 // runExtract dumps the extracted abstraction of binary at cfg.patterns to out.
 // It prints out exactly two blob messages, one with the header and one with
-// the vulncheck.Bin as the body.
+// the vulncheck.Bin as the body. With cfg.compress, the body is compressed.
 func runExtract(cfg *config, out io.Writer) (err error) {
 	defer derrors.Wrap(&err, "govulncheck")
 
@@ -39,18 +63,62 @@ func runExtract(cfg *config, out io.Writer) (err error) {
 	sortBin(bin) // sort for easier testing and validation
 	header := header{
 		Name:    extractModeID,
-		Version: extractModeVersion,
+		Version: extractModeUncompressedVersion,
+	}
+	if cfg.compress {
+		header.Version = extractModeVersion
+		header.Compression = blobCompressionZstd
 	}
 
-	enc := json.NewEncoder(out)
-
-	if err := enc.Encode(header); err != nil {
+	if err := json.NewEncoder(out).Encode(header); err != nil {
 		return fmt.Errorf("marshaling blob header: %v", err)
 	}
-	if err := enc.Encode(bin); err != nil {
+	if !cfg.compress {
+		if err := json.NewEncoder(out).Encode(bin); err != nil {
+			return fmt.Errorf("marshaling blob body: %v", err)
+		}
+		return nil
+	}
+	zw, err := zstd.NewWriter(out)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(zw).Encode(bin); err != nil {
+		zw.Close()
 		return fmt.Errorf("marshaling blob body: %v", err)
 	}
-	return nil
+	return zw.Close()
+}
+
+// blobBody returns a decoder for the body of a blob with header h,
+// the rest of which is read from dec and then r.
+func blobBody(h header, dec *json.Decoder, r io.Reader) (*json.Decoder, error) {
+	switch h.Compression {
+	case "":
+		return dec, nil
+	case blobCompressionZstd, blobCompressionGzip:
+		br := bufio.NewReader(io.MultiReader(dec.Buffered(), r))
+		// Skip the newline ending the header.
+		if b, err := br.Peek(1); err == nil && b[0] == '\n' {
+			br.Discard(1)
+		}
+		if h.Compression == blobCompressionGzip {
+			zr, err := gzip.NewReader(br)
+			if err != nil {
+				return nil, err
+			}
+			return json.NewDecoder(zr), nil
+		}
+		// Decoding synchronously starts no goroutines,
+		// so the decoder need not be closed.
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return json.NewDecoder(zr), nil
+	default:
+		return nil, fmt.Errorf("unsupported blob compression %q", h.Compression)
+	}
 }
 
 func sortBin(bin *vulncheck.Bin) {
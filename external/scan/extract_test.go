@@ -0,0 +1,89 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"github.com/google/go-cmp/cmp"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestDecodeBlob(t *testing.T) {
+	bin := &vulncheck.Bin{
+		Modules:   []*packages.Module{{Path: "golang.org/amod", Version: "v1.1.3"}},
+		GoVersion: "go1.22.1",
+	}
+	blob := func(h header, compress bool) []byte {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(h); err != nil {
+			t.Fatal(err)
+		}
+		if !compress {
+			if err := json.NewEncoder(&buf).Encode(bin); err != nil {
+				t.Fatal(err)
+			}
+			return buf.Bytes()
+		}
+		var zw io.WriteCloser = gzip.NewWriter(&buf)
+		if h.Compression == blobCompressionZstd {
+			var err error
+			if zw, err = zstd.NewWriter(&buf); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := json.NewEncoder(zw).Encode(bin); err != nil {
+			t.Fatal(err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	for _, tt := range []struct {
+		name     string
+		h        header
+		compress bool
+		ok       bool
+	}{
+		{"uncompressed", header{Name: extractModeID, Version: "0.1.0"}, false, true},
+		{"compressed", header{Name: extractModeID, Version: extractModeVersion, Compression: blobCompressionZstd}, true, true},
+		{"gzip compressed", header{Name: extractModeID, Version: extractModeVersion, Compression: blobCompressionGzip}, true, true},
+		{"newer minor version", header{Name: extractModeID, Version: "0.3.0"}, true, false},
+		{"newer major version", header{Name: extractModeID, Version: "1.0.0"}, false, false},
+		{"unknown compression", header{Name: extractModeID, Version: extractModeVersion, Compression: "xz"}, true, false},
+		{"other protocol", header{Name: "other", Version: "0.1.0"}, false, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeBlob(bytes.NewReader(blob(tt.h, tt.compress)))
+			if !tt.ok {
+				if got != nil {
+					t.Errorf("got %v; want blob to be rejected", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(bin, got); diff != "" {
+				t.Errorf("mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	// Blobs of versions other than those written are reported as such.
+	_, err := decodeBlob(bytes.NewReader(blob(header{Name: extractModeID, Version: "0.3.0"}, false)))
+	if err == nil || !strings.Contains(err.Error(), "unsupported extract version") {
+		t.Errorf("got error %v; want unsupported extract version", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// A Fetcher retrieves the content of a binary named by a URL, allowing
+// binary mode to scan published artifacts without downloading them first.
+type Fetcher interface {
+	Fetch(ctx context.Context, u *url.URL) (io.ReadCloser, error)
+}
+
+// FetcherFunc adapts an ordinary function to the Fetcher interface.
+type FetcherFunc func(ctx context.Context, u *url.URL) (io.ReadCloser, error)
+
+func (f FetcherFunc) Fetch(ctx context.Context, u *url.URL) (io.ReadCloser, error) {
+	return f(ctx, u)
+}
+
+// maxBinarySize is the maximum size of the binaries fetched from URLs,
+// which are read into memory. It is a variable for tests.
+var maxBinarySize int64 = 1 << 30
+
+var (
+	fetchersMu sync.RWMutex
+	fetchers   = map[string]Fetcher{
+		"http":  FetcherFunc(fetchHTTP),
+		"https": FetcherFunc(fetchHTTP),
+	}
+)
+
+// RegisterFetcher makes f the fetcher for binary URLs with the given
+// scheme, such as "s3" or "oci", replacing any previous fetcher for
+// that scheme. Fetchers for "http" and "https" are registered by default.
+func RegisterFetcher(scheme string, f Fetcher) {
+	fetchersMu.Lock()
+	defer fetchersMu.Unlock()
+	fetchers[scheme] = f
+}
+
+// fetcherFor returns the fetcher for the binary URL s, if s is a
+// URL with a registered scheme.
+func fetcherFor(s string) (Fetcher, *url.URL, bool) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "") {
+		return nil, nil, false
+	}
+	fetchersMu.RLock()
+	defer fetchersMu.RUnlock()
+	f, ok := fetchers[u.Scheme]
+	return f, u, ok
+}
+
+// isURL reports whether s is a binary URL that can be fetched.
+func isURL(s string) bool {
+	_, _, ok := fetcherFor(s)
+	return ok
+}
+
+// fetchBinary reads the content of the binary at the URL s.
+func fetchBinary(ctx context.Context, s string) ([]byte, error) {
+	f, u, ok := fetcherFor(s)
+	if !ok {
+		return nil, fmt.Errorf("no fetcher for %q", s)
+	}
+	rc, err := f.Fetch(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxBinarySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBinarySize {
+		return nil, fmt.Errorf("%s: binary larger than %d bytes", s, maxBinarySize)
+	}
+	return data, nil
+}
+
+func fetchHTTP(ctx context.Context, u *url.URL) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP GET %s returned unexpected status: %s", u, resp.Status)
+	}
+	if resp.ContentLength > maxBinarySize {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP GET %s: binary of %d bytes larger than %d bytes", u, resp.ContentLength, maxBinarySize)
+	}
+	return resp.Body, nil
+}
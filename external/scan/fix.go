@@ -0,0 +1,125 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"golang.org/x/mod/semver"
+)
+
+// A ModuleUpgrade is the upgrade of a module clearing the
+// findings of the vulnerabilities of its version.
+type ModuleUpgrade struct {
+	// Path is the path of the module, which is "stdlib" for the
+	// standard library and "toolchain" for the go command.
+	Path string
+
+	// From is the version of the module found, or "" if unknown.
+	From string
+
+	// To is the earliest version fixing all of Fixes, or ""
+	// if none of the vulnerabilities of the module are fixed.
+	To string
+
+	// Fixes are the IDs of the vulnerabilities To fixes.
+	Fixes []string
+
+	// Unfixed are the IDs of the vulnerabilities no version is
+	// known to fix, such as those of a module replaced by a
+	// directory, whose code has to be fixed by hand.
+	Unfixed []string
+}
+
+// GoGetArg returns the argument of go get upgrading the module,
+// as in golang.org/x/text@v0.3.8, or go@1.21.5 for the standard
+// library and the go command, or "" if u has no version to upgrade to.
+func (u *ModuleUpgrade) GoGetArg() string {
+	if u.To == "" {
+		return ""
+	}
+	if u.Path == external.GoStdModulePath || u.Path == external.GoCmdModulePath {
+		return "go@" + strings.TrimPrefix(semverToGoTag(u.To), "go")
+	}
+	return u.Path + "@" + u.To
+}
+
+// FixPlan returns, for each module of findings, the smallest upgrade
+// clearing its findings, in the order of the module paths. That is the
+// highest fixed version of the vulnerabilities found, as upgrading to
+// a version fixing each of them fixes them all.
+//
+// The upgrades are applied with go get, by which minimal version
+// selection raises the versions of the other modules required by
+// the upgraded ones as needed, and which keeps the versions of the
+// modules that are higher than To, as the plan never downgrades.
+func FixPlan(findings []*govulncheck.Finding) ([]ModuleUpgrade, error) {
+	byPath := make(map[string]*ModuleUpgrade)
+	fixes := make(map[string]map[string]bool)   // module path -> fixed OSV IDs
+	unfixed := make(map[string]map[string]bool) // module path -> unfixed OSV IDs
+	for _, f := range findings {
+		if len(f.Trace) == 0 {
+			return nil, fmt.Errorf("finding for %s has no trace", f.OSV)
+		}
+		fr := f.Trace[0]
+		u := byPath[fr.Module]
+		if u == nil {
+			u = &ModuleUpgrade{Path: fr.Module}
+			byPath[fr.Module] = u
+			fixes[fr.Module] = make(map[string]bool)
+			unfixed[fr.Module] = make(map[string]bool)
+		}
+		if fr.Version != "" {
+			if !semver.IsValid(fr.Version) {
+				return nil, fmt.Errorf("invalid version %q of %s", fr.Version, fr.Module)
+			}
+			// Scans of several builds may find several versions.
+			if u.From == "" || semver.Compare(fr.Version, u.From) > 0 {
+				u.From = fr.Version
+			}
+		}
+		if f.FixedVersion == "" {
+			unfixed[fr.Module][f.OSV] = true
+			continue
+		}
+		if !semver.IsValid(f.FixedVersion) {
+			return nil, fmt.Errorf("invalid fixed version %q of %s for %s", f.FixedVersion, fr.Module, f.OSV)
+		}
+		fixes[fr.Module][f.OSV] = true
+		if semver.Compare(f.FixedVersion, u.To) > 0 {
+			u.To = f.FixedVersion
+		}
+	}
+
+	var plan []ModuleUpgrade
+	for path, u := range byPath {
+		if u.To != "" && u.From != "" && semver.Compare(u.To, u.From) <= 0 {
+			// The findings are of an earlier version than the one
+			// selected, so there is nothing to upgrade.
+			u.To = ""
+			clear(fixes[path])
+		}
+		// A vulnerability fixed in some version is not unfixed,
+		// even if some finding for it has no fixed version.
+		for id := range fixes[path] {
+			delete(unfixed[path], id)
+		}
+		u.Fixes = slices.Sorted(maps.Keys(fixes[path]))
+		u.Unfixed = slices.Sorted(maps.Keys(unfixed[path]))
+		if u.To == "" && len(u.Unfixed) == 0 {
+			continue
+		}
+		plan = append(plan, *u)
+	}
+	slices.SortFunc(plan, func(u1, u2 ModuleUpgrade) int {
+		return strings.Compare(u1.Path, u2.Path)
+	})
+	return plan, nil
+}
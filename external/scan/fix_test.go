@@ -0,0 +1,58 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFixPlan(t *testing.T) {
+	finding := func(osv, mod, version, fixed string) *govulncheck.Finding {
+		return &govulncheck.Finding{
+			OSV:          osv,
+			FixedVersion: fixed,
+			Trace:        []*govulncheck.Frame{{Module: mod, Version: version}},
+		}
+	}
+	findings := []*govulncheck.Finding{
+		finding("GO-0000-0001", "golang.org/x/text", "v0.3.5", "v0.3.7"),
+		// A symbol finding and a module finding for the same vulnerability.
+		finding("GO-0000-0002", "golang.org/x/text", "v0.3.5", "v0.3.8"),
+		finding("GO-0000-0002", "golang.org/x/text", "v0.3.5", "v0.3.8"),
+		finding("GO-0000-0003", "stdlib", "v1.21.0", "v1.21.5"),
+		finding("GO-0000-0004", "example.com/nofix", "v1.0.0", ""),
+		// Another build selected a version already fixing GO-0000-0005.
+		finding("GO-0000-0005", "example.com/fixed", "v1.0.0", "v1.1.0"),
+		finding("GO-0000-0006", "example.com/fixed", "v1.2.0", ""),
+	}
+	plan, err := FixPlan(findings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ModuleUpgrade{
+		{Path: "example.com/fixed", From: "v1.2.0", Unfixed: []string{"GO-0000-0006"}},
+		{Path: "example.com/nofix", From: "v1.0.0", Unfixed: []string{"GO-0000-0004"}},
+		{Path: "golang.org/x/text", From: "v0.3.5", To: "v0.3.8", Fixes: []string{"GO-0000-0001", "GO-0000-0002"}},
+		{Path: "stdlib", From: "v1.21.0", To: "v1.21.5", Fixes: []string{"GO-0000-0003"}},
+	}
+	if diff := cmp.Diff(want, plan); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	var args []string
+	for _, u := range plan {
+		args = append(args, u.GoGetArg())
+	}
+	if diff := cmp.Diff([]string{"", "", "golang.org/x/text@v0.3.8", "go@1.21.5"}, args); diff != "" {
+		t.Errorf("go get arguments mismatch (-want, +got):\n%s", diff)
+	}
+
+	if _, err := FixPlan([]*govulncheck.Finding{finding("GO-0000-0001", "golang.org/x/text", "v0.3.5", "0.3.7")}); err == nil {
+		t.Error("planning an invalid fixed version succeeded; want an error")
+	}
+}
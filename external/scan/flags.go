@@ -9,24 +9,118 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/StevenACoffman/invuln/external/client"
 	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/minisign"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
 	"golang.org/x/tools/go/buildutil"
+	"golang.org/x/tools/go/packages"
 )
 
 type config struct {
 	govulncheck.Config
-	patterns []string
-	db       string
-	dir      string
-	tags     buildutil.TagsFlag
-	test     bool
-	show     ShowFlag
-	format   FormatFlag
-	version  bool
-	env      []string
+	patterns      []string
+	db            []string
+	dbHeader      []string
+	dbCert        string
+	dbKey         string
+	dbPubKey      []string
+	dbRetries     int
+	dbTimeout     time.Duration
+	dbConcurrency int
+	dbRateLimit   float64
+	osvExtra      []string
+	ghsa          string
+	severityMap   string
+	nvd           string
+	dir           string
+	tags          buildutil.TagsFlag
+	test          bool
+	show          ShowFlag
+	format        FormatFlag
+	version       bool
+	watch         bool
+	tui           bool
+	tuiVEX        string
+	cache         bool
+	cacheTTL      time.Duration
+	noCache       bool
+	noResultCache bool
+	verify        string
+	emitGraph     string
+	emitOSVDir    string
+	handlerExec   string
+	proxyCheck    bool
+	compress      bool
+	platform      string
+	platforms     []string
+	goflags       string
+	gopath        bool
+	importcfg     string
+	env           []string
+
+	// logLevel, if set, is the level of the records
+	// logged to standard error, as requested by -log.
+	logLevel *slog.Level
+
+	// publicKeys are the parsed keys of dbPubKey.
+	publicKeys []*minisign.PublicKey
+
+	// extraEntries are the entries of the files matching osvExtra.
+	extraEntries []*osv.Entry
+
+	// ratings are the parsed ratings of severityMap.
+	ratings map[string]client.Rating
+
+	// callGraphCache is set when call graphs are cached
+	// between runs, which the -cache flag implies.
+	callGraphCache bool
+
+	// keepResult is set when the result of a source scan
+	// is kept in result, for SourceResult.
+	keepResult bool
+	result     *vulncheck.Result
+
+	// binaries, if set, are the binaries scanned in binary mode,
+	// whose names are the patterns, for RunBinariesWithHandler.
+	binaries []Binary
+
+	// args are the arguments of the command, which
+	// identify the scans whose results are cached.
+	args []string
+
+	// allowedFlags, if set, are the only flags which may be given,
+	// for the scans requested of the serve command.
+	allowedFlags map[string]bool
+
+	// packageCache, if set, keeps the packages loaded by the
+	// scans requested of the serve command.
+	packageCache *packageCache
+
+	// results, if set, keeps the results of the top-level packages
+	// scanned in watch mode, so that rescans only analyze those
+	// whose code changed.
+	results *resultCache
+
+	// wd, if set, is the directory relative to which the -C
+	// directory is interpreted, and which it defaults to, for
+	// the scans requested of the serve command.
+	wd string
+
+	// pkgs, if set, are the packages loaded by the caller scanned
+	// in source mode, whose paths are the patterns, for
+	// RunPackagesWithHandler.
+	pkgs []*packages.Package
 }
 
 func parseFlags(cfg *config, stderr io.Writer, args []string) error {
@@ -39,13 +133,102 @@ func parseFlags(cfg *config, stderr io.Writer, args []string) error {
 	flags.BoolVar(&json, "json", false, "output JSON (Go compatible legacy flag, see format flag)")
 	flags.BoolVar(&cfg.test, "test", false, "analyze test files (only valid for source mode, default false)")
 	flags.StringVar(&cfg.dir, "C", "", "change to `dir` before running govulncheck")
-	flags.StringVar(&cfg.db, "db", "https://vuln.go.dev", "vulnerability database `url`")
-	flags.Var(&modeFlag, "mode", "supports 'source', 'binary', and 'extract' (default 'source')")
+	flags.Func("db", "vulnerability database `url`; may be repeated to merge several databases, the first ones taking precedence for the same advisories (default 'https://vuln.go.dev')", func(s string) error {
+		cfg.db = append(cfg.db, s)
+		return nil
+	})
+	flags.Func("db-header", "add the `header`, of the form 'Name: value', to the requests made to http(s) databases; may be repeated", func(s string) error {
+		cfg.dbHeader = append(cfg.dbHeader, s)
+		return nil
+	})
+	flags.StringVar(&cfg.dbCert, "db-cert", "", "present the PEM encoded client certificate in `file` to http(s) databases requiring one, with the key of -db-key")
+	flags.StringVar(&cfg.dbKey, "db-key", "", "read the key of the client certificate of -db-cert from the PEM encoded `file`")
+	flags.Func("db-pubkey", "only trust the databases whose endpoints are signed by the minisign public `key`, given in base64 or as a minisign.pub file; may be repeated", func(s string) error {
+		cfg.dbPubKey = append(cfg.dbPubKey, s)
+		return nil
+	})
+	flags.IntVar(&cfg.dbRetries, "db-retries", 3, "retry the requests to http(s) databases failing with network or server errors up to `N` times, with exponential backoff")
+	flags.DurationVar(&cfg.dbTimeout, "db-timeout", 0, "give up on each attempt of a request to an http(s) database after `duration` (default none)")
+	flags.IntVar(&cfg.dbConcurrency, "db-concurrency", 10, "make up to `N` requests at once to the databases")
+	flags.Float64Var(&cfg.dbRateLimit, "db-rate-limit", 0, "make at most `rate` requests per second to http(s) databases (default unlimited)")
+	flags.Func("osv-extra", "match the OSV entries in the JSON files matching the glob `pattern`, such as './advisories/*.json', along with those of the databases, taking precedence over them; may be repeated", func(s string) error {
+		cfg.osvExtra = append(cfg.osvExtra, s)
+		return nil
+	})
+	flags.StringVar(&cfg.ghsa, "ghsa", "", "add the severity, references, and fixed versions of the GitHub advisories aliased by the Go advisories, read from the GitHub GraphQL API with 'api', authenticated by GITHUB_TOKEN, or else from a clone of github/advisory-database in `source`")
+	flags.StringVar(&cfg.nvd, "nvd", "", "add the CVSS vectors of the CVEs aliased by the advisories without severity, read from the NVD CVE API at `url`, such as https://services.nvd.nist.gov or a mirror, with the key in NVD_API_KEY if set")
+	flags.StringVar(&cfg.severityMap, "severity-map", "", "override the severities of the advisories, and add priorities, with those of the JSON `file` mapping advisory IDs or aliases to a severity, or to an object with \"severity\" and \"priority\" fields")
+	flags.Var(&modeFlag, "mode", "supports 'source', 'binary', 'sbom', and 'extract' (default 'source')")
 	flags.Var(&cfg.tags, "tags", "comma-separated `list` of build tags")
-	flags.Var(&cfg.show, "show", "enable display of additional information specified by the comma separated `list`\nThe supported values are 'traces','color', 'version', and 'verbose'")
+	flags.StringVar(&cfg.platform, "platform", "", "analyze the code as built for the `goos/goarch` platform (only valid for source mode)")
+	flags.BoolVar(&cfg.StrictPlatform, "strict-platform", false, "leave out the calls made in files whose build constraints the platform does not satisfy, such as those loaded with build tags naming other platforms (only valid for source mode)")
+	flags.Func("platforms", "analyze the code for each platform in the comma-separated `list` of goos/goarch platforms and merge the findings (only valid for source mode)", func(s string) error {
+		cfg.platforms = strings.Split(s, ",")
+		return nil
+	})
+	flags.StringVar(&cfg.goflags, "goflags", "", "space-separated `flags` added to GOFLAGS when loading packages (only valid for source mode)")
+	flags.Var(&cfg.show, "show", "enable display of additional information specified by the comma separated `list`\nThe supported values are 'traces','color', 'version', 'verbose', and 'remediation'")
 	flags.Var(&cfg.format, "format", "specify format output\nThe supported values are 'text', 'json', 'sarif', and 'openvex' (default 'text')")
 	flags.BoolVar(&version, "version", false, "print the version information")
-	flags.Var(&scanFlag, "scan", "set the scanning level desired, one of 'module', 'package', or 'symbol' (default 'symbol')")
+	flags.BoolVar(&cfg.compress, "compress", false, "compress the extracted blob (only valid for extract mode, default false)")
+	flags.BoolVar(&cfg.gopath, "gopath", false, "analyze code without go.mod files in GOPATH mode (only valid for source mode, default false)")
+	flags.StringVar(&cfg.importcfg, "importcfg", "", "load the package directories given as patterns as described by the importcfg `file` instead of the go command (only valid for source mode)")
+	flags.BoolVar(&cfg.cache, "cache", false, "reuse results and call graphs cached for packages unchanged since a previous scan (only valid for source mode, default false)")
+	flags.DurationVar(&cfg.cacheTTL, "cache-ttl", 0, "cache the responses of http(s) vulnerability databases, and reuse them for `duration`, which hides the advisories published meanwhile (default not cached)")
+	flags.BoolVar(&cfg.noCache, "no-cache", false, "do not cache the responses of http(s) vulnerability databases (default false)")
+	flags.BoolVar(&cfg.noResultCache, "no-result-cache", false, "do not reuse the results of a previous scan of the same code with the same flags and database (only valid for source mode, default false)")
+	flags.BoolVar(&cfg.watch, "watch", false, "rescan whenever files change (only valid for source mode, default false)")
+	flags.BoolVar(&cfg.tui, "tui", false, "triage the vulnerabilities found in a terminal UI, writing the decisions to the OpenVEX document of -tui-vex (only valid for source and binary modes, default false)")
+	flags.StringVar(&cfg.tuiVEX, "tui-vex", "govulncheck.vex.json", "read and write the triage decisions of -tui in the OpenVEX document `file`")
+	flags.StringVar(&cfg.verify, "verify", "", "cross-check the source analysis against the `binary` built from it (only valid for source mode)")
+	flags.StringVar(&cfg.emitGraph, "emit-graph", "", "write the call graph and import graph slices leading to vulnerable symbols to `file`, in DOT format if it ends in .dot or .gv and in GraphML format if it ends in .graphml (only valid for source mode)")
+	flags.StringVar(&cfg.emitOSVDir, "emit-osv-dir", "", "write the OSV entries of the vulnerabilities found to `dir`, each to a file named after its ID, such as GO-2023-0001.json")
+	flags.Func("log", "log the internals of the scan, such as the packages loaded and the requests made to the databases, to standard error at `level` 'debug', 'info', 'warn', or 'error'", func(s string) error {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(s)); err != nil {
+			return errors.New("must be one of debug, info, warn, or error")
+		}
+		cfg.logLevel = &l
+		return nil
+	})
+	flags.StringVar(&cfg.handlerExec, "handler-exec", "", "stream the JSON output to the standard input of `command`, which writes the output instead, such as in a custom format; the command line is split at spaces (implies -format json)")
+	flags.BoolVar(&cfg.proxyCheck, "proxy-check", false, "warn about the module versions unknown to the module proxy of GOPROXY, which may be typosquats or forged versions, leaving out the modules matching GONOPROXY or GOPRIVATE (default false)")
+	flags.IntVar(&cfg.MaxDepth, "max-depth", 0, "only report module and package level findings for dependencies within `N` modules of the main module (only valid for source mode, default unlimited)")
+	flags.IntVar(&cfg.MaxFindings, "max-findings", 0, "only report the findings of `N` vulnerabilities, those called first, only counting the others by module (default unlimited)")
+	flags.Func("callgraph", "construct call graphs with the `algorithm` 'vta', 'rta', or 'cha', trading precision for speed (only valid for source mode, default 'vta')", func(s string) error {
+		cfg.CallGraph = govulncheck.CallGraphAlgorithm(s)
+		return nil
+	})
+	flags.BoolVar(&cfg.Reflection, "reflection", false, "conservatively assume that calls made through reflection, such as by templates, may call vulnerable functions (only valid for source mode, default false)")
+	flags.BoolVar(&cfg.Taint, "taint", false, "track whether data from the network, files, or the environment can reach the arguments of calls of symbols vulnerable to crafted inputs (only valid for source mode, default false)")
+	flags.BoolVar(&cfg.SkipInit, "skip-init", false, "do not analyze package initialization, that is init functions and package-level variable initializers, as an entry point (only valid for source mode, default false)")
+	flags.BoolVar(&cfg.Library, "library", false, "scan the packages as a library, analyzing reachability from all their exported functions and methods and reporting which of them expose each vulnerable symbol (only valid for source mode, default false)")
+	flags.Func("entry", "analyze reachability from the functions matching `pattern`, such as example.com/app/server.Handle*, instead of the main and exported functions; may be repeated (only valid for source mode)", func(s string) error {
+		cfg.EntryPoints = append(cfg.EntryPoints, s)
+		return nil
+	})
+	flags.Func("confidence", "only report symbol level findings with at least the confidence `level` 'certain', 'likely', or 'possible' (default 'possible')", func(s string) error {
+		cfg.MinConfidence = govulncheck.Confidence(s)
+		return nil
+	})
+	flags.Func("max-memory", "keep the heap of govulncheck under `size`, such as 4GiB, by collecting garbage more often, and by reporting only imported vulnerable packages, and failing, when building the call graph exceeds it", func(s string) error {
+		n, err := parseSize(s)
+		cfg.MaxMemory = n
+		return err
+	})
+	flags.IntVar(&cfg.Workers, "workers", 0, "run the symbol level analysis with at most `n` goroutines at once (only valid for source mode, default the number of CPUs)")
+	flags.IntVar(&cfg.LoadWorkers, "load-workers", 0, "parse at most `n` files, and build and convert to SSA at most n packages, at once when loading packages (only valid for source mode, default unlimited)")
+	flags.BoolVar(&cfg.ExportData, "export-data", false, "type check only the packages importing vulnerable packages from source, loading the others from export data, which misses the vulnerable symbols they call through interfaces (only valid for source mode, default false)")
+	flags.IntVar(&cfg.WitnessWorkers, "witness-workers", 0, "search for the call stacks of symbol level findings with at most `n` goroutines at once (only valid for source mode, default the value of -workers)")
+	flags.Func("witness", "choose the call stack shown for each symbol level finding by the `ranking` 'shortest', 'fewest-third-party', or 'main-module' (only valid for source mode, default 'shortest')", func(s string) error {
+		cfg.WitnessRanking = govulncheck.WitnessRanking(s)
+		return nil
+	})
+	flags.DurationVar(&cfg.WitnessTimeout, "witness-timeout", 0, "stop searching for the call stack of a symbol level finding after `duration`, such as 10s, and show a partial one (only valid for source mode)")
+	flags.BoolVar(&cfg.WitnessThroughVulns, "witness-through-vulns", false, "let the call stacks of symbol level findings go through other vulnerable symbols of the same vulnerability, to show the shortest ones (only valid for source mode)")
+	flags.BoolVar(&cfg.Slice, "slice", false, "include in symbol level findings the source ranges of the functions along their call stacks, for editors to highlight the whole path (only valid for source mode, default false)")
+	flags.IntVar(&cfg.WitnessDepth, "witness-depth", 0, "search for the call stacks of symbol level findings at most `n` calls away from the vulnerable symbol, and otherwise show a partial one (only valid for source mode)")
+	flags.Var(&scanFlag, "scan", "set the scanning level desired, one of 'stdlib', 'module', 'package', or 'symbol' (default 'symbol')")
 
 	// We don't want to print the whole usage message on each flags
 	// error, so we set to a no-op and do the printing ourselves.
@@ -56,7 +239,16 @@ func parseFlags(cfg *config, stderr io.Writer, args []string) error {
 Usage:
 
 	govulncheck [flags] [patterns]
-	govulncheck -mode=binary [flags] [binary]
+	govulncheck -mode=binary [flags] [binary ...]
+	govulncheck -mode=sbom [flags] [sbom]
+	govulncheck db download [flags] -o file
+	govulncheck db mirror [flags] -dest dir
+	govulncheck db serve [-addr address] -dir dir
+	govulncheck db diff [flags] -from time [patterns]
+	govulncheck db convert -src dir -dest dir
+	govulncheck cache clean
+	govulncheck serve [flags]
+	govulncheck client [-server address] [flags] [patterns]
 
 `)
 		flags.PrintDefaults()
@@ -70,7 +262,43 @@ Usage:
 		}
 		return errUsage
 	}
+	envArgs, err := setFlagsFromEnv(flags, cfg.env)
+	if err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		return errUsage
+	}
+	cfg.args = append(envArgs, args...)
+	if cfg.allowedFlags != nil {
+		var err error
+		flags.Visit(func(f *flag.Flag) {
+			if err == nil && !cfg.allowedFlags[f.Name] {
+				err = fmt.Errorf("the -%s flag is not supported by scans of the serve command", f.Name)
+			}
+		})
+		if err != nil {
+			fmt.Fprintln(flags.Output(), err)
+			return errUsage
+		}
+	}
 	cfg.patterns = flags.Args()
+	if cfg.binaries != nil {
+		if len(cfg.patterns) > 0 {
+			fmt.Fprintln(flags.Output(), "patterns cannot be given along with the binaries to scan")
+			return errUsage
+		}
+		for _, b := range cfg.binaries {
+			cfg.patterns = append(cfg.patterns, b.Name)
+		}
+	}
+	if cfg.pkgs != nil {
+		if len(cfg.patterns) > 0 {
+			fmt.Fprintln(flags.Output(), "patterns cannot be given along with the packages to scan")
+			return errUsage
+		}
+		for _, p := range cfg.pkgs {
+			cfg.patterns = append(cfg.patterns, p.PkgPath)
+		}
+	}
 	if version {
 		cfg.show = append(cfg.show, "version")
 		cfg.version = true
@@ -81,16 +309,112 @@ Usage:
 		fmt.Fprintln(flags.Output(), err)
 		return errUsage
 	}
+	setBuildEnv(cfg)
 	return nil
 }
 
+// conflictingFlags are the pairs of flags which cannot be used together.
+var conflictingFlags = [][2]string{
+	{"json", "format"},
+	{"tui", "watch"},
+	{"cache", "no-cache"},
+	{"platform", "platforms"},
+	{"importcfg", "gopath"},
+	{"entry", "skip-init"},
+	{"library", "entry"},
+}
+
+// setFlagsFromEnv sets each flag not given on the command line to the
+// value of its environment variable in env, if set and not empty,
+// named after it as in GOVULNCHECK_SCAN for -scan and
+// GOVULNCHECK_DB_RATE_LIMIT for -db-rate-limit. The -version flag,
+// which does not configure scans, is left out, since the variable
+// often holds the version of govulncheck to install, as are the flags
+// conflicting with those given on the command line, which take
+// precedence. It returns the flags set, as arguments.
+func setFlagsFromEnv(flags *flag.FlagSet, env []string) ([]string, error) {
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	overridden := maps.Clone(given)
+	for _, c := range conflictingFlags {
+		overridden[c[0]] = overridden[c[0]] || given[c[1]]
+		overridden[c[1]] = overridden[c[1]] || given[c[0]]
+	}
+	var args []string
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || overridden[f.Name] || f.Name == "version" {
+			return
+		}
+		key := flagEnvVar(f.Name)
+		v := lookupEnv(env, key)
+		if v == "" {
+			return
+		}
+		if serr := flags.Set(f.Name, v); serr != nil {
+			err = fmt.Errorf("invalid value %q of %s: %v", v, key, serr)
+			return
+		}
+		args = append(args, "-"+f.Name+"="+v)
+	})
+	return args, err
+}
+
+// flagEnvVar returns the environment variable setting the flag name.
+func flagEnvVar(name string) string {
+	return "GOVULNCHECK_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// setBuildEnv adds the platform and go command flags requested by cfg
+// to the environment used for loading packages, so that the analyzed
+// code matches the production build.
+func setBuildEnv(cfg *config) {
+	if cfg.platform == "" && cfg.goflags == "" {
+		return
+	}
+	if cfg.env == nil {
+		cfg.env = os.Environ()
+	}
+	if cfg.platform != "" {
+		cfg.GOOS, cfg.GOARCH, _ = strings.Cut(cfg.platform, "/")
+		cfg.env = append(cfg.env, "GOOS="+cfg.GOOS, "GOARCH="+cfg.GOARCH)
+	}
+	if cfg.goflags != "" {
+		goflags := cfg.goflags
+		for _, e := range cfg.env {
+			if v, ok := strings.CutPrefix(e, "GOFLAGS="); ok && v != "" {
+				goflags = v + " " + cfg.goflags
+			}
+		}
+		cfg.env = append(cfg.env, "GOFLAGS="+goflags)
+	}
+}
+
 func validateConfig(cfg *config, json bool) error {
 	// take care of default values
+	if len(cfg.db) == 0 {
+		cfg.db = []string{"https://vuln.go.dev"}
+	}
 	if cfg.ScanMode == "" {
 		cfg.ScanMode = govulncheck.ScanModeSource
 	}
 	if cfg.ScanLevel == "" {
-		cfg.ScanLevel = govulncheck.ScanLevelSymbol
+		if cfg.ScanMode == govulncheck.ScanModeSBOM {
+			// SBOMs carry no symbol information.
+			cfg.ScanLevel = govulncheck.ScanLevelPackage
+		} else {
+			cfg.ScanLevel = govulncheck.ScanLevelSymbol
+		}
+	}
+	if cfg.handlerExec != "" && !json {
+		// The command is passed the JSON output.
+		switch cfg.format {
+		case formatUnset:
+			cfg.format = formatJSON
+		case formatJSON:
+		default:
+			return fmt.Errorf("the -handler-exec flag is not supported for %s output", cfg.format)
+		}
 	}
 	if json {
 		if cfg.format != formatUnset {
@@ -108,14 +432,332 @@ func validateConfig(cfg *config, json bool) error {
 		return fmt.Errorf("the -show flag is not supported for %s output", cfg.format)
 	}
 
+	if cfg.handlerExec != "" {
+		switch {
+		case strings.TrimSpace(cfg.handlerExec) == "":
+			return fmt.Errorf("the -handler-exec flag requires a command")
+		case cfg.watch:
+			return fmt.Errorf("the -handler-exec flag is not supported with -watch")
+		case cfg.ScanMode == govulncheck.ScanModeExtract:
+			return fmt.Errorf("the -handler-exec flag is not supported in extract mode")
+		}
+	}
+
+	if cfg.watch && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -watch flag is only supported in source mode")
+	}
+
+	if cfg.tui {
+		switch {
+		case cfg.ScanMode != govulncheck.ScanModeSource && cfg.ScanMode != govulncheck.ScanModeBinary:
+			return fmt.Errorf("the -tui flag is only supported in source and binary modes")
+		case cfg.format != formatText:
+			return fmt.Errorf("the -tui flag is not supported for %s output", cfg.format)
+		case cfg.watch:
+			return fmt.Errorf("the -tui and -watch flags cannot be used together")
+		}
+	}
+
+	if cfg.cache && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -cache flag is only supported in source mode")
+	}
+
+	if cfg.cache && cfg.noCache {
+		return fmt.Errorf("the -cache and -no-cache flags cannot be used together")
+	}
+
+	if cfg.noResultCache && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -no-result-cache flag is only supported in source mode")
+	}
+
+	if cfg.cacheTTL < 0 {
+		return fmt.Errorf("invalid -cache-ttl %s: must not be negative", cfg.cacheTTL)
+	}
+
+	if cfg.platform != "" {
+		if cfg.ScanMode != govulncheck.ScanModeSource {
+			return fmt.Errorf("the -platform flag is only supported in source mode")
+		}
+		if !validPlatform(cfg.platform) {
+			return fmt.Errorf("invalid platform %q: must be of the form goos/goarch", cfg.platform)
+		}
+	}
+
+	if len(cfg.platforms) > 0 {
+		if cfg.ScanMode != govulncheck.ScanModeSource {
+			return fmt.Errorf("the -platforms flag is only supported in source mode")
+		}
+		if cfg.platform != "" {
+			return fmt.Errorf("the -platform and -platforms flags cannot be used together")
+		}
+		for _, p := range cfg.platforms {
+			if !validPlatform(p) {
+				return fmt.Errorf("invalid platform %q: must be of the form goos/goarch", p)
+			}
+		}
+	}
+
+	if cfg.goflags != "" {
+		if cfg.ScanMode != govulncheck.ScanModeSource {
+			return fmt.Errorf("the -goflags flag is only supported in source mode")
+		}
+		for _, f := range strings.Fields(cfg.goflags) {
+			if !strings.HasPrefix(f, "-") {
+				return fmt.Errorf("invalid -goflags value %q: each flag must start with -", f)
+			}
+		}
+	}
+
+	if cfg.gopath && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -gopath flag is only supported in source mode")
+	}
+
+	if cfg.importcfg != "" {
+		if cfg.ScanMode != govulncheck.ScanModeSource {
+			return fmt.Errorf("the -importcfg flag is only supported in source mode")
+		}
+		if cfg.gopath {
+			return fmt.Errorf("the -importcfg and -gopath flags cannot be used together")
+		}
+		if cfg.test {
+			return fmt.Errorf("the -test flag is not supported with -importcfg")
+		}
+	}
+
+	if cfg.MaxDepth < 0 {
+		return fmt.Errorf("the -max-depth flag must not be negative")
+	}
+	if cfg.MaxDepth > 0 && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -max-depth flag is only supported in source mode")
+	}
+
+	if cfg.MaxFindings < 0 {
+		return fmt.Errorf("the -max-findings flag must not be negative")
+	}
+
+	switch cfg.CallGraph {
+	case "", govulncheck.CallGraphVTA, govulncheck.CallGraphRTA, govulncheck.CallGraphCHA:
+	default:
+		return fmt.Errorf("invalid -callgraph %q: must be one of vta, rta, or cha", cfg.CallGraph)
+	}
+	if cfg.CallGraph != "" && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -callgraph flag is only supported in source mode")
+	}
+
+	if cfg.Reflection && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -reflection flag is only supported in source mode")
+	}
+
+	if cfg.Taint && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -taint flag is only supported in source mode")
+	}
+
+	if cfg.SkipInit && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -skip-init flag is only supported in source mode")
+	}
+
+	if len(cfg.EntryPoints) > 0 {
+		if cfg.ScanMode != govulncheck.ScanModeSource {
+			return fmt.Errorf("the -entry flag is only supported in source mode")
+		}
+		if cfg.SkipInit {
+			return fmt.Errorf("the -entry and -skip-init flags cannot be used together")
+		}
+		for _, p := range cfg.EntryPoints {
+			if err := vulncheck.CheckEntryPattern(p); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.Library {
+		if cfg.ScanMode != govulncheck.ScanModeSource {
+			return fmt.Errorf("the -library flag is only supported in source mode")
+		}
+		if len(cfg.EntryPoints) > 0 {
+			return fmt.Errorf("the -library and -entry flags cannot be used together")
+		}
+	}
+
+	switch cfg.MinConfidence {
+	case "", govulncheck.ConfidenceCertain, govulncheck.ConfidenceLikely, govulncheck.ConfidencePossible:
+	default:
+		return fmt.Errorf("invalid -confidence %q: must be one of certain, likely, or possible", cfg.MinConfidence)
+	}
+
+	if cfg.Workers < 0 {
+		return fmt.Errorf("invalid -workers %d: must not be negative", cfg.Workers)
+	}
+
+	if cfg.Workers > 0 && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -workers flag is only supported in source mode")
+	}
+
+	if cfg.LoadWorkers < 0 {
+		return fmt.Errorf("invalid -load-workers %d: must not be negative", cfg.LoadWorkers)
+	}
+
+	if cfg.LoadWorkers > 0 && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -load-workers flag is only supported in source mode")
+	}
+
+	if cfg.ExportData {
+		if cfg.ScanMode != govulncheck.ScanModeSource {
+			return fmt.Errorf("the -export-data flag is only supported in source mode")
+		}
+		if cfg.gopath || cfg.importcfg != "" {
+			return fmt.Errorf("the -export-data flag cannot be used with -gopath or -importcfg")
+		}
+	}
+
+	if cfg.WitnessWorkers < 0 {
+		return fmt.Errorf("invalid -witness-workers %d: must not be negative", cfg.WitnessWorkers)
+	}
+
+	if cfg.WitnessWorkers > 0 && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -witness-workers flag is only supported in source mode")
+	}
+
+	switch cfg.WitnessRanking {
+	case "", govulncheck.WitnessShortest, govulncheck.WitnessFewestThirdParty, govulncheck.WitnessMainModule:
+	default:
+		return fmt.Errorf("invalid -witness %q: must be one of shortest, fewest-third-party, or main-module", cfg.WitnessRanking)
+	}
+
+	if cfg.WitnessRanking != "" && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -witness flag is only supported in source mode")
+	}
+
+	if cfg.WitnessTimeout < 0 {
+		return fmt.Errorf("invalid -witness-timeout %v: must not be negative", cfg.WitnessTimeout)
+	}
+
+	if cfg.WitnessTimeout > 0 && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -witness-timeout flag is only supported in source mode")
+	}
+
+	if cfg.WitnessDepth < 0 {
+		return fmt.Errorf("invalid -witness-depth %d: must not be negative", cfg.WitnessDepth)
+	}
+
+	if cfg.WitnessDepth > 0 && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -witness-depth flag is only supported in source mode")
+	}
+
+	if cfg.WitnessThroughVulns && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -witness-through-vulns flag is only supported in source mode")
+	}
+
+	if cfg.StrictPlatform && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -strict-platform flag is only supported in source mode")
+	}
+
+	if cfg.Slice && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -slice flag is only supported in source mode")
+	}
+
+	if err := validateDBConfig(cfg); err != nil {
+		return err
+	}
+
+	if cfg.ghsa == "api" && lookupEnv(cfg.env, "GITHUB_TOKEN") == "" {
+		return fmt.Errorf("the -ghsa api source requires GITHUB_TOKEN to be set")
+	}
+
+	if cfg.emitOSVDir != "" && cfg.ScanMode == govulncheck.ScanModeExtract {
+		return fmt.Errorf("the -emit-osv-dir flag is not supported in extract mode")
+	}
+
+	if cfg.proxyCheck && cfg.ScanMode == govulncheck.ScanModeExtract {
+		return fmt.Errorf("the -proxy-check flag is not supported in extract mode")
+	}
+
+	if cfg.compress && cfg.ScanMode != govulncheck.ScanModeExtract {
+		return fmt.Errorf("the -compress flag is only supported in extract mode")
+	}
+
+	if cfg.verify != "" && cfg.ScanMode != govulncheck.ScanModeSource {
+		return fmt.Errorf("the -verify flag is only supported in source mode")
+	}
+
+	if cfg.emitGraph != "" {
+		if cfg.ScanMode != govulncheck.ScanModeSource {
+			return fmt.Errorf("the -emit-graph flag is only supported in source mode")
+		}
+		if _, ok := graphFormat(cfg.emitGraph); !ok {
+			return fmt.Errorf("invalid -emit-graph file %q: must end in .dot, .gv, or .graphml", cfg.emitGraph)
+		}
+		if cfg.cache || len(cfg.platforms) > 0 || cfg.watch {
+			return fmt.Errorf("the -emit-graph flag cannot be used with -cache, -platforms, or -watch")
+		}
+	}
+
+	if cfg.ScanLevel == govulncheck.ScanLevelStdlib && cfg.ScanMode != govulncheck.ScanModeSource && cfg.ScanMode != govulncheck.ScanModeBinary {
+		return fmt.Errorf("standard library only scanning is only supported in source and binary modes")
+	}
+
 	switch cfg.ScanMode {
 	case govulncheck.ScanModeSource:
-		if len(cfg.patterns) == 1 && isFile(cfg.patterns[0]) {
+		if cfg.pkgs == nil && len(cfg.patterns) == 1 && isFile(cfg.patterns[0]) {
 			return fmt.Errorf("%q is a file.\n\n%v", cfg.patterns[0], errNoBinaryFlag)
 		}
 		if cfg.ScanLevel == govulncheck.ScanLevelModule && len(cfg.patterns) != 0 {
 			return fmt.Errorf("patterns are not accepted for module only scanning")
 		}
+		if cfg.ScanLevel == govulncheck.ScanLevelStdlib && len(cfg.patterns) != 0 {
+			return fmt.Errorf("patterns are not accepted for standard library only scanning")
+		}
+		if cfg.CallGraph != "" && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -callgraph flag requires symbol level scanning")
+		}
+		if cfg.Reflection && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -reflection flag requires symbol level scanning")
+		}
+		if cfg.Taint && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -taint flag requires symbol level scanning")
+		}
+		if cfg.emitGraph != "" && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -emit-graph flag requires symbol level scanning")
+		}
+		if cfg.Library && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -library flag requires symbol level scanning")
+		}
+		if len(cfg.EntryPoints) > 0 && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -entry flag requires symbol level scanning")
+		}
+		if cfg.ExportData && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -export-data flag requires symbol level scanning")
+		}
+		if cfg.SkipInit && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -skip-init flag requires symbol level scanning")
+		}
+		if cfg.WitnessRanking != "" && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -witness flag requires symbol level scanning")
+		}
+		if cfg.WitnessTimeout > 0 && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -witness-timeout flag requires symbol level scanning")
+		}
+		if cfg.WitnessDepth > 0 && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -witness-depth flag requires symbol level scanning")
+		}
+		if cfg.WitnessWorkers > 0 && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -witness-workers flag requires symbol level scanning")
+		}
+		if cfg.WitnessThroughVulns && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -witness-through-vulns flag requires symbol level scanning")
+		}
+		if cfg.StrictPlatform && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -strict-platform flag requires symbol level scanning")
+		}
+		if cfg.Slice && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -slice flag requires symbol level scanning")
+		}
+		if cfg.verify != "" && !cfg.ScanLevel.WantSymbols() {
+			return fmt.Errorf("the -verify flag requires symbol level scanning")
+		}
+		if cfg.verify != "" && !isFile(cfg.verify) {
+			return fmt.Errorf("%q is not a file", cfg.verify)
+		}
 	case govulncheck.ScanModeBinary:
 		if cfg.test {
 			return fmt.Errorf("the -test flag is not supported in binary mode")
@@ -123,8 +765,29 @@ func validateConfig(cfg *config, json bool) error {
 		if len(cfg.tags) > 0 {
 			return fmt.Errorf("the -tags flag is not supported in binary mode")
 		}
+		if len(cfg.patterns) == 0 {
+			return fmt.Errorf("at least 1 binary must be provided")
+		}
+		for _, p := range cfg.patterns {
+			if cfg.binaries != nil {
+				break // the binaries are read from readers
+			}
+			if !isFile(p) && !isURL(p) {
+				return fmt.Errorf("%q is not a file", p)
+			}
+		}
+	case govulncheck.ScanModeSBOM:
+		if cfg.test {
+			return fmt.Errorf("the -test flag is not supported in sbom mode")
+		}
+		if len(cfg.tags) > 0 {
+			return fmt.Errorf("the -tags flag is not supported in sbom mode")
+		}
+		if cfg.ScanLevel == govulncheck.ScanLevelSymbol {
+			return fmt.Errorf("symbol level scanning is not supported in sbom mode")
+		}
 		if len(cfg.patterns) != 1 {
-			return fmt.Errorf("only 1 binary can be analyzed at a time")
+			return fmt.Errorf("only 1 SBOM can be scanned at a time")
 		}
 		if !isFile(cfg.patterns[0]) {
 			return fmt.Errorf("%q is not a file", cfg.patterns[0])
@@ -169,6 +832,9 @@ func validateConfig(cfg *config, json bool) error {
 			return fmt.Errorf("the json format must be set in query mode")
 		}
 		for _, pattern := range cfg.patterns {
+			if isFile(pattern) {
+				continue // a blob
+			}
 			// Parse the input here so that we can catch errors before
 			// outputting the Config.
 			if _, _, err := parseModuleQuery(pattern); err != nil {
@@ -179,6 +845,33 @@ func validateConfig(cfg *config, json bool) error {
 	return nil
 }
 
+// sizeUnits are the units of sizes accepted by parseSize.
+var sizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+// parseSize parses a positive size in bytes, with an
+// optional unit, as in 512MiB or 4GB.
+func parseSize(s string) (int64, error) {
+	num, unit := s, int64(1)
+	for _, u := range sizeUnits {
+		if n, ok := strings.CutSuffix(s, u.suffix); ok {
+			num, unit = n, u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/unit {
+		return 0, errors.New("must be a positive size in bytes, such as 512MiB or 4GB")
+	}
+	return n * unit, nil
+}
+
 func isFile(path string) bool {
 	s, err := os.Stat(path)
 	if err != nil {
@@ -187,6 +880,85 @@ func isFile(path string) bool {
 	return !s.IsDir()
 }
 
+// validateDBConfig validates the flags configuring
+// the access to vulnerability databases.
+func validateDBConfig(cfg *config) error {
+	for _, h := range cfg.dbHeader {
+		if name, _, ok := strings.Cut(h, ":"); !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid -db-header %q: must be of the form 'Name: value'", h)
+		}
+	}
+	if (cfg.dbCert == "") != (cfg.dbKey == "") {
+		return fmt.Errorf("the -db-cert and -db-key flags must be used together")
+	}
+	for _, k := range cfg.dbPubKey {
+		key, err := parsePublicKey(k)
+		if err != nil {
+			return fmt.Errorf("invalid -db-pubkey %q: %w", k, err)
+		}
+		cfg.publicKeys = append(cfg.publicKeys, key)
+	}
+	if cfg.dbRetries < 0 {
+		return fmt.Errorf("invalid -db-retries %d: must not be negative", cfg.dbRetries)
+	}
+	if cfg.dbTimeout < 0 {
+		return fmt.Errorf("invalid -db-timeout %s: must not be negative", cfg.dbTimeout)
+	}
+	if cfg.dbConcurrency < 1 {
+		return fmt.Errorf("invalid -db-concurrency %d: must be positive", cfg.dbConcurrency)
+	}
+	if cfg.dbRateLimit < 0 {
+		return fmt.Errorf("invalid -db-rate-limit %g: must not be negative", cfg.dbRateLimit)
+	}
+	var files []string
+	for _, p := range cfg.osvExtra {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return fmt.Errorf("invalid -osv-extra %q: %w", p, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("invalid -osv-extra %q: no files match", p)
+		}
+		files = append(files, matches...)
+	}
+	if cfg.severityMap != "" {
+		b, err := os.ReadFile(cfg.severityMap)
+		if err != nil {
+			return fmt.Errorf("invalid -severity-map: %w", err)
+		}
+		if cfg.ratings, err = client.ParseRatings(b); err != nil {
+			return fmt.Errorf("invalid -severity-map %s: %w", cfg.severityMap, err)
+		}
+	}
+	if len(files) > 0 {
+		entries, err := client.ReadEntries(files)
+		if err != nil {
+			return fmt.Errorf("invalid -osv-extra entry: %w", err)
+		}
+		cfg.extraEntries = entries
+	}
+	return nil
+}
+
+// parsePublicKey parses the minisign public key s, or else
+// the key in the file s.
+func parsePublicKey(s string) (*minisign.PublicKey, error) {
+	if key, err := minisign.ParsePublicKey(s); err == nil {
+		return key, nil
+	}
+	b, err := os.ReadFile(s)
+	if err != nil {
+		return nil, errors.New("neither a minisign public key nor a readable file")
+	}
+	return minisign.ParsePublicKey(string(b))
+}
+
+// validPlatform reports whether p is of the form goos/goarch.
+func validPlatform(p string) bool {
+	goos, goarch, ok := strings.Cut(p, "/")
+	return ok && goos != "" && goarch != "" && !strings.Contains(goarch, "/")
+}
+
 var errFlagParse = errors.New("see -help for details")
 
 // ShowFlag is used for parsing and validation of
@@ -194,10 +966,11 @@ var errFlagParse = errors.New("see -help for details")
 type ShowFlag []string
 
 var supportedShows = map[string]bool{
-	"traces":  true,
-	"color":   true,
-	"verbose": true,
-	"version": true,
+	"traces":      true,
+	"color":       true,
+	"verbose":     true,
+	"version":     true,
+	"remediation": true,
 }
 
 func (v *ShowFlag) Set(s string) error {
@@ -272,6 +1045,7 @@ var supportedModes = map[string]bool{
 	govulncheck.ScanModeConvert: true,
 	govulncheck.ScanModeQuery:   true,
 	govulncheck.ScanModeExtract: true,
+	govulncheck.ScanModeSBOM:    true,
 }
 
 func (f *ModeFlag) Get() interface{} { return *f }
@@ -289,6 +1063,7 @@ func (f *ModeFlag) String() string { return "" }
 type ScanFlag string
 
 var supportedLevels = map[string]bool{
+	govulncheck.ScanLevelStdlib:  true,
 	govulncheck.ScanLevelModule:  true,
 	govulncheck.ScanLevelPackage: true,
 	govulncheck.ScanLevelSymbol:  true,
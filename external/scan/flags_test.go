@@ -0,0 +1,105 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"io"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetBuildEnv(t *testing.T) {
+	cfg := &config{
+		env:      []string{"PATH=/bin", "GOFLAGS=-mod=vendor"},
+		platform: "windows/arm64",
+		goflags:  "-trimpath -buildvcs=false",
+	}
+	setBuildEnv(cfg)
+	if cfg.GOOS != "windows" || cfg.GOARCH != "arm64" {
+		t.Errorf("got platform %s/%s; want windows/arm64", cfg.GOOS, cfg.GOARCH)
+	}
+	want := []string{
+		"PATH=/bin",
+		"GOFLAGS=-mod=vendor",
+		"GOOS=windows",
+		"GOARCH=arm64",
+		"GOFLAGS=-mod=vendor -trimpath -buildvcs=false",
+	}
+	if diff := cmp.Diff(want, cfg.env); diff != "" {
+		t.Errorf("env mismatch (-want, +got):\n%s", diff)
+	}
+
+	// Without build settings, the environment is left alone.
+	cfg = &config{}
+	setBuildEnv(cfg)
+	if cfg.env != nil {
+		t.Errorf("got env %v; want nil", cfg.env)
+	}
+}
+
+func TestParseFlagsFromEnv(t *testing.T) {
+	env := []string{
+		"GOVULNCHECK_FORMAT=json",
+		"GOVULNCHECK_SCAN=module",
+		"GOVULNCHECK_DB_RETRIES=5",
+		"GOVULNCHECK_TEST=true",
+		"GOVULNCHECK_VERSION=v1.1.0",
+	}
+	cfg := &config{env: env}
+	if err := parseFlags(cfg, io.Discard, []string{"-scan", "package", "./..."}); err != nil {
+		t.Fatal(err)
+	}
+	// The flags given on the command line take precedence.
+	if cfg.format != formatJSON || cfg.ScanLevel != govulncheck.ScanLevelPackage || cfg.dbRetries != 5 || !cfg.test || cfg.version {
+		t.Errorf("got format %s, scan level %s, %d retries, test %t, and version %t; want json, package, 5, true, and false",
+			cfg.format, cfg.ScanLevel, cfg.dbRetries, cfg.test, cfg.version)
+	}
+	want := []string{"-db-retries=5", "-format=json", "-test=true", "-scan", "package", "./..."}
+	if diff := cmp.Diff(want, cfg.args); diff != "" {
+		t.Errorf("args mismatch (-want, +got):\n%s", diff)
+	}
+
+	// The flags of the environment conflicting with those
+	// given on the command line are left out.
+	cfg = &config{env: []string{"GOVULNCHECK_JSON=true", "GOVULNCHECK_CACHE=true"}}
+	if err := parseFlags(cfg, io.Discard, []string{"-format", "sarif", "-no-cache", "./..."}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.format != formatSarif || cfg.cache {
+		t.Errorf("got format %s and cache %t; want sarif and false", cfg.format, cfg.cache)
+	}
+
+	cfg = &config{env: []string{"GOVULNCHECK_DB_RETRIES=many"}}
+	if err := parseFlags(cfg, io.Discard, []string{"./..."}); err != errUsage {
+		t.Errorf("got error %v for an invalid value; want %v", err, errUsage)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want int64
+	}{
+		{"1024", 1024},
+		{"512MiB", 512 << 20},
+		{"4GiB", 4 << 30},
+		{"4GB", 4e9},
+		{"100KB", 100e3},
+		{"10B", 10},
+		{"", 0},
+		{"0", 0},
+		{"-1GiB", 0},
+		{"4G", 0},
+		{"1.5GiB", 0},
+		{"9999999999TiB", 0},
+	} {
+		got, err := parseSize(test.in)
+		if got != test.want || (err == nil) != (test.want > 0) {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", test.in, got, err, test.want)
+		}
+	}
+}
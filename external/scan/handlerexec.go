@@ -0,0 +1,40 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// startHandlerExec starts the command of the -handler-exec flag of cfg,
+// writing to stdout and stderr. It returns the writer of the standard
+// input of the command, to which the JSON output is written, and a
+// function closing it and waiting for the command to exit.
+func startHandlerExec(ctx context.Context, cfg *config, stdout, stderr io.Writer) (io.Writer, func() error, error) {
+	args := strings.Fields(cfg.handlerExec)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = cfg.env
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("starting handler: %w", err)
+	}
+	wait := func() error {
+		in.Close()
+		if err := cmd.Wait(); err != nil {
+			return fmt.Errorf("handler %s: %w", args[0], err)
+		}
+		return nil
+	}
+	return in, wait, nil
+}
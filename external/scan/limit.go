@@ -0,0 +1,127 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+)
+
+// findingLimit passes on to Handler all messages but the findings of
+// the vulnerabilities after the first max, so that the output, and the
+// memory of handlers gathering findings, stay bounded on pathological
+// dependency trees. The findings are held until flushing, so that the
+// vulnerabilities found at the most precise level, such as those
+// called, come first, and none of those affecting the code is left
+// out for less precise ones. The vulnerabilities after those are only
+// counted per module. How many were left out is reported when flushing,
+// to text, if not nil, or else as a progress message.
+type findingLimit struct {
+	govulncheck.Handler
+	max  int
+	text *TextHandler
+
+	findings []*govulncheck.Finding
+	more     map[string]int // by module path
+}
+
+func (h *findingLimit) Finding(finding *govulncheck.Finding) error {
+	h.findings = append(h.findings, finding)
+	return nil
+}
+
+func (h *findingLimit) Flush() error {
+	// The vulnerabilities, in the order they were first found,
+	// along with the most precise level they were found at.
+	var ids []string
+	precision := make(map[string]int)
+	mods := make(map[string]string)
+	for _, f := range h.findings {
+		if _, ok := precision[f.OSV]; !ok {
+			ids = append(ids, f.OSV)
+			if len(f.Trace) > 0 {
+				mods[f.OSV] = f.Trace[0].Module
+			}
+		}
+		precision[f.OSV] = max(precision[f.OSV], findingPrecision(f))
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return precision[ids[i]] > precision[ids[j]] })
+	kept := make(map[string]bool)
+	for i, id := range ids {
+		if i < h.max {
+			kept[id] = true
+			continue
+		}
+		if h.more == nil {
+			h.more = make(map[string]int)
+		}
+		h.more[mods[id]]++
+	}
+	for _, f := range h.findings {
+		if !kept[f.OSV] {
+			continue
+		}
+		if err := h.Handler.Finding(f); err != nil {
+			return err
+		}
+	}
+	h.findings = nil
+
+	if len(h.more) > 0 {
+		msg := h.overflow()
+		if h.text != nil {
+			h.text.omitted = msg
+		} else if err := h.Handler.Progress(&govulncheck.Progress{Message: msg}); err != nil {
+			return err
+		}
+	}
+	return Flush(h.Handler)
+}
+
+// findingPrecision ranks the level of finding, from 0 for
+// required modules to 3 for called symbols.
+func findingPrecision(finding *govulncheck.Finding) int {
+	if len(finding.Trace) == 0 {
+		return 0
+	}
+	switch fr := finding.Trace[0]; {
+	case fr.Function != "" && fr.Kind != govulncheck.SymbolKindFunc:
+		return 3
+	case fr.Function != "":
+		return 2 // referenced
+	case fr.Package != "":
+		return 1
+	}
+	return 0
+}
+
+// overflow describes the vulnerabilities left out, as in "Only the
+// findings of the first 100 vulnerabilities are reported, and of 412
+// more in module golang.org/x/net."
+func (h *findingLimit) overflow() string {
+	mods := make([]string, 0, len(h.more))
+	for mod := range h.more {
+		mods = append(mods, mod)
+	}
+	sort.Strings(mods)
+	var b strings.Builder
+	fmt.Fprintf(&b, "Only the findings of the first %d vulnerabilities are reported", h.max)
+	for _, mod := range mods {
+		switch mod {
+		case external.GoStdModulePath:
+			fmt.Fprintf(&b, ", and of %d more in the standard library", h.more[mod])
+		case "":
+			fmt.Fprintf(&b, ", and of %d more", h.more[mod])
+		default:
+			fmt.Fprintf(&b, ", and of %d more in module %s", h.more[mod], mod)
+		}
+	}
+	b.WriteString(". Pass a larger -max-findings to report them.")
+	return b.String()
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+)
+
+func TestFindingLimit(t *testing.T) {
+	// The findings in the order of a symbol level scan: those of
+	// modules, then those of packages, then those of symbols.
+	module := func(id, mod string) *govulncheck.Finding {
+		return &govulncheck.Finding{OSV: id, Trace: []*govulncheck.Frame{{Module: mod}}}
+	}
+	pkg := func(id, mod string) *govulncheck.Finding {
+		return &govulncheck.Finding{OSV: id, Trace: []*govulncheck.Frame{{Module: mod, Package: mod}}}
+	}
+	call := func(id, mod string) *govulncheck.Finding {
+		return &govulncheck.Finding{OSV: id, Trace: []*govulncheck.Frame{{Module: mod, Package: mod, Function: "F"}, {Module: "example.com/m", Package: "example.com/m", Function: "main"}}}
+	}
+	findings := []*govulncheck.Finding{
+		module("GO-0000-0001", "example.com/a"),
+		module("GO-0000-0002", "example.com/b"),
+		module("GO-0000-0003", "stdlib"),
+		module("GO-0000-0004", "example.com/b"),
+		module("GO-0000-0005", "example.com/a"),
+		module("GO-0000-0006", "example.com/b"),
+		pkg("GO-0000-0001", "example.com/a"),
+		pkg("GO-0000-0006", "example.com/b"),
+		call("GO-0000-0006", "example.com/b"),
+	}
+
+	mock := test.NewMockHandler()
+	h := &findingLimit{Handler: mock, max: 2}
+	for _, f := range findings {
+		if err := h.Finding(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// The called vulnerability, and then the imported one,
+	// with all their findings.
+	var got []string
+	for _, f := range mock.FindingMessages {
+		got = append(got, f.OSV)
+	}
+	if want := []string{"GO-0000-0001", "GO-0000-0006", "GO-0000-0001", "GO-0000-0006", "GO-0000-0006"}; !slices.Equal(got, want) {
+		t.Errorf("got findings of %v; want %v", got, want)
+	}
+	want := "Only the findings of the first 2 vulnerabilities are reported, and of 1 more in module example.com/a, and of 2 more in module example.com/b, and of 1 more in the standard library. Pass a larger -max-findings to report them."
+	if len(mock.ProgressMessages) != 1 || mock.ProgressMessages[0].Message != want {
+		t.Errorf("got progress messages %+v; want %q", mock.ProgressMessages, want)
+	}
+
+	// Text output ends with the summary instead, and the
+	// called vulnerability is reported.
+	for _, tc := range []struct {
+		max     int
+		omitted bool
+	}{{1, true}, {10, false}} {
+		var buf bytes.Buffer
+		th := NewTextHandler(&buf)
+		th.Config(&govulncheck.Config{ScanLevel: govulncheck.ScanLevelSymbol})
+		h = &findingLimit{Handler: th, max: tc.max, text: th}
+		for _, f := range findings {
+			if err := h.Finding(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := h.Flush(); err != errVulnerabilitiesFound {
+			t.Errorf("max %d: got error %v; want %v", tc.max, err, errVulnerabilitiesFound)
+		}
+		if got := strings.Contains(buf.String(), "Pass a larger -max-findings"); got != tc.omitted {
+			t.Errorf("max %d: text output ends with the summary %t; want %t:\n%s", tc.max, got, tc.omitted, &buf)
+		}
+		if !strings.Contains(buf.String(), "GO-0000-0006") {
+			t.Errorf("max %d: called vulnerability not reported:\n%s", tc.max, &buf)
+		}
+	}
+}
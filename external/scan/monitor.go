@@ -0,0 +1,204 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// monitorInterval is the interval between the
+// progress messages of a progressMonitor.
+var monitorInterval = 10 * time.Second
+
+// phaseNames describe the phases of scans in progress messages.
+var phaseNames = map[govulncheck.Phase]string{
+	govulncheck.PhaseLoad:      "loading packages",
+	govulncheck.PhaseFetch:     "fetching vulnerabilities",
+	govulncheck.PhaseCallGraph: "building the call graph",
+	govulncheck.PhaseAnalyze:   "analyzing call stacks",
+}
+
+// A progressMonitor passes to its handler, periodically, a progress
+// message telling the phase the scan is in, how long the phases done
+// took, and how much memory the scan uses, so that a scan taking long
+// can be told to be loading packages, building its call graph, or
+// waiting for the vulnerability database. It serializes the calls of
+// its handler, which are also made by the scan.
+type progressMonitor struct {
+	govulncheck.Handler
+
+	mu    sync.Mutex // serializes the calls of Handler, guarding the fields below
+	phase govulncheck.Phase
+	start time.Time // of phase
+	done  int       // units of phase done
+	total int       // units of phase, or 0 if unknown
+	took  []string  // durations of the phases done, as in "loading packages took 2s"
+
+	stopc chan struct{}
+	wg    sync.WaitGroup
+}
+
+// startMonitor starts monitoring the scan run with the returned
+// context, which passes the progress of its phases to that of ctx as
+// well, and whose messages are passed to the returned handler. The
+// monitor must be stopped.
+func startMonitor(ctx context.Context, handler govulncheck.Handler) (context.Context, *progressMonitor) {
+	m := &progressMonitor{Handler: handler, stopc: make(chan struct{})}
+	parent := ctx
+	ctx = govulncheck.WithProgress(ctx, func(ev *govulncheck.ProgressEvent) {
+		m.observe(ev)
+		govulncheck.ReportProgress(parent, ev)
+	})
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(monitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stopc:
+				return
+			case <-ticker.C:
+				m.report()
+			}
+		}
+	}()
+	return ctx, m
+}
+
+// stop stops reporting the progress of the scan.
+func (m *progressMonitor) stop() {
+	close(m.stopc)
+	m.wg.Wait()
+}
+
+func (m *progressMonitor) observe(ev *govulncheck.ProgressEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch ev.Kind {
+	case govulncheck.ProgressStarted:
+		m.phase, m.start = ev.Phase, time.Now()
+	case govulncheck.ProgressCompleted:
+		if ev.Phase == m.phase {
+			m.took = append(m.took, fmt.Sprintf("%s took %s", phaseNames[m.phase], time.Since(m.start).Round(100*time.Millisecond)))
+			m.phase = ""
+		}
+	}
+	if ev.Phase == m.phase {
+		m.done, m.total = ev.Done, ev.Total
+	}
+}
+
+// report passes the progress message of the scan to the handler.
+func (m *progressMonitor) report() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+	if m.phase != "" {
+		fmt.Fprintf(&b, "Still %s after %s", phaseNames[m.phase], time.Since(m.start).Round(time.Second))
+		if m.total > 0 {
+			fmt.Fprintf(&b, " (%d of %d done)", m.done, m.total)
+		}
+		b.WriteString(". ")
+	}
+	b.WriteString(memoryUsage())
+	if len(m.took) > 0 {
+		fmt.Fprintf(&b, " %s.", strings.Join(m.took, ", "))
+	}
+	// Errors writing the output are reported by the scan.
+	m.Handler.Progress(&govulncheck.Progress{Message: b.String()})
+}
+
+// memoryUsage describes the memory used by govulncheck.
+func memoryUsage() string {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/objects:bytes"},
+	}
+	metrics.Read(samples)
+	total, heap := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+	if rss := residentSize(); rss > 0 {
+		total = rss
+	}
+	return fmt.Sprintf("Using %s of memory, %s of it for the heap.", approxSize(total), approxSize(heap))
+}
+
+// residentSize returns the resident set size of govulncheck in bytes,
+// or 0 where it cannot be read, as outside of Linux.
+func residentSize() uint64 {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return pages * uint64(os.Getpagesize())
+}
+
+// approxSize formats n bytes in the largest binary unit
+// not exceeding it, to a tenth, as in 1.5GiB.
+func approxSize(n uint64) string {
+	for _, u := range []struct {
+		name string
+		size uint64
+	}{{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}} {
+		if n >= u.size {
+			return fmt.Sprintf("%.1f%s", float64(n)/float64(u.size), u.name)
+		}
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+func (m *progressMonitor) Config(c *govulncheck.Config) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Handler.Config(c)
+}
+
+func (m *progressMonitor) SBOM(s *govulncheck.SBOM) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Handler.SBOM(s)
+}
+
+func (m *progressMonitor) Progress(p *govulncheck.Progress) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Handler.Progress(p)
+}
+
+func (m *progressMonitor) OSV(e *osv.Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Handler.OSV(e)
+}
+
+func (m *progressMonitor) Finding(f *govulncheck.Finding) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Handler.Finding(f)
+}
+
+func (m *progressMonitor) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return govulncheck.Flush(m.Handler)
+}
@@ -0,0 +1,58 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+)
+
+func TestProgressMonitor(t *testing.T) {
+	var forwarded []govulncheck.ProgressKind
+	ctx := govulncheck.WithProgress(context.Background(), func(ev *govulncheck.ProgressEvent) {
+		forwarded = append(forwarded, ev.Kind)
+	})
+	h := test.NewMockHandler()
+	ctx, m := startMonitor(ctx, h)
+	defer m.stop()
+
+	load := govulncheck.StartPhase(ctx, govulncheck.PhaseLoad, 0)
+	load.Complete()
+	fetch := govulncheck.StartPhase(ctx, govulncheck.PhaseFetch, 3)
+	fetch.Advance()
+	m.report()
+
+	if len(h.ProgressMessages) != 1 {
+		t.Fatalf("got %d progress messages; want 1", len(h.ProgressMessages))
+	}
+	msg := h.ProgressMessages[0].Message
+	for _, want := range []string{"Still fetching vulnerabilities after", "(1 of 3 done)", "Using ", "of it for the heap", "loading packages took"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("progress message %q does not contain %q", msg, want)
+		}
+	}
+	if len(forwarded) != 4 {
+		t.Errorf("got %d events passed to the progress function of the context; want 4", len(forwarded))
+	}
+}
+
+func TestApproxSize(t *testing.T) {
+	for _, tc := range []struct {
+		n    uint64
+		want string
+	}{
+		{100, "100 bytes"},
+		{1536, "1.5KiB"},
+		{3 << 30, "3.0GiB"},
+	} {
+		if got := approxSize(tc.n); got != tc.want {
+			t.Errorf("approxSize(%d) = %q; want %q", tc.n, got, tc.want)
+		}
+	}
+}
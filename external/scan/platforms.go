@@ -0,0 +1,164 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// runSourcePlatforms analyzes the source code in dir once for each of
+// the platforms in cfg.platforms, as the files in a build and the
+// vulnerabilities affecting it differ between platforms, and reports
+// the merged results.
+//
+// A finding reported for several platforms is passed to handler once,
+// annotated with all of them. The SBOM lists the modules of all
+// platforms.
+func runSourcePlatforms(ctx context.Context, handler govulncheck.Handler, cfg *config, client *client.Client, dir string) error {
+	var recs []*platformRecorder
+	var memErr error
+	for _, p := range cfg.platforms {
+		msg := fmt.Sprintf("Analyzing the code for %s...", p)
+		if err := handler.Progress(&govulncheck.Progress{Message: msg}); err != nil {
+			return err
+		}
+		pcfg := *cfg
+		pcfg.platforms = nil
+		pcfg.GOOS, pcfg.GOARCH, _ = strings.Cut(p, "/")
+		pcfg.env = append(slices.Clone(cfg.env), "GOOS="+pcfg.GOOS, "GOARCH="+pcfg.GOARCH)
+		rec := &platformRecorder{Handler: handler, platform: p}
+		if err := runSource(ctx, rec, &pcfg, client, dir); err != nil {
+			if !isMemoryLimit(err) {
+				return fmt.Errorf("%s: %w", p, err)
+			}
+			memErr = fmt.Errorf("%s: %w", p, err)
+		}
+		recs = append(recs, rec)
+	}
+
+	if err := handler.SBOM(mergeSBOMs(recs)); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, r := range recs {
+		for _, e := range r.osvs {
+			if seen[e.ID] {
+				continue
+			}
+			seen[e.ID] = true
+			if err := handler.OSV(e); err != nil {
+				return err
+			}
+		}
+	}
+	findings, err := mergeFindings(recs)
+	if err != nil {
+		return err
+	}
+	for _, f := range findings {
+		if err := handler.Finding(f); err != nil {
+			return err
+		}
+	}
+	return memErr
+}
+
+// platformRecorder records the results of the analysis for platform.
+// Progress messages are passed on as they come.
+type platformRecorder struct {
+	govulncheck.Handler
+	platform string
+	sbom     *govulncheck.SBOM
+	osvs     []*osv.Entry
+	findings []*govulncheck.Finding
+}
+
+func (h *platformRecorder) SBOM(sbom *govulncheck.SBOM) error {
+	h.sbom = sbom
+	return nil
+}
+
+func (h *platformRecorder) OSV(e *osv.Entry) error {
+	h.osvs = append(h.osvs, e)
+	return nil
+}
+
+func (h *platformRecorder) Finding(f *govulncheck.Finding) error {
+	h.findings = append(h.findings, f)
+	return nil
+}
+
+// mergeSBOMs returns an SBOM listing the modules
+// and roots of the SBOMs recorded by recs.
+func mergeSBOMs(recs []*platformRecorder) *govulncheck.SBOM {
+	merged := &govulncheck.SBOM{}
+	mods := make(map[string]bool)
+	roots := make(map[string]bool)
+	libs := make(map[string]bool)
+	for _, r := range recs {
+		s := r.sbom
+		if s == nil {
+			continue
+		}
+		if merged.GoVersion == "" {
+			merged.GoVersion = s.GoVersion
+		}
+		for _, m := range s.Modules {
+			if key := m.Path + "@" + m.Version; !mods[key] {
+				mods[key] = true
+				merged.Modules = append(merged.Modules, m)
+			}
+		}
+		for _, root := range s.Roots {
+			if !roots[root] {
+				roots[root] = true
+				merged.Roots = append(merged.Roots, root)
+			}
+		}
+		for _, l := range s.CLibraries {
+			if !libs[l] {
+				libs[l] = true
+				merged.CLibraries = append(merged.CLibraries, l)
+			}
+		}
+	}
+	sort.Strings(merged.CLibraries)
+	return merged
+}
+
+// mergeFindings returns the findings recorded by recs, with the
+// findings identical across platforms merged into one annotated with
+// their platforms, in the order they were first found.
+func mergeFindings(recs []*platformRecorder) ([]*govulncheck.Finding, error) {
+	var merged []*govulncheck.Finding
+	byKey := make(map[string]*govulncheck.Finding)
+	for _, r := range recs {
+		for _, f := range r.findings {
+			b, err := json.Marshal(f)
+			if err != nil {
+				return nil, err
+			}
+			m, ok := byKey[string(b)]
+			if !ok {
+				m = f
+				byKey[string(b)] = m
+				merged = append(merged, m)
+			}
+			if !slices.Contains(m.Platforms, r.platform) {
+				m.Platforms = append(m.Platforms, r.platform)
+			}
+		}
+	}
+	return merged, nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeFindings(t *testing.T) {
+	finding := func(osv, fn string) *govulncheck.Finding {
+		return &govulncheck.Finding{OSV: osv, Trace: []*govulncheck.Frame{{Module: "m", Package: "m/p", Function: fn}}}
+	}
+	recs := []*platformRecorder{
+		{platform: "linux/amd64", findings: []*govulncheck.Finding{finding("A", "F"), finding("B", "G")}},
+		{platform: "windows/arm64", findings: []*govulncheck.Finding{finding("A", "F"), finding("A", "H")}},
+	}
+	got, err := mergeFindings(recs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*govulncheck.Finding{
+		{OSV: "A", Trace: []*govulncheck.Frame{{Module: "m", Package: "m/p", Function: "F"}}, Platforms: []string{"linux/amd64", "windows/arm64"}},
+		{OSV: "B", Trace: []*govulncheck.Frame{{Module: "m", Package: "m/p", Function: "G"}}, Platforms: []string{"linux/amd64"}},
+		{OSV: "A", Trace: []*govulncheck.Frame{{Module: "m", Package: "m/p", Function: "H"}}, Platforms: []string{"windows/arm64"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestMergeSBOMs(t *testing.T) {
+	recs := []*platformRecorder{
+		{sbom: &govulncheck.SBOM{
+			GoVersion: "go1.22.0",
+			Roots:     []string{"example.com/app"},
+			Modules:   []*govulncheck.Module{{Path: "example.com/app"}, {Path: "golang.org/x/sys", Version: "v0.1.0"}},
+		}},
+		{sbom: &govulncheck.SBOM{
+			GoVersion: "go1.22.0",
+			Roots:     []string{"example.com/app"},
+			Modules:   []*govulncheck.Module{{Path: "example.com/app"}, {Path: "golang.org/x/sys/windows", Version: "v0.2.0"}},
+		}},
+	}
+	want := &govulncheck.SBOM{
+		GoVersion: "go1.22.0",
+		Roots:     []string{"example.com/app"},
+		Modules: []*govulncheck.Module{
+			{Path: "example.com/app"},
+			{Path: "golang.org/x/sys", Version: "v0.1.0"},
+			{Path: "golang.org/x/sys/windows", Version: "v0.2.0"},
+		},
+	}
+	if diff := cmp.Diff(want, mergeSBOMs(recs)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"golang.org/x/mod/module"
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultProxy is the module proxy used when GOPROXY is not set.
+const defaultProxy = "https://proxy.golang.org"
+
+// proxyTimeout bounds each request made to the module proxy.
+const proxyTimeout = 30 * time.Second
+
+// proxyChecker passes on all messages to Handler, and checks that the
+// versions of the modules of SBOM messages are known to the module
+// proxy, reporting those that are not as warnings. A version missing
+// from the proxy, such as a forged pseudo-version or a version of a
+// typosquatted module path, may not be the code its name claims.
+type proxyChecker struct {
+	govulncheck.Handler
+	proxy   string // URL of the module proxy, or "" if there is none
+	private string // GONOPROXY patterns of the modules not to check
+	client  *http.Client
+}
+
+// newProxyChecker returns a proxyChecker of the first proxy of GOPROXY
+// in env, which leaves out the modules matching GONOPROXY, or GOPRIVATE
+// if it is not set.
+func newProxyChecker(h govulncheck.Handler, env []string) *proxyChecker {
+	private := lookupEnv(env, "GONOPROXY")
+	if private == "" {
+		private = lookupEnv(env, "GOPRIVATE")
+	}
+	return &proxyChecker{
+		Handler: h,
+		proxy:   firstProxy(lookupEnv(env, "GOPROXY")),
+		private: private,
+		client:  &http.Client{Timeout: proxyTimeout},
+	}
+}
+
+// firstProxy returns the URL of the first proxy in the GOPROXY list
+// goproxy, or "" if the list starts with "direct" or "off".
+func firstProxy(goproxy string) string {
+	if goproxy == "" {
+		return defaultProxy
+	}
+	p, _, _ := strings.Cut(goproxy, ",")
+	p, _, _ = strings.Cut(p, "|")
+	p = strings.TrimSpace(p)
+	if p == "direct" || p == "off" {
+		return ""
+	}
+	return strings.TrimSuffix(p, "/")
+}
+
+func (h *proxyChecker) SBOM(sbom *govulncheck.SBOM) error {
+	if err := h.Handler.SBOM(sbom); err != nil {
+		return err
+	}
+	if h.proxy == "" {
+		return h.Handler.Progress(&govulncheck.Progress{Message: "warning: GOPROXY names no module proxy, so the module versions cannot be checked against one"})
+	}
+	for _, w := range h.check(context.Background(), sbom.Modules) {
+		if err := h.Handler.Progress(&govulncheck.Progress{Message: w}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns the warnings for the modules whose versions the proxy
+// does not know or could not be asked about, in the order of modules.
+func (h *proxyChecker) check(ctx context.Context, modules []*govulncheck.Module) []string {
+	warnings := make([]string, len(modules))
+	var g errgroup.Group
+	g.SetLimit(10)
+	var once sync.Once
+	for i, m := range modules {
+		if !h.checkable(m) {
+			continue
+		}
+		g.Go(func() error {
+			known, err := h.known(ctx, m)
+			switch {
+			case err != nil:
+				once.Do(func() {
+					warnings[i] = fmt.Sprintf("warning: could not check the module versions against %s: %v", h.proxy, err)
+				})
+			case !known:
+				warnings[i] = fmt.Sprintf("warning: %s@%s is not known to %s; it may be a typosquatted module or a forged version", m.Path, m.Version, h.proxy)
+			}
+			return nil
+		})
+	}
+	g.Wait()
+	var ws []string
+	for _, w := range warnings {
+		if w != "" {
+			ws = append(ws, w)
+		}
+	}
+	return ws
+}
+
+// checkable reports whether m is a module with a version
+// that the proxy can be asked about.
+func (h *proxyChecker) checkable(m *govulncheck.Module) bool {
+	switch {
+	case m.Version == "", m.Version == "(devel)":
+		// Main modules and modules replaced by directories.
+		return false
+	case m.Path == "stdlib", m.Path == "toolchain":
+		return false
+	case module.MatchPrefixPatterns(h.private, m.Path):
+		return false
+	}
+	return true
+}
+
+// known reports whether the proxy has the version of m.
+func (h *proxyChecker) known(ctx context.Context, m *govulncheck.Module) (bool, error) {
+	path, err := module.EscapePath(m.Path)
+	if err != nil {
+		return false, nil
+	}
+	version, err := module.EscapeVersion(m.Version)
+	if err != nil {
+		return false, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.proxy+"/"+path+"/@v/"+version+".info", nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound, http.StatusGone:
+		return false, nil
+	}
+	return false, fmt.Errorf("%s: %s", req.URL, resp.Status)
+}
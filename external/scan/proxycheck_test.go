@@ -0,0 +1,88 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProxyChecker(t *testing.T) {
+	known := map[string]bool{
+		"/golang.org/x/text/@v/v0.3.7.info":                       true,
+		"/github.com/!burnt!sushi/toml/@v/v1.0.0.info":            true,
+		"/private.example.com/m/@v/v1.0.0.info":                   false,
+		"/golang.org/x/text/@v/v0.0.0-20990101000000-abcdef.info": false,
+	}
+	var (
+		mu        sync.Mutex
+		requested []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+		if !known[r.URL.Path] {
+			http.Error(w, "not found", http.StatusGone)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	mock := test.NewMockHandler()
+	env := []string{"GOPROXY=" + srv.URL + ",direct", "GOPRIVATE=*.example.com"}
+	h := newProxyChecker(mock, env)
+	h.client = srv.Client()
+	sbom := &govulncheck.SBOM{Modules: []*govulncheck.Module{
+		{Path: "example.com/main"},
+		{Path: "stdlib", Version: "v1.21.0"},
+		{Path: "golang.org/x/text", Version: "v0.3.7"},
+		{Path: "github.com/BurntSushi/toml", Version: "v1.0.0"},
+		{Path: "private.example.com/m", Version: "v1.0.0"},
+		{Path: "golang.org/x/text", Version: "v0.0.0-20990101000000-abcdef"},
+		{Path: "golang.org/x/texts", Version: "v0.3.7"},
+	}}
+	if err := h.SBOM(sbom); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.SBOMMessages) != 1 {
+		t.Errorf("got %d SBOM messages passed on; want 1", len(mock.SBOMMessages))
+	}
+	if len(requested) != 4 {
+		t.Errorf("got %d requests to the proxy; want 4", len(requested))
+	}
+	var got []string
+	for _, p := range mock.ProgressMessages {
+		got = append(got, p.Message)
+	}
+	want := []string{
+		"warning: golang.org/x/text@v0.0.0-20990101000000-abcdef is not known to " + srv.URL + "; it may be a typosquatted module or a forged version",
+		"warning: golang.org/x/texts@v0.3.7 is not known to " + srv.URL + "; it may be a typosquatted module or a forged version",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestFirstProxy(t *testing.T) {
+	for _, tc := range []struct {
+		goproxy, want string
+	}{
+		{"", defaultProxy},
+		{"https://goproxy.io/,direct", "https://goproxy.io"},
+		{"https://a.example.com|https://b.example.com", "https://a.example.com"},
+		{"direct", ""},
+		{"off", ""},
+	} {
+		if got := firstProxy(tc.goproxy); got != tc.want {
+			t.Errorf("firstProxy(%q) = %q; want %q", tc.goproxy, got, tc.want)
+		}
+	}
+}
@@ -9,30 +9,47 @@ import (
 	"fmt"
 	"regexp"
 
+	"github.com/StevenACoffman/invuln/external"
 	"github.com/StevenACoffman/invuln/external/client"
 	"github.com/StevenACoffman/invuln/external/govulncheck"
 	isem "github.com/StevenACoffman/invuln/external/semver"
 )
 
 // runQuery reports vulnerabilities that apply to the queries in the config.
+//
+// A query is either of the form module@version or the path of a blob
+// produced in extract mode, which stands for the modules of the binary
+// the blob was extracted from and the standard library it was built
+// with. Queries only need the vulnerability database, so with a local
+// database they work offline.
 func runQuery(ctx context.Context, handler govulncheck.Handler, cfg *config, c *client.Client) error {
-	reqs := make([]*client.ModuleRequest, len(cfg.patterns))
-	for i, query := range cfg.patterns {
+	var reqs []*client.ModuleRequest
+	for _, query := range cfg.patterns {
+		if isFile(query) {
+			blobReqs, err := blobQueries(query)
+			if err != nil {
+				return err
+			}
+			reqs = append(reqs, blobReqs...)
+			continue
+		}
 		mod, ver, err := parseModuleQuery(query)
 		if err != nil {
 			return err
 		}
-		if err := handler.Progress(queryProgressMessage(mod, ver)); err != nil {
-			return err
-		}
-		reqs[i] = &client.ModuleRequest{
+		reqs = append(reqs, &client.ModuleRequest{
 			Path: mod, Version: ver,
+		})
+	}
+	for _, req := range reqs {
+		if err := handler.Progress(queryProgressMessage(req.Path, req.Version)); err != nil {
+			return err
 		}
 	}
 
 	resps, err := c.ByModules(ctx, reqs)
 	if err != nil {
-		return err
+		return govulncheck.NewError(govulncheck.ErrorDBUnreachable, err)
 	}
 
 	ids := make(map[string]bool)
@@ -51,6 +68,31 @@ func runQuery(ctx context.Context, handler govulncheck.Handler, cfg *config, c *
 	return nil
 }
 
+// blobQueries returns the module requests for the modules recorded
+// in the blob at path, or for their replacements, which were built.
+func blobQueries(path string) ([]*client.ModuleRequest, error) {
+	bin, err := parseBlob(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if bin == nil {
+		return nil, fmt.Errorf("%s is not a valid blob", path)
+	}
+	var reqs []*client.ModuleRequest
+	for _, m := range bin.Modules {
+		if m.Replace != nil {
+			m = m.Replace
+		}
+		if m.Version != "" && m.Path != external.GoStdModulePath {
+			reqs = append(reqs, &client.ModuleRequest{Path: m.Path, Version: m.Version})
+		}
+	}
+	if v := isem.GoTagToSemver(bin.GoVersion); v != "" {
+		reqs = append(reqs, &client.ModuleRequest{Path: external.GoStdModulePath, Version: v})
+	}
+	return reqs, nil
+}
+
 func queryProgressMessage(module, version string) *govulncheck.Progress {
 	return &govulncheck.Progress{
 		Message: fmt.Sprintf("Looking up vulnerabilities in %s at %s...", module, version),
@@ -5,14 +5,20 @@
 package scan
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/StevenACoffman/invuln/external/client"
 	"github.com/StevenACoffman/invuln/external/osv"
 	"github.com/StevenACoffman/invuln/external/test"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
 	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
 )
 
 func TestRunQuery(t *testing.T) {
@@ -134,6 +140,43 @@ func TestRunQuery(t *testing.T) {
 	}
 }
 
+func TestBlobQueries(t *testing.T) {
+	bin := &vulncheck.Bin{
+		Modules: []*packages.Module{
+			{Path: "golang.org/amod", Version: "v1.1.3"},
+			{Path: "golang.org/bmod", Version: "v0.5.0", Replace: &packages.Module{Path: "example.com/bfork", Version: "v0.5.1"}},
+			{Path: "golang.org/cmod", Version: "v1.0.0", Replace: &packages.Module{Path: "../cmod"}},
+		},
+		GoVersion: "go1.22.1",
+	}
+	path := filepath.Join(t.TempDir(), "vuln.blob")
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(header{Name: extractModeID, Version: extractModeUncompressedVersion}); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(&buf).Encode(bin); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := blobQueries(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replacements are queried in place of the modules they replace,
+	// and local replacements, having no version, not at all.
+	want := []*client.ModuleRequest{
+		{Path: "golang.org/amod", Version: "v1.1.3"},
+		{Path: "example.com/bfork", Version: "v0.5.1"},
+		{Path: "stdlib", Version: "v1.22.1"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("blobQueries mismatch (-want, +got):\n%s", diff)
+	}
+}
+
 func TestParseModuleQuery(t *testing.T) {
 	for _, tc := range []struct {
 		pattern, wantMod, wantVer string
@@ -6,12 +6,17 @@ package scan
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
+	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,40 +24,91 @@ import (
 	"github.com/StevenACoffman/invuln/external/govulncheck"
 	"github.com/StevenACoffman/invuln/external/openvex"
 	"github.com/StevenACoffman/invuln/external/sarif"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
 	"golang.org/x/telemetry/counter"
+	"golang.org/x/tools/go/packages"
 )
 
 // RunGovulncheck performs main govulncheck functionality.
 // On failure, the returned error wraps an exit code error (see scan.Cmd.Wait).
 func RunGovulncheck(ctx context.Context, env []string, r io.Reader, stdout io.Writer, stderr io.Writer, args []string) error {
-	cfg := &config{env: env}
+	return RunGovulncheckWithClient(ctx, env, r, stdout, stderr, args, nil)
+}
+
+// RunGovulncheckWithClient is RunGovulncheck, except that the database
+// of c, if not nil, is used instead of those given by the -db flag.
+//
+// The failures of scans are returned as a *govulncheck.Error classifying
+// them, which is also the last message of JSON output, once started.
+func RunGovulncheckWithClient(ctx context.Context, env []string, r io.Reader, stdout io.Writer, stderr io.Writer, args []string, c *client.Client) (err error) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "db":
+			return runDB(ctx, env, stdout, stderr, args[1:])
+		case "cache":
+			return runCache(env, stdout, stderr, args[1:])
+		case "serve":
+			return runServe(ctx, env, stdout, stderr, args[1:])
+		case "client":
+			return runClient(ctx, env, stdout, stderr, args[1:])
+		}
+	}
+	return runGovulncheck(ctx, &config{env: env}, r, stdout, stderr, args, c)
+}
+
+// runGovulncheck runs the scan requested by args, as RunGovulncheckWithClient
+// does, with cfg holding the settings not given by flags, such as env.
+func runGovulncheck(ctx context.Context, cfg *config, r io.Reader, stdout io.Writer, stderr io.Writer, args []string, c *client.Client) (err error) {
+	defer func() { err = scanError(err) }()
 	if err := parseFlags(cfg, stderr, args); err != nil {
 		return err
 	}
+	if cfg.logLevel != nil {
+		ctx = govulncheck.WithLogger(ctx, slog.New(slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: cfg.logLevel})))
+	}
 
-	client, err := client.NewClient(cfg.db, nil)
-	if err != nil {
-		return fmt.Errorf("creating client: %w", err)
+	client := c
+	if client == nil {
+		client, err = newClient(ctx, cfg)
+		if err != nil {
+			return fmt.Errorf("creating client: %w", err)
+		}
+	} else {
+		// The database of c has no URL to report.
+		cfg.db = nil
 	}
 
 	prepareConfig(ctx, cfg, client)
-	var handler govulncheck.Handler
-	switch cfg.format {
-	case formatJSON:
-		handler = govulncheck.NewJSONHandler(stdout)
-	case formatSarif:
-		handler = sarif.NewHandler(stdout)
-	case formatOpenVEX:
-		handler = openvex.NewHandler(stdout)
-	default:
-		th := NewTextHandler(stdout)
-		cfg.show.Update(th)
-		handler = th
+	if cfg.handlerExec != "" {
+		in, wait, serr := startHandlerExec(ctx, cfg, stdout, stderr)
+		if serr != nil {
+			return serr
+		}
+		defer func() {
+			// A failure of the command, which may have made writing
+			// the output fail, takes precedence.
+			if werr := wait(); werr != nil {
+				err = werr
+			}
+		}()
+		stdout = in
+	}
+	if cfg.watch && !cfg.version {
+		incTelemetryFlagCounters(cfg)
+		return runWatch(ctx, cfg, client, stdout, stderr)
 	}
+	var fh govulncheck.Handler
+	if cfg.tui {
+		fh = newTriageHandler(cfg, r, stdout)
+	} else {
+		fh = newFormatHandler(ctx, cfg, stdout)
+	}
+	handler := wrapHandler(cfg, fh)
 
 	if err := handler.Config(&cfg.Config); err != nil {
 		return err
 	}
+	defer func() { reportError(fh, err) }()
 
 	if cfg.version {
 		// If the -version flag is passed, exit before doing anything else. This is different than
@@ -62,28 +118,345 @@ func RunGovulncheck(ctx context.Context, env []string, r io.Reader, stdout io.Wr
 
 	incTelemetryFlagCounters(cfg)
 
+	if cfg.ScanMode == govulncheck.ScanModeExtract {
+		return runExtract(cfg, stdout)
+	}
+	return runScan(ctx, handler, cfg, client, r)
+}
+
+// RunWithHandler runs govulncheck with the arguments args, as
+// RunGovulncheckWithClient does, except that the results are passed
+// to handler rather than written in the format the arguments request,
+// so that programs embedding govulncheck receive them as values.
+// The arguments are validated as if they requested JSON output, whose
+// messages are those passed to handler. Usage errors wrap errUsage
+// along with their message.
+//
+// The watch and extract modes, which do not report results
+// as messages, are not supported.
+func RunWithHandler(ctx context.Context, env []string, args []string, c *client.Client, handler govulncheck.Handler) error {
+	_, err := runWithHandler(ctx, &config{env: env}, args, c, handler)
+	return err
+}
+
+// A Binary is a binary scanned by RunBinariesWithHandler, read from a
+// reader rather than from a file, such as one streamed from object
+// storage. As for files, it may be an executable, a blob of extract
+// mode, or an archive of executables.
+type Binary struct {
+	// Name names the binary in findings and errors. Archives
+	// are recognized by its extension, or else by their content.
+	Name string
+
+	// R reads the Size bytes of the binary.
+	R    io.ReaderAt
+	Size int64
+}
+
+// RunBinariesWithHandler runs govulncheck in binary mode with the
+// flags args, scanning bins rather than files, and passing the results
+// to handler as RunWithHandler does. Since bins are the binaries
+// scanned, args give no patterns.
+func RunBinariesWithHandler(ctx context.Context, env []string, args []string, c *client.Client, handler govulncheck.Handler, bins []Binary) error {
+	if len(bins) == 0 {
+		return scanError(fmt.Errorf("%w: at least 1 binary must be provided", errUsage))
+	}
+	_, err := runWithHandler(ctx, &config{env: env, binaries: bins}, args, c, handler)
+	return err
+}
+
+// RunPackagesWithHandler runs govulncheck in source mode with the
+// flags args, scanning pkgs, which the caller loaded, rather than
+// loading packages, and passing the results to handler as
+// RunWithHandler does. The packages must be loaded in
+// vulncheck.PackageLoadMode, or in vulncheck.SymbolLoadMode for symbol
+// level scans. Since pkgs are the packages scanned, args give no
+// patterns, and the flags changing how packages are loaded, such as
+// -tags and -test, are not supported.
+func RunPackagesWithHandler(ctx context.Context, env []string, args []string, c *client.Client, handler govulncheck.Handler, pkgs []*packages.Package) error {
+	if len(pkgs) == 0 {
+		return scanError(fmt.Errorf("%w: at least 1 package must be provided", errUsage))
+	}
+	_, err := runWithHandler(ctx, &config{env: env, pkgs: pkgs}, args, c, handler)
+	return err
+}
+
+// SourceResult runs govulncheck in source mode with the arguments
+// args, passing the results to handler as RunWithHandler does, and
+// returns the result of the analysis, whose call stacks are those of
+// the findings. At the package level, the result has no functions,
+// and at the stdlib level, it is empty.
+//
+// Only the scans analyzing the packages once are supported, so the
+// -platforms, -verify, -cache, and -emit-graph flags are not.
+func SourceResult(ctx context.Context, env []string, args []string, c *client.Client, handler govulncheck.Handler) (*vulncheck.Result, error) {
+	cfg, err := runWithHandler(ctx, &config{env: env, keepResult: true}, args, c, handler)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.result == nil {
+		// Stdlib scans load no packages.
+		return &vulncheck.Result{}, nil
+	}
+	return cfg.result, nil
+}
+
+// runWithHandler implements RunWithHandler, SourceResult, and
+// RunBinariesWithHandler, whose settings are those of cfg, and
+// returns the configuration of the scan. Failures are returned
+// as a *govulncheck.Error, which is also reported to handler
+// once passed the configuration.
+func runWithHandler(ctx context.Context, cfg *config, args []string, c *client.Client, handler govulncheck.Handler) (_ *config, err error) {
+	defer func() { err = scanError(err) }()
+	var stderr strings.Builder
+	if err := parseFlags(cfg, &stderr, append([]string{"-format", "json"}, args...)); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" && err == errUsage {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	if cfg.watch || cfg.ScanMode == govulncheck.ScanModeExtract {
+		return nil, fmt.Errorf("%w: the watch and extract modes are not supported", errUsage)
+	}
+	if cfg.handlerExec != "" {
+		return nil, fmt.Errorf("%w: the -handler-exec flag is not supported", errUsage)
+	}
+	if cfg.logLevel != nil {
+		return nil, fmt.Errorf("%w: the -log flag is not supported; log with the logger of the context instead", errUsage)
+	}
+	if cfg.binaries != nil && cfg.ScanMode != govulncheck.ScanModeBinary {
+		return nil, fmt.Errorf("%w: binaries are only read in binary mode", errUsage)
+	}
+	if cfg.pkgs != nil {
+		if err := checkLoadedPackages(cfg); err != nil {
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+	}
+	if cfg.keepResult {
+		switch {
+		case cfg.ScanMode != govulncheck.ScanModeSource:
+			return nil, fmt.Errorf("%w: results are only returned in source mode", errUsage)
+		case len(cfg.platforms) > 0 || cfg.verify != "" || cfg.cache || cfg.emitGraph != "":
+			return nil, fmt.Errorf("%w: the -platforms, -verify, -cache, and -emit-graph flags are not supported when returning results", errUsage)
+		}
+	}
+	client := c
+	if client == nil {
+		client, err = newClient(ctx, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("creating client: %w", err)
+		}
+	} else {
+		// The database of c has no URL to report.
+		cfg.db = nil
+	}
+	prepareConfig(ctx, cfg, client)
+	wrapped := wrapHandler(cfg, handler)
+	if err := wrapped.Config(&cfg.Config); err != nil {
+		return nil, err
+	}
+	defer func() { reportError(handler, err) }()
+	return cfg, runScan(ctx, wrapped, cfg, client, nil)
+}
+
+// reportError reports the failure err of a scan to handler, as by
+// govulncheck.ReportError. Errors reporting it are ignored, as the
+// scan failed anyway.
+func reportError(handler govulncheck.Handler, err error) {
+	if e, ok := scanError(err).(*govulncheck.Error); ok {
+		govulncheck.ReportError(handler, e)
+	}
+}
+
+// runScan runs the scan of cfg, passing the results to handler,
+// which is then flushed. In convert mode, the results are read
+// from r.
+func runScan(ctx context.Context, handler govulncheck.Handler, cfg *config, client *client.Client, r io.Reader) error {
+	if cfg.MaxMemory > 0 {
+		// This is the only place the soft memory limit is set, so
+		// that the call graph guard of -max-memory, which checks
+		// the live heap against the same limit, does not race it.
+		defer debug.SetMemoryLimit(debug.SetMemoryLimit(cfg.MaxMemory))
+	}
+	if govulncheck.RecordsMetrics(ctx) {
+		handler = govulncheck.CountFindings(govulncheck.MetricsOf(ctx), handler)
+	}
+	var monitor *progressMonitor
+	if slices.Contains(cfg.show, "verbose") {
+		ctx, monitor = startMonitor(ctx, handler)
+		handler = monitor
+	}
+	var err error
 	switch cfg.ScanMode {
 	case govulncheck.ScanModeSource:
-		dir := filepath.FromSlash(cfg.dir)
-		err = runSource(ctx, handler, cfg, client, dir)
+		err = runSourceScanCached(ctx, handler, cfg, client, sourceDir(cfg))
 	case govulncheck.ScanModeBinary:
 		err = runBinary(ctx, handler, cfg, client)
-	case govulncheck.ScanModeExtract:
-		return runExtract(cfg, stdout)
+	case govulncheck.ScanModeSBOM:
+		err = runSBOM(ctx, handler, cfg, client)
 	case govulncheck.ScanModeQuery:
 		err = runQuery(ctx, handler, cfg, client)
 	case govulncheck.ScanModeConvert:
 		err = govulncheck.HandleJSON(r, handler)
 	}
-	if err != nil {
+	if monitor != nil {
+		monitor.stop()
+	}
+	// The results of scans exceeding -max-memory are
+	// reported, though the scans fail.
+	if err != nil && !isMemoryLimit(err) {
 		return err
 	}
-	return Flush(handler)
+	if ferr := Flush(handler); ferr != nil && (err == nil || !errors.Is(ferr, errVulnerabilitiesFound)) {
+		return ferr
+	}
+	return err
+}
+
+// sourceDir returns the directory of the source code scanned by cfg,
+// that of the -C flag, interpreted relative to cfg.wd if set.
+func sourceDir(cfg *config) string {
+	dir := filepath.FromSlash(cfg.dir)
+	if cfg.wd != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(cfg.wd, dir)
+	}
+	return dir
+}
+
+// newClient returns the client of the databases of cfg,
+// which enriches their entries as requested by cfg.
+func newClient(ctx context.Context, cfg *config) (*client.Client, error) {
+	c, err := client.NewMergedClientContext(ctx, cfg.db, clientOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.extraEntries) > 0 {
+		if c, err = client.WithEntries(c, cfg.extraEntries); err != nil {
+			return nil, err
+		}
+	}
+	var enrichers []client.Enricher
+	if cfg.ghsa != "" {
+		g, err := client.NewGHSAEnricher(cfg.ghsa, lookupEnv(cfg.env, "GITHUB_TOKEN"), nil)
+		if err != nil {
+			return nil, fmt.Errorf("-ghsa: %w", err)
+		}
+		enrichers = append(enrichers, g)
+	}
+	if cfg.nvd != "" {
+		opts := &client.Options{Header: make(http.Header)}
+		if key := lookupEnv(cfg.env, "NVD_API_KEY"); key != "" {
+			opts.Header.Set("apiKey", key)
+		}
+		if !cfg.noCache && cfg.cacheTTL > 0 {
+			if root, err := cacheRoot(cfg.env); err == nil {
+				opts.CacheDir = filepath.Join(root, "nvd")
+				opts.CacheTTL = cfg.cacheTTL
+			}
+		}
+		enrichers = append(enrichers, client.NewNVDEnricher(cfg.nvd, opts))
+	}
+	if cfg.ratings != nil {
+		// The ratings of the organization override all others.
+		enrichers = append(enrichers, client.NewRatingEnricher(cfg.ratings, "ratings:"+cfg.severityMap))
+	}
+	return client.Enrich(c, enrichers...), nil
+}
+
+// clientOptions returns the options of the database client of cfg.
+// The credentials are read from the environment rather than flags,
+// so that they do not show in process listings.
+func clientOptions(cfg *config) *client.Options {
+	opts := &client.Options{
+		Header:      make(http.Header),
+		BearerToken: lookupEnv(cfg.env, "GOVULNCHECK_DB_TOKEN"),
+		Username:    lookupEnv(cfg.env, "GOVULNCHECK_DB_USER"),
+		Password:    lookupEnv(cfg.env, "GOVULNCHECK_DB_PASSWORD"),
+		CertFile:    cfg.dbCert,
+		KeyFile:     cfg.dbKey,
+		Retries:     cfg.dbRetries,
+		Timeout:     cfg.dbTimeout,
+		Concurrency: cfg.dbConcurrency,
+		RateLimit:   cfg.dbRateLimit,
+		PublicKeys:  cfg.publicKeys,
+	}
+	if !cfg.noCache && cfg.cacheTTL > 0 {
+		if root, err := cacheRoot(cfg.env); err == nil {
+			opts.CacheDir = filepath.Join(root, "db")
+			opts.CacheTTL = cfg.cacheTTL
+		}
+	}
+	for _, h := range cfg.dbHeader {
+		name, value, _ := strings.Cut(h, ":")
+		opts.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return opts
+}
+
+// lookupEnv returns the value of the variable key in env,
+// which is the current environment if env is nil.
+func lookupEnv(env []string, key string) string {
+	if env == nil {
+		return os.Getenv(key)
+	}
+	var value string
+	for _, e := range env {
+		if v, ok := strings.CutPrefix(e, key+"="); ok {
+			value = v
+		}
+	}
+	return value
+}
+
+// newHandler returns a handler writing to stdout in the format
+// requested by cfg.
+func newHandler(ctx context.Context, cfg *config, stdout io.Writer) govulncheck.Handler {
+	return wrapHandler(cfg, newFormatHandler(ctx, cfg, stdout))
+}
+
+// wrapHandler returns h wrapped in the handlers which
+// write or filter the messages as requested by cfg.
+func wrapHandler(cfg *config, h govulncheck.Handler) govulncheck.Handler {
+	fh := h
+	if cfg.emitOSVDir != "" {
+		h = newOSVWriter(h, cfg.emitOSVDir)
+	}
+	if cfg.proxyCheck {
+		h = newProxyChecker(h, cfg.env)
+	}
+	if cfg.MinConfidence != "" {
+		h = &confidenceFilter{Handler: h, min: cfg.MinConfidence}
+	}
+	if cfg.MaxFindings > 0 {
+		th, _ := fh.(*TextHandler)
+		h = &findingLimit{Handler: h, max: cfg.MaxFindings, text: th}
+	}
+	return h
+}
+
+func newFormatHandler(ctx context.Context, cfg *config, stdout io.Writer) govulncheck.Handler {
+	switch cfg.format {
+	case formatJSON:
+		return govulncheck.NewJSONHandler(stdout)
+	case formatSarif:
+		return sarif.NewHandler(stdout)
+	case formatOpenVEX:
+		return openvex.NewHandler(stdout)
+	default:
+		th := NewTextHandler(stdout)
+		cfg.show.Update(th)
+		if slices.Contains(cfg.show, "remediation") {
+			th.remediate = func(findings []*govulncheck.Finding) ([]ModuleUpgrade, error) {
+				return remediationPlan(ctx, cfg, findings)
+			}
+		}
+		return th
+	}
 }
 
 func prepareConfig(ctx context.Context, cfg *config, client *client.Client) {
 	cfg.ProtocolVersion = govulncheck.ProtocolVersion
-	cfg.DB = cfg.db
+	cfg.DB = strings.Join(cfg.db, ", ")
 	if cfg.ScanMode == govulncheck.ScanModeSource && cfg.GoVersion == "" {
 		const goverPrefix = "GOVERSION="
 		for _, env := range cfg.env {
@@ -92,11 +465,14 @@ func prepareConfig(ctx context.Context, cfg *config, client *client.Client) {
 			}
 		}
 		if cfg.GoVersion == "" {
-			if out, err := exec.Command("go", "env", "GOVERSION").Output(); err == nil {
+			if out, err := exec.CommandContext(ctx, "go", "env", "GOVERSION").Output(); err == nil {
 				cfg.GoVersion = strings.TrimSpace(string(out))
 			}
 		}
 	}
+	if cfg.ScanMode == govulncheck.ScanModeBinary && len(cfg.patterns) == 1 {
+		cfg.GOOS, cfg.GOARCH = binaryPlatform(cfg.patterns[0])
+	}
 	if bi, ok := debug.ReadBuildInfo(); ok {
 		scannerVersion(cfg, bi)
 	}
@@ -158,8 +534,5 @@ func incTelemetryFlagCounters(cfg *config) {
 }
 
 func Flush(h govulncheck.Handler) error {
-	if th, ok := h.(interface{ Flush() error }); ok {
-		return th.Flush()
-	}
-	return nil
+	return govulncheck.Flush(h)
 }
@@ -5,11 +5,26 @@
 package scan
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"runtime/debug"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/test"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"golang.org/x/tools/go/packages"
 )
 
 func TestGovulncheckVersion(t *testing.T) {
@@ -56,3 +71,576 @@ func TestRunGovulncheck_ModuleModeNoPatterns(t *testing.T) {
 		t.Errorf("unexpected 'no package patterns' error in module mode: %v", err)
 	}
 }
+
+func TestClientOptions(t *testing.T) {
+	cfg := &config{
+		env: []string{
+			"GOVULNCHECK_DB_TOKEN=old",
+			"GOVULNCHECK_DB_USER=user",
+			"GOVULNCHECK_DB_TOKEN=secret",
+		},
+		dbHeader:  []string{"X-Proxy: a", "X-Proxy:b", "X-Team: security: go"},
+		dbCert:    "cert.pem",
+		dbKey:     "key.pem",
+		dbRetries: 5,
+		dbTimeout: time.Second,
+	}
+	opts := clientOptions(cfg)
+	if opts.BearerToken != "secret" || opts.Username != "user" || opts.Password != "" {
+		t.Errorf("got token %q, user %q, password %q; want secret, user, and none", opts.BearerToken, opts.Username, opts.Password)
+	}
+	if got := opts.Header.Values("X-Proxy"); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("got X-Proxy headers %q; want [a b]", got)
+	}
+	if got := opts.Header.Get("X-Team"); got != "security: go" {
+		t.Errorf("got X-Team header %q; want %q", got, "security: go")
+	}
+	if opts.CertFile != "cert.pem" || opts.KeyFile != "key.pem" {
+		t.Errorf("got certificate %s and key %s; want cert.pem and key.pem", opts.CertFile, opts.KeyFile)
+	}
+	if opts.Retries != 5 || opts.Timeout != time.Second {
+		t.Errorf("got %d retries and timeout %s; want 5 and 1s", opts.Retries, opts.Timeout)
+	}
+
+	cfg = &config{env: []string{"GOVULNDB_CACHE=/tmp/govulndb"}, cacheTTL: time.Minute}
+	opts = clientOptions(cfg)
+	if want := filepath.Join("/tmp/govulndb", "db"); opts.CacheDir != want || opts.CacheTTL != time.Minute {
+		t.Errorf("got cache %s for %s; want %s for 1m", opts.CacheDir, opts.CacheTTL, want)
+	}
+	cfg.noCache = true
+	if opts = clientOptions(cfg); opts.CacheDir != "" {
+		t.Errorf("got cache %s with -no-cache; want none", opts.CacheDir)
+	}
+}
+
+func TestRunGovulncheck_CacheClean(t *testing.T) {
+	clean := func(dir string) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		env := []string{"GOVULNDB_CACHE=" + dir}
+		if err := RunGovulncheck(context.Background(), env, nil, &stdout, &stderr, []string{"cache", "clean"}); err != nil {
+			t.Fatalf("%v: %s", err, stderr.String())
+		}
+	}
+	dir := filepath.Join(t.TempDir(), "cache")
+	for _, name := range []string{"db", "scans"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0o777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clean(dir)
+	if _, err := os.Stat(dir); err == nil {
+		t.Errorf("%s was not removed", dir)
+	}
+
+	// The other files of a shared directory are left alone.
+	if err := os.MkdirAll(filepath.Join(dir, "db"), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	other := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(other, nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	clean(dir)
+	if _, err := os.Stat(filepath.Join(dir, "db")); err == nil {
+		t.Error("db cache was not removed")
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("other file removed: %v", err)
+	}
+}
+
+func TestRunGovulncheck_DBDownload(t *testing.T) {
+	db, err := filepath.Abs(filepath.Join("..", "..", "cmd", "govulncheck", "testdata", "common", "vulndb-v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "vulndb.zip")
+	ctx := context.Background()
+	var stdout, stderr bytes.Buffer
+	args := []string{"db", "download", "-db", "file://" + filepath.ToSlash(db), "-o", out}
+	if err := RunGovulncheck(ctx, nil, nil, &stdout, &stderr, args); err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	if _, err := client.NewClient("file://"+filepath.ToSlash(out), nil); err != nil {
+		t.Errorf("reading the snapshot: %v", err)
+	}
+	matches, err := filepath.Glob(out + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+
+	stderr.Reset()
+	if err := RunGovulncheck(ctx, nil, nil, &stdout, &stderr, []string{"db", "download"}); err != errUsage {
+		t.Errorf("got error %v without -o; want %v", err, errUsage)
+	}
+	if want := "db download requires the -o flag"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("got %q; want it to contain %q", stderr.String(), want)
+	}
+}
+
+func TestRunGovulncheck_DBMirror(t *testing.T) {
+	db, err := filepath.Abs(filepath.Join("..", "..", "cmd", "govulncheck", "testdata", "common", "vulndb-v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := t.TempDir()
+	ctx := context.Background()
+	var stdout, stderr bytes.Buffer
+	args := []string{"db", "mirror", "-db", "file://" + filepath.ToSlash(db), "-dest", dest}
+	if err := RunGovulncheck(ctx, nil, nil, &stdout, &stderr, args); err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	if _, err := client.NewClient("file://"+filepath.ToSlash(dest), nil); err != nil {
+		t.Errorf("reading the mirror: %v", err)
+	}
+
+	stderr.Reset()
+	if err := RunGovulncheck(ctx, nil, nil, &stdout, &stderr, []string{"db", "mirror"}); err != errUsage {
+		t.Errorf("got error %v without -dest; want %v", err, errUsage)
+	}
+	if want := "db mirror requires the -dest flag"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("got %q; want it to contain %q", stderr.String(), want)
+	}
+}
+
+func TestRunGovulncheckWithClient(t *testing.T) {
+	c, err := client.NewInMemoryClient([]*osv.Entry{{
+		ID: "GO-0000-0001",
+		Affected: []osv.Affected{{
+			Module: osv.Module{Path: "example.com/vuln"},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{{Introduced: "0"}, {Fixed: "1.2.0"}}}},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	args := []string{"-mode", "query", "-format", "json", "example.com/vuln@v1.1.0"}
+	if err := RunGovulncheckWithClient(context.Background(), nil, nil, &stdout, &stderr, args, c); err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"id": "GO-0000-0001"`) {
+		t.Errorf("synthetic entry not reported:\n%s", stdout.String())
+	}
+	if strings.Contains(stdout.String(), "vuln.go.dev") {
+		t.Errorf("default database reported instead of the client's:\n%s", stdout.String())
+	}
+}
+
+func TestRunGovulncheck_HandlerExec(t *testing.T) {
+	for _, name := range []string{"cat", "false"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skip(err)
+		}
+	}
+	c, err := client.NewInMemoryClient([]*osv.Entry{{
+		ID: "GO-0000-0001",
+		Affected: []osv.Affected{{
+			Module: osv.Module{Path: "example.com/vuln"},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{{Introduced: "0"}, {Fixed: "1.2.0"}}}},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	var stdout, stderr bytes.Buffer
+	args := []string{"-mode", "query", "-handler-exec", "cat", "example.com/vuln@v1.1.0"}
+	if err := RunGovulncheckWithClient(ctx, nil, nil, &stdout, &stderr, args, c); err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	// cat writes the JSON output it is passed.
+	if !strings.Contains(stdout.String(), `"id": "GO-0000-0001"`) {
+		t.Errorf("entry not passed to the handler:\n%s", stdout.String())
+	}
+
+	args = []string{"-mode", "query", "-handler-exec", "false", "example.com/vuln@v1.1.0"}
+	err = RunGovulncheckWithClient(ctx, nil, nil, &stdout, &stderr, args, c)
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Errorf("got error %v for a failing handler; want its exit status", err)
+	}
+}
+
+func TestRunGovulncheck_ErrorCodes(t *testing.T) {
+	c, err := client.NewInMemoryClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	notBinary := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notBinary, []byte("not a binary"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, test := range []struct {
+		args []string
+		want govulncheck.ErrorCode
+	}{
+		{[]string{"-mode", "bogus"}, govulncheck.ErrorUsage},
+		{[]string{"-C", dir, "./..."}, govulncheck.ErrorNoGoMod},
+		{[]string{"-mode", "binary", notBinary}, govulncheck.ErrorUnsupportedBinary},
+		{[]string{"-mode", "binary", "-json", notBinary}, govulncheck.ErrorUnsupportedBinary},
+	} {
+		var stdout, stderr bytes.Buffer
+		err := RunGovulncheckWithClient(ctx, nil, nil, &stdout, &stderr, test.args, c)
+		var e *govulncheck.Error
+		if !errors.As(err, &e) || e.Code != test.want {
+			t.Errorf("%q: got error %v; want one of code %s", test.args, err, test.want)
+			continue
+		}
+		if !strings.Contains(strings.Join(test.args, " "), "-json") {
+			continue
+		}
+		// The error is the last message of the JSON output.
+		var last govulncheck.Message
+		for msg, err := range govulncheck.NewReader(&stdout).All() {
+			if err != nil {
+				t.Fatal(err)
+			}
+			last = msg
+		}
+		if last.Error == nil || last.Error.Code != test.want || last.Error.Message != err.Error() {
+			t.Errorf("%q: got last message %+v; want the error %v", test.args, last, err)
+		}
+	}
+
+	// The usage errors of the library keep wrapping errUsage.
+	err = RunWithHandler(ctx, nil, []string{"-mode", "bogus"}, c, test.NewMockHandler())
+	var e *govulncheck.Error
+	if !errors.As(err, &e) || e.Code != govulncheck.ErrorUsage || !errors.Is(err, errUsage) {
+		t.Errorf("got error %v from RunWithHandler; want a usage error", err)
+	}
+}
+
+func TestRunWithHandler(t *testing.T) {
+	c, err := client.NewInMemoryClient([]*osv.Entry{{
+		ID: "GO-0000-0001",
+		Affected: []osv.Affected{{
+			Module: osv.Module{Path: "example.com/vuln"},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{{Introduced: "0"}, {Fixed: "1.2.0"}}}},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	h := test.NewMockHandler()
+	if err := RunWithHandler(ctx, nil, []string{"-mode", "query", "example.com/vuln@v1.1.0"}, c, h); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.ConfigMessages) != 1 || h.ConfigMessages[0].ScanMode != govulncheck.ScanModeQuery {
+		t.Errorf("got config messages %+v; want one of query mode", h.ConfigMessages)
+	}
+	if len(h.OSVMessages) != 1 || h.OSVMessages[0].ID != "GO-0000-0001" {
+		t.Errorf("got OSV messages %+v; want GO-0000-0001", h.OSVMessages)
+	}
+
+	err = RunWithHandler(ctx, nil, []string{"-mode", "query", "-tags", "foo", "example.com/vuln@v1.1.0"}, c, test.NewMockHandler())
+	if !errors.Is(err, errUsage) || !strings.Contains(err.Error(), "the -tags flag is not supported in query mode") {
+		t.Errorf("got error %v; want a usage error naming -tags", err)
+	}
+	err = RunWithHandler(ctx, nil, []string{"-mode", "extract", "bin"}, c, test.NewMockHandler())
+	if !errors.Is(err, errUsage) {
+		t.Errorf("got error %v in extract mode; want a usage error", err)
+	}
+
+	_, err = SourceResult(ctx, nil, []string{"-mode", "binary", "bin"}, c, test.NewMockHandler())
+	if !errors.Is(err, errUsage) {
+		t.Errorf("got error %v returning the result of a binary; want a usage error", err)
+	}
+	_, err = SourceResult(ctx, nil, []string{"-cache", "./..."}, c, test.NewMockHandler())
+	if !errors.Is(err, errUsage) {
+		t.Errorf("got error %v returning the result of a cached scan; want a usage error", err)
+	}
+}
+
+func TestRunPackagesWithHandler(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip(err)
+	}
+	c, err := client.NewInMemoryClient([]*osv.Entry{{
+		ID: "GO-0000-0001",
+		Affected: []osv.Affected{{
+			Module: osv.Module{Path: osv.GoStdModulePath},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{{Introduced: "0"}}}},
+			EcosystemSpecific: osv.EcosystemSpecific{Packages: []osv.Package{{
+				Path:    "archive/zip",
+				Symbols: []string{"OpenReader"},
+			}}},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/m\n\ngo 1.18\n",
+		"main.go": "package main\n\nimport \"archive/zip\"\n\nfunc main() { zip.OpenReader(\"f.zip\") }\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func(mode packages.LoadMode) []*packages.Package {
+		pkgs, err := packages.Load(&packages.Config{Mode: mode, Dir: dir}, "./...")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkgs
+	}
+	ctx := context.Background()
+	env := append(os.Environ(), "GOVERSION=go1.18")
+
+	pkgs := load(vulncheck.SymbolLoadMode)
+	h := test.NewMockHandler()
+	if err := RunPackagesWithHandler(ctx, env, nil, c, h, pkgs); err != nil {
+		t.Fatal(err)
+	}
+	var called bool
+	for _, f := range h.FindingMessages {
+		if f.Trace[0].Function == "OpenReader" && len(f.Trace) > 1 {
+			called = true
+		}
+	}
+	if !called {
+		t.Errorf("got findings %+v; want the call of zip.OpenReader", h.FindingMessages)
+	}
+
+	for _, test := range []struct {
+		name string
+		args []string
+		pkgs []*packages.Package
+	}{
+		{"patterns", []string{"./..."}, pkgs},
+		{"tags", []string{"-tags", "foo"}, pkgs},
+		{"binary", []string{"-mode", "binary"}, pkgs},
+		{"no types", nil, load(vulncheck.PackageLoadMode)},
+	} {
+		err := RunPackagesWithHandler(ctx, env, test.args, c, h, test.pkgs)
+		if !errors.Is(err, errUsage) {
+			t.Errorf("%s: got error %v; want a usage error", test.name, err)
+		}
+	}
+	// Package level scans need no types.
+	if err := RunPackagesWithHandler(ctx, env, []string{"-scan", "package"}, c, h, load(vulncheck.PackageLoadMode)); err != nil {
+		t.Errorf("package level scan: %v", err)
+	}
+}
+
+func TestRunGovulncheck_NoVulnerableModules(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/m\n\ngo 1.18\n",
+		"main.go": "package main\n\nimport \"archive/zip\"\n\nfunc main() { zip.OpenReader(\"f.zip\") }\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := append(os.Environ(), "GOVERSION=go1.18")
+	for _, tc := range []struct {
+		name   string
+		module string
+		skip   bool
+	}{
+		{"affected", osv.GoStdModulePath, false},
+		{"unaffected", "example.com/vuln", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := client.NewInMemoryClient([]*osv.Entry{{
+				ID: "GO-0000-0001",
+				Affected: []osv.Affected{{
+					Module: osv.Module{Path: tc.module},
+					Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{{Introduced: "0"}}}},
+					EcosystemSpecific: osv.EcosystemSpecific{Packages: []osv.Package{{
+						Path:    "archive/zip",
+						Symbols: []string{"OpenReader"},
+					}}},
+				}},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var log bytes.Buffer
+			ctx := govulncheck.WithLogger(context.Background(), slog.New(slog.NewTextHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug})))
+			h := test.NewMockHandler()
+			if err := RunWithHandler(ctx, env, []string{"-C", dir, "./..."}, c, h); err != nil {
+				t.Fatal(err)
+			}
+			if skipped := strings.Contains(log.String(), "skipped the symbol level analysis"); skipped != tc.skip {
+				t.Errorf("skipped the symbol level analysis: %t; want %t\n%s", skipped, tc.skip, &log)
+			}
+			if got := len(h.FindingMessages) > 0; got == tc.skip {
+				t.Errorf("got findings %+v", h.FindingMessages)
+			}
+			if len(h.SBOMMessages) != 1 {
+				t.Errorf("got %d SBOM messages; want 1", len(h.SBOMMessages))
+			}
+		})
+	}
+}
+
+func TestRunGovulncheck_DBServe(t *testing.T) {
+	db, err := filepath.Abs(filepath.Join("..", "..", "cmd", "govulncheck", "testdata", "common", "vulndb-v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw := io.Pipe()
+	var stderr bytes.Buffer
+	done := make(chan error)
+	go func() {
+		done <- RunGovulncheck(ctx, nil, nil, pw, &stderr, []string{"db", "serve", "-addr", "127.0.0.1:0", "-dir", db})
+		pw.Close()
+	}()
+	line, err := bufio.NewReader(pr).ReadString('\n')
+	if err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	_, url, ok := strings.Cut(strings.TrimSpace(line), " on ")
+	if !ok {
+		t.Fatalf("got %q, want the address served on", line)
+	}
+	c, err := client.NewClient(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.LastModifiedTime(ctx); err != nil {
+		t.Error(err)
+	}
+	cancel()
+	go io.Copy(io.Discard, pr)
+	if err := <-done; err != nil {
+		t.Errorf("serving: %v", err)
+	}
+
+	stderr.Reset()
+	if err := RunGovulncheck(context.Background(), nil, nil, io.Discard, &stderr, []string{"db", "serve"}); err != errUsage {
+		t.Errorf("got error %v without -dir; want %v", err, errUsage)
+	}
+	if want := "db serve requires the -dir flag"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("got %q; want it to contain %q", stderr.String(), want)
+	}
+}
+
+func TestRunGovulncheck_DBConvert(t *testing.T) {
+	src := filepath.Join("..", "client", "testdata", "vulndb-legacy")
+	dest := filepath.Join(t.TempDir(), "v1")
+	var stdout, stderr bytes.Buffer
+	if err := RunGovulncheck(context.Background(), nil, nil, &stdout, &stderr, []string{"db", "convert", "-src", src, "-dest", dest}); err != nil {
+		t.Fatalf("%v: %s", err, stderr.String())
+	}
+	if want := "Converted 9 entries from " + src + " to " + dest + "\n"; stdout.String() != want {
+		t.Errorf("got %q; want %q", stdout.String(), want)
+	}
+	if _, err := client.NewClient("file://"+filepath.ToSlash(dest), nil); err != nil {
+		t.Error(err)
+	}
+
+	stderr.Reset()
+	if err := RunGovulncheck(context.Background(), nil, nil, io.Discard, &stderr, []string{"db", "convert", "-src", src}); err != errUsage {
+		t.Errorf("got error %v without -dest; want %v", err, errUsage)
+	}
+	if want := "db convert requires the -dest flag"; !strings.Contains(stderr.String(), want) {
+		t.Errorf("got %q; want it to contain %q", stderr.String(), want)
+	}
+}
+
+func TestRunGovulncheck_DBDiff(t *testing.T) {
+	testdata, err := filepath.Abs(filepath.Join("..", "..", "cmd", "govulncheck", "testdata", "common"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := "file://" + filepath.ToSlash(filepath.Join(testdata, "vulndb-v1"))
+	ctx := context.Background()
+	for _, tc := range []struct {
+		args []string
+		want []string
+		skip []string
+	}{
+		{
+			args: []string{"-from", "2022-01-01"},
+			want: []string{"modified  GO-2021-0054", "added     GO-2022-0969  2023-04-03  golang.org/x/net"},
+		},
+		{
+			// The vuln module does not depend on golang.org/x/net.
+			args: []string{"-from", "2021-01-01T00:00:00Z", "-C", filepath.Join(testdata, "modules", "vuln"), "."},
+			want: []string{"added     GO-2021-0054"},
+			skip: []string{"GO-2022-0969"},
+		},
+		{
+			args: []string{"-from", "2030-01-01"},
+			want: []string{"No advisories added or modified since 2030-01-01T00:00:00Z."},
+		},
+	} {
+		var stdout, stderr bytes.Buffer
+		args := append([]string{"db", "diff", "-db", db}, tc.args...)
+		if err := RunGovulncheck(ctx, nil, nil, &stdout, &stderr, args); err != nil {
+			t.Fatalf("%q: %v: %s", tc.args, err, stderr.String())
+		}
+		for _, w := range tc.want {
+			if !strings.Contains(stdout.String(), w) {
+				t.Errorf("%q: output does not contain %q:\n%s", tc.args, w, stdout.String())
+			}
+		}
+		for _, s := range tc.skip {
+			if strings.Contains(stdout.String(), s) {
+				t.Errorf("%q: output unexpectedly contains %q:\n%s", tc.args, s, stdout.String())
+			}
+		}
+	}
+
+	var stderr bytes.Buffer
+	if err := RunGovulncheck(ctx, nil, nil, io.Discard, &stderr, []string{"db", "diff", "-from", "yesterday"}); err != errUsage {
+		t.Errorf("got error %v with an invalid -from; want %v", err, errUsage)
+	}
+	if want := `invalid -from "yesterday"`; !strings.Contains(stderr.String(), want) {
+		t.Errorf("got %q; want it to contain %q", stderr.String(), want)
+	}
+}
+
+// limitRecorder records the soft memory limit of the
+// process when passed OSV entries.
+type limitRecorder struct {
+	*test.MockHandler
+	limit int64
+}
+
+func (h *limitRecorder) OSV(e *osv.Entry) error {
+	h.limit = debug.SetMemoryLimit(-1)
+	return h.MockHandler.OSV(e)
+}
+
+func TestMaxMemorySoftLimit(t *testing.T) {
+	c, err := client.NewInMemoryClient([]*osv.Entry{{
+		ID: "GO-0000-0001",
+		Affected: []osv.Affected{{
+			Module: osv.Module{Path: "example.com/vuln"},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{{Introduced: "0"}, {Fixed: "1.2.0"}}}},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := debug.SetMemoryLimit(-1)
+	h := &limitRecorder{MockHandler: test.NewMockHandler()}
+	if err := RunWithHandler(context.Background(), nil, []string{"-mode", "query", "-max-memory", "1GiB", "example.com/vuln@v1.1.0"}, c, h); err != nil {
+		t.Fatal(err)
+	}
+	if h.limit != 1<<30 {
+		t.Errorf("got soft memory limit %d during the scan; want %d", h.limit, 1<<30)
+	}
+	if after := debug.SetMemoryLimit(-1); after != before {
+		t.Errorf("got soft memory limit %d after the scan; want %d restored", after, before)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/derrors"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/sbom"
+	"github.com/StevenACoffman/invuln/external/semver"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"golang.org/x/tools/go/packages"
+)
+
+// runSBOM detects presence of vulnerable modules and packages
+// listed in a CycloneDX or SPDX SBOM.
+func runSBOM(ctx context.Context, handler govulncheck.Handler, cfg *config, client *client.Client) (err error) {
+	defer derrors.Wrap(&err, "govulncheck")
+
+	f, err := os.Open(cfg.patterns[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	comps, err := sbom.Parse(f)
+	if err != nil {
+		return err
+	}
+
+	p := &govulncheck.Progress{Message: sbomProgressMessage}
+	if err := handler.Progress(p); err != nil {
+		return err
+	}
+	return vulncheck.SBOM(ctx, handler, inventory(comps), &cfg.Config, client)
+}
+
+// inventory converts SBOM components to a vulncheck inventory.
+// Duplicate modules and packages are dropped and the standard
+// library component, if any, determines the Go version.
+func inventory(comps []sbom.Component) *vulncheck.Inventory {
+	inv := &vulncheck.Inventory{}
+	seenMods := make(map[string]bool)
+	seenPkgs := make(map[string]bool)
+	for _, c := range comps {
+		if c.Module == external.GoStdModulePath {
+			if inv.GoVersion == "" && c.Version != "" {
+				inv.GoVersion = goVersion(c.Version)
+			}
+		} else {
+			version := moduleVersion(c.Version)
+			if key := c.Module + "@" + version; !seenMods[key] {
+				seenMods[key] = true
+				inv.Modules = append(inv.Modules, &packages.Module{Path: c.Module, Version: version})
+			}
+		}
+		if c.Package != "" && !seenPkgs[c.Package] {
+			seenPkgs[c.Package] = true
+			inv.Packages = append(inv.Packages, c.Package)
+		}
+	}
+	return inv
+}
+
+// moduleVersion returns v as a canonical module version.
+// SBOM generators do not always include the "v" prefix.
+func moduleVersion(v string) string {
+	if v == "" || strings.HasPrefix(v, "v") {
+		return v
+	}
+	return "v" + v
+}
+
+// goVersion returns v, the version of the standard library
+// as listed in an SBOM, as a Go toolchain version such as go1.22.1.
+func goVersion(v string) string {
+	if strings.HasPrefix(v, "go") {
+		return v
+	}
+	return semver.SemverToGoTag(moduleVersion(v))
+}
@@ -0,0 +1,32 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/sbom"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestInventory(t *testing.T) {
+	comps := []sbom.Component{
+		{Module: "stdlib", Version: "1.21.0"},
+		{Module: "golang.org/x/text", Version: "0.3.0"},
+		{Module: "golang.org/x/text", Version: "v0.3.0", Package: "golang.org/x/text/language"},
+		{Module: "golang.org/x/text", Version: "v0.3.0", Package: "golang.org/x/text/language"},
+		{Module: "stdlib", Package: "net/http"},
+	}
+	want := &vulncheck.Inventory{
+		Modules:   []*packages.Module{{Path: "golang.org/x/text", Version: "v0.3.0"}},
+		Packages:  []string{"golang.org/x/text/language", "net/http"},
+		GoVersion: "go1.21.0",
+	}
+	if diff := cmp.Diff(want, inventory(comps)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// scanCacheVersion is changed whenever the format
+// of the cached results of scans changes.
+const scanCacheVersion = "v1"
+
+// runSourceScanCached is like runSource, but it replays the results of
+// a previous scan with the same key, as computed by scanKey, rather
+// than scanning again, and stores the results of the scans it runs.
+// Unlike the results of -cache, which are those of each package, the
+// results of whole scans are reused without loading any package, so CI
+// jobs scanning the same inputs repeatedly finish right away.
+func runSourceScanCached(ctx context.Context, handler govulncheck.Handler, cfg *config, client *client.Client, dir string) error {
+	if cfg.noResultCache || cfg.keepResult || cfg.emitGraph != "" || cfg.pkgs != nil {
+		return runSource(ctx, handler, cfg, client, dir)
+	}
+	key := scanKey(cfg, dir)
+	if key == "" {
+		return runSource(ctx, handler, cfg, client, dir)
+	}
+	cache, err := openResultCache(cfg.env, "scans")
+	if err != nil {
+		return runSource(ctx, handler, cfg, client, dir)
+	}
+	logger := govulncheck.Logger(ctx)
+	if msgs, ok := cache.get(key); ok {
+		logger.Debug("reusing the results of a previous scan", "key", key)
+		return replay(handler, msgs)
+	}
+	rec := &messageRecorder{Handler: handler}
+	if err := runSource(ctx, rec, cfg, client, dir); err != nil {
+		return err
+	}
+	// Failing to populate the cache only
+	// makes the next run slower.
+	if err := cache.put(key, rec.msgs); err != nil {
+		logger.Debug("caching the results of the scan", "err", err)
+	}
+	return nil
+}
+
+// scanKey identifies the inputs of the source scan of the code in dir
+// requested by cfg: the files of its modules, go.mod and go.sum among
+// them, the arguments of the command, such as the build tags, the
+// build environment, the generation of the vulnerability database,
+// identified by its last modification time, and the govulncheck
+// executable. It returns "" if the inputs cannot be identified, as for
+// databases with no modification time, or entries read from other
+// files or services.
+func scanKey(cfg *config, dir string) string {
+	if len(cfg.db) == 0 || cfg.DBLastModified == nil || len(cfg.osvExtra) > 0 || cfg.severityMap != "" ||
+		cfg.ghsa != "" || cfg.nvd != "" || cfg.verify != "" || cfg.gopath || cfg.importcfg != "" || !gomodExists(dir) {
+		return ""
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return ""
+	}
+	files := sourceFiles(cfg)
+	if files == nil {
+		return ""
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s %d %s\n%s %s\n", scanCacheVersion, exe, info.Size(), info.ModTime().UTC(), cfg.ScannerVersion, cfg.GoVersion)
+	fmt.Fprintf(h, "%q %s\n%s\n%q\n%q\n", cfg.db, cfg.DBLastModified.UTC(), dir, cfg.args, buildEnv(cfg))
+	for _, path := range slices.Sorted(maps.Keys(files)) {
+		// The content of files, unlike their modification
+		// times, is the same in all checkouts of the code.
+		fmt.Fprintln(h, path)
+		f, err := os.Open(path)
+		if err != nil {
+			return ""
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return ""
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// messageRecorder records the messages passed to it.
+type messageRecorder struct {
+	govulncheck.Handler
+	msgs []*govulncheck.Message
+}
+
+func (h *messageRecorder) SBOM(s *govulncheck.SBOM) error {
+	h.msgs = append(h.msgs, &govulncheck.Message{SBOM: s})
+	return h.Handler.SBOM(s)
+}
+
+func (h *messageRecorder) Progress(p *govulncheck.Progress) error {
+	h.msgs = append(h.msgs, &govulncheck.Message{Progress: p})
+	return h.Handler.Progress(p)
+}
+
+func (h *messageRecorder) OSV(e *osv.Entry) error {
+	h.msgs = append(h.msgs, &govulncheck.Message{OSV: e})
+	return h.Handler.OSV(e)
+}
+
+func (h *messageRecorder) Finding(f *govulncheck.Finding) error {
+	h.msgs = append(h.msgs, &govulncheck.Message{Finding: f})
+	return h.Handler.Finding(f)
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunSourceScanCached(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip(err)
+	}
+	db, err := filepath.Abs(filepath.Join("..", "..", "cmd", "govulncheck", "testdata", "common", "vulndb-v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/m\n\ngo 1.18\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := append(os.Environ(), "GOFLAGS=", "GOVULNDB_CACHE="+t.TempDir())
+	scan := func(args ...string) (out string, reused bool) {
+		t.Helper()
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"-db", "file://" + filepath.ToSlash(db), "-C", dir, "-format", "json", "-log", "debug"}, args...)
+		if err := RunGovulncheck(context.Background(), env, nil, &stdout, &stderr, append(args, "./...")); err != nil {
+			t.Fatalf("%v: %s", err, &stderr)
+		}
+		return stdout.String(), strings.Contains(stderr.String(), "reusing the results of a previous scan")
+	}
+
+	out, reused := scan()
+	if reused {
+		t.Fatal("first scan reused results")
+	}
+	if again, reused := scan(); !reused || again != out {
+		t.Errorf("rescan of the same inputs: reused %t and got output\n%s\nwant\n%s", reused, again, out)
+	}
+	if _, reused := scan("-no-result-cache"); reused {
+		t.Error("-no-result-cache reused results")
+	}
+	if _, reused := scan("-tags", "foo"); reused {
+		t.Error("results reused for other build tags")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "go.sum"), []byte("example.com/dep v1.0.0 h1:x=\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, reused := scan(); reused {
+		t.Error("results reused after go.sum changed")
+	}
+}
@@ -0,0 +1,627 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/tools/go/packages"
+)
+
+// defaultServeAddr returns the address the serve command listens
+// on, and the client command connects to, by default: a unix socket
+// in a directory of the temporary directory only the user can access.
+func defaultServeAddr() string {
+	return "unix:" + filepath.Join(os.TempDir(), fmt.Sprintf("govulncheck-%d", os.Getuid()), "govulncheck.sock")
+}
+
+// serveTokenEnv is the environment variable holding the token
+// authenticating the clients of the serve command listening on
+// a TCP address.
+const serveTokenEnv = "GOVULNCHECK_SERVE_TOKEN"
+
+// serveFlags are the flags which the scans requested of the serve
+// command may be given. Those writing files, running commands, reading
+// the files or the credentials of the server, changing how the go
+// command is run, or setting limits of the whole server are left out,
+// as are -watch and -tui, which do not return, -mode, since binary
+// mode would fetch binary URLs from the server, and the database
+// flags, since the server has its own database.
+var serveFlags = map[string]bool{
+	"C": true, "json": true, "test": true, "tags": true, "platform": true,
+	"strict-platform": true, "platforms": true, "show": true, "format": true,
+	"version": true, "scan": true, "log": true, "cache": true,
+	"no-result-cache": true, "proxy-check": true, "max-depth": true,
+	"max-findings": true, "callgraph": true, "reflection": true, "taint": true,
+	"skip-init": true, "library": true, "entry": true, "confidence": true,
+	"workers": true, "load-workers": true, "export-data": true,
+	"witness": true, "witness-workers": true, "witness-timeout": true,
+	"witness-through-vulns": true, "witness-depth": true, "slice": true,
+}
+
+// serveEnvVars are the environment variables of the client passed to
+// the scans requested of the serve command, which select the code
+// built and the modules it is built with. The others, such as GOFLAGS,
+// which may run commands while loading packages, and those of the
+// credentials of the client, are not sent.
+var serveEnvVars = []string{
+	"GOOS", "GOARCH", "GO386", "GOAMD64", "GOARM", "GOARM64", "GOMIPS", "GOMIPS64",
+	"GOPPC64", "GORISCV64", "GOWASM", "GOEXPERIMENT", "CGO_ENABLED",
+	"GOWORK", "GOPATH", "GOMODCACHE", "GOPROXY", "GOPRIVATE", "GONOPROXY",
+	"GONOSUMDB", "GOSUMDB", "GOINSECURE",
+}
+
+// serveEnv returns the variables of env in serveEnvVars.
+func serveEnv(env []string) []string {
+	var out []string
+	for _, e := range env {
+		if k, _, ok := strings.Cut(e, "="); ok && slices.Contains(serveEnvVars, k) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// serveNetwork returns the network and address of addr, an address of
+// the serve command: a unix socket as in unix:/tmp/govulncheck.sock,
+// or else a TCP address.
+func serveNetwork(addr string) (network, address string) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		return "unix", path
+	}
+	return "tcp", addr
+}
+
+// maxServeRequestSize is the maximum size of the body of a
+// serveRequest, which only holds arguments and environment variables.
+const maxServeRequestSize = 1 << 20
+
+// A serveRequest is a scan requested by the client command.
+type serveRequest struct {
+	// Dir is the working directory of the client, relative to
+	// which the -C directory of Args is interpreted.
+	Dir string `json:"dir"`
+	// Env holds the variables of the environment of the
+	// client in serveEnvVars, which override those of the
+	// server.
+	Env  []string `json:"env"`
+	Args []string `json:"args"`
+}
+
+// A serveFrame is part of the response to a serveRequest, which is a
+// stream of frames holding the output of the scan, in the order it
+// was written, and ending with one holding its exit code.
+type serveFrame struct {
+	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+	Exit   *int   `json:"exit,omitempty"`
+}
+
+// runServe runs the serve command, which scans source code on request of
+// the client command until ctx is done, keeping warm between scans what
+// repeated scans would otherwise redo: the vulnerabilities fetched for
+// each module version, the packages loaded from unchanged files, the
+// call graphs of unchanged programs, and the output of the scans whose
+// files, arguments, and environment did not change, which is replayed.
+// Scans run one at a time, since they would otherwise compete for
+// memory. On a TCP address, clients must present the token of
+// serveTokenEnv, which is generated unless set.
+func runServe(ctx context.Context, env []string, stdout, stderr io.Writer, args []string) error {
+	cfg := &config{env: env}
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	listen := flags.String("listen", defaultServeAddr(), "listen on `address`, a unix socket as in unix:/tmp/govulncheck.sock or a TCP address as in localhost:8080")
+	refresh := flags.Duration("refresh", time.Hour, "fetch the vulnerabilities of modules again, and rescan unchanged code, once `duration` has passed since they were fetched")
+	flags.Func("db", "vulnerability database `url`; may be repeated to merge several databases (default 'https://vuln.go.dev')", func(s string) error {
+		cfg.db = append(cfg.db, s)
+		return nil
+	})
+	flags.IntVar(&cfg.dbRetries, "db-retries", 3, "retry the requests to http(s) databases failing with network or server errors up to `N` times, with exponential backoff")
+	flags.DurationVar(&cfg.dbTimeout, "db-timeout", 0, "give up on each attempt of a request to an http(s) database after `duration` (default none)")
+	flags.IntVar(&cfg.dbConcurrency, "db-concurrency", 10, "make up to `N` requests at once to the databases")
+	flags.DurationVar(&cfg.cacheTTL, "cache-ttl", 0, "cache the responses of http(s) vulnerability databases, and reuse them for `duration`, which hides the advisories published meanwhile (default not cached)")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), `Usage:
+
+	govulncheck serve [flags]
+
+`)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return errHelp
+		}
+		return errUsage
+	}
+	if len(cfg.db) == 0 {
+		cfg.db = []string{"https://vuln.go.dev"}
+	}
+	err := validateDBConfig(cfg)
+	switch {
+	case flags.NArg() > 0:
+		err = fmt.Errorf("unexpected arguments %q", flags.Args())
+	case *refresh <= 0:
+		err = fmt.Errorf("invalid -refresh %s: must be positive", *refresh)
+	}
+	if err != nil {
+		fmt.Fprintln(flags.Output(), err)
+		return errUsage
+	}
+
+	l, err := listenServe(*listen)
+	if err != nil {
+		return err
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	s := &server{cfg: cfg, refresh: *refresh, env: env, packages: &packageCache{}}
+	srv := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	stop := context.AfterFunc(ctx, func() { srv.Close() })
+	defer stop()
+	fmt.Fprintf(stdout, "Serving scans on %s\n", *listen)
+	if network, _ := serveNetwork(*listen); network == "tcp" {
+		if s.token = lookupEnv(env, serveTokenEnv); s.token == "" {
+			s.token = rand.Text()
+			fmt.Fprintf(stdout, "Clients must set %s=%s\n", serveTokenEnv, s.token)
+		}
+	}
+	if err := srv.Serve(l); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// listenServe listens on addr, an address of the serve command,
+// removing the socket file of a unix address left by a server
+// no longer running. The socket file of a unix address is only
+// accessible to the user, as is the directory of the default one.
+func listenServe(addr string) (net.Listener, error) {
+	network, address := serveNetwork(addr)
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+	if addr == defaultServeAddr() {
+		if err := os.MkdirAll(filepath.Dir(address), 0o700); err != nil {
+			return nil, err
+		}
+		if err := checkServeDir(filepath.Dir(address)); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		if c, derr := net.Dial(network, address); derr == nil {
+			c.Close()
+			return nil, err // in use
+		}
+		if os.Remove(address) != nil {
+			return nil, err
+		}
+		if l, err = net.Listen(network, address); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.Chmod(address, 0o600); err != nil {
+		l.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// checkServeDir returns an error unless dir, the directory of the
+// default socket of the serve command, is a directory only its owner
+// can access, as other users could otherwise connect to the server,
+// or stand in for it.
+func checkServeDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil // the temporary directory is the user's
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() || fi.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s must be a directory only its owner can access", dir)
+	}
+	return nil
+}
+
+// A server runs the scans requested by clients of the serve command.
+type server struct {
+	cfg     *config // the database flags of the serve command
+	refresh time.Duration
+	env     []string // of the scans, along with that of the clients
+	token   string   // clients must present, if set
+
+	packages *packageCache
+
+	mu      sync.Mutex // serializes scans, guarding the fields below
+	client  *client.Client
+	fetched time.Time // when client was created
+	results map[string]*servedResult
+}
+
+// A servedResult is the output of a scan of source
+// code, replayed while its files do not change.
+type servedResult struct {
+	snap   map[string]fileState
+	frames []serveFrame
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/scan" {
+		http.NotFound(w, r)
+		return
+	}
+	if s.token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			http.Error(w, "invalid or missing token of "+serveTokenEnv, http.StatusUnauthorized)
+			return
+		}
+	}
+	var req serveRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxServeRequestSize)).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if !filepath.IsAbs(req.Dir) {
+		http.Error(w, fmt.Sprintf("invalid request: directory %q is not absolute", req.Dir), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == nil || time.Since(s.fetched) > s.refresh {
+		c, err := newClient(r.Context(), s.cfg)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("creating client: %v", err), http.StatusInternalServerError)
+			return
+		}
+		s.client = client.Memoize(c)
+		s.fetched = time.Now()
+		s.results = make(map[string]*servedResult)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	fw := &frameWriter{w: w, enc: json.NewEncoder(w)}
+	key := requestKey(&req)
+	env := s.scanEnv(req.Env)
+	// The files are snapshotted before the scan, so that those
+	// changed while it runs are rescanned by the next one.
+	snap := snapshotScan(req.Dir, env, req.Args)
+	if res := s.results[key]; res != nil && snap != nil && maps.Equal(res.snap, snap) {
+		for _, f := range res.frames {
+			fw.write(f)
+		}
+		return
+	}
+
+	cfg := &config{env: env, wd: req.Dir, callGraphCache: true, allowedFlags: serveFlags, packageCache: s.packages}
+	err := runGovulncheck(r.Context(), cfg, nil, frameStream{fw, false}, frameStream{fw, true}, req.Args, s.client)
+	code := 0
+	if err != nil {
+		code = 1
+		var e interface {
+			error
+			ExitCode() int
+		}
+		if errors.As(err, &e) {
+			code = e.ExitCode()
+		}
+		// Errors are written as the govulncheck command does.
+		if e == nil || err.Error() != e.Error() {
+			frameStream{fw, true}.Write([]byte(err.Error() + "\n"))
+		}
+	}
+	fw.write(serveFrame{Exit: &code})
+	if snap != nil && r.Context().Err() == nil && (err == nil || errors.Is(err, errVulnerabilitiesFound)) {
+		s.results[key] = &servedResult{snap: snap, frames: fw.frames}
+	} else {
+		delete(s.results, key)
+	}
+}
+
+// scanEnv returns the environment of the scans requested with env,
+// the environment sent by a client: that of the server, without the
+// variables setting flags, overridden by the variables of env in
+// serveEnvVars.
+func (s *server) scanEnv(env []string) []string {
+	var out []string
+	for _, e := range s.env {
+		if !strings.HasPrefix(e, "GOVULNCHECK_") {
+			out = append(out, e)
+		}
+	}
+	return append(out, serveEnv(env)...)
+}
+
+// requestKey returns the key of the results of req.
+func requestKey(req *serveRequest) string {
+	b, _ := json.Marshal(req)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// snapshotScan returns the state of the source files of the scan of
+// args run in dir with env, as returned by sourceFiles. It returns nil
+// if the scan is not of source code, or its arguments are invalid, as
+// its output is then not replayed.
+func snapshotScan(dir string, env, args []string) map[string]fileState {
+	cfg := &config{env: env, wd: dir, allowedFlags: serveFlags}
+	if err := parseFlags(cfg, io.Discard, args); err != nil || cfg.ScanMode != govulncheck.ScanModeSource {
+		return nil
+	}
+	return sourceFiles(cfg)
+}
+
+// sourceFiles returns the state of the files of the main module of the
+// source scan of cfg, and of the local modules replacing its
+// requirements, if any. The main module is that of the -C directory,
+// found as the go command does, or the directory itself if it has
+// none. It returns nil if the files cannot be listed.
+func sourceFiles(cfg *config) map[string]fileState {
+	root := sourceDir(cfg)
+	for d := root; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			root = d
+			break
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+
+	snap, err := snapshotDir(root)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return snap // no module
+	}
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return snap // reported by the scan
+	}
+	for _, r := range f.Replace {
+		if !modfile.IsDirectoryPath(r.New.Path) {
+			continue
+		}
+		dir := r.New.Path
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		rsnap, err := snapshotDir(dir)
+		if err != nil {
+			return nil
+		}
+		maps.Copy(snap, rsnap)
+	}
+	return snap
+}
+
+// A frameWriter writes the frames of the response to a scan,
+// recording them for replay.
+type frameWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	enc    *json.Encoder
+	frames []serveFrame
+	err    error // of writing w, once the client is gone
+}
+
+func (fw *frameWriter) write(f serveFrame) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	fw.frames = append(fw.frames, f)
+	if fw.err == nil {
+		fw.err = fw.enc.Encode(f)
+		if fl, ok := fw.w.(http.Flusher); ok {
+			fl.Flush()
+		}
+	}
+	return fw.err
+}
+
+// A frameStream writes the standard output, or standard
+// error if stderr is set, of a scan as frames.
+type frameStream struct {
+	fw     *frameWriter
+	stderr bool
+}
+
+func (s frameStream) Write(p []byte) (int, error) {
+	var f serveFrame
+	if s.stderr {
+		f.Stderr = string(p)
+	} else {
+		f.Stdout = string(p)
+	}
+	if err := s.fw.write(f); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// runClient runs the client command, which has the server of the serve
+// command run the scan requested by args, writing its output and
+// returning its exit code as if it ran locally. Its only flag, -server,
+// must come first; the other arguments are those of the scan.
+func runClient(ctx context.Context, env []string, stdout, stderr io.Writer, args []string) error {
+	addr := defaultServeAddr()
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		name, value, ok := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+		switch {
+		case name != "server":
+		case ok:
+			addr, args = value, args[1:]
+		case len(args) < 2:
+			fmt.Fprintln(stderr, "flag needs an argument: -server")
+			return errUsage
+		default:
+			addr, args = args[1], args[2:]
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if env == nil {
+		env = os.Environ()
+	}
+	body, err := json.Marshal(&serveRequest{Dir: wd, Env: serveEnv(env), Args: args})
+	if err != nil {
+		return err
+	}
+
+	network, address := serveNetwork(addr)
+	if addr == defaultServeAddr() {
+		if err := checkServeDir(filepath.Dir(address)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	hc := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://govulncheck/scan", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if token := lookupEnv(env, serveTokenEnv); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("connecting to the server on %s, which govulncheck serve runs: %w", addr, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server on %s: %s", addr, strings.TrimSpace(string(msg)))
+	}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var f serveFrame
+		if err := dec.Decode(&f); err != nil {
+			if err == io.EOF {
+				err = errors.New("the connection was closed before the scan completed")
+			}
+			return fmt.Errorf("server on %s: %w", addr, err)
+		}
+		if _, err := io.WriteString(stdout, f.Stdout); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(stderr, f.Stderr); err != nil {
+			return err
+		}
+		if f.Exit != nil {
+			if *f.Exit == 0 {
+				return nil
+			}
+			return &exitCodeError{message: fmt.Sprintf("exit status %d", *f.Exit), code: *f.Exit}
+		}
+	}
+}
+
+// maxWarmLoads is the number of package loads kept by the serve
+// command, the least recently used being dropped first.
+const maxWarmLoads = 4
+
+// A packageCache keeps the packages loaded by the scans of the serve
+// command, so that the scans of unchanged code loading the same
+// packages, such as those asking for other output, do not load them
+// again.
+type packageCache struct {
+	mu    sync.Mutex
+	loads map[string]*warmLoad
+}
+
+// A warmLoad holds the packages of a load, kept
+// while the files they were loaded from do not change.
+type warmLoad struct {
+	snap map[string]fileState
+	pkgs []*packages.Package
+	used time.Time
+}
+
+// load adds to graph the packages of cfg loaded with pcfg, loading them
+// unless kept since their files last changed. Loads failing with
+// package errors are not kept.
+func (c *packageCache) load(cfg *config, graph *vulncheck.PackageGraph, pcfg *packages.Config, wantSymbols bool) error {
+	b, _ := json.Marshal(struct {
+		Dir                        string
+		Env, Flags, Tags, Patterns []string
+		Tests, Symbols             bool
+	}{pcfg.Dir, pcfg.Env, pcfg.BuildFlags, cfg.tags, cfg.patterns, pcfg.Tests, wantSymbols})
+	sum := sha256.Sum256(b)
+	key := hex.EncodeToString(sum[:])
+	snap := sourceFiles(cfg)
+
+	c.mu.Lock()
+	l := c.loads[key]
+	if l != nil && snap != nil && maps.Equal(l.snap, snap) {
+		l.used = time.Now()
+		c.mu.Unlock()
+		return graph.AddLoadedPackages(l.pkgs)
+	}
+	c.mu.Unlock()
+
+	pkgs, err := vulncheck.LoadPackages(pcfg, cfg.tags, cfg.patterns, wantSymbols)
+	if err != nil {
+		return err
+	}
+	if err := graph.AddLoadedPackages(pkgs); err != nil || snap == nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loads == nil {
+		c.loads = make(map[string]*warmLoad)
+	}
+	c.loads[key] = &warmLoad{snap: snap, pkgs: pkgs, used: time.Now()}
+	for len(c.loads) > maxWarmLoads {
+		var oldest string
+		for k, l := range c.loads {
+			if oldest == "" || l.used.Before(c.loads[oldest].used) {
+				oldest = k
+			}
+		}
+		delete(c.loads, oldest)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestServe(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip(err)
+	}
+	db, err := filepath.Abs(filepath.Join("..", "..", "cmd", "govulncheck", "testdata", "common", "vulndb-v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{
+		cfg:      &config{db: []string{"file://" + filepath.ToSlash(db)}, dbConcurrency: 1},
+		refresh:  time.Hour,
+		env:      append(os.Environ(), "GOFLAGS="),
+		packages: &packageCache{},
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/m\n\ngo 1.18\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Relative -C directories are those of the client.
+	t.Chdir(filepath.Dir(dir))
+	ctx := context.Background()
+	env := os.Environ()
+	scan := func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		err := runClient(ctx, env, &stdout, &stderr, append([]string{"-server", addr}, args...))
+		return stdout.String() + stderr.String(), err
+	}
+	args := []string{"-C", filepath.Base(dir), "-scan", "package", "-format", "json", "./..."}
+
+	out, err := scan(args...)
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if !strings.Contains(out, `"scan_level": "package"`) {
+		t.Errorf("no config in the output:\n%s", out)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := func() *servedResult {
+		t.Helper()
+		res := s.results[requestKey(&serveRequest{Dir: wd, Env: serveEnv(env), Args: args})]
+		if res == nil {
+			t.Fatal("result not kept")
+		}
+		return res
+	}
+	res := kept()
+	warm := func() []*packages.Package {
+		t.Helper()
+		if len(s.packages.loads) != 1 {
+			t.Fatalf("got %d package loads kept; want 1", len(s.packages.loads))
+		}
+		for _, l := range s.packages.loads {
+			return l.pkgs
+		}
+		return nil
+	}
+	pkgs := warm()
+
+	// Unchanged code is not rescanned.
+	if again, err := scan(args...); err != nil || again != out {
+		t.Errorf("rescan of unchanged code: got %v and output\n%s\nwant\n%s", err, again, out)
+	}
+	if kept() != res {
+		t.Error("result of unchanged code replaced")
+	}
+
+	// Nor are its packages loaded again for other output.
+	if out, err := scan(append([]string{"-format", "text"}, args...)...); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if !slices.Equal(warm(), pkgs) {
+		t.Error("packages of unchanged code loaded again")
+	}
+
+	// Changed code is.
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() { println() }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scan(args...); err != nil {
+		t.Fatal(err)
+	}
+	if kept() == res {
+		t.Error("result of changed code not replaced")
+	}
+	if slices.Equal(warm(), pkgs) {
+		t.Error("packages of changed code not loaded again")
+	}
+
+	// Usage errors have the exit code of the command.
+	out, err = scan("-scan", "bogus", ".")
+	var e interface{ ExitCode() int }
+	if !errors.As(err, &e) || e.ExitCode() != 2 || !strings.Contains(out, "bogus") {
+		t.Errorf("got error %v and output %q for an invalid scan level; want exit code 2", err, out)
+	}
+}
+
+func TestServeRestrictions(t *testing.T) {
+	db, err := filepath.Abs(filepath.Join("..", "..", "cmd", "govulncheck", "testdata", "common", "vulndb-v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{
+		cfg:     &config{db: []string{"file://" + filepath.ToSlash(db)}, dbConcurrency: 1},
+		refresh: time.Hour,
+		env:     os.Environ(),
+		token:   "secret",
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	addr := strings.TrimPrefix(ts.URL, "http://")
+	scan := func(token string, args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		env := []string{serveTokenEnv + "=" + token}
+		err := runClient(context.Background(), env, &stdout, &stderr, append([]string{"-server", addr}, args...))
+		return stdout.String() + stderr.String(), err
+	}
+
+	if _, err := scan("wrong", "-version"); err == nil || !strings.Contains(err.Error(), serveTokenEnv) {
+		t.Errorf("got error %v for a wrong token; want one about %s", err, serveTokenEnv)
+	}
+	for _, args := range [][]string{
+		{"-handler-exec", "cat", "."},
+		{"-emit-osv-dir", t.TempDir(), "."},
+		{"-goflags", "-toolexec=true", "."},
+		{"-watch", "."},
+		{"-tui", "."},
+		{"-db", "file:///draft", "."},
+		{"-no-cache", "."},
+		{"-mode", "binary", "https://example.com/tool"},
+	} {
+		out, err := scan("secret", args...)
+		var e interface{ ExitCode() int }
+		if !errors.As(err, &e) || e.ExitCode() != 2 || !strings.Contains(out, "not supported by scans of the serve command") {
+			t.Errorf("%v: got error %v and output %q; want exit code 2", args, err, out)
+		}
+	}
+
+	// Requests larger than maxServeRequestSize are rejected unread.
+	body := `{"dir":"/","args":["` + strings.Repeat("x", maxServeRequestSize) + `"]}`
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/scan", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("got status %s for a request too large; want %d", resp.Status, http.StatusBadRequest)
+	}
+}
+
+func TestServeEnv(t *testing.T) {
+	s := &server{env: []string{"HOME=/home/server", "GOFLAGS=-mod=mod", "GOVULNCHECK_FORMAT=json"}}
+	got := s.scanEnv(serveEnv([]string{"HOME=/home/client", "GOOS=plan9", "GOFLAGS=-toolexec=evil", "GOVULNCHECK_HANDLER_EXEC=evil", "GITHUB_TOKEN=secret"}))
+	want := []string{"HOME=/home/server", "GOFLAGS=-mod=mod", "GOOS=plan9"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got env %q; want %q", got, want)
+	}
+}
@@ -6,7 +6,11 @@ package scan
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/StevenACoffman/invuln/external/client"
 	"github.com/StevenACoffman/invuln/external/derrors"
@@ -21,29 +25,219 @@ import (
 // symbol is actually exercised) or just imported by the package
 // (likely having a non-affecting outcome).
 func runSource(ctx context.Context, handler govulncheck.Handler, cfg *config, client *client.Client, dir string) (err error) {
+	if len(cfg.platforms) > 0 {
+		return runSourcePlatforms(ctx, handler, cfg, client, dir)
+	}
+	if cfg.verify != "" {
+		return runVerify(ctx, handler, cfg, client, dir)
+	}
+	if cfg.cache || cfg.results != nil {
+		return runSourceCached(ctx, handler, cfg, client, dir)
+	}
 	defer derrors.Wrap(&err, "govulncheck")
 
+	if cfg.ScanLevel == govulncheck.ScanLevelStdlib {
+		return vulncheck.Stdlib(ctx, handler, cfg.GoVersion, cfg.GOOS, cfg.GOARCH, client)
+	}
 	if cfg.ScanLevel.WantPackages() && len(cfg.patterns) == 0 {
 		return errNoPatterns
 	}
-	if !gomodExists(dir) {
+	if cfg.pkgs == nil && !cfg.gopath && cfg.importcfg == "" && !gomodExists(dir) {
 		return errNoGoMod
 	}
-	graph := vulncheck.NewPackageGraph(cfg.GoVersion)
-	pkgConfig := &packages.Config{
-		Dir:   dir,
-		Tests: cfg.test,
-		Env:   cfg.env,
+	scanCfg := &cfg.Config
+	wantSymbols := cfg.ScanLevel == govulncheck.ScanLevelSymbol
+	var graph *vulncheck.PackageGraph
+	if wantSymbols && cfg.pkgs == nil {
+		// Check the modules of the packages first, so that the code of
+		// projects with no vulnerable modules is not type checked nor
+		// its call graph built.
+		var affected bool
+		graph, client, affected, err = checkModules(ctx, cfg, client, dir)
+		if err != nil {
+			return err
+		}
+		switch {
+		case affected && cfg.ExportData:
+			if graph, err = loadExportPackages(ctx, cfg, client, dir, graph); err != nil {
+				return err
+			}
+		case affected:
+			graph = nil
+		default:
+			pkgCfg := cfg.Config
+			pkgCfg.ScanLevel = govulncheck.ScanLevelPackage
+			scanCfg = &pkgCfg
+			govulncheck.Logger(ctx).Debug("skipped the symbol level analysis, as no vulnerability affects the modules of the packages")
+		}
 	}
-	if err := graph.LoadPackagesAndMods(pkgConfig, cfg.tags, cfg.patterns, cfg.ScanLevel == govulncheck.ScanLevelSymbol); err != nil {
-		if isGoVersionMismatchError(err) {
-			return fmt.Errorf("%v\n\n%v", errGoVersionMismatch, err)
+	if graph == nil {
+		if graph, err = loadPackages(ctx, cfg, dir, wantSymbols); err != nil {
+			return err
 		}
-		return fmt.Errorf("loading packages: %w", err)
 	}
-
 	if cfg.ScanLevel.WantPackages() && len(graph.TopPkgs()) == 0 {
 		return errNoPackagesMatched
 	}
-	return vulncheck.Source(ctx, handler, &cfg.Config, client, graph)
+	if cfg.callGraphCache && scanCfg.ScanLevel.WantSymbols() && !cfg.ExportData && !cfg.gopath && cfg.importcfg == "" && cfg.pkgs == nil {
+		if dir, err := callGraphCacheDir(cfg.env); err == nil {
+			graph.UseCallGraphCache(dir, callGraphKey(cfg, graph))
+		}
+	}
+	if cfg.emitGraph == "" && !cfg.keepResult {
+		return vulncheck.Source(ctx, handler, scanCfg, client, graph)
+	}
+	res, err := vulncheck.SourceResult(ctx, handler, scanCfg, client, graph)
+	if err != nil {
+		return err
+	}
+	if cfg.keepResult {
+		cfg.result = res
+		return nil
+	}
+	return writeGraph(cfg.emitGraph, res)
+}
+
+// checkModules loads the packages of cfg without their syntax and types
+// and reports whether any vulnerability of c affects their modules. It
+// returns the graph of the packages and c memoized, so that scanning
+// them does not fetch their vulnerabilities again.
+func checkModules(ctx context.Context, cfg *config, c *client.Client, dir string) (*vulncheck.PackageGraph, *client.Client, bool, error) {
+	graph, err := loadPackages(ctx, cfg, dir, false)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	c = client.Memoize(c)
+	affected, err := vulncheck.Affected(ctx, c, graph, cfg.GOOS, cfg.GOARCH)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return graph, c, affected, nil
+}
+
+// loadExportPackages loads the packages of cfg for a symbol level scan
+// with their dependencies not on the import paths to the vulnerable
+// packages of c loaded from export data. The packages of pkgGraph,
+// loaded without their syntax and types, tell which those are.
+func loadExportPackages(ctx context.Context, cfg *config, c *client.Client, dir string, pkgGraph *vulncheck.PackageGraph) (*vulncheck.PackageGraph, error) {
+	paths, err := vulncheck.SourcePackages(ctx, c, pkgGraph, cfg.GOOS, cfg.GOARCH)
+	if err != nil {
+		return nil, err
+	}
+	var top []string
+	for _, p := range pkgGraph.TopPkgs() {
+		top = append(top, p.PkgPath)
+	}
+	graph := vulncheck.NewPackageGraph(cfg.GoVersion)
+	pkgConfig := packagesConfig(ctx, cfg, dir)
+	logger := govulncheck.Logger(ctx)
+	logger.Debug("loading packages from export data", "dir", dir, "patterns", cfg.patterns, "source", paths)
+	start := time.Now()
+	if err := graph.LoadExportPackages(pkgConfig, cfg.tags, cfg.patterns, top, paths); err != nil {
+		return nil, loadError(err)
+	}
+	logger.Debug("loaded packages", "packages", len(graph.TopPkgs()), "modules", len(graph.Modules()), "duration", time.Since(start))
+	return graph, nil
+}
+
+// loadPackages loads the packages of cfg into a new graph, with
+// their syntax and types if wantSymbols is set.
+func loadPackages(ctx context.Context, cfg *config, dir string, wantSymbols bool) (*vulncheck.PackageGraph, error) {
+	graph := vulncheck.NewPackageGraph(cfg.GoVersion)
+	pkgConfig := packagesConfig(ctx, cfg, dir)
+	load := graph.LoadPackagesAndMods
+	switch {
+	case cfg.pkgs != nil:
+		load = func(*packages.Config, []string, []string, bool) error {
+			return graph.AddLoadedPackages(cfg.pkgs)
+		}
+	case cfg.packageCache != nil && !cfg.gopath && cfg.importcfg == "":
+		load = func(pcfg *packages.Config, tags, patterns []string, wantSymbols bool) error {
+			return cfg.packageCache.load(cfg, graph, pcfg, wantSymbols)
+		}
+	case cfg.gopath:
+		load = graph.LoadGOPATHPackages
+	case cfg.importcfg != "":
+		data, err := os.ReadFile(cfg.importcfg)
+		if err != nil {
+			return nil, err
+		}
+		icfg, err := vulncheck.ParseImportcfg(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.importcfg, err)
+		}
+		load = func(pcfg *packages.Config, tags, dirs []string, wantSymbols bool) error {
+			return graph.LoadImportcfgPackages(pcfg, icfg, tags, dirs, wantSymbols)
+		}
+	}
+	logger := govulncheck.Logger(ctx)
+	logger.Debug("loading packages", "dir", dir, "patterns", cfg.patterns, "tags", cfg.tags, "test", cfg.test, "symbols", wantSymbols)
+	start := time.Now()
+	if err := load(pkgConfig, cfg.tags, cfg.patterns, wantSymbols); err != nil {
+		return nil, loadError(err)
+	}
+	logger.Debug("loaded packages", "packages", len(graph.TopPkgs()), "modules", len(graph.Modules()), "duration", time.Since(start))
+	return graph, nil
+}
+
+// packagesConfig returns the configuration loading the packages of cfg.
+func packagesConfig(ctx context.Context, cfg *config, dir string) *packages.Config {
+	pkgConfig := &packages.Config{
+		Context: ctx,
+		Dir:     dir,
+		Tests:   cfg.test,
+		Env:     cfg.env,
+	}
+	vulncheck.LimitLoad(pkgConfig, cfg.LoadWorkers)
+	return pkgConfig
+}
+
+// loadError returns the error of a scan failing to load its packages with err.
+func loadError(err error) error {
+	if isGoVersionMismatchError(err) {
+		return fmt.Errorf("%w\n\n%v", errGoVersionMismatch, err)
+	}
+	return govulncheck.NewError(govulncheck.ErrorBuildFailed, fmt.Errorf("loading packages: %w", err))
+}
+
+// checkLoadedPackages returns an error if the packages of cfg,
+// loaded by the caller, cannot be scanned as cfg requests.
+func checkLoadedPackages(cfg *config) error {
+	switch {
+	case cfg.ScanMode != govulncheck.ScanModeSource:
+		return errors.New("packages are only scanned in source mode")
+	case !cfg.ScanLevel.WantPackages():
+		return fmt.Errorf("packages are not scanned at the %s level", cfg.ScanLevel)
+	case cfg.test || len(cfg.tags) > 0 || cfg.goflags != "" || cfg.platform != "" || len(cfg.platforms) > 0 ||
+		cfg.gopath || cfg.importcfg != "" || cfg.cache || cfg.verify != "" || cfg.ExportData:
+		return errors.New("the -test, -tags, -goflags, -platform, -platforms, -gopath, -importcfg, -cache, -verify, and -export-data flags, which change how packages are loaded, are not supported for packages already loaded")
+	}
+	return vulncheck.CheckLoadMode(cfg.pkgs, cfg.ScanLevel.WantSymbols())
+}
+
+// writeGraph writes the call graph and import graph of res to file,
+// in the format given by its extension.
+func writeGraph(file string, res *vulncheck.Result) (err error) {
+	format, _ := graphFormat(file)
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return vulncheck.WriteGraph(res, format, f)
+}
+
+// graphFormat returns the graph format for the extension of file.
+func graphFormat(file string) (vulncheck.GraphFormat, bool) {
+	switch filepath.Ext(file) {
+	case ".dot", ".gv":
+		return vulncheck.GraphDOT, true
+	case ".graphml":
+		return vulncheck.GraphML, true
+	}
+	return "", false
 }
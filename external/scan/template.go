@@ -26,11 +26,12 @@ type findingSummary struct {
 }
 
 type summaryCounters struct {
-	VulnerabilitiesCalled   int
-	ModulesCalled           int
-	VulnerabilitiesImported int
-	VulnerabilitiesRequired int
-	StdlibCalled            bool
+	VulnerabilitiesCalled     int
+	ModulesCalled             int
+	VulnerabilitiesReferenced int
+	VulnerabilitiesImported   int
+	VulnerabilitiesRequired   int
+	StdlibCalled              bool
 }
 
 func fixupFindings(osvs []*osv.Entry, findings []*findingSummary) {
@@ -96,7 +97,18 @@ func isImported(findings []*findingSummary) bool {
 
 func isCalled(findings []*findingSummary) bool {
 	for _, f := range findings {
-		if f.Trace[0].Function != "" {
+		if fr := f.Trace[0]; fr.Function != "" && fr.Kind != govulncheck.SymbolKindFunc {
+			return true
+		}
+	}
+	return false
+}
+
+// isReferenced reports whether findings include vulnerable
+// functions referenced but not found to be called.
+func isReferenced(findings []*findingSummary) bool {
+	for _, f := range findings {
+		if f.Trace[0].Kind == govulncheck.SymbolKindFunc {
 			return true
 		}
 	}
@@ -168,6 +180,39 @@ func platforms(mod string, e *osv.Entry) []string {
 	return keys
 }
 
+// artifacts returns the sorted set of artifacts findings originate from.
+func artifacts(findings []*findingSummary) []string {
+	set := make(map[string]bool)
+	for _, f := range findings {
+		if f.Artifact != "" {
+			set[f.Artifact] = true
+		}
+	}
+	var as []string
+	for a := range set {
+		as = append(as, a)
+	}
+	sort.Strings(as)
+	return as
+}
+
+// findingPlatforms returns the sorted set of
+// platforms findings were found for.
+func findingPlatforms(findings []*findingSummary) []string {
+	set := make(map[string]bool)
+	for _, f := range findings {
+		for _, p := range f.Platforms {
+			set[p] = true
+		}
+	}
+	var ps []string
+	for p := range set {
+		ps = append(ps, p)
+	}
+	sort.Strings(ps)
+	return ps
+}
+
 func posToString(p *govulncheck.Position) string {
 	if p == nil || p.Line <= 0 {
 		return ""
@@ -189,7 +234,17 @@ func symbol(frame *govulncheck.Frame, short bool) string {
 func symbolName(frame *govulncheck.Frame) string {
 	buf := &strings.Builder{}
 	addSymbolName(buf, frame)
-	return buf.String()
+	name := buf.String()
+	if frame.TypeArgs != "" {
+		// Show the type arguments of generic
+		// instances after the function or type.
+		if frame.Receiver != "" {
+			typ, fn, _ := strings.Cut(name, ".")
+			return typ + frame.TypeArgs + "." + fn
+		}
+		return name + frame.TypeArgs
+	}
+	return name
 }
 
 // compactTrace returns a short description of the call stack.
@@ -214,25 +269,106 @@ func compactTrace(finding *govulncheck.Finding) string {
 		buf.WriteString(": ")
 	}
 
+	// Vulnerable types, variables, constants, and fields
+	// are used, and functions may be referenced, rather
+	// than called.
+	verb := " calls "
+	switch k := compact[0].Kind; {
+	case k == govulncheck.SymbolKindFunc:
+		verb = " references "
+	case k != "":
+		verb = " uses "
+	}
 	if l > 1 {
 		// print the root of the compact trace
-		addSymbol(buf, compact[iTop], true)
-		buf.WriteString(" calls ")
+		addInstance(buf, compact[iTop])
+		if l > 2 {
+			buf.WriteString(" calls ")
+		} else {
+			buf.WriteString(verb)
+		}
 	}
 	if l > 2 {
 		// print next element of the trace, if any
-		addSymbol(buf, compact[iTop-1], true)
+		addInstance(buf, compact[iTop-1])
 		buf.WriteString(", which")
 		if l > 3 {
 			// don't print the third element, just acknowledge it
 			buf.WriteString(" eventually")
 		}
-		buf.WriteString(" calls ")
+		buf.WriteString(verb)
+	}
+	addInstance(buf, compact[0]) // print the vulnerable symbol
+	if compact[0].Kind == govulncheck.SymbolKindLinked {
+		buf.WriteString(" (linked into the binary)")
+	}
+	if finding.Reflection {
+		buf.WriteString(" (possibly, through reflection)")
+	}
+	buf.WriteString(linkageNote(finding.Linkage))
+	if finding.Partial {
+		buf.WriteString(" (partial trace)")
+	}
+	if pkg := initPackage(finding); pkg != "" {
+		buf.WriteString(" (via package initialization of " + pkg + ")")
+	}
+	if len(finding.Taint) > 0 {
+		buf.WriteString(" (with " + taintSources(finding.Taint) + ")")
 	}
-	addSymbol(buf, compact[0], true) // print the vulnerable symbol
 	return buf.String()
 }
 
+// taintSources describes the sources of untrusted data
+// in ss, as in "input from the network or files".
+func taintSources(ss []govulncheck.TaintSource) string {
+	var names []string
+	for _, s := range ss {
+		switch s {
+		case govulncheck.TaintSourceNetwork:
+			names = append(names, "the network")
+		case govulncheck.TaintSourceFile:
+			names = append(names, "files")
+		case govulncheck.TaintSourceEnv:
+			names = append(names, "the environment")
+		default:
+			names = append(names, string(s))
+		}
+	}
+	switch len(names) {
+	case 1:
+		return "input from " + names[0]
+	case 2:
+		return "input from " + names[0] + " or " + names[1]
+	}
+	return "input from " + strings.Join(names[:len(names)-1], ", ") + ", or " + names[len(names)-1]
+}
+
+// linkageNote returns the note describing how
+// a vulnerable symbol is reached through linkage.
+func linkageNote(linkage govulncheck.Linkage) string {
+	switch linkage {
+	case govulncheck.LinkageLinkname:
+		return " (through a go:linkname directive)"
+	case govulncheck.LinkageAssembly:
+		return " (possibly, from assembly)"
+	}
+	return ""
+}
+
+// initPackage returns the package whose initialization, that is its
+// init functions and the initializers of its package-level variables,
+// is the entry point of the trace of finding, if any.
+func initPackage(finding *govulncheck.Finding) string {
+	if len(finding.Trace) < 2 {
+		return ""
+	}
+	root := finding.Trace[len(finding.Trace)-1]
+	if name, _, _ := strings.Cut(root.Function, "#"); root.Receiver != "" || name != "init" {
+		return ""
+	}
+	return root.Package
+}
+
 // notIdentifier reports whether ch is an invalid identifier character.
 func notIdentifier(ch rune) bool {
 	return !('a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' ||
@@ -276,6 +412,19 @@ func addSymbol(w io.Writer, frame *govulncheck.Frame, short bool) {
 	addSymbolName(w, frame)
 }
 
+// addInstance is like addSymbol with short package names,
+// but also shows the type arguments of generic instances.
+func addInstance(w io.Writer, frame *govulncheck.Frame) {
+	if frame.Function == "" {
+		return
+	}
+	if frame.Package != "" {
+		io.WriteString(w, importPathToAssumedName(frame.Package))
+		io.WriteString(w, ".")
+	}
+	io.WriteString(w, symbolName(frame))
+}
+
 func addSymbolName(w io.Writer, frame *govulncheck.Frame) {
 	if frame.Receiver != "" {
 		if frame.Receiver[0] == '*' {
@@ -286,5 +435,7 @@ func addSymbolName(w io.Writer, frame *govulncheck.Frame) {
 		io.WriteString(w, ".")
 	}
 	funcname := strings.Split(frame.Function, "$")[0]
+	// Init functions are numbered, as in init#1.
+	funcname, _, _ = strings.Cut(funcname, "#")
 	io.WriteString(w, funcname)
 }
@@ -13,12 +13,14 @@ import (
 
 func TestCompactTrace(t *testing.T) {
 	for _, tc := range []struct {
-		trace []*govulncheck.Frame
-		want  string
+		trace      []*govulncheck.Frame
+		reflection bool
+		want       string
 	}{
 		{
 			// binary mode
 			[]*govulncheck.Frame{{Function: "Foo"}},
+			false,
 			"Foo",
 		},
 		{
@@ -27,6 +29,7 @@ func TestCompactTrace(t *testing.T) {
 				{Module: "user", Function: "W"},
 				{Module: "user", Function: "U"},
 			},
+			false,
 			"W calls V",
 		},
 		{
@@ -36,6 +39,7 @@ func TestCompactTrace(t *testing.T) {
 				{Module: "user", Function: "U"},
 				{Module: "user", Function: "W"},
 			},
+			false,
 			"U calls I, which calls V",
 		},
 		{
@@ -48,12 +52,29 @@ func TestCompactTrace(t *testing.T) {
 				{Module: "user", Function: "U"},
 				{Module: "user", Function: "W"},
 			},
+			false,
 			"U calls I, which eventually calls V",
 		},
+		{
+			[]*govulncheck.Frame{
+				{Module: "vuln", Function: "V"},
+				{Module: "user", Function: "W"},
+			},
+			true,
+			"W calls V (possibly, through reflection)",
+		},
+		{
+			[]*govulncheck.Frame{
+				{Module: "vuln", Function: "T", Kind: govulncheck.SymbolKindType},
+				{Module: "user", Function: "W"},
+			},
+			false,
+			"W uses T",
+		},
 	} {
 		tc := tc
 		t.Run(tc.want, func(t *testing.T) {
-			f := &govulncheck.Finding{Trace: tc.trace}
+			f := &govulncheck.Finding{Trace: tc.trace, Reflection: tc.reflection}
 			got := compactTrace(f)
 			if diff := cmp.Diff(tc.want, got); diff != "" {
 				t.Errorf("(-want got+) %s", diff)
@@ -0,0 +1,60 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// rawTerminal puts the terminal read by r in raw mode, in which keys
+// are read as they are pressed, with stty, and returns the function
+// restoring its mode along with its size. It returns ok false if r is
+// not a terminal whose mode stty can set, as on Windows.
+func rawTerminal(r io.Reader) (restore func(), rows, cols int, ok bool) {
+	f, isFile := r.(*os.File)
+	if !isFile {
+		return nil, 0, 0, false
+	}
+	stty := func(args ...string) (string, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = f
+		out, err := cmd.Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, 0, 0, false
+	}
+	if size, err := stty("size"); err == nil {
+		fmt.Sscan(size, &rows, &cols)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, 0, 0, false
+	}
+	return func() { stty(saved) }, rows, cols, true
+}
+
+// openBrowser opens url in the browser of the user.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/StevenACoffman/invuln/external"
 	"github.com/StevenACoffman/invuln/external/govulncheck"
@@ -40,6 +41,11 @@ type TextHandler struct {
 	findings  []*findingSummary
 	scanLevel govulncheck.ScanLevel
 	scanMode  govulncheck.ScanMode
+	omitted   string // the findings left out by -max-findings, if any
+
+	// remediate, if not nil, returns the upgrades fixing the
+	// findings passed, which are then shown after the summary.
+	remediate func([]*govulncheck.Finding) ([]ModuleUpgrade, error)
 
 	err error
 
@@ -54,6 +60,8 @@ const (
 
 	binaryProgressMessage = `Scanning your binary for known vulnerabilities...`
 
+	sbomProgressMessage = `Scanning your SBOM for known vulnerabilities...`
+
 	noVulnsMessage = `No vulnerabilities found.`
 
 	noOtherVulnsMessage = `No other vulnerabilities found.`
@@ -71,8 +79,19 @@ func (h *TextHandler) Flush() error {
 		h.print(noVulnsMessage + "\n")
 	} else {
 		fixupFindings(h.osvs, h.findings)
+		for _, f := range h.findings {
+			if err := f.OSV.Load(); err != nil {
+				return err
+			}
+		}
 		counters := h.allVulns(h.findings)
 		h.summary(counters)
+		if h.remediate != nil {
+			h.remediation()
+		}
+	}
+	if h.omitted != "" {
+		h.print("\n", h.omitted, "\n")
 	}
 	if h.err != nil {
 		return h.err
@@ -80,7 +99,7 @@ func (h *TextHandler) Flush() error {
 	// We found vulnerabilities when the findings' level matches the scan level.
 	if (isCalled(h.findings) && h.scanLevel == govulncheck.ScanLevelSymbol) ||
 		(isImported(h.findings) && h.scanLevel == govulncheck.ScanLevelPackage) ||
-		(isRequired(h.findings) && h.scanLevel == govulncheck.ScanLevelModule) {
+		(isRequired(h.findings) && !h.scanLevel.WantPackages()) {
 		return errVulnerabilitiesFound
 	}
 
@@ -92,33 +111,56 @@ func (h *TextHandler) Config(config *govulncheck.Config) error {
 	h.scanLevel = config.ScanLevel
 	h.scanMode = config.ScanMode
 
-	if !h.showVersion {
+	if config.ScanMode == govulncheck.ScanModeExtract {
+		// The output is the extracted blob.
 		return nil
 	}
-	if config.GoVersion != "" {
-		h.style(keyStyle, "Go: ")
-		h.print(config.GoVersion, "\n")
-	}
-	if config.ScannerName != "" {
-		h.style(keyStyle, "Scanner: ")
-		h.print(config.ScannerName)
-		if config.ScannerVersion != "" {
-			h.print(`@`, config.ScannerVersion)
+	if h.showVersion {
+		if config.GoVersion != "" {
+			h.style(keyStyle, "Go: ")
+			h.print(config.GoVersion, "\n")
+		}
+		if config.GOOS != "" || config.GOARCH != "" {
+			h.style(keyStyle, "Platform: ")
+			h.print(config.GOOS, "/", config.GOARCH, "\n")
+		}
+		if config.ScannerName != "" {
+			h.style(keyStyle, "Scanner: ")
+			h.print(config.ScannerName)
+			if config.ScannerVersion != "" {
+				h.print(`@`, config.ScannerVersion)
+			}
+			h.print("\n")
 		}
-		h.print("\n")
 	}
+	// The database is always reported, so that reports
+	// record which version of it they were produced with.
 	if config.DB != "" {
 		h.style(keyStyle, "DB: ")
 		h.print(config.DB, "\n")
 		if config.DBLastModified != nil {
 			h.style(keyStyle, "DB updated: ")
-			h.print(*config.DBLastModified, "\n")
+			h.print(*config.DBLastModified, " (", age(time.Since(*config.DBLastModified)), ")\n")
 		}
 	}
-	h.print("\n")
+	if h.showVersion || config.DB != "" {
+		h.print("\n")
+	}
 	return h.err
 }
 
+// age describes the duration d since a past time in days.
+func age(d time.Duration) string {
+	switch days := int(d.Hours() / 24); days {
+	case 0:
+		return "less than a day ago"
+	case 1:
+		return "1 day ago"
+	default:
+		return fmt.Sprintf("%d days ago", days)
+	}
+}
+
 func (h *TextHandler) SBOM(sbom *govulncheck.SBOM) error {
 	h.sbom = sbom
 	return nil
@@ -191,7 +233,7 @@ func (h *TextHandler) Finding(finding *govulncheck.Finding) error {
 
 func (h *TextHandler) allVulns(findings []*findingSummary) summaryCounters {
 	byVuln := groupByVuln(findings)
-	var called, imported, required [][]*findingSummary
+	var called, referenced, imported, required [][]*findingSummary
 	mods := map[string]struct{}{}
 	stdlibCalled := false
 	for _, findings := range byVuln {
@@ -203,6 +245,8 @@ func (h *TextHandler) allVulns(findings []*findingSummary) summaryCounters {
 			} else {
 				mods[findings[0].Trace[0].Module] = struct{}{}
 			}
+		case isReferenced(findings):
+			referenced = append(referenced, findings)
 		case isImported(findings):
 			imported = append(imported, findings)
 		default:
@@ -218,6 +262,12 @@ func (h *TextHandler) allVulns(findings []*findingSummary) summaryCounters {
 		for index, findings := range called {
 			h.vulnerability(index, findings)
 		}
+		if len(referenced) > 0 {
+			h.style(sectionStyle, "=== Reference Results ===\n\n")
+			for index, findings := range referenced {
+				h.vulnerability(index, findings)
+			}
+		}
 	}
 
 	if h.scanLevel == govulncheck.ScanLevelPackage || (h.scanLevel.WantPackages() && h.showVerbose) {
@@ -230,7 +280,7 @@ func (h *TextHandler) allVulns(findings []*findingSummary) summaryCounters {
 		}
 	}
 
-	if h.showVerbose || h.scanLevel == govulncheck.ScanLevelModule {
+	if h.showVerbose || !h.scanLevel.WantPackages() {
 		h.style(sectionStyle, "=== Module Results ===\n\n")
 		if len(required) == 0 {
 			h.print(choose(!h.scanLevel.WantPackages(), noVulnsMessage, noOtherVulnsMessage), "\n\n")
@@ -241,11 +291,12 @@ func (h *TextHandler) allVulns(findings []*findingSummary) summaryCounters {
 	}
 
 	return summaryCounters{
-		VulnerabilitiesCalled:   len(called),
-		VulnerabilitiesImported: len(imported),
-		VulnerabilitiesRequired: len(required),
-		ModulesCalled:           len(mods),
-		StdlibCalled:            stdlibCalled,
+		VulnerabilitiesCalled:     len(called),
+		VulnerabilitiesReferenced: len(referenced),
+		VulnerabilitiesImported:   len(imported),
+		VulnerabilitiesRequired:   len(required),
+		ModulesCalled:             len(mods),
+		StdlibCalled:              stdlibCalled,
 	}
 }
 
@@ -266,8 +317,32 @@ func (h *TextHandler) vulnerability(index int, findings []*findingSummary) {
 	h.wrap("    ", description, 80)
 	h.style(defaultStyle)
 	h.print("\n")
-	h.style(keyStyle, "  More info:")
-	h.print(" ", findings[0].OSV.DatabaseSpecific.URL, "\n")
+	ds := findings[0].OSV.DatabaseSpecific
+	if ds == nil {
+		// Entries not read from a database, such as those
+		// of NewInMemoryClient, may have no specific fields.
+		ds = &osv.DatabaseSpecific{}
+	}
+	if url := ds.URL; url != "" {
+		h.style(keyStyle, "  More info:")
+		h.print(" ", url, "\n")
+	}
+	if source := ds.Source; source != "" {
+		h.style(keyStyle, "  Database:")
+		h.print(" ", source, "\n")
+	}
+	if severity := ds.Severity; severity != "" {
+		h.style(keyStyle, "  Severity:")
+		h.print(" ", severity, "\n")
+	}
+	if priority := ds.Priority; priority != "" {
+		h.style(keyStyle, "  Priority:")
+		h.print(" ", priority, "\n")
+	}
+	if enrichment := ds.Enrichment; len(enrichment) > 0 {
+		h.style(keyStyle, "  Enriched from:")
+		h.print(" ", strings.Join(enrichment, ", "), "\n")
+	}
 
 	byModule := groupByModule(findings)
 	first := true
@@ -301,7 +376,11 @@ func (h *TextHandler) vulnerability(index int, findings []*findingSummary) {
 		}
 		h.print("\n    ")
 		h.style(keyStyle, "Found in: ")
-		h.print(path, "@", foundVersion, "\n    ")
+		if foundVersion != "" {
+			h.print(path, "@", foundVersion, "\n    ")
+		} else {
+			h.print(path, " (unknown version)\n    ")
+		}
 		h.style(keyStyle, "Fixed in: ")
 		if fixedVersion != "" {
 			h.print(path, "@", fixedVersion)
@@ -320,6 +399,14 @@ func (h *TextHandler) vulnerability(index int, findings []*findingSummary) {
 			}
 			h.print("\n")
 		}
+		if artifacts := artifacts(module); len(artifacts) > 0 {
+			h.style(keyStyle, "    Artifacts: ")
+			h.print(strings.Join(artifacts, ", "), "\n")
+		}
+		if ps := findingPlatforms(module); len(ps) > 0 {
+			h.style(keyStyle, "    Found for: ")
+			h.print(strings.Join(ps, ", "), "\n")
+		}
 		h.traces(module)
 	}
 	h.print("\n")
@@ -341,10 +428,14 @@ func (h *TextHandler) pkg(summaries []*findingSummary) string {
 // traces prints out the most precise trace information
 // found in the given summaries.
 func (h *TextHandler) traces(traces []*findingSummary) {
-	// Sort the traces by the vulnerable symbol. This
-	// guarantees determinism since we are currently
-	// showing only one trace per symbol.
+	// Sort the traces by decreasing confidence, then by
+	// the vulnerable symbol. This guarantees determinism
+	// since we are currently showing only one trace per
+	// symbol.
 	sort.SliceStable(traces, func(i, j int) bool {
+		if ci, cj := traces[i].Confidence, traces[j].Confidence; ci != cj {
+			return cj.Less(ci)
+		}
 		return symbol(traces[i].Trace[0], true) < symbol(traces[j].Trace[0], true)
 	})
 
@@ -352,10 +443,20 @@ func (h *TextHandler) traces(traces []*findingSummary) {
 	// suitable for non-verbose textual output. Currently,
 	// only traces produced by symbol analysis.
 	var compacts []*findingSummary
+	seen := make(map[string]bool) // compact traces of artifacts
 	for _, t := range traces {
-		if t.Compact != "" {
-			compacts = append(compacts, t)
+		if t.Compact == "" {
+			continue
+		}
+		// The same symbol can be found in several artifacts,
+		// which are listed separately, so show it only once.
+		if t.Artifact != "" {
+			if seen[t.Compact] {
+				continue
+			}
+			seen[t.Compact] = true
 		}
+		compacts = append(compacts, t)
 	}
 
 	// binLimit is a limit on the number of binary traces
@@ -383,15 +484,38 @@ func (h *TextHandler) traces(traces []*findingSummary) {
 
 		if !h.showTraces { // show summarized traces
 			h.print(entry.Compact, "\n")
+			h.exposure(entry.Finding)
 			continue
 		}
 
 		if binary {
 			// There are no call stacks in binary mode
 			// so just show the full symbol name.
-			h.print(symbol(entry.Trace[0], false), "\n")
+			h.print(symbol(entry.Trace[0], false))
+			if entry.Trace[0].Kind == govulncheck.SymbolKindLinked {
+				h.print(" (linked into the binary)")
+			}
+			h.print("\n")
 		} else {
-			h.print("for function ", symbol(entry.Trace[0], false), "\n")
+			kind := "function"
+			if k := entry.Trace[0].Kind; k != "" && k != govulncheck.SymbolKindFunc {
+				kind = string(k)
+			}
+			h.print("for ", kind, " ", symbol(entry.Trace[0], false))
+			if entry.Reflection {
+				h.print(" (possibly, through reflection)")
+			}
+			h.print(linkageNote(entry.Linkage))
+			if entry.Partial {
+				h.print(" (partial trace)")
+			}
+			if pkg := initPackage(entry.Finding); pkg != "" {
+				h.print(" (via package initialization of ", pkg, ")")
+			}
+			if len(entry.Taint) > 0 {
+				h.print(" (with ", taintSources(entry.Taint), ")")
+			}
+			h.print("\n")
 			for i := len(entry.Trace) - 1; i >= 0; i-- {
 				t := entry.Trace[i]
 				h.print("        ")
@@ -399,12 +523,24 @@ func (h *TextHandler) traces(traces []*findingSummary) {
 				if t.Position != nil {
 					h.print(" @ ", symbolPath(t))
 				}
+				if t.BuildConstraint != "" {
+					h.print(" (//go:build ", t.BuildConstraint, ")")
+				}
 				h.print("\n")
 			}
+			h.exposure(entry.Finding)
 		}
 	}
 }
 
+// exposure prints the exported APIs of a scanned
+// library from which the symbol of finding is reachable.
+func (h *TextHandler) exposure(finding *govulncheck.Finding) {
+	if len(finding.ExposedBy) > 0 {
+		h.print("        Exposed by: ", strings.Join(finding.ExposedBy, ", "), "\n")
+	}
+}
+
 // symbolPath returns a user-friendly path to a symbol.
 func symbolPath(t *govulncheck.Frame) string {
 	// Add module path prefix to symbol paths to be more
@@ -421,11 +557,14 @@ func (h *TextHandler) summary(c summaryCounters) {
 		vulnCount = c.VulnerabilitiesCalled
 	case govulncheck.ScanLevelPackage:
 		vulnCount = c.VulnerabilitiesImported
-	case govulncheck.ScanLevelModule:
+	case govulncheck.ScanLevelModule, govulncheck.ScanLevelStdlib:
 		vulnCount = c.VulnerabilitiesRequired
 	}
 	h.style(valueStyle, vulnCount)
 	h.print(choose(vulnCount == 1, ` vulnerability`, ` vulnerabilities`))
+	if h.scanLevel == govulncheck.ScanLevelStdlib {
+		h.print(` from the Go standard library`)
+	}
 	if h.scanLevel.WantSymbols() {
 		h.print(choose(c.ModulesCalled > 0 || c.StdlibCalled, ` from `, ``))
 		if c.ModulesCalled > 0 {
@@ -442,6 +581,11 @@ func (h *TextHandler) summary(c summaryCounters) {
 	h.print(".\n")
 
 	// print summary for vulnerabilities found at other levels of scan precision
+	if h.scanLevel.WantSymbols() && c.VulnerabilitiesReferenced > 0 {
+		h.wrap("", fmt.Sprintf("Your code also references the vulnerable functions of %d %s, but doesn't appear to call them.",
+			c.VulnerabilitiesReferenced, choose(c.VulnerabilitiesReferenced == 1, "vulnerability", "vulnerabilities")), 80)
+		h.print("\n")
+	}
 	if other := h.summaryOtherVulns(c); other != "" {
 		h.wrap("", other, 80)
 		h.print("\n")
@@ -454,7 +598,64 @@ func (h *TextHandler) summary(c summaryCounters) {
 	}
 }
 
+// remediation prints the upgrades fixing the vulnerabilities
+// found at the level of the scan.
+func (h *TextHandler) remediation() {
+	var findings []*govulncheck.Finding
+	for _, fs := range groupByVuln(h.findings) {
+		switch {
+		case h.scanLevel == govulncheck.ScanLevelSymbol && !isCalled(fs),
+			h.scanLevel == govulncheck.ScanLevelPackage && !isImported(fs):
+			continue
+		}
+		for _, f := range fs {
+			findings = append(findings, f.Finding)
+		}
+	}
+	if len(findings) == 0 {
+		return
+	}
+	h.print("\n")
+	h.style(sectionStyle, "=== Remediation ===\n\n")
+	plan, err := h.remediate(findings)
+	if err != nil {
+		h.print("The upgrades fixing the vulnerabilities could not be computed: ", err, "\n")
+		return
+	}
+	var args []string
+	fixes := 0
+	for _, u := range plan {
+		if u.To != "" {
+			args = append(args, u.GoGetArg())
+			fixes += len(u.Fixes)
+		}
+	}
+	if len(args) > 0 {
+		h.print(choose(len(args) == 1, "This upgrade fixes ", "These upgrades fix "), fixes, choose(fixes == 1, " vulnerability", " vulnerabilities"), ":\n\n")
+		for _, u := range plan {
+			if u.To == "" {
+				continue
+			}
+			h.print("  ", u.Path, " ")
+			if u.From != "" {
+				h.print(moduleVersionString(u.Path, u.From), " ")
+			}
+			h.print("=> ", moduleVersionString(u.Path, u.To), " (", strings.Join(u.Fixes, ", "), ")\n")
+		}
+		h.print("\nTo apply ", choose(len(args) == 1, "it", "them"), ", run:\n\n  go get ", strings.Join(args, " "), "\n")
+	}
+	for _, u := range plan {
+		if len(u.Unfixed) > 0 {
+			h.print("\nNo fixed version is known for ", strings.Join(u.Unfixed, ", "), " of ", u.Path, ".\n")
+		}
+	}
+}
+
 func (h *TextHandler) summaryOtherVulns(c summaryCounters) string {
+	if h.scanLevel == govulncheck.ScanLevelStdlib {
+		// Only the standard library was checked.
+		return ""
+	}
 	var summary strings.Builder
 	if c.VulnerabilitiesRequired+c.VulnerabilitiesImported == 0 {
 		summary.WriteString("This scan found no other vulnerabilities in ")
@@ -481,6 +682,13 @@ func (h *TextHandler) summaryOtherVulns(c summaryCounters) string {
 
 func (h *TextHandler) summarySuggestion() string {
 	var sugg strings.Builder
+	if h.scanMode == govulncheck.ScanModeSBOM {
+		// An SBOM has no code, so symbol level scanning is not an option.
+		if !h.showVerbose {
+			sugg.WriteString("Use " + verboseMessage + ".")
+		}
+		return sugg.String()
+	}
 	switch h.scanLevel {
 	case govulncheck.ScanLevelSymbol:
 		if !h.showVerbose {
@@ -492,7 +700,7 @@ func (h *TextHandler) summarySuggestion() string {
 			sugg.WriteString(" and " + verboseMessage)
 		}
 		sugg.WriteString(".")
-	case govulncheck.ScanLevelModule:
+	case govulncheck.ScanLevelModule, govulncheck.ScanLevelStdlib:
 		sugg.WriteString("Use " + symbolMessage + ".")
 	}
 	return sugg.String()
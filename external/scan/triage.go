@@ -0,0 +1,475 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/openvex"
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// triageHelp lists the keys of the triage mode.
+const triageHelp = "up/down or k/j: move  enter: expand  o: open advisory  s: suppress  f: fix  u: undo  q: save and quit"
+
+// justifications are the justifications of suppressed vulnerabilities,
+// which pressing s again cycles through.
+var justifications = []string{
+	openvex.JustificationNotExecuted,
+	openvex.JustificationNotPresent,
+	openvex.JustificationNotControllable,
+	openvex.JustificationMitigated,
+}
+
+// A triageHandler gathers the findings of a scan, and then presents
+// them in a terminal UI, for -tui, to decide whether to suppress or fix
+// each vulnerability found. The decisions are written to the OpenVEX
+// document vex, from which the decisions of previous triages are read.
+type triageHandler struct {
+	r    io.Reader
+	w    io.Writer
+	vex  string
+	open func(url string) error // opens the advisories
+
+	scanLevel govulncheck.ScanLevel
+	osvs      []*osv.Entry
+	findings  []*findingSummary
+}
+
+func newTriageHandler(cfg *config, r io.Reader, w io.Writer) *triageHandler {
+	return &triageHandler{r: r, w: w, vex: cfg.tuiVEX, open: openBrowser}
+}
+
+func (h *triageHandler) Config(c *govulncheck.Config) error {
+	h.scanLevel = c.ScanLevel
+	return nil
+}
+
+func (h *triageHandler) SBOM(*govulncheck.SBOM) error { return nil }
+
+func (h *triageHandler) Progress(*govulncheck.Progress) error { return nil }
+
+func (h *triageHandler) OSV(entry *osv.Entry) error {
+	h.osvs = append(h.osvs, entry)
+	return nil
+}
+
+func (h *triageHandler) Finding(finding *govulncheck.Finding) error {
+	if err := validateFindings(finding); err != nil {
+		return err
+	}
+	h.findings = append(h.findings, newFindingSummary(finding))
+	return nil
+}
+
+// Flush runs the triage of the findings, once the scan is done.
+func (h *triageHandler) Flush() error {
+	if len(h.findings) == 0 {
+		fmt.Fprintln(h.w, noVulnsMessage)
+		return nil
+	}
+	fixupFindings(h.osvs, h.findings)
+	for _, f := range h.findings {
+		if err := f.OSV.Load(); err != nil {
+			return err
+		}
+	}
+	t := newTriage(h.scanLevel, h.findings)
+	if err := t.load(h.vex); err != nil {
+		return err
+	}
+	restore, rows, cols, raw := rawTerminal(h.r)
+	t.rows, t.cols, t.raw = rows, cols, raw
+	err := t.run(bufio.NewReader(h.r), h.w, h.open)
+	if raw {
+		restore()
+	}
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(openvex.NewDocument(t.statements()), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(h.vex, append(out, '\n'), 0o666); err != nil {
+		return err
+	}
+	fmt.Fprintf(h.w, "Wrote the decisions on %d of %d %s to %s.\n",
+		t.decided(), len(t.items), choose(len(t.items) == 1, "vulnerability", "vulnerabilities"), h.vex)
+	for _, it := range t.items {
+		if it.affects && it.status != openvex.StatusNotAffected {
+			return errVulnerabilitiesFound
+		}
+	}
+	return nil
+}
+
+// A triage is the state of the terminal UI of the triage mode.
+type triage struct {
+	items   []*triageItem
+	cursor  int    // index of the selected item
+	top     int    // first line shown
+	message string // shown below the items
+
+	rows, cols int  // of the terminal, or 0 if unknown
+	raw        bool // whether keys are read as pressed, or by line
+
+	// others are the statements of the previous triage on
+	// vulnerabilities not found by this scan, which are kept.
+	others []openvex.Statement
+}
+
+// A triageItem is a vulnerability found, along with the decision on it.
+type triageItem struct {
+	entry    *osv.Entry
+	findings []*findingSummary
+	level    string // called, referenced, imported, or required
+	affects  bool   // whether found at the level of the scan
+	expanded bool
+
+	status        string // VEX status, or "" if undecided
+	justification string // of not_affected
+	action        string // of affected
+}
+
+func newTriage(scanLevel govulncheck.ScanLevel, findings []*findingSummary) *triage {
+	t := &triage{}
+	for _, group := range groupByVuln(findings) {
+		it := &triageItem{entry: group[0].OSV, findings: group, level: "required", affects: true}
+		switch {
+		case isCalled(group):
+			it.level = "called"
+		case isReferenced(group):
+			it.level = "referenced"
+		case isImported(group):
+			it.level = "imported"
+		}
+		switch scanLevel {
+		case govulncheck.ScanLevelSymbol:
+			it.affects = it.level == "called"
+		case govulncheck.ScanLevelPackage:
+			it.affects = it.level != "required"
+		}
+		t.items = append(t.items, it)
+	}
+	// The vulnerabilities affecting the code come first.
+	sort.SliceStable(t.items, func(i, j int) bool {
+		if t.items[i].affects != t.items[j].affects {
+			return t.items[i].affects
+		}
+		return t.items[i].entry.ID < t.items[j].entry.ID
+	})
+	return t
+}
+
+// load sets the decisions of the previous triage recorded in the
+// OpenVEX document in file, if any.
+func (t *triage) load(file string) error {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var doc openvex.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("reading the decisions of %s: %v", file, err)
+	}
+	for _, s := range doc.Statements {
+		found := false
+		for _, it := range t.items {
+			if it.entry.ID != s.Vulnerability.Name {
+				continue
+			}
+			found = true
+			if s.Status != openvex.StatusUnderInvestigation {
+				it.status, it.justification, it.action = s.Status, s.Justification, s.ActionStatement
+			}
+		}
+		if !found {
+			t.others = append(t.others, s)
+		}
+	}
+	return nil
+}
+
+// run presents the items, reading keys from r, until the
+// user quits, or r ends.
+func (t *triage) run(r *bufio.Reader, w io.Writer, open func(string) error) error {
+	for {
+		t.render(w)
+		keys, err := readKeys(r, t.raw)
+		for _, k := range keys {
+			if k == "q" {
+				return nil
+			}
+			t.key(k, open)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readKeys reads the next key pressed, or, if not raw,
+// the keys of the next line, where an empty line is enter.
+func readKeys(r *bufio.Reader, raw bool) ([]string, error) {
+	name := func(b byte) string {
+		switch b {
+		case 'j':
+			return "down"
+		case 'k':
+			return "up"
+		case '\r', '\n', ' ':
+			return "enter"
+		case 3: // Ctrl-C
+			return "q"
+		}
+		return string(b)
+	}
+	if !raw {
+		line, err := r.ReadString('\n')
+		if line == "" {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return []string{"enter"}, err
+		}
+		var keys []string
+		for i := 0; i < len(line); i++ {
+			keys = append(keys, name(line[i]))
+		}
+		return keys, err
+	}
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if b != 0x1b { // escape
+		return []string{name(b)}, nil
+	}
+	// Arrow keys are sent as ESC [ A to D.
+	if b, err := r.ReadByte(); err != nil || b != '[' {
+		return nil, err
+	}
+	b, err = r.ReadByte()
+	switch b {
+	case 'A':
+		return []string{"up"}, err
+	case 'B':
+		return []string{"down"}, err
+	}
+	return nil, err
+}
+
+// key handles the key k, opening the advisories with open.
+func (t *triage) key(k string, open func(string) error) {
+	it := t.items[t.cursor]
+	t.message = ""
+	switch k {
+	case "up":
+		if t.cursor > 0 {
+			t.cursor--
+		}
+	case "down":
+		if t.cursor < len(t.items)-1 {
+			t.cursor++
+		}
+	case "enter":
+		it.expanded = !it.expanded
+	case "o":
+		url := advisoryURL(it.entry.ID)
+		if err := open(url); err != nil {
+			t.message = "See " + url
+		}
+	case "s":
+		// Pressing s again picks the next justification.
+		j := 0
+		if it.status == openvex.StatusNotAffected {
+			j = (slices.Index(justifications, it.justification) + 1) % len(justifications)
+		}
+		it.status, it.justification, it.action = openvex.StatusNotAffected, justifications[j], ""
+		t.message = fmt.Sprintf("Suppressed %s as %s (press s again for another justification).", it.entry.ID, it.justification)
+	case "f":
+		it.status, it.justification, it.action = openvex.StatusAffected, "", it.fixAction()
+		t.message = it.action
+	case "u":
+		it.status, it.justification, it.action = "", "", ""
+	default:
+		t.message = triageHelp
+	}
+}
+
+func advisoryURL(id string) string {
+	return "https://pkg.go.dev/vuln/" + id
+}
+
+// fixAction returns the action statement of fixing the vulnerability.
+func (it *triageItem) fixAction() string {
+	f := it.findings[0]
+	mod := f.Trace[0].Module
+	if f.FixedVersion == "" {
+		return fmt.Sprintf("No version of %s fixes %s: stop using the vulnerable code.", mod, it.entry.ID)
+	}
+	return fmt.Sprintf("Upgrade %s to %s.", mod, moduleVersionString(mod, f.FixedVersion))
+}
+
+// render draws the items, scrolled to show the selected one.
+func (t *triage) render(w io.Writer) {
+	var lines []string
+	var selected int
+	for i, it := range t.items {
+		if i == t.cursor {
+			selected = len(lines)
+		}
+		lines = append(lines, t.line(i))
+		if it.expanded {
+			lines = append(lines, it.details()...)
+		}
+	}
+	height := len(lines)
+	if t.rows > 0 {
+		// Leave room for the header and the message.
+		height = max(t.rows-4, 1)
+	}
+	if selected < t.top {
+		t.top = selected
+	} else if selected >= t.top+height {
+		t.top = selected - height + 1
+	}
+	lines = lines[t.top:min(t.top+height, len(lines))]
+
+	nl := "\n"
+	if t.raw {
+		nl = "\r\n"
+	}
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J") // clear the screen
+	fmt.Fprintf(&b, "Triage of %d %s. %s%s%s", len(t.items), choose(len(t.items) == 1, "vulnerability", "vulnerabilities"), triageHelp, nl, nl)
+	for _, l := range lines {
+		b.WriteString(t.truncate(l))
+		b.WriteString(nl)
+	}
+	if t.message != "" {
+		b.WriteString(nl)
+		b.WriteString(t.truncate(t.message))
+		b.WriteString(nl)
+	}
+	io.WriteString(w, b.String())
+}
+
+// truncate truncates line to the width of the terminal.
+func (t *triage) truncate(line string) string {
+	if r := []rune(line); t.cols > 0 && len(r) > t.cols {
+		return string(r[:t.cols])
+	}
+	return line
+}
+
+// line returns the line of the item i.
+func (t *triage) line(i int) string {
+	it := t.items[i]
+	cursor := "  "
+	if i == t.cursor {
+		cursor = "> "
+	}
+	decision := "[ ]"
+	switch it.status {
+	case openvex.StatusNotAffected:
+		decision = "[S]"
+	case openvex.StatusAffected:
+		decision = "[F]"
+	}
+	f := it.findings[0].Trace[0]
+	return fmt.Sprintf("%s%s %s %-10s %s@%s  %s", cursor, decision, it.entry.ID, it.level,
+		f.Module, moduleVersionString(f.Module, f.Version), it.entry.Summary)
+}
+
+// details returns the lines shown under the expanded item.
+func (it *triageItem) details() []string {
+	f := it.findings[0]
+	mod := f.Trace[0].Module
+	fixed := "N/A"
+	if f.FixedVersion != "" {
+		fixed = mod + "@" + moduleVersionString(mod, f.FixedVersion)
+	}
+	lines := []string{
+		"      Found in: " + mod + "@" + moduleVersionString(mod, f.Trace[0].Version),
+		"      Fixed in: " + fixed,
+	}
+	switch it.status {
+	case openvex.StatusNotAffected:
+		lines = append(lines, "      Suppressed as "+it.justification)
+	case openvex.StatusAffected:
+		lines = append(lines, "      To fix: "+it.action)
+	}
+	if it.level == "called" {
+		n := 0
+		for _, s := range it.findings {
+			if s.Compact == "" {
+				continue
+			}
+			n++
+			lines = append(lines, fmt.Sprintf("      #%d: for function %s", n, symbol(s.Trace[0], false)))
+			for i := len(s.Trace) - 1; i >= 0; i-- {
+				fr := s.Trace[i]
+				l := "          " + symbolName(fr)
+				if fr.Position != nil {
+					l += " @ " + symbolPath(fr)
+				}
+				lines = append(lines, l)
+			}
+		}
+	}
+	return append(lines, "      More info: "+advisoryURL(it.entry.ID))
+}
+
+// decided returns the number of items decided on.
+func (t *triage) decided() int {
+	n := 0
+	for _, it := range t.items {
+		if it.status != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// statements returns the statements of the decisions on the items,
+// those not decided on being under investigation, along with those
+// of the previous triage on the vulnerabilities not found.
+func (t *triage) statements() []openvex.Statement {
+	ss := slices.Clone(t.others)
+	for _, it := range t.items {
+		var findings []*govulncheck.Finding
+		for _, f := range it.findings {
+			findings = append(findings, f.Finding)
+		}
+		status := it.status
+		if status == "" {
+			status = openvex.StatusUnderInvestigation
+		}
+		s := openvex.NewStatement(it.entry, findings, status)
+		s.Justification, s.ActionStatement = it.justification, it.action
+		ss = append(ss, s)
+	}
+	sort.Slice(ss, func(i, j int) bool { return ss[i].Vulnerability.Name < ss[j].Vulnerability.Name })
+	return ss
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/openvex"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTriage(t *testing.T) {
+	vex := filepath.Join(t.TempDir(), "govulncheck.vex.json")
+	triage := func(keys string) (output string, opened []string, err error) {
+		t.Helper()
+		var buf bytes.Buffer
+		h := &triageHandler{r: strings.NewReader(keys), w: &buf, vex: vex, open: func(url string) error {
+			opened = append(opened, url)
+			return nil
+		}}
+		h.Config(&govulncheck.Config{ScanLevel: govulncheck.ScanLevelSymbol})
+		h.OSV(&osv.Entry{ID: "GO-0000-0001", Summary: "Imported vulnerability"})
+		h.OSV(&osv.Entry{ID: "GO-0000-0002", Summary: "Called vulnerability"})
+		for _, f := range []*govulncheck.Finding{
+			{OSV: "GO-0000-0001", FixedVersion: "v1.0.1", Trace: []*govulncheck.Frame{{Module: "example.com/a", Version: "v1.0.0", Package: "example.com/a/p"}}},
+			{OSV: "GO-0000-0002", FixedVersion: "v1.2.0", Trace: []*govulncheck.Frame{
+				{Module: "example.com/b", Version: "v1.0.0", Package: "example.com/b", Function: "Parse"},
+				{Module: "example.com/m", Package: "example.com/m", Function: "main"},
+			}},
+		} {
+			if err := h.Finding(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = h.Flush()
+		return buf.String(), opened, err
+	}
+	decisions := func() map[string]openvex.Statement {
+		t.Helper()
+		data, err := os.ReadFile(vex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc openvex.Document
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		m := make(map[string]openvex.Statement)
+		for _, s := range doc.Statements {
+			name := s.Vulnerability.Name
+			s.Vulnerability, s.Products = openvex.Vulnerability{}, nil
+			m[name] = s
+		}
+		return m
+	}
+
+	// The called vulnerability comes first: expand it, open its
+	// advisory, and fix it, and suppress the other one with the
+	// second justification.
+	out, opened, err := triage("\no\nf\nj\nss\nq\n")
+	if err != errVulnerabilitiesFound {
+		t.Errorf("got error %v; want %v", err, errVulnerabilitiesFound)
+	}
+	for _, want := range []string{
+		"> [ ] GO-0000-0002 called     example.com/b@v1.0.0  Called vulnerability",
+		"Fixed in: example.com/b@v1.2.0",
+		"example.com/b.Parse",
+		"Wrote the decisions on 2 of 2 vulnerabilities to " + vex,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+	if want := []string{"https://pkg.go.dev/vuln/GO-0000-0002"}; !cmp.Equal(opened, want) {
+		t.Errorf("opened %v; want %v", opened, want)
+	}
+	want := map[string]openvex.Statement{
+		"GO-0000-0001": {Status: openvex.StatusNotAffected, Justification: openvex.JustificationNotPresent},
+		"GO-0000-0002": {Status: openvex.StatusAffected, ActionStatement: "Upgrade example.com/b to v1.2.0."},
+	}
+	if diff := cmp.Diff(want, decisions()); diff != "" {
+		t.Errorf("decisions mismatch (-want, +got):\n%s", diff)
+	}
+
+	// The decisions are kept by the next triage, until changed.
+	out, _, err = triage("u\n")
+	if err != errVulnerabilitiesFound {
+		t.Errorf("got error %v; want %v", err, errVulnerabilitiesFound)
+	}
+	if !strings.Contains(out, "[S] GO-0000-0001") {
+		t.Errorf("suppressed vulnerability not shown as such:\n%s", out)
+	}
+	want["GO-0000-0002"] = openvex.Statement{Status: openvex.StatusUnderInvestigation}
+	if diff := cmp.Diff(want, decisions()); diff != "" {
+		t.Errorf("decisions mismatch (-want, +got):\n%s", diff)
+	}
+
+	// The decisions on vulnerabilities not found by the scan, say
+	// in modules since upgraded, are kept as well.
+	data, err := os.ReadFile(vex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc openvex.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	other := openvex.NewStatement(&osv.Entry{ID: "GO-0000-0009"}, nil, openvex.StatusNotAffected)
+	other.Justification = openvex.JustificationMitigated
+	doc.Statements = append(doc.Statements, other)
+	if data, err = json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(vex, data, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	out, _, err = triage("q\n")
+	if err != errVulnerabilitiesFound {
+		t.Errorf("got error %v; want %v", err, errVulnerabilitiesFound)
+	}
+	if strings.Contains(out, "GO-0000-0009") {
+		t.Errorf("vulnerability not found shown:\n%s", out)
+	}
+	want["GO-0000-0009"] = openvex.Statement{Status: openvex.StatusNotAffected, Justification: openvex.JustificationMitigated}
+	if diff := cmp.Diff(want, decisions()); diff != "" {
+		t.Errorf("decisions mismatch (-want, +got):\n%s", diff)
+	}
+}
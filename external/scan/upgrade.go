@@ -0,0 +1,474 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// remediationPlan returns the upgrades fixing findings, shown with
+// -show remediation: those of minimalUpgrades for the module of source
+// scans, and those of FixPlan otherwise.
+func remediationPlan(ctx context.Context, cfg *config, findings []*govulncheck.Finding) ([]ModuleUpgrade, error) {
+	if cfg.ScanMode == govulncheck.ScanModeSource && !cfg.gopath && cfg.importcfg == "" && cfg.pkgs == nil {
+		return minimalUpgrades(ctx, cfg.env, sourceDir(cfg), findings)
+	}
+	return FixPlan(findings)
+}
+
+// maxExactCandidates is the number of modules up to which
+// minimalUpgrades searches all their combinations for the smallest
+// set of upgrades, and above which it settles for a small one.
+const maxExactCandidates = 20
+
+// minimalUpgrades returns the smallest set of upgrades of the
+// requirements of the main module in dir clearing findings, as listed
+// by the go command run with env, in the order of the module paths.
+//
+// FixPlan upgrades each vulnerable module, adding the modules only
+// required indirectly to the requirements of the main module, while
+// upgrading one of its direct requirements often raises several of
+// them at once, through minimal version selection. Each direct
+// requirement leading to a vulnerable module is therefore tried at
+// its later versions, by upgrading it with go get in a scratch copy of
+// go.mod, to find the earliest one raising each vulnerable module to
+// a fixed version. Of the sets of upgrades clearing all the findings,
+// the smallest is returned, preferring upgrades of direct
+// requirements to new requirements. The upgrades of the standard
+// library and of the go command, and the vulnerabilities without
+// fixed versions, are those of FixPlan.
+//
+// Raising the version of a module is assumed to never lower the
+// versions it selects for the others, as minimal version selection
+// guarantees, so that the earliest versions are found by bisection.
+func minimalUpgrades(ctx context.Context, env []string, dir string, findings []*govulncheck.Finding) ([]ModuleUpgrade, error) {
+	plan, err := FixPlan(findings)
+	if err != nil {
+		return nil, err
+	}
+	targets := make(map[string]*ModuleUpgrade) // by path
+	for i := range plan {
+		u := &plan[i]
+		if u.To != "" && u.Path != external.GoStdModulePath && u.Path != external.GoCmdModulePath {
+			targets[u.Path] = u
+		}
+	}
+	if len(targets) == 0 {
+		return plan, nil
+	}
+	s, err := newUpgradeSolver(ctx, env, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(s.tmp)
+	cands, err := s.candidates(targets)
+	if err != nil {
+		return nil, err
+	}
+	return s.upgrades(plan, targets, cover(cands, slices.Sorted(maps.Keys(targets)))), nil
+}
+
+// An upgradeSolver tries upgrades of the requirements of a main module.
+type upgradeSolver struct {
+	ctx   context.Context
+	env   []string
+	dir   string // of go.mod
+	gomod []byte
+	gosum []byte
+	tmp   string // scratch directory
+
+	direct   map[string]string            // versions of the direct requirements, by path
+	selected map[string]map[string]string // versions selected after an upgrade, by path@version and path
+}
+
+func newUpgradeSolver(ctx context.Context, env []string, dir string) (*upgradeSolver, error) {
+	if env == nil {
+		env = os.Environ()
+	}
+	// The module is upgraded on its own, with the flags
+	// of go get, in copies of go.mod and go.sum.
+	env = append(slices.Clip(env), "GOWORK=off", "GOFLAGS=")
+	s := &upgradeSolver{ctx: ctx, env: env, dir: dir, selected: make(map[string]map[string]string)}
+	out, err := s.goCmd("env", "GOMOD")
+	if err != nil {
+		return nil, err
+	}
+	gomod := strings.TrimSpace(string(out))
+	if gomod == "" || gomod == os.DevNull {
+		return nil, errors.New("no go.mod file found")
+	}
+	s.dir = filepath.Dir(gomod)
+	if s.gomod, err = os.ReadFile(gomod); err != nil {
+		return nil, err
+	}
+	if s.gosum, err = os.ReadFile(filepath.Join(s.dir, "go.sum")); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	f, err := modfile.ParseLax(gomod, s.gomod, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.direct = make(map[string]string)
+	for _, r := range f.Require {
+		if !r.Indirect {
+			s.direct[r.Mod.Path] = r.Mod.Version
+		}
+	}
+	if s.tmp, err = os.MkdirTemp("", "govulncheck-upgrade"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// goCmd runs the go command with args in the directory of go.mod.
+func (s *upgradeSolver) goCmd(args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(s.ctx, "go", args...)
+	cmd.Dir = s.dir
+	cmd.Env = s.env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go %s: %v\n%s", strings.Join(args, " "), err, stderr.Bytes())
+	}
+	return out, nil
+}
+
+// scratch returns the -modfile flag of a fresh copy of go.mod and go.sum.
+func (s *upgradeSolver) scratch() (string, error) {
+	if err := os.WriteFile(filepath.Join(s.tmp, "go.mod"), s.gomod, 0o666); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(s.tmp, "go.sum"), s.gosum, 0o666); err != nil {
+		return "", err
+	}
+	return "-modfile=" + filepath.Join(s.tmp, "go.mod"), nil
+}
+
+// An upgradeCandidate is a module whose upgrade clears the findings
+// of vulnerable modules.
+type upgradeCandidate struct {
+	path string
+	// direct is whether the module is a requirement of the main
+	// module, as opposed to a vulnerable module only required
+	// indirectly, which its upgrade adds to the requirements.
+	direct bool
+	// clears holds the earliest version clearing the findings of
+	// each vulnerable module cleared, by the path of the latter.
+	clears map[string]string
+}
+
+// candidates returns the modules whose upgrades clear the findings
+// of targets: each of targets itself, and the direct requirements
+// leading to them, in the order of their paths.
+func (s *upgradeSolver) candidates(targets map[string]*ModuleUpgrade) ([]*upgradeCandidate, error) {
+	leading, err := s.leadingTo(targets)
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]*upgradeCandidate)
+	for _, path := range leading {
+		c, err := s.candidate(path, targets)
+		if err != nil {
+			return nil, err
+		}
+		if len(c.clears) > 0 {
+			byPath[path] = c
+		}
+	}
+	for path, t := range targets {
+		c := byPath[path]
+		if c == nil {
+			c = &upgradeCandidate{path: path, direct: s.direct[path] != "", clears: make(map[string]string)}
+			byPath[path] = c
+		}
+		c.clears[path] = t.To
+	}
+	var cands []*upgradeCandidate
+	for _, path := range slices.Sorted(maps.Keys(byPath)) {
+		cands = append(cands, byPath[path])
+	}
+	return cands, nil
+}
+
+// leadingTo returns the paths of the direct requirements, other than
+// targets, from which the module graph leads to targets.
+func (s *upgradeSolver) leadingTo(targets map[string]*ModuleUpgrade) ([]string, error) {
+	modfileFlag, err := s.scratch()
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.goCmd("mod", "graph", modfileFlag)
+	if err != nil {
+		return nil, err
+	}
+	requires := make(map[string][]string) // module path -> required paths
+	for _, line := range strings.Split(string(out), "\n") {
+		from, to, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		from, _, _ = strings.Cut(from, "@")
+		to, _, _ = strings.Cut(to, "@")
+		requires[from] = append(requires[from], to)
+	}
+	var paths []string
+	for path := range s.direct {
+		if targets[path] != nil {
+			continue
+		}
+		seen := map[string]bool{path: true}
+		stack := []string{path}
+		for len(stack) > 0 && !slices.Contains(paths, path) {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, r := range requires[m] {
+				if targets[r] != nil {
+					paths = append(paths, path)
+					break
+				}
+				if !seen[r] {
+					seen[r] = true
+					stack = append(stack, r)
+				}
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// candidate returns the candidate upgrading the direct requirement
+// path, with the earliest of its later releases clearing each of
+// targets which its latest release clears.
+func (s *upgradeSolver) candidate(path string, targets map[string]*ModuleUpgrade) (*upgradeCandidate, error) {
+	c := &upgradeCandidate{path: path, direct: true, clears: make(map[string]string)}
+	modfileFlag, err := s.scratch()
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.goCmd("list", modfileFlag, "-m", "-versions", "-f", `{{join .Versions " "}}`, path)
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, v := range strings.Fields(string(out)) {
+		if semver.Compare(v, s.direct[path]) > 0 && semver.Prerelease(v) == "" {
+			versions = append(versions, v)
+		}
+	}
+	if len(versions) == 0 {
+		return c, nil
+	}
+	clears := func(v, target string) (bool, error) {
+		sel, err := s.selectedAfter(path, v, targets)
+		if err != nil {
+			return false, err
+		}
+		return sel[target] != "" && semver.Compare(sel[target], targets[target].To) >= 0, nil
+	}
+	for _, target := range slices.Sorted(maps.Keys(targets)) {
+		ok, err := clears(versions[len(versions)-1], target)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		// Find the earliest version clearing target.
+		lo, hi := 0, len(versions)-1
+		for lo < hi {
+			mid := (lo + hi) / 2
+			ok, err := clears(versions[mid], target)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				hi = mid
+			} else {
+				lo = mid + 1
+			}
+		}
+		c.clears[target] = versions[lo]
+	}
+	return c, nil
+}
+
+// selectedAfter returns the versions of targets selected once path is
+// upgraded to version, by their paths. Versions which cannot be
+// upgraded to, such as those requiring a later go command, select
+// none.
+func (s *upgradeSolver) selectedAfter(path, version string, targets map[string]*ModuleUpgrade) (map[string]string, error) {
+	key := path + "@" + version
+	if sel, ok := s.selected[key]; ok {
+		return sel, nil
+	}
+	modfileFlag, err := s.scratch()
+	if err != nil {
+		return nil, err
+	}
+	sel := make(map[string]string)
+	if _, err := s.goCmd("get", modfileFlag, key); err == nil {
+		args := append([]string{"list", modfileFlag, "-m", "-f", "{{.Path}} {{.Version}}"}, slices.Sorted(maps.Keys(targets))...)
+		out, err := s.goCmd(args...)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			if p, v, ok := strings.Cut(line, " "); ok {
+				sel[p] = v
+			}
+		}
+	} else if s.ctx.Err() != nil {
+		return nil, s.ctx.Err()
+	}
+	s.selected[key] = sel
+	return sel, nil
+}
+
+// cover returns the smallest set of cands clearing all of targets,
+// preferring the sets adding the fewest new requirements, or, among
+// too many cands to try all their combinations, a small one.
+func cover(cands []*upgradeCandidate, targets []string) []*upgradeCandidate {
+	clearsAll := func(set []*upgradeCandidate) bool {
+		for _, t := range targets {
+			if !slices.ContainsFunc(set, func(c *upgradeCandidate) bool { return c.clears[t] != "" }) {
+				return false
+			}
+		}
+		return true
+	}
+	added := func(set []*upgradeCandidate) int {
+		n := 0
+		for _, c := range set {
+			if !c.direct {
+				n++
+			}
+		}
+		return n
+	}
+	if len(cands) > maxExactCandidates {
+		// Take the candidates clearing the most of the
+		// others until all are cleared.
+		var set []*upgradeCandidate
+		for !clearsAll(set) {
+			var best *upgradeCandidate
+			bestN := 0
+			for _, c := range cands {
+				n := 0
+				for _, t := range targets {
+					if c.clears[t] != "" && !slices.ContainsFunc(set, func(c *upgradeCandidate) bool { return c.clears[t] != "" }) {
+						n++
+					}
+				}
+				if n > bestN {
+					best, bestN = c, n
+				}
+			}
+			set = append(set, best)
+		}
+		return set
+	}
+	for k := 1; k <= len(cands); k++ {
+		var best []*upgradeCandidate
+		combinations(cands, k, func(set []*upgradeCandidate) {
+			if clearsAll(set) && (best == nil || added(set) < added(best)) {
+				best = slices.Clone(set)
+			}
+		})
+		if best != nil {
+			return best
+		}
+	}
+	return nil // unreachable, as each target is a candidate
+}
+
+// combinations calls f with each combination of k elements of s,
+// in order. The slice passed to f is reused.
+func combinations[T any](s []T, k int, f func([]T)) {
+	set := make([]T, 0, k)
+	var rec func(i int)
+	rec = func(i int) {
+		if len(set) == k {
+			f(set)
+			return
+		}
+		for j := i; j <= len(s)-(k-len(set)); j++ {
+			set = append(set, s[j])
+			rec(j + 1)
+			set = set[:len(set)-1]
+		}
+	}
+	rec(0)
+}
+
+// upgrades returns the upgrades of set clearing targets, each to the
+// latest of the versions clearing the targets it is chosen for, along
+// with the other upgrades of plan, in the order of the module paths.
+func (s *upgradeSolver) upgrades(plan []ModuleUpgrade, targets map[string]*ModuleUpgrade, set []*upgradeCandidate) []ModuleUpgrade {
+	byPath := make(map[string]*ModuleUpgrade)
+	for _, t := range slices.Sorted(maps.Keys(targets)) {
+		// A target is cleared by its own upgrade if chosen,
+		// as it is the smallest.
+		i := slices.IndexFunc(set, func(c *upgradeCandidate) bool { return c.path == t })
+		if i < 0 {
+			i = slices.IndexFunc(set, func(c *upgradeCandidate) bool { return c.clears[t] != "" })
+		}
+		c := set[i]
+		u := byPath[c.path]
+		if u == nil {
+			u = &ModuleUpgrade{Path: c.path, From: s.direct[c.path]}
+			if targets[c.path] != nil {
+				u.From = targets[c.path].From
+			}
+			byPath[c.path] = u
+		}
+		if semver.Compare(c.clears[t], u.To) > 0 {
+			u.To = c.clears[t]
+		}
+		u.Fixes = append(u.Fixes, targets[t].Fixes...)
+	}
+	var ups []ModuleUpgrade
+	for _, p := range plan {
+		if targets[p.Path] == nil {
+			ups = append(ups, p)
+			continue
+		}
+		if len(p.Unfixed) > 0 {
+			// The vulnerabilities without fixed
+			// versions remain with their module.
+			u := byPath[p.Path]
+			if u == nil {
+				u = &ModuleUpgrade{Path: p.Path, From: p.From}
+				byPath[p.Path] = u
+			}
+			u.Unfixed = p.Unfixed
+		}
+	}
+	for _, u := range byPath {
+		slices.Sort(u.Fixes)
+		u.Fixes = slices.Compact(u.Fixes)
+		ups = append(ups, *u)
+	}
+	slices.SortFunc(ups, func(u1, u2 ModuleUpgrade) int {
+		return strings.Compare(u1.Path, u2.Path)
+	})
+	return ups
+}
@@ -0,0 +1,194 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/zip"
+)
+
+func TestMinimalUpgrades(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip(err)
+	}
+	// example.com/dep raises example.com/vuln to its fixed version
+	// v1.1.0 from v1.2.0 on, and example.com/vuln2 to its fixed
+	// version v1.1.0 from v1.4.0 on.
+	proxy := t.TempDir()
+	writeProxyModule(t, proxy, "example.com/vuln", "v1.0.0", "v1.1.0", "v1.2.0")
+	writeProxyModule(t, proxy, "example.com/vuln2", "v1.0.0", "v1.1.0")
+	writeProxyModule(t, proxy, "example.com/dep", "v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0", "v1.4.0")
+	requires := map[string]string{
+		"v1.0.0": "example.com/vuln v1.0.0\n\texample.com/vuln2 v1.0.0",
+		"v1.1.0": "example.com/vuln v1.0.0\n\texample.com/vuln2 v1.0.0",
+		"v1.2.0": "example.com/vuln v1.1.0\n\texample.com/vuln2 v1.0.0",
+		"v1.3.0": "example.com/vuln v1.2.0\n\texample.com/vuln2 v1.0.0",
+		"v1.4.0": "example.com/vuln v1.2.0\n\texample.com/vuln2 v1.1.0",
+	}
+	for v, req := range requires {
+		writeFile(t, filepath.Join(proxy, "example.com", "dep", "@v", v+".mod"), "module example.com/dep\n\ngo 1.18\n\nrequire (\n\t"+req+"\n)\n")
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/m\n\ngo 1.18\n\nrequire example.com/dep v1.0.0\n")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\nimport _ \"example.com/dep\"\n\nfunc main() {}\n")
+	modcache := t.TempDir()
+	env := append(os.Environ(), "GOPROXY=file://"+filepath.ToSlash(proxy), "GOSUMDB=off", "GOFLAGS=-modcacherw",
+		"GOMODCACHE="+modcache, "GOWORK=off", "GOTOOLCHAIN=local")
+	cmd := exec.Command("go", "mod", "tidy")
+	cmd.Dir = dir
+	cmd.Env = env
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go mod tidy: %v\n%s", err, out)
+	}
+	gomod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		// The go command leaves the module cache read-only.
+		cmd := exec.Command("go", "clean", "-modcache")
+		cmd.Env = env
+		cmd.Run()
+	})
+
+	finding := func(osv, mod string) *govulncheck.Finding {
+		return &govulncheck.Finding{OSV: osv, FixedVersion: "v1.1.0", Trace: []*govulncheck.Frame{{Module: mod, Version: "v1.0.0"}}}
+	}
+	for _, tc := range []struct {
+		name     string
+		findings []*govulncheck.Finding
+		want     []ModuleUpgrade
+	}{
+		{
+			name:     "earliest",
+			findings: []*govulncheck.Finding{finding("GO-0000-0001", "example.com/vuln")},
+			want:     []ModuleUpgrade{{Path: "example.com/dep", From: "v1.0.0", To: "v1.2.0", Fixes: []string{"GO-0000-0001"}}},
+		},
+		{
+			name:     "one for all",
+			findings: []*govulncheck.Finding{finding("GO-0000-0001", "example.com/vuln"), finding("GO-0000-0002", "example.com/vuln2")},
+			want:     []ModuleUpgrade{{Path: "example.com/dep", From: "v1.0.0", To: "v1.4.0", Fixes: []string{"GO-0000-0001", "GO-0000-0002"}}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := minimalUpgrades(context.Background(), env, dir, tc.findings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("upgrades mismatch (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	// The go.mod file of the module is left alone.
+	if after, err := os.ReadFile(filepath.Join(dir, "go.mod")); err != nil || string(after) != string(gomod) {
+		t.Errorf("go.mod changed to\n%s", after)
+	}
+}
+
+func TestCover(t *testing.T) {
+	cand := func(path string, direct bool, clears ...string) *upgradeCandidate {
+		c := &upgradeCandidate{path: path, direct: direct, clears: make(map[string]string)}
+		for _, t := range clears {
+			c.clears[t] = "v1.0.0"
+		}
+		return c
+	}
+	cands := []*upgradeCandidate{
+		cand("a", true, "x", "y"),
+		cand("b", true, "y", "z"),
+		cand("c", true, "x", "z"),
+		cand("x", false, "x"),
+		cand("y", false, "y"),
+		cand("z", true, "z"),
+	}
+	var got []string
+	for _, c := range cover(cands, []string{"x", "y", "z"}) {
+		got = append(got, c.path)
+	}
+	// Of the sets of two, the first adding no requirements.
+	if want := []string{"a", "b"}; !cmp.Equal(got, want) {
+		t.Errorf("got cover %v; want %v", got, want)
+	}
+}
+
+func TestTextRemediation(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewTextHandler(&buf)
+	h.remediate = func(findings []*govulncheck.Finding) ([]ModuleUpgrade, error) {
+		return []ModuleUpgrade{
+			{Path: "example.com/dep", From: "v1.0.0", To: "v1.4.0", Fixes: []string{"GO-0000-0001", "GO-0000-0002"}},
+			{Path: "example.com/unfixed", From: "v1.0.0", Unfixed: []string{"GO-0000-0003"}},
+			{Path: "stdlib", From: "v1.21.0", To: "v1.21.5", Fixes: []string{"GO-0000-0004"}},
+		}, nil
+	}
+	h.Finding(&govulncheck.Finding{OSV: "GO-0000-0001", Trace: []*govulncheck.Frame{{Module: "example.com/vuln"}}})
+	h.Flush() // reports the vulnerabilities found
+	want := `=== Remediation ===
+
+These upgrades fix 3 vulnerabilities:
+
+  example.com/dep v1.0.0 => v1.4.0 (GO-0000-0001, GO-0000-0002)
+  stdlib go1.21 => go1.21.5 (GO-0000-0004)
+
+To apply them, run:
+
+  go get example.com/dep@v1.4.0 go@1.21.5
+
+No fixed version is known for GO-0000-0003 of example.com/unfixed.
+`
+	if _, got, _ := strings.Cut(buf.String(), "=== Remediation"); "=== Remediation"+got != want {
+		t.Errorf("got output\n%s\nwant it to end with\n%s", &buf, want)
+	}
+}
+
+// writeProxyModule writes versions of the module path, each with
+// a package of the same path, to the file proxy in dir.
+func writeProxyModule(t *testing.T, dir, path string, versions ...string) {
+	t.Helper()
+	vdir := filepath.Join(dir, filepath.FromSlash(path), "@v")
+	writeFile(t, filepath.Join(vdir, "list"), strings.Join(versions, "\n")+"\n")
+	for i, v := range versions {
+		writeFile(t, filepath.Join(vdir, v+".info"), fmt.Sprintf(`{"Version":%q,"Time":"2024-01-%02dT00:00:00Z"}`, v, i+1))
+		gomod := "module " + path + "\n\ngo 1.18\n"
+		writeFile(t, filepath.Join(vdir, v+".mod"), gomod)
+		src := t.TempDir()
+		writeFile(t, filepath.Join(src, "go.mod"), gomod)
+		writeFile(t, filepath.Join(src, "p.go"), "package "+filepath.Base(path)+"\n")
+		f, err := os.Create(filepath.Join(vdir, v+".zip"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := zip.CreateFromDir(f, module.Version{Path: path, Version: v}, src); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeFile(t *testing.T, name, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(data), 0o666); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/StevenACoffman/invuln/external/buildinfo"
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+)
+
+// runVerify scans the source code in dir and the binary cfg.verify
+// built from it, and reports as warnings the vulnerable symbols found
+// by only one of the two analyses.
+//
+// The results of the source scan are passed on to handler. The binary
+// scan only serves the comparison, so its findings are not reported.
+func runVerify(ctx context.Context, handler govulncheck.Handler, cfg *config, client *client.Client, dir string) error {
+	bin, err := createBin(cfg.verify)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cfg.verify, err)
+	}
+
+	scfg := *cfg
+	scfg.verify = ""
+	src := &symbolRecorder{Handler: handler, symbols: make(map[vulnSymbol]bool)}
+	if err := runSource(ctx, src, &scfg, client, dir); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Checking that %s agrees with the source analysis...", cfg.verify)
+	if err := handler.Progress(&govulncheck.Progress{Message: msg}); err != nil {
+		return err
+	}
+	if bin.SymbolPrecision == buildinfo.SymbolsNone {
+		msg := fmt.Sprintf("warning: no symbols could be recovered from %s, so it cannot be verified against the source analysis", cfg.verify)
+		return handler.Progress(&govulncheck.Progress{Message: msg})
+	}
+	bcfg := cfg.Config
+	bcfg.ScanMode = govulncheck.ScanModeBinary
+	binRec := &symbolRecorder{Handler: discardHandler{}, symbols: make(map[vulnSymbol]bool)}
+	if err := vulncheck.Binary(ctx, binRec, bin, &bcfg, client); err != nil {
+		return fmt.Errorf("%s: %w", cfg.verify, err)
+	}
+
+	for _, w := range verifyWarnings(cfg.verify, src.symbols, binRec.symbols, bin.SymbolPrecision) {
+		if err := handler.Progress(&govulncheck.Progress{Message: w}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vulnSymbol is a vulnerable symbol of a vulnerability.
+type vulnSymbol struct {
+	osv    string
+	symbol string
+}
+
+// symbolRecorder records the vulnerable symbols of the
+// symbol level findings passed to it.
+type symbolRecorder struct {
+	govulncheck.Handler
+	symbols map[vulnSymbol]bool
+}
+
+func (h *symbolRecorder) Finding(f *govulncheck.Finding) error {
+	// Only vulnerable functions are looked for in binaries.
+	switch fr := f.Trace[0]; {
+	case fr.Function == "":
+	case fr.Kind == "", fr.Kind == govulncheck.SymbolKindFunc, fr.Kind == govulncheck.SymbolKindLinked:
+		h.symbols[vulnSymbol{osv: f.OSV, symbol: symbol(fr, false)}] = true
+	}
+	return h.Handler.Finding(f)
+}
+
+// discardHandler drops all messages passed to it.
+type discardHandler struct{}
+
+func (discardHandler) Config(*govulncheck.Config) error     { return nil }
+func (discardHandler) SBOM(*govulncheck.SBOM) error         { return nil }
+func (discardHandler) Progress(*govulncheck.Progress) error { return nil }
+func (discardHandler) OSV(*osv.Entry) error                 { return nil }
+func (discardHandler) Finding(*govulncheck.Finding) error   { return nil }
+
+// verifyWarnings returns the warnings for the vulnerable symbols found
+// in only one of the source scan and the scan of binary, which has the
+// given symbol precision.
+func verifyWarnings(binary string, source, bin map[vulnSymbol]bool, precision string) []string {
+	var warnings []string
+	for s := range bin {
+		if !source[s] {
+			warnings = append(warnings, fmt.Sprintf("warning: %s (%s) is in %s but is not reachable in the source analysis; the binary may have been built with different build tags or code", s.symbol, s.osv, binary))
+		}
+	}
+	for s := range source {
+		if bin[s] {
+			continue
+		}
+		reason := "the binary may have been built from different code"
+		if precision == buildinfo.SymbolsNoInlined {
+			reason = "it may have been inlined, or " + reason
+		}
+		warnings = append(warnings, fmt.Sprintf("warning: %s (%s) is reachable in the source analysis but is not in %s; %s", s.symbol, s.osv, binary, reason))
+	}
+	sort.Strings(warnings)
+	return warnings
+}
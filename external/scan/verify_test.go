@@ -0,0 +1,53 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/buildinfo"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSymbolRecorder(t *testing.T) {
+	h := &symbolRecorder{Handler: discardHandler{}, symbols: make(map[vulnSymbol]bool)}
+	for _, f := range []*govulncheck.Finding{
+		{OSV: "GO-1", Trace: []*govulncheck.Frame{{Module: "m", Package: "m/p", Function: "F", Receiver: "*T"}}},
+		{OSV: "GO-2", Trace: []*govulncheck.Frame{{Module: "m", Package: "m/p"}}},
+		{OSV: "GO-3", Trace: []*govulncheck.Frame{{Module: "m"}}},
+	} {
+		if err := h.Finding(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[vulnSymbol]bool{{osv: "GO-1", symbol: "m/p.T.F"}: true}
+	if diff := cmp.Diff(want, h.symbols, cmp.AllowUnexported(vulnSymbol{})); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
+
+func TestVerifyWarnings(t *testing.T) {
+	both := vulnSymbol{osv: "GO-1", symbol: "m/p.F"}
+	srcOnly := vulnSymbol{osv: "GO-2", symbol: "m/p.G"}
+	binOnly := vulnSymbol{osv: "GO-3", symbol: "m/q.H"}
+	source := map[vulnSymbol]bool{both: true, srcOnly: true}
+	bin := map[vulnSymbol]bool{both: true, binOnly: true}
+
+	got := verifyWarnings("app", source, bin, "")
+	want := []string{
+		"warning: m/p.G (GO-2) is reachable in the source analysis but is not in app; the binary may have been built from different code",
+		"warning: m/q.H (GO-3) is in app but is not reachable in the source analysis; the binary may have been built with different build tags or code",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	got = verifyWarnings("app", source, bin, buildinfo.SymbolsNoInlined)
+	want[0] = "warning: m/p.G (GO-2) is reachable in the source analysis but is not in app; it may have been inlined, or the binary may have been built from different code"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
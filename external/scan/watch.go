@@ -0,0 +1,297 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/web"
+)
+
+// watchInterval is how often the file system is polled for changes
+// in watch mode.
+var watchInterval = time.Second
+
+// runWatch scans the source code in cfg.dir and then rescans it each
+// time a Go source file, go.mod, or go.sum file changes, until ctx is
+// canceled.
+//
+// Vulnerability data is fetched at most once per module version for
+// the whole session, so rescans after edits that do not change the
+// module requirements only redo package loading and analysis. Local
+// databases are the exception: they are reloaded, and the code
+// rescanned, when their files change. The results of the top-level
+// packages whose import closure did not change are kept in memory
+// for the session, so that only the packages affected by an edit are
+// analyzed again. After each rescan, the vulnerabilities that
+// appeared or went away since the previous scan are reported to
+// stderr, leaving the output of machine-readable formats intact.
+func runWatch(ctx context.Context, cfg *config, c *client.Client, stdout, stderr io.Writer) error {
+	dir := sourceDir(cfg)
+	if dir == "" {
+		dir = "."
+	}
+	c = client.Memoize(c)
+	if !cfg.cache {
+		cfg.results = newMemoryResultCache()
+	}
+
+	dbs := localDBs(cfg)
+	snapshot := func() (map[string]fileState, error) {
+		snap, err := snapshotDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		return snap, snapshotDBs(dbs, snap)
+	}
+	snap, err := snapshot()
+	if err != nil {
+		return err
+	}
+	var prev map[string]bool
+	for {
+		handler := newHandler(ctx, cfg, stdout)
+		tracker := &findingTracker{Handler: handler, level: cfg.ScanLevel, ids: make(map[string]bool)}
+		err := handler.Config(&cfg.Config)
+		if err == nil {
+			err = runSource(ctx, tracker, cfg, c, dir)
+		}
+		if err == nil {
+			err = Flush(handler)
+		}
+		// Errors, such as compile errors in code being edited,
+		// do not end the session.
+		if err != nil && !errors.Is(err, errVulnerabilitiesFound) {
+			fmt.Fprintln(stderr, err)
+		}
+		if prev != nil {
+			fmt.Fprint(stderr, watchDelta(prev, tracker.ids))
+		}
+		prev = tracker.ids
+
+		fmt.Fprintf(stderr, "\nWatching %s for changes...\n", dir)
+		var changed []string
+		snap, changed, err = waitForChange(ctx, snapshot, snap)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		fmt.Fprintf(stderr, "\nRescanning after changes to %s\n\n", strings.Join(changed, ", "))
+		if inDBs(dbs, changed) {
+			// Errors, such as those of advisories being
+			// edited, leave the previous database in use.
+			if nc, err := newClient(ctx, cfg); err != nil {
+				fmt.Fprintf(stderr, "reloading the vulnerability database: %v\n\n", err)
+			} else {
+				c = client.Memoize(nc)
+				if cfg.results != nil {
+					// The entries may have changed without
+					// the modification time of the database.
+					cfg.results = newMemoryResultCache()
+				}
+				if mod, err := c.LastModifiedTime(ctx); err == nil {
+					cfg.DBLastModified = &mod
+				}
+			}
+		}
+	}
+}
+
+// findingTracker records the vulnerabilities with findings at
+// the scan level.
+type findingTracker struct {
+	govulncheck.Handler
+	level govulncheck.ScanLevel
+	ids   map[string]bool
+}
+
+func (t *findingTracker) Finding(f *govulncheck.Finding) error {
+	frame := f.Trace[0]
+	switch {
+	case t.level == govulncheck.ScanLevelSymbol && frame.Function != "" && frame.Kind != govulncheck.SymbolKindFunc,
+		t.level == govulncheck.ScanLevelPackage && frame.Package != "",
+		!t.level.WantPackages():
+		t.ids[f.OSV] = true
+	}
+	return t.Handler.Finding(f)
+}
+
+// watchDelta describes the difference between the
+// vulnerabilities found by two consecutive scans.
+func watchDelta(prev, cur map[string]bool) string {
+	var fixed, found []string
+	for id := range prev {
+		if !cur[id] {
+			fixed = append(fixed, id)
+		}
+	}
+	for id := range cur {
+		if !prev[id] {
+			found = append(found, id)
+		}
+	}
+	if len(fixed)+len(found) == 0 {
+		return "\nNo change since the previous scan.\n"
+	}
+	sort.Strings(fixed)
+	sort.Strings(found)
+	var b strings.Builder
+	b.WriteString("\nChanges since the previous scan:\n")
+	if len(fixed) > 0 {
+		fmt.Fprintf(&b, "  No longer affected by: %s\n", strings.Join(fixed, ", "))
+	}
+	if len(found) > 0 {
+		fmt.Fprintf(&b, "  Newly affected by: %s\n", strings.Join(found, ", "))
+	}
+	return b.String()
+}
+
+// fileState is the state of a watched file used to detect changes.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshotDir returns the state of the files under dir that can
+// affect the results of a source scan.
+func snapshotDir(dir string) (map[string]fileState, error) {
+	snap := make(map[string]fileState)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			// Skip the directories ignored by the go command.
+			if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") && name != "go.mod" && name != "go.sum" && name != "go.work" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil // removed while walking
+			}
+			return err
+		}
+		snap[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return snap, err
+}
+
+// localDBs returns the paths of the databases of cfg
+// given by file URLs.
+func localDBs(cfg *config) []string {
+	var paths []string
+	for _, db := range cfg.db {
+		u, err := url.Parse(db)
+		if err != nil || u.Scheme != "file" {
+			continue
+		}
+		if path, err := web.URLToFilePath(u); err == nil {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// snapshotDBs adds the state of the files of
+// the local databases at paths to snap.
+func snapshotDBs(paths []string, snap map[string]fileState) error {
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil // removed while walking
+				}
+				return err
+			}
+			snap[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inDBs reports whether any of the files is
+// in the local databases at paths.
+func inDBs(paths, files []string) bool {
+	for _, f := range files {
+		for _, p := range paths {
+			if f == p || strings.HasPrefix(f, p+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// waitForChange polls the watched files until their snapshot differs
+// from snap and returns the new snapshot along with the changed files.
+func waitForChange(ctx context.Context, snapshot func() (map[string]fileState, error), snap map[string]fileState) (map[string]fileState, []string, error) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-ticker.C:
+		}
+		cur, err := snapshot()
+		if err != nil {
+			return nil, nil, err
+		}
+		if changed := changedFiles(snap, cur); len(changed) > 0 {
+			return cur, changed, nil
+		}
+	}
+}
+
+// changedFiles returns the sorted list of files
+// added, removed, or modified between two snapshots.
+func changedFiles(old, cur map[string]fileState) []string {
+	var changed []string
+	for path, s := range cur {
+		if o, ok := old[path]; !ok || !o.modTime.Equal(s.modTime) || o.size != s.size {
+			changed = append(changed, path)
+		}
+	}
+	for path := range old {
+		if _, ok := cur[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,198 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/m\n")
+	write("main.go", "package main\n")
+	write("README.md", "readme\n")
+	write("testdata/x.go", "package x\n")
+
+	snap, err := snapshotDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snap) != 2 {
+		t.Fatalf("got %d watched files, want 2: %v", len(snap), snap)
+	}
+
+	// Changes to files that cannot affect the scan are ignored.
+	write("README.md", "changed\n")
+	write("testdata/x.go", "package y\n")
+	write("main.go", "package main // changed\n")
+	write("sub/sub.go", "package sub\n")
+	if err := os.Remove(filepath.Join(dir, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	// Make sure the modification time changes on coarse file systems.
+	mtime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "main.go"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	cur, err := snapshotDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "go.mod"),
+		filepath.Join(dir, "main.go"),
+		filepath.Join(dir, "sub", "sub.go"),
+	}
+	if diff := cmp.Diff(want, changedFiles(snap, cur)); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+	if got := changedFiles(cur, cur); len(got) != 0 {
+		t.Errorf("got changes %v for identical snapshots", got)
+	}
+}
+
+func TestWatchDelta(t *testing.T) {
+	prev := map[string]bool{"GO-2021-0001": true, "GO-2021-0002": true}
+	cur := map[string]bool{"GO-2021-0002": true, "GO-2021-0003": true}
+	want := `
+Changes since the previous scan:
+  No longer affected by: GO-2021-0001
+  Newly affected by: GO-2021-0003
+`
+	if got := watchDelta(prev, cur); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := watchDelta(cur, cur); got != "\nNo change since the previous scan.\n" {
+		t.Errorf("got %q for identical scans", got)
+	}
+}
+
+func TestSnapshotDBs(t *testing.T) {
+	dir := t.TempDir()
+	db := filepath.Join(dir, "db")
+	if err := os.MkdirAll(filepath.Join(db, "ID"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	entry := filepath.Join(db, "ID", "GO-2024-0001.json")
+	if err := os.WriteFile(entry, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config{db: []string{"https://vuln.go.dev", "file://" + filepath.ToSlash(db)}}
+	dbs := localDBs(cfg)
+	if diff := cmp.Diff([]string{db}, dbs); diff != "" {
+		t.Fatalf("localDBs mismatch (-want, +got):\n%s", diff)
+	}
+
+	snap := make(map[string]fileState)
+	if err := snapshotDBs(dbs, snap); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(entry, []byte(`{"id":"GO-2024-0001"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cur := make(map[string]fileState)
+	if err := snapshotDBs(dbs, cur); err != nil {
+		t.Fatal(err)
+	}
+	changed := changedFiles(snap, cur)
+	if diff := cmp.Diff([]string{entry}, changed); diff != "" {
+		t.Errorf("changedFiles mismatch (-want, +got):\n%s", diff)
+	}
+	if !inDBs(dbs, changed) {
+		t.Errorf("inDBs(%v) = false; want true", changed)
+	}
+	if inDBs(dbs, []string{filepath.Join(dir, "dbx", "main.go")}) {
+		t.Error("inDBs of a file outside the databases = true; want false")
+	}
+}
+
+func TestWatchResults(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip(err)
+	}
+	db, err := filepath.Abs(filepath.Join("..", "..", "cmd", "govulncheck", "testdata", "common", "vulndb-v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/m\n\ngo 1.18\n")
+	write("a/a.go", "package a\n")
+	write("b/b.go", "package b\n")
+
+	cfg := &config{
+		db:            []string{"file://" + filepath.ToSlash(db)},
+		dbConcurrency: 1,
+		patterns:      []string{"./..."},
+		env:           append(os.Environ(), "GOFLAGS="),
+		results:       newMemoryResultCache(),
+	}
+	cfg.ScanLevel = govulncheck.ScanLevelSymbol
+	ctx := context.Background()
+	c, err := newClient(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod, err := c.LastModifiedTime(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.DBLastModified = &mod
+
+	scan := func() []string {
+		t.Helper()
+		h := test.NewMockHandler()
+		if err := runSource(ctx, h, cfg, c, dir); err != nil {
+			t.Fatal(err)
+		}
+		var msgs []string
+		for _, p := range h.ProgressMessages {
+			if strings.HasPrefix(p.Message, "Reusing") {
+				msgs = append(msgs, p.Message)
+			}
+		}
+		return msgs
+	}
+	if got := scan(); len(got) != 0 {
+		t.Errorf("first scan: got %q; want no results reused", got)
+	}
+	// Only the package edited since is analyzed again.
+	write("b/b.go", "package b\n\nfunc B() {}\n")
+	want := []string{"Reusing cached results for 1 of 2 packages."}
+	if got := scan(); !slices.Equal(got, want) {
+		t.Errorf("rescan: got %q; want %q", got, want)
+	}
+}
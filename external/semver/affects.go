@@ -5,6 +5,7 @@
 package semver
 
 import (
+	"slices"
 	"sort"
 
 	"github.com/StevenACoffman/invuln/external/osv"
@@ -56,9 +57,11 @@ func ContainsSemver(ar osv.Range, v string) bool {
 	v = canonicalizeSemverPrefix(v)
 
 	// Sort events by semver versions. Event for beginning
-	// of time, if present, always comes first.
-	sort.SliceStable(ar.Events, func(i, j int) bool {
-		e1 := ar.Events[i]
+	// of time, if present, always comes first. The events
+	// are copied, so that those of the caller are left as is.
+	events := slices.Clone(ar.Events)
+	sort.SliceStable(events, func(i, j int) bool {
+		e1 := events[i]
 		v1 := e1.Introduced
 		if v1 == "0" {
 			// -inf case.
@@ -68,7 +71,7 @@ func ContainsSemver(ar osv.Range, v string) bool {
 			v1 = e1.Fixed
 		}
 
-		e2 := ar.Events[j]
+		e2 := events[j]
 		v2 := e2.Introduced
 		if v2 == "0" {
 			// -inf case.
@@ -82,7 +85,7 @@ func ContainsSemver(ar osv.Range, v string) bool {
 	})
 
 	var affected bool
-	for _, e := range ar.Events {
+	for _, e := range events {
 		if !affected && e.Introduced != "" {
 			affected = e.Introduced == "0" || !Less(v, e.Introduced)
 		} else if affected && e.Fixed != "" {
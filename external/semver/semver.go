@@ -43,7 +43,15 @@ func canonicalizeSemverPrefix(s string) string {
 // Less returns whether v1 < v2, where v1 and v2 are
 // semver versions with either a "v", "go" or no prefix.
 func Less(v1, v2 string) bool {
-	return semver.Compare(canonicalizeSemverPrefix(v1), canonicalizeSemverPrefix(v2)) < 0
+	return Compare(v1, v2) < 0
+}
+
+// Compare returns -1, 0, or 1 as v1 is less than, equal to, or
+// greater than v2, where v1 and v2 are semver versions with either
+// a "v", "go" or no prefix. Invalid versions are less than valid
+// ones, and equal to each other.
+func Compare(v1, v2 string) int {
+	return semver.Compare(canonicalizeSemverPrefix(v1), canonicalizeSemverPrefix(v2))
 }
 
 // Valid returns whether v is valid semver, allowing
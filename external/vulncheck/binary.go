@@ -28,6 +28,13 @@ type Bin struct {
 	GoVersion  string             `json:"goVersion,omitempty"`
 	GOOS       string             `json:"goos,omitempty"`
 	GOARCH     string             `json:"goarch,omitempty"`
+	// SymbolPrecision reports how completely PkgSymbols were recovered,
+	// as one of the buildinfo.Symbols* values. It is empty when all
+	// symbols were recovered.
+	SymbolPrecision string `json:"symbolPrecision,omitempty"`
+	// ImportedLibraries are the shared libraries the
+	// binary is dynamically linked against.
+	ImportedLibraries []string `json:"importedLibraries,omitempty"`
 }
 
 // Binary detects presence of vulnerable symbols in bin and
@@ -38,7 +45,7 @@ func Binary(ctx context.Context, handler govulncheck.Handler, bin *Bin, cfg *gov
 		return err
 	}
 	if cfg.ScanLevel.WantSymbols() {
-		return emitCallFindings(handler, binaryCallstacks(vr))
+		return emitCallFindings(handler, binaryCallstacks(vr), nil, true, false)
 	}
 	return nil
 }
@@ -55,34 +62,43 @@ func binary(ctx context.Context, handler govulncheck.Handler, bin *Bin, cfg *gov
 	}
 
 	graph.AddModules(mods...)
+	govulncheck.Logger(ctx).Debug("read binary", "path", bin.Path, "go_version", bin.GoVersion, "modules", len(mods), "symbols", len(bin.PkgSymbols), "symbol_precision", bin.SymbolPrecision)
 
 	if err := handler.SBOM(bin.SBOM()); err != nil {
 		return nil, err
 	}
 
-	if err := handler.Progress(&govulncheck.Progress{Message: fetchingVulnsMessage}); err != nil {
-		return nil, err
-	}
-
-	mv, err := FetchVulnerabilities(ctx, client, mods)
+	mv, err := fetchVulnerabilities(ctx, handler, client, mods)
 	if err != nil {
 		return nil, err
 	}
 
-	// Emit OSV entries immediately in their raw unfiltered form.
-	if err := emitOSVs(handler, mv); err != nil {
-		return nil, err
-	}
-
 	if err := handler.Progress(&govulncheck.Progress{Message: checkingBinVulnsMessage}); err != nil {
 		return nil, err
 	}
 
 	// Emit warning message for ancient Go binaries, defined as binaries
 	// built with Go version without support for debug.BuildInfo (< go1.18).
-	if semver.Valid(bin.GoVersion) && semver.Less(bin.GoVersion, "go1.18") {
-		p := &govulncheck.Progress{Message: fmt.Sprintf("warning: binary built with Go version %s, only standard library vulnerabilities will be checked", bin.GoVersion)}
-		if err := handler.Progress(p); err != nil {
+	// Otherwise, warn if the symbols of the binary could only
+	// be partially recovered, which reduces the precision of
+	// symbol level findings.
+	var warning string
+	ancient := semver.Valid(bin.GoVersion) && semver.Less(bin.GoVersion, "go1.18")
+	switch {
+	case ancient:
+		warning = fmt.Sprintf("warning: binary built with Go version %s, only standard library vulnerabilities will be checked", bin.GoVersion)
+	case bin.SymbolPrecision == buildinfo.SymbolsNoInlined:
+		warning = "warning: binary has no symbol table, symbols were recovered from its function table so vulnerable functions inlined into other functions are not detected"
+	case bin.SymbolPrecision == buildinfo.SymbolsNone:
+		warning = "warning: no symbols could be recovered from binary, only module level vulnerabilities are reported"
+	}
+	if warning != "" {
+		if err := handler.Progress(&govulncheck.Progress{Message: warning}); err != nil {
+			return nil, err
+		}
+	}
+	if binaryUsesCgo(bin) {
+		if err := handler.Progress(&govulncheck.Progress{Message: cgoWarning(bin.ImportedLibraries)}); err != nil {
 			return nil, err
 		}
 	}
@@ -101,6 +117,12 @@ func binary(ctx context.Context, handler govulncheck.Handler, bin *Bin, cfg *gov
 	if !cfg.ScanLevel.WantPackages() || len(affVulns) == 0 {
 		return &Result{}, nil
 	}
+	if bin.SymbolPrecision == buildinfo.SymbolsNone && !ancient {
+		// Without symbols there is no evidence of which packages
+		// are linked into the binary, so the build information
+		// only supports module level findings.
+		return &Result{}, nil
+	}
 
 	// Group symbols per package to avoid querying affVulns all over again.
 	var pkgSymbols map[string][]string
@@ -127,6 +149,9 @@ func binary(ctx context.Context, handler govulncheck.Handler, bin *Bin, cfg *gov
 	}
 
 	symVulns := binVulnSymbols(graph, pkgSymbols, affVulns)
+	for _, v := range symVulns {
+		v.Linked = len(bin.PkgSymbols) > 0
+	}
 	return &Result{Vulns: symVulns}, nil
 }
 
@@ -217,6 +242,8 @@ func (bin *Bin) SBOM() (sbom *govulncheck.SBOM) {
 	}
 
 	sbom.GoVersion = bin.GoVersion
+	sbom.GOOS = bin.GOOS
+	sbom.GOARCH = bin.GOARCH
 	for _, mod := range bin.Modules {
 		if mod.Replace != nil {
 			mod = mod.Replace
@@ -233,5 +260,9 @@ func (bin *Bin) SBOM() (sbom *govulncheck.SBOM) {
 		Version: bin.GoVersion,
 	})
 
+	if binaryUsesCgo(bin) {
+		sbom.CLibraries = bin.ImportedLibraries
+	}
+
 	return sbom
 }
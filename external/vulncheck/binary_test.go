@@ -6,6 +6,9 @@ package vulncheck
 
 import (
 	"context"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/StevenACoffman/invuln/external/buildinfo"
@@ -86,4 +89,83 @@ func TestBinary(t *testing.T) {
 	if diff := cmp.Diff(want, res.Vulns, cmpopts.SortSlices(less), cmp.Comparer(equal)); diff != "" {
 		t.Errorf("(-want, +got): %s", diff)
 	}
+
+	// The symbols are found in the symbol table, so they are linked.
+	h := test.NewMockHandler()
+	if err := Binary(context.Background(), h, bin, cfg, c); err != nil {
+		t.Fatal(err)
+	}
+	var linked []string
+	for _, f := range h.FindingMessages {
+		if fr := f.Trace[0]; fr.Kind == govulncheck.SymbolKindLinked {
+			linked = append(linked, fr.Package+"."+fr.Function)
+		}
+	}
+	sort.Strings(linked)
+	if want := []string{"archive/zip.OpenReader", "golang.org/amod/avuln.Vuln1"}; !reflect.DeepEqual(linked, want) {
+		t.Errorf("got linked symbols %v; want %v", linked, want)
+	}
+}
+
+func TestBinarySymbolPrecisionWarning(t *testing.T) {
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		precision string
+		want      string
+	}{
+		{"", ""},
+		{buildinfo.SymbolsNoInlined, "warning: binary has no symbol table"},
+		{buildinfo.SymbolsNone, "warning: no symbols could be recovered"},
+	} {
+		bin := &Bin{
+			Modules:         []*packages.Module{{Path: "golang.org/amod", Version: "v1.1.3"}},
+			GoVersion:       "go1.20",
+			GOOS:            "linux",
+			GOARCH:          "amd64",
+			SymbolPrecision: tt.precision,
+		}
+		h := test.NewMockHandler()
+		if _, err := binary(context.Background(), h, bin, &govulncheck.Config{ScanLevel: "symbol"}, c); err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		for _, p := range h.ProgressMessages {
+			// The entries of the test client are not all well formed.
+			if strings.HasPrefix(p.Message, "warning:") && !strings.Contains(p.Message, " is malformed") {
+				got = p.Message
+			}
+		}
+		if !strings.HasPrefix(got, tt.want) || (tt.want == "") != (got == "") {
+			t.Errorf("precision %q: got warning %q; want prefix %q", tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestBinaryWithoutSymbols(t *testing.T) {
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := &Bin{
+		Modules:         []*packages.Module{{Path: "golang.org/amod", Version: "v1.1.3"}},
+		GoVersion:       "go1.20",
+		GOOS:            "linux",
+		GOARCH:          "amd64",
+		SymbolPrecision: buildinfo.SymbolsNone,
+	}
+	h := test.NewMockHandler()
+	if err := Binary(context.Background(), h, bin, &govulncheck.Config{ScanLevel: "symbol"}, c); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.FindingMessages) == 0 {
+		t.Fatal("got no findings; want module level findings")
+	}
+	for _, f := range h.FindingMessages {
+		if f.Trace[0].Package != "" {
+			t.Errorf("got %s finding for package %s; want only module level findings", f.OSV, f.Trace[0].Package)
+		}
+	}
 }
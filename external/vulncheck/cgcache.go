@@ -0,0 +1,232 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// UseCallGraphCache makes symbol level source scans of g reuse the
+// call graphs stored in dir by previous scans, and store those they
+// compute there. The key must identify the build inputs of the
+// packages of g, such as the content of their files and the build
+// configuration. The call graph algorithm and entry points are
+// accounted for separately.
+func (g *PackageGraph) UseCallGraphCache(dir, key string) {
+	g.cgCacheDir = dir
+	g.cgCacheKey = key
+}
+
+// cgCacheVersion is changed whenever the format of cached call graphs
+// or their construction changes.
+const cgCacheVersion = "v1"
+
+// cachedCallGraph is the serialized form of a call graph. Functions
+// are identified by their names, which are stable across builds of the
+// same code, and call sites by their index among the calls of their
+// function.
+type cachedCallGraph struct {
+	Funcs []string
+	Edges []cachedEdge
+}
+
+type cachedEdge struct {
+	Caller, Callee int // indices in Funcs
+	Site           int // index of the call in Caller, or -1
+}
+
+// callGraphCached is like callGraph, but it uses the call graph
+// cache of g, if any.
+func (g *PackageGraph) callGraphCached(ctx context.Context, prog *ssa.Program, entries []*ssa.Function, algorithm govulncheck.CallGraphAlgorithm) (*callgraph.Graph, error) {
+	if g.cgCacheDir == "" {
+		return callGraph(ctx, prog, entries, algorithm)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%q\n", cgCacheVersion, g.cgCacheKey, algorithm)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.String())
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintln(h, n)
+	}
+	file := filepath.Join(g.cgCacheDir, hex.EncodeToString(h.Sum(nil))+".json")
+
+	if cg, ok := readCallGraph(file, prog); ok {
+		return cg, nil
+	}
+	cg, err := callGraph(ctx, prog, entries, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	// Failing to populate the cache only
+	// makes the next scan slower.
+	_ = writeCallGraph(file, cg)
+	return cg, nil
+}
+
+// readCallGraph reads the call graph of prog stored in file. It
+// reports false if there is none or if it does not match prog.
+func readCallGraph(file string, prog *ssa.Program) (*callgraph.Graph, bool) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false
+	}
+	var c cachedCallGraph
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, false
+	}
+	byName := make(map[string]*ssa.Function)
+	ambiguous := make(map[string]bool)
+	for f := range ssautil.AllFunctions(prog) {
+		name := cachedFuncName(f)
+		if _, ok := byName[name]; ok {
+			ambiguous[name] = true
+		}
+		byName[name] = f
+	}
+	funcs := make([]*ssa.Function, len(c.Funcs))
+	for i, name := range c.Funcs {
+		if funcs[i] = byName[name]; funcs[i] == nil || ambiguous[name] {
+			return nil, false
+		}
+	}
+	cg := &callgraph.Graph{Nodes: make(map[*ssa.Function]*callgraph.Node)}
+	for _, f := range funcs {
+		cg.CreateNode(f)
+	}
+	calls := make(map[*ssa.Function][]ssa.CallInstruction)
+	for _, e := range c.Edges {
+		if e.Caller < 0 || e.Caller >= len(funcs) || e.Callee < 0 || e.Callee >= len(funcs) {
+			return nil, false
+		}
+		caller := funcs[e.Caller]
+		var site ssa.CallInstruction
+		if e.Site >= 0 {
+			cs, ok := calls[caller]
+			if !ok {
+				cs = callInstructions(caller)
+				calls[caller] = cs
+			}
+			if e.Site >= len(cs) {
+				return nil, false
+			}
+			site = cs[e.Site]
+		}
+		callgraph.AddEdge(cg.Nodes[caller], site, cg.Nodes[funcs[e.Callee]])
+	}
+	return cg, true
+}
+
+// writeCallGraph stores cg in file. Call graphs with functions
+// whose names are ambiguous cannot be stored.
+func writeCallGraph(file string, cg *callgraph.Graph) error {
+	var funcs []*ssa.Function
+	for f := range cg.Nodes {
+		if f == nil {
+			return fmt.Errorf("call graph has a root node")
+		}
+		funcs = append(funcs, f)
+	}
+	names := make(map[*ssa.Function]string)
+	for _, f := range funcs {
+		names[f] = cachedFuncName(f)
+	}
+	sort.Slice(funcs, func(i, j int) bool { return names[funcs[i]] < names[funcs[j]] })
+
+	var c cachedCallGraph
+	index := make(map[*ssa.Function]int)
+	for i, f := range funcs {
+		name := names[f]
+		if i > 0 && name == c.Funcs[i-1] {
+			return fmt.Errorf("ambiguous function name %s", name)
+		}
+		index[f] = i
+		c.Funcs = append(c.Funcs, name)
+	}
+	for _, f := range funcs {
+		var sites map[ssa.CallInstruction]int
+		for _, e := range cg.Nodes[f].Out {
+			site := -1
+			if e.Site != nil {
+				if sites == nil {
+					sites = make(map[ssa.CallInstruction]int)
+					for i, call := range callInstructions(f) {
+						sites[call] = i
+					}
+				}
+				site = sites[e.Site]
+			}
+			c.Edges = append(c.Edges, cachedEdge{Caller: index[f], Callee: index[e.Callee.Func], Site: site})
+		}
+	}
+
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that concurrent
+	// scans never observe a partially written call graph.
+	f, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), file)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// cachedFuncName returns the name identifying f in cached call graphs.
+// The type arguments of instances of generic functions are qualified
+// by the positions of their declarations, as local types, aliases, and
+// type parameters declared in different functions are often named alike,
+// as in T.
+func cachedFuncName(f *ssa.Function) string {
+	name := f.String()
+	for _, t := range f.TypeArgs() {
+		if t, ok := t.(interface{ Obj() *types.TypeName }); ok {
+			name += " " + f.Prog.Fset.Position(t.Obj().Pos()).String()
+		}
+	}
+	return name
+}
+
+// callInstructions returns the call instructions of f in order.
+func callInstructions(f *ssa.Function) []ssa.CallInstruction {
+	var calls []ssa.CallInstruction
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			if call, ok := instr.(ssa.CallInstruction); ok {
+				calls = append(calls, call)
+			}
+		}
+	}
+	return calls
+}
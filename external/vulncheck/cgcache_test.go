@@ -0,0 +1,150 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestCallGraphCache(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import (
+				"golang.org/amod/avuln"
+				"golang.org/bmod/bvuln"
+			)
+
+			type I interface{ Vuln1() }
+
+			func X(i I) {
+				i.Vuln1()
+				func() { bvuln.Vuln() }()
+			}
+
+			func Y() {
+				X(avuln.VulnData{})
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/amod@v1.1.3",
+			Files: map[string]interface{}{"avuln/avuln.go": `
+			package avuln
+
+			type VulnData struct {}
+			func (v VulnData) Vuln1() {}
+			func (v VulnData) Vuln2() {}
+			`},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	load := func() *PackageGraph {
+		graph := NewPackageGraph("go1.18")
+		if err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true); err != nil {
+			t.Fatal(err)
+		}
+		return graph
+	}
+
+	t.Run("roundtrip", func(t *testing.T) {
+		graph := load()
+		prog, pkgs := buildSSA(graph.TopPkgs(), graph.TopPkgs()[0].Fset, 0)
+		cg, err := callGraph(context.Background(), prog, entryPoints(pkgs, true), govulncheck.CallGraphVTA)
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(t.TempDir(), "cg.json")
+		if err := writeCallGraph(file, cg); err != nil {
+			t.Fatal(err)
+		}
+		// Read the call graph back into a new build of the program.
+		prog2, _ := buildSSA(load().TopPkgs(), graph.TopPkgs()[0].Fset, 0)
+		got, ok := readCallGraph(file, prog2)
+		if !ok {
+			t.Fatal("call graph not read")
+		}
+		if g, w := callEdges(got), callEdges(cg); !reflect.DeepEqual(g, w) {
+			t.Errorf("got edges %v; want %v", g, w)
+		}
+	})
+
+	t.Run("source", func(t *testing.T) {
+		c, err := newTestClient()
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir := t.TempDir()
+		var results []map[string][]string
+		for range 2 {
+			graph := load()
+			graph.UseCallGraphCache(dir, "key")
+			cfg := &govulncheck.Config{ScanLevel: "symbol"}
+			res, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, callGraphToStrMap(res))
+		}
+		if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 1 {
+			t.Errorf("got cache files %v; want one", files)
+		}
+		if !reflect.DeepEqual(results[0], results[1]) {
+			t.Errorf("cached call graph: got %v; want %v", results[1], results[0])
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "cg.json")
+		if err := os.WriteFile(file, []byte(`{"Funcs":["golang.org/entry/x.Missing"]}`), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		prog, _ := buildSSA(load().TopPkgs(), nil, 0)
+		if _, ok := readCallGraph(file, prog); ok {
+			t.Error("read call graph of unknown functions")
+		}
+	})
+}
+
+// callEdges returns the edges of cg as sorted strings.
+func callEdges(cg *callgraph.Graph) []string {
+	var edges []string
+	for _, n := range cg.Nodes {
+		for _, e := range n.Out {
+			site := "-"
+			if e.Site != nil {
+				site = e.Site.String()
+			}
+			edges = append(edges, fmt.Sprintf("%s -%s-> %s", e.Caller.Func, site, e.Callee.Func))
+		}
+	}
+	sort.Strings(edges)
+	return edges
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external"
+	"golang.org/x/tools/go/packages"
+)
+
+// cgoWarning returns the warning emitted when the scanned code uses cgo
+// to link C code, the vulnerabilities of which govulncheck does not check.
+// libs are the linked C libraries, if known.
+func cgoWarning(libs []string) string {
+	if len(libs) == 0 {
+		return "warning: cgo is used to link C code, which is not checked for vulnerabilities"
+	}
+	return fmt.Sprintf("warning: cgo is used to link the C libraries %s, which are not checked for vulnerabilities", strings.Join(libs, ", "))
+}
+
+// binaryUsesCgo reports whether bin contains functions generated
+// by cgo for packages outside of the standard library.
+//
+// The standard library uses cgo for some packages, like net and
+// os/user, but only to call the C library of the system.
+func binaryUsesCgo(bin *Bin) bool {
+	for _, sym := range bin.PkgSymbols {
+		if sym.Pkg != "main" && IsStdPackage(sym.Pkg) {
+			continue
+		}
+		if strings.HasPrefix(sym.Name, "_Cfunc_") || strings.HasPrefix(sym.Name, "_cgoexp_") {
+			return true
+		}
+	}
+	return false
+}
+
+// cgoLibraries reports whether the packages of g outside of the
+// standard library use cgo, and returns the C libraries they link as
+// named by the -l flags and pkg-config packages of #cgo directives.
+func (g *PackageGraph) cgoLibraries() (bool, []string) {
+	g.cgoOnce.Do(func() {
+		libs := make(map[string]bool)
+		for _, p := range g.packages {
+			if p.Module != nil && p.Module.Path == external.GoStdModulePath {
+				continue
+			}
+			if packageCgoLibraries(p, libs) {
+				g.cgo = true
+			}
+		}
+		for l := range libs {
+			g.cLibraries = append(g.cLibraries, l)
+		}
+		sort.Strings(g.cLibraries)
+	})
+	return g.cgo, g.cLibraries
+}
+
+// packageCgoLibraries adds the C libraries linked by the #cgo
+// directives of p to libs and reports whether p uses cgo.
+func packageCgoLibraries(p *packages.Package, libs map[string]bool) bool {
+	cgo := false
+	fset := token.NewFileSet()
+	for _, name := range p.GoFiles {
+		src, err := os.ReadFile(name)
+		if err != nil || !bytes.Contains(src, []byte(`"C"`)) {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, src, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		for _, imp := range f.Imports {
+			if path, _ := strconv.Unquote(imp.Path.Value); path != "C" {
+				continue
+			}
+			cgo = true
+			doc := imp.Doc
+			if doc == nil {
+				// The preamble of a lone import "C" documents
+				// the import declaration rather than the spec.
+				doc = importDecl(f.Decls, imp.Pos())
+			}
+			if doc != nil {
+				addCgoLibraries(doc.Text(), libs)
+			}
+		}
+	}
+	return cgo
+}
+
+// importDecl returns the documentation of the
+// import declaration containing pos, if any.
+func importDecl(decls []ast.Decl, pos token.Pos) *ast.CommentGroup {
+	for _, d := range decls {
+		if gd, ok := d.(*ast.GenDecl); ok && gd.Tok == token.IMPORT && gd.Pos() <= pos && pos < gd.End() {
+			return gd.Doc
+		}
+	}
+	return nil
+}
+
+// addCgoLibraries adds the libraries named by
+// the #cgo directives in preamble to libs.
+func addCgoLibraries(preamble string, libs map[string]bool) {
+	for _, line := range strings.Split(preamble, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#cgo ") {
+			continue
+		}
+		directive, args, ok := strings.Cut(line[len("#cgo "):], ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		switch verb := fields[len(fields)-1]; verb {
+		case "LDFLAGS":
+			for _, arg := range strings.Fields(args) {
+				switch {
+				case strings.HasPrefix(arg, "-l") && len(arg) > 2:
+					libs[arg[2:]] = true
+				case strings.HasSuffix(arg, ".a") || strings.HasSuffix(arg, ".so"):
+					libs[filepath.Base(arg)] = true
+				}
+			}
+		case "pkg-config":
+			for _, arg := range strings.Fields(args) {
+				if !strings.HasPrefix(arg, "-") {
+					libs[arg] = true
+				}
+			}
+		}
+	}
+}
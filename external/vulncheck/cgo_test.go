@@ -0,0 +1,85 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/buildinfo"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestPackageCgoLibraries(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go": `package p
+
+// #cgo linux LDFLAGS: -L/opt/lib -lssl -lcrypto
+// #cgo pkg-config: --static zlib
+// #include <openssl/ssl.h>
+import "C"
+`,
+		"b.go": `package p
+
+import (
+	"fmt"
+
+	/*
+	#cgo LDFLAGS: /opt/lib/libfoo.a
+	*/
+	"C"
+)
+
+var _ = fmt.Sprint
+`,
+		"c.go": `package p
+
+// #cgo LDFLAGS: -lnotimported
+import "fmt"
+
+var _ = fmt.Sprint
+`,
+	}
+	var goFiles []string
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		goFiles = append(goFiles, path)
+	}
+
+	libs := make(map[string]bool)
+	if !packageCgoLibraries(&packages.Package{GoFiles: goFiles}, libs) {
+		t.Fatal("package using cgo not detected")
+	}
+	want := map[string]bool{"ssl": true, "crypto": true, "zlib": true, "libfoo.a": true}
+	if diff := cmp.Diff(want, libs); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if packageCgoLibraries(&packages.Package{GoFiles: []string{filepath.Join(dir, "c.go")}}, libs) {
+		t.Error("package not using cgo detected as using cgo")
+	}
+}
+
+func TestBinaryUsesCgo(t *testing.T) {
+	for _, tt := range []struct {
+		syms []buildinfo.Symbol
+		want bool
+	}{
+		{nil, false},
+		{[]buildinfo.Symbol{{Pkg: "net", Name: "_C2func_getaddrinfo"}, {Pkg: "os/user", Name: "_Cfunc_mygetpwuid_r"}}, false},
+		{[]buildinfo.Symbol{{Pkg: "main", Name: "_Cfunc_SSL_new"}}, true},
+		{[]buildinfo.Symbol{{Pkg: "golang.org/x/foo", Name: "_cgoexp_abc_Callback"}}, true},
+	} {
+		if got := binaryUsesCgo(&Bin{PkgSymbols: tt.syms}); got != tt.want {
+			t.Errorf("binaryUsesCgo(%v) = %t; want %t", tt.syms, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"go/build"
+	"go/build/constraint"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+)
+
+// knownOS and knownArch are the values of GOOS and GOARCH,
+// as in the go/build package.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true,
+		"freebsd": true, "hurd": true, "illumos": true, "ios": true,
+		"js": true, "linux": true, "nacl": true, "netbsd": true,
+		"openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+		"windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true,
+		"armbe": true, "arm64": true, "arm64be": true, "loong64": true,
+		"mips": true, "mipsle": true, "mips64": true, "mips64le": true,
+		"mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+		"ppc64le": true, "riscv": true, "riscv64": true, "s390": true,
+		"s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+	unixOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true,
+		"freebsd": true, "hurd": true, "illumos": true, "ios": true,
+		"linux": true, "netbsd": true, "openbsd": true, "solaris": true,
+	}
+)
+
+// fileConstraints returns the build constraints of the files of the
+// packages of graph that have some, by file name.
+func fileConstraints(graph *PackageGraph) map[string]constraint.Expr {
+	m := make(map[string]constraint.Expr)
+	for _, p := range graph.packages {
+		for _, f := range p.Syntax {
+			name := p.Fset.File(f.Pos()).Name()
+			var x constraint.Expr
+			for _, cg := range f.Comments {
+				if cg.Pos() > f.Package {
+					break
+				}
+				for _, c := range cg.List {
+					if constraint.IsGoBuild(c.Text) {
+						x, _ = constraint.Parse(c.Text)
+					}
+				}
+			}
+			x = and(x, fileNameConstraint(name))
+			if x != nil {
+				m[name] = x
+			}
+		}
+	}
+	return m
+}
+
+// fileNameConstraint returns the constraint implied by the name of
+// file, as in linux && amd64 for x_linux_amd64.go, if any.
+func fileNameConstraint(file string) constraint.Expr {
+	name := strings.TrimSuffix(filepath.Base(file), ".go")
+	name = strings.TrimSuffix(name, "_test")
+	parts := strings.Split(name, "_")
+	if len(parts) < 2 {
+		return nil
+	}
+	last := parts[len(parts)-1]
+	tag := func(name string) constraint.Expr { return &constraint.TagExpr{Tag: name} }
+	switch {
+	case len(parts) >= 3 && knownOS[parts[len(parts)-2]] && knownArch[last]:
+		return and(tag(parts[len(parts)-2]), tag(last))
+	case knownOS[last], knownArch[last]:
+		return tag(last)
+	}
+	return nil
+}
+
+func and(x, y constraint.Expr) constraint.Expr {
+	switch {
+	case x == nil:
+		return y
+	case y == nil:
+		return x
+	}
+	return &constraint.AndExpr{X: x, Y: y}
+}
+
+// satisfiable reports whether x can be satisfied when building for
+// goos and goarch, whatever the other build tags are.
+func satisfiable(x constraint.Expr, goos, goarch string) bool {
+	v, known := evalPlatform(x, goos, goarch)
+	return v || !known
+}
+
+// evalPlatform evaluates x for goos and goarch. The value is known
+// only if it does not depend on build tags other than those of
+// platforms.
+func evalPlatform(x constraint.Expr, goos, goarch string) (value, known bool) {
+	switch x := x.(type) {
+	case *constraint.TagExpr:
+		switch {
+		case x.Tag == "unix":
+			return unixOS[goos], true
+		case knownOS[x.Tag]:
+			// As for the go command, android, illumos,
+			// and ios imply linux, solaris, and darwin.
+			return x.Tag == goos ||
+				x.Tag == "linux" && goos == "android" ||
+				x.Tag == "solaris" && goos == "illumos" ||
+				x.Tag == "darwin" && goos == "ios", true
+		case knownArch[x.Tag]:
+			return x.Tag == goarch, true
+		}
+		return false, false
+	case *constraint.NotExpr:
+		v, known := evalPlatform(x.X, goos, goarch)
+		return !v, known
+	case *constraint.AndExpr:
+		v1, k1 := evalPlatform(x.X, goos, goarch)
+		v2, k2 := evalPlatform(x.Y, goos, goarch)
+		switch {
+		case k1 && !v1, k2 && !v2:
+			return false, true
+		case k1 && k2:
+			return true, true
+		}
+		return false, false
+	case *constraint.OrExpr:
+		v1, k1 := evalPlatform(x.X, goos, goarch)
+		v2, k2 := evalPlatform(x.Y, goos, goarch)
+		switch {
+		case k1 && v1, k2 && v2:
+			return true, true
+		case k1 && k2:
+			return false, true
+		}
+		return false, false
+	}
+	return false, false
+}
+
+// targetPlatform returns the platform of the build, which
+// is that of the go command if goos or goarch is not set.
+func targetPlatform(goos, goarch string) (string, string) {
+	if goos == "" {
+		goos = build.Default.GOOS
+	}
+	if goarch == "" {
+		goarch = build.Default.GOARCH
+	}
+	return goos, goarch
+}
+
+// removeUnsatisfiedCalls removes the edges of cg for calls made in
+// files whose build constraints cannot be satisfied for goos and
+// goarch, so that call stacks do not go through code of other
+// platforms, which can be loaded with build tags naming them.
+func removeUnsatisfiedCalls(cg *callgraph.Graph, constraints map[string]constraint.Expr, goos, goarch string) {
+	unsatisfied := func(e *callgraph.Edge) bool {
+		if e.Site == nil {
+			return false
+		}
+		pos := instrPosition(e.Site)
+		if pos == nil {
+			return false
+		}
+		x, ok := constraints[pos.Filename]
+		return ok && !satisfiable(x, goos, goarch)
+	}
+	removed := make(map[*callgraph.Edge]bool)
+	for _, n := range cg.Nodes {
+		for _, e := range n.Out {
+			if unsatisfied(e) {
+				removed[e] = true
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+	keep := func(edges []*callgraph.Edge) []*callgraph.Edge {
+		var kept []*callgraph.Edge
+		for _, e := range edges {
+			if !removed[e] {
+				kept = append(kept, e)
+			}
+		}
+		return kept
+	}
+	for _, n := range cg.Nodes {
+		n.In = keep(n.In)
+		n.Out = keep(n.Out)
+	}
+}
+
+// annotateConstraints sets the build constraints of the functions
+// and call sites leading to the vulnerable symbols of vulns.
+func annotateConstraints(vulns []*Vuln, constraints map[string]constraint.Expr) {
+	// The constraints are formatted once for each file,
+	// rather than for each function and call site in it.
+	strs := make(map[string]string)
+	of := func(pos *token.Position) string {
+		if pos == nil {
+			return ""
+		}
+		s, ok := strs[pos.Filename]
+		if !ok {
+			if x, ok := constraints[pos.Filename]; ok {
+				s = x.String()
+			}
+			strs[pos.Filename] = s
+		}
+		return s
+	}
+	seen := make(map[*FuncNode]bool)
+	var visit func(*FuncNode)
+	visit = func(f *FuncNode) {
+		if seen[f] {
+			return
+		}
+		seen[f] = true
+		f.BuildConstraint = of(f.Pos)
+		for _, cs := range f.CallSites {
+			cs.BuildConstraint = of(cs.Pos)
+			visit(cs.Parent)
+		}
+	}
+	for _, v := range vulns {
+		if v.CallSink != nil {
+			visit(v.CallSink)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"go/build/constraint"
+	"path"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestFileNameConstraint(t *testing.T) {
+	for _, test := range []struct {
+		file, want string
+	}{
+		{"/p/x.go", ""},
+		{"/p/x_linux.go", "linux"},
+		{"/p/x_arm64.go", "arm64"},
+		{"/p/x_linux_amd64.go", "linux && amd64"},
+		{"/p/x_windows_test.go", "windows"},
+		{"/p/linux.go", ""},
+		{"/p/x_other.go", ""},
+	} {
+		got := ""
+		if x := fileNameConstraint(test.file); x != nil {
+			got = x.String()
+		}
+		if got != test.want {
+			t.Errorf("fileNameConstraint(%s) = %q; want %q", test.file, got, test.want)
+		}
+	}
+}
+
+func TestSatisfiable(t *testing.T) {
+	for _, test := range []struct {
+		expr string
+		want bool
+	}{
+		{"linux", true},
+		{"darwin", false},
+		{"unix", true},
+		{"!windows", true},
+		{"linux && arm64", false},
+		{"linux || darwin", true},
+		{"darwin && purego", false},
+		{"darwin || purego", true},
+		{"!purego", true},
+		{"android", false},
+		{"!linux && cgo", false},
+	} {
+		x, err := constraint.Parse("//go:build " + test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := satisfiable(x, "linux", "amd64"); got != test.want {
+			t.Errorf("satisfiable(%s) = %v; want %v", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestStrictPlatform(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			func X() {
+				viaDarwin()
+			}`,
+				"x/x_darwin.go": `
+			package x
+
+			import "golang.org/bmod/bvuln"
+
+			func viaDarwin() {
+				bvuln.Vuln()
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, strict := range []bool{false, true} {
+		graph := NewPackageGraph("go1.18")
+		// Loading with the darwin build tag includes x_darwin.go.
+		if err := graph.LoadPackagesAndMods(e.Config, []string{"darwin"}, []string{path.Join(e.Temp(), "entry/x")}, true); err != nil {
+			t.Fatal(err)
+		}
+		cfg := &govulncheck.Config{ScanLevel: "symbol", GOOS: "linux", GOARCH: "amd64", StrictPlatform: strict}
+		result, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var constraints []string
+		for _, v := range result.Vulns {
+			if v.CallSink == nil {
+				continue
+			}
+			for _, cs := range v.CallSink.CallSites {
+				constraints = append(constraints, cs.Parent.Name+": "+cs.BuildConstraint)
+			}
+		}
+		if strict {
+			if len(constraints) > 0 {
+				t.Errorf("strict: got calls %v; want none", constraints)
+			}
+		} else if len(constraints) != 1 || constraints[0] != "viaDarwin: darwin" {
+			t.Errorf("got calls %v; want [viaDarwin: darwin]", constraints)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"golang.org/x/tools/go/packages"
+)
+
+// moduleDepths returns, for each module with packages in g, the
+// number of module boundaries crossed by the shortest import path
+// from a top-level package to a package of the module. The modules
+// of the top-level packages have depth 0 and the modules they
+// import directly, including the standard library, have depth 1.
+func (g *PackageGraph) moduleDepths() map[string]int {
+	depths := make(map[string]int)
+	seen := make(map[*packages.Package]bool)
+	level := g.TopPkgs()
+	for d := 0; len(level) > 0; d++ {
+		// Imports within a module do not add to the depth, so
+		// they are visited before moving on to the next level.
+		var next []*packages.Package
+		stack := level
+		for len(stack) > 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			mod := pkgModPath(p)
+			if _, ok := depths[mod]; !ok {
+				depths[mod] = d
+			}
+			for _, imp := range p.Imports {
+				imp = g.GetPackage(imp.PkgPath)
+				if seen[imp] {
+					continue
+				}
+				if pkgModPath(imp) == mod {
+					stack = append(stack, imp)
+				} else {
+					next = append(next, imp)
+				}
+			}
+		}
+		level = next
+	}
+	return depths
+}
+
+// withinDepth returns the vulnerabilities of affVulns in modules at
+// most max hops away from the main module according to depths.
+// Modules of unknown depth are kept.
+func withinDepth(affVulns affectingVulns, depths map[string]int, max int) affectingVulns {
+	var filtered affectingVulns
+	for _, mv := range affVulns {
+		if d, ok := depths[mv.Module.Path]; !ok || d <= max {
+			filtered = append(filtered, mv)
+		}
+	}
+	return filtered
+}
+
+// packagesWithinDepth returns the vulnerabilities of vulns in packages
+// of modules at most max hops away from the main module according to
+// depths. Packages of modules of unknown depth are kept.
+func packagesWithinDepth(vulns []*Vuln, depths map[string]int, max int) []*Vuln {
+	var filtered []*Vuln
+	for _, v := range vulns {
+		if d, ok := depths[pkgModPath(v.Package)]; !ok || d <= max {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,62 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestModuleDepths(t *testing.T) {
+	mainMod := &packages.Module{Path: "golang.org/main"}
+	amod := &packages.Module{Path: "golang.org/amod", Version: "v1.0.0"}
+	bmod := &packages.Module{Path: "golang.org/bmod", Version: "v1.0.0"}
+	cmod := &packages.Module{Path: "golang.org/cmod", Version: "v1.0.0"}
+
+	// main -> main/internal -> amod/a -> amod/a2 -> bmod/b -> cmod/c
+	//                       -> cmod/c
+	c := &packages.Package{PkgPath: "golang.org/cmod/c", Module: cmod}
+	b := &packages.Package{PkgPath: "golang.org/bmod/b", Module: bmod, Imports: map[string]*packages.Package{c.PkgPath: c}}
+	a2 := &packages.Package{PkgPath: "golang.org/amod/a2", Module: amod, Imports: map[string]*packages.Package{b.PkgPath: b}}
+	a := &packages.Package{PkgPath: "golang.org/amod/a", Module: amod, Imports: map[string]*packages.Package{a2.PkgPath: a2}}
+	internal := &packages.Package{PkgPath: "golang.org/main/internal", Module: mainMod, Imports: map[string]*packages.Package{a.PkgPath: a, c.PkgPath: c}}
+	top := &packages.Package{PkgPath: "golang.org/main", Module: mainMod, Imports: map[string]*packages.Package{internal.PkgPath: internal}}
+
+	g := NewPackageGraph("go1.20")
+	g.AddPackages(top)
+	g.topPkgs = []*packages.Package{top}
+
+	depths := g.moduleDepths()
+	want := map[string]int{
+		"golang.org/main": 0,
+		"golang.org/amod": 1,
+		"golang.org/cmod": 1,
+		"golang.org/bmod": 2,
+	}
+	if diff := cmp.Diff(want, depths); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	affVulns := affectingVulns{
+		{Module: amod, Vulns: []*osv.Entry{{ID: "A"}}},
+		{Module: bmod, Vulns: []*osv.Entry{{ID: "B"}}},
+		{Module: &packages.Module{Path: "golang.org/unknown"}, Vulns: []*osv.Entry{{ID: "U"}}},
+	}
+	var got []string
+	for _, mv := range withinDepth(affVulns, depths, 1) {
+		got = append(got, mv.Vulns[0].ID)
+	}
+	if diff := cmp.Diff([]string{"A", "U"}, got); diff != "" {
+		t.Errorf("withinDepth mismatch (-want, +got):\n%s", diff)
+	}
+
+	vulns := []*Vuln{{Package: a}, {Package: b}, {Package: c}}
+	if got := packagesWithinDepth(vulns, depths, 1); len(got) != 2 || got[0].Package != a || got[1].Package != c {
+		t.Errorf("packagesWithinDepth() = %v; want vulnerabilities in %s and %s", got, a, c)
+	}
+}
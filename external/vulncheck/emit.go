@@ -5,9 +5,11 @@
 package vulncheck
 
 import (
+	"cmp"
 	"go/token"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/StevenACoffman/invuln/external/govulncheck"
@@ -26,59 +28,309 @@ func emitOSVs(handler govulncheck.Handler, modVulns []*ModVulns) error {
 	return nil
 }
 
+// emitFindings emits findings to handler in the order of govulncheck.CompareFindings,
+// so that the output does not depend on the order in which the findings
+// are computed, which is that of map iterations and concurrent searches.
+func emitFindings(handler govulncheck.Handler, findings []*govulncheck.Finding) error {
+	slices.SortStableFunc(findings, govulncheck.CompareFindings)
+	for _, f := range findings {
+		if err := handler.Finding(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // emitModuleFindings emits module-level findings for vulnerabilities in modVulns.
 func emitModuleFindings(handler govulncheck.Handler, affVulns affectingVulns) error {
+	var findings []*govulncheck.Finding
 	for _, vuln := range affVulns {
 		for _, osv := range vuln.Vulns {
-			if err := handler.Finding(&govulncheck.Finding{
+			findings = append(findings, &govulncheck.Finding{
 				OSV:          osv.ID,
 				FixedVersion: FixedVersion(modPath(vuln.Module), modVersion(vuln.Module), osv.Affected),
 				Trace:        []*govulncheck.Frame{frameFromModule(vuln.Module)},
-			}); err != nil {
-				return err
-			}
+			})
 		}
 	}
-	return nil
+	return emitFindings(handler, findings)
 }
 
 // emitPackageFinding emits package-level findings fod vulnerabilities in vulns.
 func emitPackageFindings(handler govulncheck.Handler, vulns []*Vuln) error {
+	var findings []*govulncheck.Finding
 	for _, v := range vulns {
-		if err := handler.Finding(&govulncheck.Finding{
+		findings = append(findings, &govulncheck.Finding{
 			OSV:          v.OSV.ID,
 			FixedVersion: FixedVersion(modPath(v.Package.Module), modVersion(v.Package.Module), v.OSV.Affected),
 			Trace:        []*govulncheck.Frame{frameFromPackage(v.Package)},
-		}); err != nil {
-			return err
-		}
+		})
 	}
-	return nil
+	return emitFindings(handler, findings)
 }
 
 // emitCallFindings emits call-level findings for vulnerabilities
-// that have a call stack in callstacks.
-func emitCallFindings(handler govulncheck.Handler, callstacks map[*Vuln]CallStack) error {
-	var vulns []*Vuln
-	for v := range callstacks {
-		vulns = append(vulns, v)
+// that have a call stack in callstacks. Binary indicates that the
+// stacks consist of the symbols found in a binary. Otherwise, the
+// stacks not starting with one of entries are reported as partial,
+// and the findings include the source ranges of their stacks if slice
+// is set.
+//
+// The instances of a generic function or method are reported
+// as one finding for the generic symbol, with the call stack
+// of the instance that has the best one. Instances with equally
+// good call stacks are told apart by their traces.
+func emitCallFindings(handler govulncheck.Handler, callstacks map[*Vuln]CallStack, entries []*FuncNode, binary, slice bool) error {
+	type symbolKey struct {
+		osv, pkg, symbol string
 	}
-
-	for _, vuln := range vulns {
-		stack := callstacks[vuln]
-		if stack == nil {
-			continue
+	isEntry := make(map[*FuncNode]bool)
+	for _, e := range entries {
+		isEntry[e] = true
+	}
+	partial := func(stack CallStack) bool {
+		return !binary && !isEntry[stack[0].Function]
+	}
+	// Consider the vulnerabilities in trace order, so that
+	// the first one wins ties whatever the map order is.
+	traces := make(map[*Vuln][]*govulncheck.Frame)
+	var candidates []*Vuln
+	for v, stack := range callstacks {
+		if stack != nil {
+			traces[v] = traceFromEntries(stack)
+			candidates = append(candidates, v)
+		}
+	}
+	slices.SortFunc(candidates, func(v1, v2 *Vuln) int {
+		return cmp.Or(
+			strings.Compare(v1.OSV.ID, v2.OSV.ID),
+			govulncheck.CompareTraces(traces[v1], traces[v2]),
+		)
+	})
+	best := make(map[symbolKey]*Vuln)
+	exposedBy := make(map[symbolKey][]string)
+	for _, v := range candidates {
+		stack := callstacks[v]
+		k := symbolKey{v.OSV.ID, v.Package.PkgPath, v.Symbol}
+		if b, ok := best[k]; !ok {
+			best[k] = v
+		} else if p1, p2 := partial(stack), partial(callstacks[b]); p1 != p2 {
+			if !p1 {
+				best[k] = v
+			}
+		} else if betterStack(stack, callstacks[b]) {
+			best[k] = v
 		}
+		exposedBy[k] = append(exposedBy[k], v.ExposedBy...)
+	}
+	var findings []*govulncheck.Finding
+	for _, vuln := range best {
+		stack := callstacks[vuln]
 		fixed := FixedVersion(modPath(vuln.Package.Module), modVersion(vuln.Package.Module), vuln.OSV.Affected)
-		if err := handler.Finding(&govulncheck.Finding{
+		confidence := govulncheck.Confidence(govulncheck.ConfidenceLikely)
+		if !binary {
+			confidence = stackConfidence(stack)
+		}
+		if c := linkageConfidence(vuln.Linkage); c.Less(confidence) {
+			confidence = c
+		}
+		trace := traces[vuln]
+		if vuln.Linked {
+			trace[0].Kind = govulncheck.SymbolKindLinked
+		}
+		f := &govulncheck.Finding{
 			OSV:          vuln.OSV.ID,
 			FixedVersion: fixed,
-			Trace:        traceFromEntries(stack),
-		}); err != nil {
-			return err
+			Trace:        trace,
+			Reflection:   reflectiveStack(stack),
+			Confidence:   confidence,
+			Taint:        vuln.Taint,
+			ExposedBy:    sortedUnique(exposedBy[symbolKey{vuln.OSV.ID, vuln.Package.PkgPath, vuln.Symbol}]),
+			Linkage:      vuln.Linkage,
+			Partial:      partial(stack),
 		}
+		if slice {
+			f.Slice = sliceOf(stack)
+		}
+		findings = append(findings, f)
 	}
-	return nil
+	return emitFindings(handler, findings)
+}
+
+// emitUseFindings emits symbol-level findings for the
+// vulnerabilities in vulns of used types, variables,
+// constants, and struct fields.
+func emitUseFindings(handler govulncheck.Handler, vulns []*Vuln) error {
+	var findings []*govulncheck.Finding
+	for _, v := range vulns {
+		if v.Use == nil {
+			continue
+		}
+		sym := frameFromPackage(v.Package)
+		sym.Function = v.Symbol
+		if v.Use.Kind == govulncheck.SymbolKindField {
+			sym.Receiver, sym.Function, _ = strings.Cut(v.Symbol, ".")
+		}
+		sym.Kind = v.Use.Kind
+		// Functions that are referenced may not be called.
+		confidence := govulncheck.Confidence(govulncheck.ConfidenceCertain)
+		if v.Use.Kind == govulncheck.SymbolKindFunc {
+			confidence = govulncheck.ConfidencePossible
+		}
+		user := frameFromPackage(v.Use.Parent.Package)
+		user.Function = v.Use.Parent.Name
+		user.Receiver = v.Use.Parent.Receiver()
+		user.Position = &govulncheck.Position{
+			Filename: pathRelativeToMod(v.Use.Pos.Filename, v.Use.Parent),
+			Offset:   v.Use.Pos.Offset,
+			Line:     v.Use.Pos.Line,
+			Column:   v.Use.Pos.Column,
+		}
+		findings = append(findings, &govulncheck.Finding{
+			OSV:          v.OSV.ID,
+			FixedVersion: FixedVersion(modPath(v.Package.Module), modVersion(v.Package.Module), v.OSV.Affected),
+			Trace:        []*govulncheck.Frame{sym, user},
+			Confidence:   confidence,
+		})
+	}
+	return emitFindings(handler, findings)
+}
+
+// sliceOf returns the source ranges along stack: for each function,
+// the lines from its declaration to the call it makes in stack, and
+// all the lines of the vulnerable symbol. The ranges are sorted by
+// file, and those overlapping or adjacent in a file are merged.
+func sliceOf(stack CallStack) []*govulncheck.SourceRange {
+	type file struct {
+		module, version, name string
+	}
+	lines := make(map[file][][2]int)
+	add := func(f *FuncNode, pos *token.Position, start, end int) {
+		if pos == nil || start <= 0 {
+			return
+		}
+		fr := frameFromPackage(f.Package)
+		k := file{fr.Module, fr.Version, pathRelativeToMod(pos.Filename, f)}
+		lines[k] = append(lines[k], [2]int{start, end})
+	}
+	for _, e := range stack {
+		f, call := e.Function, e.Call
+		switch {
+		case call == nil:
+			// The vulnerable symbol, all of whose lines count.
+			if f.Pos != nil {
+				end := f.Pos.Line
+				if f.End != nil {
+					end = f.End.Line
+				}
+				add(f, f.Pos, f.Pos.Line, end)
+			}
+		case call.Pos == nil:
+		case f.Pos != nil && f.Pos.Filename == call.Pos.Filename && f.Pos.Line <= call.Pos.Line:
+			add(f, f.Pos, f.Pos.Line, call.Pos.Line)
+		default:
+			// The call is not in the body of the declaration, as
+			// for package initialization, so only its line counts.
+			add(f, call.Pos, call.Pos.Line, call.Pos.Line)
+		}
+	}
+
+	var files []file
+	for k := range lines {
+		files = append(files, k)
+	}
+	slices.SortFunc(files, func(f1, f2 file) int {
+		return cmp.Or(
+			strings.Compare(f1.module, f2.module),
+			strings.Compare(f1.version, f2.version),
+			strings.Compare(f1.name, f2.name),
+		)
+	})
+	var ranges []*govulncheck.SourceRange
+	for _, k := range files {
+		spans := lines[k]
+		slices.SortFunc(spans, func(s1, s2 [2]int) int { return cmp.Compare(s1[0], s2[0]) })
+		var last *govulncheck.SourceRange
+		for _, s := range spans {
+			if last != nil && s[0] <= last.EndLine+1 {
+				last.EndLine = max(last.EndLine, s[1])
+				continue
+			}
+			last = &govulncheck.SourceRange{
+				Module:    k.module,
+				Version:   k.version,
+				Filename:  k.name,
+				StartLine: s[0],
+				EndLine:   s[1],
+			}
+			ranges = append(ranges, last)
+		}
+	}
+	return ranges
+}
+
+// betterStack reports whether s1 is a better witness than s2:
+// shorter, with fewer dynamic calls, or else in position order.
+func betterStack(s1, s2 CallStack) bool {
+	if len(s1) != len(s2) {
+		return len(s1) < len(s2)
+	}
+	if w1, w2 := weight(s1), weight(s2); w1 != w2 {
+		return w1 < w2
+	}
+	for i := range s1 {
+		c1, c2 := s1[i].Call, s2[i].Call
+		if c1 == nil || c2 == nil || c1 == c2 {
+			continue
+		}
+		if csLess(c1, c2) {
+			return true
+		}
+		if csLess(c2, c1) {
+			return false
+		}
+	}
+	return false
+}
+
+// stackConfidence returns the confidence of a finding with stack:
+// possible if it has a call made through reflection, likely if it has
+// another dynamic call, and certain if all its calls are static.
+func stackConfidence(stack CallStack) govulncheck.Confidence {
+	confidence := govulncheck.Confidence(govulncheck.ConfidenceCertain)
+	for _, e := range stack {
+		switch {
+		case e.Call == nil:
+		case e.Call.Reflective:
+			return govulncheck.ConfidencePossible
+		case !e.Call.Resolved:
+			confidence = govulncheck.ConfidenceLikely
+		}
+	}
+	return confidence
+}
+
+// linkageConfidence returns the highest confidence of
+// findings of vulnerable symbols reached through linkage.
+func linkageConfidence(linkage govulncheck.Linkage) govulncheck.Confidence {
+	switch linkage {
+	case govulncheck.LinkageLinkname:
+		return govulncheck.ConfidenceLikely
+	case govulncheck.LinkageAssembly:
+		return govulncheck.ConfidencePossible
+	}
+	return govulncheck.ConfidenceCertain
+}
+
+// reflectiveStack reports whether stack contains a call
+// made through reflection.
+func reflectiveStack(stack CallStack) bool {
+	for _, e := range stack {
+		if e.Call != nil && e.Call.Reflective {
+			return true
+		}
+	}
+	return false
 }
 
 // traceFromEntries creates a sequence of
@@ -90,9 +342,18 @@ func traceFromEntries(vcs CallStack) []*govulncheck.Frame {
 		e := vcs[i]
 		fr := frameFromPackage(e.Function.Package)
 		fr.Function = e.Function.Name
-		fr.Receiver = e.Function.Receiver()
+		// Generic receiver types are named without type arguments.
+		fr.Receiver, _, _ = strings.Cut(e.Function.Receiver(), "[")
+		if args := e.Function.TypeArgs; len(args) > 0 {
+			fr.TypeArgs = "[" + strings.Join(args, ", ") + "]"
+		}
 		isSink := i == (len(vcs) - 1)
 		fr.Position = posFromStackEntry(e, isSink)
+		if isSink && e.Function.Pos != nil {
+			fr.BuildConstraint = e.Function.BuildConstraint
+		} else if e.Call != nil {
+			fr.BuildConstraint = e.Call.BuildConstraint
+		}
 		frames = append(frames, fr)
 	}
 	return frames
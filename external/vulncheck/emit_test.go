@@ -0,0 +1,190 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/test"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestEmitCallFindingsDeterministic(t *testing.T) {
+	mmain := &packages.Module{Path: "example.com/m", Main: true}
+	mvuln := &packages.Module{Path: "golang.org/vuln"}
+	pmain := &packages.Package{PkgPath: "example.com/m", Module: mmain}
+	pvuln := &packages.Package{PkgPath: "golang.org/vuln", Module: mvuln}
+	pos := func(line int) *token.Position { return &token.Position{Filename: "f.go", Line: line, Offset: line} }
+
+	e1 := &FuncNode{Name: "entry1", Package: pmain, Pos: pos(1)}
+	e2 := &FuncNode{Name: "entry2", Package: pmain, Pos: pos(2)}
+	entries := []*FuncNode{e1, e2}
+	stack := func(sink *FuncNode, entry *FuncNode, line int) CallStack {
+		return CallStack{
+			{Function: entry, Call: &CallSite{Parent: entry, Resolved: true, Pos: pos(line)}},
+			{Function: sink},
+		}
+	}
+
+	callstacks := make(map[*Vuln]CallStack)
+	for i, id := range []string{"GO-3", "GO-1", "GO-2"} {
+		for _, sym := range []string{"B", "A", "C"} {
+			sink := &FuncNode{Name: sym, Package: pvuln, Pos: pos(10 + i)}
+			v := &Vuln{OSV: &osv.Entry{ID: id}, Symbol: sym, Package: pvuln, CallSink: sink}
+			callstacks[v] = stack(sink, e1, 20+i)
+		}
+	}
+	// Instances of a generic function with equally good call stacks.
+	for _, args := range []string{"int", "string", "bool"} {
+		sink := &FuncNode{Name: "G", Package: pvuln, Pos: pos(30), TypeArgs: []string{args}}
+		v := &Vuln{OSV: &osv.Entry{ID: "GO-4"}, Symbol: "G", Package: pvuln, CallSink: sink}
+		callstacks[v] = stack(sink, e2, 40)
+	}
+
+	emit := func() []string {
+		h := test.NewMockHandler()
+		if err := emitCallFindings(h, callstacks, entries, false, false); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, f := range h.FindingMessages {
+			var frames []string
+			for _, fr := range f.Trace {
+				frames = append(frames, fr.Function+fr.TypeArgs)
+			}
+			got = append(got, fmt.Sprintf("%s %s", f.OSV, strings.Join(frames, " <- ")))
+		}
+		return got
+	}
+
+	want := []string{
+		"GO-1 A <- entry1",
+		"GO-1 B <- entry1",
+		"GO-1 C <- entry1",
+		"GO-2 A <- entry1",
+		"GO-2 B <- entry1",
+		"GO-2 C <- entry1",
+		"GO-3 A <- entry1",
+		"GO-3 B <- entry1",
+		"GO-3 C <- entry1",
+		"GO-4 G[bool] <- entry2",
+	}
+	// Map iteration order is randomized, so a few runs
+	// would very likely catch any dependency on it.
+	for range 20 {
+		if got := emit(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("got findings\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestSliceOf(t *testing.T) {
+	mmain := &packages.Module{Path: "example.com/m", Dir: "/m", Main: true}
+	mvuln := &packages.Module{Path: "golang.org/vuln", Version: "v1.0.0", Dir: "/vuln"}
+	pmain := &packages.Package{PkgPath: "example.com/m", Module: mmain}
+	pvuln := &packages.Package{PkgPath: "golang.org/vuln", Module: mvuln}
+	pos := func(file string, line int) *token.Position { return &token.Position{Filename: file, Line: line} }
+
+	main := &FuncNode{Name: "main", Package: pmain, Pos: pos("/m/main.go", 10)}
+	helper := &FuncNode{Name: "helper", Package: pmain, Pos: pos("/m/main.go", 20)}
+	other := &FuncNode{Name: "other", Package: pmain, Pos: pos("/m/other.go", 5)}
+	pkgInit := &FuncNode{Name: "init", Package: pmain, Pos: pos("/m/main.go", 0)}
+	vuln := &FuncNode{Name: "Vuln", Package: pvuln, Pos: pos("/vuln/vuln.go", 3), End: pos("/vuln/vuln.go", 7)}
+
+	for _, test := range []struct {
+		name  string
+		stack CallStack
+		want  []string
+	}{
+		{
+			name: "merged",
+			stack: CallStack{
+				{Function: main, Call: &CallSite{Parent: main, Pos: pos("/m/main.go", 14)}},
+				{Function: helper, Call: &CallSite{Parent: helper, Pos: pos("/m/main.go", 22)}},
+				{Function: other, Call: &CallSite{Parent: other, Pos: pos("/m/other.go", 8)}},
+				{Function: vuln},
+			},
+			want: []string{
+				"example.com/m main.go:10-14",
+				"example.com/m main.go:20-22",
+				"example.com/m other.go:5-8",
+				"golang.org/vuln@v1.0.0 vuln.go:3-7",
+			},
+		},
+		{
+			name: "adjacent",
+			stack: CallStack{
+				{Function: main, Call: &CallSite{Parent: main, Pos: pos("/m/main.go", 19)}},
+				{Function: helper, Call: &CallSite{Parent: helper, Pos: pos("/m/main.go", 25)}},
+				{Function: vuln},
+			},
+			want: []string{
+				"example.com/m main.go:10-25",
+				"golang.org/vuln@v1.0.0 vuln.go:3-7",
+			},
+		},
+		{
+			name: "init",
+			stack: CallStack{
+				{Function: pkgInit, Call: &CallSite{Parent: pkgInit, Pos: pos("/m/other.go", 30)}},
+				{Function: vuln},
+			},
+			want: []string{
+				"example.com/m other.go:30-30",
+				"golang.org/vuln@v1.0.0 vuln.go:3-7",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, r := range sliceOf(test.stack) {
+				mod := r.Module
+				if r.Version != "" {
+					mod += "@" + r.Version
+				}
+				got = append(got, fmt.Sprintf("%s %s:%d-%d", mod, r.Filename, r.StartLine, r.EndLine))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v; want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMalformedWarnings(t *testing.T) {
+	valid := &osv.Entry{
+		ID:       "GO-2024-0001",
+		Modified: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Affected: []osv.Affected{{
+			Module: osv.Module{Path: "golang.org/vuln", Ecosystem: osv.GoEcosystem},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{{Introduced: "0"}, {Fixed: "1.0.0"}}}},
+		}},
+	}
+	unsorted := &osv.Entry{
+		ID:       "GO-2024-0002",
+		Modified: valid.Modified,
+		Affected: []osv.Affected{{
+			Module: osv.Module{Path: "golang.org/vuln", Ecosystem: osv.GoEcosystem},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver, Events: []osv.RangeEvent{{Introduced: "1.0.0"}, {Fixed: "0.9.0"}}}},
+		}},
+	}
+	mv := []*ModVulns{
+		{Module: &packages.Module{Path: "golang.org/vuln"}, Vulns: []*osv.Entry{unsorted, valid}},
+		{Module: &packages.Module{Path: "golang.org/vuln/v2"}, Vulns: []*osv.Entry{{ID: "GO-2024-0000"}, unsorted}},
+	}
+	want := []string{
+		"warning: GO-2024-0000 is malformed, so it may be matched against the wrong versions or code: modified: missing (and 1 more problem)",
+		`warning: GO-2024-0002 is malformed, so it may be matched against the wrong versions or code: affected[0].ranges[0].events[1].fixed: version "0.9.0" is not greater than the version of the previous event`,
+	}
+	if got := malformedWarnings(mv); !reflect.DeepEqual(got, want) {
+		t.Errorf("malformedWarnings =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -5,6 +5,9 @@
 package vulncheck
 
 import (
+	"fmt"
+	"path"
+	"sort"
 	"strings"
 
 	"golang.org/x/tools/go/ssa"
@@ -12,11 +15,12 @@ import (
 
 // entryPoints returns functions of topPackages considered entry
 // points of govulncheck analysis: main, inits, and exported methods
-// and functions.
+// and functions. The inits, which also run the initializers of
+// package-level variables, are left out unless inits is set.
 //
 // TODO(https://go.dev/issue/57221): currently, entry functions
 // that are generics are not considered an entry point.
-func entryPoints(topPackages []*ssa.Package) []*ssa.Function {
+func entryPoints(topPackages []*ssa.Package, inits bool) []*ssa.Function {
 	var entries []*ssa.Function
 	for _, pkg := range topPackages {
 		if pkg.Pkg.Name() == "main" {
@@ -28,7 +32,7 @@ func entryPoints(topPackages []*ssa.Package) []*ssa.Function {
 			// and the init function is synthetic
 			entries = append(entries, memberFuncs(pkg.Members["main"], pkg.Prog)...)
 			for name, member := range pkg.Members {
-				if strings.HasPrefix(name, "init#") || name == "init" {
+				if inits && (strings.HasPrefix(name, "init#") || name == "init") {
 					entries = append(entries, memberFuncs(member, pkg.Prog)...)
 				}
 			}
@@ -36,7 +40,7 @@ func entryPoints(topPackages []*ssa.Package) []*ssa.Function {
 		}
 		for _, member := range pkg.Members {
 			for _, f := range memberFuncs(member, pkg.Prog) {
-				if isEntry(f) {
+				if isEntry(f) && (inits || !isPackageInit(f)) {
 					entries = append(entries, f)
 				}
 			}
@@ -48,9 +52,91 @@ func entryPoints(topPackages []*ssa.Package) []*ssa.Function {
 func isEntry(f *ssa.Function) bool {
 	// it should be safe to ignore checking that the signature of the "init" function
 	// is valid, since it is synthetic
-	if f.Name() == "init" && f.Synthetic == "package initializer" {
+	if isPackageInit(f) {
 		return true
 	}
 
 	return f.Synthetic == "" && f.Object() != nil && f.Object().Exported()
 }
+
+// isPackageInit reports whether f is the synthetic function initializing
+// a package, which runs the initializers of its package-level variables,
+// its init functions, and the initialization of the packages it imports.
+func isPackageInit(f *ssa.Function) bool {
+	return f.Name() == "init" && f.Synthetic == "package initializer"
+}
+
+// CheckEntryPattern returns an error if pattern is not a valid
+// pattern of entry points. See govulncheck.Config.EntryPoints.
+func CheckEntryPattern(pattern string) error {
+	pkg, symbol, ok := splitEntryPattern(pattern)
+	if !ok {
+		return fmt.Errorf("invalid entry point pattern %q: must have the form package.symbol", pattern)
+	}
+	for _, p := range []string{pkg, symbol} {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid entry point pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// splitEntryPattern splits pattern into its package
+// and symbol parts, as in golang.org/x/net/http2.Server.*.
+func splitEntryPattern(pattern string) (pkg, symbol string, ok bool) {
+	slash := strings.LastIndex(pattern, "/")
+	dot := strings.Index(pattern[slash+1:], ".")
+	if dot < 0 {
+		return "", "", false
+	}
+	dot += slash + 1
+	pkg, symbol = pattern[:dot], pattern[dot+1:]
+	return pkg, symbol, pkg != "" && symbol != ""
+}
+
+// matchingEntryPoints returns the functions and methods of the packages
+// of prog matching any of patterns, sorted by name. It returns an error
+// if there are none, as the analysis would then find nothing.
+func matchingEntryPoints(prog *ssa.Program, patterns []string) ([]*ssa.Function, error) {
+	type pattern struct{ pkg, symbol string }
+	var pats []pattern
+	for _, p := range patterns {
+		if err := CheckEntryPattern(p); err != nil {
+			return nil, err
+		}
+		pkg, symbol, _ := splitEntryPattern(p)
+		pats = append(pats, pattern{pkg, symbol})
+	}
+	matches := func(f *ssa.Function) bool {
+		for _, p := range pats {
+			if ok, _ := path.Match(p.pkg, f.Pkg.Pkg.Path()); !ok {
+				continue
+			}
+			if ok, _ := path.Match(p.symbol, dbFuncName(f)); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	seen := make(map[*ssa.Function]bool)
+	var entries []*ssa.Function
+	for _, pkg := range prog.AllPackages() {
+		for _, member := range pkg.Members {
+			for _, f := range memberFuncs(member, prog) {
+				if seen[f] || f.Pkg == nil || (f.Synthetic != "" && !isPackageInit(f)) {
+					continue
+				}
+				seen[f] = true
+				if matches(f) {
+					entries = append(entries, f)
+				}
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no functions match the entry point patterns %s", strings.Join(patterns, ", "))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].String() < entries[j].String() })
+	return entries, nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestCheckEntryPattern(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		ok      bool
+	}{
+		{"fmt.Println", true},
+		{"example.com/app/server.Handle*", true},
+		{"example.com/app/*.Server.ServeHTTP", true},
+		{"example.com/app/server", false},
+		{"example.com/app/server.", false},
+		{".Handle", false},
+		{"example.com/app/server.Handle[", false},
+	} {
+		if err := CheckEntryPattern(test.pattern); (err == nil) != test.ok {
+			t.Errorf("CheckEntryPattern(%q) = %v; want ok = %v", test.pattern, err, test.ok)
+		}
+	}
+}
+
+func TestEntryPatterns(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import (
+				"golang.org/amod/avuln"
+				"golang.org/bmod/bvuln"
+			)
+
+			type Server struct{}
+
+			func (Server) HandleA() {
+				avuln.VulnData{}.Vuln1()
+			}
+
+			func HandleB() {
+				bvuln.Vuln()
+			}
+
+			func Unregistered() {
+				avuln.VulnData{}.Vuln2()
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/amod@v1.1.3",
+			Files: map[string]interface{}{"avuln/avuln.go": `
+			package avuln
+
+			type VulnData struct {}
+			func (v VulnData) Vuln1() {}
+			func (v VulnData) Vuln2() {}
+			`},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		entries []string
+		want    map[string][]string
+	}{
+		{[]string{"golang.org/entry/x.Server.HandleA"}, map[string][]string{
+			"golang.org/entry/x.Server.HandleA": {"golang.org/amod/avuln.VulnData.Vuln1"},
+		}},
+		{[]string{"golang.org/entry/x.*Handle*"}, map[string][]string{
+			"golang.org/entry/x.Server.HandleA": {"golang.org/amod/avuln.VulnData.Vuln1"},
+			"golang.org/entry/x.HandleB":        {"golang.org/bmod/bvuln.Vuln"},
+		}},
+		{[]string{"golang.org/entry/x.HandleB", "golang.org/*/x.Unregistered"}, map[string][]string{
+			"golang.org/entry/x.HandleB":      {"golang.org/bmod/bvuln.Vuln"},
+			"golang.org/entry/x.Unregistered": {"golang.org/amod/avuln.VulnData.Vuln2"},
+		}},
+	} {
+		graph := NewPackageGraph("go1.18")
+		err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := &govulncheck.Config{ScanLevel: "symbol", EntryPoints: tc.entries}
+		result, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := callGraphToStrMap(result); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("entries %v: got call graph %v; want %v", tc.entries, got, tc.want)
+		}
+	}
+
+	graph := NewPackageGraph("go1.18")
+	if err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &govulncheck.Config{ScanLevel: "symbol", EntryPoints: []string{"golang.org/entry/x.Missing"}}
+	if _, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph); err == nil {
+		t.Error("want error for entry point patterns matching no functions")
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import (
+				"golang.org/amod/avuln"
+				"golang.org/bmod/bvuln"
+			)
+
+			type Decoder struct{}
+
+			func (*Decoder) Decode() {
+				parse()
+			}
+
+			func Parse() {
+				parse()
+				bvuln.Vuln()
+			}
+
+			func parse() {
+				avuln.VulnData{}.Vuln1()
+			}
+
+			func init() {
+				avuln.VulnData{}.Vuln2()
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/amod@v1.1.3",
+			Files: map[string]interface{}{"avuln/avuln.go": `
+			package avuln
+
+			type VulnData struct {}
+			func (v VulnData) Vuln1() {}
+			func (v VulnData) Vuln2() {}
+			`},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := NewPackageGraph("go1.18")
+	if err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &govulncheck.Config{ScanLevel: "symbol", Library: true}
+	result, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for _, v := range result.Vulns {
+		if v.CallSink != nil {
+			got[v.Symbol] = v.ExposedBy
+		}
+	}
+	// Vuln2 is only called during package initialization,
+	// which is not part of the API.
+	want := map[string][]string{
+		"VulnData.Vuln1": {"golang.org/entry/x.Decoder.Decode", "golang.org/entry/x.Parse"},
+		"Vuln":           {"golang.org/entry/x.Parse"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got exposure %v; want %v", got, want)
+	}
+}
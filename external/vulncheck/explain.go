@@ -0,0 +1,132 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// An Explanation describes how a vulnerable symbol is reached from
+// the entry points of an analysis, as a whole rather than through
+// individual call stacks.
+type Explanation struct {
+	// OSV is the ID of the vulnerability.
+	OSV string
+
+	// Symbol is the vulnerable symbol, in the form package.symbol.
+	Symbol string
+
+	// Sinks are the functions corresponding to Symbol, of which there
+	// are several for the instances of generic functions.
+	Sinks []*FuncNode
+
+	// Entries are the entry points from which Symbol is reached,
+	// sorted by position.
+	Entries []*FuncNode
+
+	// Modules are the modules of the functions on the paths from
+	// Entries to Sinks, sorted by path.
+	Modules []*packages.Module
+
+	// Calls are the calls on the paths from Entries to Sinks, sorted
+	// by callee and then by caller.
+	Calls []*ExplainedCall
+}
+
+// An ExplainedCall is a call on the paths to a vulnerable symbol.
+type ExplainedCall struct {
+	Caller, Callee *FuncNode
+
+	// Site is the call site, in Caller.
+	Site *CallSite
+
+	// Dynamic indicates whether the callee is resolved at run time,
+	// as for calls of interface methods and function values, so that
+	// the call may not actually happen. It is the opposite of
+	// Site.Resolved.
+	Dynamic bool
+}
+
+// Explain explains how the vulnerable symbol of the vulnerability with
+// ID vulnID is reached in res, which is the result of a symbol level
+// source analysis. The symbol is in the form used by the vulnerability
+// database, as in Server.ServeHTTP, optionally preceded by the path of
+// its package, as in golang.org/x/net/http2.Server.ServeHTTP.
+//
+// Explain returns an error if the symbol is not found to be called.
+func Explain(res *Result, vulnID, symbol string) (*Explanation, error) {
+	var sinks []*FuncNode
+	var name string
+	seenSinks := make(map[*FuncNode]bool)
+	for _, v := range res.Vulns {
+		if v.OSV.ID != vulnID || v.CallSink == nil || v.Package == nil {
+			continue
+		}
+		full := v.Package.PkgPath + "." + v.Symbol
+		if symbol != v.Symbol && symbol != full {
+			continue
+		}
+		name = full
+		if !seenSinks[v.CallSink] {
+			seenSinks[v.CallSink] = true
+			sinks = append(sinks, v.CallSink)
+		}
+	}
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("%s: vulnerable symbol %s is not called", vulnID, symbol)
+	}
+	sort.SliceStable(sinks, func(i, j int) bool { return funcLess(sinks[i], sinks[j]) })
+
+	isEntry := make(map[*FuncNode]bool)
+	for _, e := range res.EntryFunctions {
+		isEntry[e] = true
+	}
+	x := &Explanation{OSV: vulnID, Symbol: name, Sinks: sinks}
+	modules := make(map[string]*packages.Module)
+	seen := make(map[*FuncNode]bool)
+	queue := slices.Clone(sinks)
+	for _, f := range queue {
+		seen[f] = true
+	}
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+		if isEntry[f] {
+			x.Entries = append(x.Entries, f)
+		}
+		if f.Package != nil && f.Package.Module != nil {
+			modules[f.Package.Module.Path] = f.Package.Module
+		}
+		for _, cs := range sortedCallsites(f.CallSites) {
+			x.Calls = append(x.Calls, &ExplainedCall{
+				Caller:  cs.Parent,
+				Callee:  f,
+				Site:    cs,
+				Dynamic: !cs.Resolved,
+			})
+			if !seen[cs.Parent] {
+				seen[cs.Parent] = true
+				queue = append(queue, cs.Parent)
+			}
+		}
+	}
+	sort.SliceStable(x.Entries, func(i, j int) bool { return funcLess(x.Entries[i], x.Entries[j]) })
+	sort.SliceStable(x.Calls, func(i, j int) bool {
+		c1, c2 := x.Calls[i], x.Calls[j]
+		if c1.Callee != c2.Callee {
+			return funcLess(c1.Callee, c2.Callee)
+		}
+		return funcLess(c1.Caller, c2.Caller)
+	})
+	for _, m := range modules {
+		x.Modules = append(x.Modules, m)
+	}
+	sort.Slice(x.Modules, func(i, j int) bool { return x.Modules[i].Path < x.Modules[j].Path })
+	return x, nil
+}
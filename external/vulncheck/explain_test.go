@@ -0,0 +1,121 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"fmt"
+	"go/token"
+	"reflect"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestExplain(t *testing.T) {
+	// Call graph structure for the test program
+	//    entry1      entry2
+	//      |           |
+	//    interm1       |
+	//      |    \     /
+	//      |   interm2(interface)
+	//      |   /     |
+	//     vuln1    vuln2
+	mmain := &packages.Module{Path: "example.com/m", Main: true}
+	mdep := &packages.Module{Path: "golang.org/dep"}
+	mvuln := &packages.Module{Path: "golang.org/vuln"}
+	pmain := &packages.Package{PkgPath: "example.com/m", Module: mmain}
+	pdep := &packages.Package{PkgPath: "golang.org/dep", Module: mdep}
+	pvuln := &packages.Package{PkgPath: "golang.org/vuln", Module: mvuln}
+	pos := func(line int) *token.Position { return &token.Position{Filename: "f.go", Line: line} }
+
+	e1 := &FuncNode{Name: "entry1", Package: pmain, Pos: pos(1)}
+	e2 := &FuncNode{Name: "entry2", Package: pmain, Pos: pos(2)}
+	i1 := &FuncNode{Name: "interm1", Package: pdep, Pos: pos(3), CallSites: []*CallSite{{Parent: e1, Resolved: true}}}
+	i2 := &FuncNode{Name: "interm2", Package: pdep, Pos: pos(4), CallSites: []*CallSite{{Parent: e2, Resolved: true}, {Parent: i1, Resolved: true}}}
+	v1 := &FuncNode{Name: "vuln1", Package: pvuln, Pos: pos(5), CallSites: []*CallSite{{Parent: i1, Resolved: true}, {Parent: i2, Resolved: false}}}
+	v2 := &FuncNode{Name: "vuln2", Package: pvuln, Pos: pos(6), CallSites: []*CallSite{{Parent: i2, Resolved: false}}}
+
+	o := &osv.Entry{ID: "o"}
+	res := &Result{
+		EntryFunctions: []*FuncNode{e1, e2},
+		Vulns: []*Vuln{
+			{CallSink: v1, Package: pvuln, OSV: o, Symbol: "vuln1"},
+			{CallSink: v2, Package: pvuln, OSV: o, Symbol: "vuln2"},
+			{Package: pvuln, OSV: &osv.Entry{ID: "other"}},
+		},
+	}
+
+	calls := func(x *Explanation) []string {
+		var cs []string
+		for _, c := range x.Calls {
+			cs = append(cs, fmt.Sprintf("%s->%s dynamic=%v", c.Caller.Name, c.Callee.Name, c.Dynamic))
+		}
+		return cs
+	}
+	names := func(fs []*FuncNode) []string {
+		var ns []string
+		for _, f := range fs {
+			ns = append(ns, f.Name)
+		}
+		return ns
+	}
+	modules := func(ms []*packages.Module) []string {
+		var ps []string
+		for _, m := range ms {
+			ps = append(ps, m.Path)
+		}
+		return ps
+	}
+
+	x, err := Explain(res, "o", "golang.org/vuln.vuln1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Symbol != "golang.org/vuln.vuln1" {
+		t.Errorf("got symbol %s; want golang.org/vuln.vuln1", x.Symbol)
+	}
+	if got, want := names(x.Sinks), []string{"vuln1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got sinks %v; want %v", got, want)
+	}
+	if got, want := names(x.Entries), []string{"entry1", "entry2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got entries %v; want %v", got, want)
+	}
+	if got, want := modules(x.Modules), []string{"example.com/m", "golang.org/dep", "golang.org/vuln"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got modules %v; want %v", got, want)
+	}
+	wantCalls := []string{
+		"entry1->interm1 dynamic=false",
+		"entry2->interm2 dynamic=false",
+		"interm1->interm2 dynamic=false",
+		"interm1->vuln1 dynamic=false",
+		"interm2->vuln1 dynamic=true",
+	}
+	if got := calls(x); !reflect.DeepEqual(got, wantCalls) {
+		t.Errorf("got calls %v; want %v", got, wantCalls)
+	}
+
+	// Symbols can be given without their package.
+	x, err = Explain(res, "o", "vuln2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(x.Entries), []string{"entry1", "entry2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got entries %v; want %v", got, want)
+	}
+	if got, want := len(x.Calls), 4; got != want {
+		t.Errorf("got %d calls; want %d", got, want)
+	}
+
+	for _, test := range []struct{ id, symbol string }{
+		{"o", "vuln3"},
+		{"other", "vuln1"},
+		{"missing", "vuln1"},
+	} {
+		if _, err := Explain(res, test.id, test.symbol); err == nil {
+			t.Errorf("Explain(%s, %s): want error", test.id, test.symbol)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external/client"
+	"golang.org/x/tools/go/packages"
+)
+
+// ExportLoadMode is the mode in which LoadExportPackages loads
+// packages. Lacking packages.NeedDeps, only the packages given
+// to packages.Load are type checked from source, their
+// dependencies being loaded from their export data.
+const ExportLoadMode = SymbolLoadMode&^packages.NeedDeps | packages.NeedCompiledGoFiles
+
+// SourcePackages returns the paths of the packages of graph that
+// symbol level scans need to analyze from source: the top-level
+// packages, the packages of the vulnerabilities of client affecting
+// graph on goos/goarch, and the packages importing them, directly or
+// not, that the top-level packages import. Packages loaded in
+// PackageLoadMode suffice.
+//
+// The other packages can be loaded from their export data, sparing the
+// type checking of their code, at the cost of the calls they make to
+// vulnerable symbols only through interfaces and function values,
+// which are then not seen.
+func SourcePackages(ctx context.Context, client *client.Client, graph *PackageGraph, goos, goarch string) ([]string, error) {
+	mv, err := FetchVulnerabilities(ctx, client, graph.Modules())
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mv {
+		m.UnknownVersion = graph.unknownVersions[m.Module.Path]
+	}
+	vulnPkgs := make(map[*packages.Package]bool)
+	for _, v := range importedVulnPackages(affectingVulnerabilities(mv, goos, goarch), graph) {
+		vulnPkgs[v.Package] = true
+	}
+
+	// Import graphs have no cycles, so whether a package
+	// is on a path to a vulnerable one is memoized.
+	onPath := make(map[*packages.Package]bool)
+	var visit func(*packages.Package) bool
+	visit = func(p *packages.Package) bool {
+		if on, ok := onPath[p]; ok {
+			return on
+		}
+		on := vulnPkgs[graph.GetPackage(p.PkgPath)]
+		for _, imp := range p.Imports {
+			if visit(imp) {
+				on = true
+			}
+		}
+		onPath[p] = on
+		return on
+	}
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(p *packages.Package) {
+		if !seen[p.PkgPath] {
+			seen[p.PkgPath] = true
+			paths = append(paths, p.PkgPath)
+		}
+	}
+	for _, p := range graph.TopPkgs() {
+		visit(p)
+		add(p)
+	}
+	for p, on := range onPath {
+		if on && p.PkgPath != "unsafe" {
+			add(p)
+		}
+	}
+	slices.Sort(paths)
+	return paths, nil
+}
+
+// LoadExportPackages is like LoadPackagesAndMods for symbol level
+// analysis, except that only the packages matching patterns and those
+// in paths, as returned by SourcePackages, are type checked from
+// source. Their dependencies are loaded from the export data built by
+// the go command, which reuses its build cache. Of the packages loaded,
+// those in top, the paths of the packages matching patterns, are the
+// top-level packages of g.
+func (g *PackageGraph) LoadExportPackages(cfg *packages.Config, tags, patterns, top, paths []string) error {
+	if len(tags) > 0 {
+		cfg.BuildFlags = append(cfg.BuildFlags, fmt.Sprintf("-tags=%s", strings.Join(tags, ",")))
+	}
+	cfg.Mode |= ExportLoadMode
+
+	isTop := make(map[string]bool)
+	for _, p := range top {
+		isTop[p] = true
+	}
+	roots := slices.Clone(patterns)
+	for _, p := range paths {
+		if !isTop[p] {
+			roots = append(roots, p)
+		}
+	}
+
+	progress := reportLoad(cfg)
+	pkgs, err := packages.Load(cfg, roots...)
+	if err != nil {
+		return err
+	}
+	progress.Complete()
+	// The packages matching patterns are those of the top-level
+	// paths, test variants included; those of the other paths
+	// are loaded only for their syntax and types.
+	pkgs = slices.DeleteFunc(pkgs, func(p *packages.Package) bool { return !isTop[p.PkgPath] })
+	return g.AddLoadedPackages(pkgs)
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestLoadExportPackages(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import (
+				"golang.org/cmod/c"
+				"golang.org/emod/e"
+			)
+
+			func X() {
+				c.C()
+				e.E()
+			}
+			`,
+			}},
+		{
+			Name: "golang.org/cmod@v1.1.3",
+			Files: map[string]interface{}{"c/c.go": `
+			package c
+
+			import "golang.org/amod/avuln"
+
+			func C() {
+				avuln.VulnData{}.Vuln1()
+			}
+			`},
+		},
+		{
+			Name: "golang.org/emod@v0.5.0",
+			Files: map[string]interface{}{"e/e.go": `
+			package e
+
+			import "strings"
+
+			func E() string {
+				return strings.ToUpper("e")
+			}
+			`},
+		},
+		{
+			Name: "golang.org/amod@v1.1.3",
+			Files: map[string]interface{}{"avuln/avuln.go": `
+			package avuln
+
+			type VulnData struct {}
+			func (v VulnData) Vuln1() {}
+			func (v VulnData) Vuln2() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	dir := filepath.Dir(e.File("golang.org/entry", "x/x.go"))
+	patterns := []string{"."}
+
+	cfg := *e.Config
+	cfg.Dir = dir
+	pkgGraph := NewPackageGraph("go1.20")
+	if err := pkgGraph.LoadPackagesAndMods(&cfg, nil, patterns, false); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := SourcePackages(ctx, c, pkgGraph, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"golang.org/amod/avuln", "golang.org/cmod/c", "golang.org/entry/x"}
+	if diff := cmp.Diff(want, paths); diff != "" {
+		t.Fatalf("source packages mismatch (-want, +got):\n%s", diff)
+	}
+
+	cfg = *e.Config
+	cfg.Dir = dir
+	graph := NewPackageGraph("go1.20")
+	if err := graph.LoadExportPackages(&cfg, nil, patterns, []string{"golang.org/entry/x"}, paths); err != nil {
+		t.Fatal(err)
+	}
+	if top := graph.TopPkgs(); len(top) != 1 || top[0].PkgPath != "golang.org/entry/x" {
+		t.Errorf("got top-level packages %v; want golang.org/entry/x", top)
+	}
+	for _, path := range paths {
+		if p := graph.GetPackage(path); len(p.Syntax) == 0 {
+			t.Errorf("%s not loaded from source", path)
+		}
+	}
+	for _, path := range []string{"golang.org/emod/e", "strings"} {
+		if p := graph.GetPackage(path); len(p.Syntax) > 0 {
+			t.Errorf("%s loaded from source; want export data", path)
+		}
+	}
+
+	h := test.NewMockHandler()
+	if err := Source(ctx, h, &govulncheck.Config{ScanLevel: govulncheck.ScanLevelSymbol}, c, graph); err != nil {
+		t.Fatal(err)
+	}
+	var called bool
+	for _, f := range h.FindingMessages {
+		if f.Trace[0].Function == "Vuln1" && len(f.Trace) > 1 {
+			called = true
+		}
+	}
+	if !called {
+		t.Errorf("got findings %+v; want the call of avuln.VulnData.Vuln1", h.FindingMessages)
+	}
+}
@@ -7,12 +7,23 @@ package vulncheck
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
 	"golang.org/x/tools/go/packages"
 )
 
 // FetchVulnerabilities fetches vulnerabilities that affect the supplied modules.
+//
+// Only the entries of the modules are downloaded: the client reads the
+// index of the modules in the database, and then the entries it lists
+// for them, so that scans of builds with few modules download little.
+// The entries are those of every version of the modules, as reported
+// in govulncheck.Message.OSV.
 func FetchVulnerabilities(ctx context.Context, c *client.Client, modules []*packages.Module) ([]*ModVulns, error) {
 	mreqs := make([]*client.ModuleRequest, len(modules))
 	for i, mod := range modules {
@@ -24,9 +35,10 @@ func FetchVulnerabilities(ctx context.Context, c *client.Client, modules []*pack
 			Path: modPath,
 		}
 	}
+	start := time.Now()
 	resps, err := c.ByModules(ctx, mreqs)
 	if err != nil {
-		return nil, fmt.Errorf("fetching vulnerabilities: %v", err)
+		return nil, govulncheck.NewError(govulncheck.ErrorDBUnreachable, fmt.Errorf("fetching vulnerabilities: %w", err))
 	}
 	var mv []*ModVulns
 	for i, resp := range resps {
@@ -38,5 +50,85 @@ func FetchVulnerabilities(ctx context.Context, c *client.Client, modules []*pack
 			Vulns:  resp.Entries,
 		})
 	}
+	govulncheck.Logger(ctx).Debug("fetched vulnerabilities", "modules", len(modules), "affected_modules", len(mv), "duration", time.Since(start))
 	return mv, nil
 }
+
+// fetchVulnerabilities is FetchVulnerabilities, reporting its progress
+// and how many entries it fetched to handler, which is then passed the
+// entries.
+func fetchVulnerabilities(ctx context.Context, handler govulncheck.Handler, c *client.Client, modules []*packages.Module) ([]*ModVulns, error) {
+	if err := handler.Progress(&govulncheck.Progress{Message: fetchingVulnsMessage}); err != nil {
+		return nil, err
+	}
+	progress := govulncheck.StartPhase(ctx, govulncheck.PhaseFetch, len(modules))
+	mv, err := FetchVulnerabilities(ctx, c, modules)
+	if err != nil {
+		return nil, err
+	}
+	progress.Complete()
+	if err := handler.Progress(&govulncheck.Progress{Message: fetchedMessage(len(modules), mv)}); err != nil {
+		return nil, err
+	}
+	for _, msg := range malformedWarnings(mv) {
+		if err := handler.Progress(&govulncheck.Progress{Message: msg}); err != nil {
+			return nil, err
+		}
+	}
+	// Emit OSV entries immediately in their raw unfiltered form.
+	if err := emitOSVs(handler, mv); err != nil {
+		return nil, err
+	}
+	return mv, nil
+}
+
+// fetchedMessage returns the progress message reporting
+// the entries of mv fetched for n modules.
+func fetchedMessage(n int, mv []*ModVulns) string {
+	ids := make(map[string]bool)
+	for _, m := range mv {
+		for _, v := range m.Vulns {
+			ids[v.ID] = true
+		}
+	}
+	advisories := "advisories"
+	if len(ids) == 1 {
+		advisories = "advisory"
+	}
+	modules := "modules"
+	if n == 1 {
+		modules = "module"
+	}
+	return fmt.Sprintf("Fetched %d %s for %d of %d %s.", len(ids), advisories, len(mv), n, modules)
+}
+
+// malformedWarnings returns the warnings for the entries of mv
+// that do not conform to the Go OSV format, in the order of their IDs.
+func malformedWarnings(mv []*ModVulns) []string {
+	problems := make(map[string][]osv.Problem)
+	for _, m := range mv {
+		for _, v := range m.Vulns {
+			if _, ok := problems[v.ID]; ok {
+				continue
+			}
+			problems[v.ID] = osv.Validate(v)
+		}
+	}
+	var warnings []string
+	for _, id := range slices.Sorted(maps.Keys(problems)) {
+		ps := problems[id]
+		if len(ps) == 0 {
+			continue
+		}
+		more := ""
+		switch len(ps) {
+		case 1:
+		case 2:
+			more = " (and 1 more problem)"
+		default:
+			more = fmt.Sprintf(" (and %d more problems)", len(ps)-1)
+		}
+		warnings = append(warnings, fmt.Sprintf("warning: %s is malformed, so it may be matched against the wrong versions or code: %s%s", id, ps[0], more))
+	}
+	return warnings
+}
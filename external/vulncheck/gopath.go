@@ -0,0 +1,145 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/gopath"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// LoadGOPATHPackages is like LoadPackagesAndMods for code built in
+// GOPATH mode, which has no modules. Packages are instead assigned to
+// the projects providing them, with versions derived from vendoring
+// manifests and version control tags where possible; see package
+// gopath. Vendored packages are known by their unvendored paths.
+func (g *PackageGraph) LoadGOPATHPackages(cfg *packages.Config, tags []string, patterns []string, wantSymbols bool) error {
+	if cfg.Env == nil {
+		cfg.Env = os.Environ()
+	}
+	cfg.Env = append(cfg.Env, "GO111MODULE=off")
+	if len(tags) > 0 {
+		cfg.BuildFlags = append(cfg.BuildFlags, fmt.Sprintf("-tags=%s", strings.Join(tags, ",")))
+	}
+	addLoadMode(cfg, wantSymbols)
+
+	ctx := cfg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := exec.CommandContext(ctx, "go", "env", "GOPATH")
+	cmd.Dir = cfg.Dir
+	cmd.Env = cfg.Env
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("determining GOPATH: %w", err)
+	}
+	resolver := gopath.NewResolver(filepath.SplitList(strings.TrimSpace(string(out))))
+
+	progress := reportLoad(cfg)
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return err
+	}
+	progress.Complete()
+	top := make(map[*packages.Package]bool)
+	for _, p := range pkgs {
+		top[p] = true
+	}
+	topProjects := make(map[string]bool)
+	projects := make(map[*packages.Package]gopath.Project)
+	var perrs []packages.Error
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		perrs = append(perrs, p.Errors...)
+		if IsStdPackage(p.PkgPath) || len(p.GoFiles) == 0 {
+			return
+		}
+		proj := resolver.Resolve(p.PkgPath, filepath.Dir(p.GoFiles[0]))
+		projects[p] = proj
+		if top[p] {
+			topProjects[proj.Path] = true
+		}
+	})
+	if len(perrs) > 0 {
+		err = &packageError{perrs}
+	}
+
+	g.gopath = true
+	g.unknownVersions = make(map[string]bool)
+	for p, proj := range projects {
+		mod := g.GetModule(proj.Path)
+		if mod.Version == "" {
+			mod.Version = proj.Version
+		}
+		p.Module = mod
+		p.PkgPath = unvendor(p.PkgPath)
+	}
+	for _, mod := range g.modules {
+		if projectVersionUnknown(mod, topProjects) {
+			g.unknownVersions[mod.Path] = true
+		}
+	}
+
+	g.AddPackages(pkgs...)
+	for _, p := range pkgs {
+		g.topPkgs = append(g.topPkgs, g.GetPackage(p.PkgPath))
+	}
+	return err
+}
+
+// projectVersionUnknown reports whether mod is a dependency
+// whose version could not be determined.
+func projectVersionUnknown(mod *packages.Module, topProjects map[string]bool) bool {
+	return mod.Version == "" && !topProjects[mod.Path] &&
+		mod.Path != external.GoStdModulePath && mod.Path != external.UnknownModulePath
+}
+
+// unvendor returns the import path of the package with path pkgPath
+// before it was vendored. In GOPATH mode, vendored packages have paths
+// of the form p/vendor/q.
+func unvendor(pkgPath string) string {
+	if i := strings.LastIndex(pkgPath, "/vendor/"); i >= 0 {
+		return pkgPath[i+len("/vendor/"):]
+	}
+	return pkgPath
+}
+
+// funcPkgPath returns the path of the package of f in g.
+func (g *PackageGraph) funcPkgPath(f *ssa.Function) string {
+	p := pkgPath(f)
+	if g.gopath {
+		p = unvendor(p)
+	}
+	return p
+}
+
+// markUnknownVersions marks the modules in mv whose versions could not
+// be determined, all vulnerabilities of which are reported, and warns
+// about them.
+func (g *PackageGraph) markUnknownVersions(handler govulncheck.Handler, mv []*ModVulns) error {
+	var unknown []string
+	for _, m := range mv {
+		if g.unknownVersions[m.Module.Path] {
+			m.UnknownVersion = true
+			unknown = append(unknown, m.Module.Path)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	msg := fmt.Sprintf("warning: the versions of %s could not be determined, so all of their known vulnerabilities are reported", strings.Join(unknown, ", "))
+	return handler.Progress(&govulncheck.Progress{Message: msg})
+}
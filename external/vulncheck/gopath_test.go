@@ -0,0 +1,128 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestLoadGOPATHPackages(t *testing.T) {
+	e := packagestest.Export(t, packagestest.GOPATH, []packagestest.Module{
+		{
+			Name: "example.com/app",
+			Files: map[string]interface{}{
+				"main.go": `
+			package main
+
+			import (
+				"golang.org/amod/avuln"
+				"golang.org/bmod/bvuln"
+			)
+
+			func main() {
+				avuln.VulnData{}.Vuln1()
+				bvuln.NoVuln()
+			}
+			`,
+				"Gopkg.lock": `
+[[projects]]
+  name = "golang.org/amod"
+  packages = ["avuln"]
+  revision = "0123456789abcdef"
+  version = "v1.1.3"
+
+[[projects]]
+  branch = "master"
+  name = "golang.org/bmod"
+  packages = ["bvuln"]
+  revision = "fedcba9876543210"
+`,
+				"vendor/golang.org/amod/avuln/avuln.go": `
+			package avuln
+
+			type VulnData struct{}
+
+			func (v VulnData) Vuln1() {}
+			`,
+				"vendor/golang.org/bmod/bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+
+			func NoVuln() {}
+			`,
+			},
+		},
+	})
+	defer e.Cleanup()
+
+	cfg := e.Config
+	cfg.Dir = filepath.Dir(e.File("example.com/app", "main.go"))
+	cfg.Env = append(cfg.Env, "GOFLAGS=")
+	graph := NewPackageGraph("go1.20")
+	if err := graph.LoadGOPATHPackages(cfg, nil, []string{"."}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	versions := make(map[string]string)
+	for _, m := range graph.Modules() {
+		versions[m.Path] = m.Version
+	}
+	if got := versions["golang.org/amod"]; got != "v1.1.3" {
+		t.Errorf("got golang.org/amod version %q; want v1.1.3", got)
+	}
+	if _, ok := versions["golang.org/bmod"]; !ok {
+		t.Errorf("golang.org/bmod not found in %v", versions)
+	}
+	if p := graph.GetPackage("golang.org/amod/avuln"); p.Module == nil || p.Module.Path != "golang.org/amod" {
+		t.Errorf("vendored package golang.org/amod/avuln not assigned to golang.org/amod: %v", p.Module)
+	}
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := test.NewMockHandler()
+	if err := Source(context.Background(), h, &govulncheck.Config{ScanLevel: "symbol"}, c, graph); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, f := range h.FindingMessages {
+		fr := f.Trace[0]
+		got = append(got, strings.Join([]string{f.OSV, fr.Module, fr.Version, fr.Package, fr.Function}, " "))
+	}
+	sort.Strings(got)
+	want := []string{
+		"STD stdlib v1.20.0  ",
+		"VA golang.org/amod v1.1.3  ",
+		"VA golang.org/amod v1.1.3 golang.org/amod/avuln ",
+		"VA golang.org/amod v1.1.3 golang.org/amod/avuln Vuln1",
+		// The version of golang.org/bmod is unknown,
+		// so its vulnerabilities are all reported.
+		"VB golang.org/bmod   ",
+		"VB golang.org/bmod  golang.org/bmod/bvuln ",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("findings mismatch (-want, +got):\n%s", diff)
+	}
+
+	var warned bool
+	for _, p := range h.ProgressMessages {
+		warned = warned || strings.Contains(p.Message, "the versions of golang.org/bmod could not be determined")
+	}
+	if !warned {
+		t.Error("no warning about the unknown version of golang.org/bmod")
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// GraphFormat is a file format for graphs.
+type GraphFormat string
+
+const (
+	// GraphDOT is the format of Graphviz.
+	GraphDOT GraphFormat = "dot"
+
+	// GraphML is the XML format used by Gephi, among others.
+	GraphML GraphFormat = "graphml"
+)
+
+// WriteGraph writes to w, in format, the slice of the call graph
+// computed for res and the corresponding slice of the import graph,
+// that is the functions and packages that are reachable from the entry
+// points and that reach vulnerable symbols and packages. This helps
+// find out why the analysis considers a vulnerable symbol reachable.
+//
+// In the call graph, entry points and vulnerable functions have the
+// kinds "entry" and "vulnerable", and the calls that could not be
+// resolved statically have the kind "dynamic". In the import graph,
+// the top-level packages and the vulnerable packages have the kinds
+// "entry" and "vulnerable".
+func WriteGraph(res *Result, format GraphFormat, w io.Writer) error {
+	g := newResultGraph(res)
+	bw := bufio.NewWriter(w)
+	switch format {
+	case GraphDOT:
+		g.writeDOT(bw)
+	case GraphML:
+		g.writeGraphML(bw)
+	default:
+		return fmt.Errorf("unsupported graph format %q", format)
+	}
+	return bw.Flush()
+}
+
+// resultGraph is the call graph and import graph of a Result.
+type resultGraph struct {
+	funcs   []*graphNode
+	calls   []*graphEdge
+	pkgs    []*graphNode
+	imports []*graphEdge
+}
+
+type graphNode struct {
+	id, label, kind string
+}
+
+type graphEdge struct {
+	from, to    *graphNode
+	label, kind string
+}
+
+func newResultGraph(res *Result) *resultGraph {
+	g := &resultGraph{}
+	g.addCalls(res)
+	g.addImports(res)
+	return g
+}
+
+// addCalls adds the functions and calls
+// backward reachable from the sinks of res.
+func (g *resultGraph) addCalls(res *Result) {
+	entries := make(map[*FuncNode]bool)
+	for _, e := range res.EntryFunctions {
+		entries[e] = true
+	}
+	sinks := make(map[*FuncNode]bool)
+	var funcs []*FuncNode
+	seen := make(map[*FuncNode]bool)
+	var visit func(*FuncNode)
+	visit = func(f *FuncNode) {
+		if seen[f] {
+			return
+		}
+		seen[f] = true
+		funcs = append(funcs, f)
+		for _, cs := range f.CallSites {
+			visit(cs.Parent)
+		}
+	}
+	for _, v := range res.Vulns {
+		if v.CallSink != nil {
+			sinks[v.CallSink] = true
+			visit(v.CallSink)
+		}
+	}
+	sort.SliceStable(funcs, func(i, j int) bool { return funcLess(funcs[i], funcs[j]) })
+
+	nodes := make(map[*FuncNode]*graphNode)
+	for i, f := range funcs {
+		n := &graphNode{id: fmt.Sprintf("f%d", i), label: f.String()}
+		if len(f.TypeArgs) > 0 {
+			n.label += "[" + strings.Join(f.TypeArgs, ", ") + "]"
+		}
+		switch {
+		case sinks[f]:
+			n.kind = "vulnerable"
+		case entries[f]:
+			n.kind = "entry"
+		}
+		nodes[f] = n
+		g.funcs = append(g.funcs, n)
+	}
+	for _, f := range funcs {
+		for _, cs := range sortedCallsites(f.CallSites) {
+			e := &graphEdge{from: nodes[cs.Parent], to: nodes[f]}
+			if cs.Pos != nil && cs.Pos.IsValid() {
+				e.label = fmt.Sprintf("%s:%d:%d", filepath.Base(cs.Pos.Filename), cs.Pos.Line, cs.Pos.Column)
+			}
+			if !cs.Resolved {
+				e.kind = "dynamic"
+			}
+			g.calls = append(g.calls, e)
+		}
+	}
+}
+
+// addImports adds the packages and imports forward reachable from
+// the packages of the entry functions of res, which reach the packages
+// of its vulnerabilities.
+func (g *resultGraph) addImports(res *Result) {
+	vulnerable := make(map[*packages.Package]bool)
+	for _, v := range res.Vulns {
+		if v.Package != nil {
+			vulnerable[v.Package] = true
+		}
+	}
+	top := make(map[*packages.Package]bool)
+	for _, e := range res.EntryFunctions {
+		if e.Package != nil {
+			top[e.Package] = true
+		}
+	}
+
+	// reaches memoizes whether a package reaches a vulnerable one.
+	reaches := make(map[*packages.Package]bool)
+	var reach func(*packages.Package) bool
+	reach = func(p *packages.Package) bool {
+		if r, ok := reaches[p]; ok {
+			return r
+		}
+		reaches[p] = false // cut import cycles
+		r := vulnerable[p]
+		for _, imp := range p.Imports {
+			if reach(imp) {
+				r = true
+			}
+		}
+		reaches[p] = r
+		return r
+	}
+	var pkgs []*packages.Package
+	for p := range top {
+		if reach(p) {
+			pkgs = append(pkgs, p)
+		}
+	}
+	seen := make(map[*packages.Package]bool)
+	for i := 0; i < len(pkgs); i++ {
+		seen[pkgs[i]] = true
+		for _, imp := range pkgs[i].Imports {
+			if reaches[imp] && !seen[imp] {
+				seen[imp] = true
+				pkgs = append(pkgs, imp)
+			}
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].PkgPath < pkgs[j].PkgPath })
+
+	nodes := make(map[*packages.Package]*graphNode)
+	for i, p := range pkgs {
+		n := &graphNode{id: fmt.Sprintf("p%d", i), label: p.PkgPath}
+		switch {
+		case vulnerable[p]:
+			n.kind = "vulnerable"
+		case top[p]:
+			n.kind = "entry"
+		}
+		nodes[p] = n
+		g.pkgs = append(g.pkgs, n)
+	}
+	for _, p := range pkgs {
+		var imps []*packages.Package
+		for _, imp := range p.Imports {
+			if nodes[imp] != nil {
+				imps = append(imps, imp)
+			}
+		}
+		sort.Slice(imps, func(i, j int) bool { return imps[i].PkgPath < imps[j].PkgPath })
+		for _, imp := range imps {
+			g.imports = append(g.imports, &graphEdge{from: nodes[p], to: nodes[imp]})
+		}
+	}
+}
+
+// dotStyles are the DOT attributes of node and edge kinds.
+var dotStyles = map[string]string{
+	"entry":      ` shape=box`,
+	"vulnerable": ` color=red`,
+	"dynamic":    ` style=dashed`,
+}
+
+func (g *resultGraph) writeDOT(w io.Writer) {
+	fmt.Fprintln(w, "digraph vulncheck {")
+	writeDOTCluster(w, "calls", "call graph", g.funcs, g.calls)
+	writeDOTCluster(w, "imports", "import graph", g.pkgs, g.imports)
+	fmt.Fprintln(w, "}")
+}
+
+func writeDOTCluster(w io.Writer, name, label string, nodes []*graphNode, edges []*graphEdge) {
+	fmt.Fprintf(w, "\tsubgraph cluster_%s {\n", name)
+	fmt.Fprintf(w, "\t\tlabel=%q;\n", label)
+	for _, n := range nodes {
+		fmt.Fprintf(w, "\t\t%s [label=%q%s];\n", n.id, n.label, dotStyles[n.kind])
+	}
+	for _, e := range edges {
+		fmt.Fprintf(w, "\t\t%s -> %s", e.from.id, e.to.id)
+		if e.label != "" || e.kind != "" {
+			fmt.Fprintf(w, " [label=%q%s]", e.label, dotStyles[e.kind])
+		}
+		fmt.Fprintln(w, ";")
+	}
+	fmt.Fprintln(w, "\t}")
+}
+
+func (g *resultGraph) writeGraphML(w io.Writer) {
+	fmt.Fprintln(w, xml.Header+`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(w, `  <key id="label" for="all" attr.name="label" attr.type="string"/>`)
+	fmt.Fprintln(w, `  <key id="kind" for="all" attr.name="kind" attr.type="string"/>`)
+	writeGraphMLGraph(w, "calls", g.funcs, g.calls)
+	writeGraphMLGraph(w, "imports", g.pkgs, g.imports)
+	fmt.Fprintln(w, "</graphml>")
+}
+
+func writeGraphMLGraph(w io.Writer, id string, nodes []*graphNode, edges []*graphEdge) {
+	fmt.Fprintf(w, "  <graph id=%q edgedefault=\"directed\">\n", id)
+	for _, n := range nodes {
+		fmt.Fprintf(w, "    <node id=%q>\n", n.id)
+		writeGraphMLData(w, n.label, n.kind)
+		fmt.Fprintln(w, "    </node>")
+	}
+	for _, e := range edges {
+		if e.label == "" && e.kind == "" {
+			fmt.Fprintf(w, "    <edge source=%q target=%q/>\n", e.from.id, e.to.id)
+			continue
+		}
+		fmt.Fprintf(w, "    <edge source=%q target=%q>\n", e.from.id, e.to.id)
+		writeGraphMLData(w, e.label, e.kind)
+		fmt.Fprintln(w, "    </edge>")
+	}
+	fmt.Fprintln(w, "  </graph>")
+}
+
+func writeGraphMLData(w io.Writer, label, kind string) {
+	for _, d := range []struct{ key, value string }{{"label", label}, {"kind", kind}} {
+		if d.value == "" {
+			continue
+		}
+		fmt.Fprintf(w, "      <data key=%q>", d.key)
+		xml.EscapeText(w, []byte(d.value))
+		fmt.Fprintln(w, "</data>")
+	}
+}
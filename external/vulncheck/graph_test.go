@@ -0,0 +1,99 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestWriteGraph(t *testing.T) {
+	vpkg := &packages.Package{PkgPath: "golang.org/v"}
+	mpkg := &packages.Package{PkgPath: "golang.org/m", Imports: map[string]*packages.Package{"golang.org/v": vpkg}}
+
+	main := &FuncNode{Name: "main", Package: mpkg, Pos: &token.Position{Filename: "/m/main.go", Line: 3}}
+	run := &FuncNode{Name: "run", Package: mpkg, Pos: &token.Position{Filename: "/m/main.go", Line: 7}}
+	vuln := &FuncNode{Name: "Vuln", Package: vpkg}
+	run.CallSites = []*CallSite{{Parent: main, Pos: &token.Position{Filename: "/m/main.go", Line: 4, Column: 2}, Resolved: true}}
+	vuln.CallSites = []*CallSite{{Parent: run, Pos: &token.Position{Filename: "/m/main.go", Line: 8, Column: 5}}}
+	res := &Result{
+		EntryFunctions: []*FuncNode{main},
+		Vulns:          []*Vuln{{Symbol: "Vuln", CallSink: vuln, Package: vpkg}},
+	}
+
+	for _, tc := range []struct {
+		format GraphFormat
+		want   string
+	}{
+		{GraphDOT, `
+digraph vulncheck {
+	subgraph cluster_calls {
+		label="call graph";
+		f0 [label="golang.org/m.main" shape=box];
+		f1 [label="golang.org/m.run"];
+		f2 [label="golang.org/v.Vuln" color=red];
+		f0 -> f1 [label="main.go:4:2"];
+		f1 -> f2 [label="main.go:8:5" style=dashed];
+	}
+	subgraph cluster_imports {
+		label="import graph";
+		p0 [label="golang.org/m" shape=box];
+		p1 [label="golang.org/v" color=red];
+		p0 -> p1;
+	}
+}
+`},
+		{GraphML, `
+<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="label" for="all" attr.name="label" attr.type="string"/>
+  <key id="kind" for="all" attr.name="kind" attr.type="string"/>
+  <graph id="calls" edgedefault="directed">
+    <node id="f0">
+      <data key="label">golang.org/m.main</data>
+      <data key="kind">entry</data>
+    </node>
+    <node id="f1">
+      <data key="label">golang.org/m.run</data>
+    </node>
+    <node id="f2">
+      <data key="label">golang.org/v.Vuln</data>
+      <data key="kind">vulnerable</data>
+    </node>
+    <edge source="f0" target="f1">
+      <data key="label">main.go:4:2</data>
+    </edge>
+    <edge source="f1" target="f2">
+      <data key="label">main.go:8:5</data>
+      <data key="kind">dynamic</data>
+    </edge>
+  </graph>
+  <graph id="imports" edgedefault="directed">
+    <node id="p0">
+      <data key="label">golang.org/m</data>
+      <data key="kind">entry</data>
+    </node>
+    <node id="p1">
+      <data key="label">golang.org/v</data>
+      <data key="kind">vulnerable</data>
+    </node>
+    <edge source="p0" target="p1"/>
+  </graph>
+</graphml>
+`},
+	} {
+		var got strings.Builder
+		if err := WriteGraph(res, tc.format, &got); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(strings.TrimPrefix(tc.want, "\n"), got.String()); diff != "" {
+			t.Errorf("%s mismatch (-want, +got):\n%s", tc.format, diff)
+		}
+	}
+}
@@ -74,7 +74,9 @@ func callGraphToStrMap(r *Result) map[string][]string {
 	seen := make(map[edge]bool)
 	m := make(map[string][]string)
 	for _, v := range r.Vulns {
-		updateCallGraph(m, v.CallSink, seen)
+		if v.CallSink != nil {
+			updateCallGraph(m, v.CallSink, seen)
+		}
 	}
 	sortStrMap(m)
 	return m
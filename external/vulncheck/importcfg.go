@@ -0,0 +1,328 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"golang.org/x/tools/go/packages"
+)
+
+// An Importcfg lists the packages of a build that the go command cannot
+// describe, such as one driven by Bazel or please, so that they can be
+// loaded without go list.
+//
+// Its text form extends that of the importcfg files of the compiler:
+//
+//	# comment
+//	packagedir example.com/lib=third_party/lib
+//	importmap example.com/old=example.com/lib
+//	module example.com=v1.2.3
+//	module example.com/app
+//
+// A packagedir line gives the source directory of a package, an importmap
+// line rewrites an import path as for the compiler, and a module line gives
+// the version of the module providing the packages below its path, if any.
+// Other directives, such as packagefile, are ignored. Standard library
+// packages are found in GOROOT and need not be listed.
+type Importcfg struct {
+	Dirs      map[string]string // by import path
+	ImportMap map[string]string // by import path as written
+	Modules   map[string]string // versions by module path
+}
+
+// ParseImportcfg parses the text form of an Importcfg.
+func ParseImportcfg(data []byte) (*Importcfg, error) {
+	cfg := &Importcfg{
+		Dirs:      make(map[string]string),
+		ImportMap: make(map[string]string),
+		Modules:   make(map[string]string),
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		verb, args, _ := strings.Cut(line, " ")
+		args = strings.TrimSpace(args)
+		key, value, hasValue := strings.Cut(args, "=")
+		switch verb {
+		case "packagedir", "importmap":
+			if !hasValue || key == "" || value == "" {
+				return nil, fmt.Errorf("importcfg:%d: invalid %s: syntax is \"%s path=value\"", n, verb, verb)
+			}
+			if verb == "packagedir" {
+				cfg.Dirs[key] = value
+			} else {
+				cfg.ImportMap[key] = value
+			}
+		case "module":
+			if key == "" {
+				return nil, fmt.Errorf("importcfg:%d: invalid module: syntax is \"module path[=version]\"", n)
+			}
+			cfg.Modules[key] = value
+		}
+	}
+	return cfg, s.Err()
+}
+
+// LoadImportcfgPackages is like LoadPackagesAndMods, but it loads the
+// packages in the directories dirs, and their imports, as described by
+// icfg instead of asking the go command. Relative directories are
+// interpreted relative to cfg.Dir.
+//
+// Files are selected for the GOOS and GOARCH in cfg.Env, if any, and
+// the given build tags. Cgo is disabled, as running it requires the
+// build system.
+func (g *PackageGraph) LoadImportcfgPackages(cfg *packages.Config, icfg *Importcfg, tags []string, dirs []string, wantSymbols bool) error {
+	goroot := g.GetModule(external.GoStdModulePath).Dir
+	if goroot == "" {
+		return errors.New("cannot determine GOROOT")
+	}
+	ctxt := build.Default
+	ctxt.GOROOT = goroot
+	ctxt.GOPATH = ""
+	ctxt.CgoEnabled = false
+	ctxt.BuildTags = tags
+	for _, kv := range cfg.Env {
+		if v, ok := strings.CutPrefix(kv, "GOOS="); ok {
+			ctxt.GOOS = v
+		} else if v, ok := strings.CutPrefix(kv, "GOARCH="); ok {
+			ctxt.GOARCH = v
+		}
+	}
+	ctx := cfg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	l := &importcfgLoader{
+		progress:    govulncheck.StartPhase(ctx, govulncheck.PhaseLoad, 0),
+		icfg:        icfg,
+		ctxt:        &ctxt,
+		dir:         cfg.Dir,
+		fset:        token.NewFileSet(),
+		wantSymbols: wantSymbols,
+		pkgs:        make(map[string]*packages.Package),
+		loading:     make(map[string]bool),
+		mods:        make(map[string]*packages.Module),
+	}
+
+	byDir := make(map[string]string)
+	for path, dir := range icfg.Dirs {
+		byDir[l.abs(dir)] = path
+	}
+	var pkgs []*packages.Package
+	for _, dir := range dirs {
+		if cfg.Context != nil && cfg.Context.Err() != nil {
+			return cfg.Context.Err()
+		}
+		path, ok := byDir[l.abs(dir)]
+		if !ok {
+			return fmt.Errorf("%s is not the packagedir of any package in the importcfg", dir)
+		}
+		pkgs = append(pkgs, l.load(path, l.abs(dir)))
+	}
+
+	l.progress.Complete()
+
+	var err error
+	if len(l.errs) > 0 {
+		err = &packageError{l.errs}
+	}
+	g.AddPackages(pkgs...)
+	for _, p := range pkgs {
+		g.topPkgs = append(g.topPkgs, g.GetPackage(p.PkgPath))
+	}
+	return err
+}
+
+// importcfgLoader loads packages as described by an Importcfg.
+type importcfgLoader struct {
+	progress    *govulncheck.PhaseProgress
+	icfg        *Importcfg
+	ctxt        *build.Context
+	dir         string // directory relative paths are relative to
+	fset        *token.FileSet
+	wantSymbols bool
+
+	pkgs    map[string]*packages.Package // by import path
+	loading map[string]bool              // import paths of packages being loaded
+	mods    map[string]*packages.Module  // by path
+	errs    []packages.Error
+}
+
+func (l *importcfgLoader) abs(dir string) string {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(l.dir, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+func (l *importcfgLoader) errorf(p *packages.Package, format string, args ...any) {
+	l.errs = append(l.errs, packages.Error{
+		Pos:  p.PkgPath,
+		Msg:  fmt.Sprintf(format, args...),
+		Kind: packages.ListError,
+	})
+}
+
+// load returns the package with import path path in directory dir,
+// loading it and its imports if needed.
+func (l *importcfgLoader) load(path, dir string) *packages.Package {
+	if p, ok := l.pkgs[path]; ok {
+		return p
+	}
+	p := &packages.Package{
+		ID:      path,
+		PkgPath: path,
+		Fset:    l.fset,
+		Imports: make(map[string]*packages.Package),
+		Module:  l.module(path),
+	}
+	l.pkgs[path] = p
+	l.loading[path] = true
+	defer delete(l.loading, path)
+	if path == "unsafe" {
+		p.Name = "unsafe"
+		p.Types = types.Unsafe
+		return p
+	}
+
+	bp, err := l.ctxt.ImportDir(dir, 0)
+	if err != nil {
+		l.errorf(p, "%v", err)
+		return p
+	}
+	p.Name = bp.Name
+	for _, f := range bp.GoFiles {
+		p.GoFiles = append(p.GoFiles, filepath.Join(dir, f))
+	}
+	p.CompiledGoFiles = p.GoFiles
+	for _, imp := range bp.Imports {
+		ipath, idir, ok := l.resolve(p, imp)
+		if !ok {
+			l.errorf(p, "cannot find package %q imported by %s: it is not in the importcfg", imp, path)
+			continue
+		}
+		if l.loading[ipath] {
+			l.errorf(p, "import cycle not allowed: %s imports %s", path, ipath)
+			continue
+		}
+		p.Imports[imp] = l.load(ipath, idir)
+	}
+	if l.wantSymbols {
+		l.typeCheck(p)
+	}
+	return p
+}
+
+// resolve returns the import path and directory of the package
+// imported as imp by p.
+func (l *importcfgLoader) resolve(p *packages.Package, imp string) (path, dir string, ok bool) {
+	if m, ok := l.icfg.ImportMap[imp]; ok {
+		imp = m
+	}
+	if dir, ok := l.icfg.Dirs[imp]; ok {
+		return imp, l.abs(dir), true
+	}
+	if imp == "unsafe" {
+		return imp, "", true
+	}
+	src := filepath.Join(l.ctxt.GOROOT, "src")
+	if IsStdPackage(imp) {
+		return imp, filepath.Join(src, filepath.FromSlash(imp)), true
+	}
+	if IsStdPackage(p.PkgPath) {
+		// The standard library vendors its dependencies.
+		vpath := "vendor/" + imp
+		return vpath, filepath.Join(src, filepath.FromSlash(vpath)), true
+	}
+	return "", "", false
+}
+
+// module returns the module of the package with import path path, or
+// nil for the standard library and packages of no module in the
+// importcfg, which AddPackages assigns to the right pseudo-module.
+func (l *importcfgLoader) module(path string) *packages.Module {
+	if IsStdPackage(path) {
+		return nil
+	}
+	var best string
+	for m := range l.icfg.Modules {
+		if (path == m || strings.HasPrefix(path, m+"/")) && len(m) > len(best) {
+			best = m
+		}
+	}
+	if best == "" {
+		return nil
+	}
+	m, ok := l.mods[best]
+	if !ok {
+		m = &packages.Module{Path: best, Version: l.icfg.Modules[best]}
+		l.mods[best] = m
+	}
+	return m
+}
+
+// typeCheck parses and type checks p, whose imports have been loaded.
+func (l *importcfgLoader) typeCheck(p *packages.Package) {
+	for _, f := range p.GoFiles {
+		file, err := parser.ParseFile(l.fset, f, nil, parser.AllErrors|parser.ParseComments)
+		l.progress.Advance()
+		if file != nil {
+			p.Syntax = append(p.Syntax, file)
+		}
+		if err != nil {
+			l.errorf(p, "%v", err)
+		}
+	}
+	p.TypesInfo = &types.Info{
+		Types:        make(map[ast.Expr]types.TypeAndValue),
+		Defs:         make(map[*ast.Ident]types.Object),
+		Uses:         make(map[*ast.Ident]types.Object),
+		Implicits:    make(map[ast.Node]types.Object),
+		Instances:    make(map[*ast.Ident]types.Instance),
+		Scopes:       make(map[ast.Node]*types.Scope),
+		Selections:   make(map[*ast.SelectorExpr]*types.Selection),
+		FileVersions: make(map[*ast.File]string),
+	}
+	p.TypesSizes = types.SizesFor("gc", l.ctxt.GOARCH)
+	var errs []string
+	tc := &types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			if ip := p.Imports[path]; ip != nil && ip.Types != nil {
+				return ip.Types, nil
+			}
+			return nil, fmt.Errorf("package %q was not loaded", path)
+		}),
+		Sizes: p.TypesSizes,
+		Error: func(err error) { errs = append(errs, err.Error()) },
+	}
+	p.Types, _ = tc.Check(p.PkgPath, l.fset, p.Syntax, p.TypesInfo)
+	sort.Strings(errs)
+	for _, e := range errs {
+		l.errorf(p, "%s", e)
+	}
+	p.IllTyped = len(errs) > 0
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
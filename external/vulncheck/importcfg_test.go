@@ -0,0 +1,126 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestParseImportcfg(t *testing.T) {
+	got, err := ParseImportcfg([]byte(`
+# import config
+packagefile fmt=/tmp/fmt.a
+packagedir example.com/app=app
+packagedir golang.org/amod/avuln=external/amod/avuln
+importmap golang.org/old/avuln=golang.org/amod/avuln
+module golang.org/amod=v1.1.3
+module example.com/app
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Importcfg{
+		Dirs: map[string]string{
+			"example.com/app":       "app",
+			"golang.org/amod/avuln": "external/amod/avuln",
+		},
+		ImportMap: map[string]string{"golang.org/old/avuln": "golang.org/amod/avuln"},
+		Modules:   map[string]string{"golang.org/amod": "v1.1.3", "example.com/app": ""},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+
+	if _, err := ParseImportcfg([]byte("packagedir example.com/app\n")); err == nil {
+		t.Error("got no error for packagedir without directory")
+	}
+}
+
+func TestLoadImportcfgPackages(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"app/main.go": `
+package main
+
+import "golang.org/old/avuln"
+
+func main() {
+	avuln.VulnData{}.Vuln1()
+}
+`,
+		"external/amod/avuln/avuln.go": `
+package avuln
+
+type VulnData struct{}
+
+func (v VulnData) Vuln1() {}
+
+func (v VulnData) Vuln2() {}
+`,
+	} {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	icfg := &Importcfg{
+		Dirs: map[string]string{
+			"example.com/app":       "app",
+			"golang.org/amod/avuln": "external/amod/avuln",
+		},
+		ImportMap: map[string]string{"golang.org/old/avuln": "golang.org/amod/avuln"},
+		Modules:   map[string]string{"golang.org/amod": "v1.1.3", "example.com/app": ""},
+	}
+
+	graph := NewPackageGraph("go1.20")
+	if err := graph.LoadImportcfgPackages(&packages.Config{Dir: dir}, icfg, nil, []string{"app"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if p := graph.GetPackage("golang.org/amod/avuln"); p.Module == nil || p.Module.Version != "v1.1.3" {
+		t.Errorf("golang.org/amod/avuln not assigned to golang.org/amod@v1.1.3: %v", p.Module)
+	}
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := test.NewMockHandler()
+	if err := Source(context.Background(), h, &govulncheck.Config{ScanLevel: "symbol"}, c, graph); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range h.FindingMessages {
+		fr := f.Trace[0]
+		got = append(got, strings.Join([]string{f.OSV, fr.Module, fr.Version, fr.Package, fr.Function}, " "))
+	}
+	sort.Strings(got)
+	want := []string{
+		"STD stdlib v1.20.0  ",
+		"VA golang.org/amod v1.1.3  ",
+		"VA golang.org/amod v1.1.3 golang.org/amod/avuln ",
+		"VA golang.org/amod v1.1.3 golang.org/amod/avuln Vuln1",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("findings mismatch (-want, +got):\n%s", diff)
+	}
+
+	graph = NewPackageGraph("go1.20")
+	if err := graph.LoadImportcfgPackages(&packages.Config{Dir: dir}, icfg, nil, []string{"external"}, false); err == nil {
+		t.Error("got no error for a directory that is not a packagedir")
+	}
+}
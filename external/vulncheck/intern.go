@@ -0,0 +1,87 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import "go/token"
+
+// slabSize is the number of positions, functions, or call sites an
+// interner allocates at once.
+const slabSize = 1024
+
+// An interner shares the strings and positions that the functions and
+// call sites of a vulnerability call graph repeat. A call through an
+// interface, for instance, has a call site for each of its callees,
+// all with the same name, receiver type, and position, and the call
+// graphs of large programs would otherwise hold millions of copies of
+// them. Positions are allocated in slabs, indexed by their token.Pos,
+// which spares the overhead of allocating each of them, and so are the
+// functions and call sites themselves.
+type interner struct {
+	strs  map[string]string
+	pos   map[token.Pos]*token.Position
+	slab  []token.Position
+	funcs []FuncNode
+	sites []CallSite
+}
+
+func newInterner() *interner {
+	return &interner{
+		strs: make(map[string]string),
+		pos:  make(map[token.Pos]*token.Position),
+	}
+}
+
+// string returns the copy of s shared by all callers.
+func (in *interner) string(s string) string {
+	if s == "" {
+		return s
+	}
+	if is, ok := in.strs[s]; ok {
+		return is
+	}
+	in.strs[s] = s
+	return s
+}
+
+// strings interns the elements of ss in place and returns ss.
+func (in *interner) strings(ss []string) []string {
+	for i, s := range ss {
+		ss[i] = in.string(s)
+	}
+	return ss
+}
+
+// position returns the position of pos in fset shared by all callers.
+// The position returned must not be modified.
+func (in *interner) position(fset *token.FileSet, pos token.Pos) *token.Position {
+	if p, ok := in.pos[pos]; ok {
+		return p
+	}
+	if len(in.slab) == cap(in.slab) {
+		in.slab = make([]token.Position, 0, slabSize)
+	}
+	in.slab = append(in.slab, fset.Position(pos))
+	p := &in.slab[len(in.slab)-1]
+	in.pos[pos] = p
+	return p
+}
+
+// funcNode returns a new function, allocated from a slab.
+func (in *interner) funcNode() *FuncNode {
+	if len(in.funcs) == cap(in.funcs) {
+		in.funcs = make([]FuncNode, 0, slabSize)
+	}
+	in.funcs = append(in.funcs, FuncNode{})
+	return &in.funcs[len(in.funcs)-1]
+}
+
+// callSite returns a new call site, allocated from a slab.
+func (in *interner) callSite() *CallSite {
+	if len(in.sites) == cap(in.sites) {
+		in.sites = make([]CallSite, 0, slabSize)
+	}
+	in.sites = append(in.sites, CallSite{})
+	return &in.sites[len(in.sites)-1]
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"go/token"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestInterner(t *testing.T) {
+	in := newInterner()
+	a := in.string(strings.Repeat("x", 8))
+	b := in.string(strings.Repeat("x", 8))
+	if unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Error("equal strings not shared")
+	}
+
+	// More positions than a slab holds.
+	fset := token.NewFileSet()
+	f := fset.AddFile("f.go", -1, 2*slabSize)
+	f.SetLines([]int{0, slabSize})
+	var ps []*token.Position
+	for i := range slabSize + 2 {
+		ps = append(ps, in.position(fset, f.Pos(i)))
+	}
+	if in.position(fset, f.Pos(1)) != ps[1] {
+		t.Error("positions of the same token.Pos not shared")
+	}
+	for _, i := range []int{1, slabSize + 1} {
+		want := token.Position{Filename: "f.go", Offset: i, Line: 1 + i/slabSize, Column: 1 + i%slabSize}
+		if got := *ps[i]; got != want {
+			t.Errorf("got position %v; want %v", got, want)
+		}
+	}
+}
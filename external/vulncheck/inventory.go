@@ -0,0 +1,93 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"slices"
+
+	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"golang.org/x/tools/go/packages"
+)
+
+// Inventory is an abstraction of the Go modules, and optionally
+// packages, listed in a software bill of materials. Unlike Bin,
+// it carries no symbol information so findings can be reported
+// at most at package level.
+type Inventory struct {
+	Modules []*packages.Module
+	// Packages are import paths of packages known to be used.
+	Packages  []string
+	GoVersion string
+}
+
+// SBOM detects vulnerable modules and packages listed in inv and
+// emits findings to handler.
+func SBOM(ctx context.Context, handler govulncheck.Handler, inv *Inventory, cfg *govulncheck.Config, client *client.Client) error {
+	_, err := inventory(ctx, handler, inv, cfg, client)
+	return err
+}
+
+func inventory(ctx context.Context, handler govulncheck.Handler, inv *Inventory, cfg *govulncheck.Config, client *client.Client) (*Result, error) {
+	graph := NewPackageGraph(inv.GoVersion)
+	mods := slices.Concat(inv.Modules, []*packages.Module{graph.GetModule(external.GoStdModulePath)})
+	graph.AddModules(mods...)
+
+	if err := handler.SBOM(inv.SBOM()); err != nil {
+		return nil, err
+	}
+
+	mv, err := fetchVulnerabilities(ctx, handler, client, mods)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := handler.Progress(&govulncheck.Progress{Message: checkingSBOMVulnsMessage}); err != nil {
+		return nil, err
+	}
+
+	// The target platform is not known, so platform
+	// specific vulnerabilities are conservatively included.
+	affVulns := affectingVulnerabilities(mv, "", "")
+	if err := emitModuleFindings(handler, affVulns); err != nil {
+		return nil, err
+	}
+
+	if !cfg.ScanLevel.WantPackages() || len(affVulns) == 0 {
+		return &Result{}, nil
+	}
+
+	var vulns []*Vuln
+	for _, pkg := range inv.Packages {
+		for _, osv := range affVulns.ForPackage(external.UnknownModulePath, pkg) {
+			vulns = append(vulns, &Vuln{
+				OSV:     osv,
+				Package: graph.GetPackage(pkg),
+			})
+		}
+	}
+	if err := emitPackageFindings(handler, vulns); err != nil {
+		return nil, err
+	}
+	return &Result{Vulns: vulns}, nil
+}
+
+func (inv *Inventory) SBOM() *govulncheck.SBOM {
+	sbom := &govulncheck.SBOM{GoVersion: inv.GoVersion}
+	for _, mod := range inv.Modules {
+		sbom.Modules = append(sbom.Modules, &govulncheck.Module{
+			Path:    mod.Path,
+			Version: mod.Version,
+		})
+	}
+	// add stdlib to mirror source mode output
+	sbom.Modules = append(sbom.Modules, &govulncheck.Module{
+		Path:    external.GoStdModulePath,
+		Version: inv.GoVersion,
+	})
+	return sbom
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestInventory(t *testing.T) {
+	// The modules have spare capacity, which must not be written to.
+	modules := append(make([]*packages.Module, 0, 3),
+		&packages.Module{Path: "golang.org/amod", Version: "v1.1.3"},
+		&packages.Module{Path: "golang.org/bmod", Version: "v0.5.0"},
+	)
+	inv := &Inventory{
+		Modules:   modules,
+		Packages:  []string{"golang.org/amod/avuln", "archive/zip", "net/http"},
+		GoVersion: runtime.Version(),
+	}
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &govulncheck.Config{ScanLevel: "package"}
+	h := test.NewMockHandler()
+	res, err := inventory(context.Background(), h, inv, cfg, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := modules[:cap(modules)][2]; m != nil {
+		t.Errorf("inventory wrote %s to the spare capacity of the modules", m.Path)
+	}
+
+	// Only packages listed in the inventory yield package findings.
+	want := []string{"archive/zip", "golang.org/amod/avuln"}
+	var got []string
+	for _, v := range res.Vulns {
+		got = append(got, v.Package.PkgPath)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("(-want, +got): %s", diff)
+	}
+
+	// All vulnerable modules yield module findings.
+	mods := make(map[string]bool)
+	for _, f := range h.FindingMessages {
+		if f.Trace[0].Package == "" {
+			mods[f.Trace[0].Module] = true
+		}
+	}
+	wantMods := map[string]bool{"golang.org/amod": true, "golang.org/bmod": true, "stdlib": true}
+	if diff := cmp.Diff(wantMods, mods); diff != "" {
+		t.Errorf("(-want, +got): %s", diff)
+	}
+}
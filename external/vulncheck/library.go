@@ -0,0 +1,88 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/ssa"
+)
+
+// libraryEntryPoints returns the exported functions and methods
+// of the non-main packages of topPackages, which are the entry
+// points of library scans. Package initialization is left out,
+// as it is not part of the API of a library.
+func libraryEntryPoints(topPackages []*ssa.Package) ([]*ssa.Function, error) {
+	var entries []*ssa.Function
+	for _, pkg := range topPackages {
+		if pkg.Pkg.Name() == "main" {
+			continue
+		}
+		for _, member := range pkg.Members {
+			for _, f := range memberFuncs(member, pkg.Prog) {
+				if isEntry(f) && !isPackageInit(f) {
+					entries = append(entries, f)
+				}
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no exported functions or methods to scan as a library")
+	}
+	return entries, nil
+}
+
+// markExposure sets the ExposedBy of the called vulnerabilities in
+// vulns to the API names of the functions of entries they can be
+// reached from.
+func markExposure(entries []*FuncNode, vulns []*Vuln) {
+	isEntry := make(map[*FuncNode]bool)
+	for _, e := range entries {
+		isEntry[e] = true
+	}
+	for _, v := range vulns {
+		if v.CallSink == nil {
+			continue
+		}
+		var apis []string
+		seen := map[*FuncNode]bool{v.CallSink: true}
+		queue := []*FuncNode{v.CallSink}
+		for len(queue) > 0 {
+			f := queue[0]
+			queue = queue[1:]
+			if isEntry[f] {
+				apis = append(apis, apiName(f))
+			}
+			for _, cs := range f.CallSites {
+				if !seen[cs.Parent] {
+					seen[cs.Parent] = true
+					queue = append(queue, cs.Parent)
+				}
+			}
+		}
+		v.ExposedBy = sortedUnique(apis)
+	}
+}
+
+// apiName returns the name of f in the form package.symbol,
+// as in golang.org/x/net/http2.Server.ServeConn.
+func apiName(f *FuncNode) string {
+	if f.RecvType == "" {
+		return f.Package.PkgPath + "." + f.Name
+	}
+	return f.Package.PkgPath + "." + strings.TrimPrefix(f.Receiver(), "*") + "." + f.Name
+}
+
+// sortedUnique returns the sorted distinct strings of ss.
+func sortedUnique(ss []string) []string {
+	if len(ss) == 0 {
+		return nil
+	}
+	ss = slices.Clone(ss)
+	slices.Sort(ss)
+	return slices.Compact(ss)
+}
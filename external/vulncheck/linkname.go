@@ -0,0 +1,196 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"bufio"
+	"go/token"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/packages"
+)
+
+// A link is a reference to a function made outside of Go code, so
+// that the call graph does not account for it: the function named by
+// a //go:linkname directive of a function declared without a body, or
+// a function referenced by an assembly function.
+type link struct {
+	pkgPath string
+	symbol  string // as in Go code, such as F, T.M, or (*T).M
+	pos     token.Position
+	linkage govulncheck.Linkage
+	osvs    []*osv.Entry
+}
+
+// dbName returns the name of the symbol of l
+// as in the vulnerability database.
+func (l *link) dbName() string {
+	return strings.NewReplacer("(*", "", ")", "").Replace(l.symbol)
+}
+
+// funcNode returns the node of the function of l, called by from.
+func (l *link) funcNode(from *FuncNode, graph *PackageGraph) *FuncNode {
+	fn := &FuncNode{
+		Name:    l.symbol,
+		Package: graph.GetPackage(l.pkgPath),
+	}
+	if dot := strings.LastIndex(l.symbol, "."); dot >= 0 {
+		recv := l.symbol[:dot]
+		fn.Name = l.symbol[dot+1:]
+		if strings.HasPrefix(recv, "(*") {
+			fn.RecvType = "*" + l.pkgPath + "." + strings.TrimSuffix(recv[2:], ")")
+		} else {
+			fn.RecvType = l.pkgPath + "." + recv
+		}
+	}
+	pos := l.pos
+	fn.CallSites = []*CallSite{{
+		Parent:   from,
+		Name:     fn.Name,
+		RecvType: fn.RecvType,
+		Resolved: true,
+		Pos:      &pos,
+	}}
+	return fn
+}
+
+// vulnLinks returns the links to vulnerable functions of the functions
+// in cg declared without a body, which are those implemented in
+// assembly or by a //go:linkname directive.
+func vulnLinks(cg *callgraph.Graph, affVulns affectingVulns, graph *PackageGraph) map[*callgraph.Node][]*link {
+	byPkg := make(map[string]map[string][]*link)
+	m := make(map[*callgraph.Node][]*link)
+	for f, n := range cg.Nodes {
+		if f == nil || len(f.Blocks) > 0 || f.Pkg == nil || f.Synthetic != "" || f.Signature.Recv() != nil {
+			continue
+		}
+		path := f.Pkg.Pkg.Path()
+		links, ok := byPkg[path]
+		if !ok {
+			links = pkgLinks(graph.GetPackage(path))
+			byPkg[path] = links
+		}
+		for _, l := range links[f.Name()] {
+			l.osvs = affVulns.ForSymbol(pkgModPath(graph.GetPackage(l.pkgPath)), l.pkgPath, l.dbName())
+			if len(l.osvs) > 0 {
+				m[n] = append(m[n], l)
+			}
+		}
+	}
+	return m
+}
+
+// pkgLinks returns the links of the functions of p declared without a
+// body, by function name.
+func pkgLinks(p *packages.Package) map[string][]*link {
+	links := make(map[string][]*link)
+	for _, f := range p.Syntax {
+		for _, cg := range f.Comments {
+			for _, c := range cg.List {
+				local, target, ok := linkname(c.Text)
+				if !ok {
+					continue
+				}
+				pkgPath, symbol, ok := splitEntryPattern(target)
+				if !ok || pkgPath == p.PkgPath {
+					continue
+				}
+				links[local] = append(links[local], &link{
+					pkgPath: pkgPath,
+					symbol:  symbol,
+					pos:     p.Fset.Position(c.Pos()),
+					linkage: govulncheck.LinkageLinkname,
+				})
+			}
+		}
+	}
+	for _, file := range p.OtherFiles {
+		if strings.HasSuffix(file, ".s") {
+			// Assembly files that cannot be read
+			// are also not a concern of the build.
+			_ = asmLinks(file, p.PkgPath, links)
+		}
+	}
+	return links
+}
+
+// linkname parses the //go:linkname directive in comment, if any, of
+// the form //go:linkname local target.
+func linkname(comment string) (local, target string, ok bool) {
+	rest, ok := strings.CutPrefix(comment, "//go:linkname ")
+	if !ok {
+		return "", "", false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) != 2 {
+		return "", "", false
+	}
+	return fields[0], fields[1], true
+}
+
+// asmSymbol matches the references to symbols in assembly, as in
+// ·f(SB), runtime·f(SB), or golang.org∕x∕sys∕unix·f<ABIInternal>(SB),
+// where the package path has its slashes replaced by division slashes.
+var asmSymbol = regexp.MustCompile(`([\p{L}\p{N}_.∕-]*)·([\p{L}\p{N}_]+)(?:<\w+>)?\(SB\)`)
+
+// asmLinks adds the references to functions of other packages made by
+// the assembly functions in file, of package pkgPath, to links. Each
+// reference is attributed to the function of the preceding TEXT
+// directive.
+func asmLinks(file, pkgPath string, links map[string][]*link) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var text string // the current function
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		code, _, _ := strings.Cut(s.Text(), "//")
+		fields := strings.Fields(code)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "TEXT":
+			text = ""
+			if m := asmSymbol.FindStringSubmatch(code); m != nil && asmPkgPath(m[1], pkgPath) == pkgPath {
+				text = m[2]
+			}
+			continue
+		case "DATA", "GLOBL":
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		for _, m := range asmSymbol.FindAllStringSubmatch(code, -1) {
+			if target := asmPkgPath(m[1], pkgPath); target != pkgPath {
+				links[text] = append(links[text], &link{
+					pkgPath: target,
+					symbol:  m[2],
+					pos:     token.Position{Filename: file, Line: line, Column: 1},
+					linkage: govulncheck.LinkageAssembly,
+				})
+			}
+		}
+	}
+	return s.Err()
+}
+
+// asmPkgPath returns the package path of a symbol
+// referenced as qualifier·name in package pkgPath.
+func asmPkgPath(qualifier, pkgPath string) string {
+	if qualifier == "" {
+		return pkgPath
+	}
+	return strings.ReplaceAll(qualifier, "∕", "/")
+}
@@ -0,0 +1,152 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestLinks(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import (
+				_ "unsafe"
+
+				_ "golang.org/bmod/bvuln"
+			)
+
+			//go:linkname vuln golang.org/bmod/bvuln.Vuln
+			func vuln()
+
+			func X() {
+				vuln()
+			}`,
+				"y/y.go": `
+			package y
+
+			import _ "golang.org/bmod/bvuln"
+
+			func asm()
+
+			func Y() {
+				asm()
+			}`,
+				"y/y.s": "TEXT ·asm(SB),0,$0-0\n\tCALL golang.org∕bmod∕bvuln·Vuln(SB) // the vulnerable function\n\tRET\n",
+			},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := NewPackageGraph("go1.18")
+	if err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x"), path.Join(e.Temp(), "entry/y")}, true); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &govulncheck.Config{ScanLevel: "symbol"}
+	result, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, v := range result.Vulns {
+		if v.CallSink == nil || v.Linkage == "" {
+			continue
+		}
+		for _, cs := range v.CallSink.CallSites {
+			got = append(got, string(v.Linkage)+" "+cs.Parent.String()+" -> "+v.CallSink.String())
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		"assembly golang.org/entry/y.asm -> golang.org/bmod/bvuln.Vuln",
+		"linkname golang.org/entry/x.vuln -> golang.org/bmod/bvuln.Vuln",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got links %v; want %v", got, want)
+	}
+}
+
+func TestAsmLinks(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "a.s")
+	src := `#include "textflag.h"
+
+DATA ·table+0(SB)/8, $runtime·other(SB)
+GLOBL ·table(SB), RODATA, $8
+
+TEXT ·f(SB), NOSPLIT, $0-0
+	CALL ·g(SB)
+	JMP runtime·memmove<ABIInternal>(SB)
+
+TEXT ·h(SB), NOSPLIT, $0-0
+	// CALL golang.org∕x∕sys∕unix·Commented(SB)
+	MOVQ $golang.org∕x∕sys∕unix·Syscall(SB), AX
+	RET
+`
+	if err := os.WriteFile(file, []byte(src), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	links := make(map[string][]*link)
+	if err := asmLinks(file, "example.com/p", links); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for name, ls := range links {
+		for _, l := range ls {
+			got[name] = append(got[name], l.pkgPath+"."+l.symbol)
+		}
+	}
+	want := map[string][]string{
+		"f": {"runtime.memmove"},
+		"h": {"golang.org/x/sys/unix.Syscall"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
+
+func TestLinkDBName(t *testing.T) {
+	for _, test := range []struct {
+		symbol, want, recv string
+	}{
+		{"F", "F", ""},
+		{"T.M", "T.M", "example.com/p.T"},
+		{"(*T).M", "T.M", "*example.com/p.T"},
+	} {
+		l := &link{pkgPath: "example.com/p", symbol: test.symbol}
+		if got := l.dbName(); got != test.want {
+			t.Errorf("dbName(%s) = %s; want %s", test.symbol, got, test.want)
+		}
+		if got := l.funcNode(nil, NewPackageGraph("go1.18")).RecvType; got != test.recv {
+			t.Errorf("receiver of %s = %s; want %s", test.symbol, got, test.recv)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+// A SymbolMatcher matches the packages and symbols of the code scanned
+// to vulnerable ones of OSV entries they are copies of, but are not
+// named after, such as those of internal forks of vulnerable modules
+// with renamed packages, or of packages moved to vanity import paths.
+type SymbolMatcher interface {
+	// MatchSymbol returns the vulnerable symbols that the function or
+	// method symbol of the package at importPath is a copy of, if any,
+	// named as in OSV entries, as in Reader.Read. It is called with an
+	// empty symbol to match the package itself, and then returns the
+	// vulnerable packages, with empty names.
+	MatchSymbol(importPath, symbol string) []MatchedSymbol
+}
+
+// SymbolMatcherFunc adapts an ordinary function to the SymbolMatcher interface.
+type SymbolMatcherFunc func(importPath, symbol string) []MatchedSymbol
+
+func (f SymbolMatcherFunc) MatchSymbol(importPath, symbol string) []MatchedSymbol {
+	return f(importPath, symbol)
+}
+
+// A MatchedSymbol is a vulnerable symbol of an OSV entry.
+type MatchedSymbol struct {
+	// Module is the path of the module of Package, or ""
+	// for the module whose path is the longest prefix of it.
+	Module string
+
+	// Package is the import path of the package, and Name
+	// the name of the symbol, as in OSV entries.
+	Package string
+	Name    string
+}
+
+var (
+	matchersMu sync.RWMutex
+	matchers   []SymbolMatcher
+)
+
+// RegisterSymbolMatcher adds m to the matchers consulted, in addition
+// to matching packages and symbols by name, when looking up the
+// vulnerabilities of the packages and symbols of the code scanned,
+// for all scans run by the process.
+//
+// Only the vulnerabilities of the modules of the code scanned are
+// fetched, so the symbols matched must be of those modules, as is
+// the case of forks replacing them by a replace directive.
+func RegisterSymbolMatcher(m SymbolMatcher) {
+	matchersMu.Lock()
+	defer matchersMu.Unlock()
+	matchers = append(matchers, m)
+}
+
+// matchedSymbols returns the symbols the registered
+// matchers match symbol of importPath to.
+func matchedSymbols(importPath, symbol string) []MatchedSymbol {
+	matchersMu.RLock()
+	defer matchersMu.RUnlock()
+	var syms []MatchedSymbol
+	for _, m := range matchers {
+		syms = append(syms, m.MatchSymbol(importPath, symbol)...)
+	}
+	return syms
+}
+
+// appendNewEntries appends the entries of add not in vulns to vulns.
+func appendNewEntries(vulns, add []*osv.Entry) []*osv.Entry {
+	for _, v := range add {
+		if !slices.Contains(vulns, v) {
+			vulns = append(vulns, v)
+		}
+	}
+	return vulns
+}
@@ -0,0 +1,68 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestSymbolMatcher(t *testing.T) {
+	saved := matchers
+	t.Cleanup(func() { matchers = saved })
+	matchers = nil
+
+	// example.mod/a is a fork in which package c is renamed to
+	// cfork, and its vulnerable function Parse to ParseFork.
+	RegisterSymbolMatcher(SymbolMatcherFunc(func(importPath, symbol string) []MatchedSymbol {
+		if importPath != "example.mod/a/cfork" {
+			return nil
+		}
+		switch symbol {
+		case "":
+			return []MatchedSymbol{{Package: "example.mod/a/c"}}
+		case "ParseFork":
+			return []MatchedSymbol{{Module: "example.mod/a", Package: "example.mod/a/c", Name: "Parse"}}
+		}
+		return nil
+	}))
+	entry := &osv.Entry{ID: "a", Affected: []osv.Affected{{
+		Module: osv.Module{Path: "example.mod/a"},
+		EcosystemSpecific: osv.EcosystemSpecific{
+			Packages: []osv.Package{{
+				Path:    "example.mod/a/c",
+				Symbols: []string{"Parse"},
+			}},
+		},
+	}}}
+	aff := affectingVulns{{
+		Module: &packages.Module{Path: "example.mod/a", Version: "v1.0.0"},
+		Vulns:  []*osv.Entry{entry},
+	}}
+
+	for _, test := range []struct {
+		importPath, symbol string
+		want               bool
+	}{
+		{"example.mod/a/c", "", true},
+		{"example.mod/a/c", "Parse", true},
+		{"example.mod/a/cfork", "", true},
+		{"example.mod/a/cfork", "ParseFork", true},
+		{"example.mod/a/cfork", "Parse", false},
+		{"example.mod/a/d", "", false},
+	} {
+		var got []*osv.Entry
+		if test.symbol == "" {
+			got = aff.ForPackage("example.mod/a", test.importPath)
+		} else {
+			got = aff.ForSymbol("example.mod/a", test.importPath, test.symbol)
+		}
+		if matched := len(got) == 1 && got[0] == entry; matched != test.want {
+			t.Errorf("vulnerabilities of %s %q = %v; want matched %t", test.importPath, test.symbol, got, test.want)
+		}
+	}
+}
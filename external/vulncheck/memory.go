@@ -0,0 +1,151 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+// errMemoryLimit is the cause of the cancellation of
+// call graph constructions exceeding their memory limit.
+var errMemoryLimit = errors.New("memory limit exceeded")
+
+// A MemoryLimitError is returned by Source and SourceResult when
+// building the call graph exceeds the MaxMemory of their config. The
+// vulnerable packages imported have then been reported, as at the
+// package level, and SourceResult returns them along with the error,
+// but whether their vulnerable symbols are called is unknown.
+type MemoryLimitError struct {
+	Limit int64 // in bytes
+}
+
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("building the call graph exceeded the memory limit of %s, so only the vulnerable packages imported are reported, and whether their vulnerable symbols are called is unknown", byteSize(e.Limit))
+}
+
+// memoryGuard stops the construction of a call graph when the live
+// heap exceeds a limit, so that the analysis can fall back to package
+// level results instead of the process running out of memory.
+//
+// The construction is checked between its phases, and continuously
+// through the cancellation of its context, which the call graph
+// algorithms observe between their own phases. The guard leaves the
+// soft memory limit of the runtime alone, which is that of the whole
+// process, and set for the scan by its caller.
+type memoryGuard struct {
+	limit    int64
+	cancel   context.CancelCauseFunc
+	done     chan struct{}
+	exceeded atomic.Bool
+}
+
+// newMemoryGuard returns a guard for the limit in bytes, if any, and
+// the context to build call graphs with. The guard must be stopped.
+func newMemoryGuard(ctx context.Context, limit int64) (context.Context, *memoryGuard) {
+	g := &memoryGuard{limit: limit}
+	if limit <= 0 {
+		return ctx, g
+	}
+	ctx, g.cancel = context.WithCancelCause(ctx)
+	g.done = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-g.done:
+				return
+			case <-ticker.C:
+				g.check()
+			}
+		}
+	}()
+	return ctx, g
+}
+
+// check reports whether the limit of g was exceeded,
+// and if so, cancels the context of g.
+//
+// The live heap is only known after a garbage collection, which the
+// runtime may not have run lately, so once the heap in use, garbage
+// included, exceeds the limit, a collection is run to find out.
+func (g *memoryGuard) check() bool {
+	if g.limit <= 0 {
+		return false
+	}
+	limit := uint64(g.limit)
+	if !g.exceeded.Load() && heapInUse() > limit {
+		if liveHeap() <= limit {
+			runtime.GC()
+		}
+		if liveHeap() > limit {
+			g.exceeded.Store(true)
+			g.cancel(errMemoryLimit)
+		}
+	}
+	return g.exceeded.Load()
+}
+
+// stop stops g.
+func (g *memoryGuard) stop() {
+	if g.limit <= 0 {
+		return
+	}
+	close(g.done)
+	g.cancel(nil)
+}
+
+// liveHeap returns the size in bytes of the heap
+// found to be live by the last garbage collection.
+func liveHeap() uint64 {
+	if n, ok := readMetric("/gc/heap/live:bytes"); ok {
+		return n
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// heapInUse returns the size in bytes of the objects
+// of the heap, live or not yet collected.
+func heapInUse() uint64 {
+	if n, ok := readMetric("/memory/classes/heap/objects:bytes"); ok {
+		return n
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// readMetric returns the value of the runtime metric
+// name, if it is supported.
+func readMetric(name string) (uint64, bool) {
+	sample := []metrics.Sample{{Name: name}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0, false
+	}
+	return sample[0].Value.Uint64(), true
+}
+
+// byteSize formats n bytes in the largest
+// binary unit dividing it, as in 4GiB.
+func byteSize(n int64) string {
+	for _, u := range []struct {
+		name string
+		size int64
+	}{{"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}} {
+		if n >= u.size && n%u.size == 0 {
+			return fmt.Sprintf("%d%s", n/u.size, u.name)
+		}
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
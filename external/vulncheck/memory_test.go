@@ -0,0 +1,101 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"errors"
+	"path"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestMemoryLimit(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import "golang.org/bmod/bvuln"
+
+			func X() {
+				bvuln.Vuln()
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		maxMemory int64
+		wantCalls bool
+	}{
+		{0, true},
+		{1 << 40, true},
+		{1, false},
+	} {
+		graph := NewPackageGraph("go1.18")
+		if err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true); err != nil {
+			t.Fatal(err)
+		}
+		h := test.NewMockHandler()
+		cfg := &govulncheck.Config{ScanLevel: "symbol", MaxMemory: tc.maxMemory}
+		result, err := source(context.Background(), h, cfg, c, graph)
+		var me *MemoryLimitError
+		if got := errors.As(err, &me); got == tc.wantCalls {
+			t.Errorf("maxMemory=%d: got error %v; want a memory limit error %v", tc.maxMemory, err, !tc.wantCalls)
+		}
+		if err != nil && me == nil {
+			t.Fatal(err)
+		}
+		var calls, pkgs int
+		for _, v := range result.Vulns {
+			if v.CallSink != nil {
+				calls++
+			} else if v.Use == nil {
+				pkgs++
+			}
+		}
+		if got := calls > 0; got != tc.wantCalls {
+			t.Errorf("maxMemory=%d: got %d called vulns; want called vulns %v", tc.maxMemory, calls, tc.wantCalls)
+		}
+		if !tc.wantCalls && pkgs == 0 {
+			t.Errorf("maxMemory=%d: got no imported vulnerable packages", tc.maxMemory)
+		}
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	for _, test := range []struct {
+		n    int64
+		want string
+	}{
+		{4 << 30, "4GiB"},
+		{1536 << 20, "1536MiB"},
+		{1 << 10, "1KiB"},
+		{1000, "1000 bytes"},
+	} {
+		if got := byteSize(test.n); got != test.want {
+			t.Errorf("byteSize(%d) = %q; want %q", test.n, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"fmt"
+	"go/token"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Merge returns the union of results, such as those of scans of the
+// same code for several platforms or of several binaries, for a single
+// report. Nil results are ignored.
+//
+// Functions of the same package, receiver, name, type arguments, and
+// position are merged into one, with the union of their call sites,
+// and so are the vulnerabilities of the same OSV entry, symbol, and
+// package, with the union of their taint sources and exposing
+// functions. Otherwise, and for the call stacks, the first result
+// wins. The packages of the results are shared by the merged result,
+// which does not modify results.
+func Merge(results ...*Result) *Result {
+	m := &merger{
+		pkgs:   make(map[string]*packages.Package),
+		funcs:  make(map[string]*FuncNode),
+		sites:  make(map[string]*CallSite),
+		fnOf:   make(map[*FuncNode]*FuncNode),
+		vulns:  make(map[string]*Vuln),
+		vulnOf: make(map[*Vuln]*Vuln),
+	}
+	merged := &Result{}
+	entries := make(map[*FuncNode]bool)
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		for _, e := range r.EntryFunctions {
+			if me := m.fn(e); !entries[me] {
+				entries[me] = true
+				merged.EntryFunctions = append(merged.EntryFunctions, me)
+			}
+		}
+		for _, v := range r.Vulns {
+			if mv, added := m.vuln(v); added {
+				merged.Vulns = append(merged.Vulns, mv)
+			}
+		}
+		// Visit the call stacks in the order of the vulnerabilities
+		// so that the stack kept is the same from merge to merge.
+		for _, v := range r.Vulns {
+			cs, ok := r.CallStacks[v]
+			mv := m.vulnOf[v]
+			if !ok || merged.CallStacks[mv] != nil {
+				continue
+			}
+			if merged.CallStacks == nil {
+				merged.CallStacks = make(map[*Vuln]CallStack)
+			}
+			merged.CallStacks[mv] = m.callStack(cs)
+		}
+	}
+	return merged
+}
+
+// merger maps the functions, call sites, and vulnerabilities of
+// the results being merged to those of the merged result.
+type merger struct {
+	pkgs   map[string]*packages.Package // by package path
+	funcs  map[string]*FuncNode         // by funcKey
+	sites  map[string]*CallSite         // by funcKey of the callee and siteKey
+	fnOf   map[*FuncNode]*FuncNode      // merged function of each function
+	vulns  map[string]*Vuln             // by vulnKey
+	vulnOf map[*Vuln]*Vuln              // merged vulnerability of each vulnerability
+}
+
+// pkg returns the first package of the path of p seen.
+func (m *merger) pkg(p *packages.Package) *packages.Package {
+	if p == nil {
+		return nil
+	}
+	if mp, ok := m.pkgs[p.PkgPath]; ok {
+		return mp
+	}
+	m.pkgs[p.PkgPath] = p
+	return p
+}
+
+// fn returns the merged function of f, adding the
+// call sites of f to it the first time f is seen.
+func (m *merger) fn(f *FuncNode) *FuncNode {
+	if f == nil {
+		return nil
+	}
+	if mf, ok := m.fnOf[f]; ok {
+		return mf
+	}
+	key := funcKey(f)
+	mf := m.funcs[key]
+	if mf == nil {
+		c := *f
+		c.Package = m.pkg(f.Package)
+		c.CallSites = nil
+		mf = &c
+		m.funcs[key] = mf
+	}
+	// Record f before visiting its callers, which may call it back.
+	m.fnOf[f] = mf
+	for _, s := range f.CallSites {
+		// Visit the caller even when the call site is known,
+		// since it may have other callers.
+		parent := m.fn(s.Parent)
+		key := key + "|" + siteKey(s)
+		if _, ok := m.sites[key]; ok {
+			continue
+		}
+		ms := *s
+		ms.Parent = parent
+		m.sites[key] = &ms
+		mf.CallSites = append(mf.CallSites, &ms)
+	}
+	return mf
+}
+
+// site returns the merged call site of s, a call site of f.
+func (m *merger) site(f *FuncNode, s *CallSite) *CallSite {
+	if f != nil {
+		m.fn(f)
+		if ms, ok := m.sites[funcKey(f)+"|"+siteKey(s)]; ok {
+			return ms
+		}
+	}
+	// s is not a call site of f, as in stacks made by hand.
+	ms := *s
+	ms.Parent = m.fn(s.Parent)
+	return &ms
+}
+
+// vuln returns the merged vulnerability of v,
+// reporting whether it is the first of its kind.
+func (m *merger) vuln(v *Vuln) (_ *Vuln, added bool) {
+	if mv, ok := m.vulnOf[v]; ok {
+		return mv, false
+	}
+	key := vulnKey(v)
+	mv := m.vulns[key]
+	if mv == nil {
+		c := *v
+		c.Package = m.pkg(v.Package)
+		c.CallSink = m.fn(v.CallSink)
+		c.Use = m.use(v.Use)
+		c.Taint = slices.Clone(v.Taint)
+		c.ExposedBy = slices.Clone(v.ExposedBy)
+		m.vulns[key] = &c
+		m.vulnOf[v] = &c
+		return &c, true
+	}
+	m.vulnOf[v] = mv
+	if mv.CallSink == nil {
+		mv.CallSink = m.fn(v.CallSink)
+	} else {
+		// Add the call sites of v.CallSink, which
+		// may be reached from other entry points.
+		m.fn(v.CallSink)
+	}
+	if mv.Use == nil {
+		mv.Use = m.use(v.Use)
+	}
+	for _, t := range v.Taint {
+		if !slices.Contains(mv.Taint, t) {
+			mv.Taint = append(mv.Taint, t)
+		}
+	}
+	for _, e := range v.ExposedBy {
+		if !slices.Contains(mv.ExposedBy, e) {
+			mv.ExposedBy = append(mv.ExposedBy, e)
+		}
+	}
+	mv.Linked = mv.Linked || v.Linked
+	if mv.Linkage == "" {
+		mv.Linkage = v.Linkage
+	}
+	return mv, false
+}
+
+func (m *merger) use(u *Use) *Use {
+	if u == nil {
+		return nil
+	}
+	c := *u
+	c.Parent = m.fn(u.Parent)
+	return &c
+}
+
+func (m *merger) callStack(cs CallStack) CallStack {
+	if cs == nil {
+		return nil
+	}
+	mcs := make(CallStack, len(cs))
+	for i, e := range cs {
+		mcs[i] = StackEntry{Function: m.fn(e.Function)}
+		if e.Call != nil {
+			// The call of an entry is a call site of the next function.
+			var callee *FuncNode
+			if i+1 < len(cs) {
+				callee = cs[i+1].Function
+			}
+			mcs[i].Call = m.site(callee, e.Call)
+		}
+	}
+	return mcs
+}
+
+// funcKey identifies f across results.
+func funcKey(f *FuncNode) string {
+	var pkg string
+	if f.Package != nil {
+		pkg = f.Package.PkgPath
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%s", pkg, f.RecvType, f.Name, strings.Join(f.TypeArgs, ","), posKey(f.Pos))
+}
+
+// siteKey identifies s among the call sites of a function.
+func siteKey(s *CallSite) string {
+	var parent string
+	if s.Parent != nil {
+		parent = funcKey(s.Parent)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s", parent, s.RecvType, s.Name, posKey(s.Pos))
+}
+
+// vulnKey identifies v across results.
+func vulnKey(v *Vuln) string {
+	var id, pkg string
+	if v.OSV != nil {
+		id = v.OSV.ID
+	}
+	if v.Package != nil {
+		pkg = v.Package.PkgPath
+	}
+	return id + "|" + pkg + "|" + v.Symbol
+}
+
+func posKey(pos *token.Position) string {
+	if pos == nil {
+		return ""
+	}
+	return pos.String()
+}
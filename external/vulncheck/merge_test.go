@@ -0,0 +1,103 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"go/token"
+	"slices"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages"
+)
+
+// mergeScan returns the result of a scan in which main.main calls
+// a.A, from the given file of main, which calls the vulnerable a.V.
+func mergeScan(mainFile string, taint govulncheck.TaintSource) *Result {
+	mainPkg := &packages.Package{PkgPath: "example.com/main"}
+	aPkg := &packages.Package{PkgPath: "example.com/a"}
+	main := &FuncNode{Name: "main", Package: mainPkg, Pos: &token.Position{Filename: mainFile, Line: 3}}
+	a := &FuncNode{Name: "A", Package: aPkg, Pos: &token.Position{Filename: "a.go", Line: 5}}
+	v := &FuncNode{Name: "V", Package: aPkg, Pos: &token.Position{Filename: "a.go", Line: 9}}
+	toA := &CallSite{Parent: main, Name: "A", Pos: &token.Position{Filename: mainFile, Line: 4}, Resolved: true}
+	toV := &CallSite{Parent: a, Name: "V", Pos: &token.Position{Filename: "a.go", Line: 6}, Resolved: true}
+	a.CallSites = []*CallSite{toA}
+	v.CallSites = []*CallSite{toV}
+	vuln := &Vuln{
+		OSV:      &osv.Entry{ID: "GO-0000-0001"},
+		Symbol:   "V",
+		CallSink: v,
+		Package:  aPkg,
+		Taint:    []govulncheck.TaintSource{taint},
+	}
+	return &Result{
+		EntryFunctions: []*FuncNode{main},
+		Vulns:          []*Vuln{vuln},
+		CallStacks: map[*Vuln]CallStack{vuln: {
+			{Function: main, Call: toA}, {Function: a, Call: toV}, {Function: v},
+		}},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// The same code scanned for two platforms, whose main
+	// functions are in different files.
+	linux := mergeScan("main_linux.go", govulncheck.TaintSourceNetwork)
+	windows := mergeScan("main_windows.go", govulncheck.TaintSourceFile)
+	again := mergeScan("main_linux.go", govulncheck.TaintSourceNetwork)
+	got := Merge(linux, nil, windows, again)
+
+	var entries []string
+	for _, e := range got.EntryFunctions {
+		entries = append(entries, e.Pos.Filename)
+	}
+	if want := []string{"main_linux.go", "main_windows.go"}; !cmp.Equal(entries, want) {
+		t.Errorf("entry functions in %v; want %v", entries, want)
+	}
+	if len(got.Vulns) != 1 {
+		t.Fatalf("got %d vulnerabilities; want 1", len(got.Vulns))
+	}
+	v := got.Vulns[0]
+	if want := []govulncheck.TaintSource{govulncheck.TaintSourceNetwork, govulncheck.TaintSourceFile}; !cmp.Equal(v.Taint, want) {
+		t.Errorf("taint = %v; want %v", v.Taint, want)
+	}
+
+	// a.V is called from a.A only, which is
+	// called from both main functions.
+	sink := v.CallSink
+	if len(sink.CallSites) != 1 {
+		t.Fatalf("%s has %d call sites; want 1", sink, len(sink.CallSites))
+	}
+	a := sink.CallSites[0].Parent
+	var callers []string
+	for _, s := range a.CallSites {
+		callers = append(callers, s.Parent.Pos.Filename)
+		if !slices.Contains(got.EntryFunctions, s.Parent) {
+			t.Errorf("caller %s of %s is not an entry function of the merged result", s.Parent, a)
+		}
+	}
+	if want := []string{"main_linux.go", "main_windows.go"}; !cmp.Equal(callers, want) {
+		t.Errorf("%s called from %v; want %v", a, callers, want)
+	}
+
+	// The call stack is the first one, made of merged nodes.
+	cs := got.CallStacks[v]
+	if len(cs) != 3 {
+		t.Fatalf("got call stack of %d entries; want 3", len(cs))
+	}
+	if cs[0].Function != got.EntryFunctions[0] || cs[1].Function != a || cs[2].Function != sink {
+		t.Error("call stack functions are not those of the merged result")
+	}
+	if cs[0].Call != a.CallSites[0] || cs[1].Call != sink.CallSites[0] {
+		t.Error("call stack calls are not those of the merged result")
+	}
+
+	// The results merged are not modified.
+	if len(linux.Vulns[0].Taint) != 1 || len(linux.Vulns[0].CallSink.CallSites[0].Parent.CallSites) != 1 {
+		t.Error("Merge modified its arguments")
+	}
+}
@@ -5,10 +5,15 @@
 package vulncheck
 
 import (
+	"context"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"os/exec"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/StevenACoffman/invuln/external"
 	"github.com/StevenACoffman/invuln/external/govulncheck"
@@ -25,6 +30,24 @@ type PackageGraph struct {
 	topPkgs  []*packages.Package
 	modules  map[string]*packages.Module  // all modules (even replacing ones)
 	packages map[string]*packages.Package // all packages (even dependencies)
+
+	// getMu guards packages and modules in GetPackage,
+	// which is called concurrently during analysis.
+	getMu sync.Mutex
+
+	// gopath is set if the packages were loaded in GOPATH mode, and
+	// unknownVersions holds the paths of the dependencies whose
+	// versions could not be determined then.
+	gopath          bool
+	unknownVersions map[string]bool
+
+	cgoOnce    sync.Once // guards cgo and cLibraries
+	cgo        bool      // whether non-stdlib packages use cgo
+	cLibraries []string  // C libraries linked by non-stdlib packages
+
+	// cgCacheDir and cgCacheKey are the directory and key of the
+	// call graph cache, if any. See UseCallGraphCache.
+	cgCacheDir, cgCacheKey string
 }
 
 func NewPackageGraph(goVersion string) *PackageGraph {
@@ -103,6 +126,8 @@ func (g *PackageGraph) Modules() []*packages.Module {
 	for _, m := range g.modules {
 		mods = append(mods, m)
 	}
+	// Sort for deterministic output.
+	slices.SortFunc(mods, func(m1, m2 *packages.Module) int { return strings.Compare(m1.Path, m2.Path) })
 	return mods
 }
 
@@ -184,6 +209,8 @@ func (g *PackageGraph) findModule(pkgPath string) *packages.Module {
 // GetPackage returns the package matching the path.
 // If the graph does not already know about the package, a new one is added.
 func (g *PackageGraph) GetPackage(path string) *packages.Package {
+	g.getMu.Lock()
+	defer g.getMu.Unlock()
 	if pkg, ok := g.packages[path]; ok {
 		return pkg
 	}
@@ -197,16 +224,43 @@ func (g *PackageGraph) GetPackage(path string) *packages.Package {
 // LoadPackages loads the packages specified by the patterns into the graph.
 // See golang.org/x/tools/go/packages.Load for details of how it works.
 func (g *PackageGraph) LoadPackagesAndMods(cfg *packages.Config, tags []string, patterns []string, wantSymbols bool) error {
+	pkgs, err := LoadPackages(cfg, tags, patterns, wantSymbols)
+	if err != nil {
+		return err
+	}
+	return g.AddLoadedPackages(pkgs)
+}
+
+// LoadPackages loads the packages specified by the patterns as
+// LoadPackagesAndMods does, without adding them to a graph, so that
+// they can be added to the graphs of several scans with
+// AddLoadedPackages.
+func LoadPackages(cfg *packages.Config, tags []string, patterns []string, wantSymbols bool) ([]*packages.Package, error) {
 	if len(tags) > 0 {
-		cfg.BuildFlags = []string{fmt.Sprintf("-tags=%s", strings.Join(tags, ","))}
+		cfg.BuildFlags = append(cfg.BuildFlags, fmt.Sprintf("-tags=%s", strings.Join(tags, ",")))
 	}
 
 	addLoadMode(cfg, wantSymbols)
 
+	progress := reportLoad(cfg)
 	pkgs, err := packages.Load(cfg, patterns...)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	progress.Complete()
+	return pkgs, nil
+}
+
+// AddLoadedPackages adds pkgs, loaded by the caller in PackageLoadMode,
+// or SymbolLoadMode for symbol level analysis, as the top-level packages
+// of g, along with their imports, as LoadPackagesAndMods does for the
+// packages it loads, so that packages already loaded are not loaded
+// again. The errors of the packages are returned, once they are added,
+// as LoadPackagesAndMods returns them. The packages are not copied, and
+// those without a module, such as those of the standard library, are
+// given one.
+func (g *PackageGraph) AddLoadedPackages(pkgs []*packages.Package) error {
+	var err error
 	var perrs []packages.Error
 	packages.Visit(pkgs, nil, func(p *packages.Package) {
 		perrs = append(perrs, p.Errors...)
@@ -226,14 +280,95 @@ func (g *PackageGraph) LoadPackagesAndMods(cfg *packages.Config, tags []string,
 	return err
 }
 
+const (
+	// PackageLoadMode is the mode in which packages are loaded
+	// for module and package level analysis.
+	PackageLoadMode = packages.NeedModule |
+		packages.NeedName |
+		packages.NeedFiles |
+		packages.NeedDeps |
+		packages.NeedImports
+
+	// SymbolLoadMode is the mode in which packages are loaded for
+	// symbol level analysis, which needs their syntax and types.
+	SymbolLoadMode = PackageLoadMode |
+		packages.NeedSyntax |
+		packages.NeedTypes |
+		packages.NeedTypesInfo
+)
+
 func addLoadMode(cfg *packages.Config, wantSymbols bool) {
-	cfg.Mode |=
-		packages.NeedModule |
-			packages.NeedName |
-			packages.NeedDeps |
-			packages.NeedImports
+	cfg.Mode |= PackageLoadMode
 	if wantSymbols {
-		cfg.Mode |= packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+		cfg.Mode |= SymbolLoadMode
+	}
+}
+
+// CheckLoadMode returns an error if pkgs, or the packages they import,
+// lack the information loaded in PackageLoadMode, or in SymbolLoadMode
+// if wantSymbols is set. Packages with errors are not checked, as
+// their errors are reported instead.
+func CheckLoadMode(pkgs []*packages.Package, wantSymbols bool) error {
+	var err error
+	packages.Visit(pkgs, func(p *packages.Package) bool {
+		switch {
+		case err != nil || len(p.Errors) > 0:
+		case p.PkgPath == "":
+			err = fmt.Errorf("package %s was loaded without its path (packages.NeedName)", p.ID)
+		case p.Module == nil && !IsStdPackage(p.PkgPath):
+			err = fmt.Errorf("package %s was loaded without its module (packages.NeedModule)", p.PkgPath)
+		case wantSymbols && p.PkgPath != "unsafe" && (p.Types == nil || p.TypesInfo == nil || len(p.Syntax) < len(p.CompiledGoFiles)):
+			// Package unsafe has no syntax or type information.
+			err = fmt.Errorf("package %s was loaded without its syntax and types (packages.NeedSyntax, NeedTypes, and NeedTypesInfo), which symbol level scans need", p.PkgPath)
+		}
+		return err == nil
+	}, nil)
+	return err
+}
+
+// reportLoad starts the load phase of the context of cfg, if any,
+// making cfg report the files it parses to the returned progress.
+func reportLoad(cfg *packages.Config) *govulncheck.PhaseProgress {
+	ctx := cfg.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	progress := govulncheck.StartPhase(ctx, govulncheck.PhaseLoad, 0)
+	if !govulncheck.ReportsProgress(ctx) {
+		return progress
+	}
+	parse := cfg.ParseFile
+	if parse == nil {
+		// The parser of go/packages, which is not exported.
+		parse = func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			return parser.ParseFile(fset, filename, src, parser.AllErrors|parser.ParseComments)
+		}
+	}
+	cfg.ParseFile = func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+		defer progress.Advance()
+		return parse(fset, filename, src)
+	}
+	return progress
+}
+
+// LimitLoad makes cfg parse at most n files at once, and the go
+// command build at most n packages at once, if n is positive.
+func LimitLoad(cfg *packages.Config, n int) {
+	if n <= 0 {
+		return
+	}
+	cfg.BuildFlags = append(cfg.BuildFlags, fmt.Sprintf("-p=%d", n))
+	parse := cfg.ParseFile
+	if parse == nil {
+		parse = func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			return parser.ParseFile(fset, filename, src, parser.AllErrors|parser.ParseComments)
+		}
+	}
+	sem := make(chan struct{}, n)
+	cfg.ParseFile = func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+		sem <- struct{}{}
+		defer func() { <-sem }()
+		return parse(fset, filename, src)
 	}
 }
 
@@ -303,10 +438,12 @@ func (g *PackageGraph) SBOM() *govulncheck.SBOM {
 
 	mods := append(topMods, depMods...)
 
+	_, libs := g.cgoLibraries()
 	return &govulncheck.SBOM{
-		GoVersion: goVersion,
-		Modules:   mods,
-		Roots:     roots,
+		GoVersion:  goVersion,
+		Modules:    mods,
+		Roots:      roots,
+		CLibraries: libs,
 	}
 }
 
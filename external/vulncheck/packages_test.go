@@ -0,0 +1,49 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"go/ast"
+	"go/token"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestLimitLoad(t *testing.T) {
+	var running, most atomic.Int32
+	cfg := &packages.Config{
+		BuildFlags: []string{"-tags=foo"},
+		ParseFile: func(*token.FileSet, string, []byte) (*ast.File, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+			}
+			time.Sleep(time.Millisecond)
+			return &ast.File{}, nil
+		},
+	}
+	LimitLoad(cfg, 2)
+	if want := []string{"-tags=foo", "-p=2"}; !slices.Equal(cfg.BuildFlags, want) {
+		t.Errorf("build flags = %q; want %q", cfg.BuildFlags, want)
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg.ParseFile(nil, "a.go", nil)
+		}()
+	}
+	wg.Wait()
+	if n := most.Load(); n > 2 {
+		t.Errorf("parsed %d files at once; want at most 2", n)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"go/token"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// reflectiveFuncs are the functions that call functions chosen at run
+// time through reflection, by package path and database name. The value
+// reports whether only exported methods can be called that way.
+var reflectiveFuncs = map[string]map[string]bool{
+	"reflect": {
+		"Value.Call":         false,
+		"Value.CallSlice":    false,
+		"Value.MethodByName": true,
+	},
+	"text/template": {
+		"Template.Execute":         true,
+		"Template.ExecuteTemplate": true,
+	},
+	"html/template": {
+		"Template.Execute":         true,
+		"Template.ExecuteTemplate": true,
+	},
+}
+
+// reflective reports whether f is one of reflectiveFuncs and,
+// if so, whether it only calls exported methods.
+func reflective(f *ssa.Function) (ok, methodsOnly bool) {
+	if f == nil || f.Pkg == nil {
+		return false, false
+	}
+	methodsOnly, ok = reflectiveFuncs[f.Pkg.Pkg.Path()][dbFuncName(f)]
+	return ok, methodsOnly
+}
+
+// addReflectionEdges conservatively adds edges to cg from each call of
+// reflectiveFuncs to the vulnerable functions of prog it could end up
+// calling, so that vulnerable functions called only through reflection
+// are found. The calls of the standard library are skipped, as those of
+// interest, such as text/template calling methods, are modeled at the
+// calls of their exported API.
+func addReflectionEdges(prog *ssa.Program, cg *callgraph.Graph, affVulns affectingVulns, graph *PackageGraph) {
+	var funcs, methods []*ssa.Function
+	for f := range ssautil.AllFunctions(prog) {
+		if f.Synthetic != "" {
+			continue
+		}
+		p := graph.funcPkgPath(f)
+		if len(affVulns.ForSymbol(pkgModPath(graph.GetPackage(p)), p, dbFuncName(f))) == 0 {
+			continue
+		}
+		funcs = append(funcs, f)
+		if f.Signature.Recv() != nil && token.IsExported(f.Name()) {
+			methods = append(methods, f)
+		}
+	}
+	if len(funcs) == 0 {
+		return
+	}
+
+	var nodes []*callgraph.Node
+	for f, n := range cg.Nodes {
+		if ok, _ := reflective(f); ok {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, n := range nodes {
+		targets := funcs
+		if _, methodsOnly := reflective(n.Func); methodsOnly {
+			targets = methods
+		}
+		for _, e := range n.In {
+			if e.Caller.Func.Pkg != nil && IsStdPackage(e.Caller.Func.Pkg.Pkg.Path()) {
+				continue
+			}
+			for _, t := range targets {
+				callgraph.AddEdge(e.Caller, e.Site, cg.CreateNode(t))
+			}
+		}
+	}
+}
+
+// reflectiveEdge reports whether e was added by addReflectionEdges.
+func reflectiveEdge(e *callgraph.Edge) bool {
+	if e.Site == nil {
+		return false
+	}
+	callee := e.Site.Common().StaticCallee()
+	ok, _ := reflective(callee)
+	return ok && callee != e.Callee.Func
+}
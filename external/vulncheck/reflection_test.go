@@ -0,0 +1,117 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/test"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestReflection(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import (
+				"io"
+				"text/template"
+
+				"golang.org/amod/avuln"
+			)
+
+			func X(w io.Writer) {
+				t := template.Must(template.New("x").Parse("{{.Vuln1}}"))
+				t.Execute(w, avuln.VulnData{})
+			}`,
+				"y/y.go": `
+			package y
+
+			import (
+				"reflect"
+
+				"golang.org/bmod/bvuln"
+			)
+
+			func Y(v any, name string) {
+				reflect.ValueOf(v).MethodByName(name).Call(nil)
+			}
+
+			func Z() {
+				bvuln.Vuln()
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/amod@v1.1.3",
+			Files: map[string]interface{}{"avuln/avuln.go": `
+			package avuln
+
+			type VulnData struct {}
+			func (v VulnData) Vuln1() {}
+			func (v VulnData) Vuln2() {}
+			`},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		reflection bool
+		want       map[string][]string
+	}{
+		{false, map[string][]string{
+			"golang.org/entry/y.Z": {"golang.org/bmod/bvuln.Vuln"},
+		}},
+		{true, map[string][]string{
+			"golang.org/entry/x.X": {"golang.org/amod/avuln.VulnData.Vuln1", "golang.org/amod/avuln.VulnData.Vuln2"},
+			"golang.org/entry/y.Y": {"golang.org/amod/avuln.VulnData.Vuln1", "golang.org/amod/avuln.VulnData.Vuln2", "golang.org/bmod/bvuln.Vuln"},
+			"golang.org/entry/y.Z": {"golang.org/bmod/bvuln.Vuln"},
+		}},
+	} {
+		graph := NewPackageGraph("go1.18")
+		err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x"), path.Join(e.Temp(), "entry/y")}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := &govulncheck.Config{ScanLevel: "symbol", Reflection: tc.reflection}
+		result, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := callGraphToStrMap(result); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("reflection=%v: got call graph %v; want %v", tc.reflection, got, tc.want)
+		}
+		for _, v := range result.Vulns {
+			if v.CallSink == nil {
+				continue
+			}
+			for _, cs := range v.CallSink.CallSites {
+				if want := cs.Parent.Name != "Z"; cs.Reflective != want {
+					t.Errorf("reflection=%v: call of %s in %s: got reflective %v; want %v", tc.reflection, v.Symbol, cs.Parent.Name, cs.Reflective, want)
+				}
+			}
+		}
+	}
+}
@@ -6,7 +6,9 @@ package vulncheck
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"time"
 
 	"github.com/StevenACoffman/invuln/external/client"
 	"github.com/StevenACoffman/invuln/external/govulncheck"
@@ -18,15 +20,55 @@ import (
 
 // Source detects vulnerabilities in pkgs and emits the findings to handler.
 func Source(ctx context.Context, handler govulncheck.Handler, cfg *govulncheck.Config, client *client.Client, graph *PackageGraph) error {
+	_, err := SourceResult(ctx, handler, cfg, client, graph)
+	return err
+}
+
+// SourceResult is like Source, but also returns the result of the
+// analysis, for instance to be passed to WriteGraph. If the call graph
+// exceeds cfg.MaxMemory, the result holds the vulnerable packages
+// imported, and the error is a *MemoryLimitError.
+func SourceResult(ctx context.Context, handler govulncheck.Handler, cfg *govulncheck.Config, client *client.Client, graph *PackageGraph) (*Result, error) {
 	vr, err := source(ctx, handler, cfg, client, graph)
+	var me *MemoryLimitError
+	if errors.As(err, &me) {
+		return vr, err
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if cfg.ScanLevel.WantSymbols() {
-		return emitCallFindings(handler, sourceCallstacks(vr))
+		start := time.Now()
+		stacks := sourceCallstacks(ctx, vr, cfg)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		govulncheck.Logger(ctx).Debug("searched for call stacks", "vulns", len(vr.Vulns), "stacks", len(stacks), "duration", time.Since(start))
+		if err := emitCallFindings(handler, stacks, vr.EntryFunctions, false, cfg.Slice); err != nil {
+			return nil, err
+		}
+		if err := emitUseFindings(handler, vr.Vulns); err != nil {
+			return nil, err
+		}
+		vr.CallStacks = stacks
+	}
+	return vr, nil
+}
+
+// Affected reports whether any vulnerability of client affects the
+// modules of graph on goos/goarch. Graphs of packages loaded without
+// their syntax and types suffice, so that callers can check whether
+// a symbol level scan is needed before loading them.
+func Affected(ctx context.Context, client *client.Client, graph *PackageGraph, goos, goarch string) (bool, error) {
+	mv, err := FetchVulnerabilities(ctx, client, graph.Modules())
+	if err != nil {
+		return false, err
+	}
+	for _, m := range mv {
+		m.UnknownVersion = graph.unknownVersions[m.Module.Path]
 	}
-	return nil
+	return len(affectingVulnerabilities(mv, goos, goarch)) > 0, nil
 }
 
 // source detects vulnerabilities in packages. It emits findings to handler
@@ -38,49 +80,84 @@ func source(ctx context.Context, handler govulncheck.Handler, cfg *govulncheck.C
 	defer cancel()
 
 	// If we are building the callgraph, build ssa and the callgraph in parallel
-	// with fetching vulnerabilities. If the vulns set is empty, return without
-	// waiting for SSA construction or callgraph to finish.
+	// with fetching vulnerabilities. If the vulns set is empty, or ctx is done,
+	// return without waiting for SSA construction or callgraph to finish.
 	var (
 		wg       sync.WaitGroup // guards entries, cg, and buildErr
+		prog     *ssa.Program
 		entries  []*ssa.Function
 		cg       *callgraph.Graph
 		buildErr error
 	)
+	buildCtx, guard := newMemoryGuard(ctx, cfg.MaxMemory)
 	if cfg.ScanLevel.WantSymbols() {
 		fset := graph.TopPkgs()[0].Fset
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			prog, ssaPkgs := buildSSA(graph.TopPkgs(), fset)
-			entries = entryPoints(ssaPkgs)
-			cg, buildErr = callGraph(ctx, prog, entries)
+			defer guard.stop()
+			progress := govulncheck.StartPhase(ctx, govulncheck.PhaseCallGraph, 0)
+			logger := govulncheck.Logger(ctx)
+			start := time.Now()
+			var ssaPkgs []*ssa.Package
+			prog, ssaPkgs = buildSSA(graph.TopPkgs(), fset, cfg.LoadWorkers)
+			logger.Debug("built SSA", "packages", len(prog.AllPackages()), "duration", time.Since(start))
+			if guard.check() {
+				return
+			}
+			if buildErr = buildCtx.Err(); buildErr != nil {
+				return
+			}
+			switch {
+			case len(cfg.EntryPoints) > 0:
+				entries, buildErr = matchingEntryPoints(prog, cfg.EntryPoints)
+			case cfg.Library:
+				entries, buildErr = libraryEntryPoints(ssaPkgs)
+			default:
+				entries = entryPoints(ssaPkgs, !cfg.SkipInit)
+			}
+			if buildErr == nil {
+				start := time.Now()
+				cg, buildErr = graph.callGraphCached(buildCtx, prog, entries, cfg.CallGraph)
+				if !guard.check() && buildErr == nil {
+					logger.Debug("built call graph", "algorithm", cfg.CallGraph, "entries", len(entries), "functions", len(cg.Nodes), "duration", time.Since(start))
+					progress.Complete()
+				}
+			}
 		}()
+	} else {
+		guard.stop()
 	}
 
 	if err := handler.SBOM(graph.SBOM()); err != nil {
 		return nil, err
 	}
-
-	if err := handler.Progress(&govulncheck.Progress{Message: fetchingVulnsMessage}); err != nil {
-		return nil, err
+	if cgo, libs := graph.cgoLibraries(); cgo {
+		if err := handler.Progress(&govulncheck.Progress{Message: cgoWarning(libs)}); err != nil {
+			return nil, err
+		}
 	}
 
-	mv, err := FetchVulnerabilities(ctx, client, graph.Modules())
+	mv, err := fetchVulnerabilities(ctx, handler, client, graph.Modules())
 	if err != nil {
 		return nil, err
 	}
 
-	// Emit OSV entries immediately in their raw unfiltered form.
-	if err := emitOSVs(handler, mv); err != nil {
+	if err := handler.Progress(&govulncheck.Progress{Message: checkingSrcVulnsMessage}); err != nil {
 		return nil, err
 	}
 
-	if err := handler.Progress(&govulncheck.Progress{Message: checkingSrcVulnsMessage}); err != nil {
+	if err := graph.markUnknownVersions(handler, mv); err != nil {
 		return nil, err
 	}
-
-	affVulns := affectingVulnerabilities(mv, "", "")
-	if err := emitModuleFindings(handler, affVulns); err != nil {
+	affVulns := affectingVulnerabilities(mv, cfg.GOOS, cfg.GOARCH)
+	var depths map[string]int
+	modVulns := affVulns
+	if cfg.MaxDepth > 0 {
+		depths = graph.moduleDepths()
+		modVulns = withinDepth(affVulns, depths, cfg.MaxDepth)
+	}
+	if err := emitModuleFindings(handler, modVulns); err != nil {
 		return nil, err
 	}
 
@@ -89,9 +166,15 @@ func source(ctx context.Context, handler govulncheck.Handler, cfg *govulncheck.C
 	}
 
 	impVulns := importedVulnPackages(affVulns, graph)
+	pkgVulns := impVulns
+	if cfg.MaxDepth > 0 {
+		// Vulnerable symbols called from the main module are reported
+		// regardless of depth, so only package findings are restricted.
+		pkgVulns = packagesWithinDepth(impVulns, depths, cfg.MaxDepth)
+	}
 	// Emit information on imported vulnerable packages now as
 	// call graph computation might take a while.
-	if err := emitPackageFindings(handler, impVulns); err != nil {
+	if err := emitPackageFindings(handler, pkgVulns); err != nil {
 		return nil, err
 	}
 
@@ -101,12 +184,49 @@ func source(ctx context.Context, handler govulncheck.Handler, cfg *govulncheck.C
 		return &Result{Vulns: impVulns}, nil
 	}
 
-	wg.Wait() // wait for build to finish
+	// Wait for the build to finish, unless ctx is done first.
+	built := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(built)
+	}()
+	select {
+	case <-built:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if guard.exceeded.Load() {
+		return &Result{Vulns: impVulns}, &MemoryLimitError{Limit: cfg.MaxMemory}
+	}
 	if buildErr != nil {
-		return nil, err
+		return nil, buildErr
 	}
 
-	entryFuncs, callVulns := calledVulnSymbols(entries, affVulns, cg, graph)
+	if cfg.Reflection {
+		addReflectionEdges(prog, cg, affVulns, graph)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	constraints := fileConstraints(graph)
+	if cfg.StrictPlatform {
+		goos, goarch := targetPlatform(cfg.GOOS, cfg.GOARCH)
+		removeUnsatisfiedCalls(cg, constraints, goos, goarch)
+	}
+	start := time.Now()
+	entryFuncs, callVulns := calledVulnSymbols(entries, affVulns, cg, graph, workers(cfg))
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	govulncheck.Logger(ctx).Debug("found vulnerable symbols called", "imported_vulns", len(impVulns), "called", len(callVulns), "duration", time.Since(start))
+	annotateConstraints(callVulns, constraints)
+	if cfg.Taint {
+		markTainted(cg, callVulns, graph)
+	}
+	if cfg.Library {
+		markExposure(entryFuncs, callVulns)
+	}
+	callVulns = append(callVulns, uncalled(usedVulnSymbols(affVulns, graph, workers(cfg)), callVulns)...)
 	return &Result{EntryFunctions: entryFuncs, Vulns: callVulns}, nil
 }
 
@@ -148,15 +268,20 @@ func importedVulnPackages(affVulns affectingVulns, graph *PackageGraph) []*Vuln
 // A slice of call graph is computed related to the reachable vulnerabilities. Each
 // reachable Vuln has attached FuncNode that can be upward traversed to the entry points.
 // Entry points that reach the vulnerable symbols are also returned.
-func calledVulnSymbols(sources []*ssa.Function, affVulns affectingVulns, cg *callgraph.Graph, graph *PackageGraph) ([]*FuncNode, []*Vuln) {
-	sinksWithVulns := vulnFuncs(cg, affVulns, graph)
+func calledVulnSymbols(sources []*ssa.Function, affVulns affectingVulns, cg *callgraph.Graph, graph *PackageGraph, workers int) ([]*FuncNode, []*Vuln) {
+	sinksWithVulns := vulnFuncs(cg, affVulns, graph, workers)
+	links := vulnLinks(cg, affVulns, graph)
 
 	// Compute call graph backwards reachable
-	// from vulnerable functions and methods.
+	// from vulnerable functions and methods,
+	// and from the functions linked to them.
 	var sinks []*callgraph.Node
 	for n := range sinksWithVulns {
 		sinks = append(sinks, n)
 	}
+	for n := range links {
+		sinks = append(sinks, n)
+	}
 	bcg := callGraphSlice(sinks, false)
 
 	// Interesect backwards call graph with forward
@@ -176,10 +301,16 @@ func calledVulnSymbols(sources []*ssa.Function, affVulns affectingVulns, cg *cal
 			filteredSinks[fn] = vs
 		}
 	}
+	filteredLinks := make(map[*callgraph.Node][]*link)
+	for n, ls := range links {
+		if fn, ok := fcg.Nodes[n.Func]; ok {
+			filteredLinks[fn] = ls
+		}
+	}
 
 	// Transform the resulting call graph slice into
 	// vulncheck representation.
-	return vulnCallGraph(filteredSources, filteredSinks, graph)
+	return vulnCallGraph(filteredSources, filteredSinks, filteredLinks, graph)
 }
 
 // callGraphSlice computes a slice of callgraph beginning at starts
@@ -222,20 +353,21 @@ func callGraphSlice(starts []*callgraph.Node, forward bool) *callgraph.Graph {
 }
 
 // vulnCallGraph creates vulnerability call graph in terms of sources and sinks.
-func vulnCallGraph(sources []*callgraph.Node, sinks map[*callgraph.Node][]*osv.Entry, graph *PackageGraph) ([]*FuncNode, []*Vuln) {
+func vulnCallGraph(sources []*callgraph.Node, sinks map[*callgraph.Node][]*osv.Entry, links map[*callgraph.Node][]*link, graph *PackageGraph) ([]*FuncNode, []*Vuln) {
 	var entries []*FuncNode
 	var vulns []*Vuln
 	nodes := make(map[*ssa.Function]*FuncNode)
+	in := newInterner()
 
 	// First create entries and sinks and store relevant information.
 	for _, s := range sources {
-		fn := createNode(nodes, s.Func, graph)
+		fn := createNode(nodes, s.Func, graph, in)
 		entries = append(entries, fn)
 	}
 
 	for s, osvs := range sinks {
 		f := s.Func
-		funNode := createNode(nodes, s.Func, graph)
+		funNode := createNode(nodes, s.Func, graph, in)
 
 		// Populate CallSink field for each detected vuln symbol.
 		for _, osv := range osvs {
@@ -243,6 +375,20 @@ func vulnCallGraph(sources []*callgraph.Node, sinks map[*callgraph.Node][]*osv.E
 		}
 	}
 
+	// Linked functions are called by the
+	// functions declaring or referencing them.
+	for s, ls := range links {
+		from := createNode(nodes, s.Func, graph, in)
+		for _, l := range ls {
+			funNode := l.funcNode(from, graph)
+			for _, osv := range l.osvs {
+				v := calledVuln(funNode, osv, l.dbName(), funNode.Package)
+				v.Linkage = l.linkage
+				vulns = append(vulns, v)
+			}
+		}
+	}
+
 	visited := make(map[*callgraph.Node]bool)
 	var visit func(*callgraph.Node)
 	visit = func(n *callgraph.Node) {
@@ -252,16 +398,21 @@ func vulnCallGraph(sources []*callgraph.Node, sinks map[*callgraph.Node][]*osv.E
 		visited[n] = true
 
 		for _, edge := range n.In {
-			nCallee := createNode(nodes, edge.Callee.Func, graph)
-			nCaller := createNode(nodes, edge.Caller.Func, graph)
+			nCallee := createNode(nodes, edge.Callee.Func, graph, in)
+			nCaller := createNode(nodes, edge.Caller.Func, graph, in)
 
 			call := edge.Site
-			cs := &CallSite{
+			cs := in.callSite()
+			*cs = CallSite{
 				Parent:   nCaller,
-				Name:     call.Common().Value.Name(),
-				RecvType: callRecvType(call),
+				Name:     in.string(call.Common().Value.Name()),
+				RecvType: in.string(callRecvType(call)),
 				Resolved: resolved(call),
-				Pos:      instrPosition(call),
+				Pos:      in.position(call.Parent().Prog.Fset, call.Pos()),
+			}
+			if reflectiveEdge(edge) {
+				cs.Resolved = false
+				cs.Reflective = true
 			}
 			nCallee.CallSites = append(nCallee.CallSites, cs)
 
@@ -272,31 +423,48 @@ func vulnCallGraph(sources []*callgraph.Node, sinks map[*callgraph.Node][]*osv.E
 	for s := range sinks {
 		visit(s)
 	}
+	for s := range links {
+		visit(s)
+	}
 	return entries, vulns
 }
 
-// vulnFuncs returns vulnerability information for vulnerable functions in cg.
-func vulnFuncs(cg *callgraph.Graph, affVulns affectingVulns, graph *PackageGraph) map[*callgraph.Node][]*osv.Entry {
+// vulnFuncs returns vulnerability information for vulnerable functions
+// in cg, looked up by the given number of workers.
+func vulnFuncs(cg *callgraph.Graph, affVulns affectingVulns, graph *PackageGraph, workers int) map[*callgraph.Node][]*osv.Entry {
+	var nodes []*callgraph.Node
+	for _, n := range cg.Nodes {
+		nodes = append(nodes, n)
+	}
+	var mu sync.Mutex
 	m := make(map[*callgraph.Node][]*osv.Entry)
-	for f, n := range cg.Nodes {
-		p := pkgPath(f)
-		vulns := affVulns.ForSymbol(pkgModPath(graph.GetPackage(p)), p, dbFuncName(f))
+	parallel(len(nodes), workers, func(i int) {
+		n := nodes[i]
+		p := graph.funcPkgPath(n.Func)
+		vulns := affVulns.ForSymbol(pkgModPath(graph.GetPackage(p)), p, dbFuncName(n.Func))
 		if len(vulns) > 0 {
+			mu.Lock()
 			m[n] = vulns
+			mu.Unlock()
 		}
-	}
+	})
 	return m
 }
 
-func createNode(nodes map[*ssa.Function]*FuncNode, f *ssa.Function, graph *PackageGraph) *FuncNode {
+func createNode(nodes map[*ssa.Function]*FuncNode, f *ssa.Function, graph *PackageGraph, in *interner) *FuncNode {
 	if fn, ok := nodes[f]; ok {
 		return fn
 	}
-	fn := &FuncNode{
-		Name:     f.Name(),
-		Package:  graph.GetPackage(pkgPath(f)),
-		RecvType: funcRecvType(f),
-		Pos:      funcPosition(f),
+	fn := in.funcNode()
+	*fn = FuncNode{
+		Name:     in.string(funcName(f)),
+		TypeArgs: in.strings(funcTypeArgs(f)),
+		Package:  graph.GetPackage(graph.funcPkgPath(f)),
+		RecvType: in.string(funcRecvType(f)),
+		Pos:      in.position(f.Prog.Fset, f.Pos()),
+	}
+	if syntax := f.Syntax(); syntax != nil {
+		fn.End = in.position(f.Prog.Fset, syntax.End())
 	}
 	nodes[f] = fn
 	return fn
@@ -6,8 +6,10 @@ package vulncheck
 
 import (
 	"context"
+	"errors"
 	"path"
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/StevenACoffman/invuln/external/client"
@@ -389,7 +391,7 @@ func TestNoSyntheticNodes(t *testing.T) {
 		t.Fatalf("expected VulnData.Vuln1 as called symbol; got %s", vuln.Symbol)
 	}
 
-	stack := sourceCallstacks(result)[vuln]
+	stack := sourceCallstacks(context.Background(), result, &govulncheck.Config{Workers: 2})[vuln]
 	// We don't want the call stack X -> *VulnData.Vuln1 (wrapper) -> VulnData.Vuln1.
 	// We want X -> VulnData.Vuln1.
 	if len(stack) != 2 {
@@ -519,3 +521,383 @@ func TestIssue57174(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestCallGraphAlgorithms(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import (
+				"golang.org/amod/avuln"
+				"golang.org/bmod/bvuln"
+			)
+
+			type i interface {
+				Vuln1()
+			}
+
+			type benign struct{}
+
+			func (benign) Vuln1() {}
+
+			var (
+				sink any
+				hook = func() {}
+			)
+
+			func X() {
+				var x i = benign{}
+				x.Vuln1() // VulnData.Vuln1 for RTA and CHA
+				sink = avuln.VulnData{}
+				hook() // bvuln.Vuln for CHA
+			}
+
+			func unreachable() {
+				bvuln.Vuln()
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/amod@v1.1.3",
+			Files: map[string]interface{}{"avuln/avuln.go": `
+			package avuln
+
+			type VulnData struct {}
+			func (v VulnData) Vuln1() {}
+			func (v VulnData) Vuln2() {}
+			`},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		algorithm govulncheck.CallGraphAlgorithm
+		want      []string
+	}{
+		{"", nil},
+		{govulncheck.CallGraphVTA, nil},
+		{govulncheck.CallGraphRTA, []string{"VulnData.Vuln1"}},
+		{govulncheck.CallGraphCHA, []string{"Vuln", "VulnData.Vuln1"}},
+	} {
+		t.Run(string(tc.algorithm), func(t *testing.T) {
+			graph := NewPackageGraph("go1.18")
+			err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := &govulncheck.Config{ScanLevel: "symbol", CallGraph: tc.algorithm}
+			result, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range result.Vulns {
+				if v.CallSink != nil {
+					got = append(got, v.Symbol)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("called symbols: got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenericInstances(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import "golang.org/bmod/bvuln"
+
+			func X() {
+				bvuln.Vuln(1)
+				bvuln.Vuln("a")
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln[T any](t T) {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	graph := NewPackageGraph("go1.18")
+	err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := test.NewMockHandler()
+	if err := Source(context.Background(), h, &govulncheck.Config{ScanLevel: "symbol"}, c, graph); err != nil {
+		t.Fatal(err)
+	}
+	var sinks []*govulncheck.Frame
+	for _, f := range h.FindingMessages {
+		if len(f.Trace) > 1 && f.Trace[0].Function != "" {
+			sinks = append(sinks, f.Trace[0])
+		}
+	}
+	if len(sinks) != 1 {
+		t.Fatalf("got %d symbol findings; want 1", len(sinks))
+	}
+	if got := sinks[0]; got.Function != "Vuln" || got.TypeArgs != "[int]" {
+		t.Errorf("got sink %s%s; want Vuln[int]", got.Function, got.TypeArgs)
+	}
+}
+
+// cancelingHandler cancels a scan once the code
+// starts being checked against the vulnerabilities.
+type cancelingHandler struct {
+	*test.MockHandler
+	cancel context.CancelFunc
+}
+
+func (h *cancelingHandler) Progress(p *govulncheck.Progress) error {
+	if p.Message == checkingSrcVulnsMessage {
+		h.cancel()
+	}
+	return h.MockHandler.Progress(p)
+}
+
+func TestSourceCancel(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import "golang.org/bmod/bvuln"
+
+			func X() {
+				bvuln.Vuln()
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	graph := NewPackageGraph("go1.18")
+	err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h := &cancelingHandler{MockHandler: test.NewMockHandler(), cancel: cancel}
+	err = Source(ctx, h, &govulncheck.Config{ScanLevel: "symbol"}, c, graph)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v; want %v", err, context.Canceled)
+	}
+	for _, f := range h.FindingMessages {
+		if f.Trace[0].Function != "" {
+			t.Errorf("got symbol finding %v after the scan was cancelled", f.Trace[0])
+		}
+	}
+
+	// Scans cancelled before they start fail to fetch the vulnerabilities.
+	err = Source(ctx, test.NewMockHandler(), &govulncheck.Config{ScanLevel: "symbol"}, c, graph)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v for a cancelled scan; want %v", err, context.Canceled)
+	}
+}
+
+func TestStackConfidence(t *testing.T) {
+	static := &CallSite{Resolved: true}
+	dynamic := &CallSite{}
+	reflective := &CallSite{Reflective: true}
+	for _, tc := range []struct {
+		stack CallStack
+		want  govulncheck.Confidence
+	}{
+		{CallStack{{}}, govulncheck.ConfidenceCertain},
+		{CallStack{{Call: static}, {Call: static}, {}}, govulncheck.ConfidenceCertain},
+		{CallStack{{Call: static}, {Call: dynamic}, {}}, govulncheck.ConfidenceLikely},
+		{CallStack{{Call: dynamic}, {Call: reflective}, {}}, govulncheck.ConfidencePossible},
+	} {
+		if got := stackConfidence(tc.stack); got != tc.want {
+			t.Errorf("stackConfidence(%v) = %q; want %q", tc.stack, got, tc.want)
+		}
+	}
+}
+
+func TestInitRoots(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import (
+				"golang.org/amod/avuln"
+				"golang.org/bmod/bvuln"
+			)
+
+			var v = vuln()
+
+			func vuln() int {
+				bvuln.Vuln()
+				return 0
+			}
+
+			func init() {
+				avuln.VulnData{}.Vuln1()
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/amod@v1.1.3",
+			Files: map[string]interface{}{"avuln/avuln.go": `
+			package avuln
+
+			type VulnData struct {}
+			func (v VulnData) Vuln1() {}
+			func (v VulnData) Vuln2() {}
+			`},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		skipInit bool
+		want     map[string][]string
+	}{
+		{false, map[string][]string{
+			"golang.org/entry/x.init":   {"golang.org/entry/x.init#1", "golang.org/entry/x.vuln"},
+			"golang.org/entry/x.init#1": {"golang.org/amod/avuln.VulnData.Vuln1"},
+			"golang.org/entry/x.vuln":   {"golang.org/bmod/bvuln.Vuln"},
+		}},
+		{true, map[string][]string{}},
+	} {
+		graph := NewPackageGraph("go1.18")
+		err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := &govulncheck.Config{ScanLevel: "symbol", SkipInit: tc.skipInit}
+		result, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := callGraphToStrMap(result); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("skipInit=%v: got call graph %v; want %v", tc.skipInit, got, tc.want)
+		}
+	}
+}
+
+func TestSourceProgress(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import "golang.org/bmod/bvuln"
+
+			func X() {
+				bvuln.Vuln()
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/bmod@v0.5.0",
+			Files: map[string]interface{}{"bvuln/bvuln.go": `
+			package bvuln
+
+			func Vuln() {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	var events []govulncheck.ProgressEvent
+	ctx := govulncheck.WithProgress(context.Background(), func(ev *govulncheck.ProgressEvent) {
+		events = append(events, *ev)
+	})
+	e.Config.Context = ctx
+	graph := NewPackageGraph("go1.18")
+	if err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true); err != nil {
+		t.Fatal(err)
+	}
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Source(ctx, test.NewMockHandler(), &govulncheck.Config{ScanLevel: "symbol"}, c, graph); err != nil {
+		t.Fatal(err)
+	}
+
+	completed := make(map[govulncheck.Phase]govulncheck.ProgressEvent)
+	for _, ev := range events {
+		if ev.Kind == govulncheck.ProgressCompleted {
+			completed[ev.Phase] = ev
+		}
+	}
+	for _, phase := range []govulncheck.Phase{govulncheck.PhaseLoad, govulncheck.PhaseFetch, govulncheck.PhaseCallGraph, govulncheck.PhaseAnalyze} {
+		if _, ok := completed[phase]; !ok {
+			t.Errorf("phase %s did not complete", phase)
+		}
+	}
+	// Both files of the packages, and the files of the
+	// standard library packages they import, were parsed.
+	if ev := completed[govulncheck.PhaseLoad]; ev.Done < 2 || ev.Done != ev.Total {
+		t.Errorf("got load completion %+v; want at least 2 files", ev)
+	}
+	if ev := completed[govulncheck.PhaseAnalyze]; ev.Total != 1 {
+		t.Errorf("got analysis completion %+v; want 1 vulnerability", ev)
+	}
+}
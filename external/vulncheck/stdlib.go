@@ -0,0 +1,43 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+
+	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"golang.org/x/tools/go/packages"
+)
+
+// Stdlib detects vulnerabilities in the standard library of Go version
+// goVersion and emits module level findings to handler. The goos and
+// goarch of the target platform are used to exclude vulnerabilities
+// affecting other platforms, when they are known.
+func Stdlib(ctx context.Context, handler govulncheck.Handler, goVersion, goos, goarch string, client *client.Client) error {
+	graph := NewPackageGraph(goVersion)
+	mods := []*packages.Module{graph.GetModule(external.GoStdModulePath)}
+
+	sbom := &govulncheck.SBOM{
+		GoVersion: goVersion,
+		Modules:   []*govulncheck.Module{{Path: external.GoStdModulePath, Version: goVersion}},
+		GOOS:      goos,
+		GOARCH:    goarch,
+	}
+	if err := handler.SBOM(sbom); err != nil {
+		return err
+	}
+
+	mv, err := fetchVulnerabilities(ctx, handler, client, mods)
+	if err != nil {
+		return err
+	}
+
+	if err := handler.Progress(&govulncheck.Progress{Message: checkingStdVulnsMessage}); err != nil {
+		return err
+	}
+	return emitModuleFindings(handler, affectingVulnerabilities(mv, goos, goarch))
+}
@@ -0,0 +1,44 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/test"
+)
+
+func TestStdlib(t *testing.T) {
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		goVersion string
+		want      []string
+	}{
+		{"go1.17", nil},
+		{"go1.20", []string{"STD"}},
+	} {
+		h := test.NewMockHandler()
+		if err := Stdlib(context.Background(), h, tt.goVersion, "linux", "amd64", c); err != nil {
+			t.Fatal(err)
+		}
+		if len(h.SBOMMessages) != 1 || len(h.SBOMMessages[0].Modules) != 1 {
+			t.Fatalf("%s: got SBOM %v; want only the standard library", tt.goVersion, h.SBOMMessages)
+		}
+		var got []string
+		for _, f := range h.FindingMessages {
+			got = append(got, f.OSV)
+			if f.Trace[0].Module != "stdlib" || f.Trace[0].Package != "" {
+				t.Errorf("%s: got finding %v; want module level standard library finding", tt.goVersion, f.Trace[0])
+			}
+		}
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("%s: got findings for %v; want %v", tt.goVersion, got, tt.want)
+		}
+	}
+}
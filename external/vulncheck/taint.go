@@ -0,0 +1,341 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// taint is a set of sources of untrusted data.
+type taint uint8
+
+const (
+	taintNetwork taint = 1 << iota
+	taintFile
+	taintEnv
+)
+
+// sources returns the sources in t.
+func (t taint) sources() []govulncheck.TaintSource {
+	var ss []govulncheck.TaintSource
+	if t&taintNetwork != 0 {
+		ss = append(ss, govulncheck.TaintSourceNetwork)
+	}
+	if t&taintFile != 0 {
+		ss = append(ss, govulncheck.TaintSourceFile)
+	}
+	if t&taintEnv != 0 {
+		ss = append(ss, govulncheck.TaintSourceEnv)
+	}
+	return ss
+}
+
+// taintFuncs are the functions whose results hold untrusted
+// data, by package path and database name.
+var taintFuncs = map[string]map[string]taint{
+	"os": {
+		"Getenv":    taintEnv,
+		"LookupEnv": taintEnv,
+		"Environ":   taintEnv,
+		"ReadFile":  taintFile,
+		"Open":      taintFile,
+		"OpenFile":  taintFile,
+	},
+	"io/ioutil": {
+		"ReadFile": taintFile,
+	},
+	"net": {
+		"Dial":               taintNetwork,
+		"DialTimeout":        taintNetwork,
+		"Dialer.Dial":        taintNetwork,
+		"Dialer.DialContext": taintNetwork,
+		"TCPListener.Accept": taintNetwork,
+	},
+	"net/http": {
+		"Get":         taintNetwork,
+		"Post":        taintNetwork,
+		"PostForm":    taintNetwork,
+		"Head":        taintNetwork,
+		"Client.Do":   taintNetwork,
+		"Client.Get":  taintNetwork,
+		"Client.Post": taintNetwork,
+	},
+}
+
+// taintGlobals are the package-level variables holding
+// untrusted data, by package path and name.
+var taintGlobals = map[string]map[string]taint{
+	"os": {
+		"Args":  taintEnv,
+		"Stdin": taintFile,
+	},
+}
+
+// taintParams are the types of parameters holding untrusted
+// data, such as those of HTTP handlers, by package path and name.
+var taintParams = map[string]map[string]taint{
+	"net/http": {
+		"Request": taintNetwork,
+	},
+	"net": {
+		"Conn": taintNetwork,
+	},
+}
+
+// markTainted sets the Taint of the called vulnerabilities in vulns
+// whose OSV entries describe flaws triggered by crafted inputs, to the
+// sources of untrusted data that can flow into the arguments of their
+// calls in cg.
+//
+// The analysis is flow and field insensitive. The results of calls
+// with tainted arguments are considered tainted, as are the pointer
+// arguments of such calls, which may be filled in with tainted data,
+// as with io.ReadFull.
+func markTainted(cg *callgraph.Graph, vulns []*Vuln, graph *PackageGraph) {
+	want := make(map[taintKey]bool)
+	for _, v := range vulns {
+		if v.CallSink != nil && inputDependent(v.OSV) {
+			want[taintKey{v.Package.PkgPath, v.Symbol}] = true
+		}
+	}
+	if len(want) == 0 {
+		return
+	}
+
+	t := &tainter{cg: cg, values: make(map[ssa.Value]taint)}
+	t.run()
+
+	sinks := make(map[taintKey]taint)
+	for f, n := range cg.Nodes {
+		k := taintKey{graph.funcPkgPath(f), dbFuncName(f)}
+		if !want[k] {
+			continue
+		}
+		for _, e := range n.In {
+			if e.Site != nil {
+				sinks[k] |= t.args(e.Site.Common())
+			}
+		}
+	}
+	for _, v := range vulns {
+		if v.CallSink != nil && inputDependent(v.OSV) {
+			v.Taint = sinks[taintKey{v.Package.PkgPath, v.Symbol}].sources()
+		}
+	}
+}
+
+type taintKey struct {
+	pkg, symbol string
+}
+
+// inputDependent reports whether e describes a flaw triggered by
+// crafted or untrusted inputs, such as in parsers and decompressors.
+func inputDependent(e *osv.Entry) bool {
+	// Without its details, e is classified by its summary alone.
+	_ = e.Load()
+	text := strings.ToLower(e.Summary + " " + e.Details)
+	for _, w := range []string{"pars", "decod", "decompress", "unmarshal", "crafted", "untrusted", "malicious", "input"} {
+		if strings.Contains(text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// tainter computes the tainted values of the functions of cg.
+type tainter struct {
+	cg      *callgraph.Graph
+	values  map[ssa.Value]taint
+	changed bool
+}
+
+// run propagates taint until a fixed point is reached.
+func (t *tainter) run() {
+	var funcs []*ssa.Function
+	for f := range t.cg.Nodes {
+		if f != nil && f.Blocks != nil {
+			funcs = append(funcs, f)
+		}
+	}
+	// Sort for determinism of the propagation order.
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].String() < funcs[j].String() })
+
+	for _, f := range funcs {
+		for _, p := range f.Params {
+			t.add(p, paramTaint(p.Type()))
+		}
+	}
+	for t.changed = true; t.changed; {
+		t.changed = false
+		for _, f := range funcs {
+			t.function(f)
+		}
+	}
+}
+
+func (t *tainter) add(v ssa.Value, tt taint) {
+	if v == nil || tt == 0 || t.values[v]&tt == tt {
+		return
+	}
+	t.values[v] |= tt
+	t.changed = true
+}
+
+// args returns the taint of the arguments, including
+// the receiver, of call.
+func (t *tainter) args(call *ssa.CallCommon) taint {
+	var tt taint
+	if call.IsInvoke() {
+		tt |= t.value(call.Value)
+	}
+	for _, a := range call.Args {
+		tt |= t.value(a)
+	}
+	return tt
+}
+
+// value returns the taint of v.
+func (t *tainter) value(v ssa.Value) taint {
+	if g, ok := v.(*ssa.Global); ok && g.Pkg != nil {
+		return taintGlobals[g.Pkg.Pkg.Path()][g.Name()]
+	}
+	return t.values[v]
+}
+
+// function propagates the taint of the instructions of f.
+func (t *tainter) function(f *ssa.Function) {
+	node := t.cg.Nodes[f]
+	for _, b := range f.Blocks {
+		for _, instr := range b.Instrs {
+			switch instr := instr.(type) {
+			case ssa.CallInstruction:
+				t.call(node, instr)
+			case *ssa.Store:
+				if tt := t.value(instr.Val); tt != 0 {
+					t.addr(instr.Addr, tt)
+				}
+			case *ssa.MapUpdate:
+				t.addr(instr.Map, t.value(instr.Key)|t.value(instr.Value))
+			case *ssa.Send:
+				t.add(instr.Chan, t.value(instr.X))
+			case *ssa.Return:
+				var tt taint
+				for _, r := range instr.Results {
+					tt |= t.value(r)
+				}
+				if tt != 0 && node != nil {
+					for _, e := range node.In {
+						if e.Site != nil && e.Site.Value() != nil {
+							t.add(e.Site.Value(), tt)
+						}
+					}
+				}
+			default:
+				if v, ok := instr.(ssa.Value); ok {
+					var tt taint
+					for _, op := range instr.Operands(nil) {
+						if *op != nil {
+							tt |= t.value(*op)
+						}
+					}
+					t.add(v, tt)
+				}
+			}
+		}
+	}
+}
+
+// call propagates the taint of the arguments of the call instr in the
+// function of node to the parameters of its callees, to its result
+// and to its pointer arguments, and marks the results of calls of
+// taintFuncs as tainted.
+func (t *tainter) call(node *callgraph.Node, instr ssa.CallInstruction) {
+	common := instr.Common()
+	tt := t.args(common)
+	if callee := common.StaticCallee(); callee != nil && callee.Pkg != nil {
+		tt |= taintFuncs[callee.Pkg.Pkg.Path()][dbFuncName(callee)]
+	}
+	if tt == 0 {
+		return
+	}
+	if v := instr.Value(); v != nil {
+		t.add(v, tt)
+	}
+	for _, a := range common.Args {
+		if isPointerLike(a.Type()) {
+			t.addr(a, tt)
+		}
+	}
+	if node == nil {
+		return
+	}
+	for _, e := range node.Out {
+		if e.Site != instr || e.Callee.Func == nil {
+			continue
+		}
+		params := e.Callee.Func.Params
+		args := common.Args
+		if common.IsInvoke() && len(params) > 0 {
+			t.add(params[0], t.value(common.Value))
+			params = params[1:]
+		}
+		for i := range min(len(params), len(args)) {
+			t.add(params[i], t.value(args[i]))
+		}
+	}
+}
+
+// addr taints the value at address v and the
+// values v is derived from, such as its struct.
+func (t *tainter) addr(v ssa.Value, tt taint) {
+	for v != nil && tt != 0 {
+		t.add(v, tt)
+		switch x := v.(type) {
+		case *ssa.FieldAddr:
+			v = x.X
+		case *ssa.IndexAddr:
+			v = x.X
+		case *ssa.Slice:
+			v = x.X
+		case *ssa.ChangeType:
+			v = x.X
+		case *ssa.Convert:
+			v = x.X
+		case *ssa.MakeInterface:
+			v = x.X
+		default:
+			return
+		}
+	}
+}
+
+// paramTaint returns the taint of parameters of type typ.
+func paramTaint(typ types.Type) taint {
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	named, ok := types.Unalias(typ).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return 0
+	}
+	return taintParams[named.Obj().Pkg().Path()][named.Obj().Name()]
+}
+
+// isPointerLike reports whether values of type typ
+// can be used to change the data they refer to.
+func isPointerLike(typ types.Type) bool {
+	switch typ.Underlying().(type) {
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Interface:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/test"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestTaint(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import (
+				"io"
+				"net/http"
+				"os"
+
+				"golang.org/pmod/parse"
+			)
+
+			func Env() {
+				name := os.Getenv("NAME")
+				parse.String(name + ".txt")
+			}
+
+			func Handle(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				parse.Bytes(b[1:])
+			}
+
+			func Const() {
+				parse.Trusted("const")
+			}`,
+			},
+		},
+		{
+			Name: "golang.org/pmod@v1.0.0",
+			Files: map[string]interface{}{"parse/parse.go": `
+			package parse
+
+			func String(s string) {}
+			func Bytes(b []byte) {}
+			func Trusted(s string) {}
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	affected := func(symbols ...string) []osv.Affected {
+		return []osv.Affected{{
+			Module: osv.Module{Path: "golang.org/pmod"},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver}},
+			EcosystemSpecific: osv.EcosystemSpecific{Packages: []osv.Package{{
+				Path:    "golang.org/pmod/parse",
+				Symbols: symbols,
+			}}},
+		}}
+	}
+	c, err := client.NewInMemoryClient([]*osv.Entry{
+		{ID: "VP", Details: "Parsing crafted input may panic.", Affected: affected("String", "Bytes", "Trusted")},
+		{ID: "VT", Details: "Timing side channel.", Affected: affected("String")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph := NewPackageGraph("go1.18")
+	if err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &govulncheck.Config{ScanLevel: "symbol", Taint: true}
+	result, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]govulncheck.TaintSource)
+	for _, v := range result.Vulns {
+		if v.CallSink != nil {
+			got[v.OSV.ID+" "+v.Symbol] = v.Taint
+		}
+	}
+	want := map[string][]govulncheck.TaintSource{
+		"VP String":  {govulncheck.TaintSourceEnv},
+		"VP Bytes":   {govulncheck.TaintSourceNetwork},
+		"VP Trusted": nil,
+		"VT String":  nil,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got taint %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"bytes"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"sync"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"golang.org/x/tools/go/packages"
+)
+
+// usedVulnSymbols detects the uses of vulnerable types, variables,
+// constants, and struct fields in the code of graph's packages. These
+// are only reported if their OSV entries list them explicitly, as
+// opposed to all symbols of a package being vulnerable.
+//
+// It also detects the references to vulnerable functions and methods
+// other than calls, such as a function assigned to a variable or passed
+// as a callback, which the call graph may not show to be called.
+//
+// A Vuln is returned for each used symbol and OSV entry, with the
+// first use in the top-level packages, if any, or in their imports.
+// The packages are inspected by the given number of workers.
+func usedVulnSymbols(affVulns affectingVulns, graph *PackageGraph, workers int) []*Vuln {
+	u := &useFinder{
+		affVulns: affVulns,
+		graph:    graph,
+		vulns:    make(map[useKey]*Vuln),
+		top:      make(map[*packages.Package]bool),
+	}
+	for _, p := range graph.TopPkgs() {
+		u.top[p] = true
+	}
+	var pkgs []*packages.Package
+	seen := make(map[*packages.Package]bool)
+	var visit func(*packages.Package)
+	visit = func(p *packages.Package) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		pkgs = append(pkgs, p)
+		for _, imp := range p.Imports {
+			visit(imp)
+		}
+	}
+	for _, p := range graph.TopPkgs() {
+		visit(p)
+	}
+	parallel(len(pkgs), workers, func(i int) { u.pkg(pkgs[i]) })
+
+	var vulns []*Vuln
+	for _, v := range u.vulns {
+		vulns = append(vulns, v)
+	}
+	sort.Slice(vulns, func(i, j int) bool {
+		if vulns[i].OSV.ID != vulns[j].OSV.ID {
+			return vulns[i].OSV.ID < vulns[j].OSV.ID
+		}
+		return vulns[i].Symbol < vulns[j].Symbol
+	})
+	return vulns
+}
+
+type useKey struct {
+	osv, pkg, symbol string
+}
+
+// useFinder finds the uses of vulnerable symbols.
+type useFinder struct {
+	affVulns affectingVulns
+	graph    *PackageGraph
+	top      map[*packages.Package]bool // top-level packages
+
+	mu    sync.Mutex // guards vulns
+	vulns map[useKey]*Vuln
+}
+
+// pkg records the uses of vulnerable symbols in p.
+func (u *useFinder) pkg(p *packages.Package) {
+	if p.TypesInfo == nil {
+		return
+	}
+	for _, f := range p.Syntax {
+		for _, decl := range f.Decls {
+			parent := u.parent(p, decl)
+			callees := make(map[*ast.Ident]bool)
+			ast.Inspect(decl, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.CallExpr:
+					if id := calleeIdent(n.Fun); id != nil {
+						callees[id] = true
+					}
+				case *ast.SelectorExpr:
+					if sel := p.TypesInfo.Selections[n]; sel != nil && sel.Kind() == types.FieldVal {
+						u.field(p, parent, sel.Recv(), sel.Obj(), n.Sel)
+					}
+				case *ast.CompositeLit:
+					for _, elt := range n.Elts {
+						if kv, ok := elt.(*ast.KeyValueExpr); ok {
+							if key, ok := kv.Key.(*ast.Ident); ok {
+								u.field(p, parent, p.TypesInfo.TypeOf(n), p.TypesInfo.Uses[key], key)
+							}
+						}
+					}
+				case *ast.Ident:
+					u.ident(p, parent, n, callees[n])
+				}
+				return true
+			})
+		}
+	}
+}
+
+// parent returns the function the uses in decl are attributed to.
+func (u *useFinder) parent(p *packages.Package, decl ast.Decl) *FuncNode {
+	fn := &FuncNode{Name: "init", Package: p}
+	if fd, ok := decl.(*ast.FuncDecl); ok {
+		fn.Name = fd.Name.Name
+		pos := p.Fset.Position(fd.Pos())
+		fn.Pos = &pos
+		if obj, ok := p.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+			if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+				buf := new(bytes.Buffer)
+				types.WriteType(buf, recv.Type(), nil)
+				fn.RecvType = buf.String()
+			}
+		}
+	}
+	return fn
+}
+
+// calleeIdent returns the identifier naming the function called by fun.
+func calleeIdent(fun ast.Expr) *ast.Ident {
+	switch fun := ast.Unparen(fun).(type) {
+	case *ast.Ident:
+		return fun
+	case *ast.SelectorExpr:
+		return fun.Sel
+	case *ast.IndexExpr:
+		return calleeIdent(fun.X)
+	case *ast.IndexListExpr:
+		return calleeIdent(fun.X)
+	}
+	return nil
+}
+
+// ident records the use by id of a package-level type, variable,
+// or constant, or the reference by id to a function or method unless
+// it is called, if vulnerable.
+func (u *useFinder) ident(p *packages.Package, parent *FuncNode, id *ast.Ident, called bool) {
+	obj := p.TypesInfo.Uses[id]
+	if obj == nil || obj.Pkg() == nil || obj.Pkg() == p.Types {
+		return
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		if symbol := funcSymbol(fn); symbol != "" && !called {
+			u.record(p, parent, obj.Pkg().Path(), symbol, govulncheck.SymbolKindFunc, id.Pos())
+		}
+		return
+	}
+	if obj.Parent() != obj.Pkg().Scope() {
+		return
+	}
+	var kind govulncheck.SymbolKind
+	switch obj.(type) {
+	case *types.TypeName:
+		kind = govulncheck.SymbolKindType
+	case *types.Var:
+		kind = govulncheck.SymbolKindVar
+	case *types.Const:
+		kind = govulncheck.SymbolKindConst
+	default:
+		return
+	}
+	u.record(p, parent, obj.Pkg().Path(), obj.Name(), kind, id.Pos())
+}
+
+// funcSymbol returns the database name of function or method fn, or
+// "" if fn is an interface method.
+func funcSymbol(fn *types.Func) string {
+	fn = fn.Origin()
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return fn.Name()
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || types.IsInterface(named) {
+		return ""
+	}
+	return named.Obj().Name() + "." + fn.Name()
+}
+
+// field records the use by id of field obj of a value of type recv,
+// if vulnerable. Fields are named by their struct type, as in T.F.
+func (u *useFinder) field(p *packages.Package, parent *FuncNode, recv types.Type, obj types.Object, id *ast.Ident) {
+	if recv == nil || obj == nil || obj.Pkg() == nil || obj.Pkg() == p.Types {
+		return
+	}
+	if ptr, ok := recv.(*types.Pointer); ok {
+		recv = ptr.Elem()
+	}
+	named, ok := types.Unalias(recv).(*types.Named)
+	if !ok || named.Obj().Pkg() != obj.Pkg() {
+		return
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return
+	}
+	for i := range st.NumFields() {
+		if st.Field(i) == obj {
+			symbol := named.Obj().Name() + "." + obj.Name()
+			u.record(p, parent, obj.Pkg().Path(), symbol, govulncheck.SymbolKindField, id.Pos())
+			return
+		}
+	}
+}
+
+// record records the use at pos in p of symbol of the package at
+// path if it is listed as vulnerable, unless a use in a top-level
+// package or at an earlier position was already recorded.
+func (u *useFinder) record(p *packages.Package, parent *FuncNode, path, symbol string, kind govulncheck.SymbolKind, pos token.Pos) {
+	vp := u.graph.GetPackage(path)
+	if vp == nil {
+		return
+	}
+	position := p.Fset.Position(pos)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, e := range u.affVulns.ForSymbol(pkgModPath(vp), path, symbol) {
+		// Functions are matched as in calls.
+		if kind != govulncheck.SymbolKindFunc && !listsSymbol(e, path, symbol) {
+			continue
+		}
+		key := useKey{e.ID, path, symbol}
+		if v, ok := u.vulns[key]; ok && !u.better(p, position, v.Use) {
+			continue
+		}
+		u.vulns[key] = &Vuln{
+			OSV:     e,
+			Symbol:  symbol,
+			Package: vp,
+			Use: &Use{
+				Kind:   kind,
+				Parent: parent,
+				Pos:    &position,
+			},
+		}
+	}
+}
+
+// better reports whether a use at pos in p is a better
+// example than use: uses in top-level packages come first,
+// then uses are ordered by position.
+func (u *useFinder) better(p *packages.Package, pos token.Position, use *Use) bool {
+	if top, otop := u.top[p], u.top[use.Parent.Package]; top != otop {
+		return top
+	}
+	return posLess(pos, *use.Pos)
+}
+
+// listsSymbol reports whether e lists symbol
+// as vulnerable in the package at path.
+func listsSymbol(e *osv.Entry, path, symbol string) bool {
+	for _, a := range e.Affected {
+		for _, p := range a.EcosystemSpecific.Packages {
+			if p.Path == path && contains(p.Symbols, symbol) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// uncalled returns the vulns in uses other than the
+// references to functions also found to be called in calls.
+func uncalled(uses, calls []*Vuln) []*Vuln {
+	called := make(map[useKey]bool)
+	for _, v := range calls {
+		if v.CallSink != nil {
+			called[useKey{v.OSV.ID, v.Package.PkgPath, v.Symbol}] = true
+		}
+	}
+	var vulns []*Vuln
+	for _, v := range uses {
+		if v.Use.Kind != govulncheck.SymbolKindFunc || !called[useKey{v.OSV.ID, v.Package.PkgPath, v.Symbol}] {
+			vulns = append(vulns, v)
+		}
+	}
+	return vulns
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vulncheck
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/test"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/go/packages/packagestest"
+)
+
+func TestUsedVulnSymbols(t *testing.T) {
+	e := packagestest.Export(t, packagestest.Modules, []packagestest.Module{
+		{
+			Name: "golang.org/entry",
+			Files: map[string]interface{}{
+				"x/x.go": `
+			package x
+
+			import "golang.org/cmod/c"
+
+			var limit = c.Limit
+
+			type T struct{}
+
+			func (T) M(cfg *c.Config) bool {
+				return cfg.Insecure
+			}
+
+			func X() error {
+				_ = c.Options{Verbose: true}
+				return c.ErrBad
+			}
+
+			func Y(cfg *c.Config) {
+				run(c.Parse)
+				run(cfg.Check)
+				run(c.Run)
+				c.Run()
+				_ = c.Config(c.Config{})
+			}
+
+			func run(func()) {}`,
+			},
+		},
+		{
+			Name: "golang.org/cmod@v1.0.0",
+			Files: map[string]interface{}{"c/c.go": `
+			package c
+
+			import "errors"
+
+			const Limit = 10
+
+			var ErrBad = errors.New("bad")
+
+			type Config struct {
+				Insecure bool
+				Other    bool
+			}
+
+			type Options struct {
+				Verbose bool
+			}
+
+			func Parse() {}
+
+			func (*Config) Check() {}
+
+			func Run() {}
+
+			func useInternally() bool { return Config{}.Insecure }
+			`},
+		},
+	})
+	defer e.Cleanup()
+
+	c, err := client.NewInMemoryClient([]*osv.Entry{{
+		ID: "VC",
+		Affected: []osv.Affected{{
+			Module: osv.Module{Path: "golang.org/cmod"},
+			Ranges: []osv.Range{{Type: osv.RangeTypeSemver}},
+			EcosystemSpecific: osv.EcosystemSpecific{Packages: []osv.Package{{
+				Path:    "golang.org/cmod/c",
+				Symbols: []string{"Limit", "ErrBad", "Config", "Config.Insecure", "Options.Verbose", "Unused", "Parse", "Config.Check", "Run"},
+			}}},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph := NewPackageGraph("go1.18")
+	if err := graph.LoadPackagesAndMods(e.Config, nil, []string{path.Join(e.Temp(), "entry/x")}, true); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &govulncheck.Config{ScanLevel: "symbol"}
+	result, err := source(context.Background(), test.NewMockHandler(), cfg, c, graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, v := range result.Vulns {
+		if v.Use == nil {
+			continue
+		}
+		got = append(got, fmt.Sprintf("%s %s %s used in %s at %s:%d",
+			v.OSV.ID, v.Use.Kind, v.Symbol, v.Use.Parent, filepath.Base(v.Use.Pos.Filename), v.Use.Pos.Line))
+	}
+	want := []string{
+		"VC type Config used in golang.org/entry/x.T.M at x.go:10",
+		"VC func Config.Check used in golang.org/entry/x.Y at x.go:21",
+		"VC field Config.Insecure used in golang.org/entry/x.T.M at x.go:11",
+		"VC var ErrBad used in golang.org/entry/x.X at x.go:16",
+		"VC const Limit used in golang.org/entry/x.init at x.go:6",
+		"VC field Options.Verbose used in golang.org/entry/x.X at x.go:15",
+		"VC func Parse used in golang.org/entry/x.Y at x.go:20",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want, +got):\n%s", diff)
+	}
+}
@@ -9,14 +9,19 @@ import (
 	"context"
 	"go/token"
 	"go/types"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
 	"github.com/StevenACoffman/invuln/external/osv"
 	"github.com/StevenACoffman/invuln/external/semver"
 	"golang.org/x/tools/go/callgraph"
 	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
 	"golang.org/x/tools/go/callgraph/vta"
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/types/typeutil"
@@ -26,8 +31,9 @@ import (
 
 // buildSSA creates an ssa representation for pkgs. Returns
 // the ssa program encapsulating the packages and top level
-// ssa packages corresponding to pkgs.
-func buildSSA(pkgs []*packages.Package, fset *token.FileSet) (*ssa.Program, []*ssa.Package) {
+// ssa packages corresponding to pkgs. If workers is positive,
+// at most workers packages are built at once.
+func buildSSA(pkgs []*packages.Package, fset *token.FileSet, workers int) (*ssa.Program, []*ssa.Package) {
 	prog := ssa.NewProgram(fset, ssa.InstantiateGenerics)
 
 	imports := make(map[*packages.Package]*ssa.Package)
@@ -35,6 +41,13 @@ func buildSSA(pkgs []*packages.Package, fset *token.FileSet) (*ssa.Program, []*s
 	createImports = func(pkgs map[string]*packages.Package) {
 		for _, p := range pkgs {
 			if _, ok := imports[p]; !ok {
+				if p.Types == nil {
+					// Loaded from the export data of its importers
+					// only, as by LoadExportPackages; see below.
+					imports[p] = nil
+					createImports(p.Imports)
+					continue
+				}
 				i := prog.CreatePackage(p.Types, p.Syntax, p.TypesInfo, true)
 				imports[p] = i
 				createImports(p.Imports)
@@ -45,22 +58,59 @@ func buildSSA(pkgs []*packages.Package, fset *token.FileSet) (*ssa.Program, []*s
 	for _, tp := range pkgs {
 		createImports(tp.Imports)
 	}
+	// Packages loaded from export data refer to the types of packages
+	// not loaded otherwise, which need an SSA package nonetheless.
+	var createTypes func([]*types.Package)
+	createTypes = func(tps []*types.Package) {
+		for _, tp := range tps {
+			if prog.Package(tp) == nil {
+				prog.CreatePackage(tp, nil, nil, true)
+				createTypes(tp.Imports())
+			}
+		}
+	}
+	for p, sp := range imports {
+		if sp != nil && len(p.Syntax) == 0 {
+			createTypes(p.Types.Imports())
+		}
+	}
 
 	var ssaPkgs []*ssa.Package
 	for _, tp := range pkgs {
-		if sp, ok := imports[tp]; ok {
+		if sp := imports[tp]; sp != nil {
 			ssaPkgs = append(ssaPkgs, sp)
 		} else {
 			sp := prog.CreatePackage(tp.Types, tp.Syntax, tp.TypesInfo, false)
 			ssaPkgs = append(ssaPkgs, sp)
 		}
 	}
-	prog.Build()
+	if workers > 0 {
+		all := prog.AllPackages()
+		parallel(len(all), workers, func(i int) { all[i].Build() })
+	} else {
+		prog.Build()
+	}
 	return prog, ssaPkgs
 }
 
-// callGraph builds a call graph of prog based on VTA analysis.
-func callGraph(ctx context.Context, prog *ssa.Program, entries []*ssa.Function) (*callgraph.Graph, error) {
+// callGraph builds a call graph of prog using algorithm, which
+// defaults to VTA analysis.
+func callGraph(ctx context.Context, prog *ssa.Program, entries []*ssa.Function, algorithm govulncheck.CallGraphAlgorithm) (*callgraph.Graph, error) {
+	switch algorithm {
+	case govulncheck.CallGraphCHA:
+		cg := cha.CallGraph(prog)
+		cg.DeleteNode(cg.Root) // the root has no function
+		cg.DeleteSyntheticNodes()
+		return cg, nil
+	case govulncheck.CallGraphRTA:
+		res := rta.Analyze(entries, true)
+		if res == nil { // no entries
+			return &callgraph.Graph{Nodes: make(map[*ssa.Function]*callgraph.Node)}, nil
+		}
+		res.CallGraph.DeleteSyntheticNodes()
+		return res.CallGraph, nil
+	}
+
 	entrySlice := make(map[*ssa.Function]bool)
 	for _, e := range entries {
 		entrySlice[e] = true
@@ -161,6 +211,17 @@ func funcName(f *ssa.Function) string {
 	return n
 }
 
+// funcTypeArgs returns the type arguments of f if it is an
+// instance of a generic function or method, qualified by
+// package name.
+func funcTypeArgs(f *ssa.Function) []string {
+	var args []string
+	for _, t := range f.TypeArgs() {
+		args = append(args, types.TypeString(t, func(p *types.Package) string { return p.Name() }))
+	}
+	return args
+}
+
 // memberFuncs returns functions associated with the `member`:
 // 1) `member` itself if `member` is a function
 // 2) `member` methods if `member` is a type
@@ -183,13 +244,6 @@ func memberFuncs(member ssa.Member, prog *ssa.Program) []*ssa.Function {
 	}
 }
 
-// funcPosition gives the position of `f`. Returns empty token.Position
-// if no file information on `f` is available.
-func funcPosition(f *ssa.Function) *token.Position {
-	pos := f.Prog.Fset.Position(f.Pos())
-	return &pos
-}
-
 // instrPosition gives the position of `instr`. Returns empty token.Position
 // if no file information on `instr` is available.
 func instrPosition(instr ssa.Instruction) *token.Position {
@@ -344,3 +398,40 @@ func IsStdPackage(pkg string) bool {
 	}
 	return !strings.Contains(pkg, ".")
 }
+
+// workers returns the number of goroutines to
+// run the symbol level analysis of cfg with.
+func workers(cfg *govulncheck.Config) int {
+	if cfg.Workers > 0 {
+		return cfg.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// witnessWorkers returns the number of goroutines
+// to search for the call stacks of cfg with.
+func witnessWorkers(cfg *govulncheck.Config) int {
+	if cfg.WitnessWorkers > 0 {
+		return cfg.WitnessWorkers
+	}
+	return workers(cfg)
+}
+
+// parallel calls f for each index in [0, n) from at most
+// workers goroutines at once, and waits for the calls to return.
+func parallel(n, workers int, f func(i int)) {
+	var (
+		wg   sync.WaitGroup
+		next atomic.Int64
+	)
+	for range max(1, min(workers, n)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1)) - 1; i < n; i = int(next.Add(1)) - 1 {
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -6,6 +6,7 @@ package vulncheck
 
 import (
 	"path"
+	"sync/atomic"
 	"testing"
 
 	"github.com/StevenACoffman/invuln/external/osv"
@@ -245,7 +246,7 @@ func TestDbSymbolName(t *testing.T) {
 	}
 
 	// test dbFuncName
-	prog, _ := buildSSA(graph.TopPkgs(), graph.TopPkgs()[0].Fset)
+	prog, _ := buildSSA(graph.TopPkgs(), graph.TopPkgs()[0].Fset, 0)
 	got := make(map[string]bool)
 	for f := range ssautil.AllFunctions(prog) {
 		got[dbFuncName(f)] = true
@@ -254,3 +255,16 @@ func TestDbSymbolName(t *testing.T) {
 		t.Errorf("(-want;got+): %s", diff)
 	}
 }
+
+func TestParallel(t *testing.T) {
+	for _, workers := range []int{0, 1, 3, 200} {
+		var calls [100]atomic.Int32
+		parallel(len(calls), workers, func(i int) { calls[i].Add(1) })
+		for i := range calls {
+			if n := calls[i].Load(); n != 1 {
+				t.Errorf("workers=%d: got %d calls for %d; want 1", workers, n, i)
+			}
+		}
+	}
+	parallel(0, 4, func(int) { t.Error("unexpected call") })
+}
@@ -11,15 +11,18 @@ import (
 	"time"
 
 	"github.com/StevenACoffman/invuln/external"
+	"github.com/StevenACoffman/invuln/external/govulncheck"
 	"github.com/StevenACoffman/invuln/external/osv"
 	"github.com/StevenACoffman/invuln/external/semver"
 	"golang.org/x/tools/go/packages"
 )
 
 const (
-	fetchingVulnsMessage    = "Fetching vulnerabilities from the database..."
-	checkingSrcVulnsMessage = "Checking the code against the vulnerabilities..."
-	checkingBinVulnsMessage = "Checking the binary against the vulnerabilities..."
+	fetchingVulnsMessage     = "Fetching vulnerabilities from the database..."
+	checkingSrcVulnsMessage  = "Checking the code against the vulnerabilities..."
+	checkingBinVulnsMessage  = "Checking the binary against the vulnerabilities..."
+	checkingSBOMVulnsMessage = "Checking the SBOM against the vulnerabilities..."
+	checkingStdVulnsMessage  = "Checking the standard library against the vulnerabilities..."
 )
 
 // Result contains information on detected vulnerabilities.
@@ -31,6 +34,11 @@ type Result struct {
 
 	// Vulns contains information on detected vulnerabilities.
 	Vulns []*Vuln
+
+	// CallStacks are the call stacks reported in findings for the
+	// called vulnerabilities, at most one per vulnerability. It is
+	// set by SourceResult at the symbol level.
+	CallStacks map[*Vuln]CallStack
 }
 
 // Vuln provides information on a detected vulnerability. For call
@@ -46,7 +54,8 @@ type Vuln struct {
 	// packages.
 	OSV *osv.Entry
 
-	// Symbol is the name of the detected vulnerable function or method.
+	// Symbol is the name of the detected vulnerable function or method,
+	// or of the used type, variable, constant, or struct field.
 	Symbol string
 
 	// CallSink is the FuncNode corresponding to Symbol.
@@ -55,6 +64,32 @@ type Vuln struct {
 	// is symbol, CallSink will be unavailable and set to nil.
 	CallSink *FuncNode
 
+	// Use is the use of Symbol when it is a type, variable, constant,
+	// or struct field, or when it is a function or method referenced
+	// but not found to be called. It is only available when
+	// cfg.ScanLevel is symbol.
+	Use *Use
+
+	// Taint are the sources of untrusted data that can flow into the
+	// arguments of the calls of Symbol. It is only computed when taint
+	// tracking is enabled, see govulncheck.Config.
+	Taint []govulncheck.TaintSource
+
+	// ExposedBy are the exported functions and methods of the scanned
+	// library from which Symbol can be reached, in the form
+	// package.symbol. It is only computed for library scans, see
+	// govulncheck.Config.
+	ExposedBy []string
+
+	// Linked is set when Symbol is found in the symbol table of a
+	// binary, which is not the case of stripped binaries, whose
+	// vulnerable symbols are all assumed to be present.
+	Linked bool
+
+	// Linkage is set when CallSink is not called by Go code, but
+	// through a //go:linkname directive or from assembly.
+	Linkage govulncheck.Linkage
+
 	// Package of Symbol.
 	//
 	// When the package of symbol is not imported, Package will be
@@ -62,11 +97,32 @@ type Vuln struct {
 	Package *packages.Package
 }
 
+// A Use describes a use of a vulnerable type, variable, constant, or
+// struct field, or a reference to a vulnerable function or method
+// other than a call.
+type Use struct {
+	// Kind is the kind of the used symbol.
+	Kind govulncheck.SymbolKind
+
+	// Parent is the function in which the symbol is used. Uses in
+	// package-level declarations are attributed to a function named
+	// init, with no position.
+	Parent *FuncNode
+
+	// Pos is the position of the use.
+	Pos *token.Position
+}
+
 // A FuncNode describes a function in the call graph.
 type FuncNode struct {
-	// Name is the name of the function.
+	// Name is the name of the function. For instances of generic
+	// functions and methods, it is the name of the generic one.
 	Name string
 
+	// TypeArgs are the type arguments of the instance of a generic
+	// function, or of the receiver type of a method, if any.
+	TypeArgs []string
+
 	// RecvType is the receiver object type of this function, if any.
 	RecvType string
 
@@ -76,8 +132,15 @@ type FuncNode struct {
 	// Position describes the position of the function in the file.
 	Pos *token.Position
 
+	// End is the position of the end of the function, if known.
+	End *token.Position
+
 	// CallSites is a set of call sites where this function is called.
 	CallSites []*CallSite
+
+	// BuildConstraint is the build constraint of the file of the
+	// function, if any, as in linux && amd64.
+	BuildConstraint string
 }
 
 func (fn *FuncNode) String() string {
@@ -109,6 +172,15 @@ type CallSite struct {
 
 	// Resolved indicates if the called function can be statically resolved.
 	Resolved bool
+
+	// Reflective indicates that the call is made through reflection, and
+	// was conservatively assumed to possibly call the function. It is
+	// only set when reflection is modeled, see govulncheck.Config.
+	Reflective bool
+
+	// BuildConstraint is the build constraint of the file of the call,
+	// if any, as in linux && amd64.
+	BuildConstraint string
 }
 
 // affectingVulns is an external structure for querying
@@ -120,6 +192,9 @@ type affectingVulns []*ModVulns
 type ModVulns struct {
 	Module *packages.Module
 	Vulns  []*osv.Entry
+	// UnknownVersion is set for modules whose version could not be
+	// determined, which are assumed to be affected by all of Vulns.
+	UnknownVersion bool
 }
 
 func affectingVulnerabilities(vulns []*ModVulns, os, arch string) affectingVulns {
@@ -150,7 +225,7 @@ func affectingVulnerabilities(vulns []*ModVulns, os, arch string) affectingVulns
 				if a.Module.Path != module.Path {
 					continue
 				}
-				if !affected(modVersion, a) {
+				if !mod.UnknownVersion && !affected(modVersion, a) {
 					continue
 				}
 
@@ -181,8 +256,9 @@ func affectingVulnerabilities(vulns []*ModVulns, os, arch string) affectingVulns
 		}
 
 		filtered = append(filtered, &ModVulns{
-			Module: module,
-			Vulns:  filteredVulns,
+			Module:         module,
+			Vulns:          filteredVulns,
+			UnknownVersion: mod.UnknownVersion,
 		})
 	}
 	return filtered
@@ -258,11 +334,20 @@ func (aff affectingVulns) moduleVulns(module, importPath string) *ModVulns {
 }
 
 // ForPackage returns the vulnerabilities for the importPath belonging to
-// module.
+// module, and those of the packages the registered symbol matchers
+// match it to.
 //
 // If module is unknown, ForPackage will resolve it as the most specific
 // prefix of importPath.
 func (aff affectingVulns) ForPackage(module, importPath string) []*osv.Entry {
+	vulns := aff.forPackage(module, importPath)
+	for _, s := range matchedSymbols(importPath, "") {
+		vulns = appendNewEntries(vulns, aff.forPackage(s.Module, s.Package))
+	}
+	return vulns
+}
+
+func (aff affectingVulns) forPackage(module, importPath string) []*osv.Entry {
 	mod := aff.moduleVulns(module, importPath)
 	if mod == nil {
 		return nil
@@ -294,9 +379,18 @@ Vuln:
 	return packageVulns
 }
 
-// ForSymbol returns vulnerabilities for symbol in aff.ForPackage(module, importPath).
+// ForSymbol returns vulnerabilities for symbol in aff.ForPackage(module, importPath),
+// and those of the symbols the registered symbol matchers match it to.
 func (aff affectingVulns) ForSymbol(module, importPath, symbol string) []*osv.Entry {
-	vulns := aff.ForPackage(module, importPath)
+	vulns := aff.forSymbol(module, importPath, symbol)
+	for _, s := range matchedSymbols(importPath, symbol) {
+		vulns = appendNewEntries(vulns, aff.forSymbol(s.Module, s.Package, s.Name))
+	}
+	return vulns
+}
+
+func (aff affectingVulns) forSymbol(module, importPath, symbol string) []*osv.Entry {
+	vulns := aff.forPackage(module, importPath)
 	if vulns == nil {
 		return nil
 	}
@@ -6,15 +6,20 @@ package vulncheck
 
 import (
 	"container/list"
+	"context"
 	"fmt"
 	"go/ast"
 	"go/token"
+	"iter"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
+	"github.com/StevenACoffman/invuln/external/govulncheck"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -44,33 +49,95 @@ type StackEntry struct {
 // function or method in res.CallGraph.Entries. During this search,
 // each function is visited at most once to avoid potential
 // exponential explosion. Hence, not all call stacks are analyzed.
-func sourceCallstacks(res *Result) map[*Vuln]CallStack {
-	var (
-		wg sync.WaitGroup
-		mu sync.Mutex
-	)
+//
+// If cfg.WitnessRanking is set to other than
+// govulncheck.WitnessShortest, the call stack ranked first among those
+// found by AllCallStacks is returned instead.
+//
+// The search for each call stack is limited by cfg.WitnessTimeout and
+// cfg.WitnessDepth, if set, and stops once ctx is done. When it reaches
+// a limit before finding an entry point, the partial call stack reaching
+// the farthest from the vulnerable symbol is returned instead.
+func sourceCallstacks(ctx context.Context, res *Result, cfg *govulncheck.Config) map[*Vuln]CallStack {
+	var mu sync.Mutex
 	stackPerVuln := make(map[*Vuln]CallStack)
-	for _, vuln := range res.Vulns {
-		vuln := vuln
-		wg.Add(1)
-		go func() {
-			cs := sourceCallstack(vuln, res)
-			mu.Lock()
-			stackPerVuln[vuln] = cs
-			mu.Unlock()
-			wg.Done()
-		}()
-	}
-	wg.Wait()
+	progress := govulncheck.StartPhase(ctx, govulncheck.PhaseAnalyze, len(res.Vulns))
+	parallel(len(res.Vulns), witnessWorkers(cfg), func(i int) {
+		vuln := res.Vulns[i]
+		opts := newWitnessOptions(ctx, cfg)
+		var cs CallStack
+		if ranking := cfg.WitnessRanking; ranking != "" && ranking != govulncheck.WitnessShortest {
+			var stacks []CallStack
+			for s := range allCallStacks(res, vuln, maxRankedCallStacks, opts.throughVulns) {
+				if opts.tooDeep(len(s)-1) || opts.expired() {
+					break
+				}
+				stacks = append(stacks, s)
+			}
+			if len(stacks) > 0 {
+				RankCallStacks(stacks, ranking)
+				cs = stacks[0]
+			}
+		}
+		if cs == nil {
+			cs = sourceCallstack(vuln, res, opts)
+		}
+		mu.Lock()
+		stackPerVuln[vuln] = cs
+		mu.Unlock()
+		progress.Advance()
+	})
+	if ctx.Err() == nil {
+		progress.Complete()
+	}
 
 	updateInitPositions(stackPerVuln)
 	return stackPerVuln
 }
 
+// witnessOptions are the options of the search for a call stack.
+type witnessOptions struct {
+	ctx      context.Context
+	deadline time.Time // zero if none
+	depth    int       // maximum number of calls, if positive
+
+	// throughVulns is set if call stacks may go through the other
+	// vulnerable symbols of the same package for the same vulnerability.
+	throughVulns bool
+}
+
+func newWitnessOptions(ctx context.Context, cfg *govulncheck.Config) witnessOptions {
+	opts := witnessOptions{ctx: ctx, depth: cfg.WitnessDepth, throughVulns: cfg.WitnessThroughVulns}
+	if cfg.WitnessTimeout > 0 {
+		opts.deadline = time.Now().Add(cfg.WitnessTimeout)
+	}
+	return opts
+}
+
+// tooDeep reports whether calls is more than
+// the maximum number of calls of call stacks.
+func (opts witnessOptions) tooDeep(calls int) bool {
+	return opts.depth > 0 && calls > opts.depth
+}
+
+// expired reports whether the time for the search is up,
+// or the search is cancelled.
+func (opts witnessOptions) expired() bool {
+	if opts.ctx != nil && opts.ctx.Err() != nil {
+		return true
+	}
+	return !opts.deadline.IsZero() && time.Now().After(opts.deadline)
+}
+
 // sourceCallstack finds a representative call stack for vuln.
 // This is a shortest unique call stack with the least
-// number of dynamic call sites.
-func sourceCallstack(vuln *Vuln, res *Result) CallStack {
+// number of dynamic call sites. Unless opts.throughVulns
+// is set, it does not go through other vulnerable symbols.
+//
+// If the search reaches a limit of opts before finding a call stack,
+// the partial call stack going the farthest from the vulnerable
+// symbol is returned.
+func sourceCallstack(vuln *Vuln, res *Result, opts witnessOptions) CallStack {
 	vulnSink := vuln.CallSink
 	if vulnSink == nil {
 		return nil
@@ -94,18 +161,16 @@ func sourceCallstack(vuln *Vuln, res *Result) CallStack {
 	queue := list.New()
 	queue.PushBack(&callChain{f: vulnSink})
 
-	// We want to avoid call stacks that go through
-	// other vulnerable symbols of the same package
-	// for the same vulnerability. In other words,
-	// we want unique call stacks.
-	skipSymbols := make(map[*FuncNode]bool)
-	for _, v := range res.Vulns {
-		if v.CallSink != nil && v != vuln &&
-			v.OSV == vuln.OSV && v.Package == vuln.Package {
-			skipSymbols[v.CallSink] = true
-		}
+	var skipSymbols map[*FuncNode]bool
+	if !opts.throughVulns {
+		skipSymbols = otherSinks(vuln, res)
 	}
 
+	// The deepest call chain visited, and whether
+	// the search was stopped by its limits.
+	var deepest *callChain
+	limited := false
+
 	for queue.Len() > 0 {
 		front := queue.Front()
 		c := front.Value.(*callChain)
@@ -117,10 +182,22 @@ func sourceCallstack(vuln *Vuln, res *Result) CallStack {
 		}
 		seen[f] = true
 
+		if deepest == nil || c.depth > deepest.depth {
+			deepest = c
+		}
+		if opts.expired() {
+			limited = true
+			break
+		}
+
 		// Pick a single call site for each function in determinstic order.
 		// A single call site is sufficient as we visit a function only once.
 		for _, cs := range callsites(f.CallSites, seen) {
-			nStack := &callChain{f: cs.Parent, call: cs, child: c}
+			nStack := &callChain{f: cs.Parent, call: cs, child: c, depth: c.depth + 1}
+			if opts.tooDeep(nStack.depth) {
+				limited = true
+				continue
+			}
 			if !skipSymbols[cs.Parent] {
 				queue.PushBack(nStack)
 			}
@@ -160,11 +237,166 @@ func sourceCallstack(vuln *Vuln, res *Result) CallStack {
 		return true
 	})
 	if len(candidates) == 0 {
+		if limited {
+			return deepest.CallStack()
+		}
 		return nil
 	}
 	return candidates[0]
 }
 
+// maxRankedCallStacks is the maximum number of call stacks
+// of a vulnerability compared to find the first ranked one.
+const maxRankedCallStacks = 1000
+
+// RankCallStacks sorts stacks, such as those returned by AllCallStacks,
+// by the preference of ranking. Call stacks ranked equally are sorted by
+// increasing length and then by their number of dynamic call sites, and
+// otherwise keep their order.
+func RankCallStacks(stacks []CallStack, ranking govulncheck.WitnessRanking) {
+	key := func(CallStack) int { return 0 }
+	switch ranking {
+	case govulncheck.WitnessFewestThirdParty:
+		key = thirdPartyFrames
+	case govulncheck.WitnessMainModule:
+		key = framesAfterMainModule
+	}
+	sort.SliceStable(stacks, func(i, j int) bool {
+		s1, s2 := stacks[i], stacks[j]
+		if k1, k2 := key(s1), key(s2); k1 != k2 {
+			return k1 < k2
+		}
+		if len(s1) != len(s2) {
+			return len(s1) < len(s2)
+		}
+		return weight(s1) < weight(s2)
+	})
+}
+
+// thirdPartyFrames returns the number of frames of stack in modules
+// other than the main module and the standard library.
+func thirdPartyFrames(stack CallStack) int {
+	n := 0
+	for _, e := range stack {
+		if p := e.Function.Package; p != nil && !inMainModule(p) && !IsStdPackage(p.PkgPath) {
+			n++
+		}
+	}
+	return n
+}
+
+// framesAfterMainModule returns the number of frames of stack
+// after its last frame in the main module, or the length of stack
+// if it has no frame in the main module.
+func framesAfterMainModule(stack CallStack) int {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if p := stack[i].Function.Package; p != nil && inMainModule(p) {
+			return len(stack) - 1 - i
+		}
+	}
+	return len(stack)
+}
+
+func inMainModule(p *packages.Package) bool {
+	return p.Module != nil && p.Module.Main
+}
+
+// otherSinks returns the vulnerable symbols of the same package for the
+// same vulnerability as vuln. We want to avoid call stacks that go
+// through them. In other words, we want unique call stacks.
+func otherSinks(vuln *Vuln, res *Result) map[*FuncNode]bool {
+	sinks := make(map[*FuncNode]bool)
+	for _, v := range res.Vulns {
+		if v.CallSink != nil && v != vuln &&
+			v.OSV == vuln.OSV && v.Package == vuln.Package {
+			sinks[v.CallSink] = true
+		}
+	}
+	return sinks
+}
+
+// AllCallStacks returns an iterator over the distinct call stacks from
+// the entry functions of res to the vulnerable symbol of vuln, in order
+// of increasing length. It stops after limit call stacks if limit is
+// positive. Call stacks of the same length are ordered by their
+// number of dynamic call sites.
+//
+// The representative call stack reported for vuln is found by a search
+// visiting each function only once. AllCallStacks instead follows every
+// call site, lazily, so that tools can show alternate call stacks when
+// the representative one is unhelpful, for instance because it goes
+// through generated code. Each call stack visits a function at most
+// once and, like the representative one, avoids the other vulnerable
+// symbols of the same package for the same vulnerability.
+func AllCallStacks(res *Result, vuln *Vuln, limit int) iter.Seq[CallStack] {
+	return allCallStacks(res, vuln, limit, false)
+}
+
+// allCallStacks is like AllCallStacks, but the call stacks
+// may go through other vulnerable symbols if throughVulns is set.
+func allCallStacks(res *Result, vuln *Vuln, limit int, throughVulns bool) iter.Seq[CallStack] {
+	return func(yield func(CallStack) bool) {
+		if vuln.CallSink == nil {
+			return
+		}
+		entries := make(map[*FuncNode]bool)
+		for _, e := range res.EntryFunctions {
+			entries[e] = true
+		}
+		var skipSymbols map[*FuncNode]bool
+		if !throughVulns {
+			skipSymbols = otherSinks(vuln, res)
+		}
+
+		n := 0
+		level := []*callChain{{f: vuln.CallSink}}
+		for len(level) > 0 {
+			// Call stacks of the same length are ordered
+			// by weight, as for the representative one.
+			var next []*callChain
+			var stacks []CallStack
+			for _, c := range level {
+				for _, cs := range sortedCallsites(c.f.CallSites) {
+					if c.contains(cs.Parent) {
+						continue
+					}
+					nc := &callChain{f: cs.Parent, call: cs, child: c}
+					if entries[cs.Parent] {
+						stacks = append(stacks, nc.CallStack())
+					}
+					if !skipSymbols[cs.Parent] {
+						next = append(next, nc)
+					}
+				}
+			}
+			sort.SliceStable(stacks, func(i, j int) bool { return weight(stacks[i]) < weight(stacks[j]) })
+			for _, stack := range stacks {
+				updateInitStackPositions(stack)
+				if !yield(stack) {
+					return
+				}
+				if n++; limit > 0 && n >= limit {
+					return
+				}
+			}
+			level = next
+		}
+	}
+}
+
+// sortedCallsites returns sites sorted by their caller
+// functions (funcLess) and then by their positions (csLess).
+func sortedCallsites(sites []*CallSite) []*CallSite {
+	sorted := slices.Clone(sites)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if p1, p2 := sorted[i].Parent, sorted[j].Parent; p1 != p2 {
+			return funcLess(p1, p2)
+		}
+		return csLess(sorted[i], sorted[j])
+	})
+	return sorted
+}
+
 // callsites picks a call site from sites for each non-visited function.
 // For each such function, the smallest (posLess) call site is chosen. The
 // returned slice is sorted by caller functions (funcLess). Assumes callee
@@ -198,6 +430,17 @@ type callChain struct {
 	call  *CallSite // nil for entry points
 	f     *FuncNode
 	child *callChain
+	depth int // number of calls in the chain
+}
+
+// contains reports whether f is on the call chain c.
+func (c *callChain) contains(f *FuncNode) bool {
+	for ; c != nil; c = c.child {
+		if c.f == f {
+			return true
+		}
+	}
+	return false
 }
 
 // CallStack converts callChain to CallStack type.
@@ -300,11 +543,17 @@ func funcLess(f1, f2 *FuncNode) bool {
 // and their respective calls in callStacks (see #51575).
 func updateInitPositions(callStacks map[*Vuln]CallStack) {
 	for _, cs := range callStacks {
-		for i := range cs {
-			updateInitPosition(&cs[i])
-			if i != len(cs)-1 {
-				updateInitCallPosition(&cs[i], cs[i+1])
-			}
+		updateInitStackPositions(cs)
+	}
+}
+
+// updateInitStackPositions populates non-existing positions
+// of init functions and their respective calls in cs.
+func updateInitStackPositions(cs CallStack) {
+	for i := range cs {
+		updateInitPosition(&cs[i])
+		if i != len(cs)-1 {
+			updateInitCallPosition(&cs[i], cs[i+1])
 		}
 	}
 }
@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/StevenACoffman/invuln/external/client"
 	"github.com/StevenACoffman/invuln/external/govulncheck"
@@ -66,12 +67,201 @@ func TestSourceCallstacks(t *testing.T) {
 		"vuln2": "entry2->interm2->vuln2",
 	}
 
-	stacks := sourceCallstacks(res)
+	stacks := sourceCallstacks(context.Background(), res, &govulncheck.Config{Workers: 2})
 	if got := stacksToString(stacks); !reflect.DeepEqual(want, got) {
 		t.Errorf("want %v; got %v", want, got)
 	}
 }
 
+func TestSourceCallstacksLimits(t *testing.T) {
+	// Call graph structure for the test program
+	//    entry
+	//      |
+	//    interm1
+	//      |
+	//    interm2
+	//      |
+	//     vuln
+	o := &osv.Entry{ID: "o"}
+	e := &FuncNode{Name: "entry"}
+	i1 := &FuncNode{Name: "interm1", CallSites: []*CallSite{{Parent: e, Resolved: true}}}
+	i2 := &FuncNode{Name: "interm2", CallSites: []*CallSite{{Parent: i1, Resolved: true}}}
+	v := &FuncNode{Name: "vuln", CallSites: []*CallSite{{Parent: i2, Resolved: true}}}
+
+	vp := &packages.Package{PkgPath: "v1", Module: &packages.Module{Path: "m1"}}
+	res := &Result{
+		EntryFunctions: []*FuncNode{e},
+		Vulns:          []*Vuln{{CallSink: v, Package: vp, OSV: o, Symbol: "vuln"}},
+	}
+
+	for _, tt := range []struct {
+		cfg  *govulncheck.Config
+		want string
+	}{
+		{&govulncheck.Config{}, "entry->interm1->interm2->vuln"},
+		{&govulncheck.Config{WitnessDepth: 3}, "entry->interm1->interm2->vuln"},
+		{&govulncheck.Config{WitnessDepth: 2}, "interm1->interm2->vuln"},
+		{&govulncheck.Config{WitnessDepth: 1}, "interm2->vuln"},
+		{&govulncheck.Config{WitnessDepth: 2, WitnessRanking: govulncheck.WitnessMainModule}, "interm1->interm2->vuln"},
+		{&govulncheck.Config{WitnessTimeout: time.Hour}, "entry->interm1->interm2->vuln"},
+		{&govulncheck.Config{WitnessTimeout: time.Nanosecond}, "vuln"},
+	} {
+		got := stacksToString(sourceCallstacks(context.Background(), res, tt.cfg))["vuln"]
+		if got != tt.want {
+			t.Errorf("%+v: got %q; want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestSourceCallstacksThroughVulns(t *testing.T) {
+	// Call graph structure for the test program
+	//        entry
+	//       /     \
+	//    vuln1   interm1
+	//      |       |
+	//      |     interm2
+	//       \     /
+	//        vuln2
+	o := &osv.Entry{ID: "o"}
+	e := &FuncNode{Name: "entry"}
+	v1 := &FuncNode{Name: "vuln1", CallSites: []*CallSite{{Parent: e, Resolved: true}}}
+	i1 := &FuncNode{Name: "interm1", CallSites: []*CallSite{{Parent: e, Resolved: true}}}
+	i2 := &FuncNode{Name: "interm2", CallSites: []*CallSite{{Parent: i1, Resolved: true}}}
+	v2 := &FuncNode{Name: "vuln2", CallSites: []*CallSite{{Parent: v1, Resolved: true}, {Parent: i2, Resolved: true}}}
+
+	vp := &packages.Package{PkgPath: "v1", Module: &packages.Module{Path: "m1"}}
+	res := &Result{
+		EntryFunctions: []*FuncNode{e},
+		Vulns: []*Vuln{
+			{CallSink: v1, Package: vp, OSV: o, Symbol: "vuln1"},
+			{CallSink: v2, Package: vp, OSV: o, Symbol: "vuln2"},
+		},
+	}
+
+	for _, tt := range []struct {
+		cfg  *govulncheck.Config
+		want string
+	}{
+		{&govulncheck.Config{}, "entry->interm1->interm2->vuln2"},
+		{&govulncheck.Config{WitnessThroughVulns: true}, "entry->vuln1->vuln2"},
+		{&govulncheck.Config{WitnessRanking: govulncheck.WitnessMainModule}, "entry->interm1->interm2->vuln2"},
+		{&govulncheck.Config{WitnessRanking: govulncheck.WitnessMainModule, WitnessThroughVulns: true}, "entry->vuln1->vuln2"},
+	} {
+		got := stacksToString(sourceCallstacks(context.Background(), res, tt.cfg))["vuln2"]
+		if got != tt.want {
+			t.Errorf("%+v: got %q; want %q", tt.cfg, got, tt.want)
+		}
+	}
+}
+
+func TestAllCallStacks(t *testing.T) {
+	// Call graph structure for the test program
+	//    entry1      entry2
+	//      |           |
+	//    interm1       |
+	//      |    \     /
+	//      |   interm2(interface)
+	//      |   /     |
+	//     vuln1    vuln2
+	o := &osv.Entry{ID: "o"}
+	e1 := &FuncNode{Name: "entry1"}
+	e2 := &FuncNode{Name: "entry2"}
+	i1 := &FuncNode{Name: "interm1", CallSites: []*CallSite{{Parent: e1, Resolved: true}}}
+	i2 := &FuncNode{Name: "interm2", CallSites: []*CallSite{{Parent: e2, Resolved: true}, {Parent: i1, Resolved: true}}}
+	v1 := &FuncNode{Name: "vuln1", CallSites: []*CallSite{{Parent: i1, Resolved: true}, {Parent: i2, Resolved: false}}}
+	v2 := &FuncNode{Name: "vuln2", CallSites: []*CallSite{{Parent: i2, Resolved: false}}}
+
+	vp := &packages.Package{PkgPath: "v1", Module: &packages.Module{Path: "m1"}}
+	vuln1 := &Vuln{CallSink: v1, Package: vp, OSV: o, Symbol: "vuln1"}
+	vuln2 := &Vuln{CallSink: v2, Package: vp, OSV: o, Symbol: "vuln2"}
+	res := &Result{
+		EntryFunctions: []*FuncNode{e1, e2},
+		Vulns:          []*Vuln{vuln1, vuln2},
+	}
+
+	stacks := func(vuln *Vuln, limit int) []string {
+		var got []string
+		for st := range AllCallStacks(res, vuln, limit) {
+			got = append(got, stacksToString(map[*Vuln]CallStack{vuln: st})[vuln.Symbol])
+		}
+		return got
+	}
+	for _, tt := range []struct {
+		vuln  *Vuln
+		limit int
+		want  []string
+	}{
+		{vuln1, 0, []string{
+			"entry1->interm1->vuln1",
+			"entry2->interm2->vuln1",
+			"entry1->interm1->interm2->vuln1",
+		}},
+		{vuln1, 2, []string{
+			"entry1->interm1->vuln1",
+			"entry2->interm2->vuln1",
+		}},
+		{vuln2, 0, []string{
+			"entry2->interm2->vuln2",
+			"entry1->interm1->interm2->vuln2",
+		}},
+	} {
+		if diff := cmp.Diff(tt.want, stacks(tt.vuln, tt.limit)); diff != "" {
+			t.Errorf("%s, limit %d: mismatch (-want, +got):\n%s", tt.vuln.Symbol, tt.limit, diff)
+		}
+	}
+
+	// Stopping the iteration early is supported.
+	for range AllCallStacks(res, vuln1, 0) {
+		break
+	}
+}
+
+func TestRankCallStacks(t *testing.T) {
+	main := &packages.Package{PkgPath: "example.com/m", Module: &packages.Module{Path: "example.com/m", Main: true}}
+	third := &packages.Package{PkgPath: "golang.org/x/a", Module: &packages.Module{Path: "golang.org/x/a"}}
+	vuln := &packages.Package{PkgPath: "golang.org/x/v", Module: &packages.Module{Path: "golang.org/x/v"}}
+	std := &packages.Package{PkgPath: "net/http", Module: &packages.Module{Path: "std"}}
+
+	stack := func(fs ...*FuncNode) CallStack {
+		var cs CallStack
+		for _, f := range fs {
+			cs = append(cs, StackEntry{Function: f, Call: &CallSite{Resolved: true}})
+		}
+		return cs
+	}
+	m1 := &FuncNode{Name: "main", Package: main}
+	m2 := &FuncNode{Name: "handle", Package: main}
+	a1 := &FuncNode{Name: "A1", Package: third}
+	a2 := &FuncNode{Name: "A2", Package: third}
+	s1 := &FuncNode{Name: "Serve", Package: std}
+	v := &FuncNode{Name: "Vuln", Package: vuln}
+
+	// short leaves the main module early, through a third-party module.
+	short := stack(m1, a1, a2, v)
+	// viaStd goes through the standard library only.
+	viaStd := stack(m1, s1, s1, s1, v)
+	// anchored calls the vulnerable symbol from the main module.
+	anchored := stack(m1, a1, m2, m2, m2, v)
+
+	for _, test := range []struct {
+		ranking govulncheck.WitnessRanking
+		want    []CallStack
+	}{
+		{"", []CallStack{short, viaStd, anchored}},
+		{govulncheck.WitnessShortest, []CallStack{short, viaStd, anchored}},
+		{govulncheck.WitnessFewestThirdParty, []CallStack{viaStd, anchored, short}},
+		{govulncheck.WitnessMainModule, []CallStack{anchored, short, viaStd}},
+	} {
+		t.Run(string(test.ranking), func(t *testing.T) {
+			stacks := []CallStack{anchored, viaStd, short}
+			RankCallStacks(stacks, test.ranking)
+			if !reflect.DeepEqual(stacks, test.want) {
+				t.Errorf("got %v; want %v", stacks, test.want)
+			}
+		})
+	}
+}
+
 func TestSourceUniqueCallStack(t *testing.T) {
 	// Call graph structure for the test program
 	//    entry1      entry2
@@ -102,7 +292,7 @@ func TestSourceUniqueCallStack(t *testing.T) {
 		"vuln2": "entry2->interm1->interm2->vuln2",
 	}
 
-	stacks := sourceCallstacks(res)
+	stacks := sourceCallstacks(context.Background(), res, &govulncheck.Config{Workers: 2})
 	if got := stacksToString(stacks); !reflect.DeepEqual(want, got) {
 		t.Errorf("want %v; got %v", want, got)
 	}
@@ -195,7 +385,7 @@ func TestInits(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	cs := sourceCallstacks(result)
+	cs := sourceCallstacks(context.Background(), result, &govulncheck.Config{Workers: 2})
 	want := map[string][]string{
 		"A": {
 			// Entry init's position is the package statement.
@@ -0,0 +1,125 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"io"
+
+	"github.com/StevenACoffman/invuln/external/buildinfo"
+	"github.com/StevenACoffman/invuln/external/scan"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"golang.org/x/tools/go/packages"
+)
+
+// A BinaryInfo is what govulncheck reads from a Go binary to match it
+// against vulnerabilities: its modules, Go version, platform, and
+// symbols. It is encoded to JSON by the encoding/json package.
+type BinaryInfo struct {
+	// Path is the import path of the main package.
+	Path string `json:"path,omitempty"`
+
+	// Main is the main module, if known, and Modules
+	// are the other modules the binary is built from.
+	Main    *BinaryModule   `json:"main,omitempty"`
+	Modules []*BinaryModule `json:"modules,omitempty"`
+
+	// GoVersion is the version of Go the binary is built with,
+	// such as go1.21.5, and GOOS and GOARCH its platform.
+	GoVersion string `json:"go_version,omitempty"`
+	GOOS      string `json:"goos,omitempty"`
+	GOARCH    string `json:"goarch,omitempty"`
+
+	// Symbols are the functions and methods in the binary, and
+	// SymbolPrecision how completely they were recovered, one
+	// of SymbolsComplete, SymbolsNoInlined, and SymbolsNone.
+	Symbols         []BinarySymbol `json:"symbols,omitempty"`
+	SymbolPrecision string         `json:"symbol_precision"`
+
+	// ImportedLibraries are the shared libraries the
+	// binary is dynamically linked against.
+	ImportedLibraries []string `json:"imported_libraries,omitempty"`
+}
+
+// A BinaryModule is a module a binary is built from.
+type BinaryModule struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+
+	// Replace is the module replacing this one, if any.
+	Replace *BinaryModule `json:"replace,omitempty"`
+}
+
+// A BinarySymbol is a function or method in a binary. Methods are
+// named as in the symbols of OSV entries, as in Reader.Read.
+type BinarySymbol struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+}
+
+// The values of BinaryInfo.SymbolPrecision.
+const (
+	// SymbolsComplete means that all symbols, including those
+	// inlined into other functions, were recovered.
+	SymbolsComplete = buildinfo.SymbolsComplete
+
+	// SymbolsNoInlined means that the binary has no symbol table, so
+	// that functions inlined into other functions were not recovered.
+	SymbolsNoInlined = buildinfo.SymbolsNoInlined
+
+	// SymbolsNone means that no symbols were recovered, so that
+	// only the modules of the binary can be matched.
+	SymbolsNone = buildinfo.SymbolsNone
+)
+
+// ReadBinary returns what govulncheck reads from the Go binary, or
+// blob of extract mode, at path, without matching it against
+// vulnerabilities, such as for inventories of the modules of
+// binaries. Binaries govulncheck cannot read are reported as an
+// *Error of code ErrorUnsupportedBinary.
+func ReadBinary(path string) (*BinaryInfo, error) {
+	bin, err := scan.ReadBin(path)
+	if err != nil {
+		return nil, err
+	}
+	return newBinaryInfo(bin), nil
+}
+
+// ReadBinaryFrom is ReadBinary for a binary of the given size read from r.
+func ReadBinaryFrom(r io.ReaderAt, size int64) (*BinaryInfo, error) {
+	bin, err := scan.ReadBinFromReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return newBinaryInfo(bin), nil
+}
+
+func newBinaryInfo(bin *vulncheck.Bin) *BinaryInfo {
+	info := &BinaryInfo{
+		Path:              bin.Path,
+		Main:              newBinaryModule(bin.Main),
+		GoVersion:         bin.GoVersion,
+		GOOS:              bin.GOOS,
+		GOARCH:            bin.GOARCH,
+		SymbolPrecision:   bin.SymbolPrecision,
+		ImportedLibraries: bin.ImportedLibraries,
+	}
+	if info.SymbolPrecision == "" {
+		info.SymbolPrecision = SymbolsComplete
+	}
+	for _, m := range bin.Modules {
+		info.Modules = append(info.Modules, newBinaryModule(m))
+	}
+	for _, s := range bin.PkgSymbols {
+		info.Symbols = append(info.Symbols, BinarySymbol{Package: s.Pkg, Name: s.Name})
+	}
+	return info
+}
+
+func newBinaryModule(m *packages.Module) *BinaryModule {
+	if m == nil {
+		return nil
+	}
+	return &BinaryModule{Path: m.Path, Version: m.Version, Replace: newBinaryModule(m.Replace)}
+}
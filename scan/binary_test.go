@@ -0,0 +1,63 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestReadBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	info, err := ReadBinary(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Main == nil || info.Main.Path != "github.com/StevenACoffman/invuln" {
+		t.Errorf("got main module %+v; want github.com/StevenACoffman/invuln", info.Main)
+	}
+	if info.GoVersion != runtime.Version() || info.GOOS != runtime.GOOS || info.GOARCH != runtime.GOARCH {
+		t.Errorf("got %s %s/%s; want %s %s/%s", info.GoVersion, info.GOOS, info.GOARCH, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	}
+	var tools, symbol bool
+	for _, m := range info.Modules {
+		tools = tools || m.Path == "golang.org/x/tools"
+	}
+	for _, s := range info.Symbols {
+		symbol = symbol || s.Package == "github.com/StevenACoffman/invuln/scan" && s.Name == "ReadBinary"
+	}
+	if !tools || !symbol {
+		t.Errorf("golang.org/x/tools module found: %t, ReadBinary symbol found: %t; want both", tools, symbol)
+	}
+
+	blob, err := os.ReadFile(filepath.Join("..", "cmd", "govulncheck", "testdata", "common", "testfiles", "extract", "vuln.blob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err = ReadBinaryFrom(bytes.NewReader(blob), int64(len(blob)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Modules) == 0 || info.SymbolPrecision == "" {
+		t.Errorf("got %+v from the blob; want its modules and symbol precision", info)
+	}
+
+	notBinary := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(notBinary, []byte("not a binary"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadBinary(notBinary)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != ErrorUnsupportedBinary {
+		t.Errorf("got error %v for a text file; want one of code %s", err, ErrorUnsupportedBinary)
+	}
+}
@@ -0,0 +1,573 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"golang.org/x/tools/go/packages"
+)
+
+// A Result is the result of the analysis of source code: the
+// vulnerabilities found, and the slice of the call graph of the
+// packages leading to the called ones. Results are encoded to and
+// decoded from JSON by the encoding/json package, so that they can
+// be stored, or passed to other processes, and analyzed later.
+type Result struct {
+	// EntryFunctions are the functions the call graph is entered
+	// from, such as main functions and exported functions.
+	EntryFunctions []*FuncNode
+
+	// Vulns are the vulnerabilities found.
+	Vulns []*Vuln
+}
+
+// A Vuln is a vulnerability found in a package. At the symbol level,
+// it is also a vulnerable symbol called, or used, by the code.
+type Vuln struct {
+	// OSV is the entry of the vulnerability.
+	OSV *Entry
+
+	// Symbol is the name of the vulnerable symbol,
+	// or "" for vulnerabilities found at the package level.
+	Symbol string
+
+	// Package, Module, and Version are the import path of the
+	// vulnerable package, and the path and version of its module.
+	Package string
+	Module  string
+	Version string
+
+	// CallSink is the vulnerable function called, if any.
+	CallSink *FuncNode
+
+	// CallStack is the call stack reported from an entry function
+	// to CallSink, if one was found.
+	CallStack CallStack
+
+	// Use is the use of a vulnerable symbol other than a call, if any.
+	Use *Use
+
+	// Taint are the sources of untrusted data reaching CallSink.
+	Taint []govulncheck.TaintSource
+
+	// ExposedBy are the exported symbols of the scanned packages
+	// through which the vulnerable symbol is reachable, if any.
+	ExposedBy []string
+
+	// Linked reports whether the symbol is linked into the program
+	// without being called, and Linkage how.
+	Linked  bool
+	Linkage govulncheck.Linkage
+}
+
+// A Use is a use of a vulnerable type, variable, constant, or struct
+// field, or a reference to a vulnerable function other than a call.
+type Use struct {
+	// Kind is the kind of the used symbol.
+	Kind govulncheck.SymbolKind
+
+	// Parent is the function the symbol is used in.
+	Parent *FuncNode
+
+	// Pos is the position of the use.
+	Pos *token.Position
+}
+
+// A FuncNode is a function in the call graph.
+type FuncNode struct {
+	// Name is the name of the function. For instances of generic
+	// functions and methods, it is the name of the generic one.
+	Name string
+
+	// TypeArgs are the type arguments of the instance of a generic
+	// function, or of the receiver type of a method, if any.
+	TypeArgs []string
+
+	// RecvType is the receiver type of the function, if any.
+	RecvType string
+
+	// Package, Module, and Version are the import path of the
+	// package of the function, and the path and version of its module.
+	Package string
+	Module  string
+	Version string
+
+	// Pos and End are the positions of the function and of its end,
+	// if known.
+	Pos *token.Position
+	End *token.Position
+
+	// CallSites are the calls of the function.
+	CallSites []*CallSite
+
+	// BuildConstraint is the build constraint of the file of the
+	// function, if any, as in linux && amd64.
+	BuildConstraint string
+}
+
+// A CallSite is a call of a function.
+type CallSite struct {
+	// Parent is the function the call is made in.
+	Parent *FuncNode
+
+	// Name is the name of the function, or variable, called.
+	Name string
+
+	// RecvType is the receiver type of the function called, if any.
+	RecvType string
+
+	// Pos is the position of the call.
+	Pos *token.Position
+
+	// Resolved reports whether the function called is statically
+	// known, and Reflective whether the call is made by reflection.
+	Resolved   bool
+	Reflective bool
+
+	// BuildConstraint is the build constraint of the file of the call,
+	// if any, as in linux && amd64.
+	BuildConstraint string
+}
+
+// A CallStack is a sequence of calls, from an entry function
+// to a vulnerable one.
+type CallStack []StackEntry
+
+// A StackEntry is a function of a call stack, and the call it
+// makes to the function of the next entry, which is nil for
+// the last entry.
+type StackEntry struct {
+	Function *FuncNode
+	Call     *CallSite
+}
+
+// newResult returns the Result of vr.
+func newResult(vr *vulncheck.Result) *Result {
+	c := &resultConverter{
+		funcs: make(map[*vulncheck.FuncNode]*FuncNode),
+		sites: make(map[*vulncheck.CallSite]*CallSite),
+	}
+	r := &Result{}
+	for _, f := range vr.EntryFunctions {
+		r.EntryFunctions = append(r.EntryFunctions, c.funcNode(f))
+	}
+	for _, v := range vr.Vulns {
+		rv := &Vuln{
+			OSV:       v.OSV,
+			Symbol:    v.Symbol,
+			CallSink:  c.funcNode(v.CallSink),
+			Taint:     v.Taint,
+			ExposedBy: v.ExposedBy,
+			Linked:    v.Linked,
+			Linkage:   v.Linkage,
+		}
+		rv.Package, rv.Module, rv.Version = packageInfo(v.Package)
+		if v.Use != nil {
+			rv.Use = &Use{Kind: v.Use.Kind, Parent: c.funcNode(v.Use.Parent), Pos: v.Use.Pos}
+		}
+		for _, e := range vr.CallStacks[v] {
+			rv.CallStack = append(rv.CallStack, StackEntry{Function: c.funcNode(e.Function), Call: c.callSite(e.Call)})
+		}
+		r.Vulns = append(r.Vulns, rv)
+	}
+	return r
+}
+
+// A resultConverter converts the functions and calls of a
+// vulncheck.Result, converting each only once, as they form
+// a graph with cycles.
+type resultConverter struct {
+	funcs map[*vulncheck.FuncNode]*FuncNode
+	sites map[*vulncheck.CallSite]*CallSite
+}
+
+func (c *resultConverter) funcNode(f *vulncheck.FuncNode) *FuncNode {
+	if f == nil {
+		return nil
+	}
+	if rf, ok := c.funcs[f]; ok {
+		return rf
+	}
+	rf := &FuncNode{
+		Name:            f.Name,
+		TypeArgs:        f.TypeArgs,
+		RecvType:        f.RecvType,
+		Pos:             f.Pos,
+		End:             f.End,
+		BuildConstraint: f.BuildConstraint,
+	}
+	rf.Package, rf.Module, rf.Version = packageInfo(f.Package)
+	c.funcs[f] = rf
+	for _, s := range f.CallSites {
+		rf.CallSites = append(rf.CallSites, c.callSite(s))
+	}
+	return rf
+}
+
+func (c *resultConverter) callSite(s *vulncheck.CallSite) *CallSite {
+	if s == nil {
+		return nil
+	}
+	if rs, ok := c.sites[s]; ok {
+		return rs
+	}
+	rs := &CallSite{
+		Name:            s.Name,
+		RecvType:        s.RecvType,
+		Pos:             s.Pos,
+		Resolved:        s.Resolved,
+		Reflective:      s.Reflective,
+		BuildConstraint: s.BuildConstraint,
+	}
+	c.sites[s] = rs
+	rs.Parent = c.funcNode(s.Parent)
+	return rs
+}
+
+// packageInfo returns the import path of pkg, and the
+// path and version of its module, if known.
+func packageInfo(pkg *packages.Package) (path, modPath, version string) {
+	if pkg == nil {
+		return "", "", ""
+	}
+	if pkg.Module != nil {
+		modPath, version = pkg.Module.Path, pkg.Module.Version
+	}
+	return pkg.PkgPath, modPath, version
+}
+
+// resultVersion is the version of the JSON encoding of results.
+const resultVersion = 1
+
+// The JSON encoding of a Result lists each function, call, and
+// entry once, referring to functions and calls by their index in
+// those lists, as the call graph has cycles.
+type resultJSON struct {
+	Version        int             `json:"version"`
+	OSVs           []*Entry        `json:"osvs,omitempty"`
+	Functions      []*funcNodeJSON `json:"functions,omitempty"`
+	CallSites      []*callSiteJSON `json:"call_sites,omitempty"`
+	EntryFunctions []int           `json:"entry_functions,omitempty"`
+	Vulns          []*vulnJSON     `json:"vulns,omitempty"`
+}
+
+type vulnJSON struct {
+	OSV       string                    `json:"osv"`
+	Symbol    string                    `json:"symbol,omitempty"`
+	Package   string                    `json:"package,omitempty"`
+	Module    string                    `json:"module,omitempty"`
+	Version   string                    `json:"version,omitempty"`
+	CallSink  *int                      `json:"call_sink,omitempty"`
+	CallStack []stackEntryJSON          `json:"call_stack,omitempty"`
+	Use       *useJSON                  `json:"use,omitempty"`
+	Taint     []govulncheck.TaintSource `json:"taint,omitempty"`
+	ExposedBy []string                  `json:"exposed_by,omitempty"`
+	Linked    bool                      `json:"linked,omitempty"`
+	Linkage   govulncheck.Linkage       `json:"linkage,omitempty"`
+}
+
+type useJSON struct {
+	Kind   govulncheck.SymbolKind `json:"kind"`
+	Parent *int                   `json:"parent,omitempty"`
+	Pos    *token.Position        `json:"position,omitempty"`
+}
+
+type stackEntryJSON struct {
+	Function int  `json:"function"`
+	Call     *int `json:"call,omitempty"`
+}
+
+type funcNodeJSON struct {
+	Name            string          `json:"name"`
+	TypeArgs        []string        `json:"type_args,omitempty"`
+	RecvType        string          `json:"receiver,omitempty"`
+	Package         string          `json:"package,omitempty"`
+	Module          string          `json:"module,omitempty"`
+	Version         string          `json:"version,omitempty"`
+	Pos             *token.Position `json:"position,omitempty"`
+	End             *token.Position `json:"end,omitempty"`
+	CallSites       []int           `json:"call_sites,omitempty"`
+	BuildConstraint string          `json:"build_constraint,omitempty"`
+}
+
+type callSiteJSON struct {
+	Parent          *int            `json:"parent,omitempty"`
+	Name            string          `json:"name"`
+	RecvType        string          `json:"receiver,omitempty"`
+	Pos             *token.Position `json:"position,omitempty"`
+	Resolved        bool            `json:"resolved,omitempty"`
+	Reflective      bool            `json:"reflective,omitempty"`
+	BuildConstraint string          `json:"build_constraint,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *Result) MarshalJSON() ([]byte, error) {
+	e := &resultEncoder{
+		funcs: make(map[*FuncNode]int),
+		sites: make(map[*CallSite]int),
+		osvs:  make(map[string]bool),
+	}
+	e.out.Version = resultVersion
+	for _, f := range r.EntryFunctions {
+		e.out.EntryFunctions = append(e.out.EntryFunctions, e.funcNode(f))
+	}
+	for _, v := range r.Vulns {
+		if v.OSV == nil {
+			return nil, fmt.Errorf("vulnerability of %s has no OSV entry", v.Package)
+		}
+		if !e.osvs[v.OSV.ID] {
+			e.osvs[v.OSV.ID] = true
+			e.out.OSVs = append(e.out.OSVs, v.OSV)
+		}
+		vj := &vulnJSON{
+			OSV:       v.OSV.ID,
+			Symbol:    v.Symbol,
+			Package:   v.Package,
+			Module:    v.Module,
+			Version:   v.Version,
+			CallSink:  e.optFuncNode(v.CallSink),
+			Taint:     v.Taint,
+			ExposedBy: v.ExposedBy,
+			Linked:    v.Linked,
+			Linkage:   v.Linkage,
+		}
+		if v.Use != nil {
+			vj.Use = &useJSON{Kind: v.Use.Kind, Parent: e.optFuncNode(v.Use.Parent), Pos: v.Use.Pos}
+		}
+		for _, se := range v.CallStack {
+			if se.Function == nil {
+				return nil, fmt.Errorf("call stack of %s has an entry with no function", v.OSV.ID)
+			}
+			vj.CallStack = append(vj.CallStack, stackEntryJSON{Function: e.funcNode(se.Function), Call: e.optCallSite(se.Call)})
+		}
+		e.out.Vulns = append(e.out.Vulns, vj)
+	}
+	e.fill()
+	return json.Marshal(&e.out)
+}
+
+// A resultEncoder assigns indices to the functions and calls of a
+// Result, and fills in their encodings once all are indexed.
+type resultEncoder struct {
+	out   resultJSON
+	funcs map[*FuncNode]int
+	sites map[*CallSite]int
+	osvs  map[string]bool
+
+	// pending are the functions and calls indexed but not filled in.
+	pendingFuncs []*FuncNode
+	pendingSites []*CallSite
+}
+
+func (e *resultEncoder) funcNode(f *FuncNode) int {
+	if i, ok := e.funcs[f]; ok {
+		return i
+	}
+	i := len(e.out.Functions)
+	e.funcs[f] = i
+	e.out.Functions = append(e.out.Functions, nil)
+	e.pendingFuncs = append(e.pendingFuncs, f)
+	return i
+}
+
+func (e *resultEncoder) callSite(s *CallSite) int {
+	if i, ok := e.sites[s]; ok {
+		return i
+	}
+	i := len(e.out.CallSites)
+	e.sites[s] = i
+	e.out.CallSites = append(e.out.CallSites, nil)
+	e.pendingSites = append(e.pendingSites, s)
+	return i
+}
+
+func (e *resultEncoder) optFuncNode(f *FuncNode) *int {
+	if f == nil {
+		return nil
+	}
+	i := e.funcNode(f)
+	return &i
+}
+
+func (e *resultEncoder) optCallSite(s *CallSite) *int {
+	if s == nil {
+		return nil
+	}
+	i := e.callSite(s)
+	return &i
+}
+
+// fill fills in the pending functions and calls, and those
+// they refer to, iteratively, as call graphs may be deep.
+func (e *resultEncoder) fill() {
+	for len(e.pendingFuncs) > 0 || len(e.pendingSites) > 0 {
+		for len(e.pendingFuncs) > 0 {
+			f := e.pendingFuncs[0]
+			e.pendingFuncs = e.pendingFuncs[1:]
+			fj := &funcNodeJSON{
+				Name:            f.Name,
+				TypeArgs:        f.TypeArgs,
+				RecvType:        f.RecvType,
+				Package:         f.Package,
+				Module:          f.Module,
+				Version:         f.Version,
+				Pos:             f.Pos,
+				End:             f.End,
+				BuildConstraint: f.BuildConstraint,
+			}
+			for _, s := range f.CallSites {
+				fj.CallSites = append(fj.CallSites, e.callSite(s))
+			}
+			e.out.Functions[e.funcs[f]] = fj
+		}
+		for len(e.pendingSites) > 0 {
+			s := e.pendingSites[0]
+			e.pendingSites = e.pendingSites[1:]
+			e.out.CallSites[e.sites[s]] = &callSiteJSON{
+				Parent:          e.optFuncNode(s.Parent),
+				Name:            s.Name,
+				RecvType:        s.RecvType,
+				Pos:             s.Pos,
+				Resolved:        s.Resolved,
+				Reflective:      s.Reflective,
+				BuildConstraint: s.BuildConstraint,
+			}
+		}
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *Result) UnmarshalJSON(data []byte) error {
+	var in resultJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.Version != resultVersion {
+		return fmt.Errorf("unsupported result version %d", in.Version)
+	}
+	funcs := make([]*FuncNode, len(in.Functions))
+	for i, fj := range in.Functions {
+		if fj == nil {
+			return fmt.Errorf("function %d is null", i)
+		}
+		funcs[i] = &FuncNode{
+			Name:            fj.Name,
+			TypeArgs:        fj.TypeArgs,
+			RecvType:        fj.RecvType,
+			Package:         fj.Package,
+			Module:          fj.Module,
+			Version:         fj.Version,
+			Pos:             fj.Pos,
+			End:             fj.End,
+			BuildConstraint: fj.BuildConstraint,
+		}
+	}
+	funcAt := func(i int) (*FuncNode, error) {
+		if i < 0 || i >= len(funcs) {
+			return nil, fmt.Errorf("invalid function index %d", i)
+		}
+		return funcs[i], nil
+	}
+	optFuncAt := func(i *int) (*FuncNode, error) {
+		if i == nil {
+			return nil, nil
+		}
+		return funcAt(*i)
+	}
+	sites := make([]*CallSite, len(in.CallSites))
+	for i, sj := range in.CallSites {
+		if sj == nil {
+			return fmt.Errorf("call site %d is null", i)
+		}
+		parent, err := optFuncAt(sj.Parent)
+		if err != nil {
+			return err
+		}
+		sites[i] = &CallSite{
+			Parent:          parent,
+			Name:            sj.Name,
+			RecvType:        sj.RecvType,
+			Pos:             sj.Pos,
+			Resolved:        sj.Resolved,
+			Reflective:      sj.Reflective,
+			BuildConstraint: sj.BuildConstraint,
+		}
+	}
+	optSiteAt := func(i *int) (*CallSite, error) {
+		if i == nil {
+			return nil, nil
+		}
+		if *i < 0 || *i >= len(sites) {
+			return nil, fmt.Errorf("invalid call site index %d", *i)
+		}
+		return sites[*i], nil
+	}
+	for i, fj := range in.Functions {
+		for _, si := range fj.CallSites {
+			s, err := optSiteAt(&si)
+			if err != nil {
+				return err
+			}
+			funcs[i].CallSites = append(funcs[i].CallSites, s)
+		}
+	}
+
+	osvs := make(map[string]*Entry)
+	for _, e := range in.OSVs {
+		osvs[e.ID] = e
+	}
+	res := Result{}
+	for _, i := range in.EntryFunctions {
+		f, err := funcAt(i)
+		if err != nil {
+			return err
+		}
+		res.EntryFunctions = append(res.EntryFunctions, f)
+	}
+	for _, vj := range in.Vulns {
+		v := &Vuln{
+			OSV:       osvs[vj.OSV],
+			Symbol:    vj.Symbol,
+			Package:   vj.Package,
+			Module:    vj.Module,
+			Version:   vj.Version,
+			Taint:     vj.Taint,
+			ExposedBy: vj.ExposedBy,
+			Linked:    vj.Linked,
+			Linkage:   vj.Linkage,
+		}
+		if v.OSV == nil {
+			return fmt.Errorf("no OSV entry %q", vj.OSV)
+		}
+		var err error
+		if v.CallSink, err = optFuncAt(vj.CallSink); err != nil {
+			return err
+		}
+		if vj.Use != nil {
+			v.Use = &Use{Kind: vj.Use.Kind, Pos: vj.Use.Pos}
+			if v.Use.Parent, err = optFuncAt(vj.Use.Parent); err != nil {
+				return err
+			}
+		}
+		for _, sej := range vj.CallStack {
+			var se StackEntry
+			if se.Function, err = funcAt(sej.Function); err != nil {
+				return err
+			}
+			if se.Call, err = optSiteAt(sej.Call); err != nil {
+				return err
+			}
+			v.CallStack = append(v.CallStack, se)
+		}
+		res.Vulns = append(res.Vulns, v)
+	}
+	*r = res
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/token"
+	"slices"
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestResultJSON(t *testing.T) {
+	mainPkg := &packages.Package{PkgPath: "example.com/m", Module: &packages.Module{Path: "example.com/m"}}
+	vulnPkg := &packages.Package{PkgPath: "example.com/vuln", Module: &packages.Module{Path: "example.com/vuln", Version: "v1.1.0"}}
+
+	// main calls f, f and g call each other, and g calls Vuln.
+	main := &vulncheck.FuncNode{Name: "main", Package: mainPkg, Pos: &token.Position{Filename: "main.go", Line: 3}}
+	f := &vulncheck.FuncNode{Name: "f", Package: mainPkg}
+	g := &vulncheck.FuncNode{Name: "g", Package: mainPkg}
+	sink := &vulncheck.FuncNode{Name: "Vuln", Package: vulnPkg}
+	mainF := &vulncheck.CallSite{Parent: main, Name: "f", Resolved: true, Pos: &token.Position{Filename: "main.go", Line: 4}}
+	fG := &vulncheck.CallSite{Parent: f, Name: "g", Resolved: true}
+	gF := &vulncheck.CallSite{Parent: g, Name: "f", Resolved: true}
+	gSink := &vulncheck.CallSite{Parent: g, Name: "Vuln", Resolved: true}
+	f.CallSites = []*vulncheck.CallSite{mainF, gF}
+	g.CallSites = []*vulncheck.CallSite{fG}
+	sink.CallSites = []*vulncheck.CallSite{gSink}
+
+	entry := &osv.Entry{ID: "GO-0000-0001"}
+	called := &vulncheck.Vuln{OSV: entry, Symbol: "Vuln", CallSink: sink, Package: vulnPkg, Taint: []govulncheck.TaintSource{govulncheck.TaintSourceNetwork}}
+	used := &vulncheck.Vuln{OSV: entry, Symbol: "T", Package: vulnPkg, Use: &vulncheck.Use{Kind: govulncheck.SymbolKindType, Parent: g}}
+	vr := &vulncheck.Result{
+		EntryFunctions: []*vulncheck.FuncNode{main},
+		Vulns:          []*vulncheck.Vuln{called, used},
+		CallStacks: map[*vulncheck.Vuln]vulncheck.CallStack{
+			called: {{Function: main, Call: mainF}, {Function: f, Call: fG}, {Function: g, Call: gSink}, {Function: sink}},
+		},
+	}
+
+	data, err := json.Marshal(newResult(vr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r Result
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	data2, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, data2) {
+		t.Errorf("re-encoding changed the result:\n%s\n%s", data, data2)
+	}
+
+	if len(r.Vulns) != 2 || r.Vulns[0].OSV != r.Vulns[1].OSV {
+		t.Fatalf("got vulns %+v; want two sharing one entry", r.Vulns)
+	}
+	v := r.Vulns[0]
+	if v.Module != "example.com/vuln" || v.Version != "v1.1.0" || v.CallSink.Name != "Vuln" {
+		t.Errorf("got vuln %+v; want the call of example.com/vuln@v1.1.0.Vuln", v)
+	}
+	if len(v.CallStack) != 4 || v.CallStack[0].Function != r.EntryFunctions[0] {
+		t.Fatalf("got call stack %+v; want 4 entries from the entry function", v.CallStack)
+	}
+	for i, e := range v.CallStack[:3] {
+		next := v.CallStack[i+1].Function
+		if e.Call.Parent != e.Function || !slices.Contains(next.CallSites, e.Call) {
+			t.Errorf("call %d of the call stack is not made by %s to %s", i, e.Function.Name, next.Name)
+		}
+	}
+	// The cycle between f and g is preserved.
+	f2, g2 := v.CallStack[1].Function, v.CallStack[2].Function
+	if g2.CallSites[0].Parent != f2 {
+		t.Error("the call of g by f was not decoded")
+	}
+	if f2.CallSites[1].Parent != g2 {
+		t.Error("the call of f by g was not decoded")
+	}
+	if u := r.Vulns[1].Use; u == nil || u.Parent != g2 || u.Kind != govulncheck.SymbolKindType {
+		t.Errorf("got use %+v; want a type used in g", u)
+	}
+
+	for _, bad := range []string{
+		`{"version":2}`,
+		`{"version":1,"entry_functions":[0]}`,
+		`{"version":1,"vulns":[{"osv":"GO-0000-0001"}]}`,
+	} {
+		if err := json.Unmarshal([]byte(bad), &r); err == nil {
+			t.Errorf("decoding %s succeeded; want an error", bad)
+		}
+	}
+}
@@ -5,6 +5,11 @@
 /*
 Package scan provides functionality for running govulncheck.
 
+A [Cmd] runs govulncheck as the command does, writing its output. Programs
+processing the results themselves can use a [Scanner] instead, which passes
+them to a [Handler] as values, and whose AnalyzeSource method also returns
+the [Result] of the analysis, which can be stored as JSON.
+
 See [cmd/govulncheck/main.go] as a usage example.
 
 [cmd/govulncheck/main.go]: https://go.googlesource.com/vuln/+/master/cmd/govulncheck/main.go
@@ -17,7 +22,10 @@ import (
 	"io"
 	"os"
 
+	"github.com/StevenACoffman/invuln/external/client"
+	"github.com/StevenACoffman/invuln/external/osv"
 	"github.com/StevenACoffman/invuln/external/scan"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
 )
 
 // Cmd represents an external govulncheck command being prepared or run,
@@ -43,6 +51,11 @@ type Cmd struct {
 	//
 	Env []string
 
+	// Client, if set, is the vulnerability database client used instead
+	// of the databases given by the -db flag, such as one returned by
+	// NewInMemoryClient for hermetic tests.
+	Client *Client
+
 	ctx  context.Context
 	args []string
 	done chan struct{}
@@ -108,9 +121,58 @@ func (c *Cmd) Wait() error {
 	return c.err
 }
 
+// A Client reads a vulnerability database.
+type Client = client.Client
+
+// An Entry is a vulnerability in the OSV format.
+type Entry = osv.Entry
+
+// NewInMemoryClient returns a client of a database holding entries
+// in memory, so that tests of tools running govulncheck can scan
+// against synthetic vulnerabilities without network access.
+func NewInMemoryClient(entries []*Entry) (*Client, error) {
+	return client.NewInMemoryClient(entries)
+}
+
+// A Fetcher retrieves the content of a binary named by a URL.
+type Fetcher = scan.Fetcher
+
+// FetcherFunc adapts an ordinary function to the Fetcher interface.
+type FetcherFunc = scan.FetcherFunc
+
+// RegisterFetcher makes f the fetcher for binary URLs with the given
+// scheme, so that binary mode can scan, for example, s3:// or oci://
+// artifacts. Fetchers for "http" and "https" are registered by default.
+// RegisterFetcher affects all commands run by the process.
+func RegisterFetcher(scheme string, f Fetcher) {
+	scan.RegisterFetcher(scheme, f)
+}
+
+// A SymbolMatcher matches the packages and symbols of the code
+// scanned to vulnerable ones of OSV entries they are copies of, such
+// as those of internal forks of vulnerable modules with renamed
+// packages, or of packages moved to vanity import paths.
+type SymbolMatcher = vulncheck.SymbolMatcher
+
+// SymbolMatcherFunc adapts an ordinary function to the SymbolMatcher interface.
+type SymbolMatcherFunc = vulncheck.SymbolMatcherFunc
+
+// A MatchedSymbol is a vulnerable symbol of an OSV entry.
+type MatchedSymbol = vulncheck.MatchedSymbol
+
+// RegisterSymbolMatcher adds m to the matchers consulted, in addition to
+// matching by name, when looking up the vulnerabilities of the packages
+// and symbols of the code scanned. Only the vulnerabilities of the
+// modules of the code scanned are fetched, so the symbols matched must
+// be of those modules. RegisterSymbolMatcher affects all commands run
+// by the process.
+func RegisterSymbolMatcher(m SymbolMatcher) {
+	vulncheck.RegisterSymbolMatcher(m)
+}
+
 func (c *Cmd) scan() error {
 	if err := c.ctx.Err(); err != nil {
 		return err
 	}
-	return scan.RunGovulncheck(c.ctx, c.Env, c.Stdin, c.Stdout, c.Stderr, c.args)
+	return scan.RunGovulncheckWithClient(c.ctx, c.Env, c.Stdin, c.Stdout, c.Stderr, c.args, c.Client)
 }
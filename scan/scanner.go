@@ -0,0 +1,355 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/StevenACoffman/invuln/external/govulncheck"
+	"github.com/StevenACoffman/invuln/external/scan"
+	"github.com/StevenACoffman/invuln/external/vulncheck"
+	"golang.org/x/tools/go/packages"
+)
+
+// A Handler receives the results of a scan as a stream of messages,
+// those of the JSON output of govulncheck: the Config first, then the
+// SBOM, Progress, OSV, and Finding messages in any order.
+type Handler = govulncheck.Handler
+
+// Config is the configuration of a scan, passed to Handler.Config.
+type Config = govulncheck.Config
+
+// SBOM lists the modules scanned, passed to Handler.SBOM.
+type SBOM = govulncheck.SBOM
+
+// Progress reports the progress of a scan, passed to Handler.Progress.
+type Progress = govulncheck.Progress
+
+// A Finding is a vulnerability found by a scan, passed to Handler.Finding.
+type Finding = govulncheck.Finding
+
+// A ModuleUpgrade is the upgrade of a module clearing
+// the findings of its vulnerabilities, as planned by FixPlan.
+type ModuleUpgrade = scan.ModuleUpgrade
+
+// FixPlan returns, for each module of findings, the smallest upgrade
+// clearing its findings: the highest fixed version of the
+// vulnerabilities found. The upgrades are applied by passing their
+// GoGetArg to go get, which raises the versions of the other modules
+// as needed by minimal version selection. Findings with no fixed
+// version are listed as unfixed.
+func FixPlan(findings []*Finding) ([]ModuleUpgrade, error) {
+	return scan.FixPlan(findings)
+}
+
+// A ProgressEvent reports the progress of a phase of a scan,
+// passed to Options.Progress.
+type ProgressEvent = govulncheck.ProgressEvent
+
+// A Phase is a phase of a scan reported by a ProgressEvent.
+type Phase = govulncheck.Phase
+
+// The phases of a scan. See the constants of
+// the same names of package govulncheck.
+const (
+	PhaseLoad      = govulncheck.PhaseLoad
+	PhaseFetch     = govulncheck.PhaseFetch
+	PhaseCallGraph = govulncheck.PhaseCallGraph
+	PhaseAnalyze   = govulncheck.PhaseAnalyze
+)
+
+// A ProgressKind is the kind of a ProgressEvent.
+type ProgressKind = govulncheck.ProgressKind
+
+// The kinds of ProgressEvent.
+const (
+	ProgressStarted   = govulncheck.ProgressStarted
+	ProgressAdvanced  = govulncheck.ProgressAdvanced
+	ProgressCompleted = govulncheck.ProgressCompleted
+)
+
+// An Error is the failure of a scan run by the methods of Scanner
+// or by a Cmd, as found by errors.As in the error they return. Its
+// Code classifies the failure, so that programs can tell failures
+// apart. Errors opening the files of ScanBinaryFS are not scan
+// failures, and are returned as they are.
+type Error = govulncheck.Error
+
+// An ErrorCode classifies the failure of a scan.
+type ErrorCode = govulncheck.ErrorCode
+
+// The codes of the failures of scans. See the constants of
+// the same names of package govulncheck.
+const (
+	ErrorUsage             = govulncheck.ErrorUsage
+	ErrorNoGoMod           = govulncheck.ErrorNoGoMod
+	ErrorNoPackages        = govulncheck.ErrorNoPackages
+	ErrorBuildFailed       = govulncheck.ErrorBuildFailed
+	ErrorGoVersionMismatch = govulncheck.ErrorGoVersionMismatch
+	ErrorUnsupportedBinary = govulncheck.ErrorUnsupportedBinary
+	ErrorDBUnreachable     = govulncheck.ErrorDBUnreachable
+	ErrorCanceled          = govulncheck.ErrorCanceled
+	ErrorUnknown           = govulncheck.ErrorUnknown
+)
+
+// Metrics records measurements of scans, passed to Options.Metrics,
+// for programs to export them, as to Prometheus or OpenTelemetry,
+// instead of timing scans from the outside. Its methods may be called
+// concurrently.
+type Metrics = govulncheck.Metrics
+
+// The names of the counters and timers of Metrics. See the constants
+// of the same names of package govulncheck. The phases completed are
+// timed under the names returned by PhaseMetric.
+const (
+	MetricDBRequests        = govulncheck.MetricDBRequests
+	MetricDBRetries         = govulncheck.MetricDBRetries
+	MetricDBErrors          = govulncheck.MetricDBErrors
+	MetricDBRequestDuration = govulncheck.MetricDBRequestDuration
+	MetricModuleFindings    = govulncheck.MetricModuleFindings
+	MetricPackageFindings   = govulncheck.MetricPackageFindings
+	MetricSymbolFindings    = govulncheck.MetricSymbolFindings
+)
+
+// PhaseMetric returns the name of the timer of the phases phase
+// completed, as in phase_load_duration for PhaseLoad.
+func PhaseMetric(phase Phase) string {
+	return govulncheck.PhaseMetric(phase)
+}
+
+// A Scanner scans Go code for known vulnerabilities, passing the
+// results to a Handler, so that tools can embed govulncheck without
+// running the command and parsing its output. The zero Scanner reads
+// the databases of its options, and uses the current environment.
+//
+// Scans stop once their context is done, whether they are loading
+// packages, building call graphs, searching for call stacks, or
+// reading databases, and then return the error of the context.
+type Scanner struct {
+	// Client, if set, is the vulnerability database client used
+	// instead of the databases of Options.DB.
+	Client *Client
+
+	// Env is the environment to use, as for Cmd.Env.
+	// If Env is nil, the current environment is used.
+	Env []string
+}
+
+// Options configure a scan. The zero Options scan at the
+// symbol level against https://vuln.go.dev.
+type Options struct {
+	// DB are the URLs of the vulnerability databases read when the
+	// Scanner has no Client, as for the -db flag.
+	DB []string
+
+	// Level is the scanning level, one of "stdlib", "module",
+	// "package", or "symbol", as for the -scan flag.
+	Level string
+
+	// Dir is the directory the packages are loaded from.
+	// It is only valid for ScanSource.
+	Dir string
+
+	// Tags are the build tags. They are only valid for ScanSource.
+	Tags []string
+
+	// Test reports whether test files are analyzed.
+	// It is only valid for ScanSource.
+	Test bool
+
+	// Flags are further govulncheck command line flags, such as
+	// "-platform=linux/arm64", for the settings without a field.
+	Flags []string
+
+	// Progress, if set, is passed the progress of the phases of the
+	// scan, such as the files parsed while loading packages, or the
+	// vulnerabilities analyzed, to show progress bars. Calls are
+	// serialized, and are made from the goroutines of the scan.
+	Progress func(*ProgressEvent)
+
+	// Logger, if set, is passed structured records of the internals of
+	// the scan, such as the packages loaded, the requests made to the
+	// databases, and the time taken by each phase of the analysis.
+	// Most are logged at the debug level, so that the level of the
+	// handler of Logger selects the records wanted.
+	Logger *slog.Logger
+
+	// Metrics, if set, records measurements of the scan, such as the
+	// time taken by its phases, the requests made to the databases,
+	// and the findings of each level. By default, none are recorded.
+	Metrics Metrics
+}
+
+// ScanSource scans the packages matching patterns, passing the
+// results to h. Usage errors, such as options not valid for source
+// code, are returned before h is passed any message.
+func (s *Scanner) ScanSource(ctx context.Context, patterns []string, opts *Options, h Handler) error {
+	return s.scan(ctx, "source", opts, patterns, h)
+}
+
+// AnalyzeSource scans the packages matching patterns as ScanSource
+// does, and returns the result of the analysis, whose call stacks are
+// those of the findings passed to h. The -platforms, -verify, -cache,
+// and -emit-graph flags, which analyze the packages more than once or
+// not at all, are not supported.
+func (s *Scanner) AnalyzeSource(ctx context.Context, patterns []string, opts *Options, h Handler) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, govulncheck.NewError(govulncheck.ErrorCanceled, err)
+	}
+	vr, err := scan.SourceResult(opts.context(ctx), s.env(), opts.args("source", patterns), s.Client, h)
+	if err != nil {
+		return nil, err
+	}
+	return newResult(vr), nil
+}
+
+// The modes of packages.Config in which the packages passed to
+// ScanPackages are loaded: SymbolLoadMode for symbol level scans,
+// and PackageLoadMode for the others.
+const (
+	PackageLoadMode = vulncheck.PackageLoadMode
+	SymbolLoadMode  = vulncheck.SymbolLoadMode
+)
+
+// ScanPackages scans pkgs, which the caller loaded, such as a build
+// system with its own loader or an editor holding packages in memory,
+// as ScanSource scans the packages it loads, so that they are not
+// loaded twice. The packages must be loaded in SymbolLoadMode, or in
+// PackageLoadMode for package level scans, and their dependencies are
+// scanned along with them. Since the packages are already loaded, Tags
+// and Test are not supported, and Dir is ignored.
+func (s *Scanner) ScanPackages(ctx context.Context, pkgs []*packages.Package, opts *Options, h Handler) error {
+	if err := ctx.Err(); err != nil {
+		return govulncheck.NewError(govulncheck.ErrorCanceled, err)
+	}
+	return scan.RunPackagesWithHandler(opts.context(ctx), s.env(), opts.args("source", nil), s.Client, h, pkgs)
+}
+
+// ScanBinary scans the Go binary at path, or the binary archive
+// or URL, as in binary mode, passing the results to h.
+func (s *Scanner) ScanBinary(ctx context.Context, path string, opts *Options, h Handler) error {
+	return s.scan(ctx, "binary", opts, []string{path}, h)
+}
+
+// ScanBinaryReader scans the binary of the given size read from r,
+// such as one streamed from object storage, as ScanBinary does for
+// files, without writing it to disk. The binary is named name in
+// findings and errors. Archives are recognized by the extension of
+// name, or else by their content.
+func (s *Scanner) ScanBinaryReader(ctx context.Context, name string, r io.ReaderAt, size int64, opts *Options, h Handler) error {
+	return s.scanBinaries(ctx, opts, []scan.Binary{{Name: name, R: r, Size: size}}, h)
+}
+
+// ScanBinaryFS scans the binaries at paths in fsys, as ScanBinary does
+// for files. Files implementing io.ReaderAt, as those of os.DirFS do,
+// are read in place, and others are read into memory first.
+func (s *Scanner) ScanBinaryFS(ctx context.Context, fsys fs.FS, paths []string, opts *Options, h Handler) error {
+	var bins []scan.Binary
+	for _, path := range paths {
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if r, ok := f.(io.ReaderAt); ok {
+			fi, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			bins = append(bins, scan.Binary{Name: path, R: r, Size: fi.Size()})
+			continue
+		}
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		bins = append(bins, scan.Binary{Name: path, R: bytes.NewReader(data), Size: int64(len(data))})
+	}
+	return s.scanBinaries(ctx, opts, bins, h)
+}
+
+func (s *Scanner) scanBinaries(ctx context.Context, opts *Options, bins []scan.Binary, h Handler) error {
+	if err := ctx.Err(); err != nil {
+		return govulncheck.NewError(govulncheck.ErrorCanceled, err)
+	}
+	// The binaries are the patterns of the scan, so there are no targets.
+	return scan.RunBinariesWithHandler(opts.context(ctx), s.env(), opts.args("binary", nil), s.Client, h, bins)
+}
+
+// ScanModule passes the entries of the vulnerabilities affecting the
+// module path at version, such as v1.2.3, to h.OSV, as for a
+// module@version query. No code is loaded, so no findings are made.
+func (s *Scanner) ScanModule(ctx context.Context, path, version string, opts *Options, h Handler) error {
+	return s.scan(ctx, "query", opts, []string{path + "@" + version}, h)
+}
+
+func (s *Scanner) scan(ctx context.Context, mode string, opts *Options, targets []string, h Handler) error {
+	if err := ctx.Err(); err != nil {
+		return govulncheck.NewError(govulncheck.ErrorCanceled, err)
+	}
+	return scan.RunWithHandler(opts.context(ctx), s.env(), opts.args(mode, targets), s.Client, h)
+}
+
+func (s *Scanner) env() []string {
+	if s.Env == nil {
+		return os.Environ()
+	}
+	return s.Env
+}
+
+// context returns ctx, reporting progress to the Progress
+// function of o, which may be nil, logging to its Logger,
+// and recording measurements to its Metrics.
+func (o *Options) context(ctx context.Context) context.Context {
+	if o == nil {
+		return ctx
+	}
+	if o.Progress != nil {
+		ctx = govulncheck.WithProgress(ctx, o.Progress)
+	}
+	if o.Logger != nil {
+		ctx = govulncheck.WithLogger(ctx, o.Logger)
+	}
+	if o.Metrics != nil {
+		ctx = govulncheck.WithMetrics(ctx, o.Metrics)
+	}
+	return ctx
+}
+
+// args returns the command line arguments of a scan of
+// targets in mode with o, which may be nil.
+func (o *Options) args(mode string, targets []string) []string {
+	args := []string{"-mode", mode}
+	if o != nil {
+		for _, db := range o.DB {
+			args = append(args, "-db", db)
+		}
+		if o.Level != "" {
+			args = append(args, "-scan", o.Level)
+		}
+		if o.Dir != "" {
+			args = append(args, "-C", o.Dir)
+		}
+		if len(o.Tags) > 0 {
+			args = append(args, "-tags", strings.Join(o.Tags, ","))
+		}
+		if o.Test {
+			args = append(args, "-test")
+		}
+		args = append(args, o.Flags...)
+	}
+	if len(targets) == 0 {
+		return args
+	}
+	// The targets follow "--", so that they are not taken for flags.
+	return append(append(args, "--"), targets...)
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/StevenACoffman/invuln/external/test"
+)
+
+func TestScanBinaryFS(t *testing.T) {
+	db, err := filepath.Abs(filepath.Join("..", "cmd", "govulncheck", "testdata", "common", "vulndb-v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := os.ReadFile(filepath.Join("..", "cmd", "govulncheck", "testdata", "common", "testfiles", "extract", "vuln.blob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"artifacts/vuln": {Data: blob}}
+	opts := &Options{DB: []string{"file://" + filepath.ToSlash(db)}}
+
+	var s Scanner
+	h := test.NewMockHandler()
+	if err := s.ScanBinaryFS(context.Background(), fsys, []string{"artifacts/vuln"}, opts, h); err != nil {
+		t.Fatal(err)
+	}
+	if len(h.FindingMessages) == 0 {
+		t.Error("got no findings for the blob")
+	}
+
+	if err := s.ScanBinaryFS(context.Background(), fsys, []string{"artifacts/missing"}, opts, test.NewMockHandler()); err == nil {
+		t.Error("scanning a missing file succeeded; want an error")
+	}
+}
+
+func TestScanLogger(t *testing.T) {
+	db, err := filepath.Abs(filepath.Join("..", "cmd", "govulncheck", "testdata", "common", "vulndb-v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := os.ReadFile(filepath.Join("..", "cmd", "govulncheck", "testdata", "common", "testfiles", "extract", "vuln.blob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"vuln": {Data: blob}}
+	var buf bytes.Buffer
+	opts := &Options{
+		DB:     []string{"file://" + filepath.ToSlash(db)},
+		Logger: slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+
+	var s Scanner
+	if err := s.ScanBinaryFS(context.Background(), fsys, []string{"vuln"}, opts, test.NewMockHandler()); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{`msg="read binary"`, `msg="fetched vulnerabilities"`} {
+		if !strings.Contains(buf.String(), msg) {
+			t.Errorf("log has no record with %s:\n%s", msg, &buf)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package semver compares Go module and toolchain versions, and
+// matches them against the affected ranges of OSV entries, as
+// govulncheck does, so that tools processing its findings agree
+// with it.
+//
+// Versions may have a "v" prefix, as in v1.2.3, a "go" prefix, as in
+// go1.21.5, or none. Pseudo-versions are ordered as the prereleases
+// they are, as in the go command: v1.2.4-0.20210101000000-0123456789ab,
+// of a commit following v1.2.3, is between v1.2.3 and v1.2.4. Build
+// metadata, such as +incompatible, is ignored.
+package semver
+
+import (
+	"github.com/StevenACoffman/invuln/external/osv"
+	"github.com/StevenACoffman/invuln/external/semver"
+)
+
+// A Range is an affected range of an OSV entry.
+type Range = osv.Range
+
+// Compare returns -1, 0, or 1 as v1 is less than, equal to, or
+// greater than v2. Invalid versions are less than valid ones, and
+// equal to each other.
+func Compare(v1, v2 string) int {
+	return semver.Compare(v1, v2)
+}
+
+// Less reports whether v1 is less than v2.
+func Less(v1, v2 string) bool {
+	return semver.Less(v1, v2)
+}
+
+// Valid reports whether v is a valid version.
+func Valid(v string) bool {
+	return semver.Valid(v)
+}
+
+// GoTagToSemver returns the semantic version of the Go release tag,
+// as in v1.21.5 for go1.21.5 and v1.22.0-rc.1 for go1.22rc1, or ""
+// if tag is not the tag of a release. Anything after a space in tag,
+// as in the output of go version, is ignored.
+func GoTagToSemver(tag string) string {
+	return semver.GoTagToSemver(tag)
+}
+
+// SemverToGoTag returns the Go release tag of the semantic version v,
+// as in go1.21.5 for v1.21.5 and go1.20 for v1.20.0.
+func SemverToGoTag(v string) string {
+	return semver.SemverToGoTag(v)
+}
+
+// Affects reports whether version v is in any of the semver ranges,
+// ranges of other types being ignored. If there are no semver ranges,
+// all versions are affected.
+func Affects(ranges []Range, v string) bool {
+	return semver.Affects(ranges, v)
+}
+
+// ContainsSemver reports whether version v is in the semver range r,
+// whose events delimit intervals including the introduced versions
+// and excluding the fixed ones.
+func ContainsSemver(r Range, v string) bool {
+	return semver.ContainsSemver(r, v)
+}
+
+// NonSupersededFix returns the latest fixed version of ranges, unless
+// the vulnerability was introduced again after it, and otherwise "".
+func NonSupersededFix(ranges []Range) string {
+	return semver.NonSupersededFix(ranges)
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"testing"
+
+	"github.com/StevenACoffman/invuln/external/osv"
+)
+
+func TestCompare(t *testing.T) {
+	for _, test := range []struct {
+		v1, v2 string
+		want   int
+	}{
+		{"v1.2.3", "1.2.3", 0},
+		{"go1.21.5", "v1.21.5", 0},
+		{"v1.2.3", "v1.2.4-0.20210101000000-0123456789ab", -1},
+		{"v1.2.4-0.20210101000000-0123456789ab", "v1.2.4", -1},
+		{"v0.0.0-20210101000000-0123456789ab", "v0.0.0-20220101000000-0123456789ab", -1},
+		{"v2.0.0+incompatible", "v2.0.0", 0},
+		{"bad", "v0.0.1", -1},
+	} {
+		if got := Compare(test.v1, test.v2); got != test.want {
+			t.Errorf("Compare(%q, %q) = %d; want %d", test.v1, test.v2, got, test.want)
+		}
+	}
+}
+
+func TestAffectsPseudoVersions(t *testing.T) {
+	events := []osv.RangeEvent{{Fixed: "1.2.4"}, {Introduced: "1.2.0"}}
+	ranges := []Range{{Type: osv.RangeTypeSemver, Events: events}}
+	for v, want := range map[string]bool{
+		"v1.1.9": false,
+		"v1.2.0": true,
+		// A commit after v1.2.3, which is not yet v1.2.4.
+		"v1.2.4-0.20210101000000-0123456789ab": true,
+		"v1.2.4":                               false,
+	} {
+		if got := Affects(ranges, v); got != want {
+			t.Errorf("Affects(%s) = %t; want %t", v, got, want)
+		}
+	}
+	if events[0].Fixed != "1.2.4" {
+		t.Error("the events of the range were reordered")
+	}
+	if got := NonSupersededFix(ranges); got != "1.2.4" {
+		t.Errorf("NonSupersededFix = %q; want 1.2.4", got)
+	}
+}